@@ -0,0 +1,37 @@
+/*
+Package agentbeat contains the entrypoint to Agentbeat, which runs Filebeat,
+Metricbeat and Packetbeat in one process. The Beats configured in the
+filebeat, metricbeat and packetbeat sections of the configuration file are run
+and share the publisher, the queues and the outputs, such that only one
+process with one set of queues has to be run per host.
+*/
+package main
+
+import (
+	"os"
+
+	filebeat "github.com/elastic/beats/filebeat/beater"
+	"github.com/elastic/beats/libbeat/beat"
+	metricbeat "github.com/elastic/beats/metricbeat/beater"
+	packetbeat "github.com/elastic/beats/packetbeat/beater"
+
+	// import metricbeat modules
+	_ "github.com/elastic/beats/metricbeat/include"
+
+	// import support protocol modules
+	_ "github.com/elastic/beats/packetbeat/include"
+)
+
+// Name of this Beat.
+var Name = "agentbeat"
+
+func main() {
+	supervisor := beat.NewSupervisor(
+		beat.Section{Name: "filebeat", Beater: filebeat.New()},
+		beat.Section{Name: "metricbeat", Beater: metricbeat.New()},
+		beat.Section{Name: "packetbeat", Beater: packetbeat.New()},
+	)
+	if err := beat.Run(Name, "", supervisor); err != nil {
+		os.Exit(1)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/control"
 	"github.com/elastic/beats/libbeat/logp"
 
 	cfg "github.com/elastic/beats/filebeat/config"
@@ -33,16 +35,27 @@ func (fb *Filebeat) Config(b *beat.Beat) error {
 
 	// Load Base config
 	err := b.RawConfig.Unpack(&fb.config)
+	if err != nil {
+		return fmt.Errorf("Error reading config file: %v", cfgfile.AnnotateError(err))
+	}
+
+	fb.config.Filebeat.Prospectors, err = cfgfile.ExpandVars(b.Config.Vars, fb.config.Filebeat.Prospectors)
 	if err != nil {
 		return fmt.Errorf("Error reading config file: %v", err)
 	}
 
 	// Check if optional config_dir is set to fetch additional prospector config files
-	fb.config.FetchConfigs()
+	fb.config.FetchConfigs(b.Config.Vars)
 
 	return nil
 }
 
+// ConfigSchema returns the configuration structure of filebeat, used to
+// detect unknown settings.
+func (fb *Filebeat) ConfigSchema() interface{} {
+	return cfg.Config{}
+}
+
 // Setup applies the minimum required setup to a new Filebeat instance for use.
 func (fb *Filebeat) Setup(b *beat.Beat) error {
 	fb.done = make(chan struct{})
@@ -67,7 +80,7 @@ func (fb *Filebeat) Run(b *beat.Beat) error {
 
 	// Publishes event to output
 	publisher := publish.New(config.PublishAsync,
-		publisherChan, registrar.Channel, b.Publisher.Connect())
+		publisherChan, registrar.Channel, b.Connect())
 
 	// Init and Start spooler: Harvesters dump events into the spooler.
 	spooler, err := spooler.New(config, publisherChan)
@@ -76,7 +89,13 @@ func (fb *Filebeat) Run(b *beat.Beat) error {
 		return err
 	}
 
-	crawler, err := crawler.New(spooler, config.Prospectors)
+	// Prospectors with an independent pipeline get their own spooler and
+	// publisher client, which forward the published events to the registrar
+	newPipeline := func(pipelineConfig cfg.PipelineConfig) (crawler.Pipeline, error) {
+		return newProspectorPipeline(config, pipelineConfig, registrar.Channel, b.Connect())
+	}
+
+	crawler, err := crawler.New(spooler, config.Prospectors, newPipeline)
 	if err != nil {
 		logp.Err("Could not init crawler: %v", err)
 		return err
@@ -111,6 +130,9 @@ func (fb *Filebeat) Run(b *beat.Beat) error {
 	// Stop crawler -> stop prospectors -> stop harvesters
 	defer crawler.Stop()
 
+	// List prospectors and harvesters via the control socket
+	control.RegisterInputs(crawler.Inputs)
+
 	// Blocks progressing. As soon as channel is closed, all defer statements come into play
 	<-fb.done
 
@@ -0,0 +1,62 @@
+package beater
+
+import (
+	cfg "github.com/elastic/beats/filebeat/config"
+	"github.com/elastic/beats/filebeat/input"
+	"github.com/elastic/beats/filebeat/publish"
+	"github.com/elastic/beats/filebeat/spooler"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// prospectorPipeline is the independent pipeline of a prospector. It consists
+// of a spooler and a publisher with its own publisher client, such that the
+// events of the prospector are queued, acknowledged and forwarded to the
+// registrar independent of the events of all other prospectors.
+type prospectorPipeline struct {
+	spooler   *spooler.Spooler
+	publisher publish.LogPublisher
+}
+
+// newProspectorPipeline creates and starts an independent pipeline. Options
+// not set in pipelineConfig are taken from the global filebeat options.
+func newProspectorPipeline(
+	config cfg.FilebeatConfig,
+	pipelineConfig cfg.PipelineConfig,
+	registrarChan chan []*input.FileEvent,
+	client publisher.Client,
+) (*prospectorPipeline, error) {
+	if pipelineConfig.SpoolSize > 0 {
+		config.SpoolSize = pipelineConfig.SpoolSize
+	}
+	if pipelineConfig.IdleTimeout > 0 {
+		config.IdleTimeout = pipelineConfig.IdleTimeout
+	}
+
+	publisherChan := make(chan []*input.FileEvent, 1)
+	spooler, err := spooler.New(config, publisherChan)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	p := &prospectorPipeline{
+		spooler:   spooler,
+		publisher: publish.New(config.PublishAsync, publisherChan, registrarChan, client),
+	}
+
+	logp.Info("Starting independent prospector pipeline")
+	p.publisher.Start()
+	p.spooler.Start()
+	return p, nil
+}
+
+func (p *prospectorPipeline) Channel() chan *input.FileEvent {
+	return p.spooler.Channel
+}
+
+// Stop flushes the spooler and stops the publisher.
+func (p *prospectorPipeline) Stop() {
+	p.spooler.Stop()
+	p.publisher.Stop()
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -40,15 +41,27 @@ type FilebeatConfig struct {
 	ConfigDir    string           `config:"config_dir"`
 }
 
+// PipelineConfig configures the independent pipeline of a prospector. Unset
+// options default to the global filebeat options.
+type PipelineConfig struct {
+	Enabled     bool          `config:"enabled"`
+	SpoolSize   uint64        `config:"spool_size"`
+	IdleTimeout time.Duration `config:"idle_timeout" validate:"min=0"`
+}
+
 const (
-	LogInputType   = "log"
-	StdinInputType = "stdin"
+	LogInputType     = "log"
+	StdinInputType   = "stdin"
+	OSLogInputType   = "oslog"
+	OSQueryInputType = "osquery"
 )
 
 // List of valid input types
 var ValidInputType = map[string]struct{}{
-	StdinInputType: {},
-	LogInputType:   {},
+	StdinInputType:   {},
+	LogInputType:     {},
+	OSLogInputType:   {},
+	OSQueryInputType: {},
 }
 
 // getConfigFiles returns list of config files.
@@ -82,23 +95,44 @@ func getConfigFiles(path string) (configFiles []string, err error) {
 	return configFiles, nil
 }
 
-// mergeConfigFiles reads in all config files given by list configFiles and merges them into config
-func mergeConfigFiles(configFiles []string, config *Config) error {
+// configDirFile is the content of a config file in config_dir. Variables
+// set in the file are only used for the prospectors of the file.
+type configDirFile struct {
+	Vars     map[string]interface{} `config:"vars"`
+	Filebeat FilebeatConfig
+}
+
+// mergeConfigFiles reads in all config files given by list configFiles and merges them into config.
+// The variables of the prospectors are expanded with the global vars and the vars of the file.
+func mergeConfigFiles(configFiles []string, config *Config, vars map[string]interface{}) error {
 
 	for _, file := range configFiles {
 		logp.Info("Additional configs loaded from: %s", file)
 
-		tmpConfig := &Config{}
+		tmpConfig := &configDirFile{}
 		cfgfile.Read(tmpConfig, file)
 
-		config.Filebeat.Prospectors = append(config.Filebeat.Prospectors, tmpConfig.Filebeat.Prospectors...)
+		fileVars := map[string]interface{}{}
+		for k, v := range vars {
+			fileVars[k] = v
+		}
+		for k, v := range tmpConfig.Vars {
+			fileVars[k] = v
+		}
+
+		prospectors, err := cfgfile.ExpandVars(fileVars, tmpConfig.Filebeat.Prospectors)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+
+		config.Filebeat.Prospectors = append(config.Filebeat.Prospectors, prospectors...)
 	}
 
 	return nil
 }
 
 // Fetches and merges all config files given by configDir. All are put into one config object
-func (config *Config) FetchConfigs() {
+func (config *Config) FetchConfigs(vars map[string]interface{}) {
 
 	configDir := config.Filebeat.ConfigDir
 
@@ -119,7 +153,7 @@ func (config *Config) FetchConfigs() {
 		log.Fatal("Could not use config_dir of: ", configDir, err)
 	}
 
-	err = mergeConfigFiles(configFiles, config)
+	err = mergeConfigFiles(configFiles, config, vars)
 	if err != nil {
 		log.Fatal("Error merging config files: ", err)
 	}
@@ -3,6 +3,8 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -92,7 +94,45 @@ func TestMergeConfigFiles(t *testing.T) {
 	assert.Equal(t, 2, len(files))
 
 	config := &Config{}
-	mergeConfigFiles(files, config)
+	mergeConfigFiles(files, config, nil)
 
 	assert.Equal(t, 4, len(config.Filebeat.Prospectors))
 }
+
+func TestMergeConfigFilesVars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "apps.yml")
+	content := `
+vars:
+  type: app
+filebeat.prospectors:
+- input_type: log
+  vars:
+    - {app: billing}
+    - {app: checkout}
+  paths: ["{{.log_dir}}/{{.app}}.log"]
+  document_type: "{{.type}}"
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{}
+	err = mergeConfigFiles([]string{path}, config, map[string]interface{}{"log_dir": "/var/log"})
+	assert.NoError(t, err)
+
+	if assert.Len(t, config.Filebeat.Prospectors, 2) {
+		var prospector struct {
+			Paths        []string `config:"paths"`
+			DocumentType string   `config:"document_type"`
+		}
+		assert.NoError(t, config.Filebeat.Prospectors[1].Unpack(&prospector))
+		assert.Equal(t, []string{"/var/log/checkout.log"}, prospector.Paths)
+		assert.Equal(t, "app", prospector.DocumentType)
+	}
+}
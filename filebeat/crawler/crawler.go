@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 
+	cfg "github.com/elastic/beats/filebeat/config"
+	"github.com/elastic/beats/filebeat/input"
 	"github.com/elastic/beats/filebeat/input/file"
 	"github.com/elastic/beats/filebeat/prospector"
 	"github.com/elastic/beats/filebeat/spooler"
@@ -21,16 +23,32 @@ import (
  		The harvester send their events to the spooler
  		The spooler sends the event to the publisher
  		The publisher writes the state down with the registrar
+ Pipeline: Prospectors with an independent pipeline send their events to their own
+ 		spooler and publisher instead of the shared ones, such that the registrar updates
+ 		of the prospector are not delayed by the events of other prospectors
 */
 
 type Crawler struct {
 	prospectors       []*prospector.Prospector
-	wg                sync.WaitGroup
+	pipelines         []Pipeline // independent pipeline per prospector, nil if shared
 	spooler           *spooler.Spooler
 	prospectorConfigs []*common.Config
+	newPipeline       PipelineFactory
 }
 
-func New(spooler *spooler.Spooler, prospectorConfigs []*common.Config) (*Crawler, error) {
+// Pipeline is an independent spooler and publisher used by a single prospector.
+type Pipeline interface {
+	// Channel returns the channel the prospector sends its events to.
+	Channel() chan *input.FileEvent
+
+	// Stop flushes the spooled events and stops the pipeline.
+	Stop()
+}
+
+// PipelineFactory creates and starts an independent pipeline.
+type PipelineFactory func(config cfg.PipelineConfig) (Pipeline, error)
+
+func New(spooler *spooler.Spooler, prospectorConfigs []*common.Config, newPipeline PipelineFactory) (*Crawler, error) {
 
 	if len(prospectorConfigs) == 0 {
 		return nil, fmt.Errorf("No prospectors defined. You must have at least one prospector defined in the config file.")
@@ -39,6 +57,7 @@ func New(spooler *spooler.Spooler, prospectorConfigs []*common.Config) (*Crawler
 	return &Crawler{
 		spooler:           spooler,
 		prospectorConfigs: prospectorConfigs,
+		newPipeline:       newPipeline,
 	}, nil
 }
 
@@ -49,27 +68,36 @@ func (c *Crawler) Start(states file.States) error {
 	// Prospect the globs/paths given on the command line and launch harvesters
 	for _, prospectorConfig := range c.prospectorConfigs {
 
-		prospector, err := prospector.NewProspector(prospectorConfig, states, c.spooler.Channel)
+		pipeline, err := c.createPipeline(prospectorConfig)
 		if err != nil {
+			c.abort()
+			return fmt.Errorf("Error in initing prospector pipeline: %s", err)
+		}
+
+		var spoolerChan chan *input.FileEvent
+		if pipeline != nil {
+			spoolerChan = pipeline.Channel()
+		} else {
+			spoolerChan = c.spooler.Channel
+		}
+
+		prospector, err := prospector.NewProspector(prospectorConfig, states, spoolerChan)
+		if err != nil {
+			if pipeline != nil {
+				pipeline.Stop()
+			}
+			c.abort()
 			return fmt.Errorf("Error in initing prospector: %s", err)
 		}
 		c.prospectors = append(c.prospectors, prospector)
+		c.pipelines = append(c.pipelines, pipeline)
 	}
 
 	logp.Info("Loading Prospectors completed. Number of prospectors: %v", len(c.prospectors))
 
-	c.wg = sync.WaitGroup{}
 	for i, p := range c.prospectors {
-		c.wg.Add(1)
-
-		go func(id int, prospector *prospector.Prospector) {
-			defer func() {
-				c.wg.Done()
-				logp.Debug("crawler", "Prospector %v stopped", id)
-			}()
-			logp.Debug("crawler", "Starting prospector %v", id)
-			prospector.Run()
-		}(i, p)
+		logp.Debug("crawler", "Starting prospector %v", i)
+		p.Start()
 	}
 
 	logp.Info("All prospectors are initialised and running with %d states to persist", states.Count())
@@ -79,17 +107,67 @@ func (c *Crawler) Start(states file.States) error {
 
 func (c *Crawler) Stop() {
 	logp.Info("Stopping Crawler")
-	stopProspector := func(p *prospector.Prospector) {
-		defer c.wg.Done()
+	var wg sync.WaitGroup
+	stopProspector := func(id int, p *prospector.Prospector) {
+		defer wg.Done()
 		p.Stop()
+		logp.Debug("crawler", "Prospector %v stopped", id)
 	}
 
 	logp.Info("Stopping %v prospectors", len(c.prospectors))
-	for _, p := range c.prospectors {
+	for i, p := range c.prospectors {
 		// Stop prospectors in parallel
-		c.wg.Add(1)
-		go stopProspector(p)
+		wg.Add(1)
+		go stopProspector(i, p)
+	}
+	wg.Wait()
+
+	// Stop the independent pipelines after all prospectors are stopped, such
+	// that the remaining events are flushed
+	for _, pipeline := range c.pipelines {
+		if pipeline != nil {
+			pipeline.Stop()
+		}
 	}
-	c.wg.Wait()
 	logp.Info("Crawler stopped")
 }
+
+// abort stops the prospectors and pipelines created by Start before it failed.
+// The prospectors have not been started yet.
+func (c *Crawler) abort() {
+	c.Stop()
+	c.prospectors, c.pipelines = nil, nil
+}
+
+// createPipeline returns the independent pipeline of the prospector, or nil if
+// the prospector uses the shared spooler.
+func (c *Crawler) createPipeline(prospectorConfig *common.Config) (Pipeline, error) {
+	config := struct {
+		Pipeline cfg.PipelineConfig `config:"pipeline"`
+	}{}
+	if err := prospectorConfig.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	if !config.Pipeline.Enabled {
+		return nil, nil
+	}
+	if c.newPipeline == nil {
+		return nil, fmt.Errorf("independent pipelines are not supported")
+	}
+	return c.newPipeline(config.Pipeline)
+}
+
+// Inputs returns information about all prospectors started by the crawler.
+func (c *Crawler) Inputs() []common.MapStr {
+	inputs := make([]common.MapStr, len(c.prospectors))
+	for i, p := range c.prospectors {
+		inputs[i] = p.Info()
+		inputs[i]["id"] = i
+		inputs[i]["pipeline"] = "shared"
+		if c.pipelines[i] != nil {
+			inputs[i]["pipeline"] = "independent"
+		}
+	}
+	return inputs
+}
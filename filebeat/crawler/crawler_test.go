@@ -3,8 +3,14 @@
 package crawler
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	cfg "github.com/elastic/beats/filebeat/config"
+	"github.com/elastic/beats/filebeat/input"
+	"github.com/elastic/beats/filebeat/input/file"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/stretchr/testify/assert"
 )
@@ -12,7 +18,98 @@ import (
 func TestNewCrawlerNoProspectorsError(t *testing.T) {
 	prospectorConfigs := []*common.Config{}
 
-	_, error := New(nil, prospectorConfigs)
+	_, error := New(nil, prospectorConfigs, nil)
 
 	assert.Error(t, error)
 }
+
+type testPipeline struct {
+	channel chan *input.FileEvent
+	stopped bool
+}
+
+func (p *testPipeline) Channel() chan *input.FileEvent { return p.channel }
+func (p *testPipeline) Stop()                          { p.stopped = true }
+
+func TestCrawlerIndependentPipeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"input_type": "log",
+		"paths":      []string{filepath.Join(dir, "*.log")},
+		"pipeline": map[string]interface{}{
+			"enabled":    true,
+			"spool_size": 100,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pipelineConfigs []cfg.PipelineConfig
+	pipeline := &testPipeline{channel: make(chan *input.FileEvent)}
+	newPipeline := func(config cfg.PipelineConfig) (Pipeline, error) {
+		pipelineConfigs = append(pipelineConfigs, config)
+		return pipeline, nil
+	}
+
+	crawler, err := New(nil, []*common.Config{config}, newPipeline)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.NoError(t, crawler.Start(*file.NewStates())) {
+		return
+	}
+
+	assert.Equal(t, []cfg.PipelineConfig{{Enabled: true, SpoolSize: 100}}, pipelineConfigs)
+	assert.Equal(t, "independent", crawler.Inputs()[0]["pipeline"])
+
+	crawler.Stop()
+	assert.True(t, pipeline.stopped)
+}
+
+// If a prospector can not be created, the pipelines and prospectors created
+// before are stopped.
+func TestCrawlerStartFailureStopsPipelines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crawler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var configs []*common.Config
+	for _, inputType := range []string{"log", "invalid"} {
+		config, err := common.NewConfigFrom(map[string]interface{}{
+			"input_type": inputType,
+			"paths":      []string{filepath.Join(dir, "*.log")},
+			"pipeline":   map[string]interface{}{"enabled": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, config)
+	}
+
+	var pipelines []*testPipeline
+	newPipeline := func(config cfg.PipelineConfig) (Pipeline, error) {
+		pipeline := &testPipeline{channel: make(chan *input.FileEvent)}
+		pipelines = append(pipelines, pipeline)
+		return pipeline, nil
+	}
+
+	crawler, err := New(nil, configs, newPipeline)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Error(t, crawler.Start(*file.NewStates()))
+
+	if assert.Len(t, pipelines, 2) {
+		assert.True(t, pipelines[0].stopped)
+		assert.True(t, pipelines[1].stopped)
+	}
+	assert.Empty(t, crawler.Inputs())
+}
@@ -8,6 +8,7 @@ import (
 	cfg "github.com/elastic/beats/filebeat/config"
 	"github.com/elastic/beats/filebeat/harvester/processor"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/multiline"
 
 	"github.com/dustin/go-humanize"
 	"github.com/elastic/beats/libbeat/logp"
@@ -28,29 +29,59 @@ var (
 		CloseRenamed:    false,
 		CloseEOF:        false,
 		ForceCloseFiles: false,
+		OSQuery: OSQueryConfig{
+			Binary: "osqueryd",
+		},
 	}
 )
 
 type harvesterConfig struct {
-	common.EventMetadata `config:",inline"`         // Fields and tags to add to events.
-	BufferSize           int                        `config:"harvester_buffer_size"`
-	DocumentType         string                     `config:"document_type"`
-	Encoding             string                     `config:"encoding"`
-	InputType            string                     `config:"input_type"`
-	TailFiles            bool                       `config:"tail_files"`
-	Backoff              time.Duration              `config:"backoff" validate:"min=0,nonzero"`
-	BackoffFactor        int                        `config:"backoff_factor" validate:"min=1"`
-	MaxBackoff           time.Duration              `config:"max_backoff" validate:"min=0,nonzero"`
-	CloseOlder           time.Duration              `config:"close_older"`
-	CloseRemoved         bool                       `config:"close_removed"`
-	CloseRenamed         bool                       `config:"close_renamed"`
-	CloseEOF             bool                       `config:"close_eof"`
-	ForceCloseFiles      bool                       `config:"force_close_files"`
-	ExcludeLines         []*regexp.Regexp           `config:"exclude_lines"`
-	IncludeLines         []*regexp.Regexp           `config:"include_lines"`
-	MaxBytes             int                        `config:"max_bytes" validate:"min=0,nonzero"`
-	Multiline            *processor.MultilineConfig `config:"multiline"`
-	JSON                 *processor.JSONConfig      `config:"json"`
+	common.EventMetadata `config:",inline"`    // Fields and tags to add to events.
+	BufferSize           int                   `config:"harvester_buffer_size"`
+	DocumentType         string                `config:"document_type"`
+	Encoding             string                `config:"encoding"`
+	InputType            string                `config:"input_type"`
+	TailFiles            bool                  `config:"tail_files"`
+	Backoff              time.Duration         `config:"backoff" validate:"min=0,nonzero"`
+	BackoffFactor        int                   `config:"backoff_factor" validate:"min=1"`
+	MaxBackoff           time.Duration         `config:"max_backoff" validate:"min=0,nonzero"`
+	CloseOlder           time.Duration         `config:"close_older"`
+	CloseRemoved         bool                  `config:"close_removed"`
+	CloseRenamed         bool                  `config:"close_renamed"`
+	CloseEOF             bool                  `config:"close_eof"`
+	ForceCloseFiles      bool                  `config:"force_close_files"`
+	ExcludeLines         []*regexp.Regexp      `config:"exclude_lines"`
+	IncludeLines         []*regexp.Regexp      `config:"include_lines"`
+	MaxBytes             int                   `config:"max_bytes" validate:"min=0,nonzero"`
+	Multiline            *multiline.Config     `config:"multiline"`
+	JSON                 *processor.JSONConfig `config:"json"`
+	OSLog                OSLogConfig           `config:"oslog"`
+	OSQuery              OSQueryConfig         `config:"osquery"`
+}
+
+// OSLogConfig selects the entries streamed by the oslog input type.
+type OSLogConfig struct {
+	Predicate string `config:"predicate"`
+	Level     string `config:"level"`
+}
+
+// OSQueryConfig configures the osqueryd process launched by the osquery input
+// type and the queries it schedules.
+type OSQueryConfig struct {
+	Binary       string         `config:"binary"`
+	DatabasePath string         `config:"database_path"`
+	Flags        []string       `config:"flags"`
+	Queries      []OSQueryQuery `config:"queries"`
+}
+
+// OSQueryQuery is a query scheduled by osqueryd. Unless snapshot is set, only
+// the rows added or removed since the previous run of the query are reported.
+type OSQueryQuery struct {
+	Name     string        `config:"name"`
+	Query    string        `config:"query"`
+	Interval time.Duration `config:"interval"`
+	Snapshot bool          `config:"snapshot"`
+	Removed  *bool         `config:"removed"`
 }
 
 func (config *harvesterConfig) Validate() error {
@@ -67,6 +98,42 @@ func (config *harvesterConfig) Validate() error {
 		return fmt.Errorf("Invalid input type: %v", config.InputType)
 	}
 
+	if config.InputType == cfg.OSLogInputType {
+		if config.JSON != nil {
+			return fmt.Errorf("The JSON decoder can not be used with input type %v", config.InputType)
+		}
+
+		switch config.OSLog.Level {
+		case "", "default", "info", "debug":
+		default:
+			return fmt.Errorf("Invalid oslog level: %v", config.OSLog.Level)
+		}
+	}
+
+	if config.InputType == cfg.OSQueryInputType {
+		if config.JSON != nil {
+			return fmt.Errorf("The JSON decoder can not be used with input type %v", config.InputType)
+		}
+
+		if len(config.OSQuery.Queries) == 0 {
+			return fmt.Errorf("At least one osquery query must be configured")
+		}
+
+		names := map[string]bool{}
+		for _, q := range config.OSQuery.Queries {
+			if q.Name == "" || q.Query == "" {
+				return fmt.Errorf("Each osquery query requires a name and a query")
+			}
+			if names[q.Name] {
+				return fmt.Errorf("Duplicate osquery query name: %v", q.Name)
+			}
+			names[q.Name] = true
+			if q.Interval < time.Second {
+				return fmt.Errorf("Interval of osquery query %v must be at least 1s", q.Name)
+			}
+		}
+	}
+
 	if config.JSON != nil && len(config.JSON.MessageKey) == 0 &&
 		config.Multiline != nil {
 		return fmt.Errorf("When using the JSON decoder and multiline together, you need to specify a message_key value")
@@ -2,7 +2,9 @@ package harvester
 
 import (
 	"testing"
+	"time"
 
+	cfg "github.com/elastic/beats/filebeat/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,3 +22,23 @@ func TestForceCloseFiles(t *testing.T) {
 	assert.True(t, config.CloseRemoved)
 	assert.True(t, config.CloseRenamed)
 }
+
+func TestOSQueryConfigValidate(t *testing.T) {
+
+	config := defaultConfig
+	config.InputType = cfg.OSQueryInputType
+	assert.Error(t, config.Validate())
+
+	config.OSQuery.Queries = []OSQueryQuery{
+		{Name: "ports", Query: "SELECT * FROM listening_ports", Interval: time.Minute},
+	}
+	assert.NoError(t, config.Validate())
+
+	config.OSQuery.Queries = append(config.OSQuery.Queries, config.OSQuery.Queries[0])
+	assert.Error(t, config.Validate())
+
+	config.OSQuery.Queries = []OSQueryQuery{
+		{Name: "ports", Query: "SELECT * FROM listening_ports", Interval: 0},
+	}
+	assert.Error(t, config.Validate())
+}
@@ -1,15 +1,22 @@
 /*
   The harvester package harvest different inputs for new information. Currently
-  two harvester types exist:
+  four harvester types exist:
 
    * log
    * stdin
+   * oslog
+   * osquery
 
   The log harvester reads a file line by line. In case the end of a file is found
   with an incomplete line, the line pointer stays at the beginning of the incomplete
   line. As soon as the line is completed, it is read and returned.
 
   The stdin harvesters reads data from stdin.
+
+  The oslog harvester streams the macOS unified logging system.
+
+  The osquery harvester launches osqueryd and reads the results of the
+  scheduled queries.
 */
 package harvester
 
@@ -19,6 +26,7 @@ import (
 
 	"github.com/elastic/beats/filebeat/config"
 	"github.com/elastic/beats/filebeat/harvester/encoding"
+	"github.com/elastic/beats/filebeat/harvester/processor"
 	"github.com/elastic/beats/filebeat/harvester/source"
 	"github.com/elastic/beats/filebeat/input"
 	"github.com/elastic/beats/filebeat/input/file"
@@ -63,6 +71,16 @@ func NewHarvester(
 		return nil, err
 	}
 
+	// oslog entries and osquery results are decoded into fields which are
+	// merged like JSON keys
+	if h.config.InputType == config.OSLogInputType || h.config.InputType == config.OSQueryInputType {
+		h.config.JSON = &processor.JSONConfig{
+			MessageKey:    "message",
+			KeysUnderRoot: true,
+			OverwriteKeys: true,
+		}
+	}
+
 	encoding, ok := encoding.FindEncoding(h.config.Encoding)
 	if !ok || encoding == nil {
 		return nil, fmt.Errorf("unknown encoding('%v')", h.config.Encoding)
@@ -82,6 +100,10 @@ func (h *Harvester) open() (encoding.Encoding, error) {
 		return h.openStdin()
 	case config.LogInputType:
 		return h.openFile()
+	case config.OSLogInputType:
+		return h.openOSLog()
+	case config.OSQueryInputType:
+		return h.openOSQuery()
 	default:
 		return nil, fmt.Errorf("Invalid input type")
 	}
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/beats/filebeat/harvester/source"
 	"github.com/elastic/beats/filebeat/input"
 	"github.com/elastic/beats/filebeat/input/file"
+	"github.com/elastic/beats/libbeat/common/multiline"
 	"github.com/elastic/beats/libbeat/logp"
 )
 
@@ -50,7 +51,7 @@ func (h *Harvester) Harvest() {
 
 	processor, err := createLineProcessor(
 		h.file, enc, cfg.BufferSize, cfg.MaxBytes, readerConfig,
-		cfg.JSON, cfg.Multiline, h.done)
+		cfg.InputType, cfg.JSON, cfg.Multiline, h.done)
 	if err != nil {
 		logp.Err("Stop Harvesting. Unexpected encoding line reader error: %s", err)
 		return
@@ -256,7 +257,8 @@ func (h *Harvester) sendStateUpdate() bool {
 
 func (h *Harvester) getState() file.State {
 
-	if h.config.InputType == config.StdinInputType {
+	switch h.config.InputType {
+	case config.StdinInputType, config.OSLogInputType, config.OSQueryInputType:
 		return file.State{}
 	}
 
@@ -296,8 +298,9 @@ func createLineProcessor(
 	bufferSize int,
 	maxBytes int,
 	readerConfig reader.LogFileReaderConfig,
+	inputType string,
 	jsonConfig *processor.JSONConfig,
-	mlrConfig *processor.MultilineConfig,
+	mlrConfig *multiline.Config,
 	done chan struct{},
 ) (processor.LineProcessor, error) {
 	var p processor.LineProcessor
@@ -313,7 +316,12 @@ func createLineProcessor(
 		return nil, err
 	}
 
-	if jsonConfig != nil {
+	switch {
+	case inputType == config.OSLogInputType:
+		p = processor.NewOSLogProcessor(p)
+	case inputType == config.OSQueryInputType:
+		p = processor.NewOSQueryProcessor(p)
+	case jsonConfig != nil:
 		p = processor.NewJSONProcessor(p, jsonConfig)
 	}
 
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/elastic/beats/filebeat/config"
 	"github.com/elastic/beats/filebeat/harvester/encoding"
 	"github.com/elastic/beats/filebeat/harvester/reader"
 	"github.com/elastic/beats/filebeat/harvester/source"
@@ -66,7 +67,7 @@ func TestReadLine(t *testing.T) {
 		MaxBackoffDuration: 1 * time.Second,
 		BackoffFactor:      2,
 	}
-	r, _ := createLineProcessor(source.File{readFile}, codec, 100, 1000, readConfig, nil, nil, nil)
+	r, _ := createLineProcessor(source.File{readFile}, codec, 100, 1000, readConfig, config.LogInputType, nil, nil, nil)
 
 	// Read third line
 	_, text, bytesread, _, err := readLine(r)
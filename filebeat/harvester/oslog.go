@@ -0,0 +1,42 @@
+package harvester
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/elastic/beats/filebeat/harvester/source"
+	"github.com/elastic/beats/libbeat/logp"
+	"golang.org/x/text/encoding"
+)
+
+// OSLog streams the macOS unified logging system using the log command line
+// tool and sends the entries directly to the output
+
+func (h *Harvester) openOSLog() (encoding.Encoding, error) {
+	if runtime.GOOS != "darwin" {
+		return nil, fmt.Errorf("input_type %s is only supported on macOS", h.config.InputType)
+	}
+
+	args := []string{"stream", "--style", "ndjson"}
+	if h.config.OSLog.Level != "" {
+		args = append(args, "--level", h.config.OSLog.Level)
+	}
+	if h.config.OSLog.Predicate != "" {
+		args = append(args, "--predicate", h.config.OSLog.Predicate)
+	}
+
+	cmd, err := source.NewCommand("/usr/bin/log", args...)
+	if err != nil {
+		return nil, err
+	}
+	logp.Debug("harvester", "Started oslog stream: %s", cmd.Name())
+
+	// log stream never returns EOF, the process is killed to unblock the reader
+	go func() {
+		<-h.done
+		cmd.Close()
+	}()
+
+	h.file = cmd
+	return h.encoding(h.file)
+}
@@ -0,0 +1,89 @@
+package harvester
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/filebeat/harvester/source"
+	"github.com/elastic/beats/libbeat/logp"
+	"golang.org/x/text/encoding"
+)
+
+// OSQuery launches osqueryd with the configured queries scheduled and reads the
+// query results logged to stdout
+
+type osqueryQuery struct {
+	Query    string `json:"query"`
+	Interval int64  `json:"interval"`
+	Snapshot bool   `json:"snapshot,omitempty"`
+	Removed  bool   `json:"removed"`
+}
+
+func (h *Harvester) openOSQuery() (encoding.Encoding, error) {
+	dir, err := ioutil.TempDir("", "filebeat-osquery")
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, err := h.startOSQuery(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	logp.Debug("harvester", "Started osqueryd: %s", cmd.Name())
+
+	// osqueryd never returns EOF, the process is killed to unblock the reader
+	go func() {
+		<-h.done
+		cmd.Close()
+		os.RemoveAll(dir)
+	}()
+
+	h.file = cmd
+	return h.encoding(h.file)
+}
+
+// startOSQuery writes the osquery configuration to dir and starts osqueryd.
+func (h *Harvester) startOSQuery(dir string) (*source.Command, error) {
+	config := h.config.OSQuery
+
+	schedule := map[string]osqueryQuery{}
+	for _, q := range config.Queries {
+		schedule[q.Name] = osqueryQuery{
+			Query:    q.Query,
+			Interval: int64(q.Interval.Seconds()),
+			Snapshot: q.Snapshot,
+			Removed:  q.Removed == nil || *q.Removed,
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"schedule": schedule})
+	if err != nil {
+		return nil, err
+	}
+	configPath := filepath.Join(dir, "osquery.conf")
+	if err := ioutil.WriteFile(configPath, data, 0600); err != nil {
+		return nil, err
+	}
+
+	databasePath := config.DatabasePath
+	if databasePath == "" {
+		databasePath = filepath.Join(dir, "osquery.db")
+	}
+
+	args := []string{
+		"--config_path=" + configPath,
+		"--database_path=" + databasePath,
+		"--pidfile=" + filepath.Join(dir, "osqueryd.pid"),
+		"--extensions_socket=" + filepath.Join(dir, "osquery.em"),
+		"--disable_extensions",
+		"--logger_plugin=stdout",
+		"--logger_snapshot_event_type",
+		"--utc",
+	}
+	args = append(args, config.Flags...)
+
+	return source.NewCommand(config.Binary, args...)
+}
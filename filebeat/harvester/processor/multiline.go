@@ -2,11 +2,10 @@ package processor
 
 import (
 	"errors"
-	"fmt"
-	"regexp"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/multiline"
 )
 
 // MultiLine processor combining multiple line events into one multi-line event.
@@ -22,7 +21,7 @@ import (
 // multiline event first and finally return the actual error on next call to Next.
 type MultiLine struct {
 	reader    LineProcessor
-	pred      matcher
+	pred      multiline.Matcher
 	maxBytes  int // bytes stored in content
 	maxLines  int
 	separator []byte
@@ -38,18 +37,6 @@ type MultiLine struct {
 	state func(*MultiLine) (Line, error)
 }
 
-const (
-	// Default maximum number of lines to return in one multi-line event
-	defaultMaxLines = 500
-
-	// Default timeout to finish a multi-line event.
-	defaultMultilineTimeout = 5 * time.Second
-)
-
-// Matcher represents the predicate comparing any two lines
-// to find start and end of multiline events in stream of line events.
-type matcher func(last, current []byte) bool
-
 var (
 	errMultilineTimeout = errors.New("multline timeout")
 )
@@ -60,38 +47,17 @@ func NewMultiline(
 	r LineProcessor,
 	separator string,
 	maxBytes int,
-	config *MultilineConfig,
+	config *multiline.Config,
 ) (*MultiLine, error) {
-	types := map[string]func(*regexp.Regexp) (matcher, error){
-		"before": beforeMatcher,
-		"after":  afterMatcher,
-	}
-
-	matcherType, ok := types[config.Match]
-	if !ok {
-		return nil, fmt.Errorf("unknown matcher type: %s", config.Match)
-	}
-
-	matcher, err := matcherType(config.Pattern)
+	matcher, err := multiline.NewMatcher(config)
 	if err != nil {
 		return nil, err
 	}
 
-	if config.Negate {
-		matcher = negatedMatcher(matcher)
-	}
-
-	maxLines := defaultMaxLines
-	if config.MaxLines != nil {
-		maxLines = *config.MaxLines
-	}
-
-	timeout := defaultMultilineTimeout
-	if config.Timeout != nil {
-		timeout = *config.Timeout
-		if timeout < 0 {
-			return nil, fmt.Errorf("timeout %v must not be negative", config.Timeout)
-		}
+	maxLines := config.GetMaxLines()
+	timeout, err := config.GetTimeout()
+	if err != nil {
+		return nil, err
 	}
 
 	if timeout > 0 {
@@ -266,34 +232,3 @@ func (mlr *MultiLine) addLine(l Line) {
 func (mlr *MultiLine) reset() {
 	mlr.state = (*MultiLine).readFirst
 }
-
-// matchers
-
-func afterMatcher(regex *regexp.Regexp) (matcher, error) {
-	return genPatternMatcher(regex, func(last, current []byte) []byte {
-		return current
-	})
-}
-
-func beforeMatcher(regex *regexp.Regexp) (matcher, error) {
-	return genPatternMatcher(regex, func(last, current []byte) []byte {
-		return last
-	})
-}
-
-func negatedMatcher(m matcher) matcher {
-	return func(last, current []byte) bool {
-		return !m(last, current)
-	}
-}
-
-func genPatternMatcher(
-	regex *regexp.Regexp,
-	sel func(last, current []byte) []byte,
-) (matcher, error) {
-	matcher := func(last, current []byte) bool {
-		line := sel(last, current)
-		return regex.Match(line)
-	}
-	return matcher, nil
-}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/elastic/beats/filebeat/harvester/encoding"
+	"github.com/elastic/beats/libbeat/common/multiline"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,7 +26,7 @@ func (p bufferSource) Continuable() bool          { return false }
 
 func TestMultilineAfterOK(t *testing.T) {
 	testMultilineOK(t,
-		MultilineConfig{
+		multiline.Config{
 			Pattern: regexp.MustCompile(`^[ \t] +`), // next line is indented by spaces
 			Match:   "after",
 		},
@@ -36,7 +37,7 @@ func TestMultilineAfterOK(t *testing.T) {
 
 func TestMultilineBeforeOK(t *testing.T) {
 	testMultilineOK(t,
-		MultilineConfig{
+		multiline.Config{
 			Pattern: regexp.MustCompile(`\\$`), // previous line ends with \
 			Match:   "before",
 		},
@@ -47,7 +48,7 @@ func TestMultilineBeforeOK(t *testing.T) {
 
 func TestMultilineAfterNegateOK(t *testing.T) {
 	testMultilineOK(t,
-		MultilineConfig{
+		multiline.Config{
 			Pattern: regexp.MustCompile(`^-`), // first line starts with '-' at beginning of line
 			Negate:  true,
 			Match:   "after",
@@ -59,7 +60,7 @@ func TestMultilineAfterNegateOK(t *testing.T) {
 
 func TestMultilineBeforeNegateOK(t *testing.T) {
 	testMultilineOK(t,
-		MultilineConfig{
+		multiline.Config{
 			Pattern: regexp.MustCompile(`;$`), // last line ends with ';'
 			Negate:  true,
 			Match:   "before",
@@ -69,7 +70,7 @@ func TestMultilineBeforeNegateOK(t *testing.T) {
 	)
 }
 
-func testMultilineOK(t *testing.T, cfg MultilineConfig, expected ...string) {
+func testMultilineOK(t *testing.T, cfg multiline.Config, expected ...string) {
 	_, buf := createLineBuffer(expected...)
 	reader := createMultilineTestReader(t, buf, cfg)
 
@@ -95,7 +96,7 @@ func testMultilineOK(t *testing.T, cfg MultilineConfig, expected ...string) {
 	}
 }
 
-func createMultilineTestReader(t *testing.T, in *bytes.Buffer, cfg MultilineConfig) LineProcessor {
+func createMultilineTestReader(t *testing.T, in *bytes.Buffer, cfg multiline.Config) LineProcessor {
 	encFactory, ok := encoding.FindEncoding("plain")
 	if !ok {
 		t.Fatalf("unable to find 'plain' encoding")
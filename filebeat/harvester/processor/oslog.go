@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// osLogTimeLayout is the timestamp format used by `log stream --style ndjson`.
+const osLogTimeLayout = "2006-01-02 15:04:05.000000-0700"
+
+// OSLogProcessor decodes the entries of the macOS unified log as written by
+// `log stream --style ndjson`.
+type OSLogProcessor struct {
+	reader LineProcessor
+}
+
+// osLogEntry contains the keys of an ndjson entry that are exported.
+type osLogEntry struct {
+	EventMessage       string `json:"eventMessage"`
+	Timestamp          string `json:"timestamp"`
+	Subsystem          string `json:"subsystem"`
+	Category           string `json:"category"`
+	ProcessImagePath   string `json:"processImagePath"`
+	ProcessID          int    `json:"processID"`
+	ThreadID           int    `json:"threadID"`
+	MessageType        string `json:"messageType"`
+	EventType          string `json:"eventType"`
+	SenderImagePath    string `json:"senderImagePath"`
+	ActivityIdentifier uint64 `json:"activityIdentifier"`
+}
+
+// NewOSLogProcessor creates a new processor that decodes unified log entries.
+func NewOSLogProcessor(in LineProcessor) *OSLogProcessor {
+	return &OSLogProcessor{reader: in}
+}
+
+// Next returns the next log entry. The message is returned as content, all
+// other keys are returned as fields.
+func (p *OSLogProcessor) Next() (Line, error) {
+	for {
+		line, err := p.reader.Next()
+		if err != nil {
+			return line, err
+		}
+
+		// log stream prints a header before the first entry
+		if !bytes.HasPrefix(bytes.TrimSpace(line.Content), []byte("{")) {
+			continue
+		}
+
+		var entry osLogEntry
+		if err := json.Unmarshal(line.Content, &entry); err != nil {
+			logp.Err("Error decoding oslog entry: %v", err)
+			continue
+		}
+
+		line.Content = []byte(entry.EventMessage)
+		line.Fields = entry.toMapStr()
+		return line, nil
+	}
+}
+
+func (e *osLogEntry) toMapStr() common.MapStr {
+	fields := common.MapStr{
+		"oslog": common.MapStr{
+			"subsystem":    e.Subsystem,
+			"category":     e.Category,
+			"process":      filepath.Base(e.ProcessImagePath),
+			"process_path": e.ProcessImagePath,
+			"pid":          e.ProcessID,
+			"thread_id":    e.ThreadID,
+			"level":        strings.ToLower(e.MessageType),
+			"event_type":   e.EventType,
+			"sender":       e.SenderImagePath,
+			"activity_id":  e.ActivityIdentifier,
+		},
+	}
+
+	ts, err := time.Parse(osLogTimeLayout, e.Timestamp)
+	if err != nil {
+		logp.Err("Error parsing oslog timestamp '%s': %v", e.Timestamp, err)
+	} else {
+		fields["@timestamp"] = ts.UTC().Format(time.RFC3339Nano)
+	}
+
+	return fields
+}
@@ -0,0 +1,58 @@
+// +build !integration
+
+package processor
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type linesSource struct{ lines []string }
+
+func (s *linesSource) Next() (Line, error) {
+	if len(s.lines) == 0 {
+		return Line{}, io.EOF
+	}
+	l := s.lines[0]
+	s.lines = s.lines[1:]
+	return Line{Ts: time.Now(), Content: []byte(l), Bytes: len(l)}, nil
+}
+
+func TestOSLogProcessor(t *testing.T) {
+	in := &linesSource{lines: []string{
+		"Filtering the log data using \"subsystem == \\\"com.apple.sharing\\\"\"\n",
+		`{"traceID":4,"eventMessage":"Scanning started","eventType":"logEvent",` +
+			`"timestamp":"2016-11-15 10:21:33.123456+0100","subsystem":"com.apple.sharing",` +
+			`"category":"AirDrop","processImagePath":"\/usr\/libexec\/sharingd","processID":342,` +
+			`"threadID":5712,"messageType":"Info","senderImagePath":"\/usr\/libexec\/sharingd",` +
+			`"activityIdentifier":0}` + "\n",
+	}}
+
+	p := NewOSLogProcessor(in)
+	line, err := p.Next()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "Scanning started", string(line.Content))
+	assert.Equal(t, "2016-11-15T09:21:33.123456Z", line.Fields["@timestamp"])
+	assert.Equal(t, common.MapStr{
+		"subsystem":    "com.apple.sharing",
+		"category":     "AirDrop",
+		"process":      "sharingd",
+		"process_path": "/usr/libexec/sharingd",
+		"pid":          342,
+		"thread_id":    5712,
+		"level":        "info",
+		"event_type":   "logEvent",
+		"sender":       "/usr/libexec/sharingd",
+		"activity_id":  uint64(0),
+	}, line.Fields["oslog"])
+
+	_, err = p.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// OSQueryProcessor decodes the query results written by osqueryd with the
+// stdout logger plugin. Differential results contain one row per line with
+// the action set to added or removed, snapshot results contain all rows in
+// snapshot.
+type OSQueryProcessor struct {
+	reader LineProcessor
+}
+
+// osqueryResult contains the keys of a result line that are exported.
+type osqueryResult struct {
+	Name           string                 `json:"name"`
+	HostIdentifier string                 `json:"hostIdentifier"`
+	UnixTime       json.Number            `json:"unixTime"`
+	Epoch          json.Number            `json:"epoch"`
+	Counter        json.Number            `json:"counter"`
+	Action         string                 `json:"action"`
+	Columns        map[string]interface{} `json:"columns"`
+	Snapshot       []interface{}          `json:"snapshot"`
+	Decorations    map[string]interface{} `json:"decorations"`
+}
+
+// NewOSQueryProcessor creates a new processor that decodes osquery results.
+func NewOSQueryProcessor(in LineProcessor) *OSQueryProcessor {
+	return &OSQueryProcessor{reader: in}
+}
+
+// Next returns the next query result. The raw result is returned as content,
+// the decoded result is returned as fields.
+func (p *OSQueryProcessor) Next() (Line, error) {
+	for {
+		line, err := p.reader.Next()
+		if err != nil {
+			return line, err
+		}
+
+		// osqueryd writes status logs to stdout as well, which are not JSON
+		content := bytes.TrimSpace(line.Content)
+		if !bytes.HasPrefix(content, []byte("{")) {
+			continue
+		}
+
+		var result osqueryResult
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		if err := dec.Decode(&result); err != nil {
+			logp.Err("Error decoding osquery result: %v", err)
+			continue
+		}
+
+		if result.Name == "" || result.Action == "" {
+			continue
+		}
+
+		line.Content = content
+		line.Fields = result.toMapStr()
+		return line, nil
+	}
+}
+
+func (r *osqueryResult) toMapStr() common.MapStr {
+	osquery := common.MapStr{
+		"name":            r.Name,
+		"action":          r.Action,
+		"host_identifier": r.HostIdentifier,
+	}
+	if epoch, err := r.Epoch.Int64(); err == nil {
+		osquery["epoch"] = epoch
+	}
+	if counter, err := r.Counter.Int64(); err == nil {
+		osquery["counter"] = counter
+	}
+	if r.Columns != nil {
+		osquery["columns"] = r.Columns
+	}
+	if r.Snapshot != nil {
+		osquery["snapshot"] = r.Snapshot
+	}
+	if r.Decorations != nil {
+		osquery["decorations"] = r.Decorations
+	}
+
+	fields := common.MapStr{"osquery": osquery}
+
+	unixTime, err := r.UnixTime.Int64()
+	if err != nil {
+		logp.Err("Error parsing osquery unixTime '%s': %v", r.UnixTime, err)
+	} else {
+		fields["@timestamp"] = time.Unix(unixTime, 0).UTC().Format(time.RFC3339Nano)
+	}
+
+	return fields
+}
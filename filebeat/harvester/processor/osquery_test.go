@@ -0,0 +1,55 @@
+// +build !integration
+
+package processor
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSQueryProcessor(t *testing.T) {
+	in := &linesSource{lines: []string{
+		"I1115 10:21:33.123456  1234 init.cpp:380] osquery initialized [version=2.0.0]\n",
+		`{"s":0,"f":"events.cpp","i":825,"m":"Event publisher failed setup"}` + "\n",
+		`{"name":"listening_ports","hostIdentifier":"host1","calendarTime":"Tue Nov 15 09:21:33 2016 UTC",` +
+			`"unixTime":"1479201693","epoch":0,"counter":1,"decorations":{"hostname":"host1"},` +
+			`"columns":{"pid":"342","port":"22"},"action":"added"}` + "\n",
+		`{"name":"processes","hostIdentifier":"host1","unixTime":1479201694,` +
+			`"snapshot":[{"pid":"1"},{"pid":"2"}],"action":"snapshot"}` + "\n",
+	}}
+
+	p := NewOSQueryProcessor(in)
+	line, err := p.Next()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "2016-11-15T09:21:33Z", line.Fields["@timestamp"])
+	assert.Equal(t, common.MapStr{
+		"name":            "listening_ports",
+		"action":          "added",
+		"host_identifier": "host1",
+		"epoch":           int64(0),
+		"counter":         int64(1),
+		"columns":         map[string]interface{}{"pid": "342", "port": "22"},
+		"decorations":     map[string]interface{}{"hostname": "host1"},
+	}, line.Fields["osquery"])
+	assert.True(t, json.Valid(line.Content))
+
+	line, err = p.Next()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "2016-11-15T09:21:34Z", line.Fields["@timestamp"])
+	osquery := line.Fields["osquery"].(common.MapStr)
+	assert.Equal(t, "snapshot", osquery["action"])
+	assert.Len(t, osquery["snapshot"], 2)
+
+	_, err = p.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,49 @@
+package source
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Command reads the standard output of a process. Closing the source kills
+// the process.
+type Command struct {
+	Pipe
+	cmd  *exec.Cmd
+	once sync.Once
+}
+
+// NewCommand starts the named program with the given arguments and returns a
+// source reading from its standard output.
+func NewCommand(name string, args ...string) (*Command, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = w
+	err = cmd.Start()
+	// The write end is owned by the child process now
+	w.Close()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return &Command{Pipe: Pipe{r}, cmd: cmd}, nil
+}
+
+func (c *Command) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		err = c.Pipe.Close()
+	})
+	return err
+}
+
+func (c *Command) Name() string { return strings.Join(c.cmd.Args, " ") }
@@ -42,6 +42,9 @@ func (f *FileEvent) ToMapStr() common.MapStr {
 		"offset":                f.Offset, // Offset here is the offset before the starting char.
 		"type":                  f.DocumentType,
 		"input_type":            f.InputType,
+		common.MetadataKey: common.MapStr{
+			common.MetadataInputKey: f.InputType,
+		},
 	}
 
 	if f.JSONConfig != nil && len(f.JSONFields) > 0 {
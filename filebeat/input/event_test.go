@@ -15,6 +15,10 @@ func TestFileEventToMapStr(t *testing.T) {
 	mapStr := event.ToMapStr()
 	_, found := mapStr["fields"]
 	assert.False(t, found)
+
+	event = FileEvent{InputType: "log"}
+	mapStr = event.ToMapStr()
+	assert.Equal(t, common.MapStr{"input": "log"}, mapStr[common.MetadataKey])
 }
 
 func TestFileEventToMapStrJSON(t *testing.T) {
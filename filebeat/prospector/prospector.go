@@ -2,6 +2,7 @@ package prospector
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,6 +23,9 @@ type Prospector struct {
 	done          chan struct{}
 	states        *file.States
 	wg            sync.WaitGroup
+
+	harvestersMutex sync.Mutex
+	harvesters      map[string]int // number of running harvesters per source
 }
 
 type Prospectorer interface {
@@ -38,6 +42,7 @@ func NewProspector(cfg *common.Config, states file.States, spoolerChan chan *inp
 		done:          make(chan struct{}),
 		states:        states.Copy(),
 		wg:            sync.WaitGroup{},
+		harvesters:    map[string]int{},
 	}
 
 	if err := cfg.Unpack(&prospector.config); err != nil {
@@ -68,6 +73,10 @@ func (p *Prospector) Init() error {
 		prospectorer, err = NewProspectorStdin(p)
 	case cfg.LogInputType:
 		prospectorer, err = NewProspectorLog(p)
+	case cfg.OSLogInputType:
+		prospectorer, err = NewProspectorOSLog(p)
+	case cfg.OSQueryInputType:
+		prospectorer, err = NewProspectorOSQuery(p)
 	default:
 		return fmt.Errorf("Invalid input type: %v", p.config.InputType)
 	}
@@ -135,6 +144,17 @@ func (p *Prospector) Run() {
 	}
 }
 
+// Start runs the prospector in the background until it is stopped. Unlike
+// calling Run in a goroutine, Start registers the prospector with the wait
+// group before returning, such that Stop always waits for Run to return.
+func (p *Prospector) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.Run()
+	}()
+}
+
 func (p *Prospector) Stop() {
 	logp.Info("Stopping Prospector")
 	close(p.done)
@@ -164,12 +184,48 @@ func (p *Prospector) startHarvester(state file.State, offset int64) (*harvester.
 		return nil, err
 	}
 
+	p.harvesterStarted(state.Source)
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
+		defer p.harvesterStopped(state.Source)
 		// Starts harvester and picks the right type. In case type is not set, set it to defeault (log)
 		h.Harvest()
 	}()
 
 	return h, nil
 }
+
+func (p *Prospector) harvesterStarted(source string) {
+	p.harvestersMutex.Lock()
+	defer p.harvestersMutex.Unlock()
+	p.harvesters[source]++
+}
+
+func (p *Prospector) harvesterStopped(source string) {
+	p.harvestersMutex.Lock()
+	defer p.harvestersMutex.Unlock()
+	p.harvesters[source]--
+	if p.harvesters[source] <= 0 {
+		delete(p.harvesters, source)
+	}
+}
+
+// Info returns the type and paths of the prospector and the sources of the
+// running harvesters.
+func (p *Prospector) Info() common.MapStr {
+	p.harvestersMutex.Lock()
+	defer p.harvestersMutex.Unlock()
+
+	sources := make([]string, 0, len(p.harvesters))
+	for source := range p.harvesters {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	return common.MapStr{
+		"type":       p.config.InputType,
+		"paths":      p.config.Paths,
+		"harvesters": sources,
+	}
+}
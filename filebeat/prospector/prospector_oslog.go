@@ -0,0 +1,42 @@
+package prospector
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/filebeat/harvester"
+	"github.com/elastic/beats/filebeat/input/file"
+)
+
+type ProspectorOSLog struct {
+	harvester *harvester.Harvester
+	started   bool
+}
+
+// NewProspectorOSLog creates a new oslog prospector
+// This prospector contains one harvester which is streaming the macOS unified log
+func NewProspectorOSLog(p *Prospector) (*ProspectorOSLog, error) {
+
+	prospectorer := &ProspectorOSLog{}
+
+	var err error
+
+	prospectorer.harvester, err = p.createHarvester(file.State{Source: "oslog"})
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing oslog harvester: %v", err)
+	}
+
+	return prospectorer, nil
+}
+
+func (p *ProspectorOSLog) Init() {
+	p.started = false
+}
+
+func (p *ProspectorOSLog) Run() {
+
+	// Make sure oslog harvester is only started once
+	if !p.started {
+		go p.harvester.Harvest()
+		p.started = true
+	}
+}
@@ -0,0 +1,42 @@
+package prospector
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/filebeat/harvester"
+	"github.com/elastic/beats/filebeat/input/file"
+)
+
+type ProspectorOSQuery struct {
+	harvester *harvester.Harvester
+	started   bool
+}
+
+// NewProspectorOSQuery creates a new osquery prospector
+// This prospector contains one harvester which is reading the results of osqueryd
+func NewProspectorOSQuery(p *Prospector) (*ProspectorOSQuery, error) {
+
+	prospectorer := &ProspectorOSQuery{}
+
+	var err error
+
+	prospectorer.harvester, err = p.createHarvester(file.State{Source: "osquery"})
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing osquery harvester: %v", err)
+	}
+
+	return prospectorer, nil
+}
+
+func (p *ProspectorOSQuery) Init() {
+	p.started = false
+}
+
+func (p *ProspectorOSQuery) Run() {
+
+	// Make sure osquery harvester is only started once
+	if !p.started {
+		go p.harvester.Harvest()
+		p.started = true
+	}
+}
@@ -200,8 +200,9 @@ func (r *Registrar) processEventStates(events []*FileEvent) {
 	// Take the last event found for each file source
 	for _, event := range events {
 
-		// skip stdin
-		if event.InputType == cfg.StdinInputType {
+		// skip stdin, oslog and osquery, which have no file state
+		switch event.InputType {
+		case cfg.StdinInputType, cfg.OSLogInputType, cfg.OSQueryInputType:
 			continue
 		}
 		r.states.Update(event.State)
@@ -39,16 +39,24 @@ import (
 	"math/rand"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/elastic/beats/libbeat/cfgfile"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/control"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/feature"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/paths"
 	"github.com/elastic/beats/libbeat/processors"
 	_ "github.com/elastic/beats/libbeat/processors/actions"
+	_ "github.com/elastic/beats/libbeat/processors/cisco"
+	_ "github.com/elastic/beats/libbeat/processors/eslookup"
+	_ "github.com/elastic/beats/libbeat/processors/grok"
 	"github.com/elastic/beats/libbeat/publisher"
 	svc "github.com/elastic/beats/libbeat/service"
+	"github.com/elastic/beats/libbeat/statestore"
 	"github.com/satori/go.uuid"
 )
 
@@ -97,8 +105,33 @@ type Beat struct {
 	RawConfig *common.Config       // Raw config that can be unpacked to get Beat specific config data.
 	Config    BeatConfig           // Common Beat configuration data.
 	Publisher *publisher.Publisher // Publisher
+	States    *statestore.Registry // Durable state of inputs and modules.
 
 	processors *processors.Processors // Processors
+	index      string                 // index of the events, see Connect
+}
+
+// Connect connects a new client to the publisher. The events of Beaters run
+// by a Supervisor are published to the index of their section.
+func (b *Beat) Connect() publisher.Client {
+	return b.Publisher.ConnectIndex(b.index)
+}
+
+// Reloader is an interface that can optionally be implemented by a Beat to
+// support reloading its configuration at runtime, triggered via the control
+// socket. Reload is invoked with the configuration read from the
+// configuration files. Logging settings are reloaded by libbeat.
+type Reloader interface {
+	Reload(*common.Config) error
+}
+
+// ConfigSchema is an interface that can optionally be implemented by a Beat
+// to enable the detection of unknown settings in the Beat specific
+// configuration. ConfigSchema returns the (zero) value of the struct the
+// configuration is unpacked into. Without it, only the settings shared by all
+// Beats are checked.
+type ConfigSchema interface {
+	ConfigSchema() interface{}
 }
 
 // BeatConfig struct contains the basic configuration of every beat
@@ -108,6 +141,11 @@ type BeatConfig struct {
 	Logging    logp.Logging              `config:"logging"`
 	Processors processors.PluginConfig   `config:"processors"`
 	Path       paths.Path                `config:"path"`
+	Control    control.Config            `config:"control"`
+	HTTP       control.HTTPConfig        `config:"http"`
+	Vars       map[string]interface{}    `config:"vars"`
+	StateStore statestore.Config         `config:"state_store"`
+	Features   feature.Config            `config:"features"`
 }
 
 // Run initializes and runs a Beater implementation. name is the name of the
@@ -119,8 +157,10 @@ func Run(name, version string, bt Beater) error {
 
 // instance contains everything related to a single instance of a beat.
 type instance struct {
-	data   *Beat
-	beater Beater
+	data    *Beat
+	beater  Beater
+	control *control.Server
+	http    *control.Server
 }
 
 func init() {
@@ -183,15 +223,35 @@ func (bc *instance) handleFlags() error {
 // defined in BeatConfig, initializes logging, and set GOMAXPROCS if defined
 // in the config. Lastly it invokes the Config method implemented by the beat.
 func (bc *instance) config() error {
+	if err := bc.loadConfig(); err != nil {
+		return err
+	}
+	return bc.beater.Config(bc.data)
+}
+
+// loadConfig reads the configuration file from disk and initializes the
+// settings shared by all Beats.
+func (bc *instance) loadConfig() error {
 	var err error
 	bc.data.RawConfig, err = cfgfile.Load("")
 	if err != nil {
 		return fmt.Errorf("error loading config file: %v", err)
 	}
 
+	bc.data.Config.HTTP = control.DefaultHTTPConfig
 	err = bc.data.RawConfig.Unpack(&bc.data.Config)
 	if err != nil {
-		return fmt.Errorf("error unpacking config data: %v", err)
+		return fmt.Errorf("error unpacking config data: %v", cfgfile.AnnotateError(err))
+	}
+
+	err = checkUnknownSettings(bc.data.RawConfig, bc.beater)
+	if err != nil {
+		return err
+	}
+
+	err = feature.Configure(bc.data.Config.Features)
+	if err != nil {
+		return err
 	}
 
 	err = paths.InitPaths(&bc.data.Config.Path)
@@ -208,20 +268,50 @@ func (bc *instance) config() error {
 
 	// log paths values to help with troubleshooting
 	logp.Info(paths.Paths.String())
+	logProcessLimits()
 
 	bc.data.processors, err = processors.New(bc.data.Config.Processors)
 	if err != nil {
 		return fmt.Errorf("error initializing processors: %v", err)
 	}
 
+	bc.data.States, err = statestore.NewRegistry(bc.data.Config.StateStore)
+	if err != nil {
+		return fmt.Errorf("error initializing state store: %v", err)
+	}
+
 	if bc.data.Config.Shipper.MaxProcs != nil {
 		maxProcs := *bc.data.Config.Shipper.MaxProcs
 		if maxProcs > 0 {
 			runtime.GOMAXPROCS(maxProcs)
 		}
 	}
+	return nil
+}
 
-	return bc.beater.Config(bc.data)
+// checkUnknownSettings returns an error listing all settings not known to
+// libbeat or the Beat, such that typos are reported at startup instead of
+// being silently ignored.
+func checkUnknownSettings(cfg *common.Config, bt Beater) error {
+	schemas := []interface{}{BeatConfig{}}
+	cs, checkAll := bt.(ConfigSchema)
+	if checkAll {
+		schemas = append(schemas, cs.ConfigSchema())
+	}
+
+	unknown := cfgfile.NewSchema(schemas...).CheckUnknownFields(cfg, !checkAll)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(unknown))
+	for i, path := range unknown {
+		msgs[i] = path
+		if file, line, found := cfgfile.Location(path); found {
+			msgs[i] = fmt.Sprintf("%v (%v:%v)", path, file, line)
+		}
+	}
+	return fmt.Errorf("unknown config settings: %v", strings.Join(msgs, ", "))
 }
 
 // setup initializes the Publisher and then invokes the Setup method of the
@@ -253,8 +343,11 @@ func (bc *instance) setup() error {
 }
 
 // run calls the beater Setup and Run methods. In case of errors
-// during the setup phase, it exits the process.
+// during the setup phase, it exits the process. Panics of the Beat are
+// handled before cleanup is invoked, as the state of the Beat is unknown.
 func (bc *instance) run() error {
+	defer crash.Recover()
+
 	logp.Info("%s start running.", bc.data.Name)
 	return bc.beater.Run(bc.data)
 }
@@ -265,14 +358,64 @@ func (bc *instance) run() error {
 func (bc *instance) cleanup() error {
 	logp.Info("%s cleanup", bc.data.Name)
 	defer svc.Cleanup()
+	defer bc.data.States.Close()
+	defer bc.shutdownPublisher()
+	defer bc.data.processors.Stop()
+	if bc.control != nil {
+		defer bc.control.Stop()
+	}
+	if bc.http != nil {
+		defer bc.http.Stop()
+	}
 	return bc.beater.Cleanup(bc.data)
 }
 
+// shutdownPublisher waits up to shutdown_timeout for pending events to be
+// published, once the processors flushed their events, and stops the
+// publisher before the state store is closed.
+func (bc *instance) shutdownPublisher() {
+	if bc.data.Publisher == nil {
+		return
+	}
+	bc.data.Publisher.Shutdown(bc.data.Config.Shipper.ShutdownTimeout)
+}
+
 // launch manages the lifecycle of the beat and guarantees the order in which
 // the Beater methods are invoked. If an error occurs in the lifecycle of the
 // Beat it will be returned.
 func (bc *instance) launch() (err error) {
 	defer func() { err = handleError(err) }()
+	crash.SetHandler(bc.onCrash)
+	defer crash.Recover()
+
+	if len(os.Args) > 1 && os.Args[1] == migrateConfigCommand {
+		err = migrateConfig(bc.data.Name, os.Args[2:])
+		if err == nil {
+			err = GracefulExit
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == replayCommand {
+		err = bc.replay(os.Args[2:])
+		if err == nil {
+			err = GracefulExit
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == testCommand {
+		err = bc.test(os.Args[2:])
+		if err == nil {
+			err = GracefulExit
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == upgradeCommand {
+		err = bc.upgrade(os.Args[2:])
+		if err == nil {
+			err = GracefulExit
+		}
+		return
+	}
 
 	err = bc.handleFlags()
 	if err != nil {
@@ -291,11 +434,70 @@ func (bc *instance) launch() (err error) {
 	}
 
 	svc.BeforeRun()
+	err = bc.startControl()
+	if err != nil {
+		return
+	}
+
 	svc.HandleSignals(bc.beater.Stop)
 	err = bc.run()
 	return
 }
 
+// startControl starts the control socket and the HTTP endpoint if enabled.
+func (bc *instance) startControl() error {
+	info := control.Info{
+		Beat:    bc.data.Name,
+		Version: bc.data.Version,
+		UUID:    bc.data.UUID.String(),
+	}
+
+	if config := bc.data.Config.Control; config.Enabled {
+		bc.control = control.NewServer(config, info, bc.reload)
+		if err := bc.control.Start(); err != nil {
+			return err
+		}
+	}
+
+	if config := bc.data.Config.HTTP; config.Enabled {
+		bc.http = control.NewHTTPServer(config, info)
+		if err := bc.http.Start(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reload reads the configuration files again, applies the log level and
+// debug selectors and invokes Reload if implemented by the Beat.
+func (bc *instance) reload() error {
+	cfg, err := cfgfile.Load("")
+	if err != nil {
+		return fmt.Errorf("error loading config file: %v", err)
+	}
+
+	config := BeatConfig{}
+	err = cfg.Unpack(&config)
+	if err != nil {
+		return fmt.Errorf("error unpacking config data: %v", cfgfile.AnnotateError(err))
+	}
+
+	if len(config.Logging.Selectors) > 0 {
+		logp.SetSelectors(config.Logging.Selectors)
+	}
+	if config.Logging.Level != "" {
+		err = logp.SetLevel(config.Logging.Level)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r, ok := bc.beater.(Reloader); ok {
+		return r.Reload(cfg)
+	}
+	return nil
+}
+
 // handleError handles the given error by logging it and then returning the
 // error. If the err is nil or is a GracefulExit error then the method will
 // return nil without logging anything.
@@ -0,0 +1,148 @@
+package beat
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+)
+
+// CrashExitCode is the exit code of a Beat that panicked. It differs from the
+// exit code of a Beat that failed with an error, such that restart loops can be
+// distinguished from configuration errors.
+const CrashExitCode = 3
+
+const (
+	crashReportPrefix = "-crash-"
+	crashReportTime   = "20060102T150405.000"
+	maxCrashReports   = 5
+)
+
+// onCrash writes a crash report and exits with CrashExitCode. It is the
+// handler of the panics recovered by crash.Recover, which is deferred by the
+// goroutine running the Beat and by the goroutines started by libbeat.
+// Panics of goroutines not deferring crash.Recover still crash the Beat
+// without report.
+func (bc *instance) onCrash(r interface{}) {
+	stack := goroutineStacks()
+	logp.Critical("%s panicked: %v", bc.data.Name, r)
+	fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\n", r, stack)
+
+	path, err := writeCrashReport(crashReportDir(), bc.data, r, stack, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write crash report: %v\n", err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Crash report written to %s\n", path)
+	}
+	os.Exit(CrashExitCode)
+}
+
+// crashReportDir returns the logs path, or the temporary directory if the Beat
+// panicked before the paths were initialized.
+func crashReportDir() string {
+	if paths.Paths.Logs == "" {
+		return os.TempDir()
+	}
+	return paths.Paths.Logs
+}
+
+// writeCrashReport writes the panic, the stacks of all goroutines, the last
+// log lines and the metrics of the Beat to a new file in dir. Only the last
+// maxCrashReports reports are kept.
+func writeCrashReport(
+	dir string,
+	b *Beat,
+	r interface{},
+	stack []byte,
+	now time.Time,
+) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+
+	name := b.Name + crashReportPrefix + now.UTC().Format(crashReportTime) + ".log"
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "%s %s (%s/%s) crashed at %s\n", b.Name, b.Version,
+		runtime.GOOS, runtime.GOARCH, now.Format(time.RFC3339))
+	fmt.Fprintf(w, "panic: %v\n\n", r)
+
+	fmt.Fprintf(w, "Goroutines:\n%s\n", stack)
+
+	fmt.Fprintf(w, "Last log lines:\n")
+	for _, line := range logp.RecentLines() {
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintf(w, "\nMetrics:\n")
+	writeExpvars(w)
+
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+
+	removeOldCrashReports(dir, b.Name)
+	return path, nil
+}
+
+// writeExpvars writes all expvar variables as JSON object, like the
+// /debug/vars endpoint.
+func writeExpvars(w io.Writer) {
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// goroutineStacks returns the stacks of all goroutines, starting with the
+// current one.
+func goroutineStacks() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 64<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// removeOldCrashReports removes all but the newest maxCrashReports crash
+// reports of the Beat, such that restart loops do not fill the disk.
+func removeOldCrashReports(dir, name string) {
+	reports, err := filepath.Glob(filepath.Join(dir, name+crashReportPrefix+"*.log"))
+	if err != nil || len(reports) <= maxCrashReports {
+		return
+	}
+
+	// the names of the reports sort by time
+	sort.Strings(reports)
+	for _, path := range reports[:len(reports)-maxCrashReports] {
+		if err := os.Remove(path); err != nil {
+			logp.Warn("Failed to remove old crash report %s: %v", path, err)
+		}
+	}
+}
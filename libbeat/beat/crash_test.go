@@ -0,0 +1,68 @@
+// +build !integration
+
+package beat
+
+import (
+	"expvar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteCrashReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expvar.NewInt("test.crash.events").Set(42)
+	logp.LogInit(logp.LOG_INFO, "", false, false, nil)
+	logp.Info("last message before the crash")
+
+	b := &Beat{Name: "testbeat", Version: "0.9"}
+	path, err := writeCrashReport(dir, b, "boom", goroutineStacks(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, dir, filepath.Dir(path))
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(content)
+	assert.Contains(t, report, "testbeat 0.9")
+	assert.Contains(t, report, "panic: boom")
+	assert.Contains(t, report, "TestWriteCrashReport")
+	assert.Contains(t, report, "last message before the crash")
+	assert.Contains(t, report, `"test.crash.events": 42`)
+}
+
+func TestRemoveOldCrashReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &Beat{Name: "testbeat"}
+	start := time.Now()
+	var paths []string
+	for i := 0; i < maxCrashReports+2; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		path, err := writeCrashReport(dir, b, "boom", nil, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	reports, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	assert.Equal(t, paths[2:], reports)
+}
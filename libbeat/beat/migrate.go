@@ -0,0 +1,72 @@
+package beat
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// migrateConfigCommand is the name of the subcommand converting 1.x
+// configuration files into the current format.
+const migrateConfigCommand = "migrate-config"
+
+// migrateSchema accepts the settings shared by all Beats and any settings
+// in the namespaces of the Beats.
+var migrateSchema = cfgfile.NewSchema(BeatConfig{}, struct {
+	Filebeat   *common.Config `config:"filebeat"`
+	Metricbeat *common.Config `config:"metricbeat"`
+	Packetbeat *common.Config `config:"packetbeat"`
+	Winlogbeat *common.Config `config:"winlogbeat"`
+}{})
+
+// migrateConfig implements the migrate-config subcommand. It reads the 1.x
+// configuration file given by -c and writes the converted configuration to
+// the file given by -o or to stdout. Settings that could not be migrated are
+// reported on stderr.
+func migrateConfig(name string, args []string) error {
+	flags := flag.NewFlagSet(migrateConfigCommand, flag.ContinueOnError)
+	in := flags.String("c", name+".yml", "Configuration file in the 1.x format")
+	out := flags.String("o", "", "Output file. Writes to stdout if not set")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return GracefulExit
+		}
+		return err
+	}
+
+	content, err := ioutil.ReadFile(*in)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	migrated, messages, err := cfgfile.MigrateConfig(content)
+	if err != nil {
+		return err
+	}
+
+	// report settings unknown to libbeat which were left in the config
+	cfg, err := common.NewConfigWithYAML(migrated, *in)
+	if err != nil {
+		return fmt.Errorf("error parsing migrated config: %v", err)
+	}
+	for _, path := range migrateSchema.CheckUnknownFields(cfg, false) {
+		messages = append(messages, fmt.Sprintf("%v: unknown setting", path))
+	}
+
+	for _, msg := range messages {
+		fmt.Fprintf(os.Stderr, "Not migrated: %v\n", msg)
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(migrated)
+		return err
+	}
+	if err := ioutil.WriteFile(*out, migrated, 0600); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package beat
+
+import (
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/cgroup"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// userHZ is the frequency of the clock ticks used for CPU times in /proc. It
+// is 100 on all architectures supported by the Beats.
+const userHZ = 100
+
+// minCPUInterval is the minimal interval over which the CPU usage is
+// computed. More frequent requests return the previous value.
+const minCPUInterval = time.Second
+
+// Metrics that can retrieved through the expvar web interface. The CPU and
+// memory usage of the Beat process is reported relative to the limits of its
+// cgroup, such that the usage of Beats running in containers is not compared
+// to the resources of the host.
+var processMetrics = newProcessStats()
+
+func init() {
+	expvar.Publish("libbeat.process", expvar.Func(func() interface{} {
+		return processMetrics.get(time.Now())
+	}))
+}
+
+type processStats struct {
+	sync.Mutex
+
+	procDir string
+
+	lastTime time.Time
+	lastCPU  time.Duration
+	cpuPct   float64
+}
+
+func newProcessStats() *processStats {
+	p := &processStats{procDir: "/proc/self", lastTime: time.Now()}
+	p.lastCPU, _ = readCPUTime(p.procDir)
+	return p
+}
+
+// get returns the current CPU and memory usage of the process. The CPU usage
+// is the average since the previous request.
+func (p *processStats) get(now time.Time) common.MapStr {
+	stats, err := cgroup.ReadStats(p.procDir)
+	if err != nil {
+		stats = &cgroup.Stats{}
+	}
+
+	metrics := common.MapStr{}
+
+	cpuLimit := stats.CPULimit
+	if cpuLimit == 0 {
+		cpuLimit = float64(runtime.NumCPU())
+	}
+	if cpuTime, err := readCPUTime(p.procDir); err == nil {
+		pct := p.updateCPU(now, cpuTime)
+		metrics["cpu"] = common.MapStr{
+			"total": common.MapStr{
+				"ms":  int64(cpuTime / time.Millisecond),
+				"pct": pct,
+			},
+			"limit": common.MapStr{
+				"cores": cpuLimit,
+				"pct":   pct / cpuLimit,
+			},
+		}
+	}
+
+	memory := common.MapStr{}
+	rss, err := readRSS(p.procDir)
+	if err == nil {
+		memory["rss"] = rss
+	}
+	if stats.MemoryLimit > 0 {
+		// The usage of the cgroup includes the page cache and all other
+		// processes in the cgroup, as considered by the OOM killer.
+		usage := stats.MemoryUsage
+		if usage == 0 {
+			usage = rss
+		}
+		memory["limit"] = common.MapStr{
+			"bytes": stats.MemoryLimit,
+			"pct":   float64(usage) / float64(stats.MemoryLimit),
+		}
+	}
+	if len(memory) > 0 {
+		metrics["memory"] = memory
+	}
+
+	if stats.Version > 0 {
+		metrics["cgroup"] = common.MapStr{
+			"version": stats.Version,
+			"path":    stats.Path,
+			"memory": common.MapStr{
+				"usage": stats.MemoryUsage,
+			},
+		}
+	}
+
+	return metrics
+}
+
+// updateCPU returns the CPU usage, in CPUs, since the previous update.
+func (p *processStats) updateCPU(now time.Time, cpuTime time.Duration) float64 {
+	p.Lock()
+	defer p.Unlock()
+
+	interval := now.Sub(p.lastTime)
+	if interval < minCPUInterval {
+		return p.cpuPct
+	}
+
+	p.cpuPct = float64(cpuTime-p.lastCPU) / float64(interval)
+	p.lastTime = now
+	p.lastCPU = cpuTime
+	return p.cpuPct
+}
+
+// logProcessLimits logs the resource limits the Beat is running with.
+func logProcessLimits() {
+	stats, err := cgroup.SelfStats()
+	if err != nil {
+		if err != cgroup.ErrNotFound {
+			logp.Warn("Failed to read cgroup limits: %v", err)
+		}
+		return
+	}
+
+	cpu := "none"
+	if stats.CPULimit > 0 {
+		cpu = fmt.Sprintf("%.2f CPUs", stats.CPULimit)
+	}
+	memory := "none"
+	if stats.MemoryLimit > 0 {
+		memory = fmt.Sprintf("%d bytes", stats.MemoryLimit)
+	}
+	logp.Info("Running in cgroup %s (v%d) with CPU limit: %s, memory limit: %s",
+		stats.Path, stats.Version, cpu, memory)
+}
+
+// readCPUTime returns the user and system CPU time of the process from the
+// stat file in procDir.
+func readCPUTime(procDir string) (time.Duration, error) {
+	data, err := ioutil.ReadFile(procDir + "/stat")
+	if err != nil {
+		return 0, err
+	}
+
+	// The command name can contain spaces and parentheses, the fields are
+	// counted from the last closing parenthesis.
+	s := string(data)
+	i := strings.LastIndex(s, ")")
+	if i < 0 {
+		return 0, fmt.Errorf("invalid stat file: %s", s)
+	}
+	fields := strings.Fields(s[i+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("invalid stat file: %s", s)
+	}
+
+	// utime and stime are the 14th and 15th field
+	var ticks uint64
+	for _, f := range fields[11:13] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ticks += n
+	}
+	return time.Duration(ticks) * time.Second / userHZ, nil
+}
+
+// readRSS returns the resident set size of the process from the statm file
+// in procDir.
+func readRSS(procDir string) (uint64, error) {
+	data, err := ioutil.ReadFile(procDir + "/statm")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("invalid statm file: %s", data)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,80 @@
+// +build !integration
+
+package beat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "process")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stat := func(utime, stime int) string {
+		return fmt.Sprintf("42 (file beat) S 1 42 42 0 -1 4194560 100 0 0 0 %d %d 0 0 20 0 8 0 100 0 0\n", utime, stime)
+	}
+
+	procDir := filepath.Join(dir, "proc")
+	write("proc/stat", stat(100, 50))
+	write("proc/statm", "1000 256 100 1 0 500 0\n")
+	write("proc/cgroup", "4:memory:/beat\n3:cpu,cpuacct:/beat\n")
+	write("proc/mountinfo", fmt.Sprintf(
+		"25 20 0:22 / %s/memory rw - cgroup cgroup rw,memory\n"+
+			"26 20 0:23 / %s/cpu rw - cgroup cgroup rw,cpu,cpuacct\n", dir, dir))
+	write("memory/beat/memory.limit_in_bytes", "4194304\n")
+	write("memory/beat/memory.usage_in_bytes", "1048576\n")
+	write("cpu/beat/cpu.cfs_quota_us", "200000\n")
+	write("cpu/beat/cpu.cfs_period_us", "100000\n")
+
+	start := time.Now()
+	p := &processStats{procDir: procDir, lastTime: start}
+	p.lastCPU, _ = readCPUTime(procDir)
+	assert.Equal(t, 1500*time.Millisecond, p.lastCPU)
+
+	// 1s of CPU time in 10s, 10% of a CPU and 5% of the limit of 2 CPUs.
+	write("proc/stat", stat(150, 100))
+	metrics := p.get(start.Add(10 * time.Second))
+
+	assert.Equal(t, int64(2500), getValue(t, metrics, "cpu.total.ms"))
+	assert.InDelta(t, 0.1, getValue(t, metrics, "cpu.total.pct"), 0.0001)
+	assert.Equal(t, 2.0, getValue(t, metrics, "cpu.limit.cores"))
+	assert.InDelta(t, 0.05, getValue(t, metrics, "cpu.limit.pct"), 0.0001)
+
+	assert.Equal(t, uint64(256*os.Getpagesize()), getValue(t, metrics, "memory.rss"))
+	assert.Equal(t, uint64(4194304), getValue(t, metrics, "memory.limit.bytes"))
+	assert.Equal(t, 0.25, getValue(t, metrics, "memory.limit.pct"))
+
+	assert.Equal(t, 1, getValue(t, metrics, "cgroup.version"))
+	assert.Equal(t, "/beat", getValue(t, metrics, "cgroup.path"))
+
+	// Requests within the minimal interval return the previous CPU usage.
+	write("proc/stat", stat(1000, 1000))
+	metrics = p.get(start.Add(10*time.Second + time.Millisecond))
+	assert.InDelta(t, 0.1, getValue(t, metrics, "cpu.total.pct"), 0.0001)
+}
+
+func getValue(t *testing.T, m common.MapStr, key string) interface{} {
+	v, err := m.GetValue(key)
+	assert.NoError(t, err, key)
+	return v
+}
@@ -0,0 +1,105 @@
+package beat
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/replay"
+	svc "github.com/elastic/beats/libbeat/service"
+)
+
+// replayCommand is the name of the subcommand publishing events archived in
+// files, like the files written by the file output, to the configured
+// outputs again.
+const replayCommand = "replay"
+
+// replay implements the replay subcommand. It accepts the global flags, like
+// -c and -e, followed by the files to replay. If -processors is given, the
+// processors configured in that file are applied instead of the processors of
+// the Beat configuration.
+func (bc *instance) replay(args []string) error {
+	err := cfgfile.ChangeDefaultCfgfileFlag(bc.data.Name)
+	if err != nil {
+		return fmt.Errorf("failed to set default config file path: %v", err)
+	}
+
+	flags := flag.NewFlagSet(replayCommand, flag.ContinueOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+	})
+	procsFile := flags.String("processors", "",
+		"Configuration file with the processors to apply instead of the configured processors")
+	batchSize := flags.Int("batch_size", replay.DefaultBatchSize,
+		"Number of events to publish at once")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return GracefulExit
+		}
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no files to replay given")
+	}
+
+	if err := bc.loadConfig(); err != nil {
+		return err
+	}
+	defer bc.data.States.Close()
+
+	if *procsFile != "" {
+		bc.data.processors, err = loadProcessors(*procsFile)
+		if err != nil {
+			return err
+		}
+	}
+	defer bc.data.processors.Stop()
+
+	pub, err := publisher.New(bc.data.Name, bc.data.Config.Output, bc.data.Config.Shipper)
+	if err != nil {
+		return fmt.Errorf("error initializing publisher: %v", err)
+	}
+	defer pub.Stop()
+	pub.RegisterProcessors(bc.data.processors)
+
+	client := pub.Connect()
+	defer client.Close()
+
+	r := replay.New(client, *batchSize)
+	svc.HandleSignals(r.Stop)
+
+	stats, err := r.Run(flags.Args())
+	msg := fmt.Sprintf("Replayed %v events from %v files, %v acknowledged, %v invalid lines skipped",
+		stats.Events, stats.Files, stats.Acked, stats.Invalid)
+	logp.Info("%v", msg)
+	fmt.Fprintln(os.Stderr, msg)
+	return err
+}
+
+// loadProcessors creates the processors configured in the processors section
+// of the given file.
+func loadProcessors(path string) (*processors.Processors, error) {
+	cfg, err := common.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading processors file: %v", err)
+	}
+
+	config := struct {
+		Processors processors.PluginConfig `config:"processors"`
+	}{}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("error unpacking processors file: %v", cfgfile.AnnotateError(err))
+	}
+
+	procs, err := processors.New(config.Processors)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing processors: %v", err)
+	}
+	return procs, nil
+}
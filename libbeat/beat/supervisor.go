@@ -0,0 +1,200 @@
+package beat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Section is a Beater run by the Supervisor. The Beater is run if the
+// configuration contains the section of the given name, e.g. filebeat.
+type Section struct {
+	Name   string
+	Beater Beater
+}
+
+// Supervisor is a Beater running the Beaters of multiple Beats in one
+// process. All Beaters share the publisher, and with it the queues and the
+// outputs, the processors and the state store of the process. Only the
+// Beaters whose section is present in the configuration are run.
+//
+// The Beaters are configured and set up in the order given. Run returns once
+// all Beaters have returned. If a Beater fails, the other Beaters are stopped.
+//
+// Each Beater is passed its own copy of the Beat, which is created once by
+// Config and passed to all methods of the Beater. The fields of different
+// Beats can conflict, like the source field of Filebeat and Packetbeat flows,
+// so the events of each section are published to their own index, named after
+// the Beat and the section, like agentbeat-filebeat, if the Beater publishes
+// with Beat.Connect. The index configured in the outputs takes precedence.
+type Supervisor struct {
+	sections []*supervised
+	running  []*supervised // Beaters whose section is configured
+}
+
+type supervised struct {
+	Section
+	beat     *Beat // copy of the Beat passed to the Beater
+	setup    bool  // Setup has been called
+	stopOnce sync.Once
+}
+
+var errNoSection = errors.New("no section configured")
+
+// newSectionBeat returns a copy of b publishing the events of the section to
+// its own index, unless an index is configured in the outputs.
+func newSectionBeat(b *Beat, name string) *Beat {
+	sb := *b
+	sb.index = b.Name + "-" + name
+	for _, cfg := range b.Config.Output {
+		if cfg.HasField("index") {
+			sb.index = ""
+			break
+		}
+	}
+	return &sb
+}
+
+// NewSupervisor creates a Supervisor running the given sections.
+func NewSupervisor(sections ...Section) *Supervisor {
+	s := &Supervisor{}
+	for _, section := range sections {
+		s.sections = append(s.sections, &supervised{Section: section})
+	}
+	return s
+}
+
+// ConfigSchema returns the combined configuration structure of all sections.
+// The settings in the sections of Beaters not implementing ConfigSchema are
+// not checked.
+func (s *Supervisor) ConfigSchema() interface{} {
+	var fields []reflect.StructField
+	for i, sub := range s.sections {
+		field := reflect.StructField{Name: fmt.Sprintf("Section%d", i)}
+		if cs, ok := sub.Beater.(ConfigSchema); ok {
+			field.Type = reflect.TypeOf(cs.ConfigSchema())
+			field.Tag = `config:",inline"`
+		} else {
+			field.Type = reflect.TypeOf((*common.Config)(nil))
+			field.Tag = reflect.StructTag(fmt.Sprintf(`config:"%s"`, sub.Name))
+		}
+		fields = append(fields, field)
+	}
+	return reflect.New(reflect.StructOf(fields)).Elem().Interface()
+}
+
+// Config selects the sections present in the configuration and invokes the
+// Config method of their Beaters.
+func (s *Supervisor) Config(b *Beat) error {
+	var names []string
+	for _, sub := range s.sections {
+		names = append(names, sub.Name)
+		if !b.RawConfig.HasField(sub.Name) {
+			continue
+		}
+
+		sub.beat = newSectionBeat(b, sub.Name)
+		if err := sub.Beater.Config(sub.beat); err != nil {
+			return fmt.Errorf("%s: %v", sub.Name, err)
+		}
+		s.running = append(s.running, sub)
+	}
+
+	if len(s.running) == 0 {
+		return fmt.Errorf("%v, configure at least one of %s",
+			errNoSection, strings.Join(names, ", "))
+	}
+	for _, sub := range s.running {
+		logp.Info("Supervisor runs %s", sub.Name)
+	}
+	return nil
+}
+
+// Setup invokes the Setup method of the configured Beaters.
+func (s *Supervisor) Setup(b *Beat) error {
+	for _, sub := range s.running {
+		// The publisher is created after Config.
+		sub.beat.Publisher = b.Publisher
+		sub.setup = true
+		if err := sub.Beater.Setup(sub.beat); err != nil {
+			return fmt.Errorf("%s: %v", sub.Name, err)
+		}
+	}
+	return nil
+}
+
+// Run runs all configured Beaters and blocks until all of them returned. The
+// first error returned by a Beater stops the other Beaters and is returned.
+func (s *Supervisor) Run(b *Beat) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(s.running))
+	for _, sub := range s.running {
+		wg.Add(1)
+		go func(sub *supervised) {
+			defer wg.Done()
+			defer crash.Recover()
+
+			err := sub.Beater.Run(sub.beat)
+			if err != nil {
+				logp.Err("%s failed: %v", sub.Name, err)
+				errs <- fmt.Errorf("%s: %v", sub.Name, err)
+				s.Stop()
+				return
+			}
+			logp.Info("%s finished", sub.Name)
+		}(sub)
+	}
+
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// Cleanup invokes the Cleanup method of all Beaters that have been set up,
+// in reverse order.
+func (s *Supervisor) Cleanup(b *Beat) error {
+	var errs []string
+	for i := len(s.running) - 1; i >= 0; i-- {
+		sub := s.running[i]
+		if !sub.setup {
+			continue
+		}
+		if err := sub.Beater.Cleanup(sub.beat); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sub.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Stop stops all Beaters that have been set up. Each Beater is stopped at
+// most once.
+func (s *Supervisor) Stop() {
+	for _, sub := range s.running {
+		if sub.setup {
+			sub.stopOnce.Do(sub.Beater.Stop)
+		}
+	}
+}
+
+// Reload invokes the Reload method of the configured Beaters implementing the
+// Reloader interface.
+func (s *Supervisor) Reload(cfg *common.Config) error {
+	for _, sub := range s.running {
+		if r, ok := sub.Beater.(Reloader); ok {
+			if err := r.Reload(cfg); err != nil {
+				return fmt.Errorf("%s: %v", sub.Name, err)
+			}
+		}
+	}
+	return nil
+}
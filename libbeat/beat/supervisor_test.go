@@ -0,0 +1,168 @@
+// +build !integration
+
+package beat
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type supervisedBeater struct {
+	mutex   sync.Mutex
+	calls   []string
+	done    chan struct{}
+	runErr  error
+	stopped int
+	index   string  // index of the Beat passed to Run
+	beats   []*Beat // Beats passed to Config, Setup, Run and Cleanup
+}
+
+func newSupervisedBeater() *supervisedBeater {
+	return &supervisedBeater{done: make(chan struct{})}
+}
+
+func (tb *supervisedBeater) call(name string, b *Beat) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.calls = append(tb.calls, name)
+	tb.beats = append(tb.beats, b)
+}
+
+func (tb *supervisedBeater) Config(b *Beat) error  { tb.call("config", b); return nil }
+func (tb *supervisedBeater) Setup(b *Beat) error   { tb.call("setup", b); return nil }
+func (tb *supervisedBeater) Cleanup(b *Beat) error { tb.call("cleanup", b); return nil }
+
+func (tb *supervisedBeater) Run(b *Beat) error {
+	tb.call("run", b)
+	tb.mutex.Lock()
+	tb.index = b.index
+	tb.mutex.Unlock()
+	if tb.runErr != nil {
+		return tb.runErr
+	}
+	<-tb.done
+	return nil
+}
+
+func (tb *supervisedBeater) Stop() {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.stopped++
+	close(tb.done)
+}
+
+type schemaBeater struct {
+	supervisedBeater
+}
+
+func (tb *schemaBeater) ConfigSchema() interface{} {
+	return struct {
+		First struct {
+			Period int `config:"period"`
+		} `config:"first"`
+	}{}
+}
+
+func newSupervisorBeat(t *testing.T, settings map[string]interface{}) *Beat {
+	cfg, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Beat{Name: "supervisor", RawConfig: cfg}
+}
+
+func TestSupervisorRunsConfiguredSections(t *testing.T) {
+	first, second := newSupervisedBeater(), newSupervisedBeater()
+	s := NewSupervisor(Section{"first", first}, Section{"second", second})
+	b := newSupervisorBeat(t, map[string]interface{}{
+		"first.period": 10,
+	})
+
+	assert.NoError(t, s.Config(b))
+	assert.NoError(t, s.Setup(b))
+
+	result := make(chan error)
+	go func() { result <- s.Run(b) }()
+	s.Stop()
+	s.Stop()
+	assert.NoError(t, <-result)
+	assert.NoError(t, s.Cleanup(b))
+
+	assert.Equal(t, []string{"config", "setup", "run", "cleanup"}, first.calls)
+	assert.Equal(t, 1, first.stopped)
+	assert.Equal(t, "supervisor-first", first.index)
+	assert.Empty(t, b.index)
+	assert.Empty(t, second.calls)
+	for _, sb := range first.beats {
+		assert.True(t, sb == first.beats[0], "all methods get the same Beat")
+	}
+	assert.False(t, first.beats[0] == b)
+}
+
+func TestSupervisorKeepsOutputIndex(t *testing.T) {
+	first := newSupervisedBeater()
+	s := NewSupervisor(Section{"first", first})
+	b := newSupervisorBeat(t, map[string]interface{}{
+		"first.period": 10,
+	})
+	output, err := common.NewConfigFrom(map[string]interface{}{
+		"hosts": []string{"localhost:9200"},
+		"index": "custom",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Config.Output = map[string]*common.Config{"elasticsearch": output}
+
+	assert.NoError(t, s.Config(b))
+	assert.NoError(t, s.Setup(b))
+	first.runErr = errors.New("stop")
+	s.Run(b)
+	assert.Empty(t, first.index)
+}
+
+func TestSupervisorRequiresSection(t *testing.T) {
+	s := NewSupervisor(Section{"first", newSupervisedBeater()})
+	err := s.Config(newSupervisorBeat(t, map[string]interface{}{}))
+	assert.Error(t, err)
+}
+
+func TestSupervisorStopsOnFailure(t *testing.T) {
+	first, second := newSupervisedBeater(), newSupervisedBeater()
+	second.runErr = errors.New("failed")
+	s := NewSupervisor(Section{"first", first}, Section{"second", second})
+	b := newSupervisorBeat(t, map[string]interface{}{
+		"first.period":  10,
+		"second.period": 10,
+	})
+
+	assert.NoError(t, s.Config(b))
+	assert.NoError(t, s.Setup(b))
+	err := s.Run(b)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "second: failed")
+	}
+	assert.Equal(t, 1, first.stopped)
+}
+
+func TestSupervisorConfigSchema(t *testing.T) {
+	s := NewSupervisor(
+		Section{"first", &schemaBeater{supervisedBeater{done: make(chan struct{})}}},
+		Section{"second", newSupervisedBeater()},
+	)
+	b := newSupervisorBeat(t, map[string]interface{}{
+		"first.period":  10,
+		"first.unknown": 1,
+		"second.any":    1,
+		"third":         1,
+	})
+
+	schema := cfgfile.NewSchema(BeatConfig{}, s.ConfigSchema())
+	unknown := schema.CheckUnknownFields(b.RawConfig, false)
+	assert.Equal(t, []string{"first.unknown", "third"}, unknown)
+}
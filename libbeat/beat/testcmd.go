@@ -0,0 +1,211 @@
+package beat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/cfgfile"
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+)
+
+// testCommand is the name of the subcommand testing parts of the Beat
+// configuration.
+const testCommand = "test"
+
+// testProcessorsCommand is the name of the test running events through the
+// configured processors.
+const testProcessorsCommand = "processors"
+
+// test implements the test subcommand. The first argument selects the part
+// of the configuration to test, the remaining arguments are the global flags
+// and the flags of the test.
+func (bc *instance) test(args []string) error {
+	if len(args) == 0 || args[0] != testProcessorsCommand {
+		return fmt.Errorf("usage: %v %v %v [flags], supported tests: %v",
+			bc.data.Name, testCommand, testProcessorsCommand, testProcessorsCommand)
+	}
+	return bc.testProcessors(args[1:])
+}
+
+// testProcessors reads JSON events from the file given by -event, or stdin,
+// runs them through the configured processors and prints the resulting
+// events to stdout. If -processors is given, the processors configured in
+// that file are tested instead of the processors of the Beat configuration.
+func (bc *instance) testProcessors(args []string) error {
+	err := cfgfile.ChangeDefaultCfgfileFlag(bc.data.Name)
+	if err != nil {
+		return fmt.Errorf("failed to set default config file path: %v", err)
+	}
+
+	flags := flag.NewFlagSet(testCommand+" "+testProcessorsCommand, flag.ContinueOnError)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		flags.Var(f.Value, f.Name, f.Usage)
+	})
+	eventFile := flags.String("event", "-",
+		"File with the JSON events to process, - reads the events from stdin")
+	procsFile := flags.String("processors", "",
+		"Configuration file with the processors to test instead of the configured processors")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return GracefulExit
+		}
+		return err
+	}
+
+	if err := bc.loadConfig(); err != nil {
+		return err
+	}
+	defer bc.data.States.Close()
+
+	procs := bc.data.processors
+	if *procsFile != "" {
+		procs, err = loadProcessors(*procsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	in := io.Reader(os.Stdin)
+	if *eventFile != "-" {
+		f, err := os.Open(*eventFile)
+		if err != nil {
+			return fmt.Errorf("error opening events file: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	return simulateProcessors(procs, in, out)
+}
+
+// simulateProcessors runs all events read from in through the processors.
+// Events are JSON objects, separated by whitespace or newlines, or a JSON
+// array of objects. Each resulting event is written to out, together with the
+// number of the input event. Events dropped by the processors are reported
+// as dropped. Events published by processors delaying events, like
+// multiline, are written once available, and on Stop after all events have
+// been processed.
+func simulateProcessors(procs *processors.Processors, in io.Reader, out io.Writer) error {
+	var mutex sync.Mutex
+	var writeErr error
+	write := func(format string, args ...interface{}) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if writeErr == nil {
+			_, writeErr = fmt.Fprintf(out, format, args...)
+		}
+	}
+	printEvent := func(label string, event common.MapStr) {
+		data, err := json.MarshalIndent(event, "", "  ")
+		if err != nil {
+			write("%v: failed to encode event: %v\n", label, err)
+			return
+		}
+		write("%v:\n%s\n", label, data)
+	}
+
+	procs.Start(func(event common.MapStr) {
+		printEvent("generated event", event)
+	})
+
+	n := 0
+	err := decodeEvents(in, func(event common.MapStr) {
+		n++
+		label := fmt.Sprintf("event %v", n)
+
+		event = procs.Run(common.ConvertToGenericEvent(event))
+		if event == nil {
+			write("%v: dropped\n", label)
+			return
+		}
+		printEvent(label, event)
+	})
+	procs.Stop()
+
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// decodeEvents decodes all JSON events read from in and passes them to fn.
+func decodeEvents(in io.Reader, fn func(common.MapStr)) error {
+	dec := json.NewDecoder(in)
+	for i := 1; ; i++ {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading event %v: %v", i, err)
+		}
+
+		var values []json.RawMessage
+		if data := bytes.TrimSpace(raw); len(data) > 0 && data[0] == '[' {
+			if err := json.Unmarshal(data, &values); err != nil {
+				return fmt.Errorf("error reading events: %v", err)
+			}
+		} else {
+			values = []json.RawMessage{raw}
+		}
+
+		for _, value := range values {
+			event, err := decodeEvent(value)
+			if err != nil {
+				return fmt.Errorf("error reading event %v: %v", i, err)
+			}
+			fn(event)
+		}
+	}
+}
+
+// decodeEvent decodes an event like events read from the spool, with numbers
+// converted to the integer and float types of events created by the Beats.
+func decodeEvent(data []byte) (common.MapStr, error) {
+	if data = bytes.TrimSpace(data); len(data) == 0 || data[0] != '{' {
+		return nil, errors.New("event is not a JSON object")
+	}
+
+	event, err := spool.DecodeEvent(data)
+	if err != nil {
+		return nil, err
+	}
+	return convertNumbers(event).(common.MapStr), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case common.MapStr:
+		for k, elem := range v {
+			v[k] = convertNumbers(elem)
+		}
+		return v
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = convertNumbers(elem)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
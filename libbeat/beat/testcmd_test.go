@@ -0,0 +1,82 @@
+// +build !integration
+
+package beat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestProcessors(t *testing.T, cfg []map[string]interface{}) *processors.Processors {
+	c, err := common.NewConfigFrom(map[string]interface{}{"processors": cfg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := struct {
+		Processors processors.PluginConfig `config:"processors"`
+	}{}
+	if err := c.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	procs, err := processors.New(config.Processors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return procs
+}
+
+func TestSimulateProcessors(t *testing.T) {
+	procs := newTestProcessors(t, []map[string]interface{}{
+		{"drop_event": map[string]interface{}{
+			"when": map[string]interface{}{
+				"range": map[string]interface{}{"status": map[string]interface{}{"gte": 500}},
+			},
+		}},
+		{"drop_fields": map[string]interface{}{
+			"fields": []string{"secret"},
+		}},
+	})
+
+	in := `{"message": "ok", "status": 200, "secret": "x"}
+{"message": "fail", "status": 503}
+[{"message": "a", "status": 201}, {"message": "b", "status": 500}]
+`
+	var out bytes.Buffer
+	err := simulateProcessors(procs, strings.NewReader(in), &out)
+	assert.NoError(t, err)
+
+	expected := `event 1:
+{
+  "message": "ok",
+  "status": 200
+}
+event 2: dropped
+event 3:
+{
+  "message": "a",
+  "status": 201
+}
+event 4: dropped
+`
+	assert.Equal(t, expected, out.String())
+}
+
+func TestSimulateProcessorsInvalidEvent(t *testing.T) {
+	procs := newTestProcessors(t, nil)
+
+	for _, in := range []string{
+		`{"message": "ok"} {"message":`,
+		`"message"`,
+		`[{"message": "ok"}, 1]`,
+	} {
+		var out bytes.Buffer
+		err := simulateProcessors(procs, strings.NewReader(in), &out)
+		assert.Error(t, err, in)
+	}
+}
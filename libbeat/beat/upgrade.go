@@ -0,0 +1,58 @@
+package beat
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/upgrade"
+)
+
+// upgradeCommand is the name of the subcommand replacing the binary of the
+// Beat with the binary of another release.
+const upgradeCommand = "upgrade"
+
+// upgrade implements the upgrade subcommand. It downloads the release
+// artifact of the version given by -version, verifies its signature with the
+// public key given by -public_key, replaces the running binary and restarts
+// the service unless -restart=false is given.
+func (bc *instance) upgrade(args []string) error {
+	flags := flag.NewFlagSet(upgradeCommand, flag.ContinueOnError)
+	version := flags.String("version", "", "Version to upgrade to")
+	url := flags.String("url", upgrade.DefaultURL,
+		"URL template of the release artifact, served with its .sig signature")
+	publicKey := flags.String("public_key", "",
+		"PEM encoded public key to verify the artifact signature with")
+	restart := flags.Bool("restart", true, "Restart the service after the upgrade")
+	timeout := flags.Duration("timeout", 0, "Timeout of the downloads")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return GracefulExit
+		}
+		return err
+	}
+
+	logp.LogInit(logp.LOG_INFO, "", false, true, nil)
+
+	config := upgrade.Config{
+		Beat:      bc.data.Name,
+		Version:   *version,
+		URL:       *url,
+		PublicKey: *publicKey,
+		Timeout:   *timeout,
+	}
+	if *restart {
+		config.Restart = upgrade.RestartCommand(bc.data.Name)
+		if config.Restart == nil {
+			fmt.Fprintln(os.Stderr, "Restarting the service is not supported on this platform")
+		}
+	}
+
+	if err := upgrade.Run(config); err != nil {
+		return fmt.Errorf("upgrade failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Upgraded %v from version %v to %v\n",
+		bc.data.Name, bc.data.Version, *version)
+	return nil
+}
@@ -0,0 +1,108 @@
+// beatctl queries and controls a running beat via its control socket. The
+// control socket must be enabled in the beat configuration by setting
+// control.enabled to true.
+//
+// Usage:
+//
+//	beatctl -socket <path> <command> [args]
+//
+// Commands:
+//
+//	status               Print the beat name, version and uptime
+//	metrics              Print all metrics of the beat
+//	inputs               List the active inputs (e.g. prospectors and harvesters)
+//	reload               Reload the configuration
+//	log-level [<level>]  Print or change the log level
+//	debug-selectors [<selector>...]
+//	                     Print or change the debug selectors, "*" enables all
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/elastic/beats/libbeat/control"
+)
+
+var (
+	socket  = flag.String("socket", "", "Control socket of the beat (host:port on Windows)")
+	timeout = flag.Duration("timeout", 10*time.Second, "Request timeout")
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if *socket == "" || flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := run(control.NewClient(*socket, *timeout), flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s -socket <path> <command> [args]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  status               Print the beat name, version and uptime")
+	fmt.Fprintln(os.Stderr, "  metrics              Print all metrics of the beat")
+	fmt.Fprintln(os.Stderr, "  inputs               List the active inputs")
+	fmt.Fprintln(os.Stderr, "  reload               Reload the configuration")
+	fmt.Fprintln(os.Stderr, "  log-level [<level>]  Print or change the log level")
+	fmt.Fprintln(os.Stderr, "  debug-selectors [<selector>...]")
+	fmt.Fprintln(os.Stderr, "                       Print or change the debug selectors, \"*\" enables all")
+	fmt.Fprintln(os.Stderr, "\nOptions:")
+	flag.PrintDefaults()
+}
+
+func run(client *control.Client, command string, args []string) error {
+	switch command {
+	case "status":
+		return print(client.Status())
+	case "metrics":
+		return print(client.Metrics())
+	case "inputs":
+		return print(client.Inputs())
+	case "reload":
+		if err := client.Reload(); err != nil {
+			return err
+		}
+		fmt.Println("Configuration reloaded")
+		return nil
+	case "log-level":
+		if len(args) > 0 {
+			if err := client.SetLogging(control.Logging{Level: args[0]}); err != nil {
+				return err
+			}
+		}
+		return print(client.Logging())
+	case "debug-selectors":
+		if len(args) > 0 {
+			if err := client.SetLogging(control.Logging{Selectors: args}); err != nil {
+				return err
+			}
+		}
+		return print(client.Logging())
+	default:
+		return fmt.Errorf("unknown command: %v", command)
+	}
+}
+
+func print(v interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
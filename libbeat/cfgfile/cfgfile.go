@@ -16,6 +16,7 @@ var (
 	// be called prior to flags.Parse().
 	configfiles = flagArgList("c", "beat.yml", "Configuration file")
 	testConfig  = flag.Bool("configtest", false, "Test configuration and exit.")
+	strictPerms = flag.Bool("strict.perms", true, "Strict permission checking on config files")
 )
 
 // ChangeDefaultCfgfileFlag replaces the value and default value for the `-c`
@@ -51,8 +52,21 @@ func Read(out interface{}, path string) error {
 // this method reads from the configuration file specified by the '-c' command
 // line flag.
 func Load(path string) (*common.Config, error) {
+	files := configfiles.list
+	if path != "" {
+		files = []string{path}
+	}
+
+	if *strictPerms {
+		for _, file := range files {
+			if err := checkPermissions(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if path == "" {
-		return common.LoadFiles(configfiles.list...)
+		return common.LoadFiles(files...)
 	}
 	return common.LoadFile(path)
 }
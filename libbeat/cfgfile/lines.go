@@ -0,0 +1,139 @@
+package cfgfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-ucfg"
+)
+
+// Location returns the file and line a setting is defined in, searching the
+// configuration files passed via the '-c' flag. As later files overwrite
+// settings of earlier files, the files are searched in reverse order. Array
+// indices in path are ignored. Returns false if the setting was not found.
+func Location(path string) (string, int, bool) {
+	files := configfiles.list
+	for i := len(files) - 1; i >= 0; i-- {
+		content, err := ioutil.ReadFile(files[i])
+		if err != nil {
+			continue
+		}
+		if line := findLine(content, path); line > 0 {
+			return files[i], line, true
+		}
+	}
+	return "", 0, false
+}
+
+// AnnotateError adds the file and line of the setting the error was
+// reported for to configuration errors. Other errors are returned unchanged.
+func AnnotateError(err error) error {
+	path := errorPath(err)
+	if path == "" {
+		return err
+	}
+
+	file, line, found := Location(path)
+	if !found {
+		return err
+	}
+	return fmt.Errorf("%v (%v:%v)", err, file, line)
+}
+
+// accessingPath matches the setting name ucfg appends to its error messages.
+var accessingPath = regexp.MustCompile(`accessing '([^']+)'`)
+
+// errorPath returns the path of the setting a ucfg error was reported for.
+// Not all error types implement Path(), in which case the path is parsed from
+// the error message.
+func errorPath(err error) string {
+	ucfgErr, ok := err.(ucfg.Error)
+	if !ok {
+		return ""
+	}
+	if path := ucfgErr.Path(); path != "" {
+		return path
+	}
+	if m := accessingPath.FindStringSubmatch(ucfgErr.Message()); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+type yamlKey struct {
+	indent int
+	path   []string
+}
+
+// findLine does a line based scan of a YAML document, returning the line
+// number (starting at 1) of the key matching path. It supports nested
+// mappings, list items and dotted keys, which covers the configuration files
+// shipped with the beats. Returns 0 if the key was not found.
+func findLine(content []byte, path string) int {
+	var target []string
+	for _, part := range strings.Split(path, ".") {
+		if _, err := strconv.Atoi(part); err != nil {
+			target = append(target, part)
+		}
+	}
+
+	var stack []yamlKey
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+
+		// list items start a new mapping with keys indented past the dash
+		for strings.HasPrefix(trimmed, "- ") {
+			rest := strings.TrimLeft(trimmed[2:], " ")
+			indent += len(trimmed) - len(rest)
+			trimmed = rest
+		}
+
+		colon := strings.Index(trimmed, ":")
+		if colon <= 0 {
+			continue
+		}
+		key := strings.Trim(trimmed[:colon], `"' `)
+		if strings.ContainsAny(key, " {[") {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		var keyPath []string
+		if len(stack) > 0 {
+			keyPath = append(keyPath, stack[len(stack)-1].path...)
+		}
+		keyPath = append(keyPath, strings.Split(key, ".")...)
+		stack = append(stack, yamlKey{indent: indent, path: keyPath})
+
+		if equalPath(keyPath, target) {
+			return lineNo
+		}
+	}
+	return 0
+}
+
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
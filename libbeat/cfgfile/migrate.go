@@ -0,0 +1,327 @@
+package cfgfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Settings of the 1.x packetbeat configuration which moved into the
+// packetbeat namespace.
+var packetbeatSections = []string{"interfaces", "protocols", "procs", "runoptions"}
+
+// Settings of the 1.x configuration which have no equivalent in the current
+// format, with a hint for the user.
+var removedSettings = map[string]string{
+	"output.redis.reconnect_interval": "reconnects are handled by the output backoff",
+}
+
+// Metricsets of the metricbeat system module replacing the topbeat stats.
+var topbeatStats = map[string][]string{
+	"system":       {"cpu", "memory"},
+	"proc":         {"process"},
+	"filesystem":   {"filesystem", "fsstat"},
+	"cpu_per_core": {"core"},
+}
+
+// Stats collected by topbeat if not configured otherwise.
+var topbeatDefaultStats = map[string]bool{
+	"system":       true,
+	"proc":         true,
+	"filesystem":   true,
+	"cpu_per_core": false,
+}
+
+type migration struct {
+	cfg      map[string]interface{}
+	messages []string
+}
+
+// MigrateConfig converts a YAML configuration file in the 1.x format of
+// topbeat, packetbeat or filebeat into the current format. Topbeat
+// configurations are converted into a metricbeat configuration using the
+// system module. MigrateConfig returns the converted configuration and a
+// list of messages describing the settings that could not be migrated.
+//
+// Comments of the original file are not preserved.
+func MigrateConfig(content []byte) ([]byte, []string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, nil, fmt.Errorf("error parsing config: %v", err)
+	}
+
+	cfg, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		cfg = map[string]interface{}{}
+	}
+
+	m := &migration{cfg: cfg}
+	m.migrateShipper()
+	m.migratePacketbeat()
+	m.migrateTopbeat()
+	m.migrateFilters()
+	m.migrateOutputs()
+	m.reportRemoved()
+
+	out, err := yaml.Marshal(m.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(m.messages)
+	return out, m.messages, nil
+}
+
+// migrateShipper moves all settings of the shipper section to the top level.
+func (m *migration) migrateShipper() {
+	shipper, found := take(m.cfg, "shipper")
+	if !found {
+		return
+	}
+
+	settings, ok := shipper.(map[string]interface{})
+	if !ok {
+		if shipper != nil {
+			m.report("shipper", "expected a dictionary")
+		}
+		return
+	}
+
+	for name, value := range settings {
+		if _, exists := m.cfg[name]; exists {
+			m.report("shipper."+name, "setting is already defined at the top level")
+			continue
+		}
+		m.cfg[name] = value
+	}
+}
+
+// migratePacketbeat moves the packetbeat specific sections into the
+// packetbeat namespace.
+func (m *migration) migratePacketbeat() {
+	for _, name := range packetbeatSections {
+		if value, found := take(m.cfg, name); found {
+			m.namespace("packetbeat")[name] = value
+		}
+	}
+}
+
+// migrateTopbeat replaces the topbeat input section by the system module of
+// metricbeat.
+func (m *migration) migrateTopbeat() {
+	value, found := take(m.cfg, "input")
+	if !found {
+		return
+	}
+
+	input, ok := value.(map[string]interface{})
+	if !ok {
+		if value != nil {
+			m.report("input", "expected a dictionary")
+		}
+		return
+	}
+
+	module := map[string]interface{}{"module": "system"}
+	if period, found := take(input, "period"); found {
+		switch p := period.(type) {
+		case int, float64:
+			module["period"] = fmt.Sprintf("%vs", p)
+		default:
+			module["period"] = p
+		}
+	}
+	if procs, found := take(input, "procs"); found {
+		module["processes"] = procs
+	}
+
+	enabled := map[string]bool{}
+	for name, on := range topbeatDefaultStats {
+		enabled[name] = on
+	}
+	if value, found := take(input, "stats"); found {
+		stats, _ := value.(map[string]interface{})
+		for name, on := range stats {
+			if _, known := topbeatStats[name]; !known {
+				m.report("input.stats."+name, "unknown topbeat stats")
+				continue
+			}
+			b, ok := on.(bool)
+			if !ok {
+				m.report("input.stats."+name, "expected a boolean")
+				continue
+			}
+			enabled[name] = b
+		}
+	}
+
+	var metricsets []string
+	for name, on := range enabled {
+		if on {
+			metricsets = append(metricsets, topbeatStats[name]...)
+		}
+	}
+	sort.Strings(metricsets)
+	module["metricsets"] = metricsets
+
+	for name := range input {
+		m.report("input."+name, "no equivalent setting in the metricbeat system module")
+	}
+
+	mb := m.namespace("metricbeat")
+	modules, _ := mb["modules"].([]interface{})
+	mb["modules"] = append(modules, module)
+}
+
+// migrateFilters renames the filters section to processors.
+func (m *migration) migrateFilters() {
+	filters, found := take(m.cfg, "filters")
+	if !found {
+		return
+	}
+
+	if _, exists := m.cfg["processors"]; exists {
+		m.report("filters", "processors are already defined")
+		return
+	}
+	m.cfg["processors"] = filters
+}
+
+// migrateOutputs converts hosts given as single string and the host/port
+// settings of the outputs into lists of hosts.
+func (m *migration) migrateOutputs() {
+	outputs, _ := m.cfg["output"].(map[string]interface{})
+	for name, value := range outputs {
+		output, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if host, ok := output["hosts"].(string); ok {
+			output["hosts"] = []interface{}{host}
+		}
+
+		host, ok := output["host"].(string)
+		if !ok {
+			continue
+		}
+		if _, exists := output["hosts"]; exists {
+			m.report(fmt.Sprintf("output.%v.host", name), "hosts is already defined")
+			continue
+		}
+
+		delete(output, "host")
+		if port, found := take(output, "port"); found && name != "redis" {
+			host = fmt.Sprintf("%v:%v", host, port)
+		} else if found {
+			output["port"] = port
+		}
+		output["hosts"] = []interface{}{host}
+	}
+}
+
+// reportRemoved removes all settings without an equivalent in the current
+// format and reports them.
+func (m *migration) reportRemoved() {
+	names := make([]string, 0, len(removedSettings))
+	for name := range removedSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := strings.Split(name, ".")
+		parent, ok := lookup(m.cfg, path[:len(path)-1])
+		if !ok {
+			continue
+		}
+		if _, found := take(parent, path[len(path)-1]); found {
+			m.report(name, removedSettings[name])
+		}
+	}
+}
+
+func (m *migration) report(path, msg string) {
+	m.messages = append(m.messages, fmt.Sprintf("%v: %v", path, msg))
+}
+
+// namespace returns the top level dictionary of the given name, creating it
+// if missing.
+func (m *migration) namespace(name string) map[string]interface{} {
+	ns, ok := m.cfg[name].(map[string]interface{})
+	if !ok {
+		ns = map[string]interface{}{}
+		m.cfg[name] = ns
+	}
+	return ns
+}
+
+// take removes the setting name from cfg, returning its value.
+func take(cfg map[string]interface{}, name string) (interface{}, bool) {
+	value, found := cfg[name]
+	if found {
+		delete(cfg, name)
+	}
+	return value, found
+}
+
+func lookup(cfg map[string]interface{}, path []string) (map[string]interface{}, bool) {
+	for _, name := range path {
+		sub, ok := cfg[name].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cfg = sub
+	}
+	return cfg, true
+}
+
+// normalizeYAML converts the dictionaries returned by the YAML parser into
+// map[string]interface{}, expanding dotted keys into nested dictionaries.
+func normalizeYAML(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		cfg := map[string]interface{}{}
+		for k, v := range value {
+			putDotted(cfg, fmt.Sprint(k), normalizeYAML(v))
+		}
+		return cfg
+	case map[string]interface{}:
+		cfg := map[string]interface{}{}
+		for k, v := range value {
+			putDotted(cfg, k, normalizeYAML(v))
+		}
+		return cfg
+	case []interface{}:
+		for i := range value {
+			value[i] = normalizeYAML(value[i])
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+func putDotted(cfg map[string]interface{}, key string, value interface{}) {
+	path := strings.Split(key, ".")
+	for _, name := range path[:len(path)-1] {
+		sub, ok := cfg[name].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			cfg[name] = sub
+		}
+		cfg = sub
+	}
+
+	name := path[len(path)-1]
+	if old, ok := cfg[name].(map[string]interface{}); ok {
+		if sub, ok := value.(map[string]interface{}); ok {
+			for k, v := range sub {
+				putDotted(old, k, v)
+			}
+			return
+		}
+	}
+	cfg[name] = value
+}
@@ -0,0 +1,117 @@
+// +build !integration
+
+package cfgfile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func migrate(t *testing.T, in string) (map[string]interface{}, []string) {
+	out, messages, err := MigrateConfig([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return normalizeYAML(cfg).(map[string]interface{}), messages
+}
+
+func TestMigratePacketbeat(t *testing.T) {
+	cfg, messages := migrate(t, `
+interfaces:
+  device: any
+protocols:
+  dns:
+    ports: [53]
+shipper:
+  name: test
+  tags: [a, b]
+filters:
+  - drop_fields:
+      fields: [ip]
+output:
+  elasticsearch:
+    hosts: "localhost:9200"
+  logstash:
+    host: localhost
+    port: 5044
+  redis:
+    host: localhost
+    port: 6379
+    reconnect_interval: 1
+`)
+
+	assert.Equal(t, []string{"output.redis.reconnect_interval: reconnects are handled by the output backoff"}, messages)
+	assert.Equal(t, map[string]interface{}{
+		"packetbeat": map[string]interface{}{
+			"interfaces": map[string]interface{}{"device": "any"},
+			"protocols": map[string]interface{}{
+				"dns": map[string]interface{}{"ports": []interface{}{53}},
+			},
+		},
+		"name": "test",
+		"tags": []interface{}{"a", "b"},
+		"processors": []interface{}{
+			map[string]interface{}{
+				"drop_fields": map[string]interface{}{"fields": []interface{}{"ip"}},
+			},
+		},
+		"output": map[string]interface{}{
+			"elasticsearch": map[string]interface{}{"hosts": []interface{}{"localhost:9200"}},
+			"logstash":      map[string]interface{}{"hosts": []interface{}{"localhost:5044"}},
+			"redis": map[string]interface{}{
+				"hosts": []interface{}{"localhost"},
+				"port":  6379,
+			},
+		},
+	}, cfg)
+}
+
+func TestMigrateTopbeat(t *testing.T) {
+	cfg, messages := migrate(t, `
+input:
+  period: 10
+  procs: [".*"]
+  stats:
+    system: true
+    proc: false
+    cpu_per_core: true
+    unknown: true
+output.elasticsearch:
+  hosts: ["localhost:9200"]
+`)
+
+	assert.Equal(t, []string{"input.stats.unknown: unknown topbeat stats"}, messages)
+	assert.Equal(t, map[string]interface{}{
+		"metricbeat": map[string]interface{}{
+			"modules": []interface{}{
+				map[string]interface{}{
+					"module":     "system",
+					"period":     "10s",
+					"processes":  []interface{}{".*"},
+					"metricsets": []interface{}{"core", "cpu", "filesystem", "fsstat", "memory"},
+				},
+			},
+		},
+		"output": map[string]interface{}{
+			"elasticsearch": map[string]interface{}{"hosts": []interface{}{"localhost:9200"}},
+		},
+	}, cfg)
+}
+
+func TestMigrateShipperConflict(t *testing.T) {
+	cfg, messages := migrate(t, `
+name: top
+shipper:
+  name: shipper
+`)
+
+	assert.Equal(t, []string{"shipper.name: setting is already defined at the top level"}, messages)
+	assert.Equal(t, map[string]interface{}{"name": "top"}, cfg)
+}
@@ -0,0 +1,30 @@
+// +build !windows
+
+package cfgfile
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkPermissions verifies that the configuration file at path is a regular
+// file and can not be modified by other users. A configuration file writable
+// by everyone allows any local user to change the behavior of the beat, which
+// often runs with elevated privileges.
+func checkPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("config file %s is not a regular file", path)
+	}
+
+	if perm := info.Mode().Perm(); perm&0002 != 0 {
+		return fmt.Errorf("config file %s must not be writable by others "+
+			"(permissions are %v). To disable this check, run with -strict.perms=false",
+			path, perm)
+	}
+	return nil
+}
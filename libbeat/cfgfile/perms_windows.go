@@ -0,0 +1,7 @@
+package cfgfile
+
+// checkPermissions is a no-op on Windows, where file access is controlled by
+// ACLs not reflected in the file mode.
+func checkPermissions(path string) error {
+	return nil
+}
@@ -0,0 +1,198 @@
+package cfgfile
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/go-ucfg"
+)
+
+// Schema describes the settings supported by a configuration. It is built
+// from struct types using the same `config` tags used to unpack the
+// configuration, and is used to detect unknown (e.g. misspelled) settings.
+type Schema struct {
+	root *schemaNode
+}
+
+type schemaNode struct {
+	// open nodes accept any sub-setting (maps, *common.Config, custom unpackers)
+	open     bool
+	list     bool
+	children map[string]*schemaNode
+}
+
+var (
+	tConfig   = reflect.TypeOf(common.Config{})
+	tUnpacker = reflect.TypeOf((*ucfg.Unpacker)(nil)).Elem()
+	tRegexp   = reflect.TypeOf(regexp.Regexp{})
+	tDuration = reflect.TypeOf(time.Duration(0))
+)
+
+// NewSchema creates a schema accepting all settings described by any of the
+// given struct values.
+func NewSchema(schemas ...interface{}) *Schema {
+	root := &schemaNode{children: map[string]*schemaNode{}}
+	for _, s := range schemas {
+		addStruct(root, reflect.TypeOf(s), map[reflect.Type]bool{})
+	}
+	return &Schema{root: root}
+}
+
+// CheckUnknownFields returns the full path of every setting in cfg not known
+// to the schema, sorted by name. If ignoreTopLevel is set, unknown top level
+// settings are not reported, such that only the namespaces known to the
+// schema are checked.
+func (s *Schema) CheckUnknownFields(cfg *common.Config, ignoreTopLevel bool) []string {
+	var unknown []string
+	checkNode(s.root, cfg, "", ignoreTopLevel, &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func checkNode(node *schemaNode, cfg *common.Config, path string, ignoreUnknown bool, unknown *[]string) {
+	if node.open {
+		return
+	}
+
+	for _, name := range cfg.GetFields() {
+		fullPath := name
+		if path != "" {
+			fullPath = path + "." + name
+		}
+
+		child, exists := node.children[name]
+		if !exists {
+			if !ignoreUnknown {
+				*unknown = append(*unknown, fullPath)
+			}
+			continue
+		}
+
+		if child.open || len(child.children) == 0 {
+			continue
+		}
+
+		if !child.list {
+			if sub, err := cfg.Child(name, -1); err == nil {
+				checkNode(child, sub, fullPath, false, unknown)
+			}
+			continue
+		}
+
+		// CountField does not report the length of lists, so walk the
+		// list until an index is missing.
+		for i := 0; ; i++ {
+			sub, err := cfg.Child(name, i)
+			if err != nil {
+				break
+			}
+			checkNode(child, sub, fmt.Sprintf("%v.%v", fullPath, i), false, unknown)
+		}
+	}
+}
+
+// addStruct adds the settings of the struct type t to node. parents contains
+// the struct types t is nested in. Settings of recursive types (e.g.
+// conditions) are not checked below the first recursion.
+func addStruct(node *schemaNode, t reflect.Type, parents map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if parents[t] {
+		node.open = true
+		return
+	}
+	parents[t] = true
+	defer delete(parents, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("config"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+
+		inline := false
+		for _, opt := range tag[1:] {
+			if opt == "inline" || opt == "squash" {
+				inline = true
+			}
+		}
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStruct(node, ft, parents)
+			} else {
+				node.open = true
+			}
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		// dotted names describe nested settings
+		parts := strings.Split(name, ".")
+		parent := node
+		for _, part := range parts[:len(parts)-1] {
+			parent = parent.child(part)
+		}
+		addType(parent.child(parts[len(parts)-1]), field.Type, parents)
+	}
+}
+
+func addType(node *schemaNode, t reflect.Type, parents map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == tConfig || t.Implements(tUnpacker) || reflect.PtrTo(t).Implements(tUnpacker) {
+		node.open = true
+		return
+	}
+	if t == tRegexp || t == tDuration {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		addStruct(node, t, parents)
+	case reflect.Map, reflect.Interface:
+		node.open = true
+	case reflect.Slice, reflect.Array:
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		node.list = true
+		addType(node, elem, parents)
+	}
+}
+
+func (n *schemaNode) child(name string) *schemaNode {
+	if n.children == nil {
+		n.children = map[string]*schemaNode{}
+	}
+	c, exists := n.children[name]
+	if !exists {
+		c = &schemaNode{}
+		n.children[name] = c
+	}
+	return c
+}
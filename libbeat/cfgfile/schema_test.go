@@ -0,0 +1,181 @@
+// +build !integration
+
+package cfgfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testShipperConfig struct {
+	common.EventMetadata `config:",inline"`
+	Name                 string `config:"name"`
+	QueueSize            *int   `config:"queue_size"`
+}
+
+type testBeatConfig struct {
+	Shipper testShipperConfig         `config:",inline"`
+	Output  map[string]*common.Config `config:"output"`
+	Logging struct {
+		Level string `config:"level"`
+		Files struct {
+			Path      string        `config:"path"`
+			Rotate    time.Duration `config:"rotate"`
+			Selectors []string      `config:"selectors"`
+		} `config:"files"`
+	} `config:"logging"`
+	Modules []struct {
+		Module string           `config:"module"`
+		Extra  []*common.Config `config:"extra"`
+	} `config:"test.modules"`
+}
+
+func TestSchemaUnknownFields(t *testing.T) {
+	cfg, err := common.NewConfigWithYAML([]byte(`
+name: shipper
+tags: ["a"]
+fields.env: prod
+queue_size: 10
+quee_size: 10
+output.elasticsearch.anything: true
+logging.level: debug
+logging.files.pth: /var/log
+test.modules:
+  - module: system
+    extra: [{any: 1}]
+  - modul: apache
+unknown.section: 1
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := NewSchema(testBeatConfig{})
+	assert.Equal(t, []string{
+		"logging.files.pth",
+		"quee_size",
+		"test.modules.1.modul",
+		"unknown",
+	}, schema.CheckUnknownFields(cfg, false))
+
+	assert.Equal(t, []string{
+		"logging.files.pth",
+		"test.modules.1.modul",
+	}, schema.CheckUnknownFields(cfg, true))
+}
+
+type testCondition struct {
+	Equals map[string]interface{} `config:"equals"`
+	OR     []testCondition        `config:"or"`
+	NOT    *testCondition         `config:"not"`
+}
+
+func TestSchemaRecursiveTypes(t *testing.T) {
+	cfg, err := common.NewConfigWithYAML([]byte(`
+when:
+  or:
+    - equals.type: audit
+    - not.equals.type: log
+  eqals.type: audit
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := NewSchema(struct {
+		When testCondition `config:"when"`
+	}{})
+	assert.Equal(t, []string{"when.eqals"}, schema.CheckUnknownFields(cfg, false))
+}
+
+func TestFindLine(t *testing.T) {
+	content := []byte(`# comment
+name: shipper
+logging:
+  level: debug
+  files:
+    path: /var/log
+test.modules:
+  - module: system
+    period: 10s
+  - module: apache
+    hosts: ["localhost"]
+output.elasticsearch:
+  hosts: ["localhost:9200"]
+`)
+
+	tests := map[string]int{
+		"name":                        2,
+		"logging.level":               4,
+		"logging.files.path":          6,
+		"test.modules.0.period":       9,
+		"test.modules.1.hosts":        11,
+		"output.elasticsearch.hosts":  13,
+		"output.elasticsearch":        12,
+		"logging.files.doesnotexists": 0,
+	}
+	for path, line := range tests {
+		assert.Equal(t, line, findLine(content, path), path)
+	}
+}
+
+func TestStrictPermissions(t *testing.T) {
+	if filepath.Separator != '/' {
+		t.Skip("permissions are not checked on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "cfgfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "beat.yml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("name: test\n"), 0600))
+	assert.Nil(t, os.Chmod(path, 0600))
+
+	_, err = Load(path)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.Chmod(path, 0666))
+	_, err = Load(path)
+	assert.NotNil(t, err)
+
+	*strictPerms = false
+	defer func() { *strictPerms = true }()
+	_, err = Load(path)
+	assert.Nil(t, err)
+}
+
+func TestAnnotateError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfgfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "beat.yml")
+	content := "name: test\nlogging:\n  level: info\n  files:\n    rotate: abc\n"
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	list := configfiles.list
+	configfiles.list = []string{path}
+	defer func() { configfiles.list = list }()
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := testBeatConfig{}
+	err = AnnotateError(cfg.Unpack(&config))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), path+":5")
+	}
+}
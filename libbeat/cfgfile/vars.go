@@ -0,0 +1,155 @@
+package cfgfile
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// varsKey is the setting containing the variables of a module or input
+// configuration.
+const varsKey = "vars"
+
+// varRefRegexp matches a string consisting of a single variable reference.
+var varRefRegexp = regexp.MustCompile(`^\{\{\s*\.(\w+)\s*\}\}$`)
+
+// ExpandVars renders the {{.name}} templates in the string settings of the
+// given module or input configurations with their variables.
+//
+// The variables of a configuration are set in its vars setting, which is
+// removed from the configuration. If vars is a list, the configuration is
+// expanded into one configuration per list element, such that a single
+// configuration can be used for many similar services. The global variables
+// are available in all configurations and are overwritten by the variables
+// of a configuration.
+//
+// A setting that consists of a single variable reference is replaced by the
+// value of the variable, which is not required to be a string. Configurations
+// without variables are returned unchanged.
+func ExpandVars(global map[string]interface{}, configs []*common.Config) ([]*common.Config, error) {
+	var expanded []*common.Config
+	for i, config := range configs {
+		if !config.HasField(varsKey) && len(global) == 0 {
+			expanded = append(expanded, config)
+			continue
+		}
+
+		var settings map[string]interface{}
+		if err := config.Unpack(&settings); err != nil {
+			return nil, err
+		}
+
+		varSets, err := varSets(settings[varsKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in configuration %d: %v", varsKey, i, err)
+		}
+		delete(settings, varsKey)
+
+		for _, vars := range varSets {
+			all := map[string]interface{}{}
+			for k, v := range global {
+				all[k] = v
+			}
+			for k, v := range vars {
+				all[k] = v
+			}
+
+			rendered, err := renderVars(settings, all)
+			if err != nil {
+				return nil, fmt.Errorf("error expanding %s in configuration %d: %v", varsKey, i, err)
+			}
+
+			c, err := common.NewConfigFrom(rendered)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, c)
+		}
+	}
+	return expanded, nil
+}
+
+// varSets returns the sets of variables defined by the vars setting, which is
+// either a single dictionary or a list of dictionaries.
+func varSets(v interface{}) ([]map[string]interface{}, error) {
+	switch v := v.(type) {
+	case nil:
+		return []map[string]interface{}{nil}, nil
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, nil
+	case []interface{}:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("empty list")
+		}
+		sets := make([]map[string]interface{}, 0, len(v))
+		for _, elem := range v {
+			vars, ok := elem.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("list elements must be dictionaries")
+			}
+			sets = append(sets, vars)
+		}
+		return sets, nil
+	default:
+		return nil, fmt.Errorf("must be a dictionary or a list of dictionaries")
+	}
+}
+
+// renderVars returns a copy of v in which all string values are rendered as
+// templates with the given variables.
+func renderVars(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return renderString(v, vars)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			rendered, err := renderVars(elem, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			rendered, err := renderVars(elem, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func renderString(s string, vars map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	if m := varRefRegexp.FindStringSubmatch(s); m != nil {
+		value, found := vars[m[1]]
+		if !found {
+			return nil, fmt.Errorf("undefined variable %s", m[1])
+		}
+		return value, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,137 @@
+// +build !integration
+
+package cfgfile
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func expandVars(t *testing.T, global map[string]interface{}, yaml string) ([]map[string]interface{}, error) {
+	config, err := common.NewConfigWithYAML([]byte(yaml), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var configs struct {
+		Modules []*common.Config `config:"modules"`
+	}
+	if err := config.Unpack(&configs); err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, err := ExpandVars(global, configs.Modules)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []map[string]interface{}
+	for _, c := range expanded {
+		var m map[string]interface{}
+		if err := c.Unpack(&m); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func TestExpandVars(t *testing.T) {
+	configs, err := expandVars(t, map[string]interface{}{"period": "10s"}, `
+modules:
+- module: redis
+  vars:
+    - {name: cache, port: 6379}
+    - {name: sessions, port: 6380}
+  hosts: ["localhost:{{.port}}"]
+  period: "{{.period}}"
+  fields:
+    service: "{{.name}}"
+- module: system
+  metricsets: [cpu]
+  period: "{{.period}}"
+`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"module": "redis",
+			"hosts":  []interface{}{"localhost:6379"},
+			"period": "10s",
+			"fields": map[string]interface{}{"service": "cache"},
+		},
+		{
+			"module": "redis",
+			"hosts":  []interface{}{"localhost:6380"},
+			"period": "10s",
+			"fields": map[string]interface{}{"service": "sessions"},
+		},
+		{
+			"module":     "system",
+			"metricsets": []interface{}{"cpu"},
+			"period":     "10s",
+		},
+	}, configs)
+}
+
+func TestExpandVarsValue(t *testing.T) {
+	configs, err := expandVars(t, nil, `
+modules:
+- input_type: log
+  vars:
+    paths: [/var/log/a.log, /var/log/b.log]
+    type: app
+  paths: "{{ .paths }}"
+  document_type: "{{.type}}-log"
+`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"input_type":    "log",
+			"paths":         []interface{}{"/var/log/a.log", "/var/log/b.log"},
+			"document_type": "app-log",
+		},
+	}, configs)
+}
+
+func TestExpandVarsErrors(t *testing.T) {
+	_, err := expandVars(t, nil, `
+modules:
+- module: redis
+  vars: {port: 6379}
+  hosts: ["{{.host}}:{{.port}}"]
+`)
+	assert.Error(t, err)
+
+	_, err = expandVars(t, nil, `
+modules:
+- module: redis
+  vars: {port: 6379}
+  hosts: ["localhost:{{.port"]
+`)
+	assert.Error(t, err)
+
+	_, err = expandVars(t, nil, `
+modules:
+- module: redis
+  vars: [6379]
+`)
+	assert.Error(t, err)
+}
+
+func TestExpandVarsUnchanged(t *testing.T) {
+	config, err := common.NewConfigWithYAML([]byte(`hosts: ["{{.host}}"]`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expanded, err := ExpandVars(nil, []*common.Config{config})
+	assert.NoError(t, err)
+	assert.Equal(t, []*common.Config{config}, expanded)
+}
@@ -0,0 +1,161 @@
+// Package checkpoint persists the state of inputs once the events read by the
+// input have been acknowledged by the outputs. Inputs use it to resume reading
+// after a restart where the last acknowledged event was read, without writing
+// their own registry.
+//
+// An input stores its state under a key, for example a topic partition or an
+// object name, and passes the signaler returned by Checkpoint with the events
+// read up to that state to the publisher client:
+//
+//  client.PublishEvents(events,
+//      publisher.Guaranteed,
+//      publisher.Signal(store.Checkpoint(key, state)))
+//
+// On startup the input reads the persisted state with Get.
+package checkpoint
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/statestore"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	checkpointUpdates = expvar.NewInt("libbeat.checkpoint.updates")
+	checkpointErrors  = expvar.NewInt("libbeat.checkpoint.errors")
+)
+
+// Store persists the acknowledged states of inputs in a state store.
+type Store struct {
+	mutex   sync.Mutex
+	store   *statestore.Store
+	owned   bool                     // store is closed by Close
+	pending map[string][]*checkpoint // unacknowledged checkpoints per key in publish order
+}
+
+type checkpoint struct {
+	state    json.RawMessage
+	response op.SignalResponse // 0 until acknowledged
+}
+
+// New creates a checkpoint store persisting the states in store, for example
+// a store of the state store registry of the Beat.
+func New(store *statestore.Store) *Store {
+	return &Store{
+		store:   store,
+		pending: map[string][]*checkpoint{},
+	}
+}
+
+// Open opens a checkpoint store persisted in the local file under path. A
+// relative path is resolved in the data path.
+func Open(path string) (*Store, error) {
+	backend, err := statestore.NewLocalBackend(paths.Resolve(paths.Data, path))
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(statestore.New(backend))
+	s.owned = true
+	return s, nil
+}
+
+// Close closes the state store if it was opened by Open.
+func (s *Store) Close() error {
+	if !s.owned {
+		return nil
+	}
+	return s.store.Close()
+}
+
+// Get decodes the persisted state of key into state. It returns false if no
+// state has been persisted for key.
+func (s *Store) Get(key string, state interface{}) (bool, error) {
+	var found bool
+	err := s.store.View(func(tx *statestore.Tx) error {
+		var err error
+		found, err = tx.Get(key, state)
+		return err
+	})
+	return found, err
+}
+
+// Checkpoint returns a signaler persisting state under key once the events
+// the signaler is passed with are acknowledged. The state is encoded when
+// Checkpoint is called, such that it can be modified afterwards.
+//
+// The checkpoints of a key are persisted in the order Checkpoint was called.
+// A state is only persisted after all earlier checkpoints of the key have been
+// acknowledged. Events failed to be published are acknowledged as well, as
+// they are not published again. If the events are canceled, the state and all
+// later states of the key are not persisted, such that the events are read
+// again after a restart.
+func (s *Store) Checkpoint(key string, state interface{}) op.Signaler {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		logp.Err("Failed to encode checkpoint of %s: %v", key, err)
+		checkpointErrors.Add(1)
+		return nil
+	}
+
+	cp := &checkpoint{state: raw}
+
+	s.mutex.Lock()
+	s.pending[key] = append(s.pending[key], cp)
+	s.mutex.Unlock()
+
+	return op.SignalCallback(func(response op.SignalResponse) {
+		s.ack(key, cp, response)
+	})
+}
+
+func (s *Store) ack(key string, cp *checkpoint, response op.SignalResponse) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cp.response = response
+
+	pending := s.pending[key]
+	var state json.RawMessage
+	for len(pending) > 0 {
+		head := pending[0]
+		if head.response == 0 || head.response == op.SignalCanceled {
+			break
+		}
+		state = head.state
+		pending = pending[1:]
+	}
+	if len(pending) == 0 {
+		delete(s.pending, key)
+	} else {
+		s.pending[key] = pending
+	}
+
+	if state == nil {
+		return
+	}
+
+	err := s.store.Update(func(tx *statestore.Tx) error {
+		return tx.Put(key, state)
+	})
+	if err != nil {
+		logp.Err("Failed to persist checkpoint of %s: %v", key, err)
+		checkpointErrors.Add(1)
+		return
+	}
+	checkpointUpdates.Add(1)
+}
+
+// Delete removes the persisted state of key, for example if the input does
+// not read from the source of the key anymore.
+func (s *Store) Delete(key string) error {
+	return s.store.Update(func(tx *statestore.Tx) error {
+		return tx.Delete(key)
+	})
+}
@@ -0,0 +1,92 @@
+// +build !integration
+
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+)
+
+type testState struct {
+	Offset int64 `json:"offset"`
+}
+
+func tempStore(t *testing.T) (*Store, string, func()) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "checkpoint")
+	s, err := Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return s, path, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func getOffset(t *testing.T, s *Store, key string) int64 {
+	var state testState
+	found, err := s.Get(key, &state)
+	assert.NoError(t, err)
+	if !found {
+		return -1
+	}
+	return state.Offset
+}
+
+func TestCheckpointInOrder(t *testing.T) {
+	s, path, cleanup := tempStore(t)
+	defer cleanup()
+
+	first := s.Checkpoint("a", testState{Offset: 10})
+	second := s.Checkpoint("a", testState{Offset: 20})
+	other := s.Checkpoint("b", testState{Offset: 5})
+
+	// acknowledgments of later events are not persisted before all earlier
+	// events are acknowledged
+	op.SigCompleted(second)
+	assert.Equal(t, int64(-1), getOffset(t, s, "a"))
+
+	op.SigCompleted(first)
+	assert.Equal(t, int64(20), getOffset(t, s, "a"))
+	assert.Equal(t, int64(-1), getOffset(t, s, "b"))
+
+	op.SigFailed(other, nil)
+	assert.Equal(t, int64(5), getOffset(t, s, "b"))
+
+	assert.NoError(t, s.Delete("b"))
+	assert.Equal(t, int64(-1), getOffset(t, s, "b"))
+
+	// reopen the store from disk
+	s.Close()
+	reopened, err := Open(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer reopened.Close()
+	assert.Equal(t, int64(20), getOffset(t, reopened, "a"))
+	assert.Equal(t, int64(-1), getOffset(t, reopened, "b"))
+}
+
+func TestCheckpointCanceled(t *testing.T) {
+	s, _, cleanup := tempStore(t)
+	defer cleanup()
+
+	op.SigCompleted(s.Checkpoint("a", testState{Offset: 10}))
+	canceled := s.Checkpoint("a", testState{Offset: 20})
+	later := s.Checkpoint("a", testState{Offset: 30})
+
+	op.SigCompleted(later)
+	canceled.Canceled()
+	assert.Equal(t, int64(10), getOffset(t, s, "a"))
+}
@@ -0,0 +1,262 @@
+// Package cgroup reads the CPU and memory limits and usage of the control
+// group of a process. Both the v1 (per controller hierarchies) and the v2
+// (unified hierarchy) layouts are supported.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when no cgroup is found for the process, for example
+// because cgroups are not supported by the operating system.
+var ErrNotFound = errors.New("cgroup not found")
+
+// unlimited is the smallest value that is treated as no memory limit in v1.
+// The kernel reports the largest page aligned int64 if no limit is set.
+const unlimited = 1 << 62
+
+// Stats contains the limits and usage of a cgroup. Limits are 0 if no limit
+// is set.
+type Stats struct {
+	Version int    // 1 or 2
+	Path    string // path of the cgroup within the hierarchy
+
+	CPULimit float64 // maximum number of CPUs the cgroup can use
+
+	MemoryLimit uint64 // in bytes
+	MemoryUsage uint64 // in bytes
+}
+
+// SelfStats returns the stats of the cgroup of the current process.
+func SelfStats() (*Stats, error) {
+	return ReadStats("/proc/self")
+}
+
+// ReadStats returns the stats of the cgroup of the process with the given
+// proc directory, like /proc/self.
+func ReadStats(procDir string) (*Stats, error) {
+	cgroups, err := readCgroups(filepath.Join(procDir, "cgroup"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	mounts, err := readMounts(filepath.Join(procDir, "mountinfo"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// Use the v1 controllers if they are mounted, the unified hierarchy is
+	// only used for all controllers on pure v2 systems.
+	cpuDir, cpuOK := mounts.dir("cpu", cgroups)
+	memoryDir, memoryOK := mounts.dir("memory", cgroups)
+	if cpuOK || memoryOK {
+		stats := &Stats{Version: 1, Path: cgroups["memory"]}
+		if !memoryOK {
+			stats.Path = cgroups["cpu"]
+		}
+		if cpuOK {
+			stats.CPULimit = readCPULimitV1(cpuDir)
+		}
+		if memoryOK {
+			stats.MemoryLimit, _ = readUint(filepath.Join(memoryDir, "memory.limit_in_bytes"))
+			if stats.MemoryLimit >= unlimited {
+				stats.MemoryLimit = 0
+			}
+			stats.MemoryUsage, _ = readUint(filepath.Join(memoryDir, "memory.usage_in_bytes"))
+		}
+		return stats, nil
+	}
+
+	if dir, ok := mounts.dir("", cgroups); ok {
+		stats := &Stats{Version: 2, Path: cgroups[""]}
+		stats.CPULimit = readCPULimitV2(dir)
+		stats.MemoryLimit, _ = readUint(filepath.Join(dir, "memory.max"))
+		stats.MemoryUsage, _ = readUint(filepath.Join(dir, "memory.current"))
+		return stats, nil
+	}
+
+	return nil, ErrNotFound
+}
+
+// readCgroups returns the path of the cgroup of each controller listed in the
+// cgroup file of a process. The path in the unified hierarchy is indexed by
+// the empty string.
+func readCgroups(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cgroups := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			cgroups[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			cgroups[controller] = fields[2]
+		}
+	}
+	return cgroups, scanner.Err()
+}
+
+// mount is a cgroup file system mounted at mountPoint, which contains the
+// hierarchy below root.
+type mount struct {
+	root        string
+	mountPoint  string
+	controllers []string
+}
+
+type mounts []mount
+
+// readMounts returns the cgroup file systems listed in the mountinfo file of
+// a process.
+func readMounts(path string) (mounts, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ms mounts
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /root /mnt/point rw,noatime master:1 - cgroup cgroup rw,memory
+		parts := strings.SplitN(scanner.Text(), " - ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fields := strings.Fields(parts[0])
+		fsFields := strings.Fields(parts[1])
+		if len(fields) < 5 || len(fsFields) < 3 {
+			continue
+		}
+
+		m := mount{root: fields[3], mountPoint: fields[4]}
+		switch fsFields[0] {
+		case "cgroup":
+			for _, opt := range strings.Split(fsFields[2], ",") {
+				if opt == "cpu" || opt == "memory" {
+					m.controllers = append(m.controllers, opt)
+				}
+			}
+			if len(m.controllers) == 0 {
+				continue
+			}
+		case "cgroup2":
+			m.controllers = []string{""}
+		default:
+			continue
+		}
+		ms = append(ms, m)
+	}
+	return ms, scanner.Err()
+}
+
+// dir returns the directory of the cgroup of the given controller. The empty
+// controller selects the unified hierarchy.
+func (ms mounts) dir(controller string, cgroups map[string]string) (string, bool) {
+	path, found := cgroups[controller]
+	if !found {
+		return "", false
+	}
+
+	for _, m := range ms {
+		for _, c := range m.controllers {
+			if c != controller {
+				continue
+			}
+			// Within a cgroup namespace, the root of the mount is the cgroup
+			// of the namespace and paths are relative to it.
+			rel := path
+			if m.root != "/" && strings.HasPrefix(path, m.root) {
+				rel = strings.TrimPrefix(path, m.root)
+			}
+			dir := filepath.Join(m.mountPoint, rel)
+			if _, err := os.Stat(dir); err != nil {
+				// The cgroup path is not visible in some containers, which
+				// mount their own cgroup at the mount point.
+				dir = m.mountPoint
+			}
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// readCPULimitV1 returns the CPU limit set by the CFS quota and period.
+func readCPULimitV1(dir string) float64 {
+	quota, err := readInt(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil || quota <= 0 {
+		return 0
+	}
+	period, err := readInt(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return float64(quota) / float64(period)
+}
+
+// readCPULimitV2 returns the CPU limit set in cpu.max, which contains the
+// quota, or max if no limit is set, and the period.
+func readCPULimitV2(dir string) float64 {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || period <= 0 {
+		return 0
+	}
+	return quota / period
+}
+
+// readUint reads a file containing a single unsigned integer. The v2 value
+// max, meaning no limit, is returned as 0.
+func readUint(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(data))
+	if s == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func readInt(path string) (int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,125 @@
+// +build !integration
+
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadStatsV1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cgroupPath := "/docker/0123456789ab"
+	writeFiles(t, dir, map[string]string{
+		"proc/cgroup": "11:memory:" + cgroupPath + "\n" +
+			"4:cpu,cpuacct:" + cgroupPath + "\n" +
+			"1:name=systemd:" + cgroupPath + "\n" +
+			"0::/system.slice/docker.service\n",
+		"proc/mountinfo": fmt.Sprintf(
+			"25 20 0:22 / %s/sys/fs/cgroup/memory rw,nosuid - cgroup cgroup rw,memory\n"+
+				"26 20 0:23 / %s/sys/fs/cgroup/cpu,cpuacct rw,nosuid - cgroup cgroup rw,cpu,cpuacct\n"+
+				"27 20 0:24 / %s/sys/fs/cgroup/unified rw,nosuid - cgroup2 cgroup2 rw\n",
+			dir, dir, dir),
+		"sys/fs/cgroup/memory" + cgroupPath + "/memory.limit_in_bytes":  "536870912\n",
+		"sys/fs/cgroup/memory" + cgroupPath + "/memory.usage_in_bytes":  "134217728\n",
+		"sys/fs/cgroup/cpu,cpuacct" + cgroupPath + "/cpu.cfs_quota_us":  "150000\n",
+		"sys/fs/cgroup/cpu,cpuacct" + cgroupPath + "/cpu.cfs_period_us": "100000\n",
+	})
+
+	stats, err := ReadStats(filepath.Join(dir, "proc"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &Stats{
+		Version:     1,
+		Path:        cgroupPath,
+		CPULimit:    1.5,
+		MemoryLimit: 512 * 1024 * 1024,
+		MemoryUsage: 128 * 1024 * 1024,
+	}, stats)
+}
+
+func TestReadStatsV1Unlimited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Within a cgroup namespace the cgroup is mounted at the mount point.
+	writeFiles(t, dir, map[string]string{
+		"proc/cgroup": "11:memory:/kubepods/pod1/abc\n4:cpu,cpuacct:/kubepods/pod1/abc\n",
+		"proc/mountinfo": fmt.Sprintf(
+			"25 20 0:22 /kubepods/pod1/abc %s/memory rw - cgroup cgroup rw,memory\n"+
+				"26 20 0:23 /kubepods/pod1/abc %s/cpu rw - cgroup cgroup rw,cpu,cpuacct\n",
+			dir, dir),
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		"memory/memory.usage_in_bytes": "1048576\n",
+		"cpu/cpu.cfs_quota_us":         "-1\n",
+		"cpu/cpu.cfs_period_us":        "100000\n",
+	})
+
+	stats, err := ReadStats(filepath.Join(dir, "proc"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &Stats{
+		Version:     1,
+		Path:        "/kubepods/pod1/abc",
+		MemoryUsage: 1024 * 1024,
+	}, stats)
+}
+
+func TestReadStatsV2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"proc/cgroup":    "0::/system.slice/filebeat.service\n",
+		"proc/mountinfo": fmt.Sprintf("30 24 0:26 / %s/cgroup rw,nosuid - cgroup2 cgroup2 rw\n", dir),
+		"cgroup/system.slice/filebeat.service/cpu.max":        "50000 100000\n",
+		"cgroup/system.slice/filebeat.service/memory.max":     "max\n",
+		"cgroup/system.slice/filebeat.service/memory.current": "2097152\n",
+	})
+
+	stats, err := ReadStats(filepath.Join(dir, "proc"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &Stats{
+		Version:     2,
+		Path:        "/system.slice/filebeat.service",
+		CPULimit:    0.5,
+		MemoryUsage: 2 * 1024 * 1024,
+	}, stats)
+}
+
+func TestReadStatsNotFound(t *testing.T) {
+	_, err := ReadStats("/does/not/exist")
+	assert.Equal(t, ErrNotFound, err)
+}
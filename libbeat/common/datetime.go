@@ -19,6 +19,11 @@ func (t Time) MarshalJSON() ([]byte, error) {
 	return json.Marshal(time.Time(t).UTC().Format(TsLayout))
 }
 
+// String returns the time in the TsLayout format.
+func (t Time) String() string {
+	return time.Time(t).UTC().Format(TsLayout)
+}
+
 // UnmarshalJSON implements js.Unmarshaler interface.
 // The time is expected to be a quoted string in TsLayout
 // format.
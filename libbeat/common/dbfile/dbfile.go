@@ -0,0 +1,281 @@
+// Package dbfile loads enrichment databases, like the GeoIP database or the
+// public suffix list, from local files. The files can be validated against a
+// checksum and are reloaded periodically if they change, such that the
+// databases can be updated without a restart or a new release, for example in
+// air-gapped deployments.
+package dbfile
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	reloads        = expvar.NewInt("libbeat.dbfile.reloads")
+	reloadFailures = expvar.NewInt("libbeat.dbfile.reload_failures")
+)
+
+// Config configures a database file.
+type Config struct {
+	// Path of the database file.
+	Path string `config:"path"`
+
+	// Checksum is the expected checksum of the file, as hex string. The hash
+	// algorithm is given by a 'md5:', 'sha1:' or 'sha256:' prefix, or by the
+	// length of the checksum.
+	Checksum string `config:"checksum"`
+
+	// ChecksumFile is the path of a file containing the expected checksum,
+	// for example in the format of sha256sum. It is read again on every
+	// reload, so it can be updated together with the database.
+	ChecksumFile string `config:"checksum_file"`
+
+	// ReloadInterval is the interval at which the file is checked for
+	// changes. Reloading is disabled if 0.
+	ReloadInterval time.Duration `config:"reload_interval"`
+}
+
+func (c *Config) Validate() error {
+	if c.Path == "" {
+		return errors.New("database path is required")
+	}
+	if c.Checksum != "" && c.ChecksumFile != "" {
+		return errors.New("checksum and checksum_file can not be used together")
+	}
+	if c.Checksum != "" {
+		if _, _, err := parseChecksum(c.Checksum); err != nil {
+			return err
+		}
+	}
+	if c.ReloadInterval < 0 {
+		return errors.New("reload_interval must not be negative")
+	}
+	return nil
+}
+
+// LoadFunc loads the database from the file at path.
+type LoadFunc func(path string) (interface{}, error)
+
+// File is a database loaded from a file. The database is replaced when the
+// file changes, if the new file is valid and can be loaded. Otherwise the
+// previous database is kept.
+type File struct {
+	name   string
+	config Config
+	load   LoadFunc
+
+	mutex sync.RWMutex
+	db    interface{}
+
+	// state of the loaded file
+	modTime time.Time
+	size    int64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Open loads the database from the configured file. If a reload interval is
+// configured, the file is checked for changes until Close is called.
+func Open(name string, config Config, load LoadFunc) (*File, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		name:   name,
+		config: config,
+		load:   load,
+		done:   make(chan struct{}),
+	}
+	if _, err := f.reload(); err != nil {
+		return nil, err
+	}
+	logp.Info("Loaded %s database from %s", name, config.Path)
+
+	if config.ReloadInterval > 0 {
+		f.wg.Add(1)
+		go f.run()
+	}
+	return f, nil
+}
+
+// Get returns the current database.
+func (f *File) Get() interface{} {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.db
+}
+
+// Close stops reloading the file.
+func (f *File) Close() {
+	close(f.done)
+	f.wg.Wait()
+}
+
+func (f *File) run() {
+	defer f.wg.Done()
+	defer crash.Recover()
+
+	ticker := time.NewTicker(f.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := f.reload()
+		if err != nil {
+			reloadFailures.Add(1)
+			logp.Err("Failed to reload %s database from %s, keeping the previous version: %v",
+				f.name, f.config.Path, err)
+			continue
+		}
+		if reloaded {
+			reloads.Add(1)
+			logp.Info("Reloaded %s database from %s", f.name, f.config.Path)
+		}
+	}
+}
+
+// reload loads the file if it changed since the last load. It returns true
+// if the database has been replaced.
+func (f *File) reload() (bool, error) {
+	info, err := os.Stat(f.config.Path)
+	if err != nil {
+		return false, err
+	}
+	if f.db != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+
+	if err := f.verify(); err != nil {
+		return false, err
+	}
+
+	db, err := f.load(f.config.Path)
+	if err != nil {
+		return false, err
+	}
+
+	// The file might have been replaced while it was loaded. It is loaded
+	// again on the next check in that case.
+	after, err := os.Stat(f.config.Path)
+	if err != nil {
+		return false, err
+	}
+	if !after.ModTime().Equal(info.ModTime()) || after.Size() != info.Size() {
+		return false, fmt.Errorf("file %s changed while loading", f.config.Path)
+	}
+
+	f.mutex.Lock()
+	f.db = db
+	f.mutex.Unlock()
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return true, nil
+}
+
+// verify compares the checksum of the file with the expected checksum, if
+// configured.
+func (f *File) verify() error {
+	expected := f.config.Checksum
+	if f.config.ChecksumFile != "" {
+		content, err := ioutil.ReadFile(f.config.ChecksumFile)
+		if err != nil {
+			return err
+		}
+		// Checksum files, as written by sha256sum, contain the checksum
+		// followed by the file name.
+		fields := strings.Fields(string(content))
+		if len(fields) == 0 {
+			return fmt.Errorf("checksum file %s is empty", f.config.ChecksumFile)
+		}
+		expected = fields[0]
+	}
+	if expected == "" {
+		return nil
+	}
+
+	newHash, sum, err := parseChecksum(expected)
+	if err != nil {
+		return err
+	}
+
+	actual, err := checksum(f.config.Path, newHash())
+	if err != nil {
+		return err
+	}
+	if actual != sum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s",
+			f.config.Path, sum, actual)
+	}
+	return nil
+}
+
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// parseChecksum returns the hash algorithm and the normalized hex string of
+// the checksum.
+func parseChecksum(s string) (func() hash.Hash, string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	var algorithm string
+	if i := strings.Index(s, ":"); i >= 0 {
+		algorithm, s = s[:i], s[i+1:]
+	} else {
+		switch len(s) {
+		case 2 * md5.Size:
+			algorithm = "md5"
+		case 2 * sha1.Size:
+			algorithm = "sha1"
+		case 2 * sha256.Size:
+			algorithm = "sha256"
+		}
+	}
+
+	newHash, found := hashes[algorithm]
+	if !found {
+		return nil, "", fmt.Errorf("unsupported checksum '%v'", s)
+	}
+	if _, err := hex.DecodeString(s); err != nil || len(s) != 2*newHash().Size() {
+		return nil, "", fmt.Errorf("invalid %s checksum '%v'", algorithm, s)
+	}
+	return newHash, s, nil
+}
+
+func checksum(path string, h hash.Hash) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
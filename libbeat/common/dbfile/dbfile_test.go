@@ -0,0 +1,137 @@
+// +build !integration
+
+package dbfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sha256 of "v1" and "v2"
+const (
+	sumV1 = "3bfc269594ef649228e9a74bab00f042efc91d5acc6fbee31a382e80d42388fe"
+	sumV2 = "fb04dcb6970e4c3d1873de51fd5a50d7bb46b3383113602665c350ec40b5f990"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "dbfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func write(t *testing.T, path, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadString(path string) (interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if string(content) == "invalid" {
+		return nil, errors.New("invalid database")
+	}
+	return string(content), nil
+}
+
+func TestChecksum(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "db")
+	write(t, path, "v1", time.Now())
+
+	for _, sum := range []string{sumV1, "sha256:" + sumV1, "SHA256:" + sumV1} {
+		f, err := Open("test", Config{Path: path, Checksum: sum}, loadString)
+		if assert.NoError(t, err, sum) {
+			assert.Equal(t, "v1", f.Get())
+			f.Close()
+		}
+	}
+
+	_, err := Open("test", Config{Path: path, Checksum: "md5:" + sumV1}, loadString)
+	assert.Error(t, err)
+
+	_, err = Open("test", Config{Path: path, Checksum: "sha256:" + sumV2}, loadString)
+	assert.Error(t, err)
+
+	sumFile := filepath.Join(dir, "db.sha256")
+	write(t, sumFile, sumV1+"  db\n", time.Now())
+	f, err := Open("test", Config{Path: path, ChecksumFile: sumFile}, loadString)
+	if assert.NoError(t, err) {
+		f.Close()
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "db")
+	modTime := time.Now().Add(-time.Hour)
+	write(t, path, "v1", modTime)
+
+	f, err := Open("test", Config{Path: path}, loadString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// unchanged files are not loaded again
+	reloaded, err := f.reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// invalid files keep the previous database
+	write(t, path, "invalid", modTime.Add(time.Minute))
+	_, err = f.reload()
+	assert.Error(t, err)
+	assert.Equal(t, "v1", f.Get())
+
+	write(t, path, "v2", modTime.Add(2*time.Minute))
+	reloaded, err = f.reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "v2", f.Get())
+}
+
+func TestReloadInterval(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path := filepath.Join(dir, "db")
+	modTime := time.Now().Add(-time.Hour)
+	write(t, path, "v1", modTime)
+
+	f, err := Open("test", Config{Path: path, ReloadInterval: 10 * time.Millisecond}, loadString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	write(t, path, "v2", modTime.Add(time.Minute))
+	for i := 0; i < 100 && f.Get() != "v2"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "v2", f.Get())
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Path: "db", Checksum: "abc"}).Validate())
+	assert.Error(t, (&Config{Path: "db", Checksum: sumV1, ChecksumFile: "db.sha256"}).Validate())
+	assert.NoError(t, (&Config{Path: "db", Checksum: sumV1}).Validate())
+}
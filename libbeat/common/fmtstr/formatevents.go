@@ -0,0 +1,187 @@
+// Package fmtstr implements format strings referencing fields and the
+// timestamp of an event, like `%{[type]}-%{+yyyy.MM.dd}`.
+package fmtstr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// EventFormatString is a compiled format string. Fields are referenced by
+// `%{[field]}`, nested fields by their dotted path. A default value used if
+// the field is missing is set with `%{[field]:default}`. The @timestamp of the
+// event is formatted with `%{+pattern}`, like `%{+yyyy.MM.dd}`.
+type EventFormatString struct {
+	raw       string
+	parts     []formatPart
+	fields    []string
+	timestamp bool
+}
+
+// formatPart is either a literal text, a field reference or a timestamp.
+type formatPart struct {
+	text       string
+	field      string
+	def        string
+	hasDefault bool
+	timestamp  timestampFormat
+}
+
+var (
+	errMissingClose = errors.New("missing '}' in format string")
+	errEmptyField   = errors.New("empty field name in format string")
+)
+
+// CompileEvent compiles the format string in.
+func CompileEvent(in string) (*EventFormatString, error) {
+	fs := &EventFormatString{raw: in}
+
+	rest := in
+	for len(rest) > 0 {
+		start := strings.Index(rest, "%{")
+		if start < 0 {
+			fs.parts = append(fs.parts, formatPart{text: rest})
+			break
+		}
+		if start > 0 {
+			fs.parts = append(fs.parts, formatPart{text: rest[:start]})
+		}
+		rest = rest[start+2:]
+
+		end := strings.Index(rest, "}")
+		if end < 0 {
+			return nil, errMissingClose
+		}
+		ref := rest[:end]
+		rest = rest[end+1:]
+
+		if strings.HasPrefix(ref, "+") {
+			timestamp, err := parseTimestampFormat(ref[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%v: '%v'", err, in)
+			}
+			fs.parts = append(fs.parts, formatPart{timestamp: timestamp})
+			fs.timestamp = true
+			continue
+		}
+
+		part, err := parseField(ref)
+		if err != nil {
+			return nil, fmt.Errorf("%v: '%v'", err, in)
+		}
+		fs.parts = append(fs.parts, part)
+		fs.fields = append(fs.fields, part.field)
+	}
+	return fs, nil
+}
+
+// MustCompileEvent compiles the format string in and panics on error.
+func MustCompileEvent(in string) *EventFormatString {
+	fs, err := CompileEvent(in)
+	if err != nil {
+		panic(err)
+	}
+	return fs
+}
+
+// parseField parses the field reference `[field]` or `[field]:default`.
+func parseField(in string) (formatPart, error) {
+	if !strings.HasPrefix(in, "[") {
+		return formatPart{}, errors.New("field reference must be enclosed in '[]'")
+	}
+
+	end := strings.Index(in, "]")
+	if end < 0 {
+		return formatPart{}, errors.New("missing ']' in field reference")
+	}
+
+	part := formatPart{field: strings.TrimSpace(in[1:end])}
+	if part.field == "" {
+		return formatPart{}, errEmptyField
+	}
+
+	rest := in[end+1:]
+	switch {
+	case rest == "":
+	case strings.HasPrefix(rest, ":"):
+		part.def = rest[1:]
+		part.hasDefault = true
+	default:
+		return formatPart{}, fmt.Errorf("unexpected '%v' after field reference", rest)
+	}
+	return part, nil
+}
+
+// Unpack compiles the format string when the configuration is unpacked.
+func (fs *EventFormatString) Unpack(v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("format string must be a string, got %T", v)
+	}
+
+	compiled, err := CompileEvent(s)
+	if err != nil {
+		return err
+	}
+	*fs = *compiled
+	return nil
+}
+
+// Run formats the event. An error is returned if a referenced field without
+// default value is missing or is not a primitive value, or if a timestamp is
+// referenced and the event has no @timestamp.
+func (fs *EventFormatString) Run(event common.MapStr) (string, error) {
+	if fs.IsConst() {
+		return fs.raw, nil
+	}
+
+	var buf []byte
+	for _, part := range fs.parts {
+		if part.timestamp != nil {
+			s, err := part.timestamp.format(event)
+			if err != nil {
+				return "", err
+			}
+			buf = append(buf, s...)
+			continue
+		}
+		if part.field == "" {
+			buf = append(buf, part.text...)
+			continue
+		}
+
+		value, err := event.GetValue(part.field)
+		if err != nil || value == nil {
+			if !part.hasDefault {
+				return "", fmt.Errorf("key '%v' not found in event", part.field)
+			}
+			buf = append(buf, part.def...)
+			continue
+		}
+
+		switch value.(type) {
+		case common.MapStr, map[string]interface{}, []interface{}:
+			return "", fmt.Errorf("key '%v' is not a primitive value", part.field)
+		}
+		buf = append(buf, fmt.Sprint(value)...)
+	}
+	return string(buf), nil
+}
+
+// IsConst reports if the format string references no fields and no
+// timestamp.
+func (fs *EventFormatString) IsConst() bool {
+	return len(fs.fields) == 0 && !fs.timestamp
+}
+
+// Fields returns the fields referenced by the format string.
+func (fs *EventFormatString) Fields() []string {
+	return fs.fields
+}
+
+func (fs *EventFormatString) String() string {
+	return fs.raw
+}
@@ -0,0 +1,122 @@
+// +build !integration
+
+package fmtstr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventFormatString(t *testing.T) {
+	event := common.MapStr{
+		"type":  "log",
+		"count": 3,
+		"beat":  common.MapStr{"name": "host1"},
+	}
+
+	tests := []struct {
+		format, expected string
+	}{
+		{"beats", "beats"},
+		{"%{[type]}", "log"},
+		{"%{[type]}-%{[beat.name]}", "log-host1"},
+		{"prefix-%{[count]}-suffix", "prefix-3-suffix"},
+		{"%{[missing]:default}", "default"},
+		{"%{[missing]:}x", "x"},
+		{"%{[type]:default}", "log"},
+		{"100%", "100%"},
+	}
+	for _, test := range tests {
+		fs, err := CompileEvent(test.format)
+		if !assert.NoError(t, err, test.format) {
+			continue
+		}
+		actual, err := fs.Run(event)
+		assert.NoError(t, err, test.format)
+		assert.Equal(t, test.expected, actual, test.format)
+	}
+}
+
+func TestEventFormatStringErrors(t *testing.T) {
+	for _, invalid := range []string{"%{[type]", "%{type}", "%{[]}", "%{[type]x}"} {
+		_, err := CompileEvent(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	event := common.MapStr{"beat": common.MapStr{"name": "host1"}}
+	for _, format := range []string{"%{[missing]}", "%{[beat]}", "%{[beat.name.x]}"} {
+		_, err := MustCompileEvent(format).Run(event)
+		assert.Error(t, err, format)
+	}
+}
+
+func TestEventFormatStringUnpack(t *testing.T) {
+	var config struct {
+		Topic *EventFormatString `config:"topic"`
+	}
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"topic": "%{[type]}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.NoError(t, cfg.Unpack(&config)) {
+		assert.False(t, config.Topic.IsConst())
+		assert.Equal(t, []string{"type"}, config.Topic.Fields())
+		assert.Equal(t, "%{[type]}", config.Topic.String())
+	}
+
+	cfg, err = common.NewConfigFrom(map[string]interface{}{
+		"topic": "%{[type",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, cfg.Unpack(&config))
+}
+
+func TestEventFormatStringTimestamp(t *testing.T) {
+	ts, _ := time.Parse(time.RFC3339, "2017-01-02T03:04:05+01:00")
+	event := common.MapStr{
+		"@timestamp": common.Time(ts),
+		"type":       "log",
+	}
+
+	tests := []struct {
+		format, expected string
+	}{
+		{"%{[type]}-%{+yyyy.MM.dd}", "log-2017.01.02"},
+		{"%{+yyyy.MM.dd.HH}", "2017.01.02.02"},
+		{"%{+yy-M-d H:m:s}", "17-1-2 2:4:5"},
+		{"%{+DDD}", "002"},
+		{"%{+xxxx.ww}", "2017.01"},
+	}
+	for _, test := range tests {
+		fs, err := CompileEvent(test.format)
+		if !assert.NoError(t, err, test.format) {
+			continue
+		}
+		assert.False(t, fs.IsConst(), test.format)
+		actual, err := fs.Run(event)
+		assert.NoError(t, err, test.format)
+		assert.Equal(t, test.expected, actual, test.format)
+	}
+
+	// A week belongs to the year of its thursday.
+	ts, _ = time.Parse(time.RFC3339, "2016-01-01T00:00:00Z")
+	actual, err := MustCompileEvent("%{+xxxx.ww}").Run(common.MapStr{"@timestamp": ts})
+	assert.NoError(t, err)
+	assert.Equal(t, "2015.53", actual)
+
+	_, err = MustCompileEvent("%{+yyyy}").Run(common.MapStr{"type": "log"})
+	assert.Error(t, err)
+
+	for _, invalid := range []string{"%{+}", "%{+yyyy.QQ}"} {
+		_, err := CompileEvent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,108 @@
+package fmtstr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// timestampFormat formats the @timestamp of an event in UTC. Patterns use the
+// Joda-Time letters known from Logstash and Elasticsearch, like `yyyy.MM.dd`.
+type timestampFormat []timestampToken
+
+// timestampToken is either a literal text or a date field.
+type timestampToken struct {
+	text  string
+	field func(t time.Time) int
+	width int
+}
+
+// timestampFields are the supported pattern letters and their values.
+var timestampFields = map[byte]func(t time.Time) int{
+	'y': func(t time.Time) int { return t.Year() },
+	'M': func(t time.Time) int { return int(t.Month()) },
+	'd': func(t time.Time) int { return t.Day() },
+	'H': func(t time.Time) int { return t.Hour() },
+	'm': func(t time.Time) int { return t.Minute() },
+	's': func(t time.Time) int { return t.Second() },
+	'D': func(t time.Time) int { return t.YearDay() },
+	'w': func(t time.Time) int { _, week := t.ISOWeek(); return week },
+	'x': func(t time.Time) int { year, _ := t.ISOWeek(); return year },
+}
+
+var errNoTimestamp = errors.New("event has no @timestamp")
+
+func isLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// parseTimestampFormat parses a date pattern. Runs of the same pattern letter
+// are zero padded to the length of the run. `yy` and `xx` select the last two
+// digits of the year. All characters other than letters are copied as is.
+func parseTimestampFormat(pattern string) (timestampFormat, error) {
+	if pattern == "" {
+		return nil, errors.New("empty date pattern")
+	}
+
+	var tokens timestampFormat
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		j := i + 1
+		if !isLetter(c) {
+			for j < len(pattern) && !isLetter(pattern[j]) {
+				j++
+			}
+			tokens = append(tokens, timestampToken{text: pattern[i:j]})
+			i = j
+			continue
+		}
+
+		for j < len(pattern) && pattern[j] == c {
+			j++
+		}
+		field, ok := timestampFields[c]
+		if !ok {
+			return nil, fmt.Errorf("unsupported letter '%c' in date pattern '%v'", c, pattern)
+		}
+		tokens = append(tokens, timestampToken{field: field, width: j - i})
+		i = j
+	}
+	return tokens, nil
+}
+
+// format formats the @timestamp of the event.
+func (f timestampFormat) format(event common.MapStr) (string, error) {
+	var ts time.Time
+	switch v := event["@timestamp"].(type) {
+	case common.Time:
+		ts = time.Time(v)
+	case time.Time:
+		ts = v
+	default:
+		return "", errNoTimestamp
+	}
+	ts = ts.UTC()
+
+	var buf []byte
+	for _, token := range f {
+		if token.field == nil {
+			buf = append(buf, token.text...)
+			continue
+		}
+
+		value := token.field(ts)
+		if token.width == 2 && value >= 1000 {
+			// two digit year
+			value %= 100
+		}
+		s := strconv.Itoa(value)
+		for n := len(s); n < token.width; n++ {
+			buf = append(buf, '0')
+		}
+		buf = append(buf, s...)
+	}
+	return string(buf), nil
+}
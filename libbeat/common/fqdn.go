@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// fqdnCacheTTL is the time the fully qualified domain name of a host is
+// cached.
+const fqdnCacheTTL = 1 * time.Hour
+
+var fqdnCache = NewCache(fqdnCacheTTL, 1)
+
+// DNS lookups used by FQDN. Replaced by tests.
+var (
+	lookupCNAME = net.LookupCNAME
+	lookupHost  = net.LookupHost
+	lookupAddr  = net.LookupAddr
+)
+
+// FQDN returns the fully qualified domain name of the host. The canonical name
+// of the hostname is used if it is fully qualified, otherwise the first fully
+// qualified name found by reverse lookups of the addresses of the hostname.
+// Results are cached for an hour.
+func FQDN() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	if fqdn, ok := fqdnCache.Get(hostname).(string); ok {
+		return fqdn, nil
+	}
+
+	fqdn, err := lookupFQDN(hostname)
+	if err != nil {
+		return "", err
+	}
+	fqdnCache.Put(hostname, fqdn)
+	return fqdn, nil
+}
+
+func lookupFQDN(hostname string) (string, error) {
+	if cname, err := lookupCNAME(hostname); err == nil {
+		if name := strings.TrimSuffix(cname, "."); strings.Contains(name, ".") {
+			return name, nil
+		}
+	}
+
+	addrs, err := lookupHost(hostname)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		names, err := lookupAddr(addr)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if name = strings.TrimSuffix(name, "."); strings.Contains(name, ".") {
+				return name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no fully qualified domain name found for %v", hostname)
+}
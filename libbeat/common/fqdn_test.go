@@ -0,0 +1,64 @@
+// +build !integration
+
+package common
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withLookups(
+	cname func(string) (string, error),
+	host func(string) ([]string, error),
+	addr func(string) ([]string, error),
+	f func(),
+) {
+	defer func() {
+		lookupCNAME, lookupHost, lookupAddr = net.LookupCNAME, net.LookupHost, net.LookupAddr
+	}()
+	lookupCNAME, lookupHost, lookupAddr = cname, host, addr
+	f()
+}
+
+func TestLookupFQDNCanonicalName(t *testing.T) {
+	withLookups(
+		func(string) (string, error) { return "web1.example.com.", nil },
+		func(string) ([]string, error) { return nil, errors.New("unexpected lookup") },
+		func(string) ([]string, error) { return nil, errors.New("unexpected lookup") },
+		func() {
+			fqdn, err := lookupFQDN("web1")
+			assert.NoError(t, err)
+			assert.Equal(t, "web1.example.com", fqdn)
+		})
+}
+
+func TestLookupFQDNReverse(t *testing.T) {
+	withLookups(
+		func(host string) (string, error) { return host + ".", nil },
+		func(string) ([]string, error) { return []string{"10.0.0.1", "10.0.0.2"}, nil },
+		func(addr string) ([]string, error) {
+			if addr == "10.0.0.1" {
+				return []string{"localhost."}, nil
+			}
+			return []string{"web1.example.com."}, nil
+		},
+		func() {
+			fqdn, err := lookupFQDN("web1")
+			assert.NoError(t, err)
+			assert.Equal(t, "web1.example.com", fqdn)
+		})
+}
+
+func TestLookupFQDNNotFound(t *testing.T) {
+	withLookups(
+		func(string) (string, error) { return "", errors.New("no such host") },
+		func(string) ([]string, error) { return []string{"10.0.0.1"}, nil },
+		func(string) ([]string, error) { return []string{"web1"}, nil },
+		func() {
+			_, err := lookupFQDN("web1")
+			assert.Error(t, err)
+		})
+}
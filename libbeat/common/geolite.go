@@ -3,7 +3,9 @@ package common
 import (
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/elastic/beats/libbeat/common/dbfile"
 	"github.com/elastic/beats/libbeat/logp"
 
 	"github.com/nranchev/go-libGeoIP"
@@ -12,9 +14,68 @@ import (
 // Geoip represents a string slice of GeoIP paths
 type Geoip struct {
 	Paths *[]string
+
+	// Checksum validation and periodic reload of the database file. The
+	// options are deprecated together with the GeoIP support and will be
+	// removed in version 6.0.
+	Checksum       string        `config:"checksum"`
+	ChecksumFile   string        `config:"checksum_file"`
+	ReloadInterval time.Duration `config:"reload_interval"`
 }
 
 func LoadGeoIPData(config Geoip) *libgeo.GeoIP {
+	geoipPath := findGeoIPPath(config)
+	if geoipPath == "" {
+		return nil
+	}
+
+	// follow symlink
+	geoipPath, err := filepath.EvalSymlinks(geoipPath)
+	if err != nil {
+		logp.Warn("Could not load GeoIP data: %s", err.Error())
+		return nil
+	}
+
+	geoLite, err := libgeo.Load(geoipPath)
+	if err != nil {
+		logp.Warn("Could not load GeoIP data: %s", err.Error())
+	}
+
+	logp.Info("Loaded GeoIP data from: %s", geoipPath)
+	return geoLite
+}
+
+// OpenGeoIPData opens the GeoIP database like LoadGeoIPData, but validates
+// the checksum of the database file and reloads it periodically if
+// configured. Symlinks are not resolved, such that the database can be
+// updated by replacing the symlink. It returns nil if GeoIP support is
+// disabled or the database can not be loaded.
+func OpenGeoIPData(config Geoip) *dbfile.File {
+	geoipPath := findGeoIPPath(config)
+	if geoipPath == "" {
+		return nil
+	}
+
+	dbConfig := dbfile.Config{
+		Path:           geoipPath,
+		Checksum:       config.Checksum,
+		ChecksumFile:   config.ChecksumFile,
+		ReloadInterval: config.ReloadInterval,
+	}
+	db, err := dbfile.Open("GeoIP", dbConfig, func(path string) (interface{}, error) {
+		return libgeo.Load(path)
+	})
+	if err != nil {
+		logp.Warn("Could not load GeoIP data: %s", err.Error())
+		return nil
+	}
+	return db
+}
+
+// findGeoIPPath returns the first existing path of the configured GeoIP
+// paths, or an empty string if GeoIP support is disabled or no database is
+// found.
+func findGeoIPPath(config Geoip) string {
 	geoipPaths := []string{}
 
 	if config.Paths != nil {
@@ -22,7 +83,7 @@ func LoadGeoIPData(config Geoip) *libgeo.GeoIP {
 	}
 	if len(geoipPaths) == 0 {
 		// disabled
-		return nil
+		return ""
 	} else {
 		logp.Warn("GeoIP lookup support is deprecated and will be removed in version 6.0.")
 	}
@@ -30,35 +91,17 @@ func LoadGeoIPData(config Geoip) *libgeo.GeoIP {
 	// look for the first existing path
 	var geoipPath string
 	for _, path := range geoipPaths {
-		fi, err := os.Lstat(path)
-		if err != nil {
+		if _, err := os.Lstat(path); err != nil {
 			logp.Err("GeoIP path could not be loaded: %s", path)
 			continue
 		}
-
-		if fi.Mode()&os.ModeSymlink == os.ModeSymlink {
-			// follow symlink
-			geoipPath, err = filepath.EvalSymlinks(path)
-			if err != nil {
-				logp.Warn("Could not load GeoIP data: %s", err.Error())
-				return nil
-			}
-		} else {
-			geoipPath = path
-		}
+		geoipPath = path
 		break
 	}
 
 	if len(geoipPath) == 0 {
 		logp.Warn("Couldn't load GeoIP database")
-		return nil
-	}
-
-	geoLite, err := libgeo.Load(geoipPath)
-	if err != nil {
-		logp.Warn("Could not load GeoIP data: %s", err.Error())
+		return ""
 	}
-
-	logp.Info("Loaded GeoIP data from: %s", geoipPath)
-	return geoLite
+	return geoipPath
 }
@@ -9,9 +9,19 @@ import (
 )
 
 const (
+	// MetadataKey is the key of the event metadata. Metadata can be used in
+	// processor conditions and by outputs, but is not indexed.
+	MetadataKey      = "@metadata"
 	EventMetadataKey = "_event_metadata"
 	FieldsKey        = "fields"
 	TagsKey          = "tags"
+
+	// Keys of the event metadata set by the beats. The input is the kind of
+	// source the event was read from, like the input type of Filebeat or the
+	// protocol of Packetbeat. The host is the host the event originates from.
+	MetadataInputKey  = "input"
+	MetadataModuleKey = "module"
+	MetadataHostKey   = "host"
 )
 
 var ErrorFieldsIsNotMapStr = errors.New("the value stored in fields is not a MapStr")
@@ -26,6 +36,12 @@ type EventMetadata struct {
 	Fields          MapStr
 	FieldsUnderRoot bool `config:"fields_under_root"`
 	Tags            []string
+	Namespace       string `config:"namespace"`
+}
+
+// Validate checks the namespace is valid.
+func (m *EventMetadata) Validate() error {
+	return ValidateNamespace(m.Namespace)
 }
 
 // Eventer defines a type its ability to fill a MapStr.
@@ -177,6 +193,65 @@ func (m MapStr) GetValue(key string) (interface{}, error) {
 	return mapp[keyParts[keyPartsLen-1]], nil
 }
 
+// Put associates the specified value with the specified key. If the map
+// previously contained a mapping for the key, the old value is replaced and
+// returned. The key can be expressed in dot-notation (e.g. x.y) to put a value
+// into a nested map. Intermediate maps are created as needed. If an
+// intermediate key exists but is not a MapStr an error is returned.
+func (m MapStr) Put(key string, value interface{}) (interface{}, error) {
+	keyParts := strings.Split(key, ".")
+	keyPartsLen := len(keyParts)
+
+	mapp := m
+	for i := 0; i < keyPartsLen-1; i++ {
+		keyPart := keyParts[i]
+
+		v, ok := mapp[keyPart]
+		if !ok {
+			next := MapStr{}
+			mapp[keyPart] = next
+			mapp = next
+			continue
+		}
+
+		mapp, ok = v.(MapStr)
+		if !ok {
+			return nil, fmt.Errorf("expected map but type is %T at key %s", v, keyPart)
+		}
+	}
+
+	last := keyParts[keyPartsLen-1]
+	old := mapp[last]
+	mapp[last] = value
+	return old, nil
+}
+
+// WithoutMetadata returns the event without the @metadata field. The returned
+// map shares all other values with m. If m has no metadata, m is returned.
+func (m MapStr) WithoutMetadata() MapStr {
+	if _, exists := m[MetadataKey]; !exists {
+		return m
+	}
+
+	event := make(MapStr, len(m)-1)
+	for k, v := range m {
+		if k != MetadataKey {
+			event[k] = v
+		}
+	}
+	return event
+}
+
+// SetMetadata sets key in the @metadata of the event.
+func SetMetadata(event MapStr, key string, value interface{}) {
+	meta, ok := event[MetadataKey].(MapStr)
+	if !ok {
+		meta = MapStr{}
+		event[MetadataKey] = meta
+	}
+	meta[key] = value
+}
+
 func (m MapStr) StringToPrint() string {
 	json, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
@@ -147,6 +147,46 @@ func TestHasKey(t *testing.T) {
 
 }
 
+func TestMapStrPut(t *testing.T) {
+	assert := assert.New(t)
+
+	m := MapStr{
+		"a": 1,
+		"b": MapStr{
+			"c": 2,
+		},
+	}
+
+	old, err := m.Put("a", 3)
+	assert.Nil(err)
+	assert.Equal(1, old)
+	assert.Equal(3, m["a"])
+
+	old, err = m.Put("b.d.e", "x")
+	assert.Nil(err)
+	assert.Nil(old)
+	assert.Equal(MapStr{"c": 2, "d": MapStr{"e": "x"}}, m["b"])
+
+	_, err = m.Put("a.b", 1)
+	assert.NotNil(err)
+}
+
+func TestMapStrWithoutMetadata(t *testing.T) {
+	m := MapStr{"a": 1}
+	assert.Equal(t, m, m.WithoutMetadata())
+
+	m = MapStr{"a": 1, MetadataKey: MapStr{"input": "tcp"}}
+	assert.Equal(t, MapStr{"a": 1}, m.WithoutMetadata())
+	assert.Contains(t, m, MetadataKey)
+}
+
+func TestSetMetadata(t *testing.T) {
+	m := MapStr{"a": 1}
+	SetMetadata(m, MetadataInputKey, "log")
+	SetMetadata(m, MetadataHostKey, "web-01")
+	assert.Equal(t, MapStr{"input": "log", "host": "web-01"}, m[MetadataKey])
+}
+
 func TestClone(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,53 @@
+package multiline
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultMaxLines is the default maximum number of lines to return in one
+	// multi-line event.
+	DefaultMaxLines = 500
+
+	// DefaultTimeout is the default timeout to finish a multi-line event.
+	DefaultTimeout = 5 * time.Second
+)
+
+// Config contains the options shared by all multiline implementations.
+type Config struct {
+	Negate   bool           `config:"negate"`
+	Match    string         `config:"match"       validate:"required"`
+	MaxLines *int           `config:"max_lines"`
+	Pattern  *regexp.Regexp `config:"pattern"`
+	Timeout  *time.Duration `config:"timeout"     validate:"positive"`
+}
+
+func (c *Config) Validate() error {
+	if c.Match != "after" && c.Match != "before" {
+		return fmt.Errorf("unknown matcher type: %s", c.Match)
+	}
+	return nil
+}
+
+// GetMaxLines returns the configured maximum number of lines or the default.
+func (c *Config) GetMaxLines() int {
+	if c.MaxLines != nil {
+		return *c.MaxLines
+	}
+	return DefaultMaxLines
+}
+
+// GetTimeout returns the configured timeout or the default.
+func (c *Config) GetTimeout() (time.Duration, error) {
+	if c.Timeout == nil {
+		return DefaultTimeout, nil
+	}
+
+	timeout := *c.Timeout
+	if timeout < 0 {
+		return 0, fmt.Errorf("timeout %v must not be negative", timeout)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,71 @@
+// Package multiline provides the line matching logic used to combine multiple
+// lines into one multi-line event. It is shared by filebeat's log harvester
+// and the multiline processor, so other inputs can merge continuation lines
+// using the same configuration options.
+package multiline
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Matcher represents the predicate comparing any two lines to find start
+// and end of multiline events in stream of line events. It returns true if
+// current belongs to the same multiline event as last.
+type Matcher func(last, current []byte) bool
+
+// NewMatcher creates the Matcher for the given configuration.
+func NewMatcher(config *Config) (Matcher, error) {
+	types := map[string]func(*regexp.Regexp) (Matcher, error){
+		"before": beforeMatcher,
+		"after":  afterMatcher,
+	}
+
+	matcherType, ok := types[config.Match]
+	if !ok {
+		return nil, fmt.Errorf("unknown matcher type: %s", config.Match)
+	}
+
+	if config.Pattern == nil {
+		return nil, fmt.Errorf("multiline pattern is missing")
+	}
+
+	matcher, err := matcherType(config.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Negate {
+		matcher = negatedMatcher(matcher)
+	}
+	return matcher, nil
+}
+
+func afterMatcher(regex *regexp.Regexp) (Matcher, error) {
+	return genPatternMatcher(regex, func(last, current []byte) []byte {
+		return current
+	})
+}
+
+func beforeMatcher(regex *regexp.Regexp) (Matcher, error) {
+	return genPatternMatcher(regex, func(last, current []byte) []byte {
+		return last
+	})
+}
+
+func negatedMatcher(m Matcher) Matcher {
+	return func(last, current []byte) bool {
+		return !m(last, current)
+	}
+}
+
+func genPatternMatcher(
+	regex *regexp.Regexp,
+	sel func(last, current []byte) []byte,
+) (Matcher, error) {
+	matcher := func(last, current []byte) bool {
+		line := sel(last, current)
+		return regex.Match(line)
+	}
+	return matcher, nil
+}
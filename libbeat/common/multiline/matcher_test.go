@@ -0,0 +1,41 @@
+// +build !integration
+
+package multiline
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		config   Config
+		last     string
+		current  string
+		expected bool
+	}{
+		{Config{Match: "after", Pattern: regexp.MustCompile(`^\s`)}, "line1", "  line1.1", true},
+		{Config{Match: "after", Pattern: regexp.MustCompile(`^\s`)}, "line1", "line2", false},
+		{Config{Match: "before", Pattern: regexp.MustCompile(`\\$`)}, "line1 \\", "line1.1", true},
+		{Config{Match: "after", Pattern: regexp.MustCompile(`^-`), Negate: true}, "-line1", "line1.1", true},
+		{Config{Match: "after", Pattern: regexp.MustCompile(`^-`), Negate: true}, "line1.1", "-line2", false},
+	}
+
+	for i, test := range tests {
+		m, err := NewMatcher(&test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, test.expected, m([]byte(test.last), []byte(test.current)), "test %d", i)
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	_, err := NewMatcher(&Config{Match: "around", Pattern: regexp.MustCompile(`x`)})
+	assert.NotNil(t, err)
+
+	_, err = NewMatcher(&Config{Match: "after"})
+	assert.NotNil(t, err)
+}
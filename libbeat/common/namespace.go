@@ -0,0 +1,38 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// NamespaceKey is the key of the namespace in the event metadata. Outputs
+// append the namespace to index names, topics and file paths, such that the
+// events of different tenants are kept apart.
+const NamespaceKey = "namespace"
+
+var namespaceRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateNamespace checks the namespace can be used in index names, topics
+// and file paths.
+func ValidateNamespace(namespace string) error {
+	if namespace != "" && !namespaceRegexp.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace '%v': only lower case letters, digits, '_' and '-' are allowed", namespace)
+	}
+	return nil
+}
+
+// SetNamespace sets the namespace in the metadata of the event.
+func SetNamespace(event MapStr, namespace string) {
+	SetMetadata(event, NamespaceKey, namespace)
+}
+
+// GetNamespace returns the namespace of the event or an empty string if the
+// event has no namespace.
+func GetNamespace(event MapStr) string {
+	meta, ok := event[MetadataKey].(MapStr)
+	if !ok {
+		return ""
+	}
+	namespace, _ := meta[NamespaceKey].(string)
+	return namespace
+}
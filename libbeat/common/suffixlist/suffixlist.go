@@ -0,0 +1,124 @@
+// Package suffixlist implements the public suffix list algorithm for lists
+// loaded at runtime, in the format published at https://publicsuffix.org.
+// Unlike golang.org/x/net/publicsuffix, which compiles a fixed version of the
+// list into the binary, the list can be updated without a new release.
+package suffixlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+type ruleKind uint8
+
+const (
+	normalRule ruleKind = 1 << iota
+	wildcardRule
+	exceptionRule
+)
+
+// List is a public suffix list.
+type List struct {
+	rules map[string]ruleKind
+}
+
+// Load reads the list from the file at path.
+func Load(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public suffix list %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// Parse reads the list from r. Every line contains one rule, comments start
+// with '//'. Rules in Unicode are converted to Punycode, as used in DNS.
+func Parse(r io.Reader) (*List, error) {
+	l := &List{rules: map[string]ruleKind{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		// only the first word of a line is part of the rule
+		if fields := strings.Fields(line); len(fields) > 1 {
+			line = fields[0]
+		}
+
+		kind := normalRule
+		switch {
+		case strings.HasPrefix(line, "!"):
+			kind, line = exceptionRule, line[1:]
+		case strings.HasPrefix(line, "*."):
+			kind, line = wildcardRule, line[2:]
+		}
+
+		rule, err := idna.ToASCII(strings.ToLower(line))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule '%v': %v", line, err)
+		}
+		l.rules[rule] |= kind
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.rules) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+	return l, nil
+}
+
+// PublicSuffix returns the public suffix of domain.
+func (l *List) PublicSuffix(domain string) string {
+	labels := strings.Split(strings.ToLower(domain), ".")
+	n := l.suffixLabels(labels)
+	return strings.Join(labels[len(labels)-n:], ".")
+}
+
+// EffectiveTLDPlusOne returns the public suffix of domain plus one more
+// label, e.g. "example.co.uk" for "www.example.co.uk".
+func (l *List) EffectiveTLDPlusOne(domain string) (string, error) {
+	if strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", fmt.Errorf("empty label in domain %q", domain)
+	}
+
+	labels := strings.Split(strings.ToLower(domain), ".")
+	n := l.suffixLabels(labels)
+	if n >= len(labels) {
+		return "", fmt.Errorf("cannot derive eTLD+1 for domain %q", domain)
+	}
+	return strings.Join(labels[len(labels)-n-1:], "."), nil
+}
+
+// suffixLabels returns the number of labels of the public suffix. Exception
+// rules prevail over all other rules, otherwise the rule with the most labels
+// wins. If no rule matches, the public suffix is the last label.
+func (l *List) suffixLabels(labels []string) int {
+	n := 1
+	for i := 1; i <= len(labels); i++ {
+		kind := l.rules[strings.Join(labels[len(labels)-i:], ".")]
+		if kind&exceptionRule != 0 {
+			return i - 1
+		}
+		if kind&normalRule != 0 && i > n {
+			n = i
+		}
+		if kind&wildcardRule != 0 && i < len(labels) && i+1 > n {
+			n = i + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+// +build !integration
+
+package suffixlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testList = `
+// ===BEGIN ICANN DOMAINS===
+com
+uk
+co.uk
+jp
+*.kobe.jp
+!city.kobe.jp
+ck
+*.ck
+!www.ck
+// Unicode rules are converted to Punycode
+公司.cn
+`
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	l, err := Parse(strings.NewReader(testList))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domain, expected string
+	}{
+		{"example.com", "example.com"},
+		{"www.example.com", "example.com"},
+		{"WWW.Example.COM", "example.com"},
+		{"www.example.co.uk", "example.co.uk"},
+		{"example.uk", "example.uk"},
+		{"a.b.c.kobe.jp", "b.c.kobe.jp"},
+		{"www.city.kobe.jp", "city.kobe.jp"},
+		{"b.c.ck", "b.c.ck"},
+		{"www.ck", "www.ck"},
+		{"www.www.ck", "www.ck"},
+		{"shishi.xn--55qx5d.cn", "shishi.xn--55qx5d.cn"},
+		// unlisted TLDs default to one label
+		{"www.example.test", "example.test"},
+	}
+	for _, test := range tests {
+		actual, err := l.EffectiveTLDPlusOne(test.domain)
+		assert.NoError(t, err, test.domain)
+		assert.Equal(t, test.expected, actual, test.domain)
+	}
+
+	for _, domain := range []string{"com", "co.uk", "c.kobe.jp", "c.ck", ".example.com", "example..com"} {
+		_, err := l.EffectiveTLDPlusOne(domain)
+		assert.Error(t, err, domain)
+	}
+
+	assert.Equal(t, "co.uk", l.PublicSuffix("www.example.co.uk"))
+}
+
+func TestParseEmpty(t *testing.T) {
+	_, err := Parse(strings.NewReader("// only comments\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package control
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Client queries the control API of a running beat.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client connecting to the given control socket.
+func NewClient(socket string, timeout time.Duration) *Client {
+	transport := &http.Transport{
+		Dial: func(_, _ string) (net.Conn, error) {
+			return dial(socket)
+		},
+	}
+	return &Client{
+		http: &http.Client{Transport: transport, Timeout: timeout},
+	}
+}
+
+// Status returns the status of the beat.
+func (c *Client) Status() (*Status, error) {
+	status := &Status{}
+	if err := c.do("GET", "/status", nil, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Metrics returns all metrics exported by the beat.
+func (c *Client) Metrics() (map[string]interface{}, error) {
+	var metrics map[string]interface{}
+	err := c.do("GET", "/metrics", nil, &metrics)
+	return metrics, err
+}
+
+// Inputs returns the active inputs of the beat.
+func (c *Client) Inputs() ([]common.MapStr, error) {
+	var inputs []common.MapStr
+	err := c.do("GET", "/inputs", nil, &inputs)
+	return inputs, err
+}
+
+// Logging returns the current log level and debug selectors of the beat.
+func (c *Client) Logging() (*Logging, error) {
+	logging := &Logging{}
+	if err := c.do("GET", "/logging", nil, logging); err != nil {
+		return nil, err
+	}
+	return logging, nil
+}
+
+// SetLogging changes the log level and debug selectors of the beat. The log
+// level is not changed if empty, the selectors are not changed if nil.
+func (c *Client) SetLogging(logging Logging) error {
+	return c.do("PUT", "/logging", logging, nil)
+}
+
+// Reload makes the beat reload its configuration.
+func (c *Client) Reload() error {
+	return c.do("POST", "/reload", nil, nil)
+}
+
+func (c *Client) do(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	// the host is ignored as all requests are sent to the socket
+	req, err := http.NewRequest(method, "http://beat"+path, reader)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("request failed with status %v", resp.Status)
+		}
+		return errors.New(e.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
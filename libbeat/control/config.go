@@ -0,0 +1,10 @@
+package control
+
+// Config is the configuration of the control socket.
+type Config struct {
+	Enabled bool `config:"enabled"`
+
+	// Socket is the path of the unix socket, or the name of the named pipe
+	// on Windows, like `\\.\pipe\filebeat-control`.
+	Socket string `config:"socket"`
+}
@@ -0,0 +1,72 @@
+package control
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// HTTPConfig is the configuration of the HTTP endpoint.
+type HTTPConfig struct {
+	Enabled bool   `config:"enabled"`
+	Host    string `config:"host"`
+	Port    int    `config:"port"`
+}
+
+// DefaultHTTPConfig is the default configuration of the HTTP endpoint.
+var DefaultHTTPConfig = HTTPConfig{
+	Host: "localhost",
+	Port: 5067,
+}
+
+// NewHTTPServer creates a server exposing the read-only endpoints of the
+// control API on a TCP address, such that the status and metrics of the beat
+// can be monitored remotely:
+//
+//	GET  /status   beat name, version and uptime
+//	GET  /metrics  all expvar metrics
+//	GET  /inputs   active inputs
+//
+// Endpoints changing the beat are only available on the control socket.
+func NewHTTPServer(config HTTPConfig, info Info) *Server {
+	return &Server{
+		address: net.JoinHostPort(config.Host, fmt.Sprint(config.Port)),
+		info:    info,
+		start:   time.Now(),
+	}
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) startHTTP() error {
+	l, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to start HTTP endpoint on %v: %v", s.address, err)
+	}
+	s.listener = l
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/inputs", s.handleInputs)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer crash.Recover()
+		logp.Info("HTTP endpoint listening on %v", l.Addr())
+		err := http.Serve(l, mux)
+		logp.Debug("control", "HTTP endpoint closed: %v", err)
+	}()
+	return nil
+}
@@ -0,0 +1,49 @@
+// +build !integration
+
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPServer(t *testing.T) {
+	s := NewHTTPServer(HTTPConfig{Enabled: true, Host: "127.0.0.1"}, Info{Beat: "test", Version: "1.0"})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	url := "http://" + s.Addr().String()
+
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metrics map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&metrics)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Contains(t, metrics, "memstats")
+
+	resp, err = http.Get(url + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status Status
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "test", status.Beat)
+
+	// endpoints changing the beat are not available
+	resp, err = http.Post(url+"/reload", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,70 @@
+// +build !windows
+
+package control
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/paths"
+)
+
+// DefaultSocket returns the default socket path of the given beat, located
+// in the data path.
+func DefaultSocket(beatName string) string {
+	return paths.Resolve(paths.Data, beatName+".sock")
+}
+
+// listen creates the unix socket. Only the user running the beat is allowed to
+// control it, so the socket is created in a private directory, restricted and
+// only then moved to its path. Created at its path directly, the socket would
+// be reachable by everyone until its permissions are changed.
+func listen(socket string) (net.Listener, error) {
+	// remove socket of a previous run not stopped properly
+	if info, err := os.Lstat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socket)
+	}
+
+	// short names, as the path length of unix sockets is limited
+	dir, err := ioutil.TempDir(filepath.Dir(socket), ".ctl")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	private := filepath.Join(dir, "s")
+	l, err := net.Listen("unix", private)
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed by the listener after it has been moved
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(private, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(private, socket); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &socketListener{Listener: l, socket: socket}, nil
+}
+
+// socketListener removes the socket when closed.
+type socketListener struct {
+	net.Listener
+	socket string
+}
+
+func (l *socketListener) Close() error {
+	err := l.Listener.Close()
+	os.Remove(l.socket)
+	return err
+}
+
+func dial(socket string) (net.Conn, error) {
+	return net.Dial("unix", socket)
+}
@@ -0,0 +1,463 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	modkernel32 = syscall.NewLazyDLL("kernel32.dll")
+	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
+
+	procCreateNamedPipeW            = modkernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe            = modkernel32.NewProc("ConnectNamedPipe")
+	procCreateEventW                = modkernel32.NewProc("CreateEventW")
+	procGetOverlappedResult         = modkernel32.NewProc("GetOverlappedResult")
+	procLocalFree                   = modkernel32.NewProc("LocalFree")
+	procGetSecurityDescriptorLength = modadvapi32.NewProc("GetSecurityDescriptorLength")
+	procConvertStringSDToSD         = modadvapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+)
+
+const (
+	pipeAccessDuplex          = 0x3
+	fileFlagFirstPipeInstance = 0x80000
+	fileFlagOverlapped        = 0x40000000
+	pipeRejectRemoteClients   = 0x8 // PIPE_TYPE_BYTE, PIPE_READMODE_BYTE and PIPE_WAIT are 0
+	pipeUnlimitedInstances    = 255
+	pipeBufferSize            = 4096
+	sddlRevision1             = 1
+
+	errPipeBusy      syscall.Errno = 231
+	errPipeConnected syscall.Errno = 535
+)
+
+var errListenerClosed = errors.New("control pipe closed")
+
+// DefaultSocket returns the default named pipe of the control API of the
+// given beat.
+func DefaultSocket(beatName string) string {
+	return `\\.\pipe\` + beatName + "-control"
+}
+
+// listen creates the named pipe at path, like `\\.\pipe\filebeat-control`.
+// Only LocalSystem, the Administrators and the user running the beat are
+// granted access, and remote clients are rejected. The first instance of the
+// pipe is created immediately, such that listening fails if another process
+// already created the pipe.
+func listen(path string) (net.Listener, error) {
+	sd, err := pipeSecurityDescriptor()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the security descriptor: %v", err)
+	}
+
+	l := &pipeListener{path: path, sd: sd}
+	h, err := l.createPipe(true)
+	if err != nil {
+		return nil, err
+	}
+	l.next = h
+	return l, nil
+}
+
+// pipeSecurityDescriptor returns the self-relative security descriptor
+// granting access to LocalSystem, the Administrators and the current user.
+func pipeSecurityDescriptor() ([]byte, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return nil, err
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	sid, err := user.User.Sid.String()
+	if err != nil {
+		return nil, err
+	}
+
+	sddl, err := syscall.UTF16PtrFromString(
+		fmt.Sprintf("D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GA;;;%s)", sid))
+	if err != nil {
+		return nil, err
+	}
+
+	var sd *byte
+	r, _, e := procConvertStringSDToSD.Call(
+		uintptr(unsafe.Pointer(sddl)), sddlRevision1, uintptr(unsafe.Pointer(&sd)), 0)
+	if r == 0 {
+		return nil, e
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(sd)))
+
+	// copy the descriptor, such that it is managed by the garbage collector
+	n, _, _ := procGetSecurityDescriptorLength.Call(uintptr(unsafe.Pointer(sd)))
+	buf := make([]byte, n)
+	copy(buf, (*[1 << 16]byte)(unsafe.Pointer(sd))[:n:n])
+	return buf, nil
+}
+
+// pipeListener accepts clients of a named pipe. Each client is connected to
+// its own instance of the pipe.
+type pipeListener struct {
+	path string
+	sd   []byte
+
+	mutex   sync.Mutex
+	next    syscall.Handle // instance to wait for the next client on, 0 if none
+	waiting syscall.Handle // instance Accept is waiting for a client on, 0 if none
+	closed  bool
+}
+
+func (l *pipeListener) createPipe(first bool) (syscall.Handle, error) {
+	name, err := syscall.UTF16PtrFromString(l.path)
+	if err != nil {
+		return 0, err
+	}
+
+	mode := uint32(pipeAccessDuplex | fileFlagOverlapped)
+	if first {
+		mode |= fileFlagFirstPipeInstance
+	}
+	sa := syscall.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(syscall.SecurityAttributes{})),
+		SecurityDescriptor: uintptr(unsafe.Pointer(&l.sd[0])),
+	}
+
+	r, _, e := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(name)),
+		uintptr(mode),
+		pipeRejectRemoteClients,
+		pipeUnlimitedInstances,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		uintptr(unsafe.Pointer(&sa)))
+	if syscall.Handle(r) == syscall.InvalidHandle {
+		return 0, e
+	}
+	return syscall.Handle(r), nil
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return nil, errListenerClosed
+	}
+	h := l.next
+	l.next = 0
+	l.mutex.Unlock()
+
+	if h == 0 {
+		var err error
+		if h, err = l.createPipe(false); err != nil {
+			return nil, err
+		}
+	}
+
+	o, err := newOverlapped()
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	defer syscall.CloseHandle(o.HEvent)
+
+	// The wait for a client is started while holding the mutex, such that
+	// Close either sees the pending wait and cancels it, or Accept sees that
+	// the listener has been closed.
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		syscall.CloseHandle(h)
+		return nil, errListenerClosed
+	}
+	r, _, e := procConnectNamedPipe.Call(uintptr(h), uintptr(unsafe.Pointer(o)))
+	if r == 0 && e == syscall.ERROR_IO_PENDING {
+		l.waiting = h
+	}
+	l.mutex.Unlock()
+
+	if r == 0 && e == syscall.ERROR_IO_PENDING {
+		var n uint32
+		e = getOverlappedResult(h, o, &n)
+
+		l.mutex.Lock()
+		l.waiting = 0
+		l.mutex.Unlock()
+	} else if r != 0 {
+		e = nil
+	}
+
+	if e == syscall.ERROR_OPERATION_ABORTED {
+		syscall.CloseHandle(h)
+		return nil, errListenerClosed
+	}
+	if e != nil && e != errPipeConnected {
+		syscall.CloseHandle(h)
+		return nil, e
+	}
+	return newPipeConn(h, l.path), nil
+}
+
+// Close stops accepting clients and cancels a pending Accept.
+func (l *pipeListener) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	if l.next != 0 {
+		syscall.CloseHandle(l.next)
+		l.next = 0
+	}
+	if l.waiting != 0 {
+		syscall.CancelIoEx(l.waiting, nil)
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+// dial connects to the named pipe at path. If all instances of the pipe are
+// busy, connecting is retried for a second.
+func dial(path string) (net.Conn, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		h, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+			0, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_OVERLAPPED, 0)
+		if err == nil {
+			return newPipeConn(h, path), nil
+		}
+
+		if err != errPipeBusy || time.Now().After(deadline) {
+			return nil, &os.PathError{Op: "open", Path: path, Err: err}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var errConnClosed = errors.New("use of closed control pipe")
+
+// pipeConn is one end of a named pipe opened for overlapped I/O. Pending reads
+// and writes are canceled once their deadline expires or the pipe is closed.
+type pipeConn struct {
+	h    syscall.Handle
+	addr pipeAddr
+
+	mutex   sync.Mutex
+	closed  bool
+	read    ioDeadline
+	write   ioDeadline
+	pending sync.WaitGroup // pending I/O, Close waits for it to be canceled
+}
+
+// ioDeadline is the deadline of the reads or the writes of a pipeConn. The
+// pending operation is canceled by a timer once the deadline expires.
+type ioDeadline struct {
+	t       time.Time
+	timer   *time.Timer
+	op      *syscall.Overlapped // pending operation, nil if none
+	expired bool                // op has been canceled by the deadline
+}
+
+func newPipeConn(h syscall.Handle, path string) *pipeConn {
+	return &pipeConn{h: h, addr: pipeAddr(path)}
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+	n, err := c.do(&c.read, func(o *syscall.Overlapped, n *uint32) error {
+		return syscall.ReadFile(c.h, b, n, o)
+	})
+	if err == syscall.ERROR_BROKEN_PIPE {
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n, err := c.do(&c.write, func(o *syscall.Overlapped, n *uint32) error {
+			return syscall.WriteFile(c.h, b[written:], n, o)
+		})
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// do runs one overlapped operation and waits for it to complete. The
+// operation is started while holding the mutex, such that it is either
+// canceled by Close or not started at all.
+func (c *pipeConn) do(d *ioDeadline, op func(o *syscall.Overlapped, n *uint32) error) (int, error) {
+	o, err := newOverlapped()
+	if err != nil {
+		return 0, err
+	}
+	defer syscall.CloseHandle(o.HEvent)
+
+	var n uint32
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return 0, errConnClosed
+	}
+	if !d.t.IsZero() && !time.Now().Before(d.t) {
+		c.mutex.Unlock()
+		return 0, timeoutError{}
+	}
+	err = op(o, &n)
+	if err != syscall.ERROR_IO_PENDING {
+		c.mutex.Unlock()
+		return int(n), err
+	}
+	c.pending.Add(1)
+	defer c.pending.Done()
+	d.op, d.expired = o, false
+	c.arm(d)
+	c.mutex.Unlock()
+
+	err = getOverlappedResult(c.h, o, &n)
+
+	c.mutex.Lock()
+	expired, closed := d.expired, c.closed
+	d.op = nil
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	c.mutex.Unlock()
+
+	if err == syscall.ERROR_OPERATION_ABORTED {
+		switch {
+		case closed:
+			err = errConnClosed
+		case expired:
+			err = timeoutError{}
+		}
+	}
+	return int(n), err
+}
+
+// arm cancels the pending operation of d once the deadline expires. The mutex
+// must be held.
+func (c *pipeConn) arm(d *ioDeadline) {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.op == nil || d.t.IsZero() {
+		return
+	}
+
+	op := d.op
+	cancel := func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		if d.op == op && !d.expired {
+			d.expired = true
+			syscall.CancelIoEx(c.h, op)
+		}
+	}
+	if wait := d.t.Sub(time.Now()); wait > 0 {
+		d.timer = time.AfterFunc(wait, cancel)
+	} else if !d.expired {
+		d.expired = true
+		syscall.CancelIoEx(c.h, op)
+	}
+}
+
+func (c *pipeConn) setDeadline(d *ioDeadline, t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	d.t = t
+	c.arm(d)
+}
+
+// Close cancels the pending reads and writes and closes the pipe once they
+// returned.
+func (c *pipeConn) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return errConnClosed
+	}
+	c.closed = true
+	if c.read.op != nil || c.write.op != nil {
+		syscall.CancelIoEx(c.h, nil)
+	}
+	c.mutex.Unlock()
+
+	c.pending.Wait()
+	return syscall.CloseHandle(c.h)
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.setDeadline(&c.read, t)
+	c.setDeadline(&c.write, t)
+	return nil
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	c.setDeadline(&c.read, t)
+	return nil
+}
+
+func (c *pipeConn) SetWriteDeadline(t time.Time) error {
+	c.setDeadline(&c.write, t)
+	return nil
+}
+
+// newOverlapped returns the state of an overlapped operation with its own
+// event, such that reads and writes can be pending at the same time.
+func newOverlapped() (*syscall.Overlapped, error) {
+	r, _, e := procCreateEventW.Call(0, 1, 0, 0)
+	if r == 0 {
+		return nil, e
+	}
+	return &syscall.Overlapped{HEvent: syscall.Handle(r)}, nil
+}
+
+// getOverlappedResult waits for the overlapped operation o to complete.
+func getOverlappedResult(h syscall.Handle, o *syscall.Overlapped, n *uint32) error {
+	r, _, e := procGetOverlappedResult.Call(
+		uintptr(h), uintptr(unsafe.Pointer(o)), uintptr(unsafe.Pointer(n)), 1)
+	if r == 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package control
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func connectPipe(t *testing.T) (net.Listener, net.Conn, net.Conn) {
+	path := fmt.Sprintf(`\\.\pipe\control-test-%d-%d`, os.Getpid(), time.Now().UnixNano())
+	l, err := listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	client, err := dial(path)
+	if err != nil {
+		l.Close()
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		client.Close()
+		l.Close()
+		t.FailNow()
+	}
+	return l, server, client
+}
+
+func TestPipeReadDeadline(t *testing.T) {
+	l, server, client := connectPipe(t)
+	defer l.Close()
+	defer client.Close()
+	defer server.Close()
+
+	start := time.Now()
+	server.SetReadDeadline(start.Add(100 * time.Millisecond))
+	_, err := server.Read(make([]byte, 10))
+	if assert.Error(t, err) {
+		nerr, ok := err.(net.Error)
+		assert.True(t, ok && nerr.Timeout(), "timeout error expected, got %v", err)
+	}
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// the pipe can be read again once the deadline is removed
+	server.SetReadDeadline(time.Time{})
+	_, err = client.Write([]byte("ping"))
+	assert.NoError(t, err)
+	buf := make([]byte, 10)
+	n, err := server.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+}
+
+func TestPipeDeadlineCancelsPendingRead(t *testing.T) {
+	l, server, client := connectPipe(t)
+	defer l.Close()
+	defer client.Close()
+	defer server.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := server.Read(make([]byte, 10))
+		result <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	server.SetReadDeadline(time.Now())
+	select {
+	case err := <-result:
+		nerr, ok := err.(net.Error)
+		assert.True(t, ok && nerr.Timeout(), "timeout error expected, got %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("read not canceled by the deadline")
+	}
+}
+
+func TestPipeCloseCancelsAccept(t *testing.T) {
+	path := fmt.Sprintf(`\\.\pipe\control-test-%d-%d`, os.Getpid(), time.Now().UnixNano())
+	l, err := listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := l.Accept()
+		result <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	l.Close()
+	select {
+	case err := <-result:
+		assert.Equal(t, errListenerClosed, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("accept not canceled by close")
+	}
+}
@@ -0,0 +1,59 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Logging describes the log level and the enabled debug selectors.
+type Logging struct {
+	Level     string   `json:"level,omitempty"`
+	Selectors []string `json:"selectors"`
+}
+
+func init() {
+	// Make the logging endpoint available on the HTTP server started by the
+	// -httpprof flag, next to the pprof and expvar endpoints.
+	http.HandleFunc("/debug/logging", handleLogging)
+}
+
+// handleLogging returns the log level and debug selectors on GET requests
+// and changes them on PUT requests. Settings not present in the request are
+// not changed. An empty list of selectors enables all selectors if the log
+// level is debug.
+func handleLogging(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "PUT":
+		var req struct {
+			Level     string    `json:"level"`
+			Selectors *[]string `json:"selectors"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if req.Level != "" {
+			if err := logp.SetLevel(req.Level); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		if req.Selectors != nil {
+			logp.SetSelectors(*req.Selectors)
+		}
+		logp.Info("Logging changed at runtime: level=%v, selectors=%v",
+			logp.Level(), logp.Selectors())
+	default:
+		allowMethod(w, r, "GET", "PUT")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Logging{
+		Level:     logp.Level(),
+		Selectors: logp.Selectors(),
+	})
+}
@@ -0,0 +1,210 @@
+package control
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/feature"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Info describes the beat the control server is running in.
+type Info struct {
+	Beat    string `json:"beat"`
+	Version string `json:"version"`
+	UUID    string `json:"uuid"`
+}
+
+// Status is returned by the status endpoint.
+type Status struct {
+	Info
+	StartTime time.Time `json:"start_time"`
+	Uptime    string    `json:"uptime"`
+	LogLevel  string    `json:"log_level"`
+
+	// Features reports whether each feature gate is enabled.
+	Features map[string]bool `json:"features"`
+}
+
+// InputLister returns information about the active inputs of a beat, for
+// example the prospectors and harvesters of filebeat.
+type InputLister func() []common.MapStr
+
+var (
+	listersMutex sync.Mutex
+	listers      []InputLister
+)
+
+// RegisterInputs registers a function listing active inputs. The inputs of
+// all registered listers are returned by the inputs endpoint.
+func RegisterInputs(l InputLister) {
+	listersMutex.Lock()
+	defer listersMutex.Unlock()
+	listers = append(listers, l)
+}
+
+func inputs() []common.MapStr {
+	listersMutex.Lock()
+	defer listersMutex.Unlock()
+
+	all := []common.MapStr{}
+	for _, l := range listers {
+		all = append(all, l()...)
+	}
+	return all
+}
+
+// Server serves the control API on a local socket. The API is plain HTTP
+// using JSON encoded responses:
+//
+//	GET  /status   beat name, version and uptime
+//	GET  /metrics  all expvar metrics
+//	GET  /inputs   active inputs
+//	GET  /logging  current log level and debug selectors
+//	PUT  /logging  change logging, e.g. {"level": "debug", "selectors": ["publish"]}
+//	POST /reload   reload the configuration
+type Server struct {
+	socket   string
+	address  string // TCP address of the HTTP endpoint, see NewHTTPServer
+	info     Info
+	reload   func() error
+	start    time.Time
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a control server listening on the socket configured.
+// reload is invoked on requests to the reload endpoint.
+func NewServer(config Config, info Info, reload func() error) *Server {
+	socket := config.Socket
+	if socket == "" {
+		socket = DefaultSocket(info.Beat)
+	}
+
+	return &Server{
+		socket: socket,
+		info:   info,
+		reload: reload,
+		start:  time.Now(),
+	}
+}
+
+// Start opens the socket and starts serving requests.
+func (s *Server) Start() error {
+	if s.address != "" {
+		return s.startHTTP()
+	}
+
+	l, err := listen(s.socket)
+	if err != nil {
+		return fmt.Errorf("failed to open control socket %v: %v", s.socket, err)
+	}
+	s.listener = l
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/inputs", s.handleInputs)
+	mux.HandleFunc("/logging", handleLogging)
+	mux.HandleFunc("/reload", s.handleReload)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer crash.Recover()
+		logp.Info("Control socket listening on %v", s.socket)
+
+		// close connections after each response, named pipes on Windows
+		// do not support the deadlines aborting reads of idle connections
+		srv := &http.Server{Handler: mux}
+		srv.SetKeepAlivesEnabled(false)
+		err := srv.Serve(l)
+		logp.Debug("control", "Control socket closed: %v", err)
+	}()
+	return nil
+}
+
+// Stop closes the socket.
+func (s *Server) Stop() {
+	if s.listener == nil {
+		return
+	}
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, Status{
+		Info:      s.info,
+		StartTime: s.start,
+		Uptime:    time.Since(s.start).String(),
+		LogLevel:  logp.Level(),
+		Features:  feature.States(),
+	})
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+
+	metrics := map[string]json.RawMessage{}
+	expvar.Do(func(kv expvar.KeyValue) {
+		metrics[kv.Key] = json.RawMessage(kv.Value.String())
+	})
+	writeJSON(w, http.StatusOK, metrics)
+}
+
+func (s *Server) handleInputs(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "GET") {
+		return
+	}
+	writeJSON(w, http.StatusOK, inputs())
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, "POST") {
+		return
+	}
+
+	logp.Info("Reloading configuration via control socket")
+	if err := s.reload(); err != nil {
+		logp.Err("Failed to reload configuration: %v", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, common.MapStr{"reloaded": true})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, common.MapStr{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logp.Err("Failed to encode control response: %v", err)
+	}
+}
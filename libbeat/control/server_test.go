@@ -0,0 +1,142 @@
+// +build !integration,!windows
+
+package control
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/stretchr/testify/assert"
+)
+
+func startServer(t *testing.T, reload func() error) (*Server, *Client, func()) {
+	dir, err := ioutil.TempDir("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(dir, "test.sock")
+	s := NewServer(Config{Enabled: true, Socket: socket}, Info{Beat: "test", Version: "1.0"}, reload)
+	if err := s.Start(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return s, NewClient(socket, 5*time.Second), func() {
+		s.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	_, client, stop := startServer(t, nil)
+	defer stop()
+
+	status, err := client.Status()
+	if assert.Nil(t, err) {
+		assert.Equal(t, "test", status.Beat)
+		assert.Equal(t, "1.0", status.Version)
+	}
+
+	metrics, err := client.Metrics()
+	if assert.Nil(t, err) {
+		assert.Contains(t, metrics, "memstats")
+	}
+}
+
+func TestLogging(t *testing.T) {
+	_, client, stop := startServer(t, nil)
+	defer stop()
+
+	logp.LogInit(logp.LOG_INFO, "", false, false, nil)
+	defer logp.SetLevel("info")
+
+	logging, err := client.Logging()
+	assert.Nil(t, err)
+	assert.Equal(t, &Logging{Level: "info", Selectors: []string{}}, logging)
+
+	assert.Nil(t, client.SetLogging(Logging{Level: "error"}))
+	logging, err = client.Logging()
+	assert.Nil(t, err)
+	assert.Equal(t, "error", logging.Level)
+
+	assert.NotNil(t, client.SetLogging(Logging{Level: "unknown"}))
+
+	// selectors are kept if only the level is changed
+	assert.Nil(t, client.SetLogging(Logging{Level: "debug", Selectors: []string{"publish"}}))
+	assert.Nil(t, client.SetLogging(Logging{Level: "debug"}))
+	logging, err = client.Logging()
+	assert.Nil(t, err)
+	assert.Equal(t, &Logging{Level: "debug", Selectors: []string{"publish"}}, logging)
+	assert.True(t, logp.IsDebug("publish"))
+	assert.False(t, logp.IsDebug("prospector"))
+
+	assert.Nil(t, client.SetLogging(Logging{Selectors: []string{}}))
+	assert.True(t, logp.IsDebug("prospector"))
+}
+
+func TestReload(t *testing.T) {
+	calls := 0
+	_, client, stop := startServer(t, func() error {
+		calls++
+		if calls > 1 {
+			return errors.New("reload failed")
+		}
+		return nil
+	})
+	defer stop()
+
+	assert.Nil(t, client.Reload())
+	err := client.Reload()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "reload failed", err.Error())
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestInputs(t *testing.T) {
+	_, client, stop := startServer(t, nil)
+	defer stop()
+
+	RegisterInputs(func() []common.MapStr {
+		return []common.MapStr{{"type": "log"}}
+	})
+	defer func() { listers = nil }()
+
+	inputs, err := client.Inputs()
+	assert.Nil(t, err)
+	assert.Equal(t, []common.MapStr{{"type": "log"}}, inputs)
+}
+
+func TestSocketPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "test.sock")
+	l, err := listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(socket)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// the private directory is removed
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1)
+
+	l.Close()
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,40 @@
+// Package crash recovers panics of the goroutines started by libbeat, such
+// that the Beat can write a crash report before it exits. Panics can only be
+// recovered by the goroutine that panicked, so every long running goroutine
+// defers Recover:
+//
+//   go func() {
+//       defer crash.Recover()
+//       ...
+//   }()
+//
+// The Beat registers the handler writing the crash report on startup.
+package crash
+
+import "sync/atomic"
+
+// Handler is called with the value of a recovered panic. It must not return.
+type Handler func(r interface{})
+
+var handler atomic.Value // Handler
+
+// SetHandler sets the handler called with the value of recovered panics.
+func SetHandler(h Handler) {
+	handler.Store(h)
+}
+
+// Recover recovers a panic of the calling goroutine and passes it to the
+// handler. If no handler is set or the handler returns, the panic is raised
+// again. Recover must be deferred directly by the function that is started
+// as goroutine.
+func Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	if h, _ := handler.Load().(Handler); h != nil {
+		h(r)
+	}
+	panic(r)
+}
@@ -0,0 +1,45 @@
+// +build !integration
+
+package crash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	defer SetHandler(nil)
+
+	recovered := make(chan interface{}, 1)
+	SetHandler(func(r interface{}) {
+		recovered <- r
+		select {} // the handler must not return
+	})
+
+	go func() {
+		defer Recover()
+		panic("boom")
+	}()
+	assert.Equal(t, "boom", <-recovered)
+}
+
+func TestRecoverWithoutHandler(t *testing.T) {
+	defer func() {
+		assert.Equal(t, "boom", recover())
+	}()
+
+	defer Recover()
+	panic("boom")
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	called := false
+	SetHandler(func(r interface{}) { called = true })
+	defer SetHandler(nil)
+
+	func() {
+		defer Recover()
+	}()
+	assert.False(t, called)
+}
@@ -0,0 +1,150 @@
+package envtags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// ec2Client queries the EC2 instance metadata service. It uses session tokens
+// (IMDSv2) if supported, and unauthenticated requests (IMDSv1) otherwise.
+type ec2Client struct {
+	http  *http.Client
+	url   string
+	token string
+}
+
+const ec2TokenTTL = "60"
+
+// readEC2 reads the tags and the metadata of the instance, as configured.
+// The metadata is added under the ec2 key.
+func readEC2(config EC2Config) (common.MapStr, error) {
+	c := &ec2Client{
+		http: &http.Client{Timeout: config.Timeout},
+		url:  strings.TrimSuffix(config.URL, "/"),
+	}
+	if c.http.Timeout == 0 {
+		c.http.Timeout = defaultEC2Timeout
+	}
+	if c.url == "" {
+		c.url = defaultEC2URL
+	}
+	c.token = c.requestToken()
+
+	tags := common.MapStr{}
+	if config.Metadata {
+		metadata, err := c.metadata()
+		if err != nil {
+			return nil, err
+		}
+		tags["ec2"] = metadata
+	}
+
+	if config.Tags {
+		instanceTags, err := c.tags()
+		if err != nil {
+			return nil, err
+		}
+		tags.Update(instanceTags)
+	}
+	return tags, nil
+}
+
+// requestToken returns a session token, or an empty string if the service
+// doesn't support IMDSv2.
+func (c *ec2Client) requestToken() string {
+	req, err := http.NewRequest("PUT", c.url+"/latest/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", ec2TokenTTL)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ""
+	}
+	return string(token)
+}
+
+func (c *ec2Client) get(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", c.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %v returned %v", path, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (c *ec2Client) metadata() (common.MapStr, error) {
+	body, err := c.get("/latest/dynamic/instance-identity/document")
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		InstanceID       string `json:"instanceId"`
+		InstanceType     string `json:"instanceType"`
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid instance identity document: %v", err)
+	}
+
+	return common.MapStr{
+		"instance_id":       doc.InstanceID,
+		"instance_type":     doc.InstanceType,
+		"region":            doc.Region,
+		"availability_zone": doc.AvailabilityZone,
+	}, nil
+}
+
+// tags reads the tags of the instance. The tags are only available if access
+// to tags in the instance metadata is enabled.
+func (c *ec2Client) tags() (common.MapStr, error) {
+	body, err := c.get("/latest/meta-data/tags/instance")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instance tags, make sure access "+
+			"to tags in the instance metadata is enabled: %v", err)
+	}
+
+	tags := common.MapStr{}
+	for _, key := range strings.Split(string(body), "\n") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+
+		value, err := c.get("/latest/meta-data/tags/instance/" + key)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = string(value)
+	}
+	return tags, nil
+}
@@ -0,0 +1,129 @@
+// Package envtags reads key/value tags describing the environment of the host,
+// like the environment, service or team, at startup. The tags are added as
+// fields to all events, such that the labels don't need to be configured on
+// every host.
+//
+// Tags are read from local files, for example written by cloud-init when the
+// instance is provisioned, and from the tags and instance metadata of EC2
+// instances.
+package envtags
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Config configures the sources of the tags. Sources listed later override
+// tags of the same name read from earlier sources: files are read first,
+// followed by EC2 metadata and EC2 tags.
+type Config struct {
+	Files []string  `config:"files"`
+	EC2   EC2Config `config:"ec2"`
+}
+
+// EC2Config configures the tags read from the EC2 instance metadata service.
+type EC2Config struct {
+	// Tags enables reading the tags of the instance. Access to tags in the
+	// instance metadata must be enabled for the instance.
+	Tags bool `config:"tags"`
+
+	// Metadata enables adding the instance ID, instance type, region and
+	// availability zone of the instance.
+	Metadata bool `config:"metadata"`
+
+	Timeout time.Duration `config:"timeout" validate:"min=0"`
+
+	// URL of the instance metadata service, replaced by tests.
+	URL string `config:"url"`
+}
+
+const (
+	defaultEC2URL     = "http://169.254.169.254"
+	defaultEC2Timeout = 3 * time.Second
+)
+
+// Enabled reports if any source of tags is configured.
+func (c *Config) Enabled() bool {
+	return len(c.Files) > 0 || c.EC2.Tags || c.EC2.Metadata
+}
+
+// Load reads the tags from all configured sources. Missing files and an
+// unavailable metadata service are logged, such that the same configuration
+// can be used on all hosts. Files that can't be parsed return an error.
+func Load(config Config) (common.MapStr, error) {
+	tags := common.MapStr{}
+
+	for _, path := range config.Files {
+		fileTags, err := readFile(path)
+		if os.IsNotExist(err) {
+			logp.Warn("Environment tags file %v not found", path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags.Update(fileTags)
+	}
+
+	if config.EC2.Tags || config.EC2.Metadata {
+		ec2Tags, err := readEC2(config.EC2)
+		if err != nil {
+			logp.Warn("Failed to read environment tags from EC2 instance metadata: %v", err)
+		} else {
+			tags.Update(ec2Tags)
+		}
+	}
+
+	if len(tags) > 0 {
+		logp.Info("Environment tags: %v", tags)
+	}
+	return tags, nil
+}
+
+// readFile reads tags from a file of key=value lines. Empty lines and lines
+// starting with # are ignored. Values can be quoted. The format is compatible
+// with environment files used by systemd and docker.
+func readFile(path string) (common.MapStr, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tags := common.MapStr{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid environment tag in %v line %v: "+
+				"expected key=value", path, line)
+		}
+		key := strings.TrimSpace(text[:i])
+		tags[key] = unquote(strings.TrimSpace(text[i+1:]))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read environment tags from %v: %v", path, err)
+	}
+	return tags, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 {
+		first, last := value[0], value[len(value)-1]
+		if (first == '"' || first == '\'') && first == last {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,124 @@
+// +build !integration
+
+package envtags
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTagsFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envtags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := writeTagsFile(t, dir, "first", `
+# written by cloud-init
+environment=staging
+service = "checkout"
+team='payments'
+`)
+	second := writeTagsFile(t, dir, "second", "environment=production\n")
+
+	tags, err := Load(Config{Files: []string{
+		first,
+		filepath.Join(dir, "missing"),
+		second,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"environment": "production",
+		"service":     "checkout",
+		"team":        "payments",
+	}, tags)
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "envtags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeTagsFile(t, dir, "tags", "environment=staging\nservice\n")
+	_, err = Load(Config{Files: []string{path}})
+	assert.Error(t, err)
+}
+
+// newMetadataServer simulates the EC2 instance metadata service requiring
+// session tokens.
+func newMetadataServer() *httptest.Server {
+	const token = "test-token"
+	responses := map[string]string{
+		"/latest/meta-data/tags/instance":             "Environment\nTeam\n",
+		"/latest/meta-data/tags/instance/Environment": "production",
+		"/latest/meta-data/tags/instance/Team":        "payments",
+		"/latest/dynamic/instance-identity/document": `{
+			"instanceId": "i-0123456789abcdef0",
+			"instanceType": "m4.large",
+			"region": "eu-west-1",
+			"availabilityZone": "eu-west-1a"
+		}`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" {
+			w.Write([]byte(token))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, found := responses[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestLoadEC2(t *testing.T) {
+	server := newMetadataServer()
+	defer server.Close()
+
+	tags, err := Load(Config{EC2: EC2Config{Tags: true, Metadata: true, URL: server.URL}})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"Environment": "production",
+		"Team":        "payments",
+		"ec2": common.MapStr{
+			"instance_id":       "i-0123456789abcdef0",
+			"instance_type":     "m4.large",
+			"region":            "eu-west-1",
+			"availability_zone": "eu-west-1a",
+		},
+	}, tags)
+}
+
+func TestLoadEC2Unavailable(t *testing.T) {
+	server := newMetadataServer()
+	server.Close()
+
+	tags, err := Load(Config{EC2: EC2Config{Tags: true, URL: server.URL}})
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+}
@@ -0,0 +1,187 @@
+// Package feature provides gates for experimental features. Subsystems that
+// are not yet considered stable register a gate and check if it is enabled
+// before they are used. Experimental features are disabled by default and are
+// enabled in the features section of the configuration:
+//
+//   features:
+//     spool_queue: true
+//
+// The state of all gates is published in the libbeat.features expvar map.
+package feature
+
+import (
+	"expvar"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Stage is the maturity of a feature. It determines if the feature is
+// enabled by default.
+type Stage uint8
+
+const (
+	// Experimental features are disabled by default. They might change or be
+	// removed in future releases.
+	Experimental Stage = iota
+
+	// Beta features are enabled by default, but can still be disabled.
+	Beta
+)
+
+var stageNames = map[Stage]string{
+	Experimental: "experimental",
+	Beta:         "beta",
+}
+
+func (s Stage) String() string {
+	return stageNames[s]
+}
+
+// Config enables or disables features by name. Features not configured keep
+// their default state.
+type Config map[string]bool
+
+// Gate reports if a feature is enabled.
+type Gate struct {
+	name        string
+	description string
+	stage       Stage
+	enabled     uint32
+}
+
+var (
+	gatesMutex sync.Mutex
+	gates      = map[string]*Gate{}
+)
+
+// The state of the gates can be retrieved through the expvar web interface.
+func init() {
+	expvar.Publish("libbeat.features", expvar.Func(func() interface{} {
+		return States()
+	}))
+}
+
+// New registers the gate of the named feature. The feature is enabled by
+// default unless it is experimental. New panics if a gate of the same name is
+// already registered.
+func New(name, description string, stage Stage) *Gate {
+	gatesMutex.Lock()
+	defer gatesMutex.Unlock()
+
+	if _, exists := gates[name]; exists {
+		panic(fmt.Sprintf("feature %s is already registered", name))
+	}
+
+	g := &Gate{name: name, description: description, stage: stage}
+	g.reset()
+	gates[name] = g
+	return g
+}
+
+// Name returns the name of the feature, as used in the features section.
+func (g *Gate) Name() string {
+	return g.name
+}
+
+// Description returns a short description of the feature.
+func (g *Gate) Description() string {
+	return g.description
+}
+
+// Stage returns the maturity of the feature.
+func (g *Gate) Stage() Stage {
+	return g.stage
+}
+
+// Enabled reports if the feature is enabled.
+func (g *Gate) Enabled() bool {
+	return atomic.LoadUint32(&g.enabled) != 0
+}
+
+// Require returns an error explaining how to enable the feature if it is
+// disabled. setting names the setting using the feature.
+func (g *Gate) Require(setting string) error {
+	if g.Enabled() {
+		return nil
+	}
+	return fmt.Errorf("%s requires the %s feature %s, enable it with features.%s: true",
+		setting, g.stage, g.name, g.name)
+}
+
+func (g *Gate) set(enabled bool) {
+	var v uint32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreUint32(&g.enabled, v)
+}
+
+func (g *Gate) reset() {
+	g.set(g.stage != Experimental)
+}
+
+// Configure sets the state of the gates. Features not in config are reset to
+// their default state. Configure fails if config contains an unknown feature,
+// in which case no gate is changed.
+func Configure(config Config) error {
+	gatesMutex.Lock()
+	defer gatesMutex.Unlock()
+
+	var unknown []string
+	for name := range config {
+		if _, exists := gates[name]; !exists {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown features: %v", strings.Join(unknown, ", "))
+	}
+
+	for name, g := range gates {
+		enabled, exists := config[name]
+		if !exists {
+			g.reset()
+			continue
+		}
+
+		g.set(enabled)
+		if enabled && g.stage == Experimental {
+			logp.Warn("Experimental feature %s enabled: %s", name, g.description)
+		}
+	}
+	return nil
+}
+
+// Gates returns all registered gates, sorted by name.
+func Gates() []*Gate {
+	gatesMutex.Lock()
+	defer gatesMutex.Unlock()
+
+	list := make([]*Gate, 0, len(gates))
+	for _, g := range gates {
+		list = append(list, g)
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+type byName []*Gate
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Less(i, j int) bool { return b[i].name < b[j].name }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// States returns whether each registered feature is enabled, by name.
+func States() map[string]bool {
+	states := map[string]bool{}
+	for _, g := range Gates() {
+		states[g.name] = g.Enabled()
+	}
+	return states
+}
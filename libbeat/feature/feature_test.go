@@ -0,0 +1,61 @@
+// +build !integration
+
+package feature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testExperimental = New("test_experimental", "experimental test feature", Experimental)
+	testBeta         = New("test_beta", "beta test feature", Beta)
+)
+
+func TestDefaults(t *testing.T) {
+	assert.NoError(t, Configure(nil))
+	assert.False(t, testExperimental.Enabled())
+	assert.True(t, testBeta.Enabled())
+}
+
+func TestConfigure(t *testing.T) {
+	err := Configure(Config{"test_experimental": true, "test_beta": false})
+	assert.NoError(t, err)
+	assert.True(t, testExperimental.Enabled())
+	assert.False(t, testBeta.Enabled())
+	assert.Equal(t, map[string]bool{
+		"test_experimental": true,
+		"test_beta":         false,
+	}, States())
+
+	// gates not configured are reset
+	assert.NoError(t, Configure(Config{}))
+	assert.False(t, testExperimental.Enabled())
+	assert.True(t, testBeta.Enabled())
+}
+
+func TestConfigureUnknown(t *testing.T) {
+	err := Configure(Config{"test_experimental": true, "unknown": true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown")
+	}
+	assert.False(t, testExperimental.Enabled())
+}
+
+func TestRequire(t *testing.T) {
+	assert.NoError(t, Configure(nil))
+	err := testExperimental.Require("test.setting")
+	if assert.Error(t, err) {
+		assert.Equal(t, "test.setting requires the experimental feature "+
+			"test_experimental, enable it with features.test_experimental: true",
+			err.Error())
+	}
+	assert.NoError(t, testBeta.Require("test.setting"))
+}
+
+func TestNewDuplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		New("test_beta", "duplicate", Beta)
+	})
+}
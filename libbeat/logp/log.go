@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,24 +26,52 @@ const (
 )
 
 type Logger struct {
-	toSyslog          bool
-	toStderr          bool
-	toFile            bool
-	level             Priority
-	selectors         map[string]bool
-	debugAllSelectors bool
+	toSyslog bool
+	toStderr bool
+	toFile   bool
+
+	// filter holds the current *logFilter. It is replaced as a whole when
+	// the level or the selectors change at runtime, while being read
+	// concurrently by all logging goroutines.
+	filter      atomic.Value
+	filterMutex sync.Mutex // serializes the updates of filter
 
 	logger  *log.Logger
 	syslog  [LOG_DEBUG + 1]*log.Logger
 	rotator *FileRotator
 }
 
+// logFilter selects the messages to log. It must not be modified after being
+// stored in the Logger.
+type logFilter struct {
+	level             Priority
+	selectors         map[string]bool
+	debugAllSelectors bool
+}
+
 var _log Logger
 
+func init() {
+	_log.filter.Store(&logFilter{})
+}
+
+// getFilter returns the current filter.
+func getFilter() *logFilter {
+	return _log.filter.Load().(*logFilter)
+}
+
+// updateFilter replaces the current filter with the filter returned by fn.
+func updateFilter(fn func(f logFilter) *logFilter) {
+	_log.filterMutex.Lock()
+	defer _log.filterMutex.Unlock()
+	_log.filter.Store(fn(*getFilter()))
+}
+
 func debugMessage(calldepth int, selector, format string, v ...interface{}) {
-	if _log.level >= LOG_DEBUG {
-		if !_log.debugAllSelectors {
-			selected := _log.selectors[selector]
+	filter := getFilter()
+	if filter.level >= LOG_DEBUG {
+		if !filter.debugAllSelectors {
+			selected := filter.selectors[selector]
 			if !selected {
 				return
 			}
@@ -52,17 +82,20 @@ func debugMessage(calldepth int, selector, format string, v ...interface{}) {
 }
 
 func send(calldepth int, level Priority, prefix string, format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
 	if _log.toSyslog {
-		_log.syslog[level].Output(calldepth, fmt.Sprintf(format, v...))
+		_log.syslog[level].Output(calldepth, message)
 	}
 	if _log.toStderr {
-		_log.logger.Output(calldepth, fmt.Sprintf(prefix+format, v...))
+		_log.logger.Output(calldepth, prefix+message)
 	}
+
+	// Creates a timestamp for the file log message and formats it
+	line := time.Now().Format(time.RFC3339) + " " + prefix + message
 	if _log.toFile {
-		// Creates a timestamp for the file log message and formats it
-		prefix = time.Now().Format(time.RFC3339) + " " + prefix
-		_log.rotator.WriteLine([]byte(fmt.Sprintf(prefix+format, v...)))
+		_log.rotator.WriteLine([]byte(line))
 	}
+	recent.add(line)
 }
 
 func Debug(selector string, format string, v ...interface{}) {
@@ -76,11 +109,12 @@ func MakeDebug(selector string) func(string, ...interface{}) {
 }
 
 func IsDebug(selector string) bool {
-	return _log.debugAllSelectors || _log.selectors[selector]
+	filter := getFilter()
+	return filter.debugAllSelectors || filter.selectors[selector]
 }
 
 func msg(level Priority, prefix string, format string, v ...interface{}) {
-	if _log.level >= level {
+	if getFilter().level >= level {
 		send(4, level, prefix, format, v...)
 	}
 }
@@ -104,7 +138,7 @@ func Critical(format string, v ...interface{}) {
 // WTF prints the message at CRIT level and panics immediately with the same
 // message
 func WTF(format string, v ...interface{}) {
-	msg(LOG_CRIT, "CRIT ", format, v)
+	msg(LOG_CRIT, "CRIT ", format, v...)
 	panic(fmt.Sprintf(format, v...))
 }
 
@@ -119,15 +153,15 @@ func Recover(msg string) {
 func LogInit(level Priority, prefix string, toSyslog bool, toStderr bool, debugSelectors []string) {
 	_log.toSyslog = toSyslog
 	_log.toStderr = toStderr
-	_log.level = level
 
-	_log.selectors = make(map[string]bool)
+	filter := &logFilter{level: level, selectors: make(map[string]bool)}
 	for _, selector := range debugSelectors {
-		_log.selectors[selector] = true
+		filter.selectors[selector] = true
 		if selector == "*" {
-			_log.debugAllSelectors = true
+			filter.debugAllSelectors = true
 		}
 	}
+	updateFilter(func(logFilter) *logFilter { return filter })
 
 	if _log.toSyslog {
 		SetToSyslog(true, prefix)
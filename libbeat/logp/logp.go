@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -142,21 +143,82 @@ func getLogLevel(config *Logging) (Priority, error) {
 		return LOG_INFO, nil
 	}
 
-	levels := map[string]Priority{
-		"critical": LOG_CRIT,
-		"error":    LOG_ERR,
-		"warning":  LOG_WARNING,
-		"info":     LOG_INFO,
-		"debug":    LOG_DEBUG,
-	}
+	return parseLevel(config.Level)
+}
+
+var levels = map[string]Priority{
+	"critical": LOG_CRIT,
+	"error":    LOG_ERR,
+	"warning":  LOG_WARNING,
+	"info":     LOG_INFO,
+	"debug":    LOG_DEBUG,
+}
 
-	level, ok := levels[strings.ToLower(config.Level)]
+func parseLevel(name string) (Priority, error) {
+	level, ok := levels[strings.ToLower(name)]
 	if !ok {
-		return 0, fmt.Errorf("unknown log level: %v", config.Level)
+		return 0, fmt.Errorf("unknown log level: %v", name)
 	}
 	return level, nil
 }
 
+// SetLevel changes the log level at runtime. Valid levels are critical,
+// error, warning, info and debug. Enabling debug without any debug selectors
+// configured enables all selectors, until the level is changed again.
+func SetLevel(name string) error {
+	level, err := parseLevel(name)
+	if err != nil {
+		return err
+	}
+
+	updateFilter(func(f logFilter) *logFilter {
+		f.debugAllSelectors = f.selectors["*"] || len(f.selectors) == 0 && level == LOG_DEBUG
+		f.level = level
+		return &f
+	})
+	return nil
+}
+
+// SetSelectors replaces the debug selectors at runtime. The selector "*"
+// enables all selectors. If no selectors are given and the log level is
+// debug, all selectors are enabled.
+func SetSelectors(selectors []string) {
+	enabled := make(map[string]bool, len(selectors))
+	for _, selector := range selectors {
+		enabled[selector] = true
+	}
+
+	updateFilter(func(f logFilter) *logFilter {
+		f.selectors = enabled
+		f.debugAllSelectors = enabled["*"] || len(selectors) == 0 && f.level == LOG_DEBUG
+		return &f
+	})
+}
+
+// Selectors returns the enabled debug selectors, sorted by name.
+func Selectors() []string {
+	filter := getFilter()
+	selectors := make([]string, 0, len(filter.selectors))
+	for selector, enabled := range filter.selectors {
+		if enabled {
+			selectors = append(selectors, selector)
+		}
+	}
+	sort.Strings(selectors)
+	return selectors
+}
+
+// Level returns the name of the current log level.
+func Level() string {
+	current := getFilter().level
+	for name, level := range levels {
+		if level == current {
+			return name
+		}
+	}
+	return strconv.Itoa(int(current))
+}
+
 // snapshotMap recursively walks expvar Maps and records their integer expvars
 // in a separate flat map.
 func snapshotMap(varsMap map[string]int64, path string, mp *expvar.Map) {
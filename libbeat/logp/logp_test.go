@@ -4,6 +4,7 @@ package logp
 
 import (
 	"expvar"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -70,3 +71,118 @@ func TestBuildMetricsOutputMissing(t *testing.T) {
 	metrics := buildMetricsOutput(prevVals, vals)
 	assert.Equal(t, " testLogEmpty=7", metrics)
 }
+
+// saveLogger returns a function restoring the logger settings changed by the
+// tests.
+func saveLogger() func() {
+	toSyslog, toStderr := _log.toSyslog, _log.toStderr
+	filter := getFilter()
+	return func() {
+		_log.toSyslog, _log.toStderr = toSyslog, toStderr
+		_log.filter.Store(filter)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer saveLogger()()
+
+	LogInit(LOG_INFO, "", false, false, nil)
+	assert.Equal(t, "info", Level())
+
+	assert.Nil(t, SetLevel("Warning"))
+	assert.Equal(t, "warning", Level())
+
+	assert.NotNil(t, SetLevel("verbose"))
+	assert.Equal(t, "warning", Level())
+
+	assert.Nil(t, SetLevel("debug"))
+	assert.Equal(t, "debug", Level())
+	assert.True(t, IsDebug("any"))
+
+	assert.Nil(t, SetLevel("info"))
+	assert.False(t, IsDebug("any"))
+
+	// the selector * enables all selectors independent of the level
+	SetSelectors([]string{"*"})
+	assert.Nil(t, SetLevel("debug"))
+	assert.Nil(t, SetLevel("info"))
+	assert.True(t, IsDebug("any"))
+}
+
+func TestSetSelectors(t *testing.T) {
+	defer saveLogger()()
+
+	LogInit(LOG_DEBUG, "", false, false, []string{"publish"})
+	assert.Equal(t, []string{"publish"}, Selectors())
+	assert.True(t, IsDebug("publish"))
+	assert.False(t, IsDebug("prospector"))
+
+	SetSelectors([]string{"prospector", "harvester"})
+	assert.Equal(t, []string{"harvester", "prospector"}, Selectors())
+	assert.False(t, IsDebug("publish"))
+	assert.True(t, IsDebug("prospector"))
+
+	SetSelectors([]string{"*"})
+	assert.True(t, IsDebug("publish"))
+
+	SetSelectors(nil)
+	assert.Equal(t, []string{}, Selectors())
+	assert.True(t, IsDebug("publish"))
+
+	assert.Nil(t, SetLevel("info"))
+	SetSelectors(nil)
+	assert.False(t, IsDebug("publish"))
+}
+
+// The level and selectors can be changed while other goroutines are logging,
+// run with -race.
+func TestSetLevelConcurrent(t *testing.T) {
+	defer saveLogger()()
+
+	LogInit(LOG_INFO, "", false, false, nil)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				Debug("publish", "message")
+				IsDebug("prospector")
+				Selectors()
+				Level()
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		SetLevel("debug")
+		SetSelectors([]string{"publish"})
+		SetLevel("info")
+		SetSelectors(nil)
+	}
+	close(done)
+	wg.Wait()
+
+	assert.Equal(t, "info", Level())
+	assert.False(t, IsDebug("publish"))
+}
+
+func TestLineRing(t *testing.T) {
+	r := newLineRing(3)
+	assert.Empty(t, r.get())
+
+	r.add("1")
+	r.add("2")
+	assert.Equal(t, []string{"1", "2"}, r.get())
+
+	r.add("3")
+	r.add("4")
+	r.add("5")
+	assert.Equal(t, []string{"3", "4", "5"}, r.get())
+}
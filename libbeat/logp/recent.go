@@ -0,0 +1,46 @@
+package logp
+
+import "sync"
+
+// recentLinesSize is the number of log lines kept in memory.
+const recentLinesSize = 100
+
+// lineRing keeps the last log lines in memory, such that they can be included
+// in crash reports, independent of the configured log outputs.
+type lineRing struct {
+	mutex sync.Mutex
+	lines []string
+	next  int
+}
+
+var recent = newLineRing(recentLinesSize)
+
+func newLineRing(size int) *lineRing {
+	return &lineRing{lines: make([]string, 0, size)}
+}
+
+func (r *lineRing) add(line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.lines) < cap(r.lines) {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+}
+
+func (r *lineRing) get() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	lines := make([]string, 0, len(r.lines))
+	lines = append(lines, r.lines[r.next:]...)
+	return append(lines, r.lines[:r.next]...)
+}
+
+// RecentLines returns the last log lines written, oldest first.
+func RecentLines() []string {
+	return recent.get()
+}
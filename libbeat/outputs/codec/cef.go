@@ -0,0 +1,233 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// CEFConfig configures the CEF (Common Event Format) codec. The device
+// settings are written to the header as is. The signature ID, name and
+// severity of the header and the extensions are read from event fields.
+type CEFConfig struct {
+	DeviceVendor  string `config:"device_vendor"`
+	DeviceProduct string `config:"device_product"`
+	DeviceVersion string `config:"device_version"`
+
+	SignatureID CEFField `config:"signature_id"`
+	Name        CEFField `config:"name"`
+	Severity    CEFField `config:"severity"`
+
+	// Extensions maps CEF extension keys to event fields.
+	Extensions map[string]string `config:"extensions"`
+}
+
+// CEFField sets a CEF header field to the value of an event field. Value is
+// used if the event has no such field.
+type CEFField struct {
+	Field string `config:"field"`
+	Value string `config:"value"`
+}
+
+var defaultCEFConfig = CEFConfig{
+	DeviceVendor:  "Elastic",
+	DeviceProduct: "Beats",
+	SignatureID:   CEFField{Field: "type", Value: "event"},
+	Name:          CEFField{Field: "type", Value: "event"},
+	Severity:      CEFField{Value: "0"},
+}
+
+var cefKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// cefSeverities are the severity names allowed in addition to the numeric
+// severities 0 to 10.
+var cefSeverities = map[string]bool{
+	"Unknown":   true,
+	"Low":       true,
+	"Medium":    true,
+	"High":      true,
+	"Very-High": true,
+}
+
+func (c *CEFConfig) Validate() error {
+	for key := range c.Extensions {
+		if !cefKeyRegexp.MatchString(key) {
+			return fmt.Errorf("invalid CEF extension key '%v'", key)
+		}
+	}
+	if c.Severity.Value != "" && !validCEFSeverity(c.Severity.Value) {
+		return fmt.Errorf("invalid CEF severity '%v'", c.Severity.Value)
+	}
+	return nil
+}
+
+func validCEFSeverity(s string) bool {
+	if cefSeverities[s] {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 10
+}
+
+type cefCodec struct {
+	config CEFConfig
+	header string // constant part of the header
+	keys   []string
+}
+
+func newCEFCodec(config CEFConfig) (*cefCodec, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	applyCEFDefaults(&config)
+
+	keys := make([]string, 0, len(config.Extensions))
+	for key := range config.Extensions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	header := strings.Join([]string{
+		"CEF:0",
+		escapeCEFHeader(config.DeviceVendor),
+		escapeCEFHeader(config.DeviceProduct),
+		escapeCEFHeader(config.DeviceVersion),
+	}, "|")
+
+	return &cefCodec{
+		config: config,
+		header: header,
+		keys:   keys,
+	}, nil
+}
+
+func applyCEFDefaults(config *CEFConfig) {
+	if config.DeviceVendor == "" {
+		config.DeviceVendor = defaultCEFConfig.DeviceVendor
+	}
+	if config.DeviceProduct == "" {
+		config.DeviceProduct = defaultCEFConfig.DeviceProduct
+	}
+	for _, f := range []struct{ field, def *CEFField }{
+		{&config.SignatureID, &defaultCEFConfig.SignatureID},
+		{&config.Name, &defaultCEFConfig.Name},
+		{&config.Severity, &defaultCEFConfig.Severity},
+	} {
+		if f.field.Field == "" && f.field.Value == "" {
+			*f.field = *f.def
+		} else if f.field.Value == "" {
+			f.field.Value = f.def.Value
+		}
+	}
+}
+
+// Encode encodes the event as CEF line:
+//
+//  CEF:0|Vendor|Product|Version|SignatureID|Name|Severity|key=value key=value
+func (c *cefCodec) Encode(event common.MapStr) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(c.header)
+
+	severity := c.headerValue(event, c.config.Severity)
+	if !validCEFSeverity(severity) {
+		severity = c.config.Severity.Value
+	}
+	for _, s := range []string{
+		c.headerValue(event, c.config.SignatureID),
+		c.headerValue(event, c.config.Name),
+		severity,
+	} {
+		buf.WriteByte('|')
+		buf.WriteString(escapeCEFHeader(s))
+	}
+	buf.WriteByte('|')
+
+	first := true
+	for _, key := range c.keys {
+		value, err := event.GetValue(c.config.Extensions[key])
+		if err != nil || value == nil {
+			continue
+		}
+
+		s, err := formatCEFValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode CEF extension %v: %v", key, err)
+		}
+
+		if !first {
+			buf.WriteByte(' ')
+		}
+		first = false
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		buf.WriteString(escapeCEFExtension(s))
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *cefCodec) headerValue(event common.MapStr, f CEFField) string {
+	if f.Field != "" {
+		if value, err := event.GetValue(f.Field); err == nil && value != nil {
+			if s, err := formatCEFValue(value); err == nil {
+				return s
+			}
+		}
+	}
+	return f.Value
+}
+
+// formatCEFValue formats timestamps as milliseconds since epoch, which is
+// accepted by all CEF timestamp extensions, and structured values as JSON.
+func formatCEFValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case common.Time:
+		return strconv.FormatInt(time.Time(v).UnixNano()/int64(time.Millisecond), 10), nil
+	case time.Time:
+		return strconv.FormatInt(v.UnixNano()/int64(time.Millisecond), 10), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	case common.MapStr, map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		return string(b), err
+	case fmt.Stringer:
+		return v.String(), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+var (
+	cefHeaderEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		`|`, `\|`,
+		"\r\n", " ",
+		"\n", " ",
+		"\r", " ",
+	)
+	cefExtensionEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		`=`, `\=`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\r`,
+	)
+)
+
+func escapeCEFHeader(s string) string {
+	return cefHeaderEscaper.Replace(s)
+}
+
+func escapeCEFExtension(s string) string {
+	return cefExtensionEscaper.Replace(s)
+}
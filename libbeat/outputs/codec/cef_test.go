@@ -0,0 +1,86 @@
+// +build !integration
+
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCEFEncode(t *testing.T) {
+	c, err := New(Config{CEF: &CEFConfig{
+		DeviceProduct: "Filebeat",
+		DeviceVersion: "5.1|beta",
+		Name:          CEFField{Field: "message"},
+		Severity:      CEFField{Field: "fields.severity", Value: "3"},
+		Extensions: map[string]string{
+			"src":   "client.ip",
+			"msg":   "message",
+			"rt":    "@timestamp",
+			"cs1":   "fields",
+			"cnt":   "count",
+			"dhost": "missing",
+		},
+	}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ts := time.Date(2016, 11, 1, 10, 0, 0, 0, time.UTC)
+	event := common.MapStr{
+		"@timestamp": common.Time(ts),
+		"type":       "log",
+		"message":    "login failed: user=admin\npath=C:\\",
+		"count":      1,
+		"client":     common.MapStr{"ip": "10.0.0.1"},
+		"fields":     common.MapStr{"severity": 7},
+	}
+
+	line, err := c.Encode(event)
+	assert.NoError(t, err)
+	assert.Equal(t, `CEF:0|Elastic|Filebeat|5.1\|beta|log|login failed: user=admin path=C:\\|7|`+
+		`cnt=1 cs1={"severity":7} msg=login failed: user\=admin\npath\=C:\\ rt=1477994400000 src=10.0.0.1`,
+		string(line))
+
+	// default header values are used for missing or invalid fields
+	line, err = c.Encode(common.MapStr{"fields": common.MapStr{"severity": "critical"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `CEF:0|Elastic|Filebeat|5.1\|beta|event|event|3|cs1={"severity":"critical"}`, string(line))
+}
+
+func TestCEFConfigValidate(t *testing.T) {
+	_, err := New(Config{CEF: &CEFConfig{
+		Extensions: map[string]string{"src ip": "ip"},
+	}})
+	assert.Error(t, err)
+
+	_, err = New(Config{CEF: &CEFConfig{
+		Severity: CEFField{Value: "11"},
+	}})
+	assert.Error(t, err)
+
+	_, err = New(Config{CEF: &CEFConfig{
+		Severity: CEFField{Value: "Very-High"},
+	}})
+	assert.NoError(t, err)
+
+	config := Config{JSON: &JSONConfig{}, CEF: &CEFConfig{}}
+	assert.Error(t, config.Validate())
+}
+
+func TestJSONEncode(t *testing.T) {
+	c, err := New(Config{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	line, err := c.Encode(common.MapStr{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(line))
+
+	line, err = NewJSON(true).Encode(common.MapStr{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": 1\n}", string(line))
+}
@@ -0,0 +1,84 @@
+// Package codec encodes events into the format written by the outputs. The
+// file, console, kafka, redis, logstash, syslog, socket, tcp and udp outputs
+// select the codec by their common codec setting, and encode events as JSON by
+// default.
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Codec encodes an event into the message written by an output.
+type Codec interface {
+	Encode(event common.MapStr) ([]byte, error)
+}
+
+// Config selects the codec of an output. At most one codec can be set. If no
+// codec is set, events are encoded as JSON.
+type Config struct {
+	JSON   *JSONConfig   `config:"json"`
+	CEF    *CEFConfig    `config:"cef"`
+	Format *FormatConfig `config:"format"`
+	Raw    *RawConfig    `config:"raw"`
+}
+
+// JSONConfig configures the JSON codec.
+type JSONConfig struct {
+	Pretty bool `config:"pretty"`
+}
+
+func (c *Config) Validate() error {
+	count := 0
+	for _, set := range []bool{c.JSON != nil, c.CEF != nil, c.Format != nil, c.Raw != nil} {
+		if set {
+			count++
+		}
+	}
+	if count > 1 {
+		return errors.New("only one codec can be configured")
+	}
+	return nil
+}
+
+// IsSet reports if a codec is configured.
+func (c *Config) IsSet() bool {
+	return c.JSON != nil || c.CEF != nil || c.Format != nil || c.Raw != nil
+}
+
+// New creates the configured codec.
+func New(config Config) (Codec, error) {
+	if config.CEF != nil {
+		return newCEFCodec(*config.CEF)
+	}
+	if config.Format != nil {
+		return formatCodec{format: config.Format.String}, nil
+	}
+	if config.Raw != nil {
+		return newRawCodec(*config.Raw), nil
+	}
+	if config.JSON != nil {
+		return jsonCodec{pretty: config.JSON.Pretty}, nil
+	}
+	return jsonCodec{}, nil
+}
+
+type jsonCodec struct {
+	pretty bool
+}
+
+// NewJSON creates a codec encoding events as JSON.
+func NewJSON(pretty bool) Codec {
+	return jsonCodec{pretty: pretty}
+}
+
+// Encode encodes the event without its @metadata.
+func (c jsonCodec) Encode(event common.MapStr) ([]byte, error) {
+	event = event.WithoutMetadata()
+	if c.pretty {
+		return json.MarshalIndent(event, "", "  ")
+	}
+	return json.Marshal(event)
+}
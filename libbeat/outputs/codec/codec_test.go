@@ -0,0 +1,26 @@
+// +build !integration
+
+package codec
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONEncodeWithoutMetadata(t *testing.T) {
+	c, err := New(Config{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	event := common.MapStr{
+		"message":          "hello",
+		common.MetadataKey: common.MapStr{"input": "log"},
+	}
+	line, err := c.Encode(event)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"message":"hello"}`, string(line))
+	assert.Contains(t, event, common.MetadataKey)
+}
@@ -0,0 +1,26 @@
+package codec
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+)
+
+// FormatConfig configures the format codec, which renders every event from a
+// format string, like `%{[@timestamp]} %{[message]}`.
+type FormatConfig struct {
+	String *fmtstr.EventFormatString `config:"string" validate:"required"`
+}
+
+type formatCodec struct {
+	format *fmtstr.EventFormatString
+}
+
+// Encode formats the event. An error is returned if a referenced field
+// without default value is missing in the event.
+func (c formatCodec) Encode(event common.MapStr) ([]byte, error) {
+	line, err := c.format.Run(event)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line), nil
+}
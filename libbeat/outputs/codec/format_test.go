@@ -0,0 +1,52 @@
+// +build !integration
+
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatEncode(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"format.string": "%{[@timestamp]} [%{[fields.level]:info}] %{[message]}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config Config
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(config)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ts := time.Date(2016, 11, 1, 10, 0, 0, 0, time.UTC)
+	line, err := c.Encode(common.MapStr{
+		"@timestamp": common.Time(ts),
+		"message":    "hello world",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "2016-11-01T10:00:00.000Z [info] hello world", string(line))
+
+	_, err = c.Encode(common.MapStr{"@timestamp": common.Time(ts)})
+	assert.Error(t, err)
+}
+
+func TestConfigValidateSingleCodec(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"json.pretty":   true,
+		"format.string": "%{[message]}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config Config
+	assert.Error(t, cfg.Unpack(&config))
+}
@@ -0,0 +1,44 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// RawConfig configures the raw codec, which writes the value of a single
+// event field, like the original log line, without encoding the event. The
+// default field is message.
+type RawConfig struct {
+	Field string `config:"field"`
+}
+
+type rawCodec struct {
+	field string
+}
+
+func newRawCodec(config RawConfig) rawCodec {
+	if config.Field == "" {
+		config.Field = "message"
+	}
+	return rawCodec{field: config.Field}
+}
+
+// Encode returns the value of the field. Strings are written as is, other
+// values as JSON. An error is returned if the event has no such field.
+func (c rawCodec) Encode(event common.MapStr) ([]byte, error) {
+	value, err := event.GetValue(c.field)
+	if err != nil {
+		return nil, fmt.Errorf("event has no field '%v' for the raw codec", c.field)
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
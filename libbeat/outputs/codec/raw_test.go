@@ -0,0 +1,42 @@
+// +build !integration
+
+package codec
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRawEncode(t *testing.T) {
+	c, err := New(Config{Raw: &RawConfig{}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	line, err := c.Encode(common.MapStr{
+		"message": `127.0.0.1 - - "GET / HTTP/1.1" 200`,
+		"type":    "log",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `127.0.0.1 - - "GET / HTTP/1.1" 200`, string(line))
+
+	_, err = c.Encode(common.MapStr{"type": "log"})
+	assert.Error(t, err)
+}
+
+func TestRawEncodeNestedField(t *testing.T) {
+	c, err := New(Config{Raw: &RawConfig{Field: "http.request"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	line, err := c.Encode(common.MapStr{
+		"http": common.MapStr{
+			"request": common.MapStr{"method": "GET"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"method":"GET"}`, string(line))
+}
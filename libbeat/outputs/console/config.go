@@ -1,7 +1,10 @@
 package console
 
+import "github.com/elastic/beats/libbeat/outputs/codec"
+
 type config struct {
-	Pretty bool `config:"pretty"`
+	Pretty bool         `config:"pretty"`
+	Codec  codec.Config `config:"codec"`
 }
 
 var (
@@ -1,34 +1,46 @@
 package console
 
 import (
-	"encoding/json"
 	"os"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 func init() {
-	outputs.RegisterOutputPlugin("console", New)
+	if err := outputs.RegisterOutputPlugin("console", New); err != nil {
+		panic(err)
+	}
 }
 
 type console struct {
-	config config
+	codec codec.Codec
 }
 
-func New(config *common.Config, _ int) (outputs.Outputer, error) {
-	c := &console{config: defaultConfig}
-	err := config.Unpack(&c.config)
+func New(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	config := defaultConfig
+	err := cfg.Unpack(&config)
 	if err != nil {
 		return nil, err
 	}
-	return c, nil
+
+	// pretty is kept as shortcut for the pretty option of the JSON codec
+	if !config.Codec.IsSet() {
+		config.Codec.JSON = &codec.JSONConfig{Pretty: config.Pretty}
+	}
+
+	enc, err := codec.New(config.Codec)
+	if err != nil {
+		return nil, err
+	}
+	return &console{codec: enc}, nil
 }
 
 func newConsole(pretty bool) *console {
-	return &console{config{pretty}}
+	return &console{codec: codec.NewJSON(pretty)}
 }
 
 func writeBuffer(buf []byte) error {
@@ -54,21 +66,14 @@ func (c *console) PublishEvent(
 	opts outputs.Options,
 	event common.MapStr,
 ) error {
-	var jsonEvent []byte
-	var err error
-
-	if c.config.Pretty {
-		jsonEvent, err = json.MarshalIndent(event, "", "  ")
-	} else {
-		jsonEvent, err = json.Marshal(event)
-	}
+	line, err := c.codec.Encode(event)
 	if err != nil {
-		logp.Err("Fail to convert the event to JSON (%v): %#v", err, event)
+		logp.Err("Fail to encode the event (%v): %#v", err, event)
 		op.SigCompleted(s)
 		return err
 	}
 
-	if err = writeBuffer(jsonEvent); err != nil {
+	if err = writeBuffer(line); err != nil {
 		goto fail
 	}
 	if err = writeBuffer([]byte{'\n'}); err != nil {
@@ -93,3 +93,24 @@ func TestConsoleMultipleEventsIndented(t *testing.T) {
 		"{\n  \"event\": \"event3\"\n}\n"
 	assert.Equal(t, expected, lines)
 }
+
+func TestConsoleFormatCodec(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"codec.format.string": "%{[type]}: %{[message]}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := New(cfg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := run(out.(*console),
+		common.MapStr{"type": "log", "message": "line 1"},
+		common.MapStr{"type": "log"},
+		common.MapStr{"type": "log", "message": "line 3"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "log: line 1\nlog: line 3\n", lines)
+}
@@ -0,0 +1,15 @@
+package outputs
+
+import "github.com/elastic/beats/libbeat/common"
+
+// DeadLetter is called with the events an output drops because they have
+// been rejected permanently, together with the reason of the rejection.
+type DeadLetter func(event common.MapStr, reason string)
+
+// DeadLetterOutputer is implemented by outputs reporting the events they
+// drop, e.g. events rejected by Elasticsearch due to mapping errors.
+type DeadLetterOutputer interface {
+	// SetDeadLetter registers the function receiving the dropped events. It
+	// is called before the first event is published.
+	SetDeadLetter(DeadLetter)
+}
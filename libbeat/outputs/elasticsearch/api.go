@@ -152,6 +152,21 @@ func (es *Connection) SearchURI(index string, docType string, params map[string]
 	return status, result, err
 }
 
+// Search executes a search request with the query passed in the request body.
+// Implements: https://www.elastic.co/guide/en/elasticsearch/reference/current/search-request-body.html
+func (es *Connection) Search(
+	index string, docType string,
+	params map[string]string,
+	body interface{},
+) (int, *SearchResults, error) {
+	status, resp, err := es.apiCall("POST", index, docType, "_search", params, body)
+	if err != nil {
+		return status, nil, err
+	}
+	result, err := readSearchResult(resp)
+	return status, result, err
+}
+
 func (es *Connection) CountSearchURI(
 	index string, docType string,
 	params map[string]string,
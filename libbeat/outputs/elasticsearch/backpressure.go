@@ -0,0 +1,130 @@
+package elasticsearch
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+const (
+	backpressureInitDelay = 1 * time.Second
+	backpressureMaxDelay  = 60 * time.Second
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	// number of connections currently slowed down due to backpressure
+	statBackpressure = expvar.NewInt("libbeat.es.backpressure.active")
+
+	// number of bulk requests rejected by Elasticsearch due to full queues
+	statRejectedRequests = expvar.NewInt("libbeat.es.backpressure.rejected_requests")
+)
+
+// backpressure adapts the rate and size of bulk requests if Elasticsearch
+// rejects requests due to full queues (HTTP status 429 or items failing with
+// es_rejected_execution_exception). On each rejection the batch size is
+// halved and the delay before sending the next bulk request is doubled. On
+// each successful request the batch size is doubled and the delay halved,
+// until the normal rate is reached again.
+type backpressure struct {
+	batch int // maximum batch size, 0 if not limited
+	delay time.Duration
+
+	initDelay, maxDelay time.Duration
+
+	sleep func(d time.Duration, done <-chan struct{}) bool
+}
+
+func newBackpressure(initDelay, maxDelay time.Duration) *backpressure {
+	return &backpressure{
+		initDelay: initDelay,
+		maxDelay:  maxDelay,
+		sleep:     sleep,
+	}
+}
+
+// active returns true if bulk requests are currently slowed down.
+func (b *backpressure) active() bool {
+	return b.batch > 0 || b.delay > 0
+}
+
+// batchSize returns the number of events out of n to be sent in the next
+// bulk request.
+func (b *backpressure) batchSize(n int) int {
+	if b.batch > 0 && b.batch < n {
+		return b.batch
+	}
+	return n
+}
+
+// wait blocks for the current delay before a bulk request can be sent. It
+// returns false if done is closed while waiting.
+func (b *backpressure) wait(done <-chan struct{}) bool {
+	if b.delay <= 0 {
+		return true
+	}
+	debugf("Backpressure: waiting %v before sending bulk request", b.delay)
+	return b.sleep(b.delay, done)
+}
+
+// sleep blocks for d. It returns false if done is closed before.
+func sleep(d time.Duration, done <-chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// rejected reduces the rate and size of bulk requests after a bulk request
+// of size n has been rejected.
+func (b *backpressure) rejected(n int) {
+	statRejectedRequests.Add(1)
+	if !b.active() {
+		statBackpressure.Add(1)
+	}
+
+	b.batch = b.batchSize(n) / 2
+	if b.batch < 1 {
+		b.batch = 1
+	}
+
+	b.delay *= 2
+	if b.delay < b.initDelay {
+		b.delay = b.initDelay
+	}
+	if b.delay > b.maxDelay {
+		b.delay = b.maxDelay
+	}
+
+	logp.Warn("Elasticsearch is overloaded, reducing send rate (batch size=%v, delay=%v)",
+		b.batch, b.delay)
+}
+
+// succeeded increases the rate and size of bulk requests after a successful
+// bulk request. total is the number of events passed to the client, which is
+// the batch size used if not slowed down.
+func (b *backpressure) succeeded(total int) {
+	if !b.active() {
+		return
+	}
+
+	b.delay /= 2
+	if b.delay < b.initDelay {
+		b.delay = 0
+	}
+
+	b.batch *= 2
+	if b.batch >= total {
+		b.batch = 0
+	}
+
+	if !b.active() {
+		statBackpressure.Add(-1)
+		logp.Info("Elasticsearch backpressure resolved, sending at normal rate")
+	}
+}
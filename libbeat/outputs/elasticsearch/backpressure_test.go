@@ -0,0 +1,165 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackpressureAdapts(t *testing.T) {
+	b := newBackpressure(1*time.Second, 4*time.Second)
+	assert.False(t, b.active())
+	assert.Equal(t, 50, b.batchSize(50))
+
+	b.rejected(50)
+	assert.True(t, b.active())
+	assert.Equal(t, 25, b.batchSize(50))
+	assert.Equal(t, 10, b.batchSize(10))
+	assert.Equal(t, 1*time.Second, b.delay)
+
+	b.rejected(50)
+	b.rejected(50)
+	b.rejected(50)
+	assert.Equal(t, 3, b.batchSize(50))
+	assert.Equal(t, 4*time.Second, b.delay)
+
+	b.succeeded(50)
+	assert.Equal(t, 6, b.batchSize(50))
+	assert.Equal(t, 2*time.Second, b.delay)
+
+	for i := 0; i < 3; i++ {
+		b.succeeded(50)
+	}
+	assert.Equal(t, 48, b.batchSize(50))
+	assert.Equal(t, time.Duration(0), b.delay)
+	assert.True(t, b.active())
+
+	b.succeeded(50)
+	assert.False(t, b.active())
+	assert.Equal(t, 50, b.batchSize(50))
+}
+
+func TestPublishEventsBackpressure(t *testing.T) {
+	var requests []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" {
+			return
+		}
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `{"version": {"number": "5.0.0"}}`)
+			return
+		}
+
+		lines := 0
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines++
+		}
+		requests = append(requests, lines/2)
+
+		if len(requests) == 1 {
+			w.WriteHeader(429)
+			return
+		}
+		item := `{"create": {"status": 201}}`
+		items := strings.Repeat(item+",", lines/2-1) + item
+		fmt.Fprintf(w, `{"items": [%v]}`, items)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test", nil, nil, "", "", nil, 10*time.Second, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var sleeps []time.Duration
+	client.backpressure.sleep = func(d time.Duration, done <-chan struct{}) bool {
+		sleeps = append(sleeps, d)
+		return true
+	}
+
+	events := make([]common.MapStr, 10)
+	for i := range events {
+		events[i] = common.MapStr{
+			"@timestamp": common.Time(time.Now()),
+			"type":       "test",
+			"i":          i,
+		}
+	}
+
+	failed, err := client.PublishEvents(events)
+	assert.NotNil(t, err)
+	assert.Len(t, failed, 10)
+	assert.True(t, client.IsConnected())
+
+	failed, err = client.PublishEvents(failed)
+	assert.Nil(t, err)
+	assert.Len(t, failed, 0)
+
+	assert.Equal(t, []int{10, 5, 5}, requests)
+	assert.Equal(t, []time.Duration{backpressureInitDelay}, sleeps)
+	assert.False(t, client.backpressure.active())
+}
+
+func TestBackpressureWaitCanceled(t *testing.T) {
+	b := newBackpressure(time.Minute, time.Minute)
+	assert.True(t, b.wait(nil))
+
+	b.rejected(10)
+	done := make(chan struct{})
+	close(done)
+	assert.False(t, b.wait(done))
+}
+
+func TestCloseCancelsBackpressure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" && r.Method == "GET" {
+			fmt.Fprint(w, `{"version": {"number": "5.0.0"}}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test", nil, nil, "", "", nil, 10*time.Second, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	client.backpressure.rejected(10)
+	client.backpressure.delay = time.Hour
+
+	events := []common.MapStr{{"@timestamp": common.Time(time.Now()), "type": "test"}}
+	result := make(chan error)
+	go func() {
+		_, err := client.PublishEvents(events)
+		result <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	client.Close()
+	select {
+	case err := <-result:
+		assert.Equal(t, ErrNotConnected, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("PublishEvents still waiting after Close")
+	}
+
+	// the client waits again for backpressure after reconnecting
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, client.doneChan())
+}
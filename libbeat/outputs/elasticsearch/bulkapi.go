@@ -9,12 +9,13 @@ import (
 )
 
 type bulkMeta struct {
-	Index bulkMetaIndex `json:"index"`
+	Index  *bulkMetaIndex `json:"index,omitempty"`
+	Create *bulkMetaIndex `json:"create,omitempty"`
 }
 
 type bulkMetaIndex struct {
 	Index   string `json:"_index"`
-	DocType string `json:"_type"`
+	DocType string `json:"_type,omitempty"`
 }
 
 // MetaBuilder creates meta data for bulk requests
@@ -124,6 +125,16 @@ func (r *bulkRequest) Reset(body bodyEncoder) {
 	body.AddHeader(&r.requ.Header)
 }
 
+// ResetStream prepares the request for sending a body of unknown length
+// using chunked transfer encoding. The headers are set by the encoder.
+func (r *bulkRequest) ResetStream(body io.ReadCloser, enc bulkBodyEncoder) {
+	r.requ.ContentLength = -1
+	r.requ.Header = http.Header{}
+	r.requ.Body = body
+
+	enc.AddHeader(&r.requ.Header)
+}
+
 func (conn *Connection) sendBulkRequest(requ *bulkRequest) (int, bulkResult, error) {
 	status, resp, err := conn.execHTTPRequest(requ.requ)
 	if err != nil {
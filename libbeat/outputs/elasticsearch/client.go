@@ -3,6 +3,7 @@ package elasticsearch
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"expvar"
 	"fmt"
@@ -10,10 +11,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
@@ -23,15 +28,40 @@ type Client struct {
 	index  string
 	params map[string]string
 
+	// compiled index setting, nil if the index is no format string
+	indexFormat *fmtstr.EventFormatString
+
+	// name of the data stream to append events to, empty if events are
+	// indexed into daily indices
+	dataStream string
+
+	// drops fields not defined in the template, nil if disabled
+	pruner *fieldPruner
+
+	// receives events rejected permanently, nil if disabled
+	deadLetter outputs.DeadLetter
+
 	// buffered bulk requests
 	bulkRequ *bulkRequest
 
+	// encodes events into bulk requests while sending
+	stream *bulkStream
+
 	// buffered json response reader
 	json jsonReader
 
+	// adapts send rate if elasticsearch is overloaded
+	backpressure *backpressure
+
+	// closed by Close to cancel waiting for backpressure, created by Connect
+	doneMutex sync.Mutex
+	done      chan struct{}
+
 	// additional configs
 	compressionLevel int
 	proxyURL         *url.URL
+	resolver         transport.Resolver
+	throttle         *transport.Throttle
 }
 
 type connectCallback func(client *Client) error
@@ -43,6 +73,7 @@ type Connection struct {
 
 	http              *http.Client
 	connected         bool
+	version           esVersion
 	onConnectCallback func() error
 
 	encoder bodyEncoder
@@ -81,26 +112,28 @@ func NewClient(
 	compression int,
 	onConnectCallback connectCallback,
 ) (*Client, error) {
-	proxy := http.ProxyFromEnvironment
-	if proxyURL != nil {
-		proxy = http.ProxyURL(proxyURL)
-	}
-
 	logp.Info("Elasticsearch url: %s", esURL)
 
-	dialer := transport.NetDialer(timeout)
-	dialer = transport.StatsDialer(dialer, &transport.IOStats{
-		Read:        statReadBytes,
-		Write:       statWriteBytes,
-		ReadErrors:  statReadErrors,
-		WriteErrors: statWriteErrors,
-	})
+	indexFormat, err := compileIndex(index)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, dialer, err := transport.HTTPProxy(proxyURL, newDialer(timeout, nil, nil))
+	if err != nil {
+		return nil, err
+	}
 
 	bulkRequ, err := newBulkRequest(esURL, "", "", params, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	stream, err := newBulkStream(compression)
+	if err != nil {
+		return nil, err
+	}
+
 	var encoder bodyEncoder
 	if compression == 0 {
 		encoder = newJSONEncoder(nil)
@@ -126,16 +159,23 @@ func NewClient(
 			},
 			encoder: encoder,
 		},
-		index:  index,
-		params: params,
+		index:       index,
+		indexFormat: indexFormat,
+		params:      params,
 
 		bulkRequ: bulkRequ,
+		stream:   stream,
+
+		backpressure: newBackpressure(backpressureInitDelay, backpressureMaxDelay),
 
 		compressionLevel: compression,
 		proxyURL:         proxyURL,
 	}
 
 	client.Connection.onConnectCallback = func() error {
+		if err := client.checkFeatures(); err != nil {
+			return err
+		}
 		if onConnectCallback != nil {
 			return onConnectCallback(client)
 		}
@@ -145,6 +185,34 @@ func NewClient(
 	return client, nil
 }
 
+func newDialer(
+	timeout time.Duration,
+	resolver transport.Resolver,
+	throttle *transport.Throttle,
+) transport.Dialer {
+	dialer := transport.StatsDialer(transport.ResolverNetDialer(timeout, resolver), &transport.IOStats{
+		Read:        statReadBytes,
+		Write:       statWriteBytes,
+		ReadErrors:  statReadErrors,
+		WriteErrors: statWriteErrors,
+	})
+	return transport.ThrottleDialer(throttle, dialer)
+}
+
+// setDialer configures the client to resolve the host of the URL with
+// resolver instead of the system resolver, and to limit the bytes sent with
+// throttle. Both are optional.
+func (client *Client) setDialer(resolver transport.Resolver, throttle *transport.Throttle) {
+	client.resolver = resolver
+	client.throttle = throttle
+	t := client.http.Transport.(*http.Transport)
+
+	// the proxy URL was already checked by NewClient
+	_, dialer, _ := transport.HTTPProxy(client.proxyURL,
+		newDialer(client.http.Timeout, resolver, throttle))
+	t.Dial = dialer.Dial
+}
+
 func (client *Client) Clone() *Client {
 	// when cloning the connection callback and params are not copied. A
 	// client's close is for example generated for topology-map support. With params
@@ -164,16 +232,50 @@ func (client *Client) Clone() *Client {
 		client.compressionLevel,
 		nil, // XXX: do not pass connection callback?
 	)
+	if c != nil && (client.resolver != nil || client.throttle != nil) {
+		c.setDialer(client.resolver, client.throttle)
+	}
 	return c
 }
 
+// Connect connects to Elasticsearch. Waiting for backpressure in
+// PublishEvents is canceled once the client is closed.
+func (client *Client) Connect(timeout time.Duration) error {
+	client.doneMutex.Lock()
+	if client.done == nil {
+		client.done = make(chan struct{})
+	}
+	client.doneMutex.Unlock()
+
+	return client.Connection.Connect(timeout)
+}
+
+// Close closes the connection and cancels waiting for backpressure.
+func (client *Client) Close() error {
+	client.doneMutex.Lock()
+	if client.done != nil {
+		close(client.done)
+		client.done = nil
+	}
+	client.doneMutex.Unlock()
+
+	return client.Connection.Close()
+}
+
+func (client *Client) doneChan() <-chan struct{} {
+	client.doneMutex.Lock()
+	defer client.doneMutex.Unlock()
+	return client.done
+}
+
 // PublishEvents sends all events to elasticsearch. On error a slice with all
 // events not published or confirmed to be processed by elasticsearch will be
 // returned. The input slice backing memory will be reused by return the value.
+// If elasticsearch signals backpressure, events are sent in smaller bulk
+// requests with a delay between requests.
 func (client *Client) PublishEvents(
 	events []common.MapStr,
 ) ([]common.MapStr, error) {
-	begin := time.Now()
 	publishEventsCallCount.Add(1)
 
 	if len(events) == 0 {
@@ -184,22 +286,60 @@ func (client *Client) PublishEvents(
 		return events, ErrNotConnected
 	}
 
-	body := client.encoder
-	body.Reset()
+	done := client.doneChan()
+	total := len(events)
+	var failedEvents []common.MapStr
+	for len(events) > 0 {
+		n := client.backpressure.batchSize(len(events))
+		batch := events[:n]
+		events = events[n:]
+
+		if !client.backpressure.wait(done) {
+			// the client has been closed while waiting
+			failedEvents = append(failedEvents, batch...)
+			return append(failedEvents, events...), ErrNotConnected
+		}
+		failed, rejected, err := client.publishBulk(batch)
+		if rejected {
+			client.backpressure.rejected(n)
+		} else if err == nil {
+			client.backpressure.succeeded(total)
+		}
 
-	// encode events into bulk request buffer, dropping failed elements from
-	// events slice
-	events = bulkEncodePublishRequest(body, client.index, events)
-	if len(events) == 0 {
-		return nil, nil
+		failedEvents = append(failedEvents, failed...)
+		if err != nil || rejected {
+			// return not yet published events too
+			failedEvents = append(failedEvents, events...)
+			if err == nil {
+				err = mode.ErrTempBulkFailure
+			}
+			return failedEvents, err
+		}
 	}
 
-	requ := client.bulkRequ
-	requ.Reset(body)
-	status, result, sendErr := client.sendBulkRequest(requ)
+	if len(failedEvents) > 0 {
+		return failedEvents, mode.ErrTempBulkFailure
+	}
+	return nil, nil
+}
+
+// publishBulk sends the events in one bulk request. It returns the events
+// to be retried and whether the request or some items have been rejected
+// by elasticsearch due to full queues.
+func (client *Client) publishBulk(
+	events []common.MapStr,
+) ([]common.MapStr, bool, error) {
+	begin := time.Now()
+
+	// stream events into the bulk request body, dropping events failing to
+	// encode from the events slice
+	events, status, result, sendErr := client.sendBulkStream(events)
+	if len(events) == 0 {
+		return nil, false, nil
+	}
 	if sendErr != nil {
 		logp.Err("Failed to perform any bulk index operations: %s", sendErr)
-		return events, sendErr
+		return events, status == 429, sendErr
 	}
 
 	debugf("PublishEvents: %d metrics have been  published to elasticsearch in %v.",
@@ -208,52 +348,62 @@ func (client *Client) PublishEvents(
 
 	// check response for transient errors
 	var failedEvents []common.MapStr
+	rejected := 0
 	if status != 200 {
 		failedEvents = events
 	} else {
 		client.json.init(result.raw)
-		failedEvents = bulkCollectPublishFails(&client.json, events)
+		failedEvents, rejected = bulkCollectPublishFails(&client.json, events, client.deadLetter)
 	}
 
 	ackedEvents.Add(int64(len(events) - len(failedEvents)))
 	eventsNotAcked.Add(int64(len(failedEvents)))
-	if len(failedEvents) > 0 {
-		if sendErr == nil {
-			sendErr = mode.ErrTempBulkFailure
-		}
-		return failedEvents, sendErr
+	return failedEvents, rejected > 0, nil
+}
+
+// dataStreamName returns the data stream to append the event to. The
+// namespace of the event, if set, replaces the configured namespace.
+func (client *Client) dataStreamName(event common.MapStr) string {
+	namespace := common.GetNamespace(event)
+	if namespace == "" {
+		return client.dataStream
 	}
 
-	return nil, nil
+	// type and dataset never contain '-'
+	parts := strings.SplitN(client.dataStream, "-", 3)
+	return parts[0] + "-" + parts[1] + "-" + namespace
 }
 
-// fillBulkRequest encodes all bulk requests and returns slice of events
-// successfully added to bulk request.
-func bulkEncodePublishRequest(
-	body bulkWriter,
-	index string,
-	events []common.MapStr,
-) []common.MapStr {
-	okEvents := events[:0]
-	for _, event := range events {
-		meta := eventBulkMeta(index, event)
-		err := body.Add(meta, event)
-		if err != nil {
-			logp.Err("Failed to encode event: %s", err)
-			continue
-		}
+// eventBody returns the document to index for the event.
+func (client *Client) eventBody(event common.MapStr) common.MapStr {
+	body := event.WithoutMetadata()
+	if client.pruner != nil {
+		body = client.pruner.prune(body)
+	}
+	return body
+}
+
+// bulkMeta returns the bulk action for the event. In data stream mode events
+// are appended to the data stream using the create action.
+func (client *Client) bulkMeta(event common.MapStr) (bulkMeta, error) {
+	if client.dataStream != "" {
+		return bulkMeta{
+			Create: &bulkMetaIndex{Index: client.dataStreamName(event)},
+		}, nil
+	}
 
-		okEvents = append(okEvents, event)
+	index, err := client.eventIndex(event)
+	if err != nil {
+		return bulkMeta{}, err
 	}
-	return okEvents
+	return eventBulkMeta(index, client.docType(event)), nil
 }
 
-func eventBulkMeta(index string, event common.MapStr) bulkMeta {
-	index = getIndex(event, index)
+func eventBulkMeta(index, docType string) bulkMeta {
 	meta := bulkMeta{
-		Index: bulkMetaIndex{
+		Index: &bulkMetaIndex{
 			Index:   index,
-			DocType: event["type"].(string),
+			DocType: docType,
 		},
 	}
 	return meta
@@ -280,6 +430,12 @@ func getIndex(event common.MapStr, index string) string {
 		}
 	}
 
+	// Append the namespace, such that events of different tenants are
+	// stored in different indices
+	if namespace := common.GetNamespace(event); namespace != "" {
+		index += "-" + namespace
+	}
+
 	// Append timestamp to index
 	index = fmt.Sprintf("%s-%d.%02d.%02d", index,
 		ts.Year(), ts.Month(), ts.Day())
@@ -288,16 +444,20 @@ func getIndex(event common.MapStr, index string) string {
 }
 
 // bulkCollectPublishFails checks per item errors returning all events
-// to be tried again due to error code returned for that items. If indexing an
-// event failed due to some error in the event itself (e.g. does not respect mapping),
-// the event will be dropped.
+// to be tried again due to error code returned for that items. Errors are
+// classified and counted per class. If indexing an event failed due to some
+// error in the event itself (e.g. does not respect mapping) or the state of
+// the index (e.g. index closed), the event will be dropped and passed to
+// deadLetter, if set. The number of items rejected due to full queues in
+// elasticsearch is returned too.
 func bulkCollectPublishFails(
 	reader *jsonReader,
 	events []common.MapStr,
-) []common.MapStr {
+	deadLetter outputs.DeadLetter,
+) ([]common.MapStr, int) {
 	if err := reader.expectDict(); err != nil {
 		logp.Err("Failed to parse bulk respose: expected JSON object")
-		return nil
+		return nil, 0
 	}
 
 	// find 'items' field in response
@@ -305,12 +465,12 @@ func bulkCollectPublishFails(
 		kind, name, err := reader.nextFieldName()
 		if err != nil {
 			logp.Err("Failed to parse bulk response")
-			return nil
+			return nil, 0
 		}
 
 		if kind == dictEnd {
 			logp.Err("Failed to parse bulk response: no 'items' field in response")
-			return nil
+			return nil, 0
 		}
 
 		// found items array -> continue
@@ -324,32 +484,42 @@ func bulkCollectPublishFails(
 	// check items field is an array
 	if err := reader.expectArray(); err != nil {
 		logp.Err("Failed to parse bulk respose: expected items array")
-		return nil
+		return nil, 0
 	}
 
 	count := len(events)
 	failed := events[:0]
+	rejected := 0
 	for i := 0; i < count; i++ {
 		status, msg, err := itemStatus(reader)
 		if err != nil {
-			return nil
+			return nil, 0
 		}
 
 		if status < 300 {
 			continue // ok value
 		}
 
-		if status < 500 && status != 429 {
+		class := classifyItemError(status, msg)
+		statItemErrors.Add(class.String(), 1)
+		if !class.retryable() {
 			// hard failure, don't collect
-			logp.Warn("Can not index event (status=%v): %s", status, msg)
+			logp.Warn("Can not index event (status=%v, error=%v): %s", status, class, msg)
+			if deadLetter != nil && class != errClassVersionConflict {
+				// version conflicts are reported for events already indexed
+				deadLetter(events[i], fmt.Sprintf("%v (status=%v): %s", class, status, msg))
+			}
 			continue
 		}
 
-		logp.Info("Bulk item insert failed (i=%v, status=%v): %s", i, status, msg)
+		if class == errClassRejectedExecution {
+			rejected++
+		}
+		logp.Info("Bulk item insert failed (i=%v, status=%v, error=%v): %s", i, status, class, msg)
 		failed = append(failed, events[i])
 	}
 
-	return failed
+	return failed, rejected
 }
 
 func itemStatus(reader *jsonReader) (int, []byte, error) {
@@ -434,12 +604,23 @@ func (client *Client) PublishEvent(event common.MapStr) error {
 		return ErrNotConnected
 	}
 
-	index := getIndex(event, client.index)
 	debugf("Publish event: %s", event)
 
 	// insert the events one by one
+	var index string
+	docType, params := client.docType(event), client.params
+	if client.dataStream != "" {
+		index, docType, params = client.dataStreamName(event), "_doc", withOpTypeCreate(params)
+	} else {
+		var err error
+		if index, err = client.eventIndex(event); err != nil {
+			// the event can not be indexed => don't retry
+			logp.Err("Failed to select index: %s", err)
+			return nil
+		}
+	}
 	status, _, err := client.Index(
-		index, event["type"].(string), "", client.params, event)
+		index, docType, "", params, client.eventBody(event))
 	if err != nil {
 		logp.Warn("Fail to insert a single event: %s", err)
 		if err == ErrJSONEncodeFailed {
@@ -460,12 +641,91 @@ func (client *Client) PublishEvent(event common.MapStr) error {
 	return nil
 }
 
+// checkFeatures checks the Elasticsearch version connected to supports all
+// features required by the client configuration.
+func (client *Client) checkFeatures() error {
+	if !client.version.known() {
+		return nil
+	}
+
+	if pipeline := client.params["pipeline"]; pipeline != "" && client.version.less(versionIngest) {
+		return fmt.Errorf("ingest pipeline '%v' is configured, but ingest node pipelines require Elasticsearch %v or later (connected to %v)",
+			pipeline, versionIngest, client.version)
+	}
+	if client.dataStream != "" && client.version.less(versionDataStreams) {
+		return fmt.Errorf("data stream mode is enabled, but data streams require Elasticsearch %v or later (connected to %v)",
+			versionDataStreams, client.version)
+	}
+	return nil
+}
+
+// withOpTypeCreate returns a copy of params with op_type set to create.
+func withOpTypeCreate(params map[string]string) map[string]string {
+	create := map[string]string{"op_type": "create"}
+	for k, v := range params {
+		create[k] = v
+	}
+	return create
+}
+
+// docType returns the mapping type to index the event with.
+func (client *Client) docType(event common.MapStr) string {
+	if client.version.atLeast(versionSingleType) {
+		return docTypeSingle
+	}
+	return event["type"].(string)
+}
+
 // LoadTemplate loads a template into Elasticsearch overwriting the existing
 // template if it exists. If you wish to not overwrite an existing template
 // then use CheckTemplate prior to calling this method.
 func (client *Client) LoadTemplate(templateName string, template map[string]interface{}) error {
+	return client.loadTemplate("/_template/", templateName, template)
+}
 
-	path := "/_template/" + templateName
+// CheckTemplate checks if a given template already exist. It returns true if
+// and only if Elasticsearch returns with HTTP status code 200.
+func (client *Client) CheckTemplate(templateName string) bool {
+	return client.checkTemplate("/_template/", templateName)
+}
+
+// GetTemplateVersion returns the version of the given template, and whether
+// the template exists. The version is 0 if the template is unversioned.
+func (client *Client) GetTemplateVersion(templateName string) (int, bool, error) {
+	status, body, err := client.request("GET", "/_template/"+templateName, nil, nil)
+	if status == 404 {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	var templates map[string]struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(body, &templates); err != nil {
+		return 0, false, fmt.Errorf("failed to parse template response: %v", err)
+	}
+
+	template, exists := templates[templateName]
+	return template.Version, exists, nil
+}
+
+// LoadIndexTemplate loads a composable index template, as required for data
+// streams, into Elasticsearch overwriting the existing template if it exists.
+func (client *Client) LoadIndexTemplate(templateName string, template map[string]interface{}) error {
+	return client.loadTemplate("/_index_template/", templateName, template)
+}
+
+// CheckIndexTemplate checks if a given composable index template already
+// exists.
+func (client *Client) CheckIndexTemplate(templateName string) bool {
+	return client.checkTemplate("/_index_template/", templateName)
+}
+
+func (client *Client) loadTemplate(api, templateName string, template map[string]interface{}) error {
+
+	path := api + templateName
 	status, _, err := client.request("PUT", path, nil, template)
 
 	if err != nil {
@@ -480,11 +740,9 @@ func (client *Client) LoadTemplate(templateName string, template map[string]inte
 	return nil
 }
 
-// CheckTemplate checks if a given template already exist. It returns true if
-// and only if Elasticsearch returns with HTTP status code 200.
-func (client *Client) CheckTemplate(templateName string) bool {
+func (client *Client) checkTemplate(api, templateName string) bool {
 
-	status, _, _ := client.request("HEAD", "/_template/"+templateName, nil, nil)
+	status, _, _ := client.request("HEAD", api+templateName, nil, nil)
 
 	if status != 200 {
 		return false
@@ -503,13 +761,51 @@ func (conn *Connection) Connect(timeout time.Duration) error {
 		return ErrNotConnected
 	}
 
+	conn.version = conn.queryVersion()
+	if conn.version.known() && conn.version.less(minSupportedVersion) {
+		conn.connected = false
+		return fmt.Errorf("Elasticsearch version %v at %v is not supported, the minimum required version is %v",
+			conn.version, conn.URL, minSupportedVersion)
+	}
+
 	err = conn.onConnectCallback()
 	if err != nil {
+		conn.connected = false
 		return fmt.Errorf("Connection marked as failed because the onConnect callback failed: %v", err)
 	}
 	return nil
 }
 
+// queryVersion requests the version of the Elasticsearch node. If the version
+// can not be determined, e.g. because a proxy restricts access to the
+// Elasticsearch API, a warning is logged and the output falls back to the
+// default behavior.
+func (conn *Connection) queryVersion() esVersion {
+	status, body, err := conn.request("GET", "/", nil, nil)
+	if err == nil && status != 200 {
+		err = fmt.Errorf("status %v", status)
+	}
+	if err != nil {
+		logp.Warn("Failed to query Elasticsearch version at %v: %v", conn.URL, err)
+		return esVersion{}
+	}
+
+	version, err := parseVersionResponse(body)
+	if err != nil {
+		logp.Warn("Failed to parse Elasticsearch version at %v: %v", conn.URL, err)
+		return esVersion{}
+	}
+
+	logp.Info("Connected to Elasticsearch version %v at %v", version, conn.URL)
+	return version
+}
+
+// GetVersion returns the version of the Elasticsearch node connected to or
+// "unknown" if the version could not be determined.
+func (conn *Connection) GetVersion() string {
+	return conn.version.String()
+}
+
 func (conn *Connection) Ping(timeout time.Duration) (bool, error) {
 	debugf("ES Ping(url=%v, timeout=%v)", conn.URL, timeout)
 
@@ -582,7 +878,10 @@ func (conn *Connection) execHTTPRequest(req *http.Request) (int, []byte, error)
 
 	status := resp.StatusCode
 	if status >= 300 {
-		conn.connected = false
+		// elasticsearch being overloaded does not require a reconnect
+		if status != 429 {
+			conn.connected = false
+		}
 		return status, nil, fmt.Errorf("%v", resp.Status)
 	}
 
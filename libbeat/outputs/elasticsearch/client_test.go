@@ -3,6 +3,7 @@
 package elasticsearch
 
 import (
+	"expvar"
 	"fmt"
 	"strings"
 	"testing"
@@ -75,7 +76,7 @@ func TestCollectPublishFailsNone(t *testing.T) {
 	}
 
 	reader := newJSONReader(response)
-	res := bulkCollectPublishFails(reader, events)
+	res, _ := bulkCollectPublishFails(reader, events, nil)
 	assert.Equal(t, 0, len(res))
 }
 
@@ -93,7 +94,7 @@ func TestCollectPublishFailMiddle(t *testing.T) {
 	events := []common.MapStr{event, eventFail, event}
 
 	reader := newJSONReader(response)
-	res := bulkCollectPublishFails(reader, events)
+	res, _ := bulkCollectPublishFails(reader, events, nil)
 	assert.Equal(t, 1, len(res))
 	if len(res) == 1 {
 		assert.Equal(t, eventFail, res[0])
@@ -113,11 +114,47 @@ func TestCollectPublishFailAll(t *testing.T) {
 	events := []common.MapStr{event, event, event}
 
 	reader := newJSONReader(response)
-	res := bulkCollectPublishFails(reader, events)
+	res, _ := bulkCollectPublishFails(reader, events, nil)
 	assert.Equal(t, 3, len(res))
 	assert.Equal(t, events, res)
 }
 
+func TestCollectPublishFailDeadLetter(t *testing.T) {
+	response := []byte(`
+    { "items": [
+      {"create": {"status": 200}},
+      {"create": {"status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}}},
+      {"create": {"status": 409, "error": {"type": "version_conflict_engine_exception", "reason": "exists"}}},
+      {"create": {"status": 429, "error": "ups"}}
+    ]}
+  `)
+
+	events := []common.MapStr{
+		{"field": 1},
+		{"field": 2},
+		{"field": 3},
+		{"field": 4},
+	}
+
+	var rejected []common.MapStr
+	var reasons []string
+	deadLetter := func(event common.MapStr, reason string) {
+		rejected = append(rejected, event)
+		reasons = append(reasons, reason)
+	}
+
+	reader := newJSONReader(response)
+	res, _ := bulkCollectPublishFails(reader, events, deadLetter)
+	assert.Equal(t, []common.MapStr{{"field": 4}}, res)
+
+	// version conflicts are not reported
+	assert.Equal(t, []common.MapStr{{"field": 2}}, rejected)
+	if assert.Len(t, reasons, 1) {
+		assert.Contains(t, reasons[0], "mapping_conflict (status=400)")
+		assert.Contains(t, reasons[0], "failed to parse")
+	}
+}
+
 func TestGetIndexStandard(t *testing.T) {
 
 	time := time.Now().UTC()
@@ -132,6 +169,21 @@ func TestGetIndexStandard(t *testing.T) {
 	assert.Equal(t, index, "beatname-"+extension)
 }
 
+func TestGetIndexNamespace(t *testing.T) {
+
+	time := time.Now().UTC()
+	extension := fmt.Sprintf("%d.%02d.%02d", time.Year(), time.Month(), time.Day())
+
+	event := common.MapStr{
+		"@timestamp": common.Time(time),
+		"field":      1,
+	}
+	common.SetNamespace(event, "tenant_a")
+
+	index := getIndex(event, "beatname")
+	assert.Equal(t, index, "beatname-tenant_a-"+extension)
+}
+
 func TestGetIndexOverwrite(t *testing.T) {
 
 	time := time.Now().UTC()
@@ -164,7 +216,7 @@ func BenchmarkCollectPublishFailsNone(b *testing.B) {
 	reader := newJSONReader(nil)
 	for i := 0; i < b.N; i++ {
 		reader.init(response)
-		res := bulkCollectPublishFails(reader, events)
+		res, _ := bulkCollectPublishFails(reader, events, nil)
 		if len(res) != 0 {
 			b.Fail()
 		}
@@ -187,7 +239,7 @@ func BenchmarkCollectPublishFailMiddle(b *testing.B) {
 	reader := newJSONReader(nil)
 	for i := 0; i < b.N; i++ {
 		reader.init(response)
-		res := bulkCollectPublishFails(reader, events)
+		res, _ := bulkCollectPublishFails(reader, events, nil)
 		if len(res) != 1 {
 			b.Fail()
 		}
@@ -209,9 +261,117 @@ func BenchmarkCollectPublishFailAll(b *testing.B) {
 	reader := newJSONReader(nil)
 	for i := 0; i < b.N; i++ {
 		reader.init(response)
-		res := bulkCollectPublishFails(reader, events)
+		res, _ := bulkCollectPublishFails(reader, events, nil)
 		if len(res) != 3 {
 			b.Fail()
 		}
 	}
 }
+
+func TestCollectPublishFailsClassified(t *testing.T) {
+	response := []byte(`
+    { "items": [
+      {"create": {"status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse [x]"}}},
+      {"create": {"status": 409, "error": {"type": "version_conflict_engine_exception", "reason": "document already exists"}}},
+      {"create": {"status": 429, "error": {"type": "es_rejected_execution_exception", "reason": "rejected execution"}}},
+      {"create": {"status": 403, "error": {"type": "index_closed_exception", "reason": "closed"}}},
+      {"create": {"status": 503, "error": "UnavailableShardsException[primary shard is not active]"}}
+    ]}
+  `)
+
+	events := []common.MapStr{{"i": 0}, {"i": 1}, {"i": 2}, {"i": 3}, {"i": 4}}
+	before := map[string]int64{}
+	for _, class := range itemErrorClassNames {
+		before[class] = itemErrorCount(class)
+	}
+
+	reader := newJSONReader(response)
+	res, rejected := bulkCollectPublishFails(reader, events, nil)
+	assert.Equal(t, []common.MapStr{{"i": 2}, {"i": 4}}, res)
+	assert.Equal(t, 1, rejected)
+
+	for _, class := range []string{"mapping_conflict", "version_conflict",
+		"rejected_execution", "index_closed", "server_error"} {
+		assert.Equal(t, before[class]+1, itemErrorCount(class), class)
+	}
+	assert.Equal(t, before["other"], itemErrorCount("other"))
+}
+
+func TestClassifyItemError(t *testing.T) {
+	tests := []struct {
+		status int
+		msg    string
+		class  itemErrorClass
+	}{
+		{400, `{"type": "mapper_parsing_exception", "reason": "failed to parse"}`, errClassMappingConflict},
+		{400, `{"type": "illegal_argument_exception", "reason": "mapper [x] of different type"}`, errClassMappingConflict},
+		{400, `"MapperParsingException[failed to parse [x]]"`, errClassMappingConflict},
+		{409, `"VersionConflictEngineException[document already exists]"`, errClassVersionConflict},
+		{409, ``, errClassVersionConflict},
+		{429, `"EsRejectedExecutionException[rejected execution]"`, errClassRejectedExecution},
+		{429, `"ups"`, errClassRejectedExecution},
+		{403, `{"type": "index_closed_exception"}`, errClassIndexClosed},
+		{500, `{"type": "null_pointer_exception"}`, errClassServerError},
+		{400, `{"type": "illegal_argument_exception", "reason": "invalid"}`, errClassOther},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.class, classifyItemError(test.status, []byte(test.msg)), test.msg)
+	}
+}
+
+func itemErrorCount(class string) int64 {
+	v := statItemErrors.Get(class)
+	if v == nil {
+		return 0
+	}
+	return v.(*expvar.Int).Value()
+}
+
+func TestEventIndexFormat(t *testing.T) {
+	ts, _ := time.Parse(time.RFC3339, "2017-01-02T23:30:00-02:00")
+	client, err := NewClient("http://localhost:9200", "%{[type]}-%{+yyyy.MM.dd.HH}",
+		nil, nil, "", "", nil, time.Second, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// late events are indexed by their timestamp, names are lowercased
+	event := common.MapStr{"@timestamp": common.Time(ts), "type": "Log"}
+	index, err := client.eventIndex(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "log-2017.01.03.01", index)
+
+	common.SetNamespace(event, "tenant_a")
+	index, err = client.eventIndex(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "log-2017.01.03.01-tenant_a", index)
+
+	// beat.index selects a daily index
+	event = common.MapStr{
+		"@timestamp": common.Time(ts),
+		"beat":       common.MapStr{"index": "dynamicindex"},
+	}
+	index, err = client.eventIndex(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "dynamicindex-2017.01.03", index)
+
+	for _, invalid := range []common.MapStr{
+		{"@timestamp": common.Time(ts)},
+		{"@timestamp": common.Time(ts), "type": "a b"},
+		{"@timestamp": common.Time(ts), "type": "_log"},
+		{"type": "log"},
+	} {
+		_, err := client.eventIndex(invalid)
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func TestValidateIndex(t *testing.T) {
+	for _, valid := range []string{"", "beatname", "%{[type]}-%{+yyyy.MM}", "Beats-%{[beat.name]:x}"} {
+		assert.NoError(t, validateIndex(valid), valid)
+	}
+	for _, invalid := range []string{"%{[type]", "%{+yyyy.QQ}", "beats/%{[type]}", "_%{[type]}", "a,b"} {
+		assert.Error(t, validateIndex(invalid), invalid)
+	}
+}
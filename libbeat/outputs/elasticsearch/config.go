@@ -4,29 +4,49 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
 type elasticsearchConfig struct {
-	Protocol         string             `config:"protocol"`
-	Path             string             `config:"path"`
-	Params           map[string]string  `config:"parameters"`
-	Username         string             `config:"username"`
-	Password         string             `config:"password"`
-	ProxyURL         string             `config:"proxy_url"`
-	Index            string             `config:"index"`
-	LoadBalance      bool               `config:"loadbalance"`
-	CompressionLevel int                `config:"compression_level" validate:"min=0, max=9"`
-	TLS              *outputs.TLSConfig `config:"tls"`
-	MaxRetries       int                `config:"max_retries"`
-	Timeout          time.Duration      `config:"timeout"`
-	SaveTopology     bool               `config:"save_topology"`
-	Template         Template           `config:"template"`
+	Protocol         string                 `config:"protocol"`
+	Path             string                 `config:"path"`
+	Params           map[string]string      `config:"parameters"`
+	Username         string                 `config:"username"`
+	Password         string                 `config:"password"`
+	ProxyURL         string                 `config:"proxy_url"`
+	Index            string                 `config:"index"`
+	LoadBalance      bool                   `config:"loadbalance"`
+	CompressionLevel int                    `config:"compression_level" validate:"min=0, max=9"`
+	TLS              *outputs.TLSConfig     `config:"tls"`
+	MaxRetries       int                    `config:"max_retries"`
+	Timeout          time.Duration          `config:"timeout"`
+	SaveTopology     bool                   `config:"save_topology"`
+	Template         Template               `config:"template"`
+	DataStream       dataStreamConfig       `config:"data_stream"`
+	Ordering         outputs.OrderingConfig `config:"ordering"`
+	DNS              transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec   int                    `config:"max_bytes_per_second" validate:"min=0"`
 }
 
 type Template struct {
-	Name      string `config:"name"`
-	Path      string `config:"path"`
-	Overwrite bool   `config:"overwrite"`
+	Name        string           `config:"name"`
+	Path        string           `config:"path"`
+	Overwrite   bool             `config:"overwrite"`
+	Version     int              `config:"version" validate:"min=0"`
+	Upgrade     bool             `config:"upgrade"`
+	Versions    TemplateVersions `config:"versions"`
+	PruneFields PruneFields      `config:"prune_fields"`
+}
+
+// TemplateVersions configures the templates loaded into older Elasticsearch
+// versions.
+type TemplateVersions struct {
+	ES2x TemplateVersion `config:"2x"`
+}
+
+type TemplateVersion struct {
+	Enabled bool   `config:"enabled"`
+	Path    string `config:"path"`
 }
 
 const (
@@ -46,12 +66,21 @@ var (
 		CompressionLevel: 0,
 		TLS:              nil,
 		LoadBalance:      true,
+		Template: Template{
+			Versions: TemplateVersions{
+				ES2x: TemplateVersion{Enabled: true},
+			},
+		},
+		DataStream: defaultDataStreamConfig,
 	}
 )
 
 func (c *elasticsearchConfig) Validate() error {
+	if err := validateIndex(c.Index); err != nil {
+		return err
+	}
 	if c.ProxyURL != "" {
-		if _, err := parseProxyURL(c.ProxyURL); err != nil {
+		if _, err := transport.ParseProxyURL(c.ProxyURL); err != nil {
 			return err
 		}
 	}
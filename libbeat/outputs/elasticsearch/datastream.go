@@ -0,0 +1,100 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dataStreamConfig configures the data stream mode. In data stream mode all
+// events are appended to the data stream named type-dataset-namespace using
+// the create operation. Events are never updated or overwritten.
+type dataStreamConfig struct {
+	Enabled   bool   `config:"enabled"`
+	Type      string `config:"type"`
+	Dataset   string `config:"dataset"`
+	Namespace string `config:"namespace"`
+}
+
+var defaultDataStreamConfig = dataStreamConfig{
+	Enabled:   false,
+	Type:      "logs",
+	Dataset:   "generic",
+	Namespace: "default",
+}
+
+// Priority of the index templates installed for data streams. The priority
+// is higher than the one of the built-in templates of Elasticsearch matching
+// logs-*-* and metrics-*-*.
+const dataStreamTemplatePriority = 200
+
+// Characters not allowed in data stream names.
+const dataStreamInvalidChars = `\/*?"<>| ,#:`
+
+func (c *dataStreamConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	parts := []struct {
+		name, value string
+	}{
+		{"type", c.Type},
+		{"dataset", c.Dataset},
+		{"namespace", c.Namespace},
+	}
+	for _, part := range parts {
+		switch {
+		case part.value == "":
+			return fmt.Errorf("data_stream.%v must not be empty", part.name)
+		case part.value != strings.ToLower(part.value):
+			return fmt.Errorf("data_stream.%v '%v' must be lower case", part.name, part.value)
+		case strings.ContainsAny(part.value, dataStreamInvalidChars):
+			return fmt.Errorf("data_stream.%v '%v' must not contain any of '%v'",
+				part.name, part.value, dataStreamInvalidChars)
+		case part.name != "namespace" && strings.Contains(part.value, "-"):
+			return fmt.Errorf("data_stream.%v '%v' must not contain '-'", part.name, part.value)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the data stream events are written to.
+func (c *dataStreamConfig) Name() string {
+	return c.Type + "-" + c.Dataset + "-" + c.Namespace
+}
+
+// TemplateName returns the name of the index template installed for the
+// data stream. The template is shared by all namespaces of the dataset.
+func (c *dataStreamConfig) TemplateName() string {
+	return c.Type + "-" + c.Dataset
+}
+
+// IndexTemplate converts a beat template into an index template enabling the
+// data stream. The settings and the default mapping of the beat template are
+// used as template for the backing indices. template may be nil.
+func (c *dataStreamConfig) IndexTemplate(template map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{}
+	if settings, exists := template["settings"]; exists {
+		body["settings"] = settings
+	}
+	if mappings, ok := template["mappings"].(map[string]interface{}); ok {
+		if mapping, ok := mappings["_default_"].(map[string]interface{}); ok {
+			// _all is not supported by Elasticsearch versions supporting
+			// data streams
+			typeless := make(map[string]interface{}, len(mapping))
+			for k, v := range mapping {
+				if k != "_all" {
+					typeless[k] = v
+				}
+			}
+			body["mappings"] = typeless
+		}
+	}
+
+	return map[string]interface{}{
+		"index_patterns": []interface{}{c.TemplateName() + "-*"},
+		"data_stream":    map[string]interface{}{},
+		"priority":       dataStreamTemplatePriority,
+		"template":       body,
+	}
+}
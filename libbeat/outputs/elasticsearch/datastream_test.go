@@ -0,0 +1,160 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataStreamConfig(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"data_stream.enabled": true,
+		"data_stream.dataset": "nginx",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "logs-nginx-default", config.DataStream.Name())
+	assert.Equal(t, "logs-nginx", config.DataStream.TemplateName())
+}
+
+func TestDataStreamConfigInvalid(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"data_stream.dataset": ""},
+		{"data_stream.dataset": "Nginx"},
+		{"data_stream.dataset": "nginx-access"},
+		{"data_stream.namespace": "prod*"},
+	}
+
+	for _, test := range tests {
+		test["data_stream.enabled"] = true
+		cfg, err := common.NewConfigFrom(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", test)
+	}
+
+	// settings are not validated if data streams are disabled
+	cfg, _ := common.NewConfigFrom(map[string]interface{}{"data_stream.dataset": "Nginx"})
+	config := defaultConfig
+	assert.NoError(t, cfg.Unpack(&config))
+}
+
+func TestDataStreamIndexTemplate(t *testing.T) {
+	ds := defaultDataStreamConfig
+	template := map[string]interface{}{
+		"template": "beat-*",
+		"settings": map[string]interface{}{"index.refresh_interval": "5s"},
+		"mappings": map[string]interface{}{
+			"_default_": map[string]interface{}{
+				"_all":       map[string]interface{}{"norms": false},
+				"properties": map[string]interface{}{},
+			},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"index_patterns": []interface{}{"logs-generic-*"},
+		"data_stream":    map[string]interface{}{},
+		"priority":       dataStreamTemplatePriority,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{"index.refresh_interval": "5s"},
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{},
+			},
+		},
+	}, ds.IndexTemplate(template))
+
+	assert.Equal(t, map[string]interface{}{}, ds.IndexTemplate(nil)["template"])
+}
+
+func TestDataStreamPublishEvents(t *testing.T) {
+	var actions []map[string]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "HEAD":
+			return
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `{"version": {"number": "7.10.0"}}`)
+			return
+		}
+
+		lines := 0
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			if lines%2 == 0 {
+				var action map[string]map[string]interface{}
+				json.Unmarshal(scanner.Bytes(), &action)
+				actions = append(actions, action)
+			}
+			lines++
+		}
+		item := `{"create": {"status": 201}}`
+		items := strings.Repeat(item+",", lines/2-1) + item
+		fmt.Fprintf(w, `{"items": [%v]}`, items)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, "test", nil, nil, "", "", nil, 10*time.Second, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.dataStream = "logs-generic-default"
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []common.MapStr{
+		{"@timestamp": common.Time(time.Now()), "type": "log"},
+		{"@timestamp": common.Time(time.Now()), "type": "log"},
+	}
+	failed, err := client.PublishEvents(events)
+	assert.NoError(t, err)
+	assert.Len(t, failed, 0)
+
+	expected := map[string]map[string]interface{}{
+		"create": {"_index": "logs-generic-default"},
+	}
+	assert.Equal(t, []map[string]map[string]interface{}{expected, expected}, actions)
+}
+
+func TestDataStreamNamespace(t *testing.T) {
+	client := &Client{dataStream: "logs-nginx-default"}
+
+	event := common.MapStr{}
+	assert.Equal(t, "logs-nginx-default", client.dataStreamName(event))
+
+	common.SetNamespace(event, "tenant-a")
+	assert.Equal(t, "logs-nginx-tenant-a", client.dataStreamName(event))
+}
+
+func TestDataStreamRequiresVersion(t *testing.T) {
+	server := versionMock("7.8.0")
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	client.dataStream = "logs-generic-default"
+
+	err := client.Connect(1 * time.Second)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "data streams require Elasticsearch 7.9.0")
+	}
+}
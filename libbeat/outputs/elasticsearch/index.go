@@ -0,0 +1,100 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+)
+
+// formatRefs matches the field and timestamp references of an index format
+// string.
+var formatRefs = regexp.MustCompile(`%\{[^}]*\}`)
+
+// isIndexFormat reports if the index setting is a format string. Plain index
+// names are extended by the date of the event, like `beatname-2006.01.02`.
+func isIndexFormat(index string) bool {
+	return strings.Contains(index, "%{")
+}
+
+// compileIndex compiles the index setting if it is a format string, and
+// returns nil otherwise.
+func compileIndex(index string) (*fmtstr.EventFormatString, error) {
+	if !isIndexFormat(index) {
+		return nil, nil
+	}
+	return fmtstr.CompileEvent(index)
+}
+
+// validateIndex checks that the index setting compiles, and that the literal
+// text of the setting is valid in index names.
+func validateIndex(index string) error {
+	if _, err := compileIndex(index); err != nil {
+		return fmt.Errorf("invalid index '%v': %v", index, err)
+	}
+	if index == "" {
+		return nil
+	}
+
+	// references are replaced by a valid name, as their values are only
+	// known when events are published
+	name := formatRefs.ReplaceAllString(index, "x")
+	if _, err := sanitizeIndex(name); err != nil {
+		return fmt.Errorf("invalid index '%v': %v", index, err)
+	}
+	return nil
+}
+
+// sanitizeIndex returns the index name in lowercase, as required by
+// Elasticsearch, or an error if the name contains characters not allowed in
+// index names.
+func sanitizeIndex(index string) (string, error) {
+	if index == "" {
+		return "", fmt.Errorf("index name is empty")
+	}
+	if len(index) > 255 {
+		return "", fmt.Errorf("index name '%v' is longer than 255 bytes", index)
+	}
+	if index == "." || index == ".." {
+		return "", fmt.Errorf("index name must not be '%v'", index)
+	}
+	if strings.IndexAny(index[:1], "_-+") >= 0 {
+		return "", fmt.Errorf("index name '%v' must not start with '_', '-' or '+'", index)
+	}
+	if i := strings.IndexAny(index, "\\/*?\"<>| ,#:"); i >= 0 {
+		return "", fmt.Errorf("index name '%v' must not contain '%c'", index, index[i])
+	}
+	return strings.ToLower(index), nil
+}
+
+// eventIndex returns the index of the event. If the index setting is a format
+// string, the event fields and timestamp referenced are formatted into the
+// index name, which is validated. Otherwise, or if the event overwrites the
+// index by setting beat.index, the event is indexed into a daily index. The
+// namespace of the event is appended to formatted index names.
+func (client *Client) eventIndex(event common.MapStr) (string, error) {
+	if client.indexFormat == nil || hasDynamicIndex(event) {
+		return strings.ToLower(getIndex(event, client.index)), nil
+	}
+
+	index, err := client.indexFormat.Run(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to format index '%v': %v", client.indexFormat, err)
+	}
+	if namespace := common.GetNamespace(event); namespace != "" {
+		index += "-" + namespace
+	}
+	return sanitizeIndex(index)
+}
+
+// hasDynamicIndex reports if the index is set by the event in beat.index.
+func hasDynamicIndex(event common.MapStr) bool {
+	beatMeta, ok := event["beat"].(common.MapStr)
+	if !ok {
+		return false
+	}
+	_, ok = beatMeta["index"].(string)
+	return ok
+}
@@ -0,0 +1,92 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+)
+
+// itemErrorClass classifies the errors reported for single items of a bulk
+// request.
+type itemErrorClass int
+
+const (
+	errClassOther itemErrorClass = iota
+	errClassMappingConflict
+	errClassVersionConflict
+	errClassRejectedExecution
+	errClassIndexClosed
+	errClassServerError
+)
+
+var itemErrorClassNames = map[itemErrorClass]string{
+	errClassOther:             "other",
+	errClassMappingConflict:   "mapping_conflict",
+	errClassVersionConflict:   "version_conflict",
+	errClassRejectedExecution: "rejected_execution",
+	errClassIndexClosed:       "index_closed",
+	errClassServerError:       "server_error",
+}
+
+// Number of failed bulk items per error class.
+var statItemErrors = expvar.NewMap("libbeat.es.publish.item_errors")
+
+// Patterns identifying the error class. The patterns are matched against the
+// error type of the item (e.g. version_conflict_engine_exception), with
+// underscores removed and lower cased, such that the exception names used by
+// Elasticsearch 1.x (e.g. VersionConflictEngineException) match too.
+var itemErrorPatterns = []struct {
+	pattern []byte
+	class   itemErrorClass
+}{
+	{[]byte("rejectedexecution"), errClassRejectedExecution},
+	{[]byte("versionconflict"), errClassVersionConflict},
+	{[]byte("indexclosed"), errClassIndexClosed},
+	{[]byte("mapperparsing"), errClassMappingConflict},
+	{[]byte("strictdynamicmapping"), errClassMappingConflict},
+	{[]byte("mapper ["), errClassMappingConflict},
+}
+
+func (c itemErrorClass) String() string {
+	return itemErrorClassNames[c]
+}
+
+// retryable returns true if the item might be indexed successfully when sent
+// again. Items failing due to the event itself or the state of the index are
+// dropped.
+func (c itemErrorClass) retryable() bool {
+	return c == errClassRejectedExecution || c == errClassServerError
+}
+
+// classifyItemError determines the error class from the status code and the
+// raw error message of a bulk item. The message is either a JSON string
+// (Elasticsearch 1.x) or an object with type and reason (Elasticsearch 2.x
+// and later).
+func classifyItemError(status int, msg []byte) itemErrorClass {
+	text := msg
+	var obj struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(msg, &obj) == nil && (obj.Type != "" || obj.Reason != "") {
+		text = []byte(obj.Type + " " + obj.Reason)
+	}
+	text = bytes.ToLower(bytes.Replace(text, []byte("_"), nil, -1))
+
+	for _, p := range itemErrorPatterns {
+		if bytes.Contains(text, p.pattern) {
+			return p.class
+		}
+	}
+
+	switch {
+	case status == 429:
+		return errClassRejectedExecution
+	case status == 409:
+		return errClassVersionConflict
+	case status >= 500:
+		return errClassServerError
+	default:
+		return errClassOther
+	}
+}
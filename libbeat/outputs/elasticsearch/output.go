@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +16,7 @@ import (
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/transport"
 	"github.com/elastic/beats/libbeat/paths"
 )
 
@@ -25,12 +25,19 @@ type elasticsearchOutput struct {
 	mode  mode.ConnectionMode
 	topology
 
-	template      map[string]interface{}
-	templateMutex sync.Mutex
+	template        map[string]interface{}
+	template2x      map[string]interface{}
+	templateVersion int // version of template, 0 if unversioned
+	templateMutex   sync.Mutex
+
+	// drops event fields not defined in the template, nil if disabled
+	pruner *fieldPruner
 }
 
 func init() {
-	outputs.RegisterOutputPlugin("elasticsearch", New)
+	if err := outputs.RegisterOutputPlugin("elasticsearch", New); err != nil {
+		panic(err)
+	}
 }
 
 var (
@@ -81,6 +88,16 @@ func (out *elasticsearchOutput) init(
 		return err
 	}
 
+	if prune := config.Template.PruneFields; prune.Enabled {
+		if out.template == nil {
+			return errors.New("template.prune_fields requires a template to be configured")
+		}
+		out.pruner, err = newFieldPruner(out.template, prune.Allow)
+		if err != nil {
+			return err
+		}
+	}
+
 	clients, err := modeutil.MakeClients(cfg, makeClientFactory(tlsConfig, &config, out))
 	if err != nil {
 		return err
@@ -97,7 +114,7 @@ func (out *elasticsearchOutput) init(
 
 	out.clients = clients
 	loadBalance := config.LoadBalance
-	m, err := modeutil.NewConnectionMode(clients, !loadBalance,
+	m, err := modeutil.NewConnectionMode(clients, !loadBalance, config.Ordering,
 		maxAttempts, waitRetry, config.Timeout, maxWaitRetry)
 	if err != nil {
 		return err
@@ -109,24 +126,45 @@ func (out *elasticsearchOutput) init(
 	return nil
 }
 
-// readTemplates reads the ES mapping template from the disk, if configured.
+// readTemplates reads the ES mapping templates from the disk, if configured.
 func (out *elasticsearchOutput) readTemplate(config Template) error {
 	if len(config.Name) > 0 {
-		// Look for the template in the configuration path, if it's not absolute
-		templatePath := paths.Resolve(paths.Config, config.Path)
-
-		logp.Info("Loading template enabled. Reading template file: %v", templatePath)
-
-		template, err := readTemplate(templatePath)
+		template, err := loadTemplateFile(config.Path)
 		if err != nil {
-			return fmt.Errorf("Error loading template %s: %v", templatePath, err)
+			return err
 		}
-
 		out.template = template
+
+		out.templateVersion = config.Version
+		if out.templateVersion == 0 {
+			out.templateVersion = readTemplateVersion(template)
+		}
+
+		es2x := config.Versions.ES2x
+		if es2x.Enabled && es2x.Path != "" {
+			template, err := loadTemplateFile(es2x.Path)
+			if err != nil {
+				return err
+			}
+			out.template2x = template
+		}
 	}
 	return nil
 }
 
+func loadTemplateFile(path string) (map[string]interface{}, error) {
+	// Look for the template in the configuration path, if it's not absolute
+	templatePath := paths.Resolve(paths.Config, path)
+
+	logp.Info("Loading template enabled. Reading template file: %v", templatePath)
+
+	template, err := readTemplate(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading template %s: %v", templatePath, err)
+	}
+	return template, nil
+}
+
 func readTemplate(filename string) (map[string]interface{}, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -144,39 +182,185 @@ func readTemplate(filename string) (map[string]interface{}, error) {
 	return template, nil
 }
 
+// readTemplateVersion returns the version field of the template, or 0 if the
+// template is unversioned.
+func readTemplateVersion(template map[string]interface{}) int {
+	switch v := template["version"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
 // loadTemplate checks if the index mapping template should be loaded
 // In case the template is not already loaded or overwritting is enabled, the
-// template is written to index
+// template is written to index. Versioned templates installed in
+// Elasticsearch 5.0 and later are upgraded if template.upgrade is enabled.
 func (out *elasticsearchOutput) loadTemplate(config Template, client *Client) error {
 	out.templateMutex.Lock()
 	defer out.templateMutex.Unlock()
 
 	logp.Info("Trying to load template for client: %s", client.Connection.URL)
 
-	// Check if template already exist or should be overwritten
-	exists := client.CheckTemplate(config.Name)
-	if !exists || config.Overwrite {
+	template := out.templateFor(config, client.version)
+	if template == nil {
+		logp.Info("Template loading for Elasticsearch %v is disabled.", client.version)
+		return nil
+	}
 
-		if config.Overwrite {
-			logp.Info("Existing template will be overwritten, as overwrite is enabled.")
-		}
+	// Template versions are supported by Elasticsearch 5.0 and later only.
+	version := 0
+	if !client.version.known() || client.version.atLeast(versionIngest) {
+		version = out.templateVersion
+		template = withTemplateVersion(template, version)
+	}
 
-		err := client.LoadTemplate(config.Name, out.template)
-		if err != nil {
-			return fmt.Errorf("Could not load template: %v", err)
+	load, err := shouldLoadTemplate(config, version, client)
+	if err != nil {
+		return err
+	}
+	if !load {
+		return nil
+	}
+
+	err = client.LoadTemplate(config.Name, template)
+	if err != nil {
+		return fmt.Errorf("Could not load template: %v", err)
+	}
+	return nil
+}
+
+// shouldLoadTemplate checks if the template is missing, or if the installed
+// template should be overwritten or upgraded to the given version.
+func shouldLoadTemplate(config Template, version int, client *Client) (bool, error) {
+	if config.Overwrite {
+		logp.Info("Existing template will be overwritten, as overwrite is enabled.")
+		return true, nil
+	}
+
+	// Check if template already exist or should be upgraded
+	if version == 0 {
+		if client.CheckTemplate(config.Name) {
+			logp.Info("Template already exists and will not be overwritten.")
+			return false, nil
 		}
-	} else {
-		logp.Info("Template already exists and will not be overwritten.")
+		return true, nil
+	}
+
+	installed, exists, err := client.GetTemplateVersion(config.Name)
+	if err != nil {
+		return false, fmt.Errorf("Could not check template version: %v", err)
+	}
+
+	switch {
+	case !exists:
+		return true, nil
+	case installed == version:
+		logp.Info("Template version %v already exists.", version)
+	case installed > version:
+		logp.Warn("Template version %v is newer than version %v of the Beat, "+
+			"the template will not be overwritten.", installed, version)
+	case !config.Upgrade:
+		logp.Warn("Template version %v is older than version %v of the Beat. "+
+			"Enable template.upgrade to upgrade the template.", installed, version)
+	default:
+		logp.Info("Upgrading template from version %v to %v.", installed, version)
+		return true, nil
+	}
+	return false, nil
+}
+
+// withTemplateVersion returns a copy of the template with the version field
+// set, or the template if version is 0.
+func withTemplateVersion(template map[string]interface{}, version int) map[string]interface{} {
+	if version == 0 {
+		return template
+	}
+
+	versioned := make(map[string]interface{}, len(template)+1)
+	for k, v := range template {
+		versioned[k] = v
 	}
+	versioned["version"] = version
+	return versioned
+}
+
+// loadDataStreamTemplate installs the index template enabling the data
+// stream events are written to. The template is derived from the beat
+// template, if configured.
+func (out *elasticsearchOutput) loadDataStreamTemplate(
+	config Template,
+	dataStream dataStreamConfig,
+	client *Client,
+) error {
+	out.templateMutex.Lock()
+	defer out.templateMutex.Unlock()
+
+	name := dataStream.TemplateName()
+	logp.Info("Trying to load data stream template '%s' for client: %s", name, client.Connection.URL)
 
+	if client.CheckIndexTemplate(name) && !config.Overwrite {
+		logp.Info("Data stream template already exists and will not be overwritten.")
+		return nil
+	}
+
+	err := client.LoadIndexTemplate(name, dataStream.IndexTemplate(out.template))
+	if err != nil {
+		return fmt.Errorf("Could not load data stream template: %v", err)
+	}
 	return nil
 }
 
+// templateFor selects the template to load into the given Elasticsearch
+// version. It returns nil if no template should be loaded.
+func (out *elasticsearchOutput) templateFor(
+	config Template,
+	version esVersion,
+) map[string]interface{} {
+	if version.known() && version.major == 2 {
+		if !config.Versions.ES2x.Enabled {
+			return nil
+		}
+		if out.template2x != nil {
+			return out.template2x
+		}
+	}
+
+	if version.atLeast(versionSingleType) {
+		return withIndexPatterns(out.template)
+	}
+	return out.template
+}
+
+// withIndexPatterns returns a copy of the template with the index pattern
+// set as index_patterns instead of template, as required by Elasticsearch
+// 6.0 and later.
+func withIndexPatterns(template map[string]interface{}) map[string]interface{} {
+	pattern, exists := template["template"]
+	if !exists {
+		return template
+	}
+
+	adapted := make(map[string]interface{}, len(template))
+	for k, v := range template {
+		adapted[k] = v
+	}
+	delete(adapted, "template")
+	if _, exists := adapted["index_patterns"]; !exists {
+		adapted["index_patterns"] = []interface{}{pattern}
+	}
+	return adapted
+}
+
 func makeClientFactory(
 	tls *tls.Config,
 	config *elasticsearchConfig,
 	out *elasticsearchOutput,
 ) func(string) (mode.ProtocolClient, error) {
+	resolver := transport.NewResolver(config.DNS)
+	throttle := transport.NewThrottle(config.MaxBytesPerSec)
 	return func(host string) (mode.ProtocolClient, error) {
 		esURL, err := getURL(config.Protocol, config.Path, host)
 		if err != nil {
@@ -186,7 +370,7 @@ func makeClientFactory(
 
 		var proxyURL *url.URL
 		if config.ProxyURL != "" {
-			proxyURL, err = parseProxyURL(config.ProxyURL)
+			proxyURL, err = transport.ParseProxyURL(config.ProxyURL)
 			if err != nil {
 				return nil, err
 			}
@@ -201,18 +385,34 @@ func makeClientFactory(
 
 		// define a callback to be called on connection
 		var onConnected connectCallback
-		if out.template != nil {
+		if config.DataStream.Enabled {
+			onConnected = func(client *Client) error {
+				return out.loadDataStreamTemplate(config.Template, config.DataStream, client)
+			}
+		} else if out.template != nil {
 			onConnected = func(client *Client) error {
 				return out.loadTemplate(config.Template, client)
 			}
 		}
 
-		return NewClient(
+		client, err := NewClient(
 			esURL, config.Index, proxyURL, tls,
 			config.Username, config.Password,
 			params, config.Timeout,
 			config.CompressionLevel,
 			onConnected)
+		if err != nil {
+			return nil, err
+		}
+
+		if resolver != nil || throttle != nil {
+			client.setDialer(resolver, throttle)
+		}
+		if config.DataStream.Enabled {
+			client.dataStream = config.DataStream.Name()
+		}
+		client.pruner = out.pruner
+		return client, nil
 	}
 }
 
@@ -220,6 +420,20 @@ func (out *elasticsearchOutput) Close() error {
 	return out.mode.Close()
 }
 
+// SetDeadLetter registers the function receiving events rejected by
+// Elasticsearch due to errors in the event or the state of the index.
+func (out *elasticsearchOutput) SetDeadLetter(deadLetter outputs.DeadLetter) {
+	for _, client := range out.clients {
+		client.(*Client).deadLetter = deadLetter
+	}
+}
+
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (out *elasticsearchOutput) Concurrent() bool {
+	return modeutil.IsConcurrent(out.mode)
+}
+
 func (out *elasticsearchOutput) PublishEvent(
 	signaler op.Signaler,
 	opts outputs.Options,
@@ -235,14 +449,3 @@ func (out *elasticsearchOutput) BulkPublish(
 ) error {
 	return out.mode.PublishEvents(trans, opts, events)
 }
-
-func parseProxyURL(raw string) (*url.URL, error) {
-	url, err := url.Parse(raw)
-	if err == nil && strings.HasPrefix(url.Scheme, "http") {
-		return url, err
-	}
-
-	// Proxy was bogus. Try prepending "http://" to it and
-	// see if that parses correctly.
-	return url.Parse("http://" + raw)
-}
@@ -0,0 +1,94 @@
+package elasticsearch
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// PreflightEndpoints returns the Elasticsearch nodes for the preflight checks.
+// If a proxy is configured, the connection to the proxy is checked instead of
+// the connections to the nodes.
+func (out *elasticsearchOutput) PreflightEndpoints() []preflight.Endpoint {
+	var endpoints []preflight.Endpoint
+	for _, c := range out.clients {
+		client := c.(*Client)
+		endpoint, err := preflightEndpoint(client)
+		if err != nil {
+			debugf("Skip preflight check of %s: %v", client.URL, err)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+func preflightEndpoint(client *Client) (preflight.Endpoint, error) {
+	u, err := url.Parse(client.URL)
+	if err != nil {
+		return preflight.Endpoint{}, err
+	}
+
+	endpoint := preflight.Endpoint{
+		Name:    redactURL(u),
+		Address: hostPort(u),
+		Probe:   authProbe(client),
+	}
+	if client.resolver != nil {
+		endpoint.LookupHost = client.resolver.LookupHost
+	}
+	if client.proxyURL != nil {
+		endpoint.Address = transport.ProxyAddress(client.proxyURL)
+	} else if u.Scheme == "https" {
+		transport := client.http.Transport.(*http.Transport)
+		endpoint.TLS = transport.TLSClientConfig
+		if endpoint.TLS == nil {
+			endpoint.TLS = &tls.Config{}
+		}
+	}
+	return endpoint, nil
+}
+
+// authProbe returns a probe sending a request with the credentials of the
+// client to Elasticsearch.
+func authProbe(client *Client) func(time.Duration) error {
+	return func(timeout time.Duration) error {
+		conn := client.Clone().Connection
+		conn.http.Timeout = timeout
+
+		status, _, err := conn.execRequest("GET", conn.URL, nil)
+		switch {
+		case status == http.StatusUnauthorized:
+			return fmt.Errorf("authentication failed (%v), check username and password", err)
+		case status == http.StatusForbidden:
+			return fmt.Errorf("user %s is not authorized (%v)", conn.Username, err)
+		case err != nil:
+			return err
+		}
+		return nil
+	}
+}
+
+func hostPort(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Host, port)
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	return redacted.String()
+}
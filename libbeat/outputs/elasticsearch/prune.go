@@ -0,0 +1,147 @@
+package elasticsearch
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Number of event fields dropped because they are not defined in the template.
+var statPrunedFields = expvar.NewInt("libbeat.es.publish.pruned_fields")
+
+// PruneFields configures dropping event fields not defined in the template.
+type PruneFields struct {
+	Enabled bool     `config:"enabled"`
+	Allow   []string `config:"allow"`
+}
+
+// fieldPruner drops all event fields that are not defined in the mapping of
+// the template, keeping the number of fields in the index mapping bounded.
+type fieldPruner struct {
+	root *pruneNode
+}
+
+// pruneNode is a field defined in the template. If any is set, all subfields
+// are kept.
+type pruneNode struct {
+	children map[string]*pruneNode
+	any      bool
+}
+
+// newFieldPruner creates a pruner keeping the fields defined in the default
+// mapping of template, the fields matched by path_match of its dynamic
+// templates and the fields listed in allow. A field listed in allow keeps
+// all its subfields.
+func newFieldPruner(template map[string]interface{}, allow []string) (*fieldPruner, error) {
+	mappings, _ := template["mappings"].(map[string]interface{})
+	mapping, ok := mappings["_default_"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("template has no default mapping to prune fields by")
+	}
+
+	root := &pruneNode{}
+	root.addProperties(mapping)
+
+	if dynamic, ok := mapping["dynamic_templates"].([]interface{}); ok {
+		for _, t := range dynamic {
+			named, _ := t.(map[string]interface{})
+			for _, v := range named {
+				dt, _ := v.(map[string]interface{})
+				if match, ok := dt["path_match"].(string); ok {
+					root.allow(match)
+				}
+			}
+		}
+	}
+
+	for _, field := range allow {
+		root.allow(field)
+	}
+	return &fieldPruner{root: root}, nil
+}
+
+func (n *pruneNode) child(name string) *pruneNode {
+	if n.children == nil {
+		n.children = map[string]*pruneNode{}
+	}
+	c, exists := n.children[name]
+	if !exists {
+		c = &pruneNode{}
+		n.children[name] = c
+	}
+	return c
+}
+
+// addProperties adds the fields defined in the properties of mapping. Objects
+// without properties keep all subfields.
+func (n *pruneNode) addProperties(mapping map[string]interface{}) {
+	props, ok := mapping["properties"].(map[string]interface{})
+	if !ok {
+		if t, _ := mapping["type"].(string); t == "" || t == "object" || t == "nested" {
+			n.any = true
+		}
+		return
+	}
+
+	for name, v := range props {
+		field, _ := v.(map[string]interface{})
+		n.child(name).addProperties(field)
+	}
+}
+
+// allow keeps the field and all its subfields. A trailing * is ignored, such
+// that patterns like fields.* keep all fields below fields. Patterns with
+// wildcards in other places are not supported and ignored.
+func (n *pruneNode) allow(field string) {
+	field = strings.TrimSuffix(strings.TrimSuffix(field, "*"), ".")
+	if field == "" || strings.ContainsAny(field, "*?") {
+		return
+	}
+
+	node := n
+	for _, name := range strings.Split(field, ".") {
+		node = node.child(name)
+	}
+	node.any = true
+}
+
+// prune returns a copy of the event without the fields not defined. The
+// event itself is not modified.
+func (p *fieldPruner) prune(event common.MapStr) common.MapStr {
+	return pruneObject(event, p.root)
+}
+
+func pruneObject(m map[string]interface{}, node *pruneNode) common.MapStr {
+	out := make(common.MapStr, len(m))
+	for key, value := range m {
+		child := node
+		for _, name := range strings.Split(key, ".") {
+			if child = child.children[name]; child == nil {
+				break
+			}
+			if child.any {
+				break
+			}
+		}
+		if child == nil {
+			statPrunedFields.Add(1)
+			continue
+		}
+		if child.any || child.children == nil {
+			out[key] = value
+			continue
+		}
+
+		switch v := value.(type) {
+		case common.MapStr:
+			out[key] = pruneObject(v, child)
+		case map[string]interface{}:
+			out[key] = pruneObject(v, child)
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
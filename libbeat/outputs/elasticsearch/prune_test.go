@@ -0,0 +1,89 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const pruneTestTemplate = `{
+  "mappings": {
+    "_default_": {
+      "dynamic_templates": [
+        {"fields": {"path_match": "fields.*", "mapping": {"type": "keyword"}}}
+      ],
+      "properties": {
+        "@timestamp": {"type": "date"},
+        "message": {"type": "text"},
+        "beat": {
+          "properties": {
+            "name": {"type": "keyword"},
+            "hostname": {"type": "keyword"}
+          }
+        },
+        "labels": {"type": "object"}
+      }
+    }
+  },
+  "template": "test-*"
+}`
+
+func newTestPruner(t *testing.T, allow ...string) *fieldPruner {
+	var template map[string]interface{}
+	if err := json.Unmarshal([]byte(pruneTestTemplate), &template); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newFieldPruner(template, allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPruneFields(t *testing.T) {
+	p := newTestPruner(t, "kubernetes.labels")
+
+	event := common.MapStr{
+		"@timestamp": "2016-08-01T00:00:00Z",
+		"message":    "hello",
+		"beat": common.MapStr{
+			"name":    "test",
+			"version": "5.0.0",
+		},
+		"beat.hostname": "host",
+		"labels":        common.MapStr{"app": "web"},
+		"fields":        common.MapStr{"env": "prod"},
+		"kubernetes": map[string]interface{}{
+			"labels":    common.MapStr{"tier": "frontend"},
+			"namespace": "default",
+		},
+		"unknown": 1,
+	}
+
+	pruned := p.prune(event)
+	assert.Equal(t, common.MapStr{
+		"@timestamp":    "2016-08-01T00:00:00Z",
+		"message":       "hello",
+		"beat":          common.MapStr{"name": "test"},
+		"beat.hostname": "host",
+		"labels":        common.MapStr{"app": "web"},
+		"fields":        common.MapStr{"env": "prod"},
+		"kubernetes": common.MapStr{
+			"labels": common.MapStr{"tier": "frontend"},
+		},
+	}, pruned)
+
+	// the original event is not modified
+	assert.Contains(t, event, "unknown")
+	assert.Contains(t, event["beat"], "version")
+}
+
+func TestPruneFieldsNoDefaultMapping(t *testing.T) {
+	_, err := newFieldPruner(map[string]interface{}{"template": "test-*"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var errBulkStreamAborted = errors.New("bulk request aborted")
+
+// bulkStream encodes bulk items directly into the body of a bulk request
+// while the request is being sent, using chunked transfer encoding. Only one
+// item is buffered at a time, such that memory usage does not grow with the
+// size of the bulk request.
+type bulkStream struct {
+	pipe    *io.PipeWriter
+	out     io.Writer
+	gzip    *gzip.Writer
+	scratch bytes.Buffer
+}
+
+func newBulkStream(compression int) (*bulkStream, error) {
+	s := &bulkStream{}
+	if compression > 0 {
+		w, err := gzip.NewWriterLevel(nil, compression)
+		if err != nil {
+			return nil, err
+		}
+		s.gzip = w
+	}
+	return s, nil
+}
+
+// reset prepares the stream for writing a new request body to pipe.
+func (s *bulkStream) reset(pipe *io.PipeWriter) {
+	s.pipe = pipe
+	s.out = pipe
+	if s.gzip != nil {
+		s.gzip.Reset(pipe)
+		s.out = s.gzip
+	}
+}
+
+// encode encodes one bulk item into the scratch buffer. The item is written
+// to the request body by flush. If encoding fails, the request body is not
+// modified.
+func (s *bulkStream) encode(meta, obj interface{}) error {
+	s.scratch.Reset()
+	enc := json.NewEncoder(&s.scratch)
+	if err := enc.Encode(meta); err != nil {
+		return err
+	}
+	return enc.Encode(obj)
+}
+
+// flush writes the last item encoded to the request body. It blocks until
+// the item has been consumed by the HTTP client.
+func (s *bulkStream) flush() error {
+	_, err := s.out.Write(s.scratch.Bytes())
+	return err
+}
+
+// close completes the request body. If err is not nil, reading the request
+// body fails with err.
+func (s *bulkStream) close(err error) {
+	if err == nil && s.gzip != nil {
+		err = s.gzip.Close()
+	}
+	s.pipe.CloseWithError(err)
+}
+
+// sendBulkStream sends the events in one bulk request, encoding the events
+// while the request is sent. Events failing to encode are dropped. It returns
+// the events added to the bulk request. If the request failed before all
+// events have been added, all events are returned.
+func (client *Client) sendBulkStream(
+	events []common.MapStr,
+) ([]common.MapStr, int, bulkResult, error) {
+	stream := client.stream
+
+	// skip events failing to encode up to the first valid one, such that
+	// no empty bulk request is sent
+	for len(events) > 0 {
+		err := client.encodeEvent(events[0])
+		if err == nil {
+			break
+		}
+		logp.Err("Failed to encode event: %s", err)
+		events = events[1:]
+	}
+	if len(events) == 0 {
+		return nil, 0, bulkResult{}, nil
+	}
+
+	reader, writer := io.Pipe()
+	stream.reset(writer)
+	requ := client.bulkRequ
+	requ.ResetStream(reader, client.encoder)
+
+	type encodeResult struct {
+		events   []common.MapStr
+		complete bool
+	}
+	encoded := make(chan encodeResult, 1)
+	go func() {
+		defer crash.Recover()
+
+		okEvents := make([]common.MapStr, 0, len(events))
+		err := stream.flush()
+		if err == nil {
+			okEvents = append(okEvents, events[0])
+		}
+
+		for _, event := range events[1:] {
+			if err != nil {
+				break
+			}
+
+			if encErr := client.encodeEvent(event); encErr != nil {
+				logp.Err("Failed to encode event: %s", encErr)
+				continue
+			}
+			if err = stream.flush(); err == nil {
+				okEvents = append(okEvents, event)
+			}
+		}
+
+		stream.close(err)
+		encoded <- encodeResult{okEvents, err == nil}
+	}()
+
+	status, result, err := client.sendBulkRequest(requ)
+
+	// unblock the encoder if the request failed before the body was sent
+	reader.CloseWithError(errBulkStreamAborted)
+
+	res := <-encoded
+	if !res.complete {
+		return events, status, result, err
+	}
+	return res.events, status, result, err
+}
+
+// encodeEvent encodes the bulk action and document of the event into the
+// stream. Encoding fails if no valid index can be selected for the event.
+func (client *Client) encodeEvent(event common.MapStr) error {
+	meta, err := client.bulkMeta(event)
+	if err != nil {
+		return err
+	}
+	return client.stream.encode(meta, client.eventBody(event))
+}
@@ -0,0 +1,146 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkRecord struct {
+	chunked bool
+	gzip    bool
+	lines   int
+}
+
+// bulkStreamMock acknowledges all items of bulk requests and records the
+// requests received.
+func bulkStreamMock(requests *[]bulkRecord) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" || r.URL.Path == "/" {
+			return
+		}
+
+		record := bulkRecord{
+			chunked: len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked",
+			gzip:    r.Header.Get("Content-Encoding") == "gzip",
+		}
+
+		var body io.Reader = r.Body
+		if record.gzip {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(400)
+				return
+			}
+			body = gz
+		}
+
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			record.lines++
+		}
+		*requests = append(*requests, record)
+
+		item := `{"index": {"status": 201}}`
+		items := strings.Repeat(item+",", record.lines/2-1) + item
+		fmt.Fprintf(w, `{"items": [%v]}`, items)
+	}))
+}
+
+func streamTestEvents(n int) []common.MapStr {
+	events := make([]common.MapStr, n)
+	for i := range events {
+		events[i] = common.MapStr{
+			"@timestamp": common.Time(time.Now()),
+			"type":       "test",
+			"i":          i,
+		}
+	}
+	return events
+}
+
+func TestPublishEventsStream(t *testing.T) {
+	for _, compression := range []int{0, 3} {
+		var requests []bulkRecord
+		server := bulkStreamMock(&requests)
+
+		client, err := NewClient(server.URL, "test", nil, nil, "", "", nil, 10*time.Second, compression, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Connect(10 * time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 2; i++ {
+			failed, err := client.PublishEvents(streamTestEvents(100))
+			assert.NoError(t, err)
+			assert.Len(t, failed, 0)
+		}
+
+		expected := bulkRecord{chunked: true, gzip: compression > 0, lines: 200}
+		assert.Equal(t, []bulkRecord{expected, expected}, requests)
+		server.Close()
+	}
+}
+
+func TestPublishEventsStreamDropsUnencodable(t *testing.T) {
+	var requests []bulkRecord
+	server := bulkStreamMock(&requests)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	events := streamTestEvents(4)
+	events[0]["invalid"] = make(chan int)
+	events[2]["invalid"] = make(chan int)
+
+	failed, err := client.PublishEvents(events)
+	assert.NoError(t, err)
+	assert.Len(t, failed, 0)
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, 4, requests[0].lines)
+	}
+
+	// no request is sent if no event can be encoded
+	failed, err = client.PublishEvents(events[:1])
+	assert.NoError(t, err)
+	assert.Len(t, failed, 0)
+	assert.Len(t, requests, 1)
+}
+
+func TestPublishEventsStreamAborted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" || r.URL.Path == "/" {
+			return
+		}
+		// fail without reading the request body
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(413)
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	if err := client.Connect(10 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	events := streamTestEvents(2000)
+	failed, err := client.PublishEvents(events)
+	assert.Error(t, err)
+	assert.Len(t, failed, len(events))
+}
@@ -66,6 +66,12 @@ func getURL(defaultScheme string, defaultPath string, rawURL string) (string, er
 	return addr.String(), nil
 }
 
+// MakeURL creates the url of an Elasticsearch host as configured in the hosts
+// setting, adding missing parts with defaults (scheme, host, port).
+func MakeURL(defaultScheme string, defaultPath string, rawURL string) (string, error) {
+	return getURL(defaultScheme, defaultPath, rawURL)
+}
+
 func makeURL(url, path string, params map[string]string) string {
 	u := url + path
 	if len(params) > 0 {
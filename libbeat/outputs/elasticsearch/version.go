@@ -0,0 +1,99 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// esVersion is the version of the Elasticsearch node a client is connected
+// to. The zero value is used if the version could not be determined.
+type esVersion struct {
+	major, minor, bugfix int
+	number               string
+}
+
+// Minimum Elasticsearch version supported by the output.
+var minSupportedVersion = esVersion{major: 2, number: "2.0.0"}
+
+// Elasticsearch versions introducing features the output depends on.
+var (
+	// ingest node pipelines
+	versionIngest = esVersion{major: 5, number: "5.0.0"}
+
+	// one mapping type per index only, index_patterns in templates
+	versionSingleType = esVersion{major: 6, number: "6.0.0"}
+
+	// data streams and composable index templates
+	versionDataStreams = esVersion{major: 7, minor: 9, number: "7.9.0"}
+)
+
+// docTypeSingle is the mapping type used for all events if Elasticsearch
+// supports one mapping type per index only.
+const docTypeSingle = "doc"
+
+// parseVersion parses a version number like 5.0.0 or 5.0.0-alpha5.
+func parseVersion(number string) (esVersion, error) {
+	v := esVersion{number: number}
+
+	parts := strings.SplitN(strings.SplitN(number, "-", 2)[0], ".", 3)
+	if len(parts) < 2 {
+		return v, fmt.Errorf("invalid version number '%v'", number)
+	}
+
+	fields := []*int{&v.major, &v.minor, &v.bugfix}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, fmt.Errorf("invalid version number '%v'", number)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+// parseVersionResponse parses the version number from the response to
+// GET /.
+func parseVersionResponse(body []byte) (esVersion, error) {
+	var info struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return esVersion{}, err
+	}
+	if info.Version.Number == "" {
+		return esVersion{}, fmt.Errorf("response contains no version number")
+	}
+	return parseVersion(info.Version.Number)
+}
+
+// known returns true if the version has been determined.
+func (v esVersion) known() bool {
+	return v.number != ""
+}
+
+// less returns true if v is older than other.
+func (v esVersion) less(other esVersion) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.bugfix < other.bugfix
+}
+
+// atLeast returns true if the version is known and not older than other.
+func (v esVersion) atLeast(other esVersion) bool {
+	return v.known() && !v.less(other)
+}
+
+func (v esVersion) String() string {
+	if !v.known() {
+		return "unknown"
+	}
+	return v.number
+}
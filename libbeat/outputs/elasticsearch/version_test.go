@@ -0,0 +1,218 @@
+// +build !integration
+
+package elasticsearch
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func versionMock(number string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/" {
+			fmt.Fprintf(w, `{"name": "test", "version": {"number": "%v"}}`, number)
+		}
+	}))
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		number   string
+		expected esVersion
+	}{
+		{"2.4.1", esVersion{2, 4, 1, "2.4.1"}},
+		{"5.0.0-alpha5", esVersion{5, 0, 0, "5.0.0-alpha5"}},
+		{"6.0", esVersion{6, 0, 0, "6.0"}},
+	}
+
+	for _, test := range tests {
+		v, err := parseVersion(test.number)
+		assert.NoError(t, err, test.number)
+		assert.Equal(t, test.expected, v, test.number)
+	}
+
+	for _, number := range []string{"", "5", "five.0.0", "5.x"} {
+		_, err := parseVersion(number)
+		assert.Error(t, err, number)
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	v2, _ := parseVersion("2.4.1")
+	v5, _ := parseVersion("5.0.0-alpha5")
+
+	assert.True(t, v2.less(v5))
+	assert.False(t, v5.less(v2))
+	assert.True(t, v5.atLeast(versionIngest))
+	assert.False(t, v2.atLeast(versionIngest))
+	assert.False(t, esVersion{}.atLeast(minSupportedVersion))
+	assert.Equal(t, "unknown", esVersion{}.String())
+}
+
+func TestConnectDetectsVersion(t *testing.T) {
+	server := versionMock("5.0.0-alpha5")
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	assert.NoError(t, client.Connect(1*time.Second))
+	assert.Equal(t, "5.0.0-alpha5", client.GetVersion())
+}
+
+func TestConnectUnsupportedVersion(t *testing.T) {
+	server := versionMock("1.7.5")
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	err := client.Connect(1 * time.Second)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "version 1.7.5")
+		assert.Contains(t, err.Error(), "not supported")
+	}
+	assert.False(t, client.IsConnected())
+}
+
+func TestConnectUnknownVersion(t *testing.T) {
+	server := ElasticsearchMock(403, nil)
+	defer server.Close()
+
+	client := newTestClient(server.URL)
+	assert.NoError(t, client.Connect(1*time.Second))
+	assert.Equal(t, "unknown", client.GetVersion())
+}
+
+func TestConnectPipelineRequiresIngest(t *testing.T) {
+	server := versionMock("2.4.1")
+	defer server.Close()
+
+	params := map[string]string{"pipeline": "test"}
+	client, err := NewClient(server.URL, "", nil, nil, "", "", params, time.Second, 0, nil)
+	assert.NoError(t, err)
+
+	err = client.Connect(1 * time.Second)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ingest node pipelines require Elasticsearch 5.0.0")
+	}
+	assert.False(t, client.IsConnected())
+}
+
+func TestDocType(t *testing.T) {
+	event := common.MapStr{"type": "log"}
+	client := &Client{}
+
+	assert.Equal(t, "log", client.docType(event))
+
+	client.version, _ = parseVersion("5.0.0")
+	assert.Equal(t, "log", client.docType(event))
+
+	client.version, _ = parseVersion("6.0.0")
+	assert.Equal(t, docTypeSingle, client.docType(event))
+}
+
+func TestTemplateFor(t *testing.T) {
+	template := map[string]interface{}{"template": "beat-*", "settings": "5x"}
+	template2x := map[string]interface{}{"template": "beat-*", "settings": "2x"}
+	out := &elasticsearchOutput{template: template, template2x: template2x}
+
+	config := defaultConfig.Template
+	v2, _ := parseVersion("2.4.1")
+	v5, _ := parseVersion("5.0.0")
+	v6, _ := parseVersion("6.0.0")
+
+	assert.Equal(t, template, out.templateFor(config, esVersion{}))
+	assert.Equal(t, template, out.templateFor(config, v5))
+	assert.Equal(t, template2x, out.templateFor(config, v2))
+	assert.Equal(t, map[string]interface{}{
+		"index_patterns": []interface{}{"beat-*"},
+		"settings":       "5x",
+	}, out.templateFor(config, v6))
+
+	config.Versions.ES2x.Enabled = false
+	assert.Nil(t, out.templateFor(config, v2))
+	assert.Equal(t, template, out.templateFor(config, v5))
+}
+
+// templateMock serves a template store, keeping the version of the template
+// installed, -1 if no template is installed.
+func templateMock(installed *int, loads *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_template/beat" {
+			return
+		}
+
+		switch r.Method {
+		case "GET", "HEAD":
+			if *installed < 0 {
+				w.WriteHeader(404)
+				return
+			}
+			fmt.Fprintf(w, `{"beat": {"version": %v}}`, *installed)
+		case "PUT":
+			var template struct {
+				Version int `json:"version"`
+			}
+			var body io.Reader = r.Body
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				body, _ = gzip.NewReader(r.Body)
+			}
+			json.NewDecoder(body).Decode(&template)
+			*installed = template.Version
+			*loads++
+		}
+	}))
+}
+
+func TestLoadTemplateVersion(t *testing.T) {
+	tests := []struct {
+		installed int
+		upgrade   bool
+		expected  int
+		loaded    bool
+	}{
+		{-1, false, 3, true}, // missing
+		{3, false, 3, false},
+		{2, false, 2, false}, // upgrade disabled
+		{2, true, 3, true},
+		{4, true, 4, false}, // newer template installed
+	}
+
+	for _, test := range tests {
+		installed, loads := test.installed, 0
+		server := templateMock(&installed, &loads)
+
+		client := newTestClient(server.URL)
+		client.version, _ = parseVersion("5.4.0")
+
+		out := &elasticsearchOutput{
+			template:        map[string]interface{}{"template": "beat-*"},
+			templateVersion: 3,
+		}
+		config := Template{Name: "beat", Upgrade: test.upgrade}
+
+		err := out.loadTemplate(config, client)
+		server.Close()
+
+		assert.NoError(t, err, "%+v", test)
+		assert.Equal(t, test.expected, installed, "%+v", test)
+		assert.Equal(t, test.loaded, loads == 1, "%+v", test)
+	}
+}
+
+func TestReadTemplateVersion(t *testing.T) {
+	assert.Equal(t, 0, readTemplateVersion(map[string]interface{}{}))
+	assert.Equal(t, 2, readTemplateVersion(map[string]interface{}{"version": float64(2)}))
+
+	// the version is not set in templates for Elasticsearch 2.x
+	template := map[string]interface{}{"template": "beat-*"}
+	assert.Equal(t, template, withTemplateVersion(template, 0))
+	assert.Equal(t, map[string]interface{}{"template": "beat-*", "version": 2},
+		withTemplateVersion(template, 2))
+}
@@ -0,0 +1,368 @@
+package fileout
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// gzipSuffix is the suffix of compressed rolled files.
+const gzipSuffix = ".gz"
+
+// dateLayout is the layout of the date in rolled file names.
+const dateLayout = "2006-01-02"
+
+// archiveFile writes events to the file name in path, and rolls the file when
+// it reaches rotateEveryBytes, or when the rotation interval elapses. Rolled
+// files are optionally compressed, and removed once they exceed the retention
+// limits. By default, rolled files are numbered like the files of the
+// FileRotator, with name.1 being the newest file. If dateInName is set,
+// rolled files are named after the UTC date the file was started, like
+// name-2006-01-02.1, and numbered in the order they are rolled on that date,
+// such that they are never renamed again.
+type archiveFile struct {
+	path             string
+	name             string
+	rotateEveryBytes uint64
+	rotateInterval   time.Duration
+	keepFiles        int
+	compress         bool
+	dateInName       bool
+	maxAge           time.Duration
+	maxTotalSize     uint64
+
+	current *os.File
+	size    uint64
+	started time.Time
+
+	now func() time.Time
+}
+
+// rolledFile is a rolled file found in the path.
+type rolledFile struct {
+	path    string
+	number  int
+	date    string
+	size    uint64
+	modTime time.Time
+}
+
+func (a *archiveFile) WriteLine(line []byte) error {
+	now := a.now()
+	if a.current == nil {
+		if err := a.open(now); err != nil {
+			return err
+		}
+	} else if a.shouldRoll(now) {
+		if err := a.roll(now); err != nil {
+			return err
+		}
+		if err := a.create(now); err != nil {
+			return err
+		}
+	}
+
+	line = append(line, '\n')
+	if _, err := a.current.Write(line); err != nil {
+		return err
+	}
+	a.size += uint64(len(line))
+	return nil
+}
+
+// Close closes the current file without rolling it.
+func (a *archiveFile) Close() error {
+	if a.current == nil {
+		return nil
+	}
+	err := a.current.Close()
+	a.current = nil
+	return err
+}
+
+// shouldRoll reports if the current file reached the maximum size, or if an
+// interval boundary has been crossed since the file was started. Intervals are
+// aligned to UTC, such that a rotation interval of 24h rolls files at
+// midnight UTC.
+func (a *archiveFile) shouldRoll(now time.Time) bool {
+	return a.size >= a.rotateEveryBytes || intervalCrossed(a.rotateInterval, a.started, now)
+}
+
+// intervalCrossed reports if an interval boundary lies between started and
+// now. It returns false if interval is 0.
+func intervalCrossed(interval time.Duration, started, now time.Time) bool {
+	return interval > 0 && !now.Truncate(interval).Equal(started.Truncate(interval))
+}
+
+// open rolls the file left by a previous run, before creating a new file.
+func (a *archiveFile) open(now time.Time) error {
+	info, err := os.Stat(a.currentPath())
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	case info.Size() == 0:
+		if err := os.Remove(a.currentPath()); err != nil {
+			return err
+		}
+	default:
+		a.started = info.ModTime()
+		if err := a.rollFile(a.currentPath(), now); err != nil {
+			return err
+		}
+	}
+	return a.create(now)
+}
+
+func (a *archiveFile) create(now time.Time) error {
+	f, err := os.OpenFile(a.currentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	a.current = f
+	a.size = 0
+	a.started = now
+	return nil
+}
+
+// roll closes the current file and rolls it. Empty files are removed instead.
+func (a *archiveFile) roll(now time.Time) error {
+	f := a.current
+	a.current = nil
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if a.size == 0 {
+		return os.Remove(f.Name())
+	}
+	return a.rollFile(f.Name(), now)
+}
+
+// rollFile renames the file under path to the next rolled name, compresses
+// it and removes the rolled files exceeding the retention limits.
+func (a *archiveFile) rollFile(path string, now time.Time) error {
+	rolled, err := a.rolledFiles()
+	if err != nil {
+		return err
+	}
+
+	var target string
+	if a.dateInName {
+		date := a.started.UTC().Format(dateLayout)
+		number := 1
+		for _, f := range rolled {
+			if f.date == date && f.number >= number {
+				number = f.number + 1
+			}
+		}
+		target = filepath.Join(a.path, fmt.Sprintf("%s-%s.%d", a.name, date, number))
+	} else {
+		// shift all files, starting with the oldest one
+		sort.Sort(sort.Reverse(byNumber(rolled)))
+		for _, f := range rolled {
+			shifted := a.numberedPath(f.number+1, strings.HasSuffix(f.path, gzipSuffix))
+			if err := os.Rename(f.path, shifted); err != nil {
+				return err
+			}
+		}
+		target = a.numberedPath(1, false)
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		return err
+	}
+	if a.compress {
+		if err := compressFile(target); err != nil {
+			logp.Err("Failed to compress rolled file %v: %v", target, err)
+		}
+	}
+
+	return a.removeOldFiles(now)
+}
+
+// removeOldFiles removes the oldest rolled files, such that at most
+// keepFiles-1 files are kept, no file is older than maxAge, and the total
+// size of the rolled files does not exceed maxTotalSize.
+func (a *archiveFile) removeOldFiles(now time.Time) error {
+	rolled, err := a.rolledFiles()
+	if err != nil {
+		return err
+	}
+	sort.Sort(byAge(rolled))
+
+	var total uint64
+	for i, f := range rolled {
+		total += f.size
+
+		keep := i < a.keepFiles-1
+		if a.maxAge > 0 && now.Sub(f.modTime) > a.maxAge {
+			keep = false
+		}
+		if a.maxTotalSize > 0 && total > a.maxTotalSize {
+			keep = false
+		}
+		if keep {
+			continue
+		}
+
+		logp.Info("Removing rolled file %v", f.path)
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// rolledFiles returns the rolled files in path.
+func (a *archiveFile) rolledFiles() ([]rolledFile, error) {
+	paths, err := filepath.Glob(filepath.Join(a.path, a.name+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []rolledFile
+	for _, path := range paths {
+		f, ok := a.parseRolledName(filepath.Base(path))
+		if !ok {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		f.path = path
+		f.size = uint64(info.Size())
+		f.modTime = info.ModTime()
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// parseRolledName parses the date and number of a rolled file, like name.1,
+// name.1.gz or name-2006-01-02.1.gz.
+func (a *archiveFile) parseRolledName(name string) (rolledFile, bool) {
+	var f rolledFile
+
+	rest := strings.TrimSuffix(name, gzipSuffix)
+	if a.dateInName {
+		prefix := a.name + "-"
+		if !strings.HasPrefix(rest, prefix) || len(rest) < len(prefix)+len(dateLayout)+2 {
+			return f, false
+		}
+		rest = rest[len(prefix):]
+		f.date = rest[:len(dateLayout)]
+		if _, err := time.Parse(dateLayout, f.date); err != nil {
+			return f, false
+		}
+		rest = rest[len(dateLayout):]
+	} else {
+		rest = strings.TrimPrefix(rest, a.name)
+	}
+
+	if !strings.HasPrefix(rest, ".") {
+		return f, false
+	}
+	number, err := strconv.Atoi(rest[1:])
+	if err != nil || number < 1 {
+		return f, false
+	}
+	f.number = number
+	return f, true
+}
+
+func (a *archiveFile) currentPath() string {
+	return filepath.Join(a.path, a.name)
+}
+
+func (a *archiveFile) numberedPath(number int, compressed bool) string {
+	path := filepath.Join(a.path, a.name+"."+strconv.Itoa(number))
+	if compressed {
+		path += gzipSuffix
+	}
+	return path
+}
+
+type byNumber []rolledFile
+
+func (f byNumber) Len() int           { return len(f) }
+func (f byNumber) Less(i, j int) bool { return f[i].number < f[j].number }
+func (f byNumber) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+
+// byAge sorts rolled files from the newest to the oldest file.
+type byAge []rolledFile
+
+func (f byAge) Len() int      { return len(f) }
+func (f byAge) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f byAge) Less(i, j int) bool {
+	if !f[i].modTime.Equal(f[j].modTime) {
+		return f[i].modTime.After(f[j].modTime)
+	}
+	if f[i].date != f[j].date {
+		return f[i].date > f[j].date
+	}
+	if f[i].number != f[j].number {
+		// numbers of files without date count up with age, numbers of
+		// files with date count down
+		return (f[i].number < f[j].number) == (f[i].date == "")
+	}
+	return false
+}
+
+// compressFile replaces the file under path by its gzip compressed version.
+// The modification time of the file is kept, such that the retention limits
+// apply to the time the events were written.
+func compressFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tempfile := path + gzipSuffix + ".new"
+	out, err := os.OpenFile(tempfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempfile, path+gzipSuffix)
+	}
+	if err != nil {
+		os.Remove(tempfile)
+		return err
+	}
+
+	os.Chtimes(path+gzipSuffix, info.ModTime(), info.ModTime())
+	in.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,177 @@
+// +build !integration
+
+package fileout
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newTestArchiveFile(t *testing.T) (*archiveFile, *testClock, func()) {
+	dir, err := ioutil.TempDir("", "fileout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &testClock{now: time.Date(2017, 3, 1, 10, 0, 0, 0, time.UTC)}
+	a := &archiveFile{
+		path:             dir,
+		name:             "beat",
+		rotateEveryBytes: 10,
+		keepFiles:        7,
+		now:              clock.Now,
+	}
+	return a, clock, func() { os.RemoveAll(dir) }
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestArchiveFileRotateBySize(t *testing.T) {
+	a, _, cleanup := newTestArchiveFile(t)
+	defer cleanup()
+	a.rotateEveryBytes = 8
+	a.compress = true
+
+	writeLines(t, a, "event 0", "event 1", "event 2")
+	assert.NoError(t, a.Close())
+
+	assert.Equal(t, []string{"beat", "beat.1.gz", "beat.2.gz"}, listFiles(t, a.path))
+	assert.Equal(t, "event 1\n", readGzip(t, filepath.Join(a.path, "beat.1.gz")))
+	assert.Equal(t, "event 0\n", readGzip(t, filepath.Join(a.path, "beat.2.gz")))
+}
+
+func TestArchiveFileRotateByInterval(t *testing.T) {
+	a, clock, cleanup := newTestArchiveFile(t)
+	defer cleanup()
+	a.rotateEveryBytes = 1024
+	a.rotateInterval = time.Hour
+
+	writeLines(t, a, "event 0")
+	clock.now = clock.now.Add(30 * time.Minute)
+	writeLines(t, a, "event 1")
+	clock.now = clock.now.Add(30 * time.Minute)
+	writeLines(t, a, "event 2")
+	assert.NoError(t, a.Close())
+
+	assert.Equal(t, []string{"beat", "beat.1"}, listFiles(t, a.path))
+	content, err := ioutil.ReadFile(filepath.Join(a.path, "beat.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "event 0\nevent 1\n", string(content))
+}
+
+func TestArchiveFileDateInName(t *testing.T) {
+	a, clock, cleanup := newTestArchiveFile(t)
+	defer cleanup()
+	a.dateInName = true
+	a.rotateInterval = 24 * time.Hour
+
+	writeLines(t, a, "event 0", "event 1", "event 2")
+	clock.now = clock.now.Add(24 * time.Hour)
+	writeLines(t, a, "event 3")
+	assert.NoError(t, a.Close())
+
+	assert.Equal(t, []string{
+		"beat",
+		"beat-2017-03-01.1",
+		"beat-2017-03-01.2",
+	}, listFiles(t, a.path))
+
+	// the file left by the previous run is rolled on open
+	os.Chtimes(filepath.Join(a.path, "beat"), clock.now, clock.now)
+	writeLines(t, a, "event 4")
+	assert.NoError(t, a.Close())
+
+	assert.Equal(t, []string{
+		"beat",
+		"beat-2017-03-01.1",
+		"beat-2017-03-01.2",
+		"beat-2017-03-02.1",
+	}, listFiles(t, a.path))
+}
+
+func TestArchiveFileRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(a *archiveFile)
+		expected []string
+	}{
+		{
+			name:     "number of files",
+			setup:    func(a *archiveFile) { a.keepFiles = 3 },
+			expected: []string{"beat", "beat.1", "beat.2"},
+		},
+		{
+			name:     "max age",
+			setup:    func(a *archiveFile) { a.maxAge = 90 * time.Minute },
+			expected: []string{"beat", "beat.1"},
+		},
+		{
+			name:     "max total size",
+			setup:    func(a *archiveFile) { a.maxTotalSize = 20 },
+			expected: []string{"beat", "beat.1", "beat.2", "beat.3"},
+		},
+	}
+
+	for _, test := range tests {
+		a, clock, cleanup := newTestArchiveFile(t)
+		test.setup(a)
+
+		for i := 0; i < 6; i++ {
+			writeLines(t, a, "event")
+			// age the rolled files, as the retention compares the
+			// modification times against the clock
+			if a.current != nil {
+				ts := clock.now.Add(-time.Duration(5-i) * time.Hour)
+				os.Chtimes(a.current.Name(), ts, ts)
+			}
+			a.size = a.rotateEveryBytes
+		}
+		assert.NoError(t, a.Close())
+
+		assert.Equal(t, test.expected, listFiles(t, a.path), test.name)
+		cleanup()
+	}
+}
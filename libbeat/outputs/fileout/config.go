@@ -2,16 +2,31 @@ package fileout
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 type config struct {
-	Index         string `config:"index"`
-	Path          string `config:"path"`
-	Filename      string `config:"filename"`
-	RotateEveryKb int    `config:"rotate_every_kb" validate:"min=1"`
-	NumberOfFiles int    `config:"number_of_files"`
+	Index          string          `config:"index"`
+	Path           string          `config:"path"`
+	Filename       string          `config:"filename"`
+	RotateEveryKb  int             `config:"rotate_every_kb" validate:"min=1"`
+	RotateInterval time.Duration   `config:"rotate_interval" validate:"min=0"`
+	NumberOfFiles  int             `config:"number_of_files"`
+	Compress       bool            `config:"compress"`
+	DateInFilename bool            `config:"date_in_filename"`
+	Retention      retentionConfig `config:"retention"`
+	Manifest       bool            `config:"manifest"`
+	Codec          codec.Config    `config:"codec"`
+}
+
+// retentionConfig limits the rolled files kept, in addition to
+// number_of_files.
+type retentionConfig struct {
+	MaxAge         time.Duration `config:"max_age"           validate:"min=0"`
+	MaxTotalSizeKb int           `config:"max_total_size_kb" validate:"min=0"`
 }
 
 var (
@@ -31,5 +46,16 @@ func (c *config) Validate() error {
 			logp.RotatorMaxFiles)
 	}
 
+	if c.Manifest && c.archive() {
+		return fmt.Errorf("compress, date_in_filename and retention can not be combined with manifest")
+	}
+
 	return nil
 }
+
+// archive reports if rolled files are compressed, named by date or removed
+// by the retention limits.
+func (c *config) archive() bool {
+	return c.Compress || c.DateInFilename ||
+		c.Retention.MaxAge > 0 || c.Retention.MaxTotalSizeKb > 0
+}
@@ -1,20 +1,41 @@
 package fileout
 
 import (
-	"encoding/json"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 func init() {
-	outputs.RegisterOutputPlugin("file", New)
+	if err := outputs.RegisterOutputPlugin("file", New); err != nil {
+		panic(err)
+	}
 }
 
 type fileOutput struct {
-	rotator logp.FileRotator
+	rotator  logp.FileRotator
+	manifest bool
+	codec    codec.Codec
+	config   config
+
+	// writer of events without namespace
+	writer lineWriter
+
+	// writers of events with namespace to a subdirectory of path
+	mutex      sync.Mutex
+	namespaces map[string]lineWriter
+}
+
+// lineWriter writes encoded events, one event per line.
+type lineWriter interface {
+	WriteLine(line []byte) error
 }
 
 // New instantiates a new file output instance.
@@ -36,6 +57,12 @@ func New(cfg *common.Config, _ int) (outputs.Outputer, error) {
 }
 
 func (out *fileOutput) init(config config) error {
+	var err error
+	out.codec, err = codec.New(config.Codec)
+	if err != nil {
+		return err
+	}
+
 	out.rotator.Path = config.Path
 	out.rotator.Name = config.Filename
 	if out.rotator.Name == "" {
@@ -52,7 +79,7 @@ func (out *fileOutput) init(config config) error {
 	logp.Info("Number of files set to: %v", keepfiles)
 	out.rotator.KeepFiles = &keepfiles
 
-	err := out.rotator.CreateDirectory()
+	err = out.rotator.CreateDirectory()
 	if err != nil {
 		return err
 	}
@@ -62,12 +89,113 @@ func (out *fileOutput) init(config config) error {
 		return err
 	}
 
+	out.manifest = config.Manifest
+	if out.manifest {
+		logp.Info("File output writes manifests of rolled files")
+	}
+	if config.RotateInterval > 0 {
+		logp.Info("Rotate interval set to: %v", config.RotateInterval)
+	}
+	out.config = config
+
+	out.writer = out.newWriter(out.rotator.Path)
+	out.namespaces = map[string]lineWriter{}
 	return nil
 }
 
+// newWriter creates the writer of the files in path. If manifests are
+// enabled, rolled files are sealed with a manifest instead of being rotated.
+// Files rotated by time, compressed, named by date or with retention limits
+// are written by the archive writer.
+func (out *fileOutput) newWriter(path string) lineWriter {
+	if out.manifest {
+		return &rollingFile{
+			path:             path,
+			name:             out.rotator.Name,
+			rotateEveryBytes: *out.rotator.RotateEveryBytes,
+			rotateInterval:   out.config.RotateInterval,
+			keepFiles:        *out.rotator.KeepFiles,
+		}
+	}
+
+	if out.config.archive() || out.config.RotateInterval > 0 {
+		return &archiveFile{
+			path:             path,
+			name:             out.rotator.Name,
+			rotateEveryBytes: *out.rotator.RotateEveryBytes,
+			rotateInterval:   out.config.RotateInterval,
+			keepFiles:        *out.rotator.KeepFiles,
+			compress:         out.config.Compress,
+			dateInName:       out.config.DateInFilename,
+			maxAge:           out.config.Retention.MaxAge,
+			maxTotalSize:     uint64(out.config.Retention.MaxTotalSizeKb) * 1024,
+			now:              time.Now,
+		}
+	}
+
+	if path == out.rotator.Path {
+		return &out.rotator
+	}
+	return &logp.FileRotator{
+		Path:             path,
+		Name:             out.rotator.Name,
+		RotateEveryBytes: out.rotator.RotateEveryBytes,
+		KeepFiles:        out.rotator.KeepFiles,
+	}
+}
+
+// writerFor returns the writer to write events of the namespace to. Events
+// with namespace are written to a subdirectory of path named after the
+// namespace.
+func (out *fileOutput) writerFor(namespace string) (lineWriter, error) {
+	if namespace == "" {
+		return out.writer, nil
+	}
+	if err := common.ValidateNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	out.mutex.Lock()
+	defer out.mutex.Unlock()
+
+	if writer, exists := out.namespaces[namespace]; exists {
+		return writer, nil
+	}
+
+	dir := &logp.FileRotator{Path: filepath.Join(out.rotator.Path, namespace)}
+	if err := dir.CreateDirectory(); err != nil {
+		return nil, err
+	}
+	logp.Info("File output path for namespace %v set to: %v", namespace, dir.Path)
+
+	writer := out.newWriter(dir.Path)
+	out.namespaces[namespace] = writer
+	return writer, nil
+}
+
 // Implement Outputer
 func (out *fileOutput) Close() error {
-	return nil
+	out.mutex.Lock()
+	defer out.mutex.Unlock()
+
+	var firstErr error
+	writers := []lineWriter{out.writer}
+	for _, w := range out.namespaces {
+		writers = append(writers, w)
+	}
+	for _, w := range writers {
+		closer, ok := w.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			logp.Err("Failed to close file: %v", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
 }
 
 func (out *fileOutput) PublishEvent(
@@ -75,16 +203,25 @@ func (out *fileOutput) PublishEvent(
 	opts outputs.Options,
 	event common.MapStr,
 ) error {
-	jsonEvent, err := json.Marshal(event)
+	line, err := out.codec.Encode(event)
+	if err != nil {
+		// mark as success so event is not sent again.
+		op.SigCompleted(sig)
+
+		logp.Err("Fail to encode event(%v): %#v", err, event)
+		return err
+	}
+
+	writer, err := out.writerFor(common.GetNamespace(event))
 	if err != nil {
 		// mark as success so event is not sent again.
 		op.SigCompleted(sig)
 
-		logp.Err("Fail to json encode event(%v): %#v", err, event)
+		logp.Err("Fail to open file for event: %v", err)
 		return err
 	}
 
-	err = out.rotator.WriteLine(jsonEvent)
+	err = writer.WriteLine(line)
 	if err != nil {
 		if opts.Guaranteed {
 			logp.Critical("Unable to write events to file: %s", err)
@@ -0,0 +1,428 @@
+package fileout
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// manifestSuffix is the suffix of the manifest written next to every rolled
+// file.
+const manifestSuffix = ".manifest"
+
+// offsetSuffix is the suffix of the file storing the offset of the next event,
+// such that offsets continue after restarts even if loaders removed all
+// rolled files.
+const offsetSuffix = ".offset"
+
+// manifest describes a rolled file. It is written after the file has been
+// synced and renamed, such that a file with a manifest is complete.
+type manifest struct {
+	File        string `json:"file"`
+	FirstOffset uint64 `json:"first_offset"`
+	LastOffset  uint64 `json:"last_offset"`
+	Count       uint64 `json:"count"`
+	Size        uint64 `json:"size"`
+	Checksum    string `json:"checksum"`
+}
+
+// rollingFile writes events to the file name in path. When the file reaches
+// rotateEveryBytes, when the rotation interval elapses, or on close, it is
+// synced and atomically renamed to
+// name-<offset>, where offset is the offset of the first event in the file,
+// and a manifest is written next to it. Offsets count the events written to
+// the path and continue after restarts. Rolled files are never renamed
+// again, such that batch loaders can consume them and their manifests
+// without racing the output. Only the newest keepFiles-1 rolled files are
+// kept.
+type rollingFile struct {
+	path             string
+	name             string
+	rotateEveryBytes uint64
+	rotateInterval   time.Duration
+	keepFiles        int
+
+	current    *os.File
+	started    time.Time
+	hash       hash.Hash
+	size       uint64
+	count      uint64
+	nextOffset uint64 // offset of the next event written
+}
+
+func (r *rollingFile) WriteLine(line []byte) error {
+	if r.current == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	} else if r.size >= r.rotateEveryBytes ||
+		intervalCrossed(r.rotateInterval, r.started, time.Now()) {
+		if err := r.roll(); err != nil {
+			return err
+		}
+		if err := r.create(); err != nil {
+			return err
+		}
+	}
+
+	line = append(line, '\n')
+	if _, err := r.current.Write(line); err != nil {
+		return err
+	}
+	r.hash.Write(line)
+	r.size += uint64(len(line))
+	r.count++
+	r.nextOffset++
+	return nil
+}
+
+// Close rolls the current file, such that the events written last are
+// available to loaders.
+func (r *rollingFile) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.roll()
+}
+
+// open restores the offset and seals the files left by a previous run before
+// creating a new file.
+func (r *rollingFile) open() error {
+	offset, err := readOffset(r.offsetPath())
+	if err != nil {
+		return err
+	}
+	r.nextOffset = offset
+
+	if err := r.repair(); err != nil {
+		return err
+	}
+	if err := r.recover(); err != nil {
+		return err
+	}
+	return r.create()
+}
+
+// repair writes the manifests of rolled files whose manifest is missing,
+// because the Beat stopped after renaming the file. The offset is advanced
+// past all rolled files.
+func (r *rollingFile) repair() error {
+	paths, err := filepath.Glob(filepath.Join(r.path, r.name+"-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		firstOffset, ok := r.parseRolledName(filepath.Base(path))
+		if !ok {
+			continue
+		}
+
+		m, err := readManifest(path + manifestSuffix)
+		if os.IsNotExist(err) {
+			logp.Info("Writing missing manifest of file %v", path)
+			m, err = scanFile(path)
+			if err != nil {
+				return err
+			}
+			m.File = filepath.Base(path)
+			m.FirstOffset = firstOffset
+			m.LastOffset = firstOffset + m.Count - 1
+			err = writeManifest(path+manifestSuffix, m)
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.LastOffset+1 > r.nextOffset {
+			r.nextOffset = m.LastOffset + 1
+		}
+	}
+	return nil
+}
+
+// recover seals the file written by a previous run. Incomplete last lines are
+// kept, as the events may have been acknowledged.
+func (r *rollingFile) recover() error {
+	path := r.currentPath()
+	m, err := scanFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.Count == 0 {
+		return os.Remove(path)
+	}
+
+	logp.Info("Rolling file %v left by previous run with %v events", path, m.Count)
+	m.FirstOffset = r.nextOffset
+	r.nextOffset += m.Count
+	return r.seal(path, m)
+}
+
+func (r *rollingFile) create() error {
+	f, err := os.OpenFile(r.currentPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	r.current = f
+	r.started = time.Now()
+	r.hash = sha256.New()
+	r.size = 0
+	r.count = 0
+	return nil
+}
+
+// roll syncs and closes the current file and seals it. Empty files are
+// removed instead.
+func (r *rollingFile) roll() error {
+	f := r.current
+	r.current = nil
+
+	err := f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.count == 0 {
+		return os.Remove(f.Name())
+	}
+
+	return r.seal(f.Name(), manifest{
+		FirstOffset: r.nextOffset - r.count,
+		Count:       r.count,
+		Size:        r.size,
+		Checksum:    checksum(r.hash),
+	})
+}
+
+// seal renames the synced file under path to its rolled name and writes its
+// manifest and the offset of the next event.
+func (r *rollingFile) seal(path string, m manifest) error {
+	m.File = r.rolledName(m.FirstOffset)
+	m.LastOffset = m.FirstOffset + m.Count - 1
+
+	rolled := filepath.Join(r.path, m.File)
+	if err := os.Rename(path, rolled); err != nil {
+		return err
+	}
+	if err := writeManifest(rolled+manifestSuffix, m); err != nil {
+		return err
+	}
+	offset := []byte(strconv.FormatUint(m.LastOffset+1, 10))
+	if err := writeFileAtomic(r.offsetPath(), offset); err != nil {
+		return err
+	}
+	syncDir(r.path)
+
+	return r.removeOldFiles()
+}
+
+// removeOldFiles removes the oldest rolled files and their manifests, such
+// that at most keepFiles-1 rolled files are kept. Files already removed by
+// loaders are ignored.
+func (r *rollingFile) removeOldFiles() error {
+	manifests, err := r.manifests()
+	if err != nil {
+		return err
+	}
+
+	for len(manifests) > r.keepFiles-1 {
+		m := manifests[0]
+		manifests = manifests[1:]
+
+		path := filepath.Join(r.path, m.File)
+		if err := os.Remove(path + manifestSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifests returns the manifests of the rolled files in path, ordered by
+// offset.
+func (r *rollingFile) manifests() ([]manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(r.path, r.name+"-*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []manifest
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), manifestSuffix)
+		if _, ok := r.parseRolledName(name); !ok {
+			continue
+		}
+
+		m, err := readManifest(path)
+		if err != nil {
+			logp.Warn("Ignoring invalid manifest %v: %v", path, err)
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+
+	sort.Sort(byOffset(manifests))
+	return manifests, nil
+}
+
+func (r *rollingFile) currentPath() string {
+	return filepath.Join(r.path, r.name)
+}
+
+func (r *rollingFile) offsetPath() string {
+	return filepath.Join(r.path, r.name+offsetSuffix)
+}
+
+// rolledName returns the name of a rolled file. Offsets are zero padded, such
+// that the names sort by offset.
+func (r *rollingFile) rolledName(firstOffset uint64) string {
+	return fmt.Sprintf("%s-%020d", r.name, firstOffset)
+}
+
+// parseRolledName returns the offset of the first event in the rolled file
+// with the given name.
+func (r *rollingFile) parseRolledName(name string) (uint64, bool) {
+	digits := strings.TrimPrefix(name, r.name+"-")
+	if len(digits) != 20 || len(digits) == len(name) {
+		return 0, false
+	}
+	offset, err := strconv.ParseUint(digits, 10, 64)
+	return offset, err == nil
+}
+
+type byOffset []manifest
+
+func (m byOffset) Len() int           { return len(m) }
+func (m byOffset) Less(i, j int) bool { return m[i].FirstOffset < m[j].FirstOffset }
+func (m byOffset) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+func readManifest(path string) (manifest, error) {
+	var m manifest
+
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&m)
+	return m, err
+}
+
+// writeManifest atomically writes the manifest to path.
+func writeManifest(path string, m manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// scanFile computes the event count, size and checksum of the manifest of the
+// file under path.
+func scanFile(path string) (manifest, error) {
+	var m manifest
+
+	f, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(f, h))
+	for {
+		line, err := reader.ReadBytes('\n')
+		m.Size += uint64(len(line))
+		if len(line) > 0 {
+			m.Count++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+	}
+
+	m.Checksum = checksum(h)
+	return m, nil
+}
+
+func checksum(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// readOffset reads the offset stored by a previous run. It returns 0 if no
+// offset has been stored yet.
+func readOffset(path string) (uint64, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset in %v: %v", path, err)
+	}
+	return offset, nil
+}
+
+// writeFileAtomic atomically replaces the file under path by writing and
+// syncing a temporary file first.
+func writeFileAtomic(path string, data []byte) error {
+	tempfile := path + ".new"
+	f, err := os.OpenFile(tempfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	// Directly close file because of windows
+	f.Close()
+	if err != nil {
+		os.Remove(tempfile)
+		return err
+	}
+	return os.Rename(tempfile, path)
+}
+
+// syncDir syncs the directory, such that renames are persisted. Errors are
+// ignored, as directories can not be synced on all platforms.
+func syncDir(path string) {
+	d, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
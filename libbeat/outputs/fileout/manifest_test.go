@@ -0,0 +1,140 @@
+// +build !integration
+
+package fileout
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRollingFile(t *testing.T) (*rollingFile, func()) {
+	dir, err := ioutil.TempDir("", "fileout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &rollingFile{
+		path:             dir,
+		name:             "beat",
+		rotateEveryBytes: 10,
+		keepFiles:        3,
+	}
+	return r, func() { os.RemoveAll(dir) }
+}
+
+func writeLines(t *testing.T, r lineWriter, lines ...string) {
+	for _, line := range lines {
+		if err := r.WriteLine([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRollingFileManifests(t *testing.T) {
+	r, cleanup := newTestRollingFile(t)
+	defer cleanup()
+
+	writeLines(t, r, "event 0", "event 1", "event 2")
+	assert.NoError(t, r.Close())
+
+	manifests, err := r.manifests()
+	assert.NoError(t, err)
+	assert.Equal(t, []manifest{
+		{
+			File:        "beat-00000000000000000000",
+			FirstOffset: 0,
+			LastOffset:  1,
+			Count:       2,
+			Size:        16,
+			Checksum:    "sha256:258e91f04968b98a2513de134b876a667c72187468b208a6a9e134d15fa8022a",
+		},
+		{
+			File:        "beat-00000000000000000002",
+			FirstOffset: 2,
+			LastOffset:  2,
+			Count:       1,
+			Size:        8,
+			Checksum:    "sha256:b580f6c53bcbcee45e3fa28541baa300b6a51466b58674c4b730d08f2c9324a8",
+		},
+	}, manifests)
+
+	content, err := ioutil.ReadFile(filepath.Join(r.path, manifests[0].File))
+	assert.NoError(t, err)
+	assert.Equal(t, "event 0\nevent 1\n", string(content))
+
+	_, err = os.Stat(r.currentPath())
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Verify that offsets continue after a restart, and that the file left by
+// the previous run is sealed.
+func TestRollingFileRestart(t *testing.T) {
+	r, cleanup := newTestRollingFile(t)
+	defer cleanup()
+
+	writeLines(t, r, "event 0", "event 1", "event 2")
+	r.current.Close()
+
+	restarted := &rollingFile{
+		path:             r.path,
+		name:             r.name,
+		rotateEveryBytes: r.rotateEveryBytes,
+		keepFiles:        r.keepFiles,
+	}
+	writeLines(t, restarted, "event 3")
+	assert.NoError(t, restarted.Close())
+
+	manifests, err := restarted.manifests()
+	assert.NoError(t, err)
+	if assert.Len(t, manifests, 2) {
+		assert.Equal(t, uint64(2), manifests[0].FirstOffset)
+		assert.Equal(t, uint64(1), manifests[0].Count)
+		assert.Equal(t, uint64(3), manifests[1].FirstOffset)
+		assert.Equal(t, uint64(3), manifests[1].LastOffset)
+	}
+}
+
+// Verify that a rolled file without manifest gets its manifest on restart.
+func TestRollingFileRepair(t *testing.T) {
+	r, cleanup := newTestRollingFile(t)
+	defer cleanup()
+	r.keepFiles = 10
+
+	path := filepath.Join(r.path, r.rolledName(5))
+	if err := ioutil.WriteFile(path, []byte("event 5\nevent 6\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLines(t, r, "event 7")
+	assert.NoError(t, r.Close())
+
+	manifests, err := r.manifests()
+	assert.NoError(t, err)
+	if assert.Len(t, manifests, 2) {
+		assert.Equal(t, uint64(5), manifests[0].FirstOffset)
+		assert.Equal(t, uint64(6), manifests[0].LastOffset)
+		assert.Equal(t, uint64(7), manifests[1].FirstOffset)
+	}
+}
+
+func TestRollingFileRemoveOldFiles(t *testing.T) {
+	r, cleanup := newTestRollingFile(t)
+	defer cleanup()
+
+	writeLines(t, r, "event 0", "event 1", "event 2", "event 3", "event 4", "event 5")
+	assert.NoError(t, r.Close())
+
+	manifests, err := r.manifests()
+	assert.NoError(t, err)
+	if assert.Len(t, manifests, 2) {
+		assert.Equal(t, uint64(2), manifests[0].FirstOffset)
+		assert.Equal(t, uint64(4), manifests[1].FirstOffset)
+	}
+
+	_, err = os.Stat(filepath.Join(r.path, r.rolledName(0)))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,307 @@
+package httpout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// client sends batches of events to an HTTP endpoint.
+type client struct {
+	url         string
+	index       string
+	method      string
+	headers     map[string]string
+	username    string
+	password    string
+	bearerToken string
+	format      string
+	contentType string
+
+	http      *http.Client
+	proxyURL  *url.URL
+	resolver  transport.Resolver
+	connected bool
+
+	// buffer of the request body, reused between requests
+	buf              bytes.Buffer
+	compressionLevel int
+
+	deadLetter outputs.DeadLetter
+}
+
+// maximum size of the response body included in error messages
+const maxErrorBodySize = 1024
+
+var (
+	// ErrNotConnected indicates failure due to client having no valid connection
+	ErrNotConnected = errors.New("not connected")
+)
+
+func newClient(
+	endpoint string,
+	config *httpConfig,
+	tls *tls.Config,
+	proxyURL *url.URL,
+	resolver transport.Resolver,
+	throttle *transport.Throttle,
+) *client {
+	dialer := transport.StatsDialer(transport.ResolverNetDialer(config.Timeout, resolver),
+		&transport.IOStats{
+			Read:        statReadBytes,
+			Write:       statWriteBytes,
+			ReadErrors:  statReadErrors,
+			WriteErrors: statWriteErrors,
+		})
+	dialer = transport.ThrottleDialer(throttle, dialer)
+
+	// the proxy URL was already checked by the config validation
+	proxy, dialer, _ := transport.HTTPProxy(proxyURL, dialer)
+
+	contentType := config.ContentType
+	if contentType == "" {
+		contentType = contentTypes[config.Format]
+	}
+
+	return &client{
+		url:         makeURL(endpoint, config.Params),
+		index:       config.Index,
+		method:      strings.ToUpper(config.Method),
+		headers:     config.Headers,
+		username:    config.Username,
+		password:    config.Password,
+		bearerToken: config.BearerToken,
+		format:      config.Format,
+		contentType: contentType,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Dial:            dialer.Dial,
+				TLSClientConfig: tls,
+				Proxy:           proxy,
+			},
+			Timeout: config.Timeout,
+		},
+		proxyURL:         proxyURL,
+		resolver:         resolver,
+		compressionLevel: config.CompressionLevel,
+	}
+}
+
+// Connect marks the client as connected. No request is sent, as arbitrary
+// endpoints can not be checked without side effects. Connection problems are
+// reported when publishing.
+func (c *client) Connect(timeout time.Duration) error {
+	c.connected = true
+	return nil
+}
+
+func (c *client) Close() error {
+	c.connected = false
+	return nil
+}
+
+func (c *client) IsConnected() bool {
+	return c.connected
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+// PublishEvents sends all events in one request. Events are returned to be
+// retried if the request failed or the endpoint responded with a server error
+// or 429. Events rejected with another status are dropped and passed to the
+// dead letter output, if configured.
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	publishEventsCallCount.Add(1)
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+	if !c.connected {
+		return events, ErrNotConnected
+	}
+
+	begin := time.Now()
+
+	// drop events failing to encode from the events slice
+	events, err := c.encode(events)
+	if err != nil {
+		return events, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	status, msg, err := c.send()
+	if err != nil {
+		logp.Err("Failed to publish events to %s: %v", c.redactedURL(), err)
+		c.connected = false
+		eventsNotAcked.Add(int64(len(events)))
+		return events, err
+	}
+
+	switch {
+	case status == 429 || status >= 500:
+		eventsNotAcked.Add(int64(len(events)))
+		return events, fmt.Errorf("%s responded with status %v: %s",
+			c.redactedURL(), status, msg)
+
+	case status >= 300:
+		logp.Err("Dropping %d events rejected by %s with status %v: %s",
+			len(events), c.redactedURL(), status, msg)
+		eventsDropped.Add(int64(len(events)))
+		if c.deadLetter != nil {
+			reason := fmt.Sprintf("rejected (status=%v): %s", status, msg)
+			for _, event := range events {
+				c.deadLetter(event, reason)
+			}
+		}
+		return nil, nil
+	}
+
+	debugf("PublishEvents: %d events have been published to %s in %v.",
+		len(events), c.redactedURL(), time.Now().Sub(begin))
+	ackedEvents.Add(int64(len(events)))
+	return nil, nil
+}
+
+// encode writes the events to the request body, as JSON array or as one JSON
+// document per line. Events failing to encode are dropped from the returned
+// slice.
+func (c *client) encode(events []common.MapStr) ([]common.MapStr, error) {
+	c.buf.Reset()
+
+	var w io.Writer = &c.buf
+	var gz *gzip.Writer
+	if c.compressionLevel > 0 {
+		var err error
+		gz, err = gzip.NewWriterLevel(&c.buf, c.compressionLevel)
+		if err != nil {
+			return events, err
+		}
+		w = gz
+	}
+
+	okEvents := events[:0]
+	for _, event := range events {
+		doc, err := c.encodeEvent(event)
+		if err != nil {
+			logp.Err("Failed to encode event: %s", err)
+			continue
+		}
+
+		switch {
+		case c.format != formatJSON:
+		case len(okEvents) == 0:
+			w.Write([]byte{'['})
+		default:
+			w.Write([]byte{','})
+		}
+		w.Write(doc)
+		if c.format != formatJSON {
+			w.Write([]byte{'\n'})
+		}
+		okEvents = append(okEvents, event)
+	}
+	if c.format == formatJSON && len(okEvents) > 0 {
+		w.Write([]byte{']'})
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return events, err
+		}
+	}
+	return okEvents, nil
+}
+
+// encodeEvent encodes the event without the @metadata field, unless the
+// events are sent to the Logstash http input. Like the Logstash output, the
+// metadata then contains the beat, which is the configured index, and the
+// type of the event, unless set in the event metadata.
+func (c *client) encodeEvent(event common.MapStr) ([]byte, error) {
+	if c.format != formatJSONLines {
+		return json.Marshal(event.WithoutMetadata())
+	}
+
+	meta := common.MapStr{
+		"beat": c.index,
+		"type": event["type"],
+	}
+	if eventMeta, ok := event[common.MetadataKey].(common.MapStr); ok {
+		meta.Update(eventMeta)
+	}
+
+	doc := make(common.MapStr, len(event)+1)
+	for k, v := range event {
+		doc[k] = v
+	}
+	doc[common.MetadataKey] = meta
+	return json.Marshal(doc)
+}
+
+// send sends the encoded body. The response body is only returned if the
+// request failed.
+func (c *client) send() (int, string, error) {
+	req, err := http.NewRequest(c.method, c.url, bytes.NewReader(c.buf.Bytes()))
+	if err != nil {
+		return 0, "", err
+	}
+
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", c.contentType)
+	if c.compressionLevel > 0 {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	switch {
+	case c.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	case c.username != "" || c.password != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return resp.StatusCode, "", nil
+	}
+
+	msg, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(bytes.TrimSpace(msg)), nil
+}
+
+func (c *client) redactedURL() string {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return c.url
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
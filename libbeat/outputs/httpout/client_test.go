@@ -0,0 +1,291 @@
+// +build !integration
+
+package httpout
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// newTestServer records the requests and responds with status.
+func newTestServer(status int) (*httptest.Server, *[]testRequest) {
+	var requests []testRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(400)
+				return
+			}
+			body = gz
+		}
+		raw, _ := ioutil.ReadAll(body)
+
+		requests = append(requests, testRequest{
+			method: r.Method,
+			header: r.Header,
+			body:   string(raw),
+		})
+		w.WriteHeader(status)
+		w.Write([]byte("response"))
+	}))
+	return srv, &requests
+}
+
+func newTestClient(t *testing.T, url string, settings map[string]interface{}) *client {
+	cfg, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	endpoint, err := getURL(config.Protocol, config.Path, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(endpoint, &config, nil, nil, nil, nil)
+	if err := c.Connect(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testEvents(n int) []common.MapStr {
+	var events []common.MapStr
+	for i := 0; i < n; i++ {
+		events = append(events, common.MapStr{
+			"message":   i,
+			"@metadata": common.MapStr{"beat": "test"},
+		})
+	}
+	return events
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"method": "GET"},
+		{"format": "xml"},
+		{"bearer_token": "token", "username": "user"},
+	} {
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", invalid)
+	}
+}
+
+func TestGetURL(t *testing.T) {
+	tests := []struct {
+		scheme, path, host, expected string
+	}{
+		{"", "", "localhost:8080", "http://localhost:8080"},
+		{"https", "/ingest", "localhost", "https://localhost/ingest"},
+		{"https", "/ingest", "http://localhost/hook", "http://localhost/hook"},
+	}
+	for _, test := range tests {
+		url, err := getURL(test.scheme, test.path, test.host)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, url)
+	}
+
+	_, err := getURL("", "", "http://")
+	assert.Error(t, err)
+}
+
+func TestPublishEventsJSON(t *testing.T) {
+	srv, requests := newTestServer(200)
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, map[string]interface{}{
+		"path":       "/ingest",
+		"parameters": map[string]interface{}{"source": "beats"},
+		"headers":    map[string]interface{}{"X-Api-Key": "secret"},
+	})
+	assert.Equal(t, srv.URL+"/ingest?source=beats", c.url)
+
+	failed, err := c.PublishEvents(testEvents(2))
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+
+	if assert.Len(t, *requests, 1) {
+		req := (*requests)[0]
+		assert.Equal(t, "POST", req.method)
+		assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+		assert.Equal(t, "secret", req.header.Get("X-Api-Key"))
+
+		var docs []map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(req.body), &docs))
+		assert.Equal(t, []map[string]interface{}{
+			{"message": 0.0},
+			{"message": 1.0},
+		}, docs)
+	}
+}
+
+func TestPublishEventsNDJSONCompressed(t *testing.T) {
+	srv, requests := newTestServer(202)
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, map[string]interface{}{
+		"method":            "put",
+		"format":            "ndjson",
+		"compression_level": 3,
+		"bearer_token":      "token",
+	})
+
+	failed, err := c.PublishEvents(testEvents(2))
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+
+	if assert.Len(t, *requests, 1) {
+		req := (*requests)[0]
+		assert.Equal(t, "PUT", req.method)
+		assert.Equal(t, "application/x-ndjson", req.header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", req.header.Get("Authorization"))
+		assert.Equal(t, "{\"message\":0}\n{\"message\":1}\n", req.body)
+	}
+}
+
+func TestPublishEventsJSONLines(t *testing.T) {
+	srv, requests := newTestServer(200)
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, map[string]interface{}{
+		"format": "json_lines",
+		"index":  "testbeat",
+	})
+
+	events := []common.MapStr{
+		{"message": "a", "type": "log"},
+		{"message": "b", "@metadata": common.MapStr{"type": "custom", "pipeline": "p"}},
+	}
+	failed, err := c.PublishEvents(events)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+
+	if assert.Len(t, *requests, 1) {
+		req := (*requests)[0]
+		assert.Equal(t, "application/x-ndjson", req.header.Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSuffix(req.body, "\n"), "\n")
+		var docs []map[string]interface{}
+		for _, line := range lines {
+			var doc map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &doc))
+			docs = append(docs, doc)
+		}
+		assert.Equal(t, []map[string]interface{}{
+			{
+				"message":   "a",
+				"type":      "log",
+				"@metadata": map[string]interface{}{"beat": "testbeat", "type": "log"},
+			},
+			{
+				"message": "b",
+				"@metadata": map[string]interface{}{
+					"beat": "testbeat", "type": "custom", "pipeline": "p",
+				},
+			},
+		}, docs)
+	}
+
+	// the events are not modified
+	assert.Equal(t, common.MapStr{"message": "a", "type": "log"}, events[0])
+}
+
+func TestPublishEventsBasicAuth(t *testing.T) {
+	srv, requests := newTestServer(200)
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, map[string]interface{}{
+		"username": "user",
+		"password": "pass",
+	})
+	_, err := c.PublishEvents(testEvents(1))
+	assert.NoError(t, err)
+
+	if assert.Len(t, *requests, 1) {
+		req := &http.Request{Header: (*requests)[0].header}
+		user, pass, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+	}
+}
+
+// Verify that events are returned to be retried on server errors.
+func TestPublishEventsServerError(t *testing.T) {
+	for _, status := range []int{429, 500, 503} {
+		srv, _ := newTestServer(status)
+
+		c := newTestClient(t, srv.URL, nil)
+		events := testEvents(2)
+		failed, err := c.PublishEvents(events)
+		assert.Error(t, err)
+		assert.Equal(t, events, failed)
+		assert.True(t, c.IsConnected())
+
+		srv.Close()
+	}
+}
+
+// Verify that events rejected with a client error are dropped and passed to
+// the dead letter output.
+func TestPublishEventsRejected(t *testing.T) {
+	srv, _ := newTestServer(400)
+	defer srv.Close()
+
+	c := newTestClient(t, srv.URL, nil)
+
+	var dropped []string
+	c.deadLetter = func(event common.MapStr, reason string) {
+		dropped = append(dropped, reason)
+	}
+
+	failed, err := c.PublishEvents(testEvents(2))
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	if assert.Len(t, dropped, 2) {
+		assert.True(t, strings.Contains(dropped[0], "status=400"), dropped[0])
+		assert.True(t, strings.Contains(dropped[0], "response"), dropped[0])
+	}
+}
+
+// Verify that the client is reconnected after a request failed.
+func TestPublishEventsConnectionError(t *testing.T) {
+	srv, _ := newTestServer(200)
+	url := srv.URL
+	srv.Close()
+
+	c := newTestClient(t, url, nil)
+	events := testEvents(1)
+	failed, err := c.PublishEvents(events)
+	assert.Error(t, err)
+	assert.Equal(t, events, failed)
+	assert.False(t, c.IsConnected())
+
+	failed, err = c.PublishEvents(events)
+	assert.Equal(t, ErrNotConnected, err)
+	assert.Equal(t, events, failed)
+}
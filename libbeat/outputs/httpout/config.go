@@ -0,0 +1,91 @@
+package httpout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type httpConfig struct {
+	Index            string                 `config:"index"`
+	Protocol         string                 `config:"protocol"`
+	Path             string                 `config:"path"`
+	Params           map[string]string      `config:"parameters"`
+	Method           string                 `config:"method"`
+	Headers          map[string]string      `config:"headers"`
+	Username         string                 `config:"username"`
+	Password         string                 `config:"password"`
+	BearerToken      string                 `config:"bearer_token"`
+	Format           string                 `config:"format"`
+	ContentType      string                 `config:"content_type"`
+	ProxyURL         string                 `config:"proxy_url"`
+	LoadBalance      bool                   `config:"loadbalance"`
+	CompressionLevel int                    `config:"compression_level" validate:"min=0, max=9"`
+	TLS              *outputs.TLSConfig     `config:"tls"`
+	MaxRetries       int                    `config:"max_retries"`
+	Timeout          time.Duration          `config:"timeout"`
+	Ordering         outputs.OrderingConfig `config:"ordering"`
+	DNS              transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec   int                    `config:"max_bytes_per_second" validate:"min=0"`
+}
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+
+	// formatJSONLines sends one event per line, including the @metadata
+	// known from the Logstash output, as expected by the json_lines codec of
+	// the Logstash http input.
+	formatJSONLines = "json_lines"
+
+	defaultBulkSize = 50
+)
+
+var (
+	defaultConfig = httpConfig{
+		Protocol:         "",
+		Path:             "",
+		Method:           "POST",
+		Format:           formatJSON,
+		ProxyURL:         "",
+		Username:         "",
+		Password:         "",
+		Timeout:          90 * time.Second,
+		MaxRetries:       3,
+		CompressionLevel: 0,
+		LoadBalance:      true,
+	}
+)
+
+var contentTypes = map[string]string{
+	formatJSON:      "application/json",
+	formatNDJSON:    "application/x-ndjson",
+	formatJSONLines: "application/x-ndjson",
+}
+
+func (c *httpConfig) Validate() error {
+	if c.ProxyURL != "" {
+		if _, err := transport.ParseProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+
+	switch strings.ToUpper(c.Method) {
+	case "POST", "PUT", "PATCH":
+	default:
+		return fmt.Errorf("unsupported method '%v', use POST, PUT or PATCH", c.Method)
+	}
+
+	if _, ok := contentTypes[c.Format]; !ok {
+		return fmt.Errorf("unsupported format '%v', use json, ndjson or json_lines", c.Format)
+	}
+
+	if c.BearerToken != "" && (c.Username != "" || c.Password != "") {
+		return errors.New("username and password can not be combined with bearer_token")
+	}
+	return nil
+}
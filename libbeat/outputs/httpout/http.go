@@ -0,0 +1,191 @@
+// Package httpout implements the http output, which sends batches of events
+// to arbitrary HTTP endpoints like webhooks or internal ingestion APIs.
+package httpout
+
+import (
+	"expvar"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type httpOutput struct {
+	mode    mode.ConnectionMode
+	clients []mode.ProtocolClient
+}
+
+var debugf = logp.MakeDebug("http")
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	ackedEvents            = expvar.NewInt("libbeat.http.published_and_acked_events")
+	eventsNotAcked         = expvar.NewInt("libbeat.http.published_but_not_acked_events")
+	eventsDropped          = expvar.NewInt("libbeat.http.dropped_events")
+	publishEventsCallCount = expvar.NewInt("libbeat.http.call_count.PublishEvents")
+
+	statReadBytes   = expvar.NewInt("libbeat.http.publish.read_bytes")
+	statWriteBytes  = expvar.NewInt("libbeat.http.publish.write_bytes")
+	statReadErrors  = expvar.NewInt("libbeat.http.publish.read_errors")
+	statWriteErrors = expvar.NewInt("libbeat.http.publish.write_errors")
+)
+
+const (
+	defaultWaitRetry    = 1 * time.Second
+	defaultMaxWaitRetry = 60 * time.Second
+)
+
+func init() {
+	if err := outputs.RegisterOutputPlugin("http", New); err != nil {
+		panic(err)
+	}
+}
+
+// New instantiates a new output plugin instance publishing to HTTP endpoints.
+func New(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	if !cfg.HasField("bulk_max_size") {
+		cfg.SetInt("bulk_max_size", -1, defaultBulkSize)
+	}
+
+	out := &httpOutput{}
+	if err := out.init(cfg); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (out *httpOutput) init(cfg *common.Config) error {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return err
+	}
+
+	tls, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return err
+	}
+
+	var proxyURL *url.URL
+	if config.ProxyURL != "" {
+		proxyURL, err = transport.ParseProxyURL(config.ProxyURL)
+		if err != nil {
+			return err
+		}
+		logp.Info("Using proxy URL: %s", proxyURL)
+	}
+
+	resolver := transport.NewResolver(config.DNS)
+	throttle := transport.NewThrottle(config.MaxBytesPerSec)
+	clients, err := modeutil.MakeClients(cfg, func(host string) (mode.ProtocolClient, error) {
+		endpoint, err := getURL(config.Protocol, config.Path, host)
+		if err != nil {
+			logp.Err("Invalid host param set: %s, Error: %v", host, err)
+			return nil, err
+		}
+
+		client := newClient(endpoint, &config, tls, proxyURL, resolver, throttle)
+		logp.Info("HTTP url: %s", client.redactedURL())
+		return client, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxRetries := config.MaxRetries
+	maxAttempts := maxRetries + 1 // maximum number of send attempts (-1 = infinite)
+	if maxRetries < 0 {
+		maxAttempts = 0
+	}
+
+	m, err := modeutil.NewConnectionMode(clients, !config.LoadBalance, config.Ordering,
+		maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
+	if err != nil {
+		return err
+	}
+
+	out.mode = m
+	out.clients = clients
+	return nil
+}
+
+func (out *httpOutput) Close() error {
+	return out.mode.Close()
+}
+
+// SetDeadLetter registers the function receiving events rejected by the
+// endpoint with a client error.
+func (out *httpOutput) SetDeadLetter(deadLetter outputs.DeadLetter) {
+	for _, c := range out.clients {
+		c.(*client).deadLetter = deadLetter
+	}
+}
+
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (out *httpOutput) Concurrent() bool {
+	return modeutil.IsConcurrent(out.mode)
+}
+
+func (out *httpOutput) PublishEvent(
+	signaler op.Signaler,
+	opts outputs.Options,
+	event common.MapStr,
+) error {
+	return out.mode.PublishEvent(signaler, opts, event)
+}
+
+func (out *httpOutput) BulkPublish(
+	signaler op.Signaler,
+	opts outputs.Options,
+	events []common.MapStr,
+) error {
+	return out.mode.PublishEvents(signaler, opts, events)
+}
+
+// getURL creates the URL of a host, adding the default scheme and path if
+// they are not set in the hosts setting.
+func getURL(defaultScheme, defaultPath, rawURL string) (string, error) {
+	if defaultScheme == "" {
+		defaultScheme = "http"
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = defaultScheme + "://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing host in '%v'", rawURL)
+	}
+	if u.Path == "" {
+		u.Path = defaultPath
+	}
+	return u.String(), nil
+}
+
+func makeURL(endpoint string, params map[string]string) string {
+	if len(params) == 0 {
+		return endpoint
+	}
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Add(k, v)
+	}
+
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + values.Encode()
+}
@@ -0,0 +1,57 @@
+package httpout
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// PreflightEndpoints returns the HTTP endpoints for the preflight checks. If a
+// proxy is configured, the connection to the proxy is checked instead of the
+// connections to the endpoints. No request is sent to the endpoints.
+func (out *httpOutput) PreflightEndpoints() []preflight.Endpoint {
+	var endpoints []preflight.Endpoint
+	for _, c := range out.clients {
+		client := c.(*client)
+		u, err := url.Parse(client.url)
+		if err != nil {
+			debugf("Skip preflight check of %s: %v", client.redactedURL(), err)
+			continue
+		}
+
+		endpoint := preflight.Endpoint{
+			Name:    client.redactedURL(),
+			Address: hostPort(u),
+		}
+		if client.proxyURL != nil {
+			endpoint.Address = transport.ProxyAddress(client.proxyURL)
+		} else if u.Scheme == "https" {
+			transport := client.http.Transport.(*http.Transport)
+			endpoint.TLS = transport.TLSClientConfig
+			if endpoint.TLS == nil {
+				endpoint.TLS = &tls.Config{}
+			}
+		}
+		if client.resolver != nil {
+			endpoint.LookupHost = client.resolver.LookupHost
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+func hostPort(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Host, port)
+}
@@ -0,0 +1,21 @@
+/*
+Package include imports all output packages of libbeat so that they register
+their builders with the output registry. It is imported by the publisher, such
+that all Beats support the standard outputs. Outputs maintained outside of
+libbeat are registered the same way, by importing their package in the main
+package of the Beat.
+*/
+package include
+
+import (
+	_ "github.com/elastic/beats/libbeat/outputs/console"
+	_ "github.com/elastic/beats/libbeat/outputs/elasticsearch"
+	_ "github.com/elastic/beats/libbeat/outputs/fileout"
+	_ "github.com/elastic/beats/libbeat/outputs/httpout"
+	_ "github.com/elastic/beats/libbeat/outputs/kafka"
+	_ "github.com/elastic/beats/libbeat/outputs/logstash"
+	_ "github.com/elastic/beats/libbeat/outputs/netout"
+	_ "github.com/elastic/beats/libbeat/outputs/redis"
+	_ "github.com/elastic/beats/libbeat/outputs/socketout"
+	_ "github.com/elastic/beats/libbeat/outputs/syslog"
+)
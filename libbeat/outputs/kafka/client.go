@@ -1,7 +1,6 @@
 package kafka
 
 import (
-	"encoding/json"
 	"expvar"
 	"sync"
 	"sync/atomic"
@@ -10,14 +9,16 @@ import (
 	"github.com/Shopify/sarama"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 type client struct {
-	hosts   []string
-	topic   string
-	useType bool
-	config  sarama.Config
+	hosts  []string
+	topic  *topicSelector
+	codec  codec.Codec
+	config sarama.Config
 
 	producer sarama.AsyncProducer
 
@@ -38,14 +39,20 @@ var (
 	ackedEvents            = expvar.NewInt("libbeat.kafka.published_and_acked_events")
 	eventsNotAcked         = expvar.NewInt("libbeat.kafka.published_but_not_acked_events")
 	publishEventsCallCount = expvar.NewInt("libbeat.kafka.call_count.PublishEvents")
+	eventsDropped          = expvar.NewInt("libbeat.kafka.dropped_events")
 )
 
-func newKafkaClient(hosts []string, topic string, useType bool, cfg *sarama.Config) (*client, error) {
+func newKafkaClient(
+	hosts []string,
+	topic *topicSelector,
+	enc codec.Codec,
+	cfg *sarama.Config,
+) (*client, error) {
 	c := &client{
-		hosts:   hosts,
-		useType: useType,
-		topic:   topic,
-		config:  *cfg,
+		hosts:  hosts,
+		topic:  topic,
+		codec:  enc,
+		config: *cfg,
 	}
 	return c, nil
 }
@@ -113,13 +120,18 @@ func (c *client) AsyncPublishEvents(
 	ch := c.producer.Input()
 
 	for _, event := range events {
-		topic := c.topic
-		if c.useType {
-			topic = event["type"].(string)
+		topic, err := c.topic.selectTopic(event)
+		if err != nil {
+			logp.Err("Dropping event, no kafka topic selected: %v", err)
+			eventsDropped.Add(1)
+			ref.done()
+			continue
 		}
 
-		jsonEvent, err := json.Marshal(event)
+		value, err := c.codec.Encode(event)
 		if err != nil {
+			logp.Err("Dropping event, failed to encode event: %v", err)
+			eventsDropped.Add(1)
 			ref.done()
 			continue
 		}
@@ -127,7 +139,7 @@ func (c *client) AsyncPublishEvents(
 		msg := &sarama.ProducerMessage{
 			Metadata: ref,
 			Topic:    topic,
-			Value:    sarama.ByteEncoder(jsonEvent),
+			Value:    sarama.ByteEncoder(value),
 		}
 
 		ch <- msg
@@ -139,6 +151,7 @@ func (c *client) AsyncPublishEvents(
 func (c *client) successWorker(ch <-chan *sarama.ProducerMessage) {
 	defer c.wg.Done()
 	defer debugf("Stop kafka ack worker")
+	defer crash.Recover()
 
 	for msg := range ch {
 		ref := msg.Metadata.(*msgRef)
@@ -149,6 +162,7 @@ func (c *client) successWorker(ch <-chan *sarama.ProducerMessage) {
 func (c *client) errorWorker(ch <-chan *sarama.ProducerError) {
 	defer c.wg.Done()
 	defer debugf("Stop kafka error handler")
+	defer crash.Recover()
 
 	for errMsg := range ch {
 		msg := errMsg.Msg
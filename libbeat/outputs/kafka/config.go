@@ -6,24 +6,30 @@ import (
 	"strings"
 	"time"
 
+	"github.com/elastic/beats/libbeat/common/fmtstr"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 type kafkaConfig struct {
-	Hosts           []string           `config:"hosts"               validate:"required"`
-	TLS             *outputs.TLSConfig `config:"tls"`
-	Timeout         time.Duration      `config:"timeout"             validate:"min=1"`
-	Worker          int                `config:"worker"              validate:"min=1"`
-	UseType         bool               `config:"use_type"`
-	Topic           string             `config:"topic"`
-	KeepAlive       time.Duration      `config:"keep_alive"          validate:"min=0"`
-	MaxMessageBytes *int               `config:"max_message_bytes"   validate:"min=1"`
-	RequiredACKs    *int               `config:"required_acks"       validate:"min=-1"`
-	BrokerTimeout   time.Duration      `config:"broker_timeout"      validate:"min=1"`
-	Compression     string             `config:"compression"`
-	MaxRetries      int                `config:"max_retries"         validate:"min=-1,nonzero"`
-	ClientID        string             `config:"client_id"`
-	ChanBufferSize  int                `config:"channel_buffer_size" validate:"min=1"`
+	Hosts           []string                  `config:"hosts"               validate:"required"`
+	TLS             *outputs.TLSConfig        `config:"tls"`
+	Timeout         time.Duration             `config:"timeout"             validate:"min=1"`
+	Worker          int                       `config:"worker"              validate:"min=1"`
+	UseType         bool                      `config:"use_type"`
+	Topic           *fmtstr.EventFormatString `config:"topic"`
+	DefaultTopic    string                    `config:"default_topic"`
+	Topics          []topicConfig             `config:"topics"`
+	KeepAlive       time.Duration             `config:"keep_alive"          validate:"min=0"`
+	MaxMessageBytes *int                      `config:"max_message_bytes"   validate:"min=1"`
+	RequiredACKs    *int                      `config:"required_acks"       validate:"min=-1"`
+	BrokerTimeout   time.Duration             `config:"broker_timeout"      validate:"min=1"`
+	Compression     string                    `config:"compression"`
+	MaxRetries      int                       `config:"max_retries"         validate:"min=-1,nonzero"`
+	ClientID        string                    `config:"client_id"`
+	ChanBufferSize  int                       `config:"channel_buffer_size" validate:"min=1"`
+	Ordering        outputs.OrderingConfig    `config:"ordering"`
+	Codec           codec.Config              `config:"codec"`
 }
 
 var (
@@ -33,7 +39,7 @@ var (
 		Timeout:         30 * time.Second,
 		Worker:          1,
 		UseType:         false,
-		Topic:           "",
+		Topic:           nil,
 		KeepAlive:       0,
 		MaxMessageBytes: nil, // use library default
 		RequiredACKs:    nil, // use library default
@@ -50,8 +56,8 @@ func (c *kafkaConfig) Validate() error {
 		return errors.New("no hosts configured")
 	}
 
-	if c.UseType == false && c.Topic == "" {
-		return errors.New("use_type must be true or topic must be set")
+	if c.UseType == false && c.Topic == nil && c.DefaultTopic == "" && !c.hasCatchAllTopic() {
+		return errors.New("use_type must be true or topic, default_topic or a topics rule without condition must be set")
 	}
 
 	if _, ok := compressionModes[strings.ToLower(c.Compression)]; !ok {
@@ -60,3 +66,14 @@ func (c *kafkaConfig) Validate() error {
 
 	return nil
 }
+
+// hasCatchAllTopic reports if a topic mapping rule matches all events, such
+// that no fallback topic is required.
+func (c *kafkaConfig) hasCatchAllTopic() bool {
+	for _, rule := range c.Topics {
+		if rule.When == nil {
+			return true
+		}
+	}
+	return false
+}
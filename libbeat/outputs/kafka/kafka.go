@@ -12,6 +12,7 @@ import (
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
 )
@@ -33,7 +34,9 @@ const (
 
 func init() {
 	sarama.Logger = kafkaLogger{}
-	outputs.RegisterOutputPlugin("kafka", New)
+	if err := outputs.RegisterOutputPlugin("kafka", New); err != nil {
+		panic(err)
+	}
 }
 
 var debugf = logp.MakeDebug("kafka")
@@ -76,6 +79,10 @@ func (k *kafka) init(cfg *common.Config) error {
 		return err
 	}
 
+	if _, err := codec.New(k.config.Codec); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -96,10 +103,16 @@ func (k *kafka) initMode(guaranteed bool) (mode.ConnectionMode, error) {
 
 	var clients []mode.AsyncProtocolClient
 	hosts := k.config.Hosts
-	topic := k.config.Topic
-	useType := k.config.UseType
+	topic, err := newTopicSelector(&k.config)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := codec.New(k.config.Codec)
+	if err != nil {
+		return nil, err
+	}
 	for i := 0; i < worker; i++ {
-		client, err := newKafkaClient(hosts, topic, useType, libCfg)
+		client, err := newKafkaClient(hosts, topic, enc, libCfg)
 		if err != nil {
 			logp.Err("Failed to create kafka client: %v", err)
 			return nil, err
@@ -115,6 +128,7 @@ func (k *kafka) initMode(guaranteed bool) (mode.ConnectionMode, error) {
 	mode, err := modeutil.NewAsyncConnectionMode(
 		clients,
 		false,
+		k.config.Ordering,
 		maxAttempts,
 		defaultWaitRetry,
 		libCfg.Net.WriteTimeout,
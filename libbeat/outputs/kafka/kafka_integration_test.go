@@ -12,8 +12,10 @@ import (
 
 	"github.com/Shopify/sarama"
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,7 +49,8 @@ func newTestKafkaClient(t *testing.T, topic string) *client {
 	hosts := []string{getTestKafkaHost()}
 	t.Logf("host: %v", hosts)
 
-	client, err := newKafkaClient(hosts, topic, false, nil)
+	selector := &topicSelector{topic: fmtstr.MustCompileEvent(topic)}
+	client, err := newKafkaClient(hosts, selector, codec.NewJSON(false), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,93 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// topicConfig configures a topic mapping rule. Events matching the condition
+// are published to the topic. Rules without condition match all events.
+type topicConfig struct {
+	Topic *fmtstr.EventFormatString   `config:"topic" validate:"required"`
+	When  *processors.ConditionConfig `config:"when"`
+}
+
+type topicRule struct {
+	topic *fmtstr.EventFormatString
+	cond  *processors.Condition
+}
+
+// topicSelector selects the topic of an event. The topic of the first
+// matching rule of the topics setting is used. Otherwise the type of the event
+// is used if use_type is set, or the topic setting. If the topic can not be
+// formatted because a field is missing, the default topic is used.
+type topicSelector struct {
+	rules        []topicRule
+	useType      bool
+	topic        *fmtstr.EventFormatString
+	defaultTopic string
+}
+
+var errNoTopic = errors.New("no topic selected")
+
+func newTopicSelector(config *kafkaConfig) (*topicSelector, error) {
+	s := &topicSelector{
+		useType:      config.UseType,
+		topic:        config.Topic,
+		defaultTopic: config.DefaultTopic,
+	}
+
+	for i, rule := range config.Topics {
+		cond, err := processors.NewCondition(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition of topic rule %d: %v", i, err)
+		}
+		s.rules = append(s.rules, topicRule{topic: rule.Topic, cond: cond})
+	}
+	return s, nil
+}
+
+// selectTopic returns the topic of event, including the namespace of the
+// event. An error is returned if no topic is selected and no default topic is
+// configured.
+func (s *topicSelector) selectTopic(event common.MapStr) (string, error) {
+	topic, err := s.eventTopic(event)
+	if err != nil || topic == "" {
+		if s.defaultTopic == "" {
+			if err == nil {
+				err = errNoTopic
+			}
+			return "", err
+		}
+		topic = s.defaultTopic
+	}
+
+	if namespace := common.GetNamespace(event); namespace != "" {
+		topic += "-" + namespace
+	}
+	return topic, nil
+}
+
+func (s *topicSelector) eventTopic(event common.MapStr) (string, error) {
+	for _, rule := range s.rules {
+		if rule.cond == nil || rule.cond.Check(event) {
+			return rule.topic.Run(event)
+		}
+	}
+
+	if s.useType {
+		if typ, ok := event["type"].(string); ok {
+			return typ, nil
+		}
+		return "", errors.New("event has no type")
+	}
+
+	if s.topic == nil {
+		return "", errNoTopic
+	}
+	return s.topic.Run(event)
+}
@@ -0,0 +1,141 @@
+// +build !integration
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTopicSelector(t *testing.T, settings map[string]interface{}) *topicSelector {
+	settings["hosts"] = []string{"localhost:9092"}
+	cfg, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := newTopicSelector(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSelectTopicFormat(t *testing.T) {
+	s := newTestTopicSelector(t, map[string]interface{}{
+		"topic":         "%{[type]}-%{[beat.name]}",
+		"default_topic": "beats",
+	})
+
+	topic, err := s.selectTopic(common.MapStr{
+		"type": "log",
+		"beat": common.MapStr{"name": "host1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "log-host1", topic)
+
+	// missing fields select the default topic
+	topic, err = s.selectTopic(common.MapStr{"type": "log"})
+	assert.NoError(t, err)
+	assert.Equal(t, "beats", topic)
+}
+
+func TestSelectTopicRules(t *testing.T) {
+	s := newTestTopicSelector(t, map[string]interface{}{
+		"topic": "beats",
+		"topics": []map[string]interface{}{
+			{
+				"topic": "critical-%{[type]}",
+				"when":  map[string]interface{}{"equals.level": "critical"},
+			},
+			{
+				"topic": "audit",
+				"when":  map[string]interface{}{"equals.type": "audit"},
+			},
+		},
+	})
+
+	tests := []struct {
+		event    common.MapStr
+		expected string
+	}{
+		{common.MapStr{"type": "log", "level": "critical"}, "critical-log"},
+		{common.MapStr{"type": "audit"}, "audit"},
+		{common.MapStr{"type": "log"}, "beats"},
+		{common.MapStr{
+			"type":             "log",
+			common.MetadataKey: common.MapStr{common.NamespaceKey: "tenant1"},
+		}, "beats-tenant1"},
+	}
+	for _, test := range tests {
+		topic, err := s.selectTopic(test.event)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, topic, "%v", test.event)
+	}
+}
+
+func TestSelectTopicUseType(t *testing.T) {
+	s := newTestTopicSelector(t, map[string]interface{}{
+		"use_type": true,
+	})
+
+	topic, err := s.selectTopic(common.MapStr{"type": "log"})
+	assert.NoError(t, err)
+	assert.Equal(t, "log", topic)
+
+	_, err = s.selectTopic(common.MapStr{})
+	assert.Error(t, err)
+}
+
+func TestSelectTopicRulesCatchAll(t *testing.T) {
+	s := newTestTopicSelector(t, map[string]interface{}{
+		"topics": []map[string]interface{}{
+			{
+				"topic": "audit",
+				"when":  map[string]interface{}{"equals.type": "audit"},
+			},
+			{"topic": "%{[type]:beats}"},
+		},
+	})
+
+	tests := []struct {
+		event    common.MapStr
+		expected string
+	}{
+		{common.MapStr{"type": "audit"}, "audit"},
+		{common.MapStr{"type": "log"}, "log"},
+		{common.MapStr{}, "beats"},
+	}
+	for _, test := range tests {
+		topic, err := s.selectTopic(test.event)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, topic, "%v", test.event)
+	}
+}
+
+func TestTopicConfigValidate(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"hosts": []string{"localhost:9092"}},
+		{"hosts": []string{"localhost:9092"}, "topic": "%{[type"},
+		{"hosts": []string{"localhost:9092"}, "topics": []map[string]interface{}{
+			{"topic": "audit", "when": map[string]interface{}{"equals.type": "audit"}},
+		}},
+		{"hosts": []string{"localhost:9092"}, "topic": "beats", "topics": []map[string]interface{}{
+			{"when": map[string]interface{}{"equals.type": "audit"}},
+		}},
+	} {
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", invalid)
+	}
+}
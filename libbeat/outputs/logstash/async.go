@@ -1,6 +1,7 @@
 package logstash
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
@@ -19,8 +21,13 @@ type asyncClient struct {
 	connect func() error
 }
 
+// msgRef tracks the windows of a batch sent to Logstash. With pipelining,
+// the windows are in flight at the same time, and the callback is called once
+// all windows have been acknowledged or failed, with the events not
+// acknowledged.
 type msgRef struct {
 	count     int32
+	mutex     sync.Mutex
 	batch     []common.MapStr
 	err       error
 	cb        func([]common.MapStr, error)
@@ -33,14 +40,16 @@ func newAsyncLumberjackClient(
 	queueSize int,
 	compressLevel int,
 	maxWindowSize int,
+	slowStart bool,
 	timeout time.Duration,
 	beat string,
+	eventCodec codec.Codec,
 ) (*asyncClient, error) {
 	c := &asyncClient{}
 	c.Client = conn
-	c.win.init(defaultStartMaxWindowSize, maxWindowSize)
+	c.win.init(startWindowSize(slowStart, maxWindowSize), maxWindowSize)
 
-	enc, err := makeLogstashEventEncoder(beat)
+	enc, err := makeLogstashEventEncoder(beat, eventCodec)
 	if err != nil {
 		return nil, err
 	}
@@ -115,8 +124,16 @@ func (c *asyncClient) AsyncPublishEvents(
 			_ = c.Close()
 
 			logp.Err("Failed to publish events caused by: %v", err)
-			eventsNotAcked.Add(int64(len(events)))
-			return err
+			if len(events) == ref.batchSize {
+				eventsNotAcked.Add(int64(len(events)))
+				return err
+			}
+
+			// Windows already in flight are still acknowledged or failed by
+			// Logstash. The callback reports all events not acknowledged,
+			// including the events not sent.
+			ref.setErr(err)
+			break
 		}
 	}
 	ref.dec()
@@ -165,26 +182,40 @@ func (r *msgRef) callback(seq uint32, err error) {
 
 func (r *msgRef) done(n uint32) {
 	ackedEvents.Add(int64(n))
+	r.mutex.Lock()
 	r.batch = r.batch[n:]
+	r.mutex.Unlock()
 	r.win.tryGrowWindow(r.batchSize)
 	r.dec()
 }
 
 func (r *msgRef) fail(n uint32, err error) {
 	ackedEvents.Add(int64(n))
+	r.mutex.Lock()
 	r.err = err
 	r.batch = r.batch[n:]
+	r.mutex.Unlock()
 	r.win.shrinkWindow()
 	r.dec()
 }
 
+func (r *msgRef) setErr(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
 func (r *msgRef) dec() {
 	i := atomic.AddInt32(&r.count, -1)
 	if i > 0 {
 		return
 	}
 
+	r.mutex.Lock()
 	err := r.err
+	r.mutex.Unlock()
 	if err != nil {
 		eventsNotAcked.Add(int64(len(r.batch)))
 		r.cb(r.batch, err)
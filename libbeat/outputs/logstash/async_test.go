@@ -7,9 +7,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/elastic/go-lumber/server/v2"
+	"github.com/stretchr/testify/assert"
+
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/transport"
+	"github.com/elastic/beats/libbeat/outputs/transport/transptest"
 )
 
 type testAsyncDriver struct {
@@ -35,13 +39,66 @@ func TestAsyncMultiFailMaxTimeouts(t *testing.T) {
 	testMultiFailMaxTimeouts(t, makeAsyncTestClient)
 }
 
+func TestAsyncPipelinedWindows(t *testing.T) {
+	enableLogging([]string{"*"})
+	mock := transptest.NewMockServerTCP(t, 1*time.Second, "", nil)
+	server, _ := v2.NewWithListener(mock.Listener)
+	defer server.Close()
+
+	transp, err := mock.Transp()
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer transp.Close()
+
+	client, err := newAsyncLumberjackClient(transp,
+		3, 3, testMaxWindowSize, true, 1*time.Second, "testbeat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Connect(1 * time.Second)
+	defer client.Close()
+
+	events := make([]common.MapStr, 25)
+	for i := range events {
+		events[i] = common.MapStr{"type": "test", "line": i}
+	}
+
+	results := make(chan error, 1)
+	err = client.AsyncPublishEvents(func(failed []common.MapStr, err error) {
+		assert.Len(t, failed, 0)
+		results <- err
+	}, events)
+	assert.NoError(t, err)
+
+	// The batch is split into windows, which are all sent before the first
+	// window is acknowledged. The test server only reads the next window once
+	// the previous one has been acknowledged.
+	for _, size := range []int{10, 10, 5} {
+		batch := server.Receive()
+		assert.Len(t, batch.Events, size)
+		batch.ACK()
+	}
+
+	assert.NoError(t, <-results)
+
+	// the window grows with every acknowledged window, up to the batch size
+	assert.Equal(t, 25, client.win.get())
+}
+
+func TestSlowStartDisabled(t *testing.T) {
+	assert.Equal(t, defaultStartMaxWindowSize, startWindowSize(true, 2048))
+	assert.Equal(t, 2048, startWindowSize(false, 2048))
+	assert.Equal(t, 5, startWindowSize(true, 5))
+}
+
 func makeAsyncTestClient(conn *transport.Client) testClientDriver {
 	return newAsyncTestDriver(newAsyncTestClient(conn))
 }
 
 func newAsyncTestClient(conn *transport.Client) *asyncClient {
 	c, err := newAsyncLumberjackClient(conn,
-		1, 3, testMaxWindowSize, 100*time.Millisecond, "testbeat")
+		1, 3, testMaxWindowSize, true, 100*time.Millisecond, "testbeat", nil)
 	if err != nil {
 		panic(err)
 	}
@@ -45,7 +45,7 @@ type testDriverCommand struct {
 
 func newLumberjackTestClient(conn *transport.Client) *client {
 	c, err := newLumberjackClient(conn, 3,
-		testMaxWindowSize, 100*time.Millisecond, "test")
+		testMaxWindowSize, true, 100*time.Millisecond, "test", nil)
 	if err != nil {
 		panic(err)
 	}
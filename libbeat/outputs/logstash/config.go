@@ -4,20 +4,27 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
 type logstashConfig struct {
-	Index            string                `config:"index"`
-	Port             int                   `config:"port"`
-	LoadBalance      bool                  `config:"loadbalance"`
-	BulkMaxSize      int                   `config:"bulk_max_size"`
-	Timeout          time.Duration         `config:"timeout"`
-	Pipelining       int                   `config:"pipelining"        validate:"min=0"`
-	CompressionLevel int                   `config:"compression_level" validate:"min=0, max=9"`
-	MaxRetries       int                   `config:"max_retries"       validate:"min=-1"`
-	TLS              *outputs.TLSConfig    `config:"tls"`
-	Proxy            transport.ProxyConfig `config:",inline"`
+	Index            string                 `config:"index"`
+	Port             int                    `config:"port"`
+	LoadBalance      bool                   `config:"loadbalance"`
+	BulkMaxSize      int                    `config:"bulk_max_size"`
+	Timeout          time.Duration          `config:"timeout"`
+	Pipelining       int                    `config:"pipelining"        validate:"min=0"`
+	SlowStart        bool                   `config:"slow_start"`
+	CompressionLevel int                    `config:"compression_level" validate:"min=0, max=9"`
+	MaxRetries       int                    `config:"max_retries"       validate:"min=-1"`
+	TLS              *outputs.TLSConfig     `config:"tls"`
+	Proxy            transport.ProxyConfig  `config:",inline"`
+	ProxyProtocol    int                    `config:"proxy_protocol"    validate:"min=0, max=2"`
+	Ordering         outputs.OrderingConfig `config:"ordering"`
+	DNS              transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec   int                    `config:"max_bytes_per_second" validate:"min=0"`
+	Codec            codec.Config           `config:"codec"`
 }
 
 var (
@@ -25,6 +32,7 @@ var (
 		Port:             10200,
 		LoadBalance:      false,
 		BulkMaxSize:      2048,
+		SlowStart:        true,
 		CompressionLevel: 3,
 		Timeout:          30 * time.Second,
 		MaxRetries:       3,
@@ -9,6 +9,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 )
 
 type encoder struct {
@@ -18,36 +19,61 @@ type encoder struct {
 
 var hex = "0123456789abcdef"
 
-func makeLogstashEventEncoder(beat string) (func(interface{}) ([]byte, error), error) {
+// makeLogstashEventEncoder creates the encoder of the JSON documents sent to
+// Logstash. If a codec is given, the document contains the event encoded by
+// the codec in the message field, instead of the event fields. Events the
+// codec fails to encode are sent with their fields, such that the batch is
+// not retried forever.
+func makeLogstashEventEncoder(
+	beat string,
+	eventCodec codec.Codec,
+) (func(interface{}) ([]byte, error), error) {
 	enc := encoder{buf: bytes.NewBuffer(nil)}
 
-	beatName, err := json.Marshal(beat)
-	if err != nil {
-		return nil, err
-	}
-
 	cb := func(rawEvent interface{}) ([]byte, error) {
 		event := rawEvent.(common.MapStr)
 		buf := enc.buf
 		buf.Reset()
 
-		buf.WriteRune('{')
-		if _, hasMeta := event["@metadata"]; !hasMeta {
-			typ := event["type"].(string)
-			buf.WriteString(`"@metadata":{"type":`)
-			encodeString(buf, typ)
+		// Metadata set in the event takes precedence over the defaults.
+		meta := common.MapStr{
+			"type": event["type"],
+			"beat": beat,
+		}
+		if eventMeta, ok := event[common.MetadataKey].(common.MapStr); ok {
+			meta.Update(eventMeta)
+		}
+
+		buf.WriteString(`{"@metadata":{`)
+		if err := enc.encodeKeyValues(meta); err != nil {
+			logp.Err("jsonEncode failed with: %v", err)
+			return nil, err
+		}
+		b := buf.Bytes()
+		b[len(b)-1] = '}'
+		buf.WriteRune(',')
+
+		fields := event.WithoutMetadata()
+		if eventCodec != nil {
+			if line, err := eventCodec.Encode(event); err != nil {
+				logp.Err("Failed to encode event, sending event fields: %v", err)
+			} else {
+				fields = common.MapStr{"message": string(line)}
+				for _, key := range []string{"@timestamp", "type"} {
+					if value, exists := event[key]; exists {
+						fields[key] = value
+					}
+				}
+			}
 		}
 
-		buf.WriteString(`,"beat":`)
-		buf.Write(beatName)
-		buf.WriteString(`},`)
-		err := enc.encodeKeyValues(event)
+		err := enc.encodeKeyValues(fields)
 		if err != nil {
 			logp.Err("jsonEncode failed with: %v", err)
 			return nil, err
 		}
 
-		b := buf.Bytes()
+		b = buf.Bytes()
 		b[len(b)-1] = '}'
 
 		return buf.Bytes(), nil
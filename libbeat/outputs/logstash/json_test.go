@@ -0,0 +1,97 @@
+// +build !integration
+
+package logstash
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeEventMetadata(t *testing.T) {
+	enc, err := makeLogstashEventEncoder("testbeat", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		event    common.MapStr
+		expected map[string]interface{}
+	}{
+		{
+			common.MapStr{"type": "log", "message": "hello"},
+			map[string]interface{}{
+				"@metadata": map[string]interface{}{"type": "log", "beat": "testbeat"},
+				"type":      "log",
+				"message":   "hello",
+			},
+		},
+		{
+			common.MapStr{
+				"type":             "log",
+				common.MetadataKey: common.MapStr{"input": "tcp", "beat": "other"},
+			},
+			map[string]interface{}{
+				"@metadata": map[string]interface{}{"type": "log", "beat": "other", "input": "tcp"},
+				"type":      "log",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		raw, err := enc(test.event)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatalf("invalid json '%s': %v", raw, err)
+		}
+		assert.Equal(t, test.expected, decoded)
+	}
+}
+
+func TestEncodeEventCodec(t *testing.T) {
+	eventCodec, err := codec.New(codec.Config{Raw: &codec.RawConfig{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := makeLogstashEventEncoder("testbeat", eventCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := enc(common.MapStr{
+		"@timestamp": "2016-11-01T10:00:00.000Z",
+		"type":       "log",
+		"message":    "hello",
+		"source":     "/var/log/messages",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("invalid json '%s': %v", raw, err)
+	}
+	assert.Equal(t, map[string]interface{}{
+		"@metadata":  map[string]interface{}{"type": "log", "beat": "testbeat"},
+		"@timestamp": "2016-11-01T10:00:00.000Z",
+		"type":       "log",
+		"message":    "hello",
+	}, decoded)
+
+	// events the codec can not encode are sent with their fields
+	raw, err = enc(common.MapStr{"type": "log", "source": "/var/log/messages"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t,
+		`{"@metadata":{"type":"log","beat":"testbeat"},"type":"log","source":"/var/log/messages"}`,
+		string(raw))
+}
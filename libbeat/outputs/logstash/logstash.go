@@ -13,8 +13,10 @@ import (
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/preflight"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
@@ -43,7 +45,9 @@ const (
 func init() {
 	log.Logger = logstashLogger{}
 
-	outputs.RegisterOutputPlugin("logstash", new)
+	if err := outputs.RegisterOutputPlugin("logstash", new); err != nil {
+		panic(err)
+	}
 }
 
 func new(cfg *common.Config, _ int) (outputs.Outputer, error) {
@@ -57,6 +61,8 @@ func new(cfg *common.Config, _ int) (outputs.Outputer, error) {
 type logstash struct {
 	mode  mode.ConnectionMode
 	index string
+
+	endpoints []preflight.Endpoint // endpoints for the preflight checks
 }
 
 func (lj *logstash) init(cfg *common.Config) error {
@@ -76,10 +82,22 @@ func (lj *logstash) init(cfg *common.Config) error {
 		return err
 	}
 
+	// events are sent as JSON documents, unless a codec is configured
+	var eventCodec codec.Codec
+	if config.Codec.IsSet() {
+		eventCodec, err = codec.New(config.Codec)
+		if err != nil {
+			return err
+		}
+	}
+
 	transp := &transport.Config{
-		Timeout: config.Timeout,
-		Proxy:   &config.Proxy,
-		TLS:     tls,
+		Timeout:       config.Timeout,
+		Proxy:         &config.Proxy,
+		ProxyProtocol: config.ProxyProtocol,
+		TLS:           tls,
+		Resolver:      transport.NewResolver(config.DNS),
+		Throttle:      transport.NewThrottle(config.MaxBytesPerSec),
 		Stats: &transport.IOStats{
 			Read:        statReadBytes,
 			Write:       statWriteBytes,
@@ -91,16 +109,16 @@ func (lj *logstash) init(cfg *common.Config) error {
 	logp.Info("Max Retries set to: %v", sendRetries)
 	var m mode.ConnectionMode
 	if config.Pipelining == 0 {
-		clients, err := modeutil.MakeClients(cfg, makeClientFactory(&config, transp))
+		clients, err := modeutil.MakeClients(cfg, makeClientFactory(&config, transp, eventCodec))
 		if err == nil {
-			m, err = modeutil.NewConnectionMode(clients, !config.LoadBalance,
+			m, err = modeutil.NewConnectionMode(clients, !config.LoadBalance, config.Ordering,
 				maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
 		}
 	} else {
 		clients, err := modeutil.MakeAsyncClients(cfg,
-			makeAsyncClientFactory(&config, transp))
+			makeAsyncClientFactory(&config, transp, eventCodec))
 		if err == nil {
-			m, err = modeutil.NewAsyncConnectionMode(clients, !config.LoadBalance,
+			m, err = modeutil.NewAsyncConnectionMode(clients, !config.LoadBalance, config.Ordering,
 				maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
 		}
 	}
@@ -111,12 +129,24 @@ func (lj *logstash) init(cfg *common.Config) error {
 	lj.mode = m
 	lj.index = config.Index
 
+	hosts, err := modeutil.ReadHostList(cfg)
+	if err != nil {
+		return err
+	}
+	lj.endpoints = transport.PreflightEndpoints(transp, hosts, config.Port)
+
 	return nil
 }
 
+// PreflightEndpoints returns the Logstash hosts for the preflight checks.
+func (lj *logstash) PreflightEndpoints() []preflight.Endpoint {
+	return lj.endpoints
+}
+
 func makeClientFactory(
 	cfg *logstashConfig,
 	tcfg *transport.Config,
+	eventCodec codec.Codec,
 ) modeutil.ClientFactory {
 	compressLvl := cfg.CompressionLevel
 	maxBulkSz := cfg.BulkMaxSize
@@ -127,13 +157,15 @@ func makeClientFactory(
 		if err != nil {
 			return nil, err
 		}
-		return newLumberjackClient(t, compressLvl, maxBulkSz, to, cfg.Index)
+		return newLumberjackClient(t, compressLvl, maxBulkSz, cfg.SlowStart, to,
+			cfg.Index, eventCodec)
 	}
 }
 
 func makeAsyncClientFactory(
 	cfg *logstashConfig,
 	tcfg *transport.Config,
+	eventCodec codec.Codec,
 ) modeutil.AsyncClientFactory {
 	compressLvl := cfg.CompressionLevel
 	maxBulkSz := cfg.BulkMaxSize
@@ -145,7 +177,8 @@ func makeAsyncClientFactory(
 		if err != nil {
 			return nil, err
 		}
-		return newAsyncLumberjackClient(t, queueSize, compressLvl, maxBulkSz, to, cfg.Index)
+		return newAsyncLumberjackClient(t, queueSize, compressLvl, maxBulkSz,
+			cfg.SlowStart, to, cfg.Index, eventCodec)
 	}
 }
 
@@ -153,6 +186,12 @@ func (lj *logstash) Close() error {
 	return lj.mode.Close()
 }
 
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (lj *logstash) Concurrent() bool {
+	return modeutil.IsConcurrent(lj.mode)
+}
+
 // TODO: update Outputer interface to support multiple events for batch-like
 //       processing (e.g. for filebeat). Batch like processing might reduce
 //       send/receive overhead per event for other implementors too.
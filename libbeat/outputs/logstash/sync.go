@@ -7,6 +7,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
@@ -25,14 +26,16 @@ func newLumberjackClient(
 	conn *transport.Client,
 	compressLevel int,
 	maxWindowSize int,
+	slowStart bool,
 	timeout time.Duration,
 	beat string,
+	eventCodec codec.Codec,
 ) (*client, error) {
 	c := &client{}
 	c.Client = conn
-	c.win.init(defaultStartMaxWindowSize, maxWindowSize)
+	c.win.init(startWindowSize(slowStart, maxWindowSize), maxWindowSize)
 
-	enc, err := makeLogstashEventEncoder(beat)
+	enc, err := makeLogstashEventEncoder(beat, eventCodec)
 	if err != nil {
 		return nil, err
 	}
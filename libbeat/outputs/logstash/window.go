@@ -2,20 +2,37 @@ package logstash
 
 import (
 	"math"
+	"sync"
 	"sync/atomic"
 )
 
+// window is the sliding window limiting the number of events sent to
+// Logstash in one lumberjack batch. The window grows while batches are
+// acknowledged, and shrinks on errors. With pipelining the window is updated
+// by the ACK handler while publishers read it, so updates are serialized by
+// the mutex.
 type window struct {
+	mutex sync.Mutex
+
 	windowSize      int32
 	maxOkWindowSize int // max window size sending was successful for
 	maxWindowSize   int
 }
 
 func (w *window) init(start, max int) {
-	*w = window{
-		windowSize:    int32(start),
-		maxWindowSize: max,
+	w.windowSize = int32(start)
+	w.maxOkWindowSize = 0
+	w.maxWindowSize = max
+}
+
+// startWindowSize returns the initial window size. With slow start the window
+// starts small and grows up to max, otherwise the window starts at max and is
+// only reduced on errors.
+func startWindowSize(slowStart bool, max int) int {
+	if !slowStart || max < defaultStartMaxWindowSize {
+		return max
 	}
+	return defaultStartMaxWindowSize
 }
 
 func (w *window) get() int {
@@ -26,6 +43,9 @@ func (w *window) get() int {
 // (window size grows exponentially)
 // TODO: use duration until ACK to estimate an ok max window size value
 func (w *window) tryGrowWindow(batchSize int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
 	windowSize := w.get()
 
 	if windowSize <= batchSize {
@@ -62,6 +82,9 @@ func (w *window) tryGrowWindow(batchSize int) {
 }
 
 func (w *window) shrinkWindow() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
 	windowSize := w.get()
 	orig := windowSize
 
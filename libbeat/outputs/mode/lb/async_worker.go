@@ -99,7 +99,7 @@ func (w *asyncWorker) connect() bool {
 
 func (w *asyncWorker) sendLoop() (done bool) {
 	for {
-		msg, ok := w.ctx.receive()
+		msg, ok := w.ctx.receiveFor(w.id)
 		if !ok {
 			return true
 		}
@@ -115,10 +115,26 @@ func (w *asyncWorker) sendLoop() (done bool) {
 
 func (w *asyncWorker) onMessage(msg eventsMessage) error {
 	var err error
+	var handled chan struct{}
+	if msg.ordered {
+		// wait for the result of ordered events before publishing the next
+		// message, such that failed events are retried before any later event
+		// with the same key is published
+		handled = make(chan struct{})
+	}
+
 	if msg.event != nil {
-		err = w.client.AsyncPublishEvent(w.handleResult(msg), msg.event)
+		handleResult := w.handleResult(msg)
+		err = w.client.AsyncPublishEvent(func(err error) {
+			handleResult(err)
+			closeHandled(handled)
+		}, msg.event)
 	} else {
-		err = w.client.AsyncPublishEvents(w.handleResults(msg), msg.events)
+		handleResults := w.handleResults(msg)
+		err = w.client.AsyncPublishEvents(func(events []common.MapStr, err error) {
+			handleResults(events, err)
+			closeHandled(handled)
+		}, msg.events)
 	}
 
 	if err != nil {
@@ -130,9 +146,22 @@ func (w *asyncWorker) onMessage(msg eventsMessage) error {
 		// deadlock on retries channel if client puts multiple failed outstanding
 		// events into the pipeline
 		w.onFail(msg, err)
+		return err
+	}
+
+	if handled != nil {
+		select {
+		case <-handled:
+		case <-w.ctx.done:
+		}
 	}
+	return nil
+}
 
-	return err
+func closeHandled(handled chan struct{}) {
+	if handled != nil {
+		close(handled)
+	}
 }
 
 func (w *asyncWorker) handleResult(msg eventsMessage) func(error) {
@@ -187,6 +216,13 @@ func (w *asyncWorker) handleResults(msg eventsMessage) func([]common.MapStr, err
 
 		// re-insert non-published events into pipeline
 		if len(events) != 0 {
+			if msg.ordered {
+				// the worker retries the events before publishing the next message
+				msg.events = events
+				w.ctx.pushFailed(msg)
+				return
+			}
+
 			go func() {
 				debugf("add non-published events back into pipeline: %v", len(events))
 				msg.events = events
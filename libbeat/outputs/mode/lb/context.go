@@ -28,10 +28,23 @@ type context struct {
 	// The retries channel is buffered to mitigate possible deadlocks when all
 	// workers become unresponsive.
 	work, retries chan eventsMessage
+
+	// ordering key and per worker queues. If set, events with the same value in
+	// the key field are always forwarded to the same worker, which publishes
+	// and retries them in order.
+	key    string
+	queues []workerQueue
+}
+
+// workerQueue forwards ordered messages to one worker. The retries channel
+// holds the failed message of the worker until it is sent again.
+type workerQueue struct {
+	work, retries chan eventsMessage
 }
 
 type eventsMessage struct {
 	worker       int
+	ordered      bool // worker is fixed by ordering key
 	attemptsLeft int
 	signaler     op.Signaler
 	events       []common.MapStr
@@ -48,6 +61,23 @@ func makeContext(nClients, maxAttempts int, timeout time.Duration) context {
 	}
 }
 
+func makeOrderedContext(nClients, maxAttempts int, timeout time.Duration, key string) context {
+	ctx := makeContext(nClients, maxAttempts, timeout)
+	if nClients == 0 {
+		return ctx
+	}
+
+	ctx.key = key
+	ctx.queues = make([]workerQueue, nClients)
+	for i := range ctx.queues {
+		ctx.queues[i] = workerQueue{
+			work:    make(chan eventsMessage),
+			retries: make(chan eventsMessage, 1),
+		}
+	}
+	return ctx
+}
+
 func (ctx *context) Close() error {
 	debugf("close context")
 	close(ctx.done)
@@ -60,17 +90,44 @@ func (ctx *context) pushEvents(msg eventsMessage, guaranteed bool) bool {
 		maxAttempts = -1
 	}
 	msg.attemptsLeft = maxAttempts
-	ok := ctx.forwardEvent(ctx.work, msg)
-	if !ok {
-		dropping(msg)
+
+	if ctx.queues == nil {
+		ok := ctx.forwardEvent(ctx.work, msg)
+		if !ok {
+			dropping(msg)
+		}
+		return ok
+	}
+
+	ok := true
+	for _, part := range ctx.partition(msg) {
+		ch := ctx.work
+		if part.ordered {
+			ch = ctx.queues[part.worker].work
+		}
+		if !ctx.forwardEvent(ch, part) {
+			dropping(part)
+			ok = false
+		}
 	}
 	return ok
 }
 
+// retryQueue returns the channel failed messages are returned to. Ordered
+// messages are returned to the worker they have been forwarded to.
+func (ctx *context) retryQueue(msg eventsMessage) chan eventsMessage {
+	if msg.ordered {
+		return ctx.queues[msg.worker].retries
+	}
+	return ctx.retries
+}
+
 func (ctx *context) pushFailed(msg eventsMessage) bool {
-	ok := ctx.forwardEvent(ctx.retries, msg)
+	ok := ctx.forwardEvent(ctx.retryQueue(msg), msg)
 	if !ok {
 		dropping(msg)
+	} else {
+		mode.Retried(1)
 	}
 	return ok
 }
@@ -82,7 +139,8 @@ func (ctx *context) tryPushFailed(msg eventsMessage) bool {
 	}
 
 	select {
-	case ctx.retries <- msg:
+	case ctx.retryQueue(msg) <- msg:
+		mode.Retried(1)
 		return true
 	default:
 		return false
@@ -140,6 +198,47 @@ func (ctx *context) receive() (eventsMessage, bool) {
 	return msg, true
 }
 
+// receiveFor returns the next message to be published by the given worker.
+// Failed ordered messages of the worker take precedence over all other
+// messages, such that no later event with the same key is published before
+// them.
+func (ctx *context) receiveFor(worker int) (eventsMessage, bool) {
+	if ctx.queues == nil {
+		return ctx.receive()
+	}
+
+	var msg eventsMessage
+	queue := ctx.queues[worker]
+
+	select {
+	case msg = <-queue.retries:
+		debugf("events from worker retries queue")
+		return msg, true
+	default:
+	}
+
+	select {
+	case msg = <-ctx.retries:
+		debugf("events from retries queue")
+		return msg, true
+	default:
+	}
+
+	select {
+	case <-ctx.done:
+		return msg, false
+	case msg = <-queue.retries:
+		debugf("events from worker retries queue")
+	case msg = <-ctx.retries:
+		debugf("events from retries queue")
+	case msg = <-ctx.work:
+		debugf("events from worker worker queue")
+	case msg = <-queue.work:
+		debugf("events from ordered worker queue")
+	}
+	return msg, true
+}
+
 // dropping is called when a message is dropped. It updates the
 // relevant counters and sends a failed signal.
 func dropping(msg eventsMessage) {
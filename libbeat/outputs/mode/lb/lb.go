@@ -6,6 +6,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/outputs/mode"
@@ -78,10 +79,31 @@ func New(
 		maxAttempts = -1
 	}
 
-	m := &LB{
-		ctx: makeContext(makeWorkers.count(), maxAttempts, timeout),
+	return newLB(makeContext(makeWorkers.count(), maxAttempts, timeout), makeWorkers)
+}
+
+// NewOrdered creates a new load balancer connection mode publishing events
+// with the same value in the key field in order. Events are assigned to
+// workers by the hash of their key, and each worker publishes and retries its
+// events in order. Events without key are load balanced between all workers.
+func NewOrdered(
+	makeWorkers WorkerFactory,
+	key string,
+	maxAttempts int,
+	timeout time.Duration,
+) (*LB, error) {
+	debugf("configure ordering key: %v", key)
+
+	if maxAttempts == 0 {
+		maxAttempts = -1
 	}
 
+	ctx := makeOrderedContext(makeWorkers.count(), maxAttempts, timeout, key)
+	return newLB(ctx, makeWorkers)
+}
+
+func newLB(ctx context, makeWorkers WorkerFactory) (*LB, error) {
+	m := &LB{ctx: ctx}
 	if err := m.start(makeWorkers); err != nil {
 		return nil, err
 	}
@@ -100,6 +122,7 @@ func (m *LB) start(makeWorkers WorkerFactory) error {
 	var waitStart sync.WaitGroup
 	run := func(w worker) {
 		defer m.wg.Done()
+		defer crash.Recover()
 		waitStart.Done()
 		w.run()
 	}
@@ -0,0 +1,67 @@
+package lb
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+)
+
+// partition splits msg into one message per worker the events are assigned
+// to by their ordering key. Events without ordering key are collected into one
+// message that can be published by any worker. The order of the events in each
+// message is kept.
+func (ctx *context) partition(msg eventsMessage) []eventsMessage {
+	if len(msg.events) == 0 {
+		if worker, ok := ctx.workerFor(msg.event); ok {
+			msg.worker = worker
+			msg.ordered = true
+		}
+		return []eventsMessage{msg}
+	}
+
+	unordered := -1
+	index := map[int]int{} // worker -> index of message in parts
+	var parts []eventsMessage
+	for _, event := range msg.events {
+		worker, ordered := ctx.workerFor(event)
+		if !ordered {
+			worker = unordered
+		}
+
+		i, exists := index[worker]
+		if !exists {
+			i = len(parts)
+			index[worker] = i
+
+			part := msg
+			part.events = nil
+			part.worker = worker
+			part.ordered = ordered
+			parts = append(parts, part)
+		}
+		parts[i].events = append(parts[i].events, event)
+	}
+
+	if len(parts) > 1 {
+		signaler := op.SplitSignaler(msg.signaler, len(parts))
+		for i := range parts {
+			parts[i].signaler = signaler
+		}
+	}
+	return parts
+}
+
+// workerFor returns the worker publishing events with the ordering key of
+// event. If the event has no ordering key, false is returned.
+func (ctx *context) workerFor(event common.MapStr) (int, bool) {
+	value, err := event.GetValue(ctx.key)
+	if err != nil || value == nil {
+		return 0, false
+	}
+
+	h := fnv.New32a()
+	fmt.Fprint(h, value)
+	return int(h.Sum32() % uint32(len(ctx.queues))), true
+}
@@ -0,0 +1,182 @@
+// +build !integration
+
+package lb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"github.com/elastic/beats/libbeat/outputs/mode/modetest"
+	"github.com/stretchr/testify/assert"
+)
+
+type orderedCollector struct {
+	sync.Mutex
+	published map[string][]int // sequence numbers published per key
+	workers   map[string]map[int]bool
+}
+
+// publish returns a publish callback for the worker with the given id that
+// fails every other call.
+func (c *orderedCollector) publish(id int) func([]common.MapStr) ([]common.MapStr, error) {
+	calls := 0
+	return func(events []common.MapStr) ([]common.MapStr, error) {
+		c.Lock()
+		defer c.Unlock()
+
+		calls++
+		if calls%2 == 1 {
+			return events, errors.New("fail")
+		}
+
+		for _, event := range events {
+			key, _ := event["key"].(string)
+			c.published[key] = append(c.published[key], event["seq"].(int))
+			if c.workers[key] == nil {
+				c.workers[key] = map[int]bool{}
+			}
+			c.workers[key][id] = true
+		}
+		return nil, nil
+	}
+}
+
+func (c *orderedCollector) asyncPublish(id int) func(func([]common.MapStr, error), []common.MapStr) error {
+	publish := c.publish(id)
+	return func(cb func([]common.MapStr, error), events []common.MapStr) error {
+		go func() {
+			rest, err := publish(events)
+			cb(rest, err)
+		}()
+		return nil
+	}
+}
+
+func testOrderedLB(t *testing.T, m mode.ConnectionMode, collector *orderedCollector) {
+	defer m.Close()
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	var wg sync.WaitGroup
+	total := 0
+	for batch := 0; batch < 20; batch++ {
+		var events []common.MapStr
+		for _, key := range keys {
+			events = append(events, common.MapStr{"key": key, "seq": batch})
+		}
+		events = append(events, common.MapStr{"seq": batch})
+		total += len(events)
+
+		wg.Add(1)
+		signal := op.SignalCallback(func(r op.SignalResponse) {
+			assert.Equal(t, op.SignalCompleted, r)
+			wg.Done()
+		})
+		m.PublishEvents(signal, testGuaranteed, events)
+	}
+	wg.Wait()
+
+	collector.Lock()
+	defer collector.Unlock()
+
+	count := 0
+	for key, seqs := range collector.published {
+		count += len(seqs)
+		if key == "" {
+			continue
+		}
+
+		assert.Len(t, collector.workers[key], 1, "key %v", key)
+		for i, seq := range seqs {
+			assert.Equal(t, i, seq, "key %v", key)
+		}
+	}
+	assert.Equal(t, total, count)
+}
+
+func newOrderedCollector() *orderedCollector {
+	return &orderedCollector{
+		published: map[string][]int{},
+		workers:   map[string]map[int]bool{},
+	}
+}
+
+func TestOrderedLBSync(t *testing.T) {
+	collector := newOrderedCollector()
+	var clients []mode.ProtocolClient
+	for i := 0; i < 3; i++ {
+		clients = append(clients, modetest.NewMockClient(&modetest.MockClient{
+			Connected: true,
+			CBPublish: collector.publish(i),
+		}))
+	}
+
+	m, err := NewOrdered(
+		SyncClients(clients, 1*time.Millisecond, 1*time.Millisecond),
+		"key", 0, 10*time.Millisecond)
+	if assert.NoError(t, err) {
+		testOrderedLB(t, m, collector)
+	}
+}
+
+func TestOrderedLBAsync(t *testing.T) {
+	collector := newOrderedCollector()
+	var clients []mode.AsyncProtocolClient
+	for i := 0; i < 3; i++ {
+		clients = append(clients, modetest.NewMockClient(&modetest.MockClient{
+			Connected:      true,
+			CBAsyncPublish: collector.asyncPublish(i),
+		}))
+	}
+
+	m, err := NewOrdered(
+		AsyncClients(clients, 1*time.Millisecond, 1*time.Millisecond),
+		"key", 0, 10*time.Millisecond)
+	if assert.NoError(t, err) {
+		testOrderedLB(t, m, collector)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	ctx := makeOrderedContext(2, -1, 0, "fields.key")
+	signaled := 0
+	signaler := op.SignalCallback(func(op.SignalResponse) { signaled++ })
+
+	events := []common.MapStr{
+		{"fields": common.MapStr{"key": "a"}, "seq": 0},
+		{"seq": 1},
+		{"fields": common.MapStr{"key": "b"}, "seq": 2},
+		{"fields": common.MapStr{"key": "a"}, "seq": 3},
+		{"seq": 4},
+	}
+	parts := ctx.partition(eventsMessage{signaler: signaler, events: events})
+
+	seqs := map[int][]int{}
+	for _, part := range parts {
+		worker := -1
+		if part.ordered {
+			worker = part.worker
+		}
+		for _, event := range part.events {
+			seqs[worker] = append(seqs[worker], event["seq"].(int))
+		}
+		op.SigCompleted(part.signaler)
+	}
+
+	assert.Equal(t, []int{1, 4}, seqs[-1])
+	workerA, _ := ctx.workerFor(events[0])
+	assert.Equal(t, []int{0, 3}, seqs[workerA])
+	assert.Equal(t, 1, signaled)
+
+	part := ctx.partition(eventsMessage{event: events[1]})
+	assert.Len(t, part, 1)
+	assert.False(t, part[0].ordered)
+
+	part = ctx.partition(eventsMessage{event: events[2]})
+	assert.Len(t, part, 1)
+	assert.True(t, part[0].ordered)
+}
@@ -99,7 +99,7 @@ func (w *syncWorker) connect() bool {
 
 func (w *syncWorker) sendLoop() (done bool) {
 	for {
-		msg, ok := w.ctx.receive()
+		msg, ok := w.ctx.receiveFor(w.id)
 		if !ok {
 			return true
 		}
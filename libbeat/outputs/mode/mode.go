@@ -16,6 +16,7 @@ import (
 // Metrics that can retrieved through the expvar web interface.
 var (
 	messagesDropped = expvar.NewInt("libbeat.outputs.messages_dropped")
+	messagesRetried = expvar.NewInt("libbeat.outputs.messages_retried")
 )
 
 // ErrNoHostsConfigured indicates missing host or hosts configuration
@@ -94,3 +95,7 @@ var (
 func Dropped(i int) {
 	messagesDropped.Add(int64(i))
 }
+
+func Retried(i int) {
+	messagesRetried.Add(int64(i))
+}
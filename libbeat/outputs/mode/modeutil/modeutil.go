@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/mode/lb"
 	"github.com/elastic/beats/libbeat/outputs/mode/single"
@@ -13,9 +14,13 @@ type ClientFactory func(host string) (mode.ProtocolClient, error)
 
 type AsyncClientFactory func(string) (mode.AsyncProtocolClient, error)
 
+// NewConnectionMode creates the connection mode for the given clients. If
+// ordering is enabled and events are load balanced between multiple clients,
+// events with the same ordering key are always published by the same client.
 func NewConnectionMode(
 	clients []mode.ProtocolClient,
 	failover bool,
+	ordering outputs.OrderingConfig,
 	maxAttempts int,
 	waitRetry, timeout, maxWaitRetry time.Duration,
 ) (mode.ConnectionMode, error) {
@@ -26,21 +31,42 @@ func NewConnectionMode(
 	if len(clients) == 1 {
 		return single.New(clients[0], maxAttempts, waitRetry, timeout, maxWaitRetry)
 	}
+	if ordering.Enabled {
+		return lb.NewOrdered(lb.SyncClients(clients, waitRetry, maxWaitRetry),
+			ordering.Key, maxAttempts, timeout)
+	}
 	return lb.NewSync(clients, maxAttempts, waitRetry, timeout, maxWaitRetry)
 }
 
+// NewAsyncConnectionMode creates the connection mode for the given
+// asynchronous clients. If ordering is enabled, events with the same ordering
+// key are always published by the same client, and each client waits for the
+// result of ordered events before publishing more events.
 func NewAsyncConnectionMode(
 	clients []mode.AsyncProtocolClient,
 	failover bool,
+	ordering outputs.OrderingConfig,
 	maxAttempts int,
 	waitRetry, timeout, maxWaitRetry time.Duration,
 ) (mode.ConnectionMode, error) {
 	if failover {
 		clients = NewAsyncFailoverClient(clients)
 	}
+	if ordering.Enabled {
+		return lb.NewOrdered(lb.AsyncClients(clients, waitRetry, maxWaitRetry),
+			ordering.Key, maxAttempts, timeout)
+	}
 	return lb.NewAsync(clients, maxAttempts, waitRetry, timeout, maxWaitRetry)
 }
 
+// IsConcurrent reports if m can be called from multiple goroutines at the same
+// time. Only the load balancing modes support concurrent calls, the single
+// connection mode publishes on one connection only.
+func IsConcurrent(m mode.ConnectionMode) bool {
+	_, ok := m.(*lb.LB)
+	return ok
+}
+
 // MakeClients will create a list from of ProtocolClient instances from
 // outputer configuration host list and client factory function.
 func MakeClients(
@@ -157,6 +157,7 @@ func (s *Mode) publish(
 			debugf("max number of attempts reached")
 			break
 		}
+		mode.Retried(1)
 	}
 
 	debugf("messages dropped")
@@ -0,0 +1,185 @@
+// +build !integration
+
+package netout
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+var testMetrics = newMetrics("test")
+
+func newTestClient(t *testing.T, network, addr, framing string) *client {
+	conn, err := transport.NewClient(&transport.Config{Timeout: time.Second}, network, addr, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := codec.New(codec.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(conn, testMetrics, enc, network == "tcp", framing, time.Second)
+	if err := c.Connect(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testEvents() []common.MapStr {
+	return []common.MapStr{
+		{"message": "disk almost full"},
+		{"message": "line 1\nline 2"},
+	}
+}
+
+func listenTCP(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestPublishTCPNewline(t *testing.T) {
+	l := listenTCP(t)
+	defer l.Close()
+
+	c := newTestClient(t, "tcp", l.Addr().String(), framingNewline)
+	defer c.Close()
+
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	rest, err := c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+	assert.Nil(t, rest)
+
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(server)
+	for _, expected := range []string{
+		`{"message":"disk almost full"}` + "\n",
+		`{"message":"line 1\nline 2"}` + "\n",
+	} {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+}
+
+func TestPublishTCPLengthPrefixed(t *testing.T) {
+	l := listenTCP(t)
+	defer l.Close()
+
+	c := newTestClient(t, "tcp", l.Addr().String(), framingLengthPrefixed)
+	defer c.Close()
+
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	_, err = c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	for _, expected := range []string{
+		`{"message":"disk almost full"}`,
+		`{"message":"line 1\nline 2"}`,
+	} {
+		var size uint32
+		assert.NoError(t, binary.Read(server, binary.BigEndian, &size))
+		msg := make([]byte, size)
+		_, err := io.ReadFull(server, msg)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(msg))
+	}
+}
+
+func TestPublishUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := newTestClient(t, "udp", conn.LocalAddr().String(), framingNewline)
+	defer c.Close()
+
+	_, err = c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	for _, expected := range []string{
+		`{"message":"disk almost full"}` + "\n",
+		`{"message":"line 1\nline 2"}` + "\n",
+	} {
+		n, _, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(buf[:n]))
+	}
+}
+
+func TestPublishNotConnected(t *testing.T) {
+	conn, err := transport.NewClient(&transport.Config{Timeout: time.Second}, "tcp", "127.0.0.1:9000", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := codec.New(codec.Config{})
+	c := newClient(conn, testMetrics, enc, true, framingNewline, time.Second)
+
+	events := testEvents()
+	rest, err := c.PublishEvents(events)
+	assert.Equal(t, transport.ErrNotConnected, err)
+	assert.Equal(t, events, rest)
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		network  string
+		settings map[string]interface{}
+		ok       bool
+	}{
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost:9000"}}, true},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost"}, "port": 9000}, true},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost"}}, false},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "framing": "octets"}, false},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "backoff.init": "2m"}, false},
+		{"udp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "tls.certificate_authorities": []string{"ca.pem"}}, false},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "proxy_url": "http://proxy:3128"}, true},
+		{"tcp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "proxy_url": "ftp://proxy:21"}, false},
+		{"udp", map[string]interface{}{"hosts": []string{"localhost:9000"}, "proxy_url": "socks5://proxy:1080"}, false},
+	}
+
+	for _, test := range tests {
+		cfg, err := common.NewConfigFrom(test.settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := &netOutput{}
+		err = out.init(cfg, test.network, testMetrics)
+		if test.ok {
+			assert.NoError(t, err, "%v", test.settings)
+			out.Close()
+		} else {
+			assert.Error(t, err, "%v", test.settings)
+		}
+	}
+}
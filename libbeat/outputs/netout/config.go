@@ -0,0 +1,69 @@
+package netout
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type netConfig struct {
+	Port           int                    `config:"port" validate:"min=0"`
+	Framing        string                 `config:"framing"`
+	LoadBalance    bool                   `config:"loadbalance"`
+	Timeout        time.Duration          `config:"timeout"`
+	MaxRetries     int                    `config:"max_retries"`
+	Backoff        backoffConfig          `config:"backoff"`
+	TLS            *outputs.TLSConfig     `config:"tls"`
+	Ordering       outputs.OrderingConfig `config:"ordering"`
+	DNS            transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec int                    `config:"max_bytes_per_second" validate:"min=0"`
+	Codec          codec.Config           `config:"codec"`
+	Proxy          transport.ProxyConfig  `config:",inline"`
+}
+
+// backoffConfig sets the wait before reconnecting after a failure, doubling
+// after every failed attempt up to the maximum.
+type backoffConfig struct {
+	Init time.Duration `config:"init" validate:"nonzero,positive"`
+	Max  time.Duration `config:"max" validate:"nonzero,positive"`
+}
+
+const (
+	// framingNewline terminates every message with a newline. Newlines in
+	// the messages are replaced by spaces.
+	framingNewline = "newline"
+
+	// framingLengthPrefixed prefixes every message with its length, as 4
+	// byte unsigned integer in network byte order.
+	framingLengthPrefixed = "length_prefixed"
+)
+
+var (
+	defaultConfig = netConfig{
+		Framing:     framingNewline,
+		LoadBalance: true,
+		Timeout:     30 * time.Second,
+		MaxRetries:  3,
+		Backoff: backoffConfig{
+			Init: 1 * time.Second,
+			Max:  60 * time.Second,
+		},
+	}
+)
+
+func (c *netConfig) Validate() error {
+	switch c.Framing {
+	case framingNewline, framingLengthPrefixed:
+	default:
+		return fmt.Errorf("unsupported framing '%v', use newline or length_prefixed", c.Framing)
+	}
+
+	if c.Backoff.Max < c.Backoff.Init {
+		return errors.New("backoff.max must not be less than backoff.init")
+	}
+	return nil
+}
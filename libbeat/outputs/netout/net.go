@@ -0,0 +1,201 @@
+// Package netout implements the tcp and udp outputs, which send the encoded
+// events as newline delimited or length prefixed messages to any network
+// service, for example to feed custom collectors.
+package netout
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type netOutput struct {
+	mode mode.ConnectionMode
+
+	endpoints []preflight.Endpoint // endpoints for the preflight checks
+}
+
+// metrics that can retrieved through the expvar web interface, published
+// under the name of the output.
+type metrics struct {
+	ackedEvents            *expvar.Int
+	eventsNotAcked         *expvar.Int
+	eventsDropped          *expvar.Int
+	publishEventsCallCount *expvar.Int
+
+	stats transport.IOStats
+}
+
+var debugf = logp.MakeDebug("netout")
+
+var (
+	tcpMetrics = newMetrics("tcp")
+	udpMetrics = newMetrics("udp")
+)
+
+func init() {
+	if err := outputs.RegisterOutputPlugin("tcp", newTCP); err != nil {
+		panic(err)
+	}
+	if err := outputs.RegisterOutputPlugin("udp", newUDP); err != nil {
+		panic(err)
+	}
+}
+
+func newMetrics(name string) *metrics {
+	prefix := "libbeat." + name + "."
+	return &metrics{
+		ackedEvents:            expvar.NewInt(prefix + "published_and_acked_events"),
+		eventsNotAcked:         expvar.NewInt(prefix + "published_but_not_acked_events"),
+		eventsDropped:          expvar.NewInt(prefix + "dropped_events"),
+		publishEventsCallCount: expvar.NewInt(prefix + "call_count.PublishEvents"),
+		stats: transport.IOStats{
+			Read:        expvar.NewInt(prefix + "publish.read_bytes"),
+			Write:       expvar.NewInt(prefix + "publish.write_bytes"),
+			ReadErrors:  expvar.NewInt(prefix + "publish.read_errors"),
+			WriteErrors: expvar.NewInt(prefix + "publish.write_errors"),
+		},
+	}
+}
+
+// newTCP instantiates a new output plugin instance sending events over tcp,
+// optionally secured by TLS.
+func newTCP(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	out := &netOutput{}
+	if err := out.init(cfg, "tcp", tcpMetrics); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// newUDP instantiates a new output plugin instance sending every event in a
+// udp datagram.
+func newUDP(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	out := &netOutput{}
+	if err := out.init(cfg, "udp", udpMetrics); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (out *netOutput) init(cfg *common.Config, network string, metrics *metrics) error {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return err
+	}
+
+	stream := network == "tcp"
+	if !stream && config.TLS != nil {
+		return fmt.Errorf("tls is not supported by the %v output", network)
+	}
+	if !stream && config.Proxy.URL != "" {
+		return fmt.Errorf("proxy_url is not supported by the %v output", network)
+	}
+
+	enc, err := codec.New(config.Codec)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return err
+	}
+
+	transp := &transport.Config{
+		Timeout:  config.Timeout,
+		TLS:      tlsConfig,
+		Resolver: transport.NewResolver(config.DNS),
+		Throttle: transport.NewThrottle(config.MaxBytesPerSec),
+		Stats:    &metrics.stats,
+	}
+	if stream {
+		transp.Proxy = &config.Proxy
+	}
+
+	hosts, err := modeutil.ReadHostList(cfg)
+	if err != nil {
+		return err
+	}
+	if config.Port == 0 {
+		for _, host := range hosts {
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				return fmt.Errorf("no port configured for host %v", host)
+			}
+		}
+	}
+
+	clients, err := modeutil.MakeClients(cfg, func(host string) (mode.ProtocolClient, error) {
+		conn, err := transport.NewClient(transp, network, host, config.Port)
+		if err != nil {
+			return nil, err
+		}
+		return newClient(conn, metrics, enc, stream, config.Framing, config.Timeout), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxRetries := config.MaxRetries
+	maxAttempts := maxRetries + 1 // maximum number of send attempts (-1 = infinite)
+	if maxRetries < 0 {
+		maxAttempts = 0
+	}
+
+	// The connection mode reconnects with exponential backoff after
+	// connection and write errors.
+	m, err := modeutil.NewConnectionMode(clients, !config.LoadBalance, config.Ordering,
+		maxAttempts, config.Backoff.Init, config.Timeout, config.Backoff.Max)
+	if err != nil {
+		return err
+	}
+
+	out.mode = m
+	if stream {
+		// udp has no connection to check
+		out.endpoints = transport.PreflightEndpoints(transp, hosts, config.Port)
+	}
+	return nil
+}
+
+func (out *netOutput) Close() error {
+	return out.mode.Close()
+}
+
+// PreflightEndpoints returns the tcp hosts for the preflight checks. Hosts
+// receiving events over udp are not checked.
+func (out *netOutput) PreflightEndpoints() []preflight.Endpoint {
+	return out.endpoints
+}
+
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (out *netOutput) Concurrent() bool {
+	return modeutil.IsConcurrent(out.mode)
+}
+
+func (out *netOutput) PublishEvent(
+	signaler op.Signaler,
+	opts outputs.Options,
+	event common.MapStr,
+) error {
+	return out.mode.PublishEvent(signaler, opts, event)
+}
+
+func (out *netOutput) BulkPublish(
+	signaler op.Signaler,
+	opts outputs.Options,
+	events []common.MapStr,
+) error {
+	return out.mode.PublishEvents(signaler, opts, events)
+}
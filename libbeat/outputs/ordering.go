@@ -0,0 +1,23 @@
+package outputs
+
+import "errors"
+
+// ErrOrderingKeyMissing indicates ordering being enabled without a key.
+var ErrOrderingKeyMissing = errors.New("ordering.key is required if ordering is enabled")
+
+// OrderingConfig defines config file options for publishing events with the
+// same key in order.
+type OrderingConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Key is the event field whose value is used as ordering key. Events
+	// without the field are not ordered.
+	Key string `config:"key"`
+}
+
+func (c *OrderingConfig) Validate() error {
+	if c.Enabled && c.Key == "" {
+		return ErrOrderingKeyMissing
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package outputs
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
@@ -32,6 +36,21 @@ type BulkOutputer interface {
 	BulkPublish(sig op.Signaler, opts Options, event []common.MapStr) error
 }
 
+// ConcurrentOutputer is implemented by outputs that can be called by multiple
+// publisher workers at the same time.
+type ConcurrentOutputer interface {
+	// Concurrent reports if PublishEvent and BulkPublish can be called
+	// concurrently.
+	Concurrent() bool
+}
+
+// IsConcurrent reports if out can be called by multiple publisher workers at
+// the same time.
+func IsConcurrent(out Outputer) bool {
+	c, ok := out.(ConcurrentOutputer)
+	return ok && c.Concurrent()
+}
+
 // Create and initialize the output plugin
 type OutputBuilder func(config *common.Config, topologyExpire int) (Outputer, error)
 
@@ -53,39 +72,85 @@ type bulkOutputAdapter struct {
 
 var outputsPlugins = make(map[string]OutputBuilder)
 
-func RegisterOutputPlugin(name string, builder OutputBuilder) {
+// RegisterOutputPlugin registers the builder of the output configured in the
+// output.<name> section. Outputs register themselves in the init function of
+// their package, such that outputs maintained outside of libbeat are enabled
+// by importing their package in the main package of the Beat. An error is
+// returned if the name is empty, builder is nil, or if an output has already
+// been registered under the name.
+func RegisterOutputPlugin(name string, builder OutputBuilder) error {
+	if name == "" {
+		return fmt.Errorf("output name is required")
+	}
+	if _, exists := outputsPlugins[name]; exists {
+		return fmt.Errorf("output '%s' is already registered", name)
+	}
+	if builder == nil {
+		return fmt.Errorf("output '%s' cannot be registered with a nil builder", name)
+	}
+
 	outputsPlugins[name] = builder
+	logp.Debug("outputs", "Output registered: %s", name)
+	return nil
 }
 
 func FindOutputPlugin(name string) OutputBuilder {
 	return outputsPlugins[name]
 }
 
+// RegisteredOutputPlugins returns the sorted names of the registered outputs.
+func RegisteredOutputPlugins() []string {
+	names := make([]string, 0, len(outputsPlugins))
+	for name := range outputsPlugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InitOutputs creates the enabled outputs of configs, in the order of their
+// names. An error is returned if an output is not registered.
 func InitOutputs(
 	beatName string,
 	configs map[string]*common.Config,
 	topologyExpire int,
 ) ([]OutputPlugin, error) {
-	var plugins []OutputPlugin = nil
-	for name, plugin := range outputsPlugins {
-		config, exists := configs[name]
-		if !exists {
-			continue
-		}
+	var names []string
+	for name, config := range configs {
 		if !config.Enabled() {
 			continue
 		}
+		if FindOutputPlugin(name) == nil {
+			return nil, fmt.Errorf("output '%s' is not registered, known outputs: %s",
+				name, strings.Join(RegisteredOutputPlugins(), ", "))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plugins []OutputPlugin = nil
+	for _, name := range names {
+		config := configs[name]
+		builder := FindOutputPlugin(name)
 
 		if !config.HasField("index") {
 			config.SetString("index", -1, beatName)
 		}
 
-		output, err := plugin(config, topologyExpire)
+		preflightConfig, err := readPreflightConfig(config)
+		if err != nil {
+			logp.Err("invalid preflight settings of %s output: %s", name, err)
+			return nil, err
+		}
+
+		output, err := builder(config, topologyExpire)
 		if err != nil {
 			logp.Err("failed to initialize %s plugin as output: %s", name, err)
 			return nil, err
 		}
 
+		runPreflight(name, preflightConfig, output)
+
 		plugin := OutputPlugin{Name: name, Config: config, Output: output}
 		plugins = append(plugins, plugin)
 		logp.Info("Activated %s as output plugin.", name)
@@ -0,0 +1,66 @@
+// +build !integration
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOutput struct {
+	name string
+}
+
+func (t *testOutput) PublishEvent(op.Signaler, Options, common.MapStr) error { return nil }
+func (t *testOutput) Close() error                                          { return nil }
+
+func testOutputBuilder(name string) OutputBuilder {
+	return func(*common.Config, int) (Outputer, error) {
+		return &testOutput{name: name}, nil
+	}
+}
+
+func TestRegisterOutputPlugin(t *testing.T) {
+	defer delete(outputsPlugins, "test-register")
+
+	assert.NoError(t, RegisterOutputPlugin("test-register", testOutputBuilder("a")))
+	assert.NotNil(t, FindOutputPlugin("test-register"))
+	assert.Contains(t, RegisteredOutputPlugins(), "test-register")
+
+	assert.Error(t, RegisterOutputPlugin("test-register", testOutputBuilder("b")))
+	assert.Error(t, RegisterOutputPlugin("", testOutputBuilder("c")))
+	assert.Error(t, RegisterOutputPlugin("test-nil", nil))
+}
+
+func TestInitOutputs(t *testing.T) {
+	defer delete(outputsPlugins, "test-b")
+	defer delete(outputsPlugins, "test-a")
+	RegisterOutputPlugin("test-a", testOutputBuilder("a"))
+	RegisterOutputPlugin("test-b", testOutputBuilder("b"))
+
+	disabled, _ := common.NewConfigFrom(map[string]interface{}{"enable": false})
+	plugins, err := InitOutputs("testbeat", map[string]*common.Config{
+		"test-b":   common.NewConfig(),
+		"test-a":   common.NewConfig(),
+		"test-off": disabled,
+	}, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, plugins, 2) {
+		assert.Equal(t, "test-a", plugins[0].Name)
+		assert.Equal(t, "test-b", plugins[1].Name)
+		index, _ := plugins[0].Config.String("index", -1)
+		assert.Equal(t, "testbeat", index)
+	}
+}
+
+func TestInitOutputsNotRegistered(t *testing.T) {
+	_, err := InitOutputs("testbeat", map[string]*common.Config{
+		"test-unknown": common.NewConfig(),
+	}, 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "output 'test-unknown' is not registered")
+	}
+}
@@ -0,0 +1,68 @@
+package outputs
+
+import (
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+)
+
+// Preflighter is implemented by outputs that can check their endpoints on
+// startup.
+type Preflighter interface {
+	// PreflightEndpoints returns the endpoints the output connects to.
+	PreflightEndpoints() []preflight.Endpoint
+}
+
+type preflightConfig struct {
+	Enabled bool          `config:"enabled"`
+	Timeout time.Duration `config:"timeout" validate:"min=0"`
+}
+
+var defaultPreflightConfig = preflightConfig{
+	Enabled: true,
+	Timeout: 5 * time.Second,
+}
+
+func readPreflightConfig(cfg *common.Config) (preflightConfig, error) {
+	config := defaultPreflightConfig
+	if !cfg.HasField("preflight") {
+		return config, nil
+	}
+
+	sub, err := cfg.Child("preflight", -1)
+	if err != nil {
+		return config, err
+	}
+	err = sub.Unpack(&config)
+	return config, err
+}
+
+// runPreflight checks the endpoints of the output and logs the result of all
+// endpoints as a single message. Failed checks do not stop the Beat, as the
+// endpoints might become available later.
+func runPreflight(name string, config preflightConfig, out Outputer) {
+	p, ok := out.(Preflighter)
+	if !ok || !config.Enabled {
+		return
+	}
+
+	results := preflight.CheckAll(p.PreflightEndpoints(), config.Timeout)
+	if len(results) == 0 {
+		return
+	}
+
+	msgs := make([]string, len(results))
+	for i, r := range results {
+		msgs[i] = r.String()
+	}
+	if failed := preflight.Failed(results); len(failed) > 0 {
+		logp.Err("Preflight check of output %s failed for %d of %d endpoints: %s",
+			name, len(failed), len(results), strings.Join(msgs, "; "))
+	} else {
+		logp.Info("Preflight check of output %s passed: %s",
+			name, strings.Join(msgs, "; "))
+	}
+}
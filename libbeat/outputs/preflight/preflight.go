@@ -0,0 +1,273 @@
+// Package preflight checks the endpoints of the outputs on startup, such that
+// name resolution, connection, certificate and authentication problems are
+// reported with a specific error instead of failing publish attempts.
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Step is the check an endpoint failed.
+type Step string
+
+// Checks run on every endpoint, in this order.
+const (
+	StepDNS  Step = "dns"
+	StepTCP  Step = "tcp"
+	StepTLS  Step = "tls"
+	StepAuth Step = "auth"
+)
+
+// Endpoint is a host an output connects to.
+type Endpoint struct {
+	// Name of the endpoint in the diagnosis, for example the URL.
+	Name string
+
+	// Address to connect to, as host:port.
+	Address string
+
+	// TLS configures the TLS handshake, nil if the connection is not
+	// encrypted.
+	TLS *tls.Config
+
+	// LookupHost resolves the host of the address like the output. The system
+	// resolver is used if nil.
+	LookupHost func(host string) ([]string, error)
+
+	// Probe checks if the output is authorized to publish events. It is
+	// optional and only called if all other checks passed.
+	Probe func(timeout time.Duration) error
+}
+
+// Result is the result of the checks of an endpoint. Err is nil if all checks
+// passed, Step is the failed check otherwise.
+type Result struct {
+	Endpoint string
+	Step     Step
+	Err      error
+}
+
+func (r Result) String() string {
+	if r.Err == nil {
+		return fmt.Sprintf("%s: ok", r.Endpoint)
+	}
+	return fmt.Sprintf("%s: %s failed: %v", r.Endpoint, r.Step, r.Err)
+}
+
+// Failed returns the failed results.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// CheckAll checks all endpoints concurrently. The results are in the order of
+// the endpoints.
+func CheckAll(endpoints []Endpoint, timeout time.Duration) []Result {
+	results := make([]Result, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i := range endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = Check(endpoints[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// Check resolves the host of the endpoint, connects to it, checks the
+// certificate of the server if TLS is enabled and probes the authentication.
+// Each step is limited by timeout.
+func Check(e Endpoint, timeout time.Duration) Result {
+	result := Result{Endpoint: e.Name}
+	fail := func(step Step, err error) Result {
+		result.Step, result.Err = step, err
+		return result
+	}
+
+	host, _, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		return fail(StepDNS, err)
+	}
+
+	address := e.Address
+	if net.ParseIP(host) == nil {
+		lookup := e.LookupHost
+		if lookup == nil {
+			lookup = net.LookupHost
+		}
+
+		addrs, err := resolve(host, lookup, timeout)
+		if err != nil {
+			return fail(StepDNS, err)
+		}
+		if e.LookupHost != nil {
+			_, port, _ := net.SplitHostPort(e.Address)
+			address = net.JoinHostPort(addrs[0], port)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return fail(StepTCP, describeDialError(err, e.Address, timeout))
+	}
+	defer conn.Close()
+
+	if e.TLS != nil {
+		if err := checkTLS(conn, e.TLS, host, timeout); err != nil {
+			return fail(StepTLS, err)
+		}
+	}
+
+	if e.Probe != nil {
+		if err := e.Probe(timeout); err != nil {
+			return fail(StepAuth, err)
+		}
+	}
+	return result
+}
+
+func resolve(
+	host string,
+	lookupHost func(string) ([]string, error),
+	timeout time.Duration,
+) ([]string, error) {
+	type lookup struct {
+		addrs []string
+		err   error
+	}
+
+	ch := make(chan lookup, 1)
+	go func() {
+		addrs, err := lookupHost(host)
+		ch <- lookup{addrs, err}
+	}()
+
+	select {
+	case l := <-ch:
+		if l.err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %v", host, l.err)
+		}
+		if len(l.addrs) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+		return l.addrs, nil
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("resolving %s timed out after %v", host, timeout)
+	}
+}
+
+func describeDialError(err error, address string, timeout time.Duration) error {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return fmt.Errorf("connecting to %s timed out after %v, "+
+			"check that no firewall blocks the connection", address, timeout)
+	}
+	if strings.Contains(err.Error(), "connection refused") {
+		return fmt.Errorf("connection to %s refused, "+
+			"check that the service is running and listening on this port", address)
+	}
+	return err
+}
+
+// checkTLS runs the TLS handshake on conn and verifies the certificate of the
+// server. The certificate is verified after the handshake, such that expired
+// certificates, hostname mismatches and unknown authorities can be reported
+// with specific errors.
+func checkTLS(conn net.Conn, config *tls.Config, host string, timeout time.Duration) error {
+	verify := !config.InsecureSkipVerify
+	serverName := config.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+
+	config = config.Clone()
+	config.InsecureSkipVerify = true
+	config.ServerName = serverName
+
+	tlsConn := tls.Client(conn, config)
+	tlsConn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake failed: %v", err)
+	}
+
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if !verify || len(certs) == 0 {
+		return nil
+	}
+	return verifyCertificate(certs, config.RootCAs, serverName, time.Now())
+}
+
+func verifyCertificate(
+	certs []*x509.Certificate,
+	roots *x509.CertPool,
+	serverName string,
+	now time.Time,
+) error {
+	leaf := certs[0]
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate of %s expired at %v",
+			serverName, leaf.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate of %s is not valid before %v, "+
+			"check the clock of this host", serverName, leaf.NotBefore.Format(time.RFC3339))
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case x509.HostnameError:
+		return fmt.Errorf("certificate is not valid for %s, it is valid for %s",
+			serverName, strings.Join(certificateNames(leaf), ", "))
+	case x509.UnknownAuthorityError:
+		return fmt.Errorf("certificate of %s is signed by the unknown authority %q, "+
+			"add the CA certificate to tls.certificate_authorities",
+			serverName, issuer(leaf))
+	case x509.CertificateInvalidError:
+		return fmt.Errorf("certificate of %s is invalid: %v", serverName, err)
+	default:
+		return err
+	}
+}
+
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+func issuer(cert *x509.Certificate) string {
+	if cn := cert.Issuer.CommonName; cn != "" {
+		return cn
+	}
+	return cert.Issuer.String()
+}
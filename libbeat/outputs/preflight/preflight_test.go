@@ -0,0 +1,164 @@
+// +build !integration
+
+package preflight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTimeout = 5 * time.Second
+
+func newTLSServer() (*httptest.Server, *x509.CertPool) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	return srv, roots
+}
+
+func TestCheckDNS(t *testing.T) {
+	r := Check(Endpoint{Name: "test", Address: "unknown.invalid:9200"}, testTimeout)
+	assert.Equal(t, StepDNS, r.Step)
+	assert.Error(t, r.Err)
+}
+
+// Verify that the address is resolved with the lookup function of the
+// endpoint, if set.
+func TestCheckLookupHost(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	r := Check(Endpoint{
+		Name:    "test",
+		Address: net.JoinHostPort("logstash.invalid", port),
+		LookupHost: func(host string) ([]string, error) {
+			return []string{"127.0.0.1"}, nil
+		},
+	}, testTimeout)
+	assert.NoError(t, r.Err)
+}
+
+func TestCheckTCPRefused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	r := Check(Endpoint{Name: "test", Address: address}, testTimeout)
+	assert.Equal(t, StepTCP, r.Step)
+	if assert.Error(t, r.Err) {
+		assert.Contains(t, r.Err.Error(), "refused")
+	}
+}
+
+func TestCheckTLS(t *testing.T) {
+	srv, roots := newTLSServer()
+	defer srv.Close()
+	address := srv.Listener.Addr().String()
+
+	r := Check(Endpoint{
+		Name:    "test",
+		Address: address,
+		TLS:     &tls.Config{RootCAs: roots},
+	}, testTimeout)
+	assert.NoError(t, r.Err)
+	assert.Equal(t, "test: ok", r.String())
+}
+
+func TestCheckTLSUnknownAuthority(t *testing.T) {
+	srv, _ := newTLSServer()
+	defer srv.Close()
+
+	r := Check(Endpoint{
+		Name:    "test",
+		Address: srv.Listener.Addr().String(),
+		TLS:     &tls.Config{RootCAs: x509.NewCertPool()},
+	}, testTimeout)
+	assert.Equal(t, StepTLS, r.Step)
+	if assert.Error(t, r.Err) {
+		assert.Contains(t, r.Err.Error(), "unknown authority")
+		assert.Contains(t, r.Err.Error(), "tls.certificate_authorities")
+	}
+
+	// verification is skipped if insecure is set
+	r = Check(Endpoint{
+		Name:    "test",
+		Address: srv.Listener.Addr().String(),
+		TLS:     &tls.Config{InsecureSkipVerify: true},
+	}, testTimeout)
+	assert.NoError(t, r.Err)
+}
+
+func TestCheckTLSHostnameMismatch(t *testing.T) {
+	srv, roots := newTLSServer()
+	defer srv.Close()
+
+	r := Check(Endpoint{
+		Name:    "test",
+		Address: srv.Listener.Addr().String(),
+		TLS:     &tls.Config{RootCAs: roots, ServerName: "elasticsearch.local"},
+	}, testTimeout)
+	assert.Equal(t, StepTLS, r.Step)
+	if assert.Error(t, r.Err) {
+		assert.Contains(t, r.Err.Error(), "not valid for elasticsearch.local")
+		assert.Contains(t, r.Err.Error(), "example.com")
+	}
+}
+
+func TestVerifyCertificateExpired(t *testing.T) {
+	srv, roots := newTLSServer()
+	defer srv.Close()
+
+	cert := srv.Certificate()
+	err := verifyCertificate([]*x509.Certificate{cert}, roots, "example.com",
+		cert.NotAfter.Add(time.Hour))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "expired")
+	}
+}
+
+func TestCheckProbe(t *testing.T) {
+	srv, roots := newTLSServer()
+	defer srv.Close()
+
+	r := Check(Endpoint{
+		Name:    "test",
+		Address: srv.Listener.Addr().String(),
+		TLS:     &tls.Config{RootCAs: roots},
+		Probe: func(time.Duration) error {
+			return errors.New("authentication failed")
+		},
+	}, testTimeout)
+	assert.Equal(t, StepAuth, r.Step)
+	assert.Equal(t, "test: auth failed: authentication failed", r.String())
+}
+
+func TestCheckAll(t *testing.T) {
+	srv, roots := newTLSServer()
+	defer srv.Close()
+
+	results := CheckAll([]Endpoint{
+		{Name: "ok", Address: srv.Listener.Addr().String(), TLS: &tls.Config{RootCAs: roots}},
+		{Name: "failed", Address: "unknown.invalid:9200"},
+	}, testTimeout)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "ok", results[0].Endpoint)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, "failed", results[1].Endpoint)
+	}
+	assert.Len(t, Failed(results), 1)
+}
@@ -1,7 +1,6 @@
 package redis
 
 import (
-	"encoding/json"
 	"errors"
 	"regexp"
 	"strconv"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
@@ -26,7 +26,18 @@ type client struct {
 	db       int
 	list     []byte
 	password string
-	publish  publishFn
+
+	// keys selects the destination of every event, if key rules are set
+	keys *keySelector
+
+	codec codec.Codec
+
+	conn       redis.Conn
+	selectedDb int
+	publishers map[redisDataType]publishFn
+
+	// check verifies the node after connecting, if set
+	check func(redis.Conn) error
 }
 
 type redisDataType uint16
@@ -43,6 +54,7 @@ func newClient(tc *transport.Client, pass string, db int, dest []byte, dt redisD
 		db:       db,
 		dataType: dt,
 		list:     dest,
+		codec:    codec.NewJSON(false),
 	}
 }
 
@@ -60,12 +72,38 @@ func (c *client) Connect(to time.Duration) error {
 		}
 	}()
 
-	if err = initRedisConn(conn, c.password, c.db); err == nil {
-		c.publish, err = makePublish(conn, c.dataType)
+	err = initRedisConn(conn, c.password, c.db)
+	if err == nil && c.check != nil {
+		err = c.check(conn)
+	}
+	if err == nil {
+		c.publishers, err = c.makePublishers(conn)
+	}
+	if err == nil {
+		c.conn = conn
+		c.selectedDb = c.db
 	}
 	return err
 }
 
+// makePublishers creates the publish functions of the data types events are
+// published with.
+func (c *client) makePublishers(conn redis.Conn) (map[redisDataType]publishFn, error) {
+	publishers := map[redisDataType]publishFn{}
+	for _, dt := range []redisDataType{redisListType, redisChannelType} {
+		if dt != c.dataType && (c.keys == nil || !c.keys.usesDataType(dt)) {
+			continue
+		}
+
+		publish, err := makePublish(conn, dt, c.codec)
+		if err != nil {
+			return nil, err
+		}
+		publishers[dt] = publish
+	}
+	return publishers, nil
+}
+
 func initRedisConn(c redis.Conn, pwd string, db int) error {
 	if pwd != "" {
 		if _, err := c.Do("AUTH", pwd); err != nil {
@@ -97,17 +135,53 @@ func (c *client) PublishEvent(event common.MapStr) error {
 }
 
 func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
-	return c.publish(c.list, events)
+	if c.keys == nil {
+		return c.publishers[c.dataType](c.list, events)
+	}
+
+	var failed []common.MapStr
+	var lastErr error
+	batches := c.keys.split(events)
+	for i, batch := range batches {
+		rest, err := c.publishTo(batch.dest, batch.events)
+		if err == nil {
+			continue
+		}
+
+		failed = append(failed, rest...)
+		lastErr = err
+		if _, ok := err.(redis.Error); !ok {
+			// connection failed -> retry the events of all remaining batches
+			for _, batch := range batches[i+1:] {
+				failed = append(failed, batch.events...)
+			}
+			break
+		}
+	}
+	return failed, lastErr
+}
+
+// publishTo publishes the events to the destination, selecting the database
+// of the destination first.
+func (c *client) publishTo(dest destination, events []common.MapStr) ([]common.MapStr, error) {
+	if dest.db != c.selectedDb {
+		if _, err := c.conn.Do("SELECT", dest.db); err != nil {
+			logp.Err("Failed to select redis db %v: %v", dest.db, err)
+			return events, err
+		}
+		c.selectedDb = dest.db
+	}
+	return c.publishers[dest.dataType]([]byte(dest.key), events)
 }
 
-func makePublish(conn redis.Conn, dt redisDataType) (publishFn, error) {
+func makePublish(conn redis.Conn, dt redisDataType, enc codec.Codec) (publishFn, error) {
 	if dt == redisChannelType {
-		return makePublishPUBLISH(conn)
+		return makePublishPUBLISH(conn, enc)
 	}
-	return makePublishRPUSH(conn)
+	return makePublishRPUSH(conn, enc)
 }
 
-func makePublishRPUSH(conn redis.Conn) (publishFn, error) {
+func makePublishRPUSH(conn redis.Conn, enc codec.Codec) (publishFn, error) {
 	var major, minor int
 	var versionRaw [][]byte
 
@@ -144,21 +218,21 @@ func makePublishRPUSH(conn redis.Conn) (publishFn, error) {
 	// See: http://redis.io/commands/rpush
 	multiValue := major > 2 || (major == 2 && minor >= 4)
 	if multiValue {
-		return publishEventsBulk(conn, "RPUSH"), nil
+		return publishEventsBulk(conn, "RPUSH", enc), nil
 	}
-	return publishEventsPipeline(conn, "RPUSH"), nil
+	return publishEventsPipeline(conn, "RPUSH", enc), nil
 }
 
-func makePublishPUBLISH(conn redis.Conn) (publishFn, error) {
-	return publishEventsPipeline(conn, "PUBLISH"), nil
+func makePublishPUBLISH(conn redis.Conn, enc codec.Codec) (publishFn, error) {
+	return publishEventsPipeline(conn, "PUBLISH", enc), nil
 }
 
-func publishEventsBulk(conn redis.Conn, command string) publishFn {
+func publishEventsBulk(conn redis.Conn, command string, enc codec.Codec) publishFn {
 	return func(dest []byte, events []common.MapStr) ([]common.MapStr, error) {
 		args := make([]interface{}, 1, len(events)+1)
 		args[0] = dest
 
-		events, args = serializeEvents(args, 1, events)
+		events, args = serializeEvents(enc, args, 1, events)
 		if (len(args) - 1) == 0 {
 			return nil, nil
 		}
@@ -174,13 +248,13 @@ func publishEventsBulk(conn redis.Conn, command string) publishFn {
 	}
 }
 
-func publishEventsPipeline(conn redis.Conn, command string) publishFn {
+func publishEventsPipeline(conn redis.Conn, command string, enc codec.Codec) publishFn {
 	return func(dest []byte, events []common.MapStr) ([]common.MapStr, error) {
 		var args [2]interface{}
 		args[0] = dest
 
 		serialized := make([]interface{}, 0, len(events))
-		events, serialized = serializeEvents(serialized, 0, events)
+		events, serialized = serializeEvents(enc, serialized, 0, events)
 		if len(serialized) == 0 {
 			return nil, nil
 		}
@@ -221,18 +295,19 @@ func publishEventsPipeline(conn redis.Conn, command string) publishFn {
 }
 
 func serializeEvents(
+	enc codec.Codec,
 	to []interface{},
 	i int,
 	events []common.MapStr,
 ) ([]common.MapStr, []interface{}) {
 	okEvents := events
 	for _, event := range events {
-		jsonEvent, err := json.Marshal(event)
+		encoded, err := enc.Encode(event)
 		if err != nil {
-			logp.Err("Failed to convert the event to JSON (%v): %#v", err, event)
+			logp.Err("Failed to encode the event (%v): %#v", err, event)
 			goto failLoop
 		}
-		to = append(to, jsonEvent)
+		to = append(to, encoded)
 		i++
 	}
 	return okEvents, to
@@ -241,13 +316,13 @@ failLoop:
 	okEvents = events[:i]
 	restEvents := events[i+1:]
 	for _, event := range restEvents {
-		jsonEvent, err := json.Marshal(event)
+		encoded, err := enc.Encode(event)
 		if err != nil {
-			logp.Err("Failed to convert the event to JSON (%v): %#v", err, event)
+			logp.Err("Failed to encode the event (%v): %#v", err, event)
 			i++
 			continue
 		}
-		to = append(to, jsonEvent)
+		to = append(to, encoded)
 		i++
 	}
 
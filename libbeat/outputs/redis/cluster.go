@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// numClusterSlots is the number of hash slots of a Redis Cluster.
+const numClusterSlots = 16384
+
+type clusterConfig struct {
+	Enabled bool `config:"enabled"`
+}
+
+// clusterLocator locates the master of the cluster node owning the hash slot
+// of the key events are published to. The slot owner is read from the seed
+// nodes with CLUSTER SLOTS. If the node redirects the key to another node with
+// a MOVED reply, that node is used on reconnect.
+type clusterLocator struct {
+	transp   *transport.Config
+	seeds    []string
+	port     int
+	password string
+	slot     int
+
+	mutex      sync.Mutex
+	redirectTo string
+}
+
+func newClusterLocator(
+	transp *transport.Config,
+	seeds []string,
+	port int,
+	password string,
+	key []byte,
+) *clusterLocator {
+	return &clusterLocator{
+		transp:   transp,
+		seeds:    seeds,
+		port:     port,
+		password: password,
+		slot:     clusterSlot(key),
+	}
+}
+
+func (c *clusterLocator) locate(timeout time.Duration) (string, error) {
+	c.mutex.Lock()
+	addr := c.redirectTo
+	c.redirectTo = ""
+	c.mutex.Unlock()
+	if addr != "" {
+		return addr, nil
+	}
+
+	for _, seed := range c.seeds {
+		addr, err := c.askNode(seed, timeout)
+		if err != nil {
+			logp.Warn("Failed to get the owner of hash slot %v from redis node %v: %v",
+				c.slot, seed, err)
+			continue
+		}
+		return addr, nil
+	}
+	return "", fmt.Errorf("no redis cluster node returned the owner of hash slot %v", c.slot)
+}
+
+func (c *clusterLocator) askNode(host string, timeout time.Duration) (string, error) {
+	conn, err := dialNode(c.transp, host, c.port, c.password, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return "", err
+	}
+	return slotOwner(reply, c.slot)
+}
+
+func (c *clusterLocator) check(conn redis.Conn) error {
+	return nil
+}
+
+// failed reports if the slot has been moved to another node, or the cluster
+// is reconfigured. The slot is only migrated on ASK replies, such that the
+// events are retried on the same node.
+func (c *clusterLocator) failed(err error) bool {
+	redisErr, ok := err.(redis.Error)
+	if !ok {
+		return false
+	}
+
+	msg := string(redisErr)
+	switch {
+	case strings.HasPrefix(msg, "MOVED "):
+		// MOVED <slot> <host>:<port>
+		fields := strings.Fields(msg)
+		if len(fields) == 3 {
+			c.mutex.Lock()
+			c.redirectTo = fields[2]
+			c.mutex.Unlock()
+		}
+		return true
+	case strings.HasPrefix(msg, "CLUSTERDOWN"), strings.HasPrefix(msg, "READONLY"):
+		return true
+	}
+	return false
+}
+
+// slotOwner returns the address of the master serving the slot from a
+// CLUSTER SLOTS reply. Every entry of the reply contains the first and last
+// slot of a range, followed by the master and the replicas as ip, port and
+// node ID.
+func slotOwner(reply []interface{}, slot int) (string, error) {
+	for _, entry := range reply {
+		values, err := redis.Values(entry, nil)
+		if err != nil || len(values) < 3 {
+			return "", fmt.Errorf("invalid CLUSTER SLOTS reply")
+		}
+
+		first, err := redis.Int(values[0], nil)
+		if err != nil {
+			return "", err
+		}
+		last, err := redis.Int(values[1], nil)
+		if err != nil {
+			return "", err
+		}
+		if slot < first || slot > last {
+			continue
+		}
+
+		master, err := redis.Values(values[2], nil)
+		if err != nil || len(master) < 2 {
+			return "", fmt.Errorf("invalid CLUSTER SLOTS reply")
+		}
+		ip, err := redis.String(master[0], nil)
+		if err != nil {
+			return "", err
+		}
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return "", err
+		}
+		return net.JoinHostPort(ip, strconv.Itoa(port)), nil
+	}
+	return "", fmt.Errorf("hash slot %v is not served by any node", slot)
+}
+
+// clusterSlot returns the hash slot of the key. If the key contains a hash
+// tag, like `{beats}`, only the tag is hashed.
+func clusterSlot(key []byte) int {
+	if start := bytes.IndexByte(key, '{'); start >= 0 {
+		if end := bytes.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % numClusterSlots
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used for Redis Cluster
+// hash slots.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,88 @@
+// +build !integration
+
+package redis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestClusterSlot(t *testing.T) {
+	assert.Equal(t, 0x31C3, int(crc16([]byte("123456789"))))
+	assert.Equal(t, 12182, clusterSlot([]byte("foo")))
+
+	// only hash tags are hashed
+	assert.Equal(t, clusterSlot([]byte("user1000")), clusterSlot([]byte("{user1000}.following")))
+
+	// empty hash tags are ignored
+	assert.Equal(t, int(crc16([]byte("{}beats")))%numClusterSlots, clusterSlot([]byte("{}beats")))
+}
+
+func TestSlotOwner(t *testing.T) {
+	node := func(ip string, port int64) []interface{} {
+		return []interface{}{[]byte(ip), port, []byte("id")}
+	}
+	reply := []interface{}{
+		[]interface{}{int64(0), int64(5460), node("10.0.0.1", 7000), node("10.0.0.4", 7003)},
+		[]interface{}{int64(5461), int64(10922), node("10.0.0.2", 7001)},
+		[]interface{}{int64(10923), int64(16383), node("10.0.0.3", 7002)},
+	}
+
+	tests := []struct {
+		slot     int
+		expected string
+	}{
+		{0, "10.0.0.1:7000"},
+		{5461, "10.0.0.2:7001"},
+		{12182, "10.0.0.3:7002"},
+	}
+	for _, test := range tests {
+		addr, err := slotOwner(reply, test.slot)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, addr)
+	}
+
+	_, err := slotOwner(reply[:2], 12182)
+	assert.Error(t, err)
+}
+
+func TestClusterLocatorFailed(t *testing.T) {
+	c := newClusterLocator(nil, []string{"localhost"}, 6379, "", []byte("beats"))
+
+	assert.False(t, c.failed(errors.New("i/o timeout")))
+	assert.False(t, c.failed(redis.Error("ASK 3999 10.0.0.2:7001")))
+	assert.True(t, c.failed(redis.Error("CLUSTERDOWN The cluster is down")))
+
+	assert.True(t, c.failed(redis.Error("MOVED 3999 10.0.0.2:7001")))
+	addr, err := c.locate(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.2:7001", addr)
+}
+
+func TestSentinelLocatorFailed(t *testing.T) {
+	s := &sentinelLocator{}
+	assert.True(t, s.failed(redis.Error("READONLY You can't write against a read only replica.")))
+	assert.False(t, s.failed(redis.Error("ERR unknown command")))
+	assert.False(t, s.failed(errors.New("EOF")))
+}
+
+func TestDiscoveryConfigValidate(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"sentinel.hosts": []string{"localhost"}},
+		{"sentinel.hosts": []string{"localhost"}, "sentinel.master_name": "m", "cluster.enabled": true},
+		{"cluster.enabled": true, "db": 1},
+	} {
+		invalid["index"] = "beats"
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", invalid)
+	}
+}
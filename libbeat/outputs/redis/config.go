@@ -6,21 +6,31 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
 type redisConfig struct {
-	Password    string                `config:"password"`
-	Index       string                `config:"index"`
-	Port        int                   `config:"port"`
-	LoadBalance bool                  `config:"loadbalance"`
-	Timeout     time.Duration         `config:"timeout"`
-	MaxRetries  int                   `config:"max_retries"`
-	TLS         *outputs.TLSConfig    `config:"tls"`
-	Proxy       transport.ProxyConfig `config:",inline"`
-
-	Db       int    `config:"db"`
-	DataType string `config:"datatype"`
+	Password       string                 `config:"password"`
+	Index          string                 `config:"index"`
+	Port           int                    `config:"port"`
+	LoadBalance    bool                   `config:"loadbalance"`
+	Timeout        time.Duration          `config:"timeout"`
+	MaxRetries     int                    `config:"max_retries"`
+	TLS            *outputs.TLSConfig     `config:"tls"`
+	Proxy          transport.ProxyConfig  `config:",inline"`
+	Ordering       outputs.OrderingConfig `config:"ordering"`
+	DNS            transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec int                    `config:"max_bytes_per_second" validate:"min=0"`
+
+	Db       int         `config:"db"`
+	DataType string      `config:"datatype"`
+	Keys     []keyConfig `config:"keys"`
+
+	Codec codec.Config `config:"codec"`
+
+	Sentinel sentinelConfig `config:"sentinel"`
+	Cluster  clusterConfig  `config:"cluster"`
 
 	HostTopology     string `config:"host_topology"`
 	PasswordTopology string `config:"password_topology"`
@@ -43,15 +53,35 @@ var (
 )
 
 func (c *redisConfig) Validate() error {
-	switch c.DataType {
-	case "", "list", "channel":
-	default:
-		return fmt.Errorf("redis data type %v not supported", c.DataType)
+	if _, err := parseDataType(c.DataType); err != nil {
+		return err
+	}
+	for i, rule := range c.Keys {
+		if _, err := parseDataType(rule.DataType); err != nil {
+			return fmt.Errorf("key rule %d: %v", i, err)
+		}
 	}
 
 	if c.Index == "" {
 		return errors.New("index required")
 	}
 
+	if len(c.Sentinel.Hosts) > 0 {
+		if c.Sentinel.MasterName == "" {
+			return errors.New("sentinel.master_name required if sentinel hosts are set")
+		}
+		if c.Cluster.Enabled {
+			return errors.New("sentinel and cluster can not be combined")
+		}
+	}
+	if c.Cluster.Enabled {
+		if c.Db != 0 {
+			return errors.New("redis cluster only supports db 0")
+		}
+		if len(c.Keys) > 0 {
+			return errors.New("keys can not be combined with cluster, as all events are published to the node serving the index key")
+		}
+	}
+
 	return nil
 }
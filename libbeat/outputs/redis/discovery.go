@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// nodeLocator locates the Redis node events are published to, if the
+// configured hosts are not the Redis servers to publish to, like Sentinels or
+// the seed nodes of a Redis Cluster.
+type nodeLocator interface {
+	// locate returns the address of the node to publish to.
+	locate(timeout time.Duration) (string, error)
+
+	// check is called after connecting to the located node, to verify the
+	// node accepts events.
+	check(conn redis.Conn) error
+
+	// failed is called with the errors returned by the node, and reports if
+	// the node must be located again.
+	failed(err error) bool
+}
+
+// discoveryClient connects to the node returned by the locator. On errors
+// indicating a failover or a cluster reconfiguration, the connection is
+// closed, such that the node is located again on reconnect.
+type discoveryClient struct {
+	*client
+	addr string // address of the located node
+
+	transp    *transport.Config
+	port      int
+	locator   nodeLocator
+	newClient func(conn *transport.Client) *client
+}
+
+var errNotConnected = errors.New("not connected")
+
+func newDiscoveryClient(
+	transp *transport.Config,
+	port int,
+	locator nodeLocator,
+	newClient func(conn *transport.Client) *client,
+) *discoveryClient {
+	return &discoveryClient{
+		transp:    transp,
+		port:      port,
+		locator:   locator,
+		newClient: newClient,
+	}
+}
+
+func (d *discoveryClient) Connect(timeout time.Duration) error {
+	if d.client != nil {
+		d.client.Close()
+		d.client = nil
+	}
+
+	addr, err := d.locator.locate(timeout)
+	if err != nil {
+		return err
+	}
+	debugf("connect to located redis node %v", addr)
+
+	conn, err := transport.NewClient(d.transp, "tcp", addr, d.port)
+	if err != nil {
+		return err
+	}
+	c := d.newClient(conn)
+	c.check = d.locator.check
+	if err := c.Connect(timeout); err != nil {
+		c.Close()
+		return err
+	}
+
+	d.client = c
+	d.addr = addr
+	return nil
+}
+
+func (d *discoveryClient) Close() error {
+	if d.client == nil {
+		return nil
+	}
+	return d.client.Close()
+}
+
+func (d *discoveryClient) IsConnected() bool {
+	return d.client != nil && d.client.IsConnected()
+}
+
+func (d *discoveryClient) PublishEvent(event common.MapStr) error {
+	_, err := d.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+func (d *discoveryClient) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	if d.client == nil {
+		return events, errNotConnected
+	}
+
+	rest, err := d.client.PublishEvents(events)
+	if err != nil && d.locator.failed(err) {
+		logp.Info("Redis node %v is not accepting events anymore (%v), locating the node again",
+			d.addr, err)
+		d.client.Close()
+	}
+	return rest, err
+}
+
+// dialNode connects to a Redis server, like a Sentinel or a cluster node, to
+// query the topology.
+func dialNode(
+	transp *transport.Config,
+	host string,
+	port int,
+	password string,
+	timeout time.Duration,
+) (redis.Conn, error) {
+	t, err := transport.NewClient(transp, "tcp", host, port)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Connect(); err != nil {
+		return nil, err
+	}
+
+	conn := redis.NewConn(t, timeout, timeout)
+	if password != "" {
+		if _, err := conn.Do("AUTH", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/fmtstr"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// keyConfig configures a key mapping rule. Events matching the condition are
+// published to the key, using the data type and database of the rule if set.
+// Rules without condition match all events.
+type keyConfig struct {
+	Key      *fmtstr.EventFormatString   `config:"key"      validate:"required"`
+	DataType string                      `config:"datatype"`
+	Db       *int                        `config:"db"       validate:"min=0"`
+	When     *processors.ConditionConfig `config:"when"`
+}
+
+// destination is the key, data type and database events are published to.
+type destination struct {
+	key      string
+	dataType redisDataType
+	db       int
+}
+
+type keyRule struct {
+	key      *fmtstr.EventFormatString
+	dataType redisDataType
+	db       int
+	cond     *processors.Condition
+}
+
+// keySelector selects the destination of an event. The destination of the
+// first matching rule of the keys setting is used. Otherwise, or if the key
+// can not be formatted because a field is missing, the events are published
+// to the index, using the datatype and db settings.
+type keySelector struct {
+	rules    []keyRule
+	fallback destination
+}
+
+// eventBatch are the events published to the same destination.
+type eventBatch struct {
+	dest   destination
+	events []common.MapStr
+}
+
+func newKeySelector(rules []keyConfig, fallback destination) (*keySelector, error) {
+	s := &keySelector{fallback: fallback}
+	for i, rule := range rules {
+		cond, err := processors.NewCondition(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition of key rule %d: %v", i, err)
+		}
+
+		dataType := fallback.dataType
+		if rule.DataType != "" {
+			dataType, err = parseDataType(rule.DataType)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		db := fallback.db
+		if rule.Db != nil {
+			db = *rule.Db
+		}
+
+		s.rules = append(s.rules, keyRule{
+			key:      rule.Key,
+			dataType: dataType,
+			db:       db,
+			cond:     cond,
+		})
+	}
+	return s, nil
+}
+
+// usesDataType reports if any event can be published with the data type.
+func (s *keySelector) usesDataType(dataType redisDataType) bool {
+	if s.fallback.dataType == dataType {
+		return true
+	}
+	for _, rule := range s.rules {
+		if rule.dataType == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *keySelector) selectDestination(event common.MapStr) destination {
+	for _, rule := range s.rules {
+		if rule.cond != nil && !rule.cond.Check(event) {
+			continue
+		}
+
+		key, err := rule.key.Run(event)
+		if err != nil || key == "" {
+			debugf("Failed to format redis key, using key '%v': %v", s.fallback.key, err)
+			return s.fallback
+		}
+		return destination{key: key, dataType: rule.dataType, db: rule.db}
+	}
+	return s.fallback
+}
+
+// split groups the events by destination. The batches are ordered by the
+// first event of every destination, and keep the order of the events.
+func (s *keySelector) split(events []common.MapStr) []eventBatch {
+	var batches []eventBatch
+	index := map[destination]int{}
+	for _, event := range events {
+		dest := s.selectDestination(event)
+		i, exists := index[dest]
+		if !exists {
+			i = len(batches)
+			index[dest] = i
+			batches = append(batches, eventBatch{dest: dest})
+		}
+		batches[i].events = append(batches[i].events, event)
+	}
+	return batches
+}
+
+func parseDataType(dataType string) (redisDataType, error) {
+	switch dataType {
+	case "", "list":
+		return redisListType, nil
+	case "channel":
+		return redisChannelType, nil
+	default:
+		return 0, fmt.Errorf("redis data type %v not supported", dataType)
+	}
+}
@@ -0,0 +1,79 @@
+// +build !integration
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func TestKeySelectorSplit(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"index": "beats",
+		"keys": []map[string]interface{}{
+			{
+				"key":               "alerts",
+				"datatype":          "channel",
+				"when.equals.level": "critical",
+			},
+			{
+				"key":                "%{[type]}",
+				"db":                 2,
+				"when.contains.type": "log",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := newKeySelector(config.Keys, destination{key: "beats", dataType: redisListType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, keys.usesDataType(redisChannelType))
+
+	events := []common.MapStr{
+		{"type": "log", "n": 0},
+		{"message": "no type", "n": 1},
+		{"type": "log", "level": "critical", "n": 2},
+		{"type": "log", "n": 3},
+	}
+	assert.Equal(t, []eventBatch{
+		{
+			dest:   destination{key: "log", dataType: redisListType, db: 2},
+			events: []common.MapStr{events[0], events[3]},
+		},
+		{
+			dest:   destination{key: "beats", dataType: redisListType},
+			events: []common.MapStr{events[1]},
+		},
+		{
+			dest:   destination{key: "alerts", dataType: redisChannelType},
+			events: []common.MapStr{events[2]},
+		},
+	}, keys.split(events))
+}
+
+func TestKeysConfigValidate(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"keys": []map[string]interface{}{{"key": "k", "datatype": "set"}}},
+		{"keys": []map[string]interface{}{{"key": "k"}}, "cluster.enabled": true},
+		{"keys": []map[string]interface{}{{"datatype": "list"}}},
+	} {
+		invalid["index"] = "beats"
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", invalid)
+	}
+}
@@ -1,7 +1,6 @@
 package redis
 
 import (
-	"errors"
 	"expvar"
 	"fmt"
 	"time"
@@ -10,14 +9,18 @@ import (
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
 	"github.com/elastic/beats/libbeat/outputs/mode"
 	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/preflight"
 	"github.com/elastic/beats/libbeat/outputs/transport"
 )
 
 type redisOut struct {
 	mode mode.ConnectionMode
 	topology
+
+	endpoints []preflight.Endpoint // endpoints for the preflight checks
 }
 
 var debugf = logp.MakeDebug("redis")
@@ -36,7 +39,9 @@ const (
 )
 
 func init() {
-	outputs.RegisterOutputPlugin("redis", new)
+	if err := outputs.RegisterOutputPlugin("redis", new); err != nil {
+		panic(err)
+	}
 }
 
 func new(cfg *common.Config, expireTopo int) (outputs.Outputer, error) {
@@ -59,14 +64,9 @@ func (r *redisOut) init(cfg *common.Config, expireTopo int) error {
 		maxAttempts = 0
 	}
 
-	var dataType redisDataType
-	switch config.DataType {
-	case "", "list":
-		dataType = redisListType
-	case "channel":
-		dataType = redisChannelType
-	default:
-		return errors.New("Bad Redis data type")
+	dataType, err := parseDataType(config.DataType)
+	if err != nil {
+		return err
 	}
 
 	index := []byte(config.Index)
@@ -74,15 +74,34 @@ func (r *redisOut) init(cfg *common.Config, expireTopo int) error {
 		return fmt.Errorf("missing %v", cfg.PathOf("index"))
 	}
 
+	var keys *keySelector
+	if len(config.Keys) > 0 {
+		keys, err = newKeySelector(config.Keys, destination{
+			key:      config.Index,
+			dataType: dataType,
+			db:       config.Db,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	enc, err := codec.New(config.Codec)
+	if err != nil {
+		return err
+	}
+
 	tls, err := outputs.LoadTLSConfig(config.TLS)
 	if err != nil {
 		return err
 	}
 
 	transp := &transport.Config{
-		Timeout: config.Timeout,
-		Proxy:   &config.Proxy,
-		TLS:     tls,
+		Timeout:  config.Timeout,
+		Proxy:    &config.Proxy,
+		TLS:      tls,
+		Resolver: transport.NewResolver(config.DNS),
+		Throttle: transport.NewThrottle(config.MaxBytesPerSec),
 		Stats: &transport.IOStats{
 			Read:        statReadBytes,
 			Write:       statWriteBytes,
@@ -100,32 +119,116 @@ func (r *redisOut) init(cfg *common.Config, expireTopo int) error {
 	})
 
 	// configure publisher clients
-	clients, err := modeutil.MakeClients(cfg, func(host string) (mode.ProtocolClient, error) {
-		t, err := transport.NewClient(transp, "tcp", host, config.Port)
-		if err != nil {
-			return nil, err
+	makeClient := func(t *transport.Client) *client {
+		c := newClient(t, config.Password, config.Db, index, dataType)
+		c.keys = keys
+		c.codec = enc
+		return c
+	}
+
+	var clients []mode.ProtocolClient
+	hosts, err := modeutil.ReadHostList(cfg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(config.Sentinel.Hosts) > 0:
+		logp.Info("Redis master '%v' is discovered by sentinels %v",
+			config.Sentinel.MasterName, config.Sentinel.Hosts)
+		clients, err = makeDiscoveryClients(cfg, func() mode.ProtocolClient {
+			locator := &sentinelLocator{transp: transp, config: config.Sentinel}
+			return newDiscoveryClient(transp, config.Port, locator, makeClient)
+		})
+	case config.Cluster.Enabled:
+		if len(hosts) == 0 {
+			return mode.ErrNoHostsConfigured
 		}
-		return newClient(t, config.Password, config.Db, index, dataType), nil
-	})
+		seeds := uniqueHosts(hosts)
+		logp.Info("Redis cluster node of key '%s' is discovered from %v", index, seeds)
+		clients, err = makeDiscoveryClients(cfg, func() mode.ProtocolClient {
+			locator := newClusterLocator(transp, seeds, config.Port, config.Password, index)
+			return newDiscoveryClient(transp, config.Port, locator, makeClient)
+		})
+	default:
+		clients, err = modeutil.MakeClients(cfg, func(host string) (mode.ProtocolClient, error) {
+			t, err := transport.NewClient(transp, "tcp", host, config.Port)
+			if err != nil {
+				return nil, err
+			}
+			return makeClient(t), nil
+		})
+	}
 	if err != nil {
 		return err
 	}
 
 	logp.Info("Max Retries set to: %v", sendRetries)
-	m, err := modeutil.NewConnectionMode(clients, !config.LoadBalance,
+	m, err := modeutil.NewConnectionMode(clients, !config.LoadBalance, config.Ordering,
 		maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
 	if err != nil {
 		return err
 	}
 
 	r.mode = m
+
+	if len(config.Sentinel.Hosts) > 0 {
+		r.endpoints = transport.PreflightEndpoints(transp, config.Sentinel.Hosts, defaultSentinelPort)
+	} else {
+		r.endpoints = transport.PreflightEndpoints(transp, hosts, config.Port)
+	}
 	return nil
 }
 
+// makeDiscoveryClients creates one client per worker, as all clients publish
+// to the located node.
+func makeDiscoveryClients(
+	cfg *common.Config,
+	newClient func() mode.ProtocolClient,
+) ([]mode.ProtocolClient, error) {
+	config := struct {
+		Worker int `config:"worker" validate:"min=1"`
+	}{
+		Worker: 1,
+	}
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	clients := make([]mode.ProtocolClient, config.Worker)
+	for i := range clients {
+		clients[i] = newClient()
+	}
+	return clients, nil
+}
+
+// uniqueHosts removes the duplicate hosts added by ReadHostList per worker.
+func uniqueHosts(hosts []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, host := range hosts {
+		if !seen[host] {
+			seen[host] = true
+			unique = append(unique, host)
+		}
+	}
+	return unique
+}
+
+// PreflightEndpoints returns the Redis hosts for the preflight checks.
+func (r *redisOut) PreflightEndpoints() []preflight.Endpoint {
+	return r.endpoints
+}
+
 func (r *redisOut) Close() error {
 	return r.mode.Close()
 }
 
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (r *redisOut) Concurrent() bool {
+	return modeutil.IsConcurrent(r.mode)
+}
+
 func (r *redisOut) PublishEvent(
 	signaler op.Signaler,
 	opts outputs.Options,
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+const defaultSentinelPort = 26379
+
+type sentinelConfig struct {
+	Hosts      []string `config:"hosts"`
+	MasterName string   `config:"master_name"`
+	Password   string   `config:"password"`
+}
+
+// sentinelLocator asks the Redis Sentinels for the address of the current
+// master. The Sentinels are asked in order, until one of them knows the
+// master.
+type sentinelLocator struct {
+	transp *transport.Config
+	config sentinelConfig
+}
+
+func (s *sentinelLocator) locate(timeout time.Duration) (string, error) {
+	for _, host := range s.config.Hosts {
+		addr, err := s.askSentinel(host, timeout)
+		if err != nil {
+			logp.Warn("Failed to get redis master '%v' from sentinel %v: %v",
+				s.config.MasterName, host, err)
+			continue
+		}
+		return addr, nil
+	}
+	return "", fmt.Errorf("no sentinel returned the address of master '%v'",
+		s.config.MasterName)
+}
+
+func (s *sentinelLocator) askSentinel(host string, timeout time.Duration) (string, error) {
+	conn, err := dialNode(s.transp, host, defaultSentinelPort, s.config.Password, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.config.MasterName))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("unknown master")
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+// check verifies the located node is still the master, as the Sentinels
+// might not have noticed a failover yet.
+func (s *sentinelLocator) check(conn redis.Conn) error {
+	reply, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(reply) == 0 {
+		return fmt.Errorf("empty ROLE reply")
+	}
+
+	role, err := redis.String(reply[0], nil)
+	if err != nil {
+		return err
+	}
+	if role != "master" {
+		return fmt.Errorf("redis node has the role '%v' instead of master", role)
+	}
+	return nil
+}
+
+// failed reports if the node has been demoted to a replica.
+func (s *sentinelLocator) failed(err error) bool {
+	if err, ok := err.(redis.Error); ok {
+		return strings.HasPrefix(string(err), "READONLY")
+	}
+	return false
+}
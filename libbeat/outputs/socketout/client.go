@@ -0,0 +1,121 @@
+package socketout
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// conn is a connection to the socket or named pipe.
+type conn interface {
+	io.WriteCloser
+	SetWriteDeadline(t time.Time) error
+}
+
+// client writes the events of a batch as newline terminated messages at once.
+type client struct {
+	path    string
+	timeout time.Duration
+	codec   codec.Codec
+
+	conn conn
+
+	// buffer of the messages, reused between batches
+	buf bytes.Buffer
+}
+
+func newClient(path string, timeout time.Duration, codec codec.Codec) *client {
+	return &client{
+		path:    path,
+		timeout: timeout,
+		codec:   codec,
+	}
+}
+
+func (c *client) Connect(timeout time.Duration) error {
+	debugf("connect to %v", c.path)
+	_ = c.Close()
+
+	conn, err := dial(c.path, c.timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *client) IsConnected() bool {
+	return c.conn != nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	debugf("close connection")
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+// PublishEvents writes the events. Events which can not be encoded are
+// dropped. On a write error the connection is closed and all events are
+// returned, as it is unknown which messages were read by the daemon.
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	publishEventsCallCount.Add(1)
+	if !c.IsConnected() {
+		return events, transport.ErrNotConnected
+	}
+
+	c.buf.Reset()
+	for _, event := range events {
+		msg, err := c.codec.Encode(event)
+		if err != nil {
+			logp.Err("Dropping event, failed to encode event: %v", err)
+			eventsDropped.Add(1)
+			continue
+		}
+
+		// newlines would end the message early
+		for _, b := range msg {
+			if b == '\n' || b == '\r' {
+				b = ' '
+			}
+			c.buf.WriteByte(b)
+		}
+		c.buf.WriteByte('\n')
+	}
+
+	if c.buf.Len() > 0 {
+		if err := c.write(c.buf.Bytes()); err != nil {
+			logp.Err("Failed to write events to %v: %v", c.path, err)
+			statWriteErrors.Add(1)
+			_ = c.Close()
+			eventsNotAcked.Add(int64(len(events)))
+			return events, err
+		}
+	}
+
+	ackedEvents.Add(int64(len(events)))
+	return nil, nil
+}
+
+func (c *client) write(b []byte) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	n, err := c.conn.Write(b)
+	statWriteBytes.Add(int64(n))
+	return err
+}
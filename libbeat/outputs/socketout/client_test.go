@@ -0,0 +1,138 @@
+// +build !integration
+// +build !windows
+
+package socketout
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+func listen(t *testing.T) (net.Listener, string, func()) {
+	dir, err := ioutil.TempDir("", "socketout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "beat.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, path, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func accept(t *testing.T, l net.Listener) *bufio.Reader {
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	return bufio.NewReader(server)
+}
+
+func TestPublishEvents(t *testing.T) {
+	l, path, cleanup := listen(t)
+	defer cleanup()
+
+	enc, _ := codec.New(codec.Config{})
+	c := newClient(path, time.Second, enc)
+	assert.NoError(t, c.Connect(time.Second))
+	defer c.Close()
+	r := accept(t, l)
+
+	rest, err := c.PublishEvents([]common.MapStr{
+		{"type": "log", "message": "first"},
+		{"type": "log", "message": "line 1\nline 2"},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, rest)
+
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"message":"first","type":"log"}`+"\n", line)
+	line, err = r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"message":"line 1\nline 2","type":"log"}`+"\n", line)
+}
+
+func TestPublishRawNewlines(t *testing.T) {
+	l, path, cleanup := listen(t)
+	defer cleanup()
+
+	enc, _ := codec.New(codec.Config{Raw: &codec.RawConfig{Field: "message"}})
+	c := newClient(path, time.Second, enc)
+	assert.NoError(t, c.Connect(time.Second))
+	defer c.Close()
+	r := accept(t, l)
+
+	_, err := c.PublishEvents([]common.MapStr{{"message": "line 1\nline 2"}})
+	assert.NoError(t, err)
+
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1 line 2\n", line)
+}
+
+func TestReconnect(t *testing.T) {
+	l, path, cleanup := listen(t)
+	defer cleanup()
+
+	enc, _ := codec.New(codec.Config{})
+	c := newClient(path, time.Second, enc)
+	assert.NoError(t, c.Connect(time.Second))
+	defer c.Close()
+
+	// the daemon restarts, closing the connection
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	events := []common.MapStr{{"type": "log"}}
+	var rest []common.MapStr
+	for i := 0; i < 10 && err == nil; i++ {
+		rest, err = c.PublishEvents(events)
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Error(t, err)
+	assert.Equal(t, events, rest)
+	assert.False(t, c.IsConnected())
+
+	assert.NoError(t, c.Connect(time.Second))
+	r := accept(t, l)
+	rest, err = c.PublishEvents(events)
+	assert.NoError(t, err)
+	assert.Nil(t, rest)
+
+	line, err := r.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"log"}`+"\n", line)
+}
+
+func TestConnectMissingSocket(t *testing.T) {
+	_, path, cleanup := listen(t)
+	cleanup()
+
+	enc, _ := codec.New(codec.Config{})
+	c := newClient(path, time.Second, enc)
+	assert.Error(t, c.Connect(time.Second))
+
+	rest, err := c.PublishEvents([]common.MapStr{{"type": "log"}})
+	assert.Error(t, err)
+	assert.Len(t, rest, 1)
+}
@@ -0,0 +1,21 @@
+package socketout
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+type socketConfig struct {
+	Path       string        `config:"path" validate:"required"`
+	Timeout    time.Duration `config:"timeout"`
+	MaxRetries int           `config:"max_retries"`
+	Codec      codec.Config  `config:"codec"`
+}
+
+var (
+	defaultConfig = socketConfig{
+		Timeout:    30 * time.Second,
+		MaxRetries: 3,
+	}
+)
@@ -0,0 +1,13 @@
+// +build !windows
+
+package socketout
+
+import (
+	"net"
+	"time"
+)
+
+// dial connects to the Unix domain socket at path.
+func dial(path string, timeout time.Duration) (conn, error) {
+	return net.DialTimeout("unix", path, timeout)
+}
@@ -0,0 +1,37 @@
+package socketout
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// ERROR_PIPE_BUSY is returned if all instances of the pipe are in use.
+const errPipeBusy syscall.Errno = 231
+
+// dial opens the named pipe at path, like `\\.\pipe\beats`. If all instances
+// of the pipe are busy, opening is retried until the timeout.
+func dial(path string, timeout time.Duration) (conn, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err == nil {
+			return pipeConn{f}, nil
+		}
+
+		if perr, ok := err.(*os.PathError); !ok || perr.Err != errPipeBusy || time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// pipeConn is the client end of a named pipe. The pipe is not opened for
+// overlapped I/O, so writes can not time out and deadlines are ignored.
+type pipeConn struct {
+	*os.File
+}
+
+func (pipeConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+// Package socketout implements the socket output, which writes events as
+// newline delimited JSON to a Unix domain socket, or to a named pipe on
+// Windows, for example to feed a local daemon without opening TCP ports.
+package socketout
+
+import (
+	"expvar"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+)
+
+type socketOutput struct {
+	mode mode.ConnectionMode
+}
+
+var debugf = logp.MakeDebug("socket")
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	ackedEvents            = expvar.NewInt("libbeat.socket.published_and_acked_events")
+	eventsNotAcked         = expvar.NewInt("libbeat.socket.published_but_not_acked_events")
+	eventsDropped          = expvar.NewInt("libbeat.socket.dropped_events")
+	publishEventsCallCount = expvar.NewInt("libbeat.socket.call_count.PublishEvents")
+
+	statWriteBytes  = expvar.NewInt("libbeat.socket.publish.write_bytes")
+	statWriteErrors = expvar.NewInt("libbeat.socket.publish.write_errors")
+)
+
+const (
+	defaultWaitRetry    = 1 * time.Second
+	defaultMaxWaitRetry = 60 * time.Second
+)
+
+func init() {
+	if err := outputs.RegisterOutputPlugin("socket", New); err != nil {
+		panic(err)
+	}
+}
+
+// New instantiates a new output plugin instance writing to a Unix domain
+// socket or named pipe.
+func New(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	out := &socketOutput{}
+	if err := out.init(cfg); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (out *socketOutput) init(cfg *common.Config) error {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return err
+	}
+
+	enc, err := codec.New(config.Codec)
+	if err != nil {
+		return err
+	}
+
+	maxRetries := config.MaxRetries
+	maxAttempts := maxRetries + 1 // maximum number of send attempts (-1 = infinite)
+	if maxRetries < 0 {
+		maxAttempts = 0
+	}
+
+	// The connection mode reconnects with backoff if the socket is not
+	// available or the daemon closed the connection.
+	clients := []mode.ProtocolClient{newClient(config.Path, config.Timeout, enc)}
+	m, err := modeutil.NewConnectionMode(clients, false, outputs.OrderingConfig{},
+		maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
+	if err != nil {
+		return err
+	}
+
+	out.mode = m
+	return nil
+}
+
+func (out *socketOutput) Close() error {
+	return out.mode.Close()
+}
+
+func (out *socketOutput) PublishEvent(
+	signaler op.Signaler,
+	opts outputs.Options,
+	event common.MapStr,
+) error {
+	return out.mode.PublishEvent(signaler, opts, event)
+}
+
+func (out *socketOutput) BulkPublish(
+	signaler op.Signaler,
+	opts outputs.Options,
+	events []common.MapStr,
+) error {
+	return out.mode.PublishEvents(signaler, opts, events)
+}
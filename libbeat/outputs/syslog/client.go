@@ -0,0 +1,127 @@
+package syslog
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+// client sends syslog messages to a single host. Over udp, every message is
+// sent in its own datagram. Over tcp and tls, the messages of a batch are
+// framed and written at once.
+type client struct {
+	*transport.Client
+	formatter *formatter
+	stream    bool
+	framing   string
+	timeout   time.Duration
+
+	// buffer of the framed messages, reused between batches
+	buf bytes.Buffer
+}
+
+func newClient(
+	conn *transport.Client,
+	formatter *formatter,
+	stream bool,
+	framing string,
+	timeout time.Duration,
+) *client {
+	return &client{
+		Client:    conn,
+		formatter: formatter,
+		stream:    stream,
+		framing:   framing,
+		timeout:   timeout,
+	}
+}
+
+func (c *client) Connect(timeout time.Duration) error {
+	debugf("connect")
+	return c.Client.Connect()
+}
+
+func (c *client) Close() error {
+	debugf("close connection")
+	return c.Client.Close()
+}
+
+func (c *client) PublishEvent(event common.MapStr) error {
+	_, err := c.PublishEvents([]common.MapStr{event})
+	return err
+}
+
+// PublishEvents sends the events. Events which can not be formatted are
+// dropped. On a write error the connection is closed and all events are
+// returned, as it is unknown which messages were received.
+func (c *client) PublishEvents(events []common.MapStr) ([]common.MapStr, error) {
+	publishEventsCallCount.Add(1)
+	if !c.IsConnected() {
+		return events, transport.ErrNotConnected
+	}
+
+	c.buf.Reset()
+	for i, event := range events {
+		msg, err := c.formatter.Format(event)
+		if err != nil {
+			logp.Err("Dropping event, failed to format syslog message: %v", err)
+			eventsDropped.Add(1)
+			continue
+		}
+
+		if !c.stream {
+			if err := c.write(msg); err != nil {
+				return c.fail(events[i:], err)
+			}
+			continue
+		}
+		c.frame(msg)
+	}
+
+	if c.stream && c.buf.Len() > 0 {
+		if err := c.write(c.buf.Bytes()); err != nil {
+			return c.fail(events, err)
+		}
+	}
+
+	ackedEvents.Add(int64(len(events)))
+	return nil, nil
+}
+
+// frame appends the message to the buffer, framed as configured.
+func (c *client) frame(msg []byte) {
+	if c.framing == framingOctetCounting {
+		c.buf.WriteString(strconv.Itoa(len(msg)))
+		c.buf.WriteByte(' ')
+		c.buf.Write(msg)
+		return
+	}
+
+	// newlines would end the message early
+	for _, b := range msg {
+		if b == '\n' || b == '\r' {
+			b = ' '
+		}
+		c.buf.WriteByte(b)
+	}
+	c.buf.WriteByte('\n')
+}
+
+func (c *client) write(b []byte) error {
+	if err := c.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return err
+	}
+	_, err := c.Write(b)
+	return err
+}
+
+func (c *client) fail(events []common.MapStr, err error) ([]common.MapStr, error) {
+	logp.Err("Failed to publish events to syslog: %v", err)
+	_ = c.Close()
+	eventsNotAcked.Add(int64(len(events)))
+	return events, err
+}
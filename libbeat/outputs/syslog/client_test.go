@@ -0,0 +1,157 @@
+// +build !integration
+
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+func newTestClient(t *testing.T, network, addr string, settings map[string]interface{}) *client {
+	f := newTestFormatter(t, settings)
+	conn, err := transport.NewClient(&transport.Config{Timeout: time.Second}, network, addr, 514)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := defaultConfig
+	if framing, ok := settings["framing"].(string); ok {
+		config.Framing = framing
+	}
+	config.Format = f.format
+	c := newClient(conn, f, network == "tcp", config.framing(), time.Second)
+	if err := c.Connect(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func testEvents() []common.MapStr {
+	first, second := testEvent(), testEvent()
+	second["message"] = "line 1\nline 2"
+	return []common.MapStr{first, second, {"type": "no message"}}
+}
+
+func TestPublishTCPOctetCounting(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c := newTestClient(t, "tcp", l.Addr().String(), map[string]interface{}{
+		"codec.raw.field": "message",
+	})
+	defer c.Close()
+
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	rest, err := c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+	assert.Nil(t, rest)
+
+	expected := "70 <14>1 2016-11-01T10:04:05.123Z web-1 testbeat - log - disk almost full" +
+		"67 <14>1 2016-11-01T10:04:05.123Z web-1 testbeat - log - line 1\nline 2"
+	buf := make([]byte, len(expected))
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = readFull(server, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(buf))
+}
+
+func TestPublishTCPNonTransparent(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	c := newTestClient(t, "tcp", l.Addr().String(), map[string]interface{}{
+		"format":          "rfc3164",
+		"codec.raw.field": "message",
+	})
+	defer c.Close()
+
+	server, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	_, err = c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	reader := bufio.NewReader(server)
+	for _, expected := range []string{
+		"<14>Nov  1 10:04:05 web-1 testbeat: disk almost full\n",
+		"<14>Nov  1 10:04:05 web-1 testbeat: line 1 line 2\n",
+	} {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+}
+
+func TestPublishUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := newTestClient(t, "udp", conn.LocalAddr().String(), map[string]interface{}{
+		"codec.raw.field": "message",
+	})
+	defer c.Close()
+
+	_, err = c.PublishEvents(testEvents())
+	assert.NoError(t, err)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 1024)
+	for _, expected := range []string{
+		"<14>1 2016-11-01T10:04:05.123Z web-1 testbeat - log - disk almost full",
+		"<14>1 2016-11-01T10:04:05.123Z web-1 testbeat - log - line 1\nline 2",
+	} {
+		n, _, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(buf[:n]))
+	}
+}
+
+func TestPublishNotConnected(t *testing.T) {
+	conn, err := transport.NewClient(&transport.Config{Timeout: time.Second}, "tcp", "127.0.0.1:514", 514)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(conn, newTestFormatter(t, map[string]interface{}{}), true, framingOctetCounting, time.Second)
+
+	events := testEvents()
+	rest, err := c.PublishEvents(events)
+	assert.Equal(t, transport.ErrNotConnected, err)
+	assert.Equal(t, events, rest)
+}
+
+func readFull(conn net.Conn, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,130 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type syslogConfig struct {
+	Index          string                 `config:"index"`
+	Protocol       string                 `config:"protocol"`
+	Port           int                    `config:"port"`
+	Format         string                 `config:"format"`
+	Framing        string                 `config:"framing"`
+	LoadBalance    bool                   `config:"loadbalance"`
+	Timeout        time.Duration          `config:"timeout"`
+	MaxRetries     int                    `config:"max_retries"`
+	TLS            *outputs.TLSConfig     `config:"tls"`
+	Ordering       outputs.OrderingConfig `config:"ordering"`
+	DNS            transport.DNSConfig    `config:"dns"`
+	MaxBytesPerSec int                    `config:"max_bytes_per_second" validate:"min=0"`
+
+	Facility priorityConfig `config:"facility"`
+	Severity priorityConfig `config:"severity"`
+	Hostname headerConfig   `config:"hostname"`
+	AppName  headerConfig   `config:"app_name"`
+	MsgID    headerConfig   `config:"msg_id"`
+	Codec    codec.Config   `config:"codec"`
+}
+
+// priorityConfig sets the facility or severity of the messages from an event
+// field. Field values are looked up in the mapping first, and can otherwise be
+// a name or number of a facility or severity. Value is used if the event has
+// no such field or the field value is no valid facility or severity.
+type priorityConfig struct {
+	Field   string            `config:"field"`
+	Value   string            `config:"value"`
+	Mapping map[string]string `config:"mapping"`
+}
+
+// headerConfig sets a header field of the messages to the value of an event
+// field. Value is used if the event has no such field.
+type headerConfig struct {
+	Field string `config:"field"`
+	Value string `config:"value"`
+}
+
+const (
+	protocolUDP = "udp"
+	protocolTCP = "tcp"
+	protocolTLS = "tls"
+
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+
+	// framingOctetCounting prefixes every message with its length, as
+	// required by RFC 5425 for syslog over TLS.
+	framingOctetCounting = "octet_counting"
+
+	// framingNonTransparent terminates every message with a newline, as
+	// expected by legacy collectors.
+	framingNonTransparent = "non_transparent"
+)
+
+var (
+	defaultConfig = syslogConfig{
+		Protocol:    protocolUDP,
+		Port:        514,
+		Format:      formatRFC5424,
+		LoadBalance: true,
+		Timeout:     30 * time.Second,
+		MaxRetries:  3,
+		Facility:    priorityConfig{Value: "user"},
+		Severity:    priorityConfig{Value: "info"},
+		Hostname:    headerConfig{Field: "beat.hostname"},
+		MsgID:       headerConfig{Field: "type"},
+	}
+)
+
+func (c *syslogConfig) Validate() error {
+	switch c.Protocol {
+	case protocolUDP, protocolTCP:
+		if c.TLS != nil {
+			return fmt.Errorf("tls requires the protocol %v", protocolTLS)
+		}
+	case protocolTLS:
+	default:
+		return fmt.Errorf("unsupported protocol '%v', use udp, tcp or tls", c.Protocol)
+	}
+
+	switch c.Format {
+	case formatRFC5424, formatRFC3164:
+	default:
+		return fmt.Errorf("unsupported format '%v', use rfc5424 or rfc3164", c.Format)
+	}
+
+	switch c.Framing {
+	case "", framingOctetCounting, framingNonTransparent:
+	default:
+		return fmt.Errorf("unsupported framing '%v', use octet_counting or non_transparent", c.Framing)
+	}
+	if c.Framing != "" && c.Protocol == protocolUDP {
+		return errors.New("framing is not supported by the udp protocol")
+	}
+
+	if _, err := newPriority(c.Facility, facilities, maxFacility); err != nil {
+		return fmt.Errorf("invalid facility: %v", err)
+	}
+	if _, err := newPriority(c.Severity, severities, maxSeverity); err != nil {
+		return fmt.Errorf("invalid severity: %v", err)
+	}
+	return nil
+}
+
+// framing returns the framing of messages sent over tcp or tls. By default,
+// RFC 5424 messages are octet counted and RFC 3164 messages newline
+// terminated.
+func (c *syslogConfig) framing() string {
+	if c.Framing != "" {
+		return c.Framing
+	}
+	if c.Format == formatRFC3164 {
+		return framingNonTransparent
+	}
+	return framingOctetCounting
+}
@@ -0,0 +1,139 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/codec"
+)
+
+const (
+	// nilValue is written for empty header fields of RFC 5424 messages.
+	nilValue = "-"
+
+	rfc5424TimeLayout = "2006-01-02T15:04:05.000Z07:00"
+	rfc3164TimeLayout = time.Stamp
+
+	// maximum lengths of the header fields defined by RFC 5424
+	maxHostnameLen = 255
+	maxAppNameLen  = 48
+	maxMsgIDLen    = 32
+
+	// maxTagLen is the maximum length of the tag of RFC 3164 messages.
+	maxTagLen = 32
+)
+
+// formatter renders events into syslog messages. The message text is the
+// event encoded by the codec.
+type formatter struct {
+	format   string
+	facility *priority
+	severity *priority
+	hostname headerConfig
+	appName  headerConfig
+	msgID    headerConfig
+	codec    codec.Codec
+
+	// location of the RFC 3164 timestamps, which have no time zone
+	location *time.Location
+}
+
+func newFormatter(config *syslogConfig) (*formatter, error) {
+	facility, err := newPriority(config.Facility, facilities, maxFacility)
+	if err != nil {
+		return nil, err
+	}
+	severity, err := newPriority(config.Severity, severities, maxSeverity)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := codec.New(config.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	appName := config.AppName
+	if appName.Field == "" && appName.Value == "" {
+		appName.Value = config.Index
+	}
+
+	return &formatter{
+		format:   config.Format,
+		facility: facility,
+		severity: severity,
+		hostname: config.Hostname,
+		appName:  appName,
+		msgID:    config.MsgID,
+		codec:    enc,
+		location: time.Local,
+	}, nil
+}
+
+// Format renders the event into a syslog message, without framing.
+func (f *formatter) Format(event common.MapStr) ([]byte, error) {
+	msg, err := f.codec.Encode(event)
+	if err != nil {
+		return nil, err
+	}
+
+	pri := f.facility.get(event)*8 + f.severity.get(event)
+	ts := time.Now()
+	if t, ok := event["@timestamp"].(common.Time); ok {
+		ts = time.Time(t)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('<')
+	buf.WriteString(strconv.Itoa(pri))
+	buf.WriteByte('>')
+
+	if f.format == formatRFC3164 {
+		// <PRI>TIMESTAMP HOSTNAME TAG: MSG
+		buf.WriteString(ts.In(f.location).Format(rfc3164TimeLayout))
+		buf.WriteByte(' ')
+		buf.WriteString(headerValue(event, f.hostname, maxHostnameLen))
+		buf.WriteByte(' ')
+		buf.WriteString(headerValue(event, f.appName, maxTagLen))
+		buf.WriteString(": ")
+	} else {
+		// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+		fmt.Fprintf(&buf, "1 %s %s %s %s %s %s ",
+			ts.UTC().Format(rfc5424TimeLayout),
+			headerValue(event, f.hostname, maxHostnameLen),
+			headerValue(event, f.appName, maxAppNameLen),
+			nilValue,
+			headerValue(event, f.msgID, maxMsgIDLen),
+			nilValue)
+	}
+	buf.Write(msg)
+	return buf.Bytes(), nil
+}
+
+// headerValue returns the value of a header field. Header fields consist of
+// printable ASCII characters without spaces, other characters are replaced
+// with underscores. Empty values are written as nil value.
+func headerValue(event common.MapStr, config headerConfig, maxLen int) string {
+	value := config.Value
+	if config.Field != "" {
+		if v, err := event.GetValue(config.Field); err == nil {
+			value = fmt.Sprint(v)
+		}
+	}
+	if value == "" {
+		return nilValue
+	}
+
+	b := []byte(value)
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,150 @@
+// +build !integration
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func newTestFormatter(t *testing.T, settings map[string]interface{}) *formatter {
+	cfg, err := common.NewConfigFrom(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig
+	config.Index = "testbeat"
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := newFormatter(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.location = time.UTC
+	return f
+}
+
+func testEvent() common.MapStr {
+	return common.MapStr{
+		"@timestamp": common.Time(time.Date(2016, 11, 1, 10, 4, 5, 123000000, time.UTC)),
+		"beat":       common.MapStr{"hostname": "web-1", "name": "web-1"},
+		"type":       "log",
+		"level":      "warn",
+		"message":    "disk almost full",
+	}
+}
+
+func TestFormatRFC5424(t *testing.T) {
+	f := newTestFormatter(t, map[string]interface{}{
+		"codec.raw.field": "message",
+	})
+
+	msg, err := f.Format(testEvent())
+	assert.NoError(t, err)
+	assert.Equal(t, "<14>1 2016-11-01T10:04:05.123Z web-1 testbeat - log - disk almost full", string(msg))
+}
+
+func TestFormatRFC3164(t *testing.T) {
+	f := newTestFormatter(t, map[string]interface{}{
+		"format":          "rfc3164",
+		"facility.value":  "local3",
+		"codec.raw.field": "message",
+	})
+
+	msg, err := f.Format(testEvent())
+	assert.NoError(t, err)
+	assert.Equal(t, "<158>Nov  1 10:04:05 web-1 testbeat: disk almost full", string(msg))
+}
+
+func TestFormatJSONMessage(t *testing.T) {
+	f := newTestFormatter(t, map[string]interface{}{})
+
+	msg, err := f.Format(common.MapStr{"message": "hello"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(msg), ` testbeat - - - {"message":"hello"}`)
+}
+
+func TestFormatSeverityMapping(t *testing.T) {
+	f := newTestFormatter(t, map[string]interface{}{
+		"severity.field":         "level",
+		"severity.value":         "notice",
+		"severity.mapping.fatal": "emerg",
+		"facility.field":         "fields.facility",
+		"codec.raw.field":        "message",
+	})
+
+	tests := []struct {
+		level    interface{}
+		expected string
+	}{
+		{"warn", "<12>"},    // alias of warning
+		{"ERROR", "<11>"},   // names are case insensitive
+		{"fatal", "<8>"},    // mapping takes precedence
+		{2, "<10>"},         // numeric severity
+		{"unknown", "<13>"}, // default
+		{"12", "<13>"},      // out of range
+		{nil, "<13>"},       // missing field
+	}
+	for _, test := range tests {
+		event := testEvent()
+		delete(event, "level")
+		if test.level != nil {
+			event["level"] = test.level
+		}
+
+		msg, err := f.Format(event)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, string(msg[:len(test.expected)]), "level %v", test.level)
+	}
+
+	event := testEvent()
+	event["fields"] = common.MapStr{"facility": "auth"}
+	msg, err := f.Format(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "<36>", string(msg[:4]))
+}
+
+func TestFormatHeaderValues(t *testing.T) {
+	f := newTestFormatter(t, map[string]interface{}{
+		"hostname.value":  "fallback",
+		"app_name.field":  "app",
+		"msg_id.value":    "",
+		"msg_id.field":    "",
+		"codec.raw.field": "message",
+	})
+
+	event := testEvent()
+	event["beat"] = common.MapStr{"hostname": "web 1\tprod"}
+	event["app"] = "a-very-long-application-name-exceeding-the-limit-of-rfc5424"
+	msg, err := f.Format(event)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"<14>1 2016-11-01T10:04:05.123Z web_1_prod a-very-long-application-name-exceeding-the-limit - - - disk almost full",
+		string(msg))
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"protocol": "http"},
+		{"format": "rfc1234"},
+		{"framing": "octet_counting"},
+		{"protocol": "tcp", "framing": "length"},
+		{"protocol": "tcp", "tls.certificate_authorities": []string{"ca.pem"}},
+		{"facility.value": "local8"},
+		{"severity.value": "8"},
+		{"severity.mapping.fatal": "deadly"},
+	} {
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := defaultConfig
+		assert.Error(t, cfg.Unpack(&config), "%v", invalid)
+	}
+}
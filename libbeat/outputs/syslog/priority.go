@@ -0,0 +1,125 @@
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+const (
+	maxFacility = 23
+	maxSeverity = 7
+)
+
+// facilities are the facility names of RFC 5424 and their codes.
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"ntp":      12,
+	"security": 13,
+	"console":  14,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// severities are the severity names of RFC 5424, and common aliases used by
+// loggers, and their codes.
+var severities = map[string]int{
+	"emerg":         0,
+	"emergency":     0,
+	"panic":         0,
+	"alert":         1,
+	"crit":          2,
+	"critical":      2,
+	"fatal":         2,
+	"err":           3,
+	"error":         3,
+	"warning":       4,
+	"warn":          4,
+	"notice":        5,
+	"info":          6,
+	"informational": 6,
+	"debug":         7,
+}
+
+// priority reads the facility or severity of a message from an event.
+type priority struct {
+	field   string
+	value   int
+	mapping map[string]int
+	names   map[string]int
+	max     int
+}
+
+func newPriority(config priorityConfig, names map[string]int, max int) (*priority, error) {
+	p := &priority{
+		field:   config.Field,
+		mapping: map[string]int{},
+		names:   names,
+		max:     max,
+	}
+
+	var ok bool
+	if p.value, ok = p.parse(config.Value); !ok {
+		return nil, fmt.Errorf("unknown value '%v'", config.Value)
+	}
+	for from, to := range config.Mapping {
+		code, ok := p.parse(to)
+		if !ok {
+			return nil, fmt.Errorf("unknown value '%v' in the mapping of '%v'", to, from)
+		}
+		p.mapping[from] = code
+	}
+	return p, nil
+}
+
+// parse parses a name or number, ignoring the case of names.
+func (p *priority) parse(s string) (int, bool) {
+	if code, found := p.names[strings.ToLower(s)]; found {
+		return code, true
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 0 || code > p.max {
+		return 0, false
+	}
+	return code, true
+}
+
+// get returns the code for the event.
+func (p *priority) get(event common.MapStr) int {
+	if p.field == "" {
+		return p.value
+	}
+
+	v, err := event.GetValue(p.field)
+	if err != nil {
+		return p.value
+	}
+
+	s := fmt.Sprint(v)
+	if code, found := p.mapping[s]; found {
+		return code
+	}
+	if code, ok := p.parse(s); ok {
+		return code
+	}
+	return p.value
+}
@@ -0,0 +1,167 @@
+// Package syslog implements the syslog output, which sends events as RFC 5424
+// or RFC 3164 syslog messages over udp, tcp or tls, for example to the
+// collectors of a SIEM.
+package syslog
+
+import (
+	"crypto/tls"
+	"expvar"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/mode"
+	"github.com/elastic/beats/libbeat/outputs/mode/modeutil"
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+	"github.com/elastic/beats/libbeat/outputs/transport"
+)
+
+type syslogOutput struct {
+	mode mode.ConnectionMode
+
+	endpoints []preflight.Endpoint // endpoints for the preflight checks
+}
+
+var debugf = logp.MakeDebug("syslog")
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	ackedEvents            = expvar.NewInt("libbeat.syslog.published_and_acked_events")
+	eventsNotAcked         = expvar.NewInt("libbeat.syslog.published_but_not_acked_events")
+	eventsDropped          = expvar.NewInt("libbeat.syslog.dropped_events")
+	publishEventsCallCount = expvar.NewInt("libbeat.syslog.call_count.PublishEvents")
+
+	statReadBytes   = expvar.NewInt("libbeat.syslog.publish.read_bytes")
+	statWriteBytes  = expvar.NewInt("libbeat.syslog.publish.write_bytes")
+	statReadErrors  = expvar.NewInt("libbeat.syslog.publish.read_errors")
+	statWriteErrors = expvar.NewInt("libbeat.syslog.publish.write_errors")
+)
+
+const (
+	defaultWaitRetry    = 1 * time.Second
+	defaultMaxWaitRetry = 60 * time.Second
+)
+
+func init() {
+	if err := outputs.RegisterOutputPlugin("syslog", New); err != nil {
+		panic(err)
+	}
+}
+
+// New instantiates a new output plugin instance publishing to syslog
+// collectors.
+func New(cfg *common.Config, _ int) (outputs.Outputer, error) {
+	out := &syslogOutput{}
+	if err := out.init(cfg); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (out *syslogOutput) init(cfg *common.Config) error {
+	config := defaultConfig
+	if err := cfg.Unpack(&config); err != nil {
+		return err
+	}
+
+	formatter, err := newFormatter(&config)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return err
+	}
+	if config.Protocol == protocolTLS && tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
+	transp := &transport.Config{
+		Timeout:  config.Timeout,
+		TLS:      tlsConfig,
+		Resolver: transport.NewResolver(config.DNS),
+		Throttle: transport.NewThrottle(config.MaxBytesPerSec),
+		Stats: &transport.IOStats{
+			Read:        statReadBytes,
+			Write:       statWriteBytes,
+			ReadErrors:  statReadErrors,
+			WriteErrors: statWriteErrors,
+		},
+	}
+
+	network := "tcp"
+	stream := config.Protocol != protocolUDP
+	if !stream {
+		network = "udp"
+	}
+	framing := config.framing()
+
+	hosts, err := modeutil.ReadHostList(cfg)
+	if err != nil {
+		return err
+	}
+	clients, err := modeutil.MakeClients(cfg, func(host string) (mode.ProtocolClient, error) {
+		conn, err := transport.NewClient(transp, network, host, config.Port)
+		if err != nil {
+			return nil, err
+		}
+		return newClient(conn, formatter, stream, framing, config.Timeout), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	maxRetries := config.MaxRetries
+	maxAttempts := maxRetries + 1 // maximum number of send attempts (-1 = infinite)
+	if maxRetries < 0 {
+		maxAttempts = 0
+	}
+
+	m, err := modeutil.NewConnectionMode(clients, !config.LoadBalance, config.Ordering,
+		maxAttempts, defaultWaitRetry, config.Timeout, defaultMaxWaitRetry)
+	if err != nil {
+		return err
+	}
+
+	out.mode = m
+	if stream {
+		// udp has no connection to check
+		out.endpoints = transport.PreflightEndpoints(transp, hosts, config.Port)
+	}
+	return nil
+}
+
+func (out *syslogOutput) Close() error {
+	return out.mode.Close()
+}
+
+// PreflightEndpoints returns the syslog hosts for the preflight checks. Hosts
+// receiving messages over udp are not checked.
+func (out *syslogOutput) PreflightEndpoints() []preflight.Endpoint {
+	return out.endpoints
+}
+
+// Concurrent reports if the output can be called by multiple publisher
+// workers, which is the case if events are load balanced.
+func (out *syslogOutput) Concurrent() bool {
+	return modeutil.IsConcurrent(out.mode)
+}
+
+func (out *syslogOutput) PublishEvent(
+	signaler op.Signaler,
+	opts outputs.Options,
+	event common.MapStr,
+) error {
+	return out.mode.PublishEvent(signaler, opts, event)
+}
+
+func (out *syslogOutput) BulkPublish(
+	signaler op.Signaler,
+	opts outputs.Options,
+	events []common.MapStr,
+) error {
+	return out.mode.PublishEvents(signaler, opts, events)
+}
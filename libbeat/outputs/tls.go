@@ -1,9 +1,14 @@
 package outputs
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io/ioutil"
 
 	"github.com/elastic/beats/libbeat/logp"
@@ -28,18 +33,59 @@ var (
 
 	// ErrUnknownCurveID indicates an unknown curve id has been configured
 	ErrUnknownCurveID = errors.New("unknown curve id")
+
+	// ErrInvalidTLSVersionRange indicates the minimum TLS version being
+	// greater than the maximum TLS version.
+	ErrInvalidTLSVersionRange = errors.New("min_version is greater than max_version")
+
+	// ErrInvalidVerificationMode indicates an unknown verification mode string
+	// given.
+	ErrInvalidVerificationMode = errors.New("invalid verification mode, must be one of full, certificate or none")
+
+	// ErrInsecureVerificationMode indicates insecure being enabled together
+	// with a verification mode other than none.
+	ErrInsecureVerificationMode = errors.New("insecure can only be combined with verification_mode none")
+
+	// ErrInvalidPin indicates a configured pin not being a base64 encoded
+	// SHA-256 hash.
+	ErrInvalidPin = errors.New("pin must be a base64 encoded SHA-256 hash")
+
+	// ErrPinMismatch indicates none of the certificates presented by the
+	// server matching a configured pin.
+	ErrPinMismatch = errors.New("no certificate matches the configured ca_sha256 pins")
+
+	// ErrKeyPassphrase indicates the certificate key being encrypted while no
+	// passphrase has been configured.
+	ErrKeyPassphrase = errors.New("certificate key is encrypted, but key_passphrase is not configured")
+)
+
+// verificationMode configures how server certificates are verified.
+type verificationMode uint8
+
+const (
+	// verifyFull verifies the certificate chain and the host name.
+	verifyFull verificationMode = iota
+
+	// verifyCertificate verifies the certificate chain, but not the host name.
+	verifyCertificate
+
+	// verifyNone accepts any certificate.
+	verifyNone
 )
 
 // TLSConfig defines config file options for TLS clients.
 type TLSConfig struct {
-	Certificate    string   `config:"certificate"`
-	CertificateKey string   `config:"certificate_key"`
-	CAs            []string `config:"certificate_authorities"`
-	Insecure       bool     `config:"insecure"`
-	CipherSuites   []string `config:"cipher_suites"`
-	MinVersion     string   `config:"min_version"`
-	MaxVersion     string   `config:"max_version"`
-	CurveTypes     []string `config:"curve_types"`
+	Certificate      string   `config:"certificate"`
+	CertificateKey   string   `config:"certificate_key"`
+	Passphrase       string   `config:"key_passphrase"`
+	CAs              []string `config:"certificate_authorities"`
+	CASha256         []string `config:"ca_sha256"`
+	Insecure         bool     `config:"insecure"`
+	VerificationMode string   `config:"verification_mode"`
+	CipherSuites     []string `config:"cipher_suites"`
+	MinVersion       string   `config:"min_version"`
+	MaxVersion       string   `config:"max_version"`
+	CurveTypes       []string `config:"curve_types"`
 }
 
 func (c *TLSConfig) Validate() error {
@@ -53,10 +99,23 @@ func (c *TLSConfig) Validate() error {
 		return ErrKeyNoCertificate
 	}
 
-	if _, err := parseTLSVersion(c.MinVersion); err != nil {
+	minVersion, err := parseTLSVersion(c.MinVersion)
+	if err != nil {
+		return err
+	}
+	maxVersion, err := parseTLSVersion(c.MaxVersion)
+	if err != nil {
+		return err
+	}
+	if minVersion != 0 && maxVersion != 0 && minVersion > maxVersion {
+		return ErrInvalidTLSVersionRange
+	}
+
+	if _, err := c.verificationMode(); err != nil {
 		return err
 	}
-	if _, err := parseTLSVersion(c.MaxVersion); err != nil {
+
+	if _, err := parsePins(c.CASha256); err != nil {
 		return err
 	}
 
@@ -71,10 +130,28 @@ func (c *TLSConfig) Validate() error {
 	return nil
 }
 
+// verificationMode parses the configured verification mode. The insecure
+// setting is an alias for the verification mode none.
+func (c *TLSConfig) verificationMode() (verificationMode, error) {
+	mode, err := parseVerificationMode(c.VerificationMode)
+	if err != nil {
+		return 0, err
+	}
+
+	if c.Insecure {
+		if c.VerificationMode != "" && mode != verifyNone {
+			return 0, ErrInsecureVerificationMode
+		}
+		mode = verifyNone
+	}
+	return mode, nil
+}
+
 // LoadTLSConfig will load a certificate from config with all TLS based keys
 // defined. If Certificate and CertificateKey are configured, client authentication
 // will be configured. If no CAs are configured, the host CA will be used by go
-// built-in TLS support.
+// built-in TLS support. If pins are configured, the certificate chain presented
+// by the server must contain a certificate with a matching public key.
 func LoadTLSConfig(config *TLSConfig) (*tls.Config, error) {
 	if config == nil {
 		return nil, nil
@@ -93,9 +170,9 @@ func LoadTLSConfig(config *TLSConfig) (*tls.Config, error) {
 	case !hasCertificate && hasKey:
 		return nil, ErrKeyNoCertificate
 	case hasCertificate && hasKey:
-		cert, err := tls.LoadX509KeyPair(certificate, key)
+		cert, err := loadCertificate(certificate, key, config.Passphrase)
 		if err != nil {
-			logp.Critical("Failed loading client certificate", err)
+			logp.Critical("Failed loading client certificate: %v", err)
 			return nil, err
 		}
 		certs = []tls.Certificate{cert}
@@ -130,6 +207,19 @@ func LoadTLSConfig(config *TLSConfig) (*tls.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if maxVersion != 0 && minVersion > maxVersion {
+		return nil, ErrInvalidTLSVersionRange
+	}
+
+	mode, err := config.verificationMode()
+	if err != nil {
+		return nil, err
+	}
+
+	pins, err := parsePins(config.CASha256)
+	if err != nil {
+		return nil, err
+	}
 
 	cipherSuites, err := parseTLSCipherSuites(config.CipherSuites)
 	if err != nil {
@@ -146,13 +236,160 @@ func LoadTLSConfig(config *TLSConfig) (*tls.Config, error) {
 		MaxVersion:         maxVersion,
 		Certificates:       certs,
 		RootCAs:            roots,
-		InsecureSkipVerify: config.Insecure,
+		InsecureSkipVerify: mode != verifyFull,
 		CipherSuites:       cipherSuites,
 		CurvePreferences:   curveIDs,
 	}
+	if mode == verifyCertificate || len(pins) > 0 {
+		tlsConfig.VerifyPeerCertificate = makeVerifyPeerCertificate(mode, roots, pins)
+	}
 	return &tlsConfig, nil
 }
 
+// loadCertificate loads the client certificate and its key. Keys encrypted
+// with a passphrase are decrypted using the passphrase.
+func loadCertificate(certificate, key, passphrase string) (tls.Certificate, error) {
+	certPEM, err := ioutil.ReadFile(certificate)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	keyPEM, err = decryptKey(keyPEM, passphrase)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// decryptKey decrypts the first private key of a PEM file if it is encrypted,
+// and returns it PEM encoded again. Unencrypted keys are returned unchanged.
+func decryptKey(keyPEM []byte, passphrase string) ([]byte, error) {
+	for rest := keyPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return keyPEM, nil
+		}
+
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, errors.New("encrypted PKCS#8 keys are not supported, " +
+				"convert the key to the traditional PEM encryption")
+		}
+		if !x509.IsEncryptedPEMBlock(block) {
+			continue
+		}
+		if passphrase == "" {
+			return nil, ErrKeyPassphrase
+		}
+
+		der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt certificate key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+	}
+}
+
+// makeVerifyPeerCertificate returns the callback verifying the certificate
+// chain without the host name in the certificate mode, and checking the pins.
+// The certificate chain is verified by the TLS library in the full mode, and
+// not verified at all in the none mode, in which case the pins are checked
+// against the certificates presented by the server.
+func makeVerifyPeerCertificate(
+	mode verificationMode,
+	roots *x509.CertPool,
+	pins [][]byte,
+) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if mode != verifyFull {
+			certs := make([]*x509.Certificate, len(rawCerts))
+			for i, raw := range rawCerts {
+				cert, err := x509.ParseCertificate(raw)
+				if err != nil {
+					return err
+				}
+				certs[i] = cert
+			}
+			if len(certs) == 0 {
+				return errors.New("server did not present a certificate")
+			}
+
+			if mode == verifyNone {
+				verifiedChains = [][]*x509.Certificate{certs}
+			} else {
+				opts := x509.VerifyOptions{
+					Roots:         roots,
+					Intermediates: x509.NewCertPool(),
+				}
+				for _, cert := range certs[1:] {
+					opts.Intermediates.AddCert(cert)
+				}
+
+				var err error
+				verifiedChains, err = certs[0].Verify(opts)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(pins) == 0 {
+			return nil
+		}
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if matchesPin(cert, pins) {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+}
+
+// matchesPin reports if the SHA-256 hash of the public key of the certificate
+// is one of the pins.
+func matchesPin(cert *x509.Certificate, pins [][]byte) bool {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if bytes.Equal(hash[:], pin) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePins(pins []string) ([][]byte, error) {
+	var list [][]byte
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, ErrInvalidPin
+		}
+		list = append(list, hash)
+	}
+	return list, nil
+}
+
+func parseVerificationMode(s string) (verificationMode, error) {
+	modes := map[string]verificationMode{
+		"":            verifyFull,
+		"full":        verifyFull,
+		"certificate": verifyCertificate,
+		"none":        verifyNone,
+	}
+
+	mode, ok := modes[s]
+	if !ok {
+		return 0, ErrInvalidVerificationMode
+	}
+	return mode, nil
+}
+
 func parseTLSVersion(s string) (uint16, error) {
 	versions := map[string]uint16{
 		"":        0,
@@ -160,6 +397,7 @@ func parseTLSVersion(s string) (uint16, error) {
 		"1.0":     tls.VersionTLS10,
 		"1.1":     tls.VersionTLS11,
 		"1.2":     tls.VersionTLS12,
+		"1.3":     tls.VersionTLS13,
 	}
 
 	id, ok := versions[s]
@@ -202,9 +440,10 @@ func parseTLSCipherSuites(names []string) ([]uint16, error) {
 
 func parseCurveTypes(names []string) ([]tls.CurveID, error) {
 	curveIDs := map[string]tls.CurveID{
-		"P-256": tls.CurveP256,
-		"P-384": tls.CurveP384,
-		"P-521": tls.CurveP521,
+		"P-256":  tls.CurveP256,
+		"P-384":  tls.CurveP384,
+		"P-521":  tls.CurveP521,
+		"X25519": tls.X25519,
 	}
 
 	var list []tls.CurveID
@@ -1,9 +1,21 @@
+//go:build !integration
 // +build !integration
 
 package outputs
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/elastic/beats/libbeat/common"
@@ -175,3 +187,164 @@ func TestUnknownCurveType(t *testing.T) {
 	})
 	assert.NotNil(t, err)
 }
+
+func TestInsecureVerificationModeConflict(t *testing.T) {
+	_, err := load(`
+    insecure: true
+    verification_mode: certificate
+  `)
+	assert.Error(t, err)
+
+	_, err = load(`
+    insecure: true
+    verification_mode: none
+  `)
+	assert.NoError(t, err)
+}
+
+func TestInvalidVersionRangeFail(t *testing.T) {
+	_, err := LoadTLSConfig(&TLSConfig{
+		MinVersion: "1.2",
+		MaxVersion: "1.1",
+	})
+	assert.Equal(t, ErrInvalidTLSVersionRange, err)
+}
+
+func TestUnknownVerificationModeFail(t *testing.T) {
+	_, err := LoadTLSConfig(&TLSConfig{
+		VerificationMode: "strict",
+	})
+	assert.Equal(t, ErrInvalidVerificationMode, err)
+}
+
+func TestInvalidPinFail(t *testing.T) {
+	_, err := LoadTLSConfig(&TLSConfig{
+		CASha256: []string{"c2hvcnQ="},
+	})
+	assert.Equal(t, ErrInvalidPin, err)
+}
+
+func TestKeyPassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPEM, err := ioutil.ReadFile("logstash/ca_test.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	encrypted, err := x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes,
+		[]byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(dir, "encrypted.key")
+	if err := ioutil.WriteFile(key, pem.EncodeToMemory(encrypted), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		passphrase string
+		ok         bool
+	}{
+		{"secret", true},
+		{"wrong", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		cfg, err := LoadTLSConfig(&TLSConfig{
+			Certificate:    "logstash/ca_test.pem",
+			CertificateKey: key,
+			Passphrase:     test.passphrase,
+		})
+		if test.ok {
+			if assert.NoError(t, err, test.passphrase) {
+				assert.Len(t, cfg.Certificates, 1)
+			}
+		} else {
+			assert.Error(t, err, test.passphrase)
+		}
+	}
+
+	// the passphrase is ignored for unencrypted keys
+	_, err = LoadTLSConfig(&TLSConfig{
+		Certificate:    "logstash/ca_test.pem",
+		CertificateKey: "logstash/ca_test.key",
+		Passphrase:     "secret",
+	})
+	assert.NoError(t, err)
+}
+
+func TestVerificationModesAndPins(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert := server.Certificate()
+	ca := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := ioutil.WriteFile(ca, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name       string
+		config     TLSConfig
+		serverName string
+		ok         bool
+	}{
+		{"full", TLSConfig{CAs: []string{ca}}, "", true},
+		{"full wrong host", TLSConfig{CAs: []string{ca}}, "wrong.example", false},
+		{"full unknown CA", TLSConfig{}, "", false},
+		{"certificate wrong host",
+			TLSConfig{CAs: []string{ca}, VerificationMode: "certificate"}, "wrong.example", true},
+		{"certificate unknown CA",
+			TLSConfig{VerificationMode: "certificate"}, "", false},
+		{"none", TLSConfig{VerificationMode: "none"}, "wrong.example", true},
+		{"insecure", TLSConfig{Insecure: true}, "wrong.example", true},
+		{"full pin",
+			TLSConfig{CAs: []string{ca}, CASha256: []string{otherPin, pin}}, "", true},
+		{"full pin mismatch",
+			TLSConfig{CAs: []string{ca}, CASha256: []string{otherPin}}, "", false},
+		{"certificate pin mismatch",
+			TLSConfig{CAs: []string{ca}, VerificationMode: "certificate", CASha256: []string{otherPin}},
+			"wrong.example", false},
+		{"none pin",
+			TLSConfig{VerificationMode: "none", CASha256: []string{pin}}, "", true},
+		{"none pin mismatch",
+			TLSConfig{VerificationMode: "none", CASha256: []string{otherPin}}, "", false},
+	}
+
+	for _, test := range tests {
+		tlsConfig, err := LoadTLSConfig(&test.config)
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+		tlsConfig.ServerName = test.serverName
+
+		conn, err := tls.Dial("tcp", server.Listener.Addr().String(), tlsConfig)
+		if test.ok {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.Error(t, err, test.name)
+		}
+		if err == nil {
+			conn.Close()
+		}
+	}
+}
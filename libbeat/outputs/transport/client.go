@@ -18,22 +18,30 @@ type Client struct {
 }
 
 type Config struct {
-	Proxy   *ProxyConfig
-	TLS     *tls.Config
-	Timeout time.Duration
-	Stats   *IOStats
+	Proxy         *ProxyConfig
+	ProxyProtocol int // PROXY protocol version, 0 if disabled
+	TLS           *tls.Config
+	Timeout       time.Duration
+	Stats         *IOStats
+	Resolver      Resolver  // resolves host names, nil for the system resolver
+	Throttle      *Throttle // limits the bytes written, nil if unlimited
 }
 
 func MakeDialer(c *Config) (Dialer, error) {
 	var err error
-	dialer := NetDialer(c.Timeout)
+	dialer := ResolverNetDialer(c.Timeout, c.Resolver)
 	dialer, err = ProxyDialer(c.Proxy, dialer)
 	if err != nil {
 		return nil, err
 	}
+	dialer, err = ProxyProtocolDialer(c.ProxyProtocol, dialer)
+	if err != nil {
+		return nil, err
+	}
 	if c.Stats != nil {
 		dialer = StatsDialer(dialer, c.Stats)
 	}
+	dialer = ThrottleDialer(c.Throttle, dialer)
 	dialer = TLSDialer(c.TLS, c.Timeout, dialer)
 	return dialer, nil
 }
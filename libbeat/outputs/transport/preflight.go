@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"net/url"
+
+	"github.com/elastic/beats/libbeat/outputs/preflight"
+)
+
+// PreflightEndpoints returns the endpoints for the preflight checks of the
+// hosts. If a proxy is configured, the connection to the proxy is checked
+// instead of the connections to the hosts.
+func PreflightEndpoints(c *Config, hosts []string, defaultPort int) []preflight.Endpoint {
+	var proxyAddress string
+	if c.Proxy != nil && c.Proxy.URL != "" {
+		u, err := url.Parse(c.Proxy.URL)
+		if err != nil {
+			return nil
+		}
+		proxyAddress = ProxyAddress(u)
+	}
+
+	endpoints := make([]preflight.Endpoint, len(hosts))
+	for i, host := range hosts {
+		address := fullAddress(host, defaultPort)
+		endpoints[i] = preflight.Endpoint{Name: address, Address: address, TLS: c.TLS}
+		if c.Resolver != nil {
+			endpoints[i].LookupHost = c.Resolver.LookupHost
+		}
+		if proxyAddress != "" {
+			endpoints[i].Address = proxyAddress
+			endpoints[i].TLS = nil
+			endpoints[i].LookupHost = nil
+		}
+	}
+	return endpoints
+}
@@ -1,21 +1,25 @@
 package transport
 
 import (
+	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/elastic/beats/libbeat/logp"
 	"golang.org/x/net/proxy"
 )
 
 // ProxyConfig holds the configuration information required to proxy
-// connections through a SOCKS5 proxy server.
+// connections through a SOCKS5 or HTTP proxy server.
 type ProxyConfig struct {
-	// URL of the SOCKS proxy. Scheme must be socks5. Username and password can be
+	// URL of the proxy. Scheme must be socks5 or http, the latter tunneling
+	// the connections with HTTP CONNECT. Username and password can be
 	// embedded in the URL.
 	URL string `config:"proxy_url"`
 
-	// Resolve names locally instead of on the SOCKS server.
+	// Resolve names locally instead of on the proxy server.
 	LocalResolve bool `config:"proxy_use_local_resolver"`
 }
 
@@ -28,7 +32,7 @@ func (c *ProxyConfig) Validate() error {
 	if err != nil {
 		return err
 	}
-	if _, err := proxy.FromURL(url, nil); err != nil {
+	if _, err := proxyFromURL(url, nil); err != nil {
 		return err
 	}
 
@@ -45,7 +49,7 @@ func ProxyDialer(config *ProxyConfig, forward Dialer) (Dialer, error) {
 		return nil, err
 	}
 
-	if _, err := proxy.FromURL(url, nil); err != nil {
+	if _, err := proxyFromURL(url, nil); err != nil {
 		return nil, err
 	}
 
@@ -67,15 +71,90 @@ func ProxyDialer(config *ProxyConfig, forward Dialer) (Dialer, error) {
 			}
 		} else {
 			// Do not resolve the address locally. It will be resolved on the
-			// SOCKS server. The beat will have no control over the randomization
+			// proxy server. The beat will have no control over the randomization
 			// of the IP used when multiple IPs are returned by DNS.
 			addresses = []string{host}
 		}
 
-		dialer, err := proxy.FromURL(url, forward)
+		dialer, err := proxyFromURL(url, forward)
 		if err != nil {
 			return nil, err
 		}
 		return dialWith(dialer, network, host, addresses, port)
 	}), nil
 }
+
+// proxyFromURL returns the dialer connecting through the proxy server of the
+// URL, dialing the proxy server with forward.
+func proxyFromURL(u *url.URL, forward Dialer) (Dialer, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL '%v' has no host", u)
+	}
+
+	switch u.Scheme {
+	case "http":
+		return newHTTPConnectDialer(u, forward), nil
+	case "socks5":
+		withPort := *u
+		withPort.Host = ProxyAddress(u)
+		return proxy.FromURL(&withPort, forward)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme '%v', must be socks5 or http", u.Scheme)
+}
+
+// ProxyAddress returns the host and port of the proxy server, using the
+// default port of the scheme if the URL has no port.
+func ProxyAddress(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Host, port)
+}
+
+// ParseProxyURL parses the proxy_url setting of the HTTP based outputs.
+// Values without scheme, like "proxy:3128", are parsed as HTTP proxies.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	url, err := url.Parse(raw)
+	if err == nil && strings.HasPrefix(url.Scheme, "http") {
+		return url, err
+	}
+	if err == nil && url.Scheme == "socks5" {
+		_, err = proxyFromURL(url, nil)
+		return url, err
+	}
+
+	// Proxy was bogus. Try prepending "http://" to it and
+	// see if that parses correctly.
+	return url.Parse("http://" + raw)
+}
+
+// HTTPProxy returns the proxy function and the dialer of the http.Transport
+// of HTTP based outputs. HTTP and HTTPS proxies are handled by net/http,
+// which authenticates with the credentials of the URL. SOCKS5 proxies are
+// handled by the dialer. Without proxy URL, the proxy environment variables
+// are used.
+func HTTPProxy(
+	proxyURL *url.URL,
+	dialer Dialer,
+) (func(*http.Request) (*url.URL, error), Dialer, error) {
+	if proxyURL == nil {
+		return http.ProxyFromEnvironment, dialer, nil
+	}
+	if proxyURL.Scheme != "socks5" {
+		return http.ProxyURL(proxyURL), dialer, nil
+	}
+
+	dialer, err := ProxyDialer(&ProxyConfig{URL: proxyURL.String()}, dialer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, dialer, nil
+}
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// httpConnectDialer tunnels tcp connections through an HTTP proxy server with
+// the CONNECT method.
+type httpConnectDialer struct {
+	address string
+	auth    string // value of the Proxy-Authorization header, if any
+	forward Dialer
+}
+
+func newHTTPConnectDialer(u *url.URL, forward Dialer) *httpConnectDialer {
+	d := &httpConnectDialer{
+		address: ProxyAddress(u),
+		forward: forward,
+	}
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := u.User.Username() + ":" + password
+		d.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	return d
+}
+
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network type %v for HTTP proxy", network)
+	}
+
+	conn, err := d.forward.Dial("tcp", d.address)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.auth != "" {
+		req.Header.Set("Proxy-Authorization", d.auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %v refused connection to %v: %v",
+			d.address, address, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		// the server already sent data through the tunnel
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads the data buffered while reading the proxy response
+// before reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Signature starting a PROXY protocol version 2 header.
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// Address families and commands of the PROXY protocol version 2.
+const (
+	proxyV2Local   = 0x20
+	proxyV2Proxy   = 0x21
+	proxyV2TCPv4   = 0x11
+	proxyV2TCPv6   = 0x21
+	proxyV2Unspec  = 0x00
+	proxyV2AddrLen = 2*net.IPv6len + 4
+)
+
+// ProxyProtocolDialer sends a PROXY protocol header of the given version
+// (1 or 2) on each new connection, such that servers behind a load
+// balancer (e.g. HAProxy or AWS NLB) see the source address of the beat.
+// If version is 0, no header is sent. The header must be sent before any
+// other data, so the dialer must be applied before the TLS dialer.
+func ProxyProtocolDialer(version int, forward Dialer) (Dialer, error) {
+	var header func(src, dst net.Addr) []byte
+	switch version {
+	case 0:
+		return forward, nil
+	case 1:
+		header = proxyProtocolV1Header
+	case 2:
+		header = proxyProtocolV2Header
+	default:
+		return nil, fmt.Errorf("unsupported PROXY protocol version %v", version)
+	}
+
+	return DialerFunc(func(network, address string) (net.Conn, error) {
+		conn, err := forward.Dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := conn.Write(header(conn.LocalAddr(), conn.RemoteAddr())); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}), nil
+}
+
+// proxyProtocolV1Header creates a human readable version 1 header, e.g.
+// "PROXY TCP4 192.168.0.1 192.168.0.11 56324 5044\r\n".
+func proxyProtocolV1Header(src, dst net.Addr) []byte {
+	srcTCP, ok1 := src.(*net.TCPAddr)
+	dstTCP, ok2 := dst.(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		return []byte("PROXY UNKNOWN\r\n")
+	}
+
+	family := "TCP4"
+	srcIP, dstIP := srcTCP.IP.To4(), dstTCP.IP.To4()
+	if srcIP == nil || dstIP == nil {
+		family = "TCP6"
+		srcIP, dstIP = srcTCP.IP.To16(), dstTCP.IP.To16()
+	}
+
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n",
+		family, srcIP, dstIP, srcTCP.Port, dstTCP.Port))
+}
+
+// proxyProtocolV2Header creates a binary version 2 header.
+func proxyProtocolV2Header(src, dst net.Addr) []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, 16+proxyV2AddrLen))
+	buf.Write(proxyProtocolV2Signature)
+
+	srcTCP, ok1 := src.(*net.TCPAddr)
+	dstTCP, ok2 := dst.(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		buf.Write([]byte{proxyV2Local, proxyV2Unspec, 0, 0})
+		return buf.Bytes()
+	}
+
+	family := byte(proxyV2TCPv4)
+	srcIP, dstIP := srcTCP.IP.To4(), dstTCP.IP.To4()
+	if srcIP == nil || dstIP == nil {
+		family = proxyV2TCPv6
+		srcIP, dstIP = srcTCP.IP.To16(), dstTCP.IP.To16()
+	}
+
+	buf.Write([]byte{proxyV2Proxy, family})
+	binary.Write(buf, binary.BigEndian, uint16(2*len(srcIP)+4))
+	buf.Write(srcIP)
+	buf.Write(dstIP)
+	binary.Write(buf, binary.BigEndian, uint16(srcTCP.Port))
+	binary.Write(buf, binary.BigEndian, uint16(dstTCP.Port))
+	return buf.Bytes()
+}
@@ -0,0 +1,114 @@
+// +build !integration
+
+package transport
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyProtocolV1Header(t *testing.T) {
+	tests := []struct {
+		src, dst net.Addr
+		expected string
+	}{
+		{
+			&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			&net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 5044},
+			"PROXY TCP4 192.168.0.1 192.168.0.11 56324 5044\r\n",
+		},
+		{
+			&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324},
+			&net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 5044},
+			"PROXY TCP6 2001:db8::1 2001:db8::2 56324 5044\r\n",
+		},
+		{
+			&net.UnixAddr{Name: "/tmp/a", Net: "unix"},
+			&net.UnixAddr{Name: "/tmp/b", Net: "unix"},
+			"PROXY UNKNOWN\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, string(proxyProtocolV1Header(test.src, test.dst)))
+	}
+}
+
+func TestProxyProtocolV2Header(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 0x1234}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 5044}
+
+	expected := append([]byte{}, proxyProtocolV2Signature...)
+	expected = append(expected,
+		0x21, 0x11, 0, 12,
+		192, 168, 0, 1,
+		192, 168, 0, 11,
+		0x12, 0x34,
+		0x13, 0xb4,
+	)
+	assert.Equal(t, expected, proxyProtocolV2Header(src, dst))
+
+	src6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1}
+	header := proxyProtocolV2Header(src6, dst)
+	assert.Equal(t, []byte{0x21, 0x21, 0, 36}, header[12:16])
+	assert.Len(t, header, 16+36)
+
+	unix := &net.UnixAddr{Name: "/tmp/a", Net: "unix"}
+	header = proxyProtocolV2Header(unix, unix)
+	assert.Equal(t, []byte{0x20, 0x00, 0, 0}, header[12:])
+}
+
+func TestProxyProtocolDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	dialer, err := ProxyProtocolDialer(1, NetDialer(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialer.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("hello\n"))
+
+	local := conn.LocalAddr().(*net.TCPAddr)
+	expected := proxyProtocolV1Header(local, l.Addr())
+	select {
+	case line := <-received:
+		assert.Equal(t, string(expected), line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for PROXY header")
+	}
+}
+
+func TestProxyProtocolDialerVersions(t *testing.T) {
+	forward := NetDialer(time.Second)
+
+	d, err := ProxyProtocolDialer(0, forward)
+	assert.NoError(t, err)
+	assert.NotNil(t, d)
+
+	_, err = ProxyProtocolDialer(3, forward)
+	assert.Error(t, err)
+}
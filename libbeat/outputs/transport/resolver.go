@@ -0,0 +1,269 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Resolver resolves host names to IP addresses.
+type Resolver interface {
+	LookupHost(host string) ([]string, error)
+}
+
+// DNSConfig configures the resolution of the host names of an output.
+type DNSConfig struct {
+	// Timeout of a lookup, 0 for no timeout of the system resolver. Queries
+	// to the nameservers time out after 2s by default.
+	Timeout time.Duration `config:"timeout" validate:"min=0"`
+
+	// Family is the preferred IP family: any, ipv4 or ipv6. If no address of
+	// the preferred family is found, the addresses of the other family are
+	// used.
+	Family string `config:"family"`
+
+	// Nameservers to query instead of the system resolver, as IP[:port].
+	// The results of the nameservers are cached for the TTL of the records.
+	Nameservers []string `config:"nameservers"`
+
+	// MinTTL is the minimum time the results of the nameservers are cached.
+	MinTTL time.Duration `config:"min_ttl" validate:"min=0"`
+}
+
+const (
+	familyAny  = "any"
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+
+	defaultNameserverPort    = "53"
+	defaultNameserverTimeout = 2 * time.Second
+)
+
+var errNoAddresses = errors.New("no addresses found")
+
+func (c *DNSConfig) Validate() error {
+	switch c.Family {
+	case "", familyAny, familyIPv4, familyIPv6:
+	default:
+		return fmt.Errorf("unsupported dns.family '%v'", c.Family)
+	}
+
+	for _, ns := range c.Nameservers {
+		host, _, err := net.SplitHostPort(nameserverAddress(ns))
+		if err != nil {
+			return fmt.Errorf("invalid nameserver '%v': %v", ns, err)
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid nameserver '%v': not an IP address", ns)
+		}
+	}
+	return nil
+}
+
+// NewResolver creates the resolver configured by config. It returns nil if
+// the system resolver is used without changes, such that the host names are
+// resolved like before on every connection attempt.
+func NewResolver(config DNSConfig) Resolver {
+	if config.Timeout == 0 && len(config.Nameservers) == 0 &&
+		(config.Family == "" || config.Family == familyAny) {
+		return nil
+	}
+
+	r := &resolver{config: config}
+	if len(config.Nameservers) > 0 {
+		for _, ns := range config.Nameservers {
+			r.nameservers = append(r.nameservers, nameserverAddress(ns))
+		}
+		r.cache = map[string]resolverEntry{}
+		logp.Info("Resolving hosts with the nameservers %v", r.nameservers)
+	}
+	return r
+}
+
+func nameserverAddress(ns string) string {
+	if _, _, err := net.SplitHostPort(ns); err == nil {
+		return ns
+	}
+	return net.JoinHostPort(ns, defaultNameserverPort)
+}
+
+type resolver struct {
+	config      DNSConfig
+	nameservers []string
+
+	mutex sync.Mutex
+	cache map[string]resolverEntry
+}
+
+type resolverEntry struct {
+	addresses []string
+	expires   time.Time
+}
+
+// LookupHost resolves host with the nameservers if configured, or with the
+// system resolver otherwise. The addresses are filtered by the preferred
+// family.
+func (r *resolver) LookupHost(host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	var addresses []string
+	var err error
+	if len(r.nameservers) > 0 {
+		addresses, err = r.lookupCached(host)
+	} else {
+		addresses, err = r.lookupSystem(host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return preferFamily(addresses, r.config.Family), nil
+}
+
+func (r *resolver) lookupSystem(host string) ([]string, error) {
+	if r.config.Timeout <= 0 {
+		return net.LookupHost(host)
+	}
+
+	type lookup struct {
+		addresses []string
+		err       error
+	}
+
+	ch := make(chan lookup, 1)
+	go func() {
+		addresses, err := net.LookupHost(host)
+		ch <- lookup{addresses, err}
+	}()
+
+	select {
+	case l := <-ch:
+		return l.addresses, l.err
+	case <-time.After(r.config.Timeout):
+		return nil, fmt.Errorf("lookup %v timed out after %v", host, r.config.Timeout)
+	}
+}
+
+func (r *resolver) lookupCached(host string) ([]string, error) {
+	now := time.Now()
+
+	r.mutex.Lock()
+	entry, found := r.cache[host]
+	r.mutex.Unlock()
+	if found && now.Before(entry.expires) {
+		return entry.addresses, nil
+	}
+
+	addresses, ttl, err := r.lookupNameservers(host)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < r.config.MinTTL {
+		ttl = r.config.MinTTL
+	}
+	debugf("resolved %v to %v (ttl=%v)", host, addresses, ttl)
+
+	r.mutex.Lock()
+	r.cache[host] = resolverEntry{addresses: addresses, expires: now.Add(ttl)}
+	r.mutex.Unlock()
+	return addresses, nil
+}
+
+// lookupNameservers queries the A and AAAA records of host. The nameservers
+// are queried in order until one answers. The returned TTL is the lowest TTL of
+// the records.
+func (r *resolver) lookupNameservers(host string) ([]string, time.Duration, error) {
+	qtypes := []uint16{dns.TypeA, dns.TypeAAAA}
+
+	timeout := r.config.Timeout
+	if timeout <= 0 {
+		timeout = defaultNameserverTimeout
+	}
+	client := &dns.Client{Timeout: timeout}
+
+	var err error
+	for _, ns := range r.nameservers {
+		var addresses []string
+		var ttl time.Duration
+		addresses, ttl, err = queryNameserver(client, ns, host, qtypes)
+		if err == nil {
+			return addresses, ttl, nil
+		}
+		debugf("lookup %v on %v failed: %v", host, ns, err)
+	}
+	return nil, 0, fmt.Errorf("lookup %v: %v", host, err)
+}
+
+func queryNameserver(
+	client *dns.Client,
+	ns, host string,
+	qtypes []uint16,
+) ([]string, time.Duration, error) {
+	var addresses []string
+	var minTTL uint32
+	for _, qtype := range qtypes {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(host), qtype)
+
+		resp, _, err := client.Exchange(m, ns)
+		if err != nil {
+			return nil, 0, err
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			return nil, 0, fmt.Errorf("nameserver %v returned %v", ns,
+				dns.RcodeToString[resp.Rcode])
+		}
+
+		for _, rr := range resp.Answer {
+			var ip net.IP
+			switch rr := rr.(type) {
+			case *dns.A:
+				ip = rr.A
+			case *dns.AAAA:
+				ip = rr.AAAA
+			default:
+				continue
+			}
+
+			addresses = append(addresses, ip.String())
+			if ttl := rr.Header().Ttl; len(addresses) == 1 || ttl < minTTL {
+				minTTL = ttl
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, 0, errNoAddresses
+	}
+	return addresses, time.Duration(minTTL) * time.Second, nil
+}
+
+// preferFamily returns the addresses of the preferred family, or all
+// addresses if none of the addresses is of the preferred family.
+func preferFamily(addresses []string, family string) []string {
+	if family == "" || family == familyAny {
+		return addresses
+	}
+
+	var preferred []string
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if isIPv4 := ip.To4() != nil; isIPv4 == (family == familyIPv4) {
+			preferred = append(preferred, address)
+		}
+	}
+	if len(preferred) == 0 {
+		return addresses
+	}
+	return preferred
+}
@@ -0,0 +1,194 @@
+// +build !integration
+
+package transport
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSConfigValidate(t *testing.T) {
+	valid := []DNSConfig{
+		{},
+		{Family: "any"},
+		{Family: "ipv4"},
+		{Family: "ipv6", Nameservers: []string{"10.0.0.1", "10.0.0.2:5353", "[::1]:53"}},
+	}
+	for _, config := range valid {
+		assert.NoError(t, config.Validate(), "%v", config)
+	}
+
+	invalid := []DNSConfig{
+		{Family: "ipv5"},
+		{Nameservers: []string{"10.0.0.1:53:53"}},
+		{Nameservers: []string{"ns.example.com"}},
+	}
+	for _, config := range invalid {
+		assert.Error(t, config.Validate(), "%v", config)
+	}
+}
+
+func TestNewResolverDefault(t *testing.T) {
+	assert.Nil(t, NewResolver(DNSConfig{}))
+	assert.Nil(t, NewResolver(DNSConfig{Family: "any"}))
+	assert.NotNil(t, NewResolver(DNSConfig{Family: "ipv6"}))
+	assert.NotNil(t, NewResolver(DNSConfig{Timeout: time.Second}))
+}
+
+func TestPreferFamily(t *testing.T) {
+	addresses := []string{"10.0.0.1", "::1", "10.0.0.2"}
+
+	assert.Equal(t, addresses, preferFamily(addresses, ""))
+	assert.Equal(t, addresses, preferFamily(addresses, "any"))
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, preferFamily(addresses, "ipv4"))
+	assert.Equal(t, []string{"::1"}, preferFamily(addresses, "ipv6"))
+
+	// fall back to the other family
+	assert.Equal(t, []string{"10.0.0.1"}, preferFamily([]string{"10.0.0.1"}, "ipv6"))
+}
+
+func TestResolverIPAddress(t *testing.T) {
+	r := NewResolver(DNSConfig{Nameservers: []string{"127.0.0.1:1"}})
+	addresses, err := r.LookupHost("10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+}
+
+// testNameserver answers A queries with address and counts the queries.
+type testNameserver struct {
+	server *dns.Server
+
+	mutex   sync.Mutex
+	address string
+	ttl     uint32
+	queries int
+}
+
+func newTestNameserver(t *testing.T, address string, ttl uint32) *testNameserver {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := &testNameserver{address: address, ttl: ttl}
+	started := make(chan struct{})
+	ns.server = &dns.Server{
+		PacketConn:        conn,
+		Handler:           dns.HandlerFunc(ns.serve),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go ns.server.ActivateAndServe()
+	<-started
+	return ns
+}
+
+func (ns *testNameserver) serve(w dns.ResponseWriter, req *dns.Msg) {
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	if req.Question[0].Qtype == dns.TypeA {
+		ns.queries++
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{
+				Name:   req.Question[0].Name,
+				Rrtype: dns.TypeA,
+				Class:  dns.ClassINET,
+				Ttl:    ns.ttl,
+			},
+			A: net.ParseIP(ns.address),
+		})
+	}
+	w.WriteMsg(resp)
+}
+
+func (ns *testNameserver) Addr() string {
+	return ns.server.PacketConn.LocalAddr().String()
+}
+
+func (ns *testNameserver) setAddress(address string) {
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
+	ns.address = address
+}
+
+func (ns *testNameserver) Queries() int {
+	ns.mutex.Lock()
+	defer ns.mutex.Unlock()
+	return ns.queries
+}
+
+func TestResolverNameservers(t *testing.T) {
+	ns := newTestNameserver(t, "10.0.0.1", 3600)
+	defer ns.server.Shutdown()
+
+	r := NewResolver(DNSConfig{
+		Timeout:     time.Second,
+		Nameservers: []string{ns.Addr()},
+	})
+
+	for i := 0; i < 3; i++ {
+		addresses, err := r.LookupHost("logstash.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, addresses)
+	}
+
+	// the result is cached for the TTL of the record
+	assert.Equal(t, 1, ns.Queries())
+}
+
+// Verify that the host is resolved again once the TTL of the record expired.
+func TestResolverNameserversTTL(t *testing.T) {
+	ns := newTestNameserver(t, "10.0.0.1", 0)
+	defer ns.server.Shutdown()
+
+	r := NewResolver(DNSConfig{
+		Timeout:     time.Second,
+		Nameservers: []string{ns.Addr()},
+		MinTTL:      50 * time.Millisecond,
+	})
+
+	addresses, err := r.LookupHost("logstash.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+
+	ns.setAddress("10.0.0.2")
+	addresses, err = r.LookupHost("logstash.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+
+	time.Sleep(100 * time.Millisecond)
+	addresses, err = r.LookupHost("logstash.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2"}, addresses)
+	assert.Equal(t, 2, ns.Queries())
+}
+
+// Verify that the next nameserver is queried if a nameserver does not answer.
+func TestResolverNameserversFallback(t *testing.T) {
+	ns := newTestNameserver(t, "10.0.0.1", 3600)
+	defer ns.server.Shutdown()
+
+	// nothing answers on the port of the closed connection
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unavailable := conn.LocalAddr().String()
+	conn.Close()
+
+	r := NewResolver(DNSConfig{
+		Timeout:     200 * time.Millisecond,
+		Nameservers: []string{unavailable, ns.Addr()},
+	})
+
+	addresses, err := r.LookupHost("logstash.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, addresses)
+}
@@ -9,6 +9,17 @@ import (
 )
 
 func NetDialer(timeout time.Duration) Dialer {
+	return ResolverNetDialer(timeout, nil)
+}
+
+// ResolverNetDialer returns a dialer resolving host names with r. The system
+// resolver is used if r is nil.
+func ResolverNetDialer(timeout time.Duration, r Resolver) Dialer {
+	lookupHost := net.LookupHost
+	if r != nil {
+		lookupHost = r.LookupHost
+	}
+
 	return DialerFunc(func(network, address string) (net.Conn, error) {
 		switch network {
 		case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
@@ -21,7 +32,7 @@ func NetDialer(timeout time.Duration) Dialer {
 			return nil, err
 		}
 
-		addresses, err := net.LookupHost(host)
+		addresses, err := lookupHost(host)
 		if err != nil {
 			logp.Warn(`DNS lookup failure "%s": %v`, host, err)
 			return nil, err
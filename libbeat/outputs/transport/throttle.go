@@ -0,0 +1,120 @@
+package transport
+
+import (
+	"expvar"
+	"net"
+	"sync"
+	"time"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	throttledWrites = expvar.NewInt("libbeat.outputs.throttle.throttled_writes")
+	throttleWaitMs  = expvar.NewInt("libbeat.outputs.throttle.wait_ms")
+)
+
+// Throttle limits the number of bytes per second written by all connections of
+// an output. Writes exceeding the limit are delayed.
+type Throttle struct {
+	mutex  sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewThrottle creates a throttle limiting the connections to bytesPerSecond,
+// or returns nil if bytesPerSecond is 0. Up to one second of bandwidth can be
+// written in a burst.
+func NewThrottle(bytesPerSecond int) *Throttle {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Throttle{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// ThrottleDialer returns a dialer whose connections are limited by t. d is
+// returned if t is nil.
+func ThrottleDialer(t *Throttle, d Dialer) Dialer {
+	if t == nil {
+		return d
+	}
+	return ConnWrapper(d, func(c net.Conn) net.Conn {
+		return &throttledConn{c, t}
+	})
+}
+
+// reserve takes n bytes from the bucket and returns the time to wait until
+// they can be written.
+func (t *Throttle) reserve(n int) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.rate {
+		t.tokens = t.rate
+	}
+	t.last = now
+
+	t.tokens -= float64(n)
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// wait blocks until n bytes can be written.
+func (t *Throttle) wait(n int) {
+	delay := t.reserve(n)
+	if delay <= 0 {
+		return
+	}
+
+	throttledWrites.Add(1)
+	throttleWaitMs.Add(int64(delay / time.Millisecond))
+	t.sleep(delay)
+}
+
+// chunkSize returns the maximum number of bytes written at once, such that
+// large payloads are spread over time instead of being written in bursts.
+func (t *Throttle) chunkSize() int {
+	size := int(t.rate / 10)
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+type throttledConn struct {
+	net.Conn
+	throttle *Throttle
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	chunk := c.throttle.chunkSize()
+
+	written := 0
+	for written < len(b) {
+		end := written + chunk
+		if end > len(b) {
+			end = len(b)
+		}
+
+		c.throttle.wait(end - written)
+		n, err := c.Conn.Write(b[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
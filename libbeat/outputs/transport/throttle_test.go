@@ -0,0 +1,85 @@
+// +build !integration
+
+package transport
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestThrottle returns a throttle whose clock is advanced by sleeping.
+func newTestThrottle(bytesPerSecond int) (*Throttle, *time.Duration) {
+	t := NewThrottle(bytesPerSecond)
+
+	var slept time.Duration
+	now := time.Now()
+	t.last = now
+	t.now = func() time.Time { return now }
+	t.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+	return t, &slept
+}
+
+type recordingConn struct {
+	net.Conn
+	writes []int
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.writes = append(c.writes, len(b))
+	return len(b), nil
+}
+
+func TestNewThrottleUnlimited(t *testing.T) {
+	assert.Nil(t, NewThrottle(0))
+
+	d := DialerFunc(func(network, address string) (net.Conn, error) {
+		return nil, nil
+	})
+	assert.NotNil(t, ThrottleDialer(nil, d))
+}
+
+func TestThrottleReserve(t *testing.T) {
+	throttle, _ := newTestThrottle(1000)
+
+	// the first second of bandwidth is available as burst
+	assert.Equal(t, time.Duration(0), throttle.reserve(1000))
+	assert.Equal(t, 500*time.Millisecond, throttle.reserve(500))
+}
+
+func TestThrottledConnWrite(t *testing.T) {
+	throttle, slept := newTestThrottle(1000)
+	conn := &recordingConn{}
+	c := &throttledConn{conn, throttle}
+
+	n, err := c.Write(make([]byte, 3000))
+	assert.NoError(t, err)
+	assert.Equal(t, 3000, n)
+
+	// writes are split into chunks of 1/10s of bandwidth
+	assert.Len(t, conn.writes, 30)
+	for _, size := range conn.writes {
+		assert.Equal(t, 100, size)
+	}
+
+	// the burst of 1000 bytes is written without delay
+	assert.Equal(t, 2*time.Second, *slept)
+}
+
+// Verify that connections of the same output share the limit.
+func TestThrottleShared(t *testing.T) {
+	throttle, slept := newTestThrottle(1000)
+	c1 := &throttledConn{&recordingConn{}, throttle}
+	c2 := &throttledConn{&recordingConn{}, throttle}
+
+	c1.Write(make([]byte, 1000))
+	assert.Equal(t, time.Duration(0), *slept)
+
+	c2.Write(make([]byte, 1000))
+	assert.Equal(t, time.Second, *slept)
+}
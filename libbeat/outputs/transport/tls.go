@@ -29,8 +29,13 @@ func TLSDialer(tlscfg *tls.Config, timeout time.Duration, forward Dialer) Dialer
 			return nil, err
 		}
 
-		tlscfg.ServerName = host
-		conn := tls.Client(socket, tlscfg)
+		// copy the config, the dialer can be used concurrently for
+		// different hosts
+		cfg := tlscfg.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+		conn := tls.Client(socket, cfg)
 		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
 			_ = conn.Close()
 			return nil, err
@@ -39,6 +44,10 @@ func TLSDialer(tlscfg *tls.Config, timeout time.Duration, forward Dialer) Dialer
 			_ = conn.Close()
 			return nil, err
 		}
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
 
 		return conn, nil
 	})
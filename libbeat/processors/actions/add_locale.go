@@ -0,0 +1,104 @@
+package actions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// AddLocale adds the timezone of the machine, or of a configured location,
+// to the beat.timezone field, so that local timestamps in log messages can be
+// converted. The timezone is reported as offset from UTC (e.g. -07:00) or as
+// abbreviation (e.g. PDT). It is computed for the time of the event, so that
+// daylight saving time changes are taken into account.
+type AddLocale struct {
+	config   addLocaleConfig
+	location *time.Location
+	Cond     *processors.Condition
+}
+
+type addLocaleConfig struct {
+	Format   string                      `config:"format"`
+	Timezone string                      `config:"timezone"`
+	Cond     *processors.ConditionConfig `config:"when"`
+}
+
+const (
+	localeFormatOffset       = "offset"
+	localeFormatAbbreviation = "abbreviation"
+)
+
+func init() {
+	if err := processors.RegisterPlugin("add_locale", newAddLocale); err != nil {
+		panic(err)
+	}
+}
+
+func newAddLocale(c common.Config) (processors.Processor, error) {
+	err := checkConfig("add_locale", c, "format", "timezone", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := addLocaleConfig{Format: localeFormatOffset}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the add_locale configuration: %s", err)
+	}
+
+	switch config.Format {
+	case localeFormatOffset, localeFormatAbbreviation:
+	default:
+		return nil, fmt.Errorf("invalid add_locale format '%s', expected %s or %s",
+			config.Format, localeFormatOffset, localeFormatAbbreviation)
+	}
+
+	location := time.Local
+	if config.Timezone != "" {
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid add_locale timezone: %v", err)
+		}
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddLocale{config: config, location: location, Cond: cond}, nil
+}
+
+func (l *AddLocale) Run(event common.MapStr) (common.MapStr, error) {
+	if l.Cond != nil && !l.Cond.Check(event) {
+		return event, nil
+	}
+
+	ts := time.Now()
+	if t, ok := event["@timestamp"].(common.Time); ok {
+		ts = time.Time(t)
+	}
+
+	_, err := event.Put("beat.timezone", l.timezone(ts))
+	return event, err
+}
+
+// timezone returns the timezone of the location at time ts in the configured
+// format.
+func (l *AddLocale) timezone(ts time.Time) string {
+	t := ts.In(l.location)
+	if l.config.Format == localeFormatAbbreviation {
+		name, _ := t.Zone()
+		return name
+	}
+	return t.Format("-07:00")
+}
+
+func (l *AddLocale) String() string {
+	str := fmt.Sprintf("add_locale=[format=%s, timezone=%s]", l.config.Format, l.location)
+	if l.Cond != nil {
+		str += ", condition=" + l.Cond.String()
+	}
+	return str
+}
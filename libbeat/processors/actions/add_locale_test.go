@@ -0,0 +1,71 @@
+// +build !integration
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAddLocale(t *testing.T, cfg map[string]interface{}) *AddLocale {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newAddLocale(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*AddLocale)
+}
+
+func TestAddLocaleOffset(t *testing.T) {
+	p := newTestAddLocale(t, map[string]interface{}{"timezone": "America/New_York"})
+
+	winter := time.Date(2016, 1, 15, 12, 0, 0, 0, time.UTC)
+	event, err := p.Run(common.MapStr{
+		"@timestamp": common.Time(winter),
+		"beat":       common.MapStr{"name": "test"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"name": "test", "timezone": "-05:00"}, event["beat"])
+
+	// daylight saving time is taken into account
+	summer := time.Date(2016, 7, 15, 12, 0, 0, 0, time.UTC)
+	event, err = p.Run(common.MapStr{"@timestamp": common.Time(summer)})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"timezone": "-04:00"}, event["beat"])
+}
+
+func TestAddLocaleAbbreviation(t *testing.T) {
+	p := newTestAddLocale(t, map[string]interface{}{
+		"format":   "abbreviation",
+		"timezone": "Europe/Berlin",
+	})
+
+	ts := time.Date(2016, 1, 15, 12, 0, 0, 0, time.UTC)
+	event, err := p.Run(common.MapStr{"@timestamp": common.Time(ts)})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"timezone": "CET"}, event["beat"])
+}
+
+func TestAddLocaleInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"format": "name"},
+		{"timezone": "Nowhere/Special"},
+		{"zone": "UTC"},
+	}
+
+	for _, cfg := range tests {
+		c, err := common.NewConfigFrom(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = newAddLocale(*c)
+		assert.Error(t, err, "config: %v", cfg)
+	}
+}
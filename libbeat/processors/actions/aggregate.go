@@ -0,0 +1,278 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// Aggregate groups events by a set of key fields and periodically emits one
+// summary event per key, containing the number of events, the sums of the
+// configured numeric fields and the first and last event timestamps seen in
+// the window.
+type Aggregate struct {
+	config aggregateConfig
+	Cond   *processors.Condition
+
+	mutex   sync.Mutex
+	buckets map[string]*aggregateBucket
+	out     func(common.MapStr)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type aggregateConfig struct {
+	Fields      []string                    `config:"fields" validate:"required"`
+	Sum         []string                    `config:"sum"`
+	Window      time.Duration               `config:"window" validate:"nonzero,positive"`
+	MaxKeys     int                         `config:"max_keys" validate:"min=1"`
+	Type        string                      `config:"type"`
+	PassThrough bool                        `config:"pass_through"`
+	Cond        *processors.ConditionConfig `config:"when"`
+}
+
+type aggregateBucket struct {
+	key   common.MapStr
+	count int64
+	sums  map[string]float64
+	first time.Time
+	last  time.Time
+}
+
+var defaultAggregateConfig = aggregateConfig{
+	Window:  1 * time.Minute,
+	MaxKeys: 10000,
+	Type:    "aggregate",
+}
+
+func init() {
+	if err := processors.RegisterPlugin("aggregate", newAggregate); err != nil {
+		panic(err)
+	}
+}
+
+func newAggregate(c common.Config) (processors.Processor, error) {
+	err := checkConfig("aggregate", c, "fields", "sum", "window", "max_keys",
+		"type", "pass_through", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultAggregateConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the aggregate configuration: %s", err)
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Aggregate{
+		config:  config,
+		Cond:    cond,
+		buckets: map[string]*aggregateBucket{},
+	}, nil
+}
+
+func (a *Aggregate) Run(event common.MapStr) (common.MapStr, error) {
+	if a.Cond != nil && !a.Cond.Check(event) {
+		return event, nil
+	}
+
+	key := common.MapStr{}
+	var keyParts []string
+	for _, field := range a.config.Fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			// events missing a key field are not aggregated
+			return event, nil
+		}
+		key.Put(field, v)
+		keyParts = append(keyParts, fmt.Sprint(v))
+	}
+	id := strings.Join(keyParts, "\x00")
+
+	ts := time.Now()
+	if t, ok := event["@timestamp"].(common.Time); ok {
+		ts = time.Time(t)
+	}
+
+	var flush []common.MapStr
+
+	a.mutex.Lock()
+	bucket, exists := a.buckets[id]
+	if !exists {
+		if len(a.buckets) >= a.config.MaxKeys {
+			flush = a.collect()
+		}
+		bucket = &aggregateBucket{key: key, sums: map[string]float64{}, first: ts, last: ts}
+		a.buckets[id] = bucket
+	}
+	bucket.count++
+	if ts.Before(bucket.first) {
+		bucket.first = ts
+	}
+	if ts.After(bucket.last) {
+		bucket.last = ts
+	}
+	for _, field := range a.config.Sum {
+		v, err := event.GetValue(field)
+		if err != nil {
+			continue
+		}
+		if f, ok := toFloat(v); ok {
+			bucket.sums[field] += f
+		}
+	}
+	a.mutex.Unlock()
+
+	a.publish(flush)
+
+	if a.config.PassThrough {
+		return event, nil
+	}
+	return nil, nil
+}
+
+// Start starts the periodic flushing of aggregated events to out.
+func (a *Aggregate) Start(out func(common.MapStr)) {
+	a.mutex.Lock()
+	a.out = out
+	a.mutex.Unlock()
+
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer crash.Recover()
+
+		ticker := time.NewTicker(a.config.Window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-ticker.C:
+				a.flush()
+			}
+		}
+	}()
+}
+
+// Stop stops the flush timer and emits all pending aggregated events.
+func (a *Aggregate) Stop() {
+	if a.done == nil {
+		return
+	}
+	close(a.done)
+	a.wg.Wait()
+	a.done = nil
+	a.flush()
+}
+
+func (a *Aggregate) flush() {
+	a.mutex.Lock()
+	events := a.collect()
+	a.mutex.Unlock()
+
+	a.publish(events)
+}
+
+func (a *Aggregate) publish(events []common.MapStr) {
+	if len(events) == 0 {
+		return
+	}
+
+	a.mutex.Lock()
+	out := a.out
+	a.mutex.Unlock()
+
+	if out == nil {
+		logp.Warn("aggregate: dropping %d aggregated events, publisher not started", len(events))
+		return
+	}
+	for _, event := range events {
+		out(event)
+	}
+}
+
+// collect creates the summary events for all buckets and resets the state.
+// Must be called with the mutex held.
+func (a *Aggregate) collect() []common.MapStr {
+	if len(a.buckets) == 0 {
+		return nil
+	}
+
+	now := common.Time(time.Now())
+	events := make([]common.MapStr, 0, len(a.buckets))
+	for _, b := range a.buckets {
+		summary := common.MapStr{
+			"count": b.count,
+			"first": common.Time(b.first),
+			"last":  common.Time(b.last),
+		}
+		if len(b.sums) > 0 {
+			sums := common.MapStr{}
+			for field, sum := range b.sums {
+				sums.Put(field, sum)
+			}
+			summary["sum"] = sums
+		}
+
+		event := b.key
+		event["@timestamp"] = now
+		event["type"] = a.config.Type
+		event["aggregate"] = summary
+		events = append(events, event)
+	}
+
+	a.buckets = map[string]*aggregateBucket{}
+	return events
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func (a *Aggregate) String() string {
+	s := fmt.Sprintf("aggregate=[fields=%s, sum=%s, window=%v]",
+		strings.Join(a.config.Fields, ","), strings.Join(a.config.Sum, ","), a.config.Window)
+	if a.Cond != nil {
+		s += ", condition=" + a.Cond.String()
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+// +build !integration
+
+package actions
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAggregate(t *testing.T, cfg map[string]interface{}) *Aggregate {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newAggregate(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*Aggregate)
+}
+
+type eventCollector struct {
+	sync.Mutex
+	events []common.MapStr
+}
+
+func (c *eventCollector) add(event common.MapStr) {
+	c.Lock()
+	defer c.Unlock()
+	c.events = append(c.events, event)
+}
+
+func TestAggregateFlushOnStop(t *testing.T) {
+	a := newTestAggregate(t, map[string]interface{}{
+		"fields": []string{"client_ip"},
+		"sum":    []string{"bytes"},
+		"window": "1h",
+	})
+
+	collector := &eventCollector{}
+	a.Start(collector.add)
+
+	t0 := time.Date(2016, 7, 1, 10, 0, 0, 0, time.UTC)
+	events := []common.MapStr{
+		{"@timestamp": common.Time(t0.Add(2 * time.Second)), "client_ip": "10.0.0.1", "bytes": 100},
+		{"@timestamp": common.Time(t0), "client_ip": "10.0.0.1", "bytes": uint64(50)},
+		{"@timestamp": common.Time(t0), "client_ip": "10.0.0.2", "bytes": 1.5},
+		{"@timestamp": common.Time(t0), "other": "x"},
+	}
+	var passed []common.MapStr
+	for _, e := range events {
+		out, err := a.Run(e)
+		assert.Nil(t, err)
+		if out != nil {
+			passed = append(passed, out)
+		}
+	}
+
+	// only the event missing the key field is passed through
+	assert.Len(t, passed, 1)
+
+	a.Stop()
+	assert.Len(t, collector.events, 2)
+	for _, e := range collector.events {
+		summary := e["aggregate"].(common.MapStr)
+		switch e["client_ip"] {
+		case "10.0.0.1":
+			assert.Equal(t, int64(2), summary["count"])
+			assert.Equal(t, 150.0, summary["sum"].(common.MapStr)["bytes"])
+			assert.Equal(t, common.Time(t0), summary["first"])
+			assert.Equal(t, common.Time(t0.Add(2*time.Second)), summary["last"])
+		case "10.0.0.2":
+			assert.Equal(t, int64(1), summary["count"])
+			assert.Equal(t, 1.5, summary["sum"].(common.MapStr)["bytes"])
+		default:
+			t.Errorf("unexpected event: %v", e)
+		}
+		assert.Equal(t, "aggregate", e["type"])
+	}
+}
+
+func TestAggregateMaxKeys(t *testing.T) {
+	a := newTestAggregate(t, map[string]interface{}{
+		"fields":       []string{"user"},
+		"window":       "1h",
+		"max_keys":     2,
+		"pass_through": true,
+	})
+
+	collector := &eventCollector{}
+	a.Start(collector.add)
+	defer a.Stop()
+
+	for _, user := range []string{"a", "b", "a", "c"} {
+		out, _ := a.Run(common.MapStr{"user": user})
+		assert.NotNil(t, out)
+	}
+	assert.Len(t, collector.events, 2)
+}
+
+func TestAggregatePeriodicFlush(t *testing.T) {
+	a := newTestAggregate(t, map[string]interface{}{
+		"fields": []string{"user"},
+		"window": "10ms",
+	})
+
+	collector := &eventCollector{}
+	a.Start(collector.add)
+	defer a.Stop()
+
+	a.Run(common.MapStr{"user": "a"})
+	time.Sleep(50 * time.Millisecond)
+
+	collector.Lock()
+	defer collector.Unlock()
+	assert.Len(t, collector.events, 1)
+}
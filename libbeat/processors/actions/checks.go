@@ -0,0 +1,25 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// checkConfig returns an error if the processor configuration contains any
+// option not listed in allowed.
+func checkConfig(name string, c common.Config, allowed ...string) error {
+	for _, field := range c.GetFields() {
+		found := false
+		for _, a := range allowed {
+			if field == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unexpected %s option in the %s configuration", field, name)
+		}
+	}
+	return nil
+}
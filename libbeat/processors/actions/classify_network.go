@@ -0,0 +1,111 @@
+package actions
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// ClassifyNetwork sets a field to the name of the most specific configured
+// network the IP address in a source field belongs to.
+type ClassifyNetwork struct {
+	config classifyNetworkConfig
+	trie   ipTrie
+	Cond   *processors.Condition
+}
+
+type classifyNetworkConfig struct {
+	Field    string                      `config:"field" validate:"required"`
+	Target   string                      `config:"target" validate:"required"`
+	Networks map[string][]string         `config:"networks" validate:"required"`
+	Default  string                      `config:"default"`
+	Cond     *processors.ConditionConfig `config:"when"`
+}
+
+func init() {
+	if err := processors.RegisterPlugin("classify_network", newClassifyNetwork); err != nil {
+		panic(err)
+	}
+}
+
+func newClassifyNetwork(c common.Config) (processors.Processor, error) {
+	err := checkConfig("classify_network", c, "field", "target", "networks", "default", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := classifyNetworkConfig{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the classify_network configuration: %s", err)
+	}
+
+	f := &ClassifyNetwork{config: config}
+	for name, cidrs := range config.Networks {
+		for _, cidr := range cidrs {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %s for %s in the classify_network configuration: %v",
+					cidr, name, err)
+			}
+			f.trie.insert(network, name)
+		}
+	}
+
+	f.Cond, err = processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *ClassifyNetwork) Run(event common.MapStr) (common.MapStr, error) {
+	if f.Cond != nil && !f.Cond.Check(event) {
+		return event, nil
+	}
+
+	value, err := event.GetValue(f.config.Field)
+	if err != nil {
+		return event, nil
+	}
+
+	var ip net.IP
+	switch v := value.(type) {
+	case string:
+		ip = net.ParseIP(v)
+	case net.IP:
+		ip = v
+	}
+	if ip == nil {
+		return event, fmt.Errorf("field %s is not a valid IP address: %v", f.config.Field, value)
+	}
+
+	name, found := f.trie.lookup(ip)
+	if !found {
+		if f.config.Default == "" {
+			return event, nil
+		}
+		name = f.config.Default
+	}
+
+	_, err = event.Put(f.config.Target, name)
+	return event, err
+}
+
+func (f *ClassifyNetwork) String() string {
+	var names []string
+	for name := range f.config.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := fmt.Sprintf("classify_network=[field=%s, target=%s, networks=%s]",
+		f.config.Field, f.config.Target, strings.Join(names, ","))
+	if f.Cond != nil {
+		s += ", condition=" + f.Cond.String()
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+// +build !integration
+
+package actions
+
+import (
+	"net"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPTrieLongestPrefix(t *testing.T) {
+	var trie ipTrie
+	for cidr, name := range map[string]string{
+		"10.0.0.0/8":    "internal",
+		"10.20.0.0/16":  "dmz",
+		"10.20.30.0/24": "vpn",
+		"fd00::/8":      "internal6",
+		"0.0.0.0/0":     "any",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trie.insert(network, name)
+	}
+
+	tests := map[string]string{
+		"10.1.2.3":   "internal",
+		"10.20.1.1":  "dmz",
+		"10.20.30.4": "vpn",
+		"8.8.8.8":    "any",
+		"fd12::1":    "internal6",
+	}
+	for ip, expected := range tests {
+		name, found := trie.lookup(net.ParseIP(ip))
+		assert.True(t, found, ip)
+		assert.Equal(t, expected, name, ip)
+	}
+
+	_, found := trie.lookup(net.ParseIP("2001:db8::1"))
+	assert.False(t, found)
+}
+
+func TestClassifyNetwork(t *testing.T) {
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"field":  "client_ip",
+		"target": "client_network",
+		"networks": map[string]interface{}{
+			"internal": []string{"10.0.0.0/8", "192.168.0.0/16"},
+			"dmz":      []string{"172.16.10.0/24"},
+		},
+		"default": "external",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newClassifyNetwork(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"192.168.1.1": "internal",
+		"172.16.10.9": "dmz",
+		"1.2.3.4":     "external",
+	}
+	for ip, expected := range tests {
+		event, err := p.Run(common.MapStr{"client_ip": ip})
+		assert.Nil(t, err)
+		assert.Equal(t, expected, event["client_network"])
+	}
+
+	_, err = p.Run(common.MapStr{"client_ip": "not-an-ip"})
+	assert.NotNil(t, err)
+}
+
+func TestClassifyNetworkInvalidCIDR(t *testing.T) {
+	c, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":    "ip",
+		"target":   "net",
+		"networks": map[string]interface{}{"a": []string{"10.0.0.0/33"}},
+	})
+	_, err := newClassifyNetwork(*c)
+	assert.NotNil(t, err)
+}
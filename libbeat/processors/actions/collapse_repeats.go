@@ -0,0 +1,213 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// CollapseRepeats collapses runs of consecutive events with the same values
+// of the key fields into the first event of the run, with the number of
+// events in the run stored in the target field, like syslog's "last message
+// repeated N times". Runs are tracked per group_by fields, such that events of
+// different sources do not interrupt each other's runs. A run ends once an
+// event with different key values arrives, or after the window expires.
+type CollapseRepeats struct {
+	config collapseRepeatsConfig
+	Cond   *processors.Condition
+
+	mutex   sync.Mutex
+	pending map[string]*repeatedEvent
+	out     func(common.MapStr)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type collapseRepeatsConfig struct {
+	Fields  []string                    `config:"fields"`
+	GroupBy []string                    `config:"group_by"`
+	Window  time.Duration               `config:"window" validate:"nonzero,positive"`
+	Target  string                      `config:"target"`
+	Cond    *processors.ConditionConfig `config:"when"`
+}
+
+type repeatedEvent struct {
+	event   common.MapStr
+	key     string
+	count   int
+	started time.Time
+}
+
+var defaultCollapseRepeatsConfig = collapseRepeatsConfig{
+	Fields: []string{"message"},
+	Window: 30 * time.Second,
+	Target: "repeat_count",
+}
+
+func init() {
+	if err := processors.RegisterPlugin("collapse_repeats", newCollapseRepeats); err != nil {
+		panic(err)
+	}
+}
+
+func newCollapseRepeats(c common.Config) (processors.Processor, error) {
+	err := checkConfig("collapse_repeats", c, "fields", "group_by", "window",
+		"target", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultCollapseRepeatsConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the collapse_repeats configuration: %s", err)
+	}
+	if len(config.Fields) == 0 {
+		return nil, fmt.Errorf("collapse_repeats requires at least one field")
+	}
+	if config.Target == "" {
+		return nil, fmt.Errorf("collapse_repeats target must not be empty")
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CollapseRepeats{
+		config:  config,
+		Cond:    cond,
+		pending: map[string]*repeatedEvent{},
+	}, nil
+}
+
+// Run counts the event as a repeat of the pending event of its group if the
+// key fields are equal. Otherwise the event starts a new run and the
+// previously pending event is returned.
+func (r *CollapseRepeats) Run(event common.MapStr) (common.MapStr, error) {
+	if r.Cond != nil && !r.Cond.Check(event) {
+		return event, nil
+	}
+
+	var keyParts []string
+	for _, field := range r.config.Fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			// events missing a key field are not collapsed
+			return event, nil
+		}
+		keyParts = append(keyParts, fmt.Sprint(v))
+	}
+	key := strings.Join(keyParts, "\x00")
+
+	var groupParts []string
+	for _, field := range r.config.GroupBy {
+		v, _ := event.GetValue(field)
+		groupParts = append(groupParts, fmt.Sprint(v))
+	}
+	group := strings.Join(groupParts, "\x00")
+
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, exists := r.pending[group]
+	if exists && p.key == key && now.Sub(p.started) < r.config.Window {
+		p.count++
+		return nil, nil
+	}
+
+	r.pending[group] = &repeatedEvent{
+		event:   event,
+		key:     key,
+		count:   1,
+		started: now,
+	}
+	if !exists {
+		return nil, nil
+	}
+	return r.finish(p), nil
+}
+
+// finish returns the first event of the run. The target field is only set if
+// the event has been repeated.
+func (r *CollapseRepeats) finish(p *repeatedEvent) common.MapStr {
+	if p.count > 1 {
+		p.event.Put(r.config.Target, p.count)
+	}
+	return p.event
+}
+
+// Start starts the timer publishing pending events once their window expires.
+func (r *CollapseRepeats) Start(out func(common.MapStr)) {
+	r.mutex.Lock()
+	r.out = out
+	r.mutex.Unlock()
+
+	r.done = make(chan struct{})
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer crash.Recover()
+
+		ticker := time.NewTicker(r.config.Window / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case now := <-ticker.C:
+				r.flush(now.Add(-r.config.Window))
+			}
+		}
+	}()
+}
+
+// Stop stops the timer and publishes all pending events.
+func (r *CollapseRepeats) Stop() {
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.done = nil
+	r.flush(time.Now())
+}
+
+// flush publishes all pending events of runs started before before.
+func (r *CollapseRepeats) flush(before time.Time) {
+	var events []common.MapStr
+
+	r.mutex.Lock()
+	out := r.out
+	for group, p := range r.pending {
+		if p.started.After(before) {
+			continue
+		}
+		events = append(events, r.finish(p))
+		delete(r.pending, group)
+	}
+	r.mutex.Unlock()
+
+	if out == nil {
+		return
+	}
+	for _, event := range events {
+		out(event)
+	}
+}
+
+func (r *CollapseRepeats) String() string {
+	s := fmt.Sprintf("collapse_repeats=[fields=%v, group_by=%v, window=%v]",
+		r.config.Fields, r.config.GroupBy, r.config.Window)
+	if r.Cond != nil {
+		s += ", condition=" + r.Cond.String()
+	}
+	return s
+}
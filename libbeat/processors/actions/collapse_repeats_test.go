@@ -0,0 +1,118 @@
+// +build !integration
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCollapseRepeats(t *testing.T, cfg map[string]interface{}) *CollapseRepeats {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newCollapseRepeats(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*CollapseRepeats)
+}
+
+func TestCollapseRepeats(t *testing.T) {
+	r := newTestCollapseRepeats(t, map[string]interface{}{
+		"group_by": []string{"source"},
+		"window":   "1h",
+	})
+
+	collector := &eventCollector{}
+	r.Start(collector.add)
+
+	events := []common.MapStr{
+		{"source": "a", "message": "disk full", "n": 1},
+		{"source": "b", "message": "other"},
+		{"source": "a", "message": "disk full", "n": 2},
+		{"source": "a", "message": "disk full", "n": 3},
+		{"source": "b", "message": "other"},
+		{"source": "a", "message": "disk ok"},
+		{"source": "a"},
+	}
+
+	var out []common.MapStr
+	for _, e := range events {
+		event, err := r.Run(e)
+		assert.Nil(t, err)
+		if event != nil {
+			out = append(out, event)
+		}
+	}
+
+	// The first event of the run is published with the repeat count, events
+	// without key field are passed through.
+	assert.Equal(t, []common.MapStr{
+		{"source": "a", "message": "disk full", "n": 1, "repeat_count": 3},
+		{"source": "a"},
+	}, out)
+
+	r.Stop()
+	assert.Len(t, collector.events, 2)
+	for _, e := range collector.events {
+		switch e["source"] {
+		case "a":
+			assert.Equal(t, common.MapStr{"source": "a", "message": "disk ok"}, e)
+		case "b":
+			assert.Equal(t, common.MapStr{"source": "b", "message": "other", "repeat_count": 2}, e)
+		default:
+			t.Errorf("unexpected event %v", e)
+		}
+	}
+}
+
+func TestCollapseRepeatsWindow(t *testing.T) {
+	r := newTestCollapseRepeats(t, map[string]interface{}{
+		"fields": []string{"message", "level"},
+		"target": "repeated",
+		"window": "1h",
+	})
+
+	collector := &eventCollector{}
+	r.Start(collector.add)
+	defer r.Stop()
+
+	for i := 0; i < 3; i++ {
+		event, err := r.Run(common.MapStr{"message": "timeout", "level": "error"})
+		assert.Nil(t, err)
+		assert.Nil(t, event)
+	}
+
+	// Runs started before the window are published by the timer.
+	r.flush(time.Now())
+	assert.Equal(t, []common.MapStr{
+		{"message": "timeout", "level": "error", "repeated": 3},
+	}, collector.events)
+
+	// The next repeat starts a new run.
+	event, err := r.Run(common.MapStr{"message": "timeout", "level": "error"})
+	assert.Nil(t, err)
+	assert.Nil(t, event)
+	assert.Len(t, r.pending, 1)
+}
+
+func TestCollapseRepeatsConfigInvalid(t *testing.T) {
+	for _, invalid := range []map[string]interface{}{
+		{"fields": []string{}},
+		{"window": "0s"},
+		{"target": ""},
+		{"unknown": true},
+	} {
+		c, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = newCollapseRepeats(*c)
+		assert.Error(t, err, "%v", invalid)
+	}
+}
@@ -0,0 +1,61 @@
+package actions
+
+import "net"
+
+// ipTrie is a binary radix tree mapping IP networks to names. Lookups return
+// the name of the most specific network containing the address. IPv4
+// addresses are stored in their IPv4-in-IPv6 form, so both address families
+// share the same tree.
+type ipTrie struct {
+	root ipTrieNode
+}
+
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	name     string
+	terminal bool
+}
+
+func (t *ipTrie) insert(network *net.IPNet, name string) {
+	ip := network.IP.To16()
+	ones, bits := network.Mask.Size()
+	if bits == 8*net.IPv4len {
+		ones += 8 * (net.IPv6len - net.IPv4len)
+	}
+
+	node := &t.root
+	for i := 0; i < ones; i++ {
+		b := bit(ip, i)
+		if node.children[b] == nil {
+			node.children[b] = &ipTrieNode{}
+		}
+		node = node.children[b]
+	}
+	node.name = name
+	node.terminal = true
+}
+
+func (t *ipTrie) lookup(ip net.IP) (string, bool) {
+	ip = ip.To16()
+	if ip == nil {
+		return "", false
+	}
+
+	var name string
+	found := false
+	node := &t.root
+	for i := 0; node != nil; i++ {
+		if node.terminal {
+			name, found = node.name, true
+		}
+		if i == 8*net.IPv6len {
+			break
+		}
+		node = node.children[bit(ip, i)]
+	}
+	return name, found
+}
+
+func bit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
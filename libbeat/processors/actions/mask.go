@@ -0,0 +1,310 @@
+package actions
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// Mask redacts or tokenizes personal data, like email addresses, credit card
+// numbers and IP addresses, found in string fields of the event.
+type Mask struct {
+	config    maskConfig
+	detectors []maskDetector
+	Cond      *processors.Condition
+}
+
+type maskConfig struct {
+	Fields      []string                    `config:"fields" validate:"required"`
+	Detectors   []string                    `config:"detectors"`
+	Patterns    []maskPattern               `config:"patterns"`
+	Method      string                      `config:"method"`
+	Replacement string                      `config:"replacement"`
+	Key         string                      `config:"key"`
+	Cond        *processors.ConditionConfig `config:"when"`
+}
+
+type maskPattern struct {
+	Name    string `config:"name" validate:"required"`
+	Pattern string `config:"pattern" validate:"required"`
+}
+
+// maskDetector finds one kind of personal data. valid, if set, filters out
+// matches of the regular expression that are no valid values. find, if set,
+// is used instead of the regular expression and returns the index pairs of
+// the values found.
+type maskDetector struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+	find  func(s string) [][]int
+}
+
+var defaultMaskConfig = maskConfig{
+	Detectors:   []string{"email", "credit_card", "ip"},
+	Method:      "redact",
+	Replacement: "[REDACTED]",
+}
+
+var builtinMaskDetectors = map[string]maskDetector{
+	"email": {
+		re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	"credit_card": {
+		re:    regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid: validCreditCard,
+	},
+	"ip": {
+		find: findIPs,
+	},
+}
+
+func init() {
+	if err := processors.RegisterPlugin("mask", newMask); err != nil {
+		panic(err)
+	}
+}
+
+func newMask(c common.Config) (processors.Processor, error) {
+	err := checkConfig("mask", c, "fields", "detectors", "patterns", "method",
+		"replacement", "key", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultMaskConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the mask configuration: %s", err)
+	}
+
+	switch config.Method {
+	case "redact":
+	case "tokenize":
+		if config.Key == "" {
+			return nil, fmt.Errorf("the mask configuration requires a key for the tokenize method")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported method '%s' in the mask configuration", config.Method)
+	}
+
+	var detectors []maskDetector
+	for _, name := range config.Detectors {
+		d, found := builtinMaskDetectors[name]
+		if !found {
+			return nil, fmt.Errorf("unknown detector '%s' in the mask configuration", name)
+		}
+		d.name = name
+		detectors = append(detectors, d)
+	}
+	for _, p := range config.Patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s' in the mask configuration: %v", p.Name, err)
+		}
+		detectors = append(detectors, maskDetector{name: p.Name, re: re})
+	}
+	if len(detectors) == 0 {
+		return nil, fmt.Errorf("the mask configuration requires at least one detector or pattern")
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mask{config: config, detectors: detectors, Cond: cond}, nil
+}
+
+func (m *Mask) Run(event common.MapStr) (common.MapStr, error) {
+	if m.Cond != nil && !m.Cond.Check(event) {
+		return event, nil
+	}
+
+	for _, field := range m.config.Fields {
+		value, err := event.GetValue(field)
+		if err != nil {
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			event.Put(field, m.mask(v))
+		case []string:
+			masked := make([]string, len(v))
+			for i, s := range v {
+				masked[i] = m.mask(s)
+			}
+			event.Put(field, masked)
+		case []interface{}:
+			masked := make([]interface{}, len(v))
+			for i, elem := range v {
+				if s, ok := elem.(string); ok {
+					elem = m.mask(s)
+				}
+				masked[i] = elem
+			}
+			event.Put(field, masked)
+		}
+	}
+	return event, nil
+}
+
+// mask replaces all matches of all detectors in s. Detectors are applied in
+// order, so values already replaced are not matched by later detectors.
+func (m *Mask) mask(s string) string {
+	for _, d := range m.detectors {
+		matches := d.matches(s)
+		if len(matches) == 0 {
+			continue
+		}
+
+		var buf bytes.Buffer
+		last := 0
+		for _, loc := range matches {
+			buf.WriteString(s[last:loc[0]])
+			buf.WriteString(m.replace(d.name, s[loc[0]:loc[1]]))
+			last = loc[1]
+		}
+		buf.WriteString(s[last:])
+		s = buf.String()
+	}
+	return s
+}
+
+// matches returns the index pairs of the values found in s.
+func (d *maskDetector) matches(s string) [][]int {
+	if d.find != nil {
+		return d.find(s)
+	}
+
+	locs := d.re.FindAllStringIndex(s, -1)
+	if d.valid == nil {
+		return locs
+	}
+	valid := locs[:0]
+	for _, loc := range locs {
+		if d.valid(s[loc[0]:loc[1]]) {
+			valid = append(valid, loc)
+		}
+	}
+	return valid
+}
+
+// maxIPLength is the length of the longest textual IP address, an IPv4-mapped
+// IPv6 address like ffff:ffff:ffff:ffff:ffff:ffff:255.255.255.255.
+const maxIPLength = 45
+
+// findIPs returns the index pairs of the IPv4 and IPv6 addresses in s. Runs of
+// hex digits, colons and dots are candidates, and the longest substring of a
+// run parsed by net.ParseIP is an address. Addresses must not start or end in
+// the middle of a group of digits, such that the colon of a preceding word,
+// like in `ip:2001:db8::1`, is not part of the address, but the IPv4-mapped
+// address `::ffff:10.0.0.1` is found as a whole.
+func findIPs(s string) [][]int {
+	var locs [][]int
+	for i := 0; i < len(s); {
+		if !isIPChar(s[i]) {
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(s) && isIPChar(s[end]) {
+			end++
+		}
+		locs = append(locs, findIPsInRun(s, i, end)...)
+		i = end
+	}
+	return locs
+}
+
+func findIPsInRun(s string, start, end int) [][]int {
+	var locs [][]int
+	for i := start; i < end; i++ {
+		if i > start && isHexDigit(s[i-1]) {
+			continue
+		}
+
+		last := end
+		if last > i+maxIPLength {
+			last = i + maxIPLength
+		}
+		for j := last; j > i+1; j-- {
+			if j < end && isHexDigit(s[j]) {
+				continue
+			}
+			if net.ParseIP(s[i:j]) != nil {
+				locs = append(locs, []int{i, j})
+				i = j - 1
+				break
+			}
+		}
+	}
+	return locs
+}
+
+func isIPChar(c byte) bool {
+	return c == ':' || c == '.' || isHexDigit(c)
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func (m *Mask) replace(detector, match string) string {
+	if m.config.Method == "redact" {
+		return m.config.Replacement
+	}
+
+	// the token is a keyed hash of the value, such that equal values can
+	// still be correlated without revealing them
+	mac := hmac.New(sha256.New, []byte(m.config.Key))
+	mac.Write([]byte(match))
+	return detector + ":" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// validCreditCard checks the number of digits and the Luhn checksum of a
+// potential credit card number.
+func validCreditCard(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func (m *Mask) String() string {
+	names := make([]string, len(m.detectors))
+	for i, d := range m.detectors {
+		names[i] = d.name
+	}
+
+	s := fmt.Sprintf("mask=[fields=%s, detectors=%s, method=%s]",
+		strings.Join(m.config.Fields, ","), strings.Join(names, ","), m.config.Method)
+	if m.Cond != nil {
+		s += ", condition=" + m.Cond.String()
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+// +build !integration
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMask(t *testing.T, cfg map[string]interface{}) *Mask {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newMask(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*Mask)
+}
+
+func TestMaskRedact(t *testing.T) {
+	p := newTestMask(t, map[string]interface{}{
+		"fields": []string{"message", "user.notes"},
+	})
+
+	event, err := p.Run(common.MapStr{
+		"message": "login of jane.doe@example.com from 10.1.2.3 and 2001:db8::1 at 12:30:45",
+		"user": common.MapStr{
+			"notes": []interface{}{"card 4111 1111 1111 1111", "order 1234567890123", 42},
+		},
+		"client_ip": "10.1.2.3",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{
+		"message": "login of [REDACTED] from [REDACTED] and [REDACTED] at 12:30:45",
+		"user": common.MapStr{
+			"notes": []interface{}{"card [REDACTED]", "order 1234567890123", 42},
+		},
+		"client_ip": "10.1.2.3",
+	}, event)
+}
+
+func TestMaskInvalidIPNotMasked(t *testing.T) {
+	p := newTestMask(t, map[string]interface{}{
+		"fields":    []string{"message"},
+		"detectors": []string{"ip"},
+	})
+
+	event, _ := p.Run(common.MapStr{"message": "version 1.2.3.4 and 999.1.1.1"})
+	assert.Equal(t, "version [REDACTED] and 999.1.1.1", event["message"])
+}
+
+func TestMaskIP(t *testing.T) {
+	p := newTestMask(t, map[string]interface{}{
+		"fields":    []string{"message"},
+		"detectors": []string{"ip"},
+	})
+
+	tests := []struct {
+		in, out string
+	}{
+		{"client ip:2001:db8::1", "client ip:[REDACTED]"},
+		{"::ffff:10.0.0.1", "[REDACTED]"},
+		{"from ::1 to fe80::1:2", "from [REDACTED] to [REDACTED]"},
+		{"addr=10.0.0.1:8080", "addr=[REDACTED]:8080"},
+		{"[2001:db8::7]:443, 192.168.0.1.", "[[REDACTED]]:443, [REDACTED]."},
+		{"deadbeef 1234.1.1.1 12:30:45", "deadbeef 1234.1.1.1 12:30:45"},
+	}
+
+	for _, test := range tests {
+		event, _ := p.Run(common.MapStr{"message": test.in})
+		assert.Equal(t, test.out, event["message"], test.in)
+	}
+}
+
+func TestMaskTokenize(t *testing.T) {
+	p := newTestMask(t, map[string]interface{}{
+		"fields":    []string{"message"},
+		"detectors": []string{"email"},
+		"method":    "tokenize",
+		"key":       "secret",
+	})
+
+	e1, _ := p.Run(common.MapStr{"message": "from jane@example.com"})
+	e2, _ := p.Run(common.MapStr{"message": "to jane@example.com"})
+	e3, _ := p.Run(common.MapStr{"message": "to john@example.com"})
+
+	token := strings.TrimPrefix(e1["message"].(string), "from ")
+	assert.True(t, strings.HasPrefix(token, "email:"), token)
+	assert.Len(t, token, len("email:")+32)
+	assert.Equal(t, "to "+token, e2["message"])
+	assert.NotEqual(t, "to "+token, e3["message"])
+}
+
+func TestMaskCustomPattern(t *testing.T) {
+	p := newTestMask(t, map[string]interface{}{
+		"fields":      []string{"message"},
+		"detectors":   []string{},
+		"replacement": "***",
+		"patterns": []map[string]interface{}{
+			{"name": "ssn", "pattern": `\d{3}-\d{2}-\d{4}`},
+		},
+	})
+
+	event, _ := p.Run(common.MapStr{"message": "ssn 078-05-1120 of jane@example.com"})
+	assert.Equal(t, "ssn *** of jane@example.com", event["message"])
+}
+
+func TestMaskInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{},
+		{"fields": []string{"message"}, "detectors": []string{"phone"}},
+		{"fields": []string{"message"}, "method": "encrypt"},
+		{"fields": []string{"message"}, "method": "tokenize"},
+		{"fields": []string{"message"}, "detectors": []string{}},
+		{"fields": []string{"message"}, "patterns": []map[string]interface{}{
+			{"name": "broken", "pattern": "("},
+		}},
+		{"fields": []string{"message"}, "unknown": true},
+	}
+
+	for _, test := range tests {
+		c, err := common.NewConfigFrom(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = newMask(*c)
+		assert.Error(t, err, "%v", test)
+	}
+}
+
+func TestValidCreditCard(t *testing.T) {
+	assert.True(t, validCreditCard("4111111111111111"))
+	assert.True(t, validCreditCard("5500-0000-0000-0004"))
+	assert.False(t, validCreditCard("4111111111111112"))
+	assert.False(t, validCreditCard("411111111111"))
+}
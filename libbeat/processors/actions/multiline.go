@@ -0,0 +1,218 @@
+package actions
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/multiline"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// Multiline merges the text field of consecutive events into one event, using
+// the same matching rules as filebeat's multiline option. Events are grouped
+// by the group_by fields (e.g. the source of a line), so lines from different
+// sources are never merged. Pending events are published once the timeout
+// expires without new lines being added.
+type Multiline struct {
+	config   multilineConfig
+	matcher  multiline.Matcher
+	maxLines int
+	timeout  time.Duration
+	Cond     *processors.Condition
+
+	mutex   sync.Mutex
+	pending map[string]*multilineEvent
+	out     func(common.MapStr)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type multilineConfig struct {
+	multiline.Config `config:",inline"`
+	Field            string                      `config:"field"`
+	GroupBy          []string                    `config:"group_by"`
+	Cond             *processors.ConditionConfig `config:"when"`
+}
+
+type multilineEvent struct {
+	event    common.MapStr
+	lines    []string
+	last     []byte
+	numLines int
+	updated  time.Time
+}
+
+func init() {
+	if err := processors.RegisterPlugin("multiline", newMultiline); err != nil {
+		panic(err)
+	}
+}
+
+func newMultiline(c common.Config) (processors.Processor, error) {
+	err := checkConfig("multiline", c, "pattern", "negate", "match", "max_lines",
+		"timeout", "field", "group_by", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := multilineConfig{Field: "message"}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the multiline configuration: %s", err)
+	}
+
+	matcher, err := multiline.NewMatcher(&config.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := config.GetTimeout()
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = multiline.DefaultTimeout
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Multiline{
+		config:   config,
+		matcher:  matcher,
+		maxLines: config.GetMaxLines(),
+		timeout:  timeout,
+		Cond:     cond,
+		pending:  map[string]*multilineEvent{},
+	}, nil
+}
+
+// Run adds the event to the pending multiline event of its group. If the
+// event starts a new multiline event, the previously pending one is returned.
+func (m *Multiline) Run(event common.MapStr) (common.MapStr, error) {
+	if m.Cond != nil && !m.Cond.Check(event) {
+		return event, nil
+	}
+
+	value, err := event.GetValue(m.config.Field)
+	if err != nil {
+		return event, nil
+	}
+	line, ok := value.(string)
+	if !ok {
+		return event, fmt.Errorf("multiline field %s is not a string", m.config.Field)
+	}
+
+	var keyParts []string
+	for _, field := range m.config.GroupBy {
+		v, _ := event.GetValue(field)
+		keyParts = append(keyParts, fmt.Sprint(v))
+	}
+	key := strings.Join(keyParts, "\x00")
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current := []byte(line)
+	p, exists := m.pending[key]
+	if exists && m.matcher(p.last, current) {
+		if m.maxLines <= 0 || p.numLines < m.maxLines {
+			p.lines = append(p.lines, line)
+			p.numLines++
+		}
+		p.last = current
+		p.updated = time.Now()
+		return nil, nil
+	}
+
+	m.pending[key] = &multilineEvent{
+		event:    event,
+		lines:    []string{line},
+		last:     current,
+		numLines: 1,
+		updated:  time.Now(),
+	}
+	if !exists {
+		return nil, nil
+	}
+	return m.finish(p), nil
+}
+
+func (m *Multiline) finish(p *multilineEvent) common.MapStr {
+	p.event.Put(m.config.Field, strings.Join(p.lines, "\n"))
+	return p.event
+}
+
+// Start starts the timer publishing pending multiline events after timeout.
+func (m *Multiline) Start(out func(common.MapStr)) {
+	m.mutex.Lock()
+	m.out = out
+	m.mutex.Unlock()
+
+	m.done = make(chan struct{})
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer crash.Recover()
+
+		ticker := time.NewTicker(m.timeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case now := <-ticker.C:
+				m.flush(now.Add(-m.timeout))
+			}
+		}
+	}()
+}
+
+// Stop stops the timer and publishes all pending multiline events.
+func (m *Multiline) Stop() {
+	if m.done == nil {
+		return
+	}
+	close(m.done)
+	m.wg.Wait()
+	m.done = nil
+	m.flush(time.Now())
+}
+
+// flush publishes all pending events not updated since before.
+func (m *Multiline) flush(before time.Time) {
+	var events []common.MapStr
+
+	m.mutex.Lock()
+	out := m.out
+	for key, p := range m.pending {
+		if p.updated.After(before) {
+			continue
+		}
+		events = append(events, m.finish(p))
+		delete(m.pending, key)
+	}
+	m.mutex.Unlock()
+
+	if out == nil {
+		return
+	}
+	for _, event := range events {
+		out(event)
+	}
+}
+
+func (m *Multiline) String() string {
+	s := fmt.Sprintf("multiline=[field=%s, pattern=%v, match=%s, negate=%v]",
+		m.config.Field, m.config.Pattern, m.config.Match, m.config.Negate)
+	if m.Cond != nil {
+		s += ", condition=" + m.Cond.String()
+	}
+	return s
+}
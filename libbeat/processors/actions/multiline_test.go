@@ -0,0 +1,83 @@
+// +build !integration
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMultiline(t *testing.T, cfg map[string]interface{}) *Multiline {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newMultiline(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*Multiline)
+}
+
+func TestMultilineProcessor(t *testing.T) {
+	m := newTestMultiline(t, map[string]interface{}{
+		"pattern":  `^\s`,
+		"match":    "after",
+		"group_by": []string{"source"},
+		"timeout":  "1h",
+	})
+
+	collector := &eventCollector{}
+	m.Start(collector.add)
+
+	lines := []common.MapStr{
+		{"source": "a", "message": "line1"},
+		{"source": "b", "message": "other1"},
+		{"source": "a", "message": "  line1.1"},
+		{"source": "b", "message": "  other1.1"},
+		{"source": "a", "message": "  line1.2"},
+		{"source": "a", "message": "line2"},
+	}
+
+	var out []common.MapStr
+	for _, e := range lines {
+		event, err := m.Run(e)
+		assert.Nil(t, err)
+		if event != nil {
+			out = append(out, event)
+		}
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{"source": "a", "message": "line1\n  line1.1\n  line1.2"},
+	}, out)
+
+	m.Stop()
+	assert.Len(t, collector.events, 2)
+	for _, e := range collector.events {
+		switch e["source"] {
+		case "a":
+			assert.Equal(t, "line2", e["message"])
+		case "b":
+			assert.Equal(t, "other1\n  other1.1", e["message"])
+		}
+	}
+}
+
+func TestMultilineProcessorMaxLines(t *testing.T) {
+	m := newTestMultiline(t, map[string]interface{}{
+		"pattern":   `^\s`,
+		"match":     "after",
+		"max_lines": 2,
+	})
+
+	for _, line := range []string{"a", " b", " c", " d"} {
+		event, _ := m.Run(common.MapStr{"message": line})
+		assert.Nil(t, event)
+	}
+
+	event, _ := m.Run(common.MapStr{"message": "e"})
+	assert.Equal(t, "a\n b", event["message"])
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// SetNamespace sets the namespace of the event, either to a fixed value or
+// to the value of an event field. Outputs append the namespace to index
+// names, topics and file paths.
+type SetNamespace struct {
+	config setNamespaceConfig
+	Cond   *processors.Condition
+}
+
+type setNamespaceConfig struct {
+	Namespace string                      `config:"namespace"`
+	Field     string                      `config:"field"`
+	Cond      *processors.ConditionConfig `config:"when"`
+}
+
+func init() {
+	if err := processors.RegisterPlugin("set_namespace", newSetNamespace); err != nil {
+		panic(err)
+	}
+}
+
+func newSetNamespace(c common.Config) (processors.Processor, error) {
+	err := checkConfig("set_namespace", c, "namespace", "field", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := setNamespaceConfig{}
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the set_namespace configuration: %s", err)
+	}
+
+	if (config.Namespace == "") == (config.Field == "") {
+		return nil, fmt.Errorf("the set_namespace configuration requires either namespace or field")
+	}
+	if err := common.ValidateNamespace(config.Namespace); err != nil {
+		return nil, err
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetNamespace{config: config, Cond: cond}, nil
+}
+
+func (s *SetNamespace) Run(event common.MapStr) (common.MapStr, error) {
+	if s.Cond != nil && !s.Cond.Check(event) {
+		return event, nil
+	}
+
+	namespace := s.config.Namespace
+	if s.config.Field != "" {
+		value, err := event.GetValue(s.config.Field)
+		if err != nil {
+			return event, nil
+		}
+
+		namespace, _ = value.(string)
+		if err := common.ValidateNamespace(namespace); err != nil {
+			return event, err
+		}
+	}
+
+	if namespace != "" {
+		common.SetNamespace(event, namespace)
+	}
+	return event, nil
+}
+
+func (s *SetNamespace) String() string {
+	str := fmt.Sprintf("set_namespace=[namespace=%s, field=%s]", s.config.Namespace, s.config.Field)
+	if s.Cond != nil {
+		str += ", condition=" + s.Cond.String()
+	}
+	return str
+}
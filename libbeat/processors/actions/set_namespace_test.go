@@ -0,0 +1,69 @@
+// +build !integration
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSetNamespace(t *testing.T, cfg map[string]interface{}) *SetNamespace {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newSetNamespace(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*SetNamespace)
+}
+
+func TestSetNamespaceFixed(t *testing.T) {
+	p := newTestSetNamespace(t, map[string]interface{}{"namespace": "tenant_a"})
+
+	event, err := p.Run(common.MapStr{"message": "hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, "tenant_a", common.GetNamespace(event))
+}
+
+func TestSetNamespaceFromField(t *testing.T) {
+	p := newTestSetNamespace(t, map[string]interface{}{"field": "kubernetes.namespace"})
+
+	event, err := p.Run(common.MapStr{
+		"kubernetes": common.MapStr{"namespace": "team-b"},
+		"@metadata":  common.MapStr{"type": "log"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"type": "log", "namespace": "team-b"}, event["@metadata"])
+
+	// events without the field or with an invalid value are not modified
+	event, err = p.Run(common.MapStr{"message": "hello"})
+	assert.Nil(t, err)
+	assert.Equal(t, "", common.GetNamespace(event))
+
+	event, err = p.Run(common.MapStr{"kubernetes": common.MapStr{"namespace": "Team B"}})
+	assert.Error(t, err)
+	assert.Equal(t, "", common.GetNamespace(event))
+}
+
+func TestSetNamespaceInvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{},
+		{"namespace": "a", "field": "b"},
+		{"namespace": "../etc"},
+		{"namespace": "a", "target": "b"},
+	}
+
+	for _, test := range tests {
+		c, err := common.NewConfigFrom(test)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = newSetNamespace(*c)
+		assert.Error(t, err, "%v", test)
+	}
+}
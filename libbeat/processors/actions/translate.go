@@ -0,0 +1,321 @@
+package actions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+	"gopkg.in/yaml.v2"
+)
+
+// Translate enriches events by looking up the value of a source field in a
+// dictionary loaded from a CSV, YAML or JSON file. The dictionary file is
+// checked for modifications every reload_period and reloaded if changed.
+type Translate struct {
+	config translateConfig
+	Cond   *processors.Condition
+
+	mutex     sync.RWMutex
+	dict      map[string]interface{}
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+type translateConfig struct {
+	Field          string                      `config:"field" validate:"required"`
+	Target         string                      `config:"target"`
+	DictionaryPath string                      `config:"dictionary_path" validate:"required"`
+	Format         string                      `config:"format"`
+	KeyColumn      string                      `config:"key_column"`
+	ReloadPeriod   time.Duration               `config:"reload_period"`
+	Override       bool                        `config:"override"`
+	Cond           *processors.ConditionConfig `config:"when"`
+}
+
+var defaultTranslateConfig = translateConfig{
+	Target:       "translation",
+	ReloadPeriod: 1 * time.Minute,
+}
+
+func init() {
+	if err := processors.RegisterPlugin("translate", newTranslate); err != nil {
+		panic(err)
+	}
+}
+
+func newTranslate(c common.Config) (processors.Processor, error) {
+	err := checkConfig("translate", c, "field", "target", "dictionary_path",
+		"format", "key_column", "reload_period", "override", "when")
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultTranslateConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the translate configuration: %s", err)
+	}
+
+	if config.Format == "" {
+		config.Format = strings.TrimPrefix(filepath.Ext(config.DictionaryPath), ".")
+	}
+	switch config.Format {
+	case "csv", "json", "yaml", "yml":
+	default:
+		return nil, fmt.Errorf("unsupported dictionary format '%s' in the translate configuration", config.Format)
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Translate{config: config, Cond: cond}
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Translate) Run(event common.MapStr) (common.MapStr, error) {
+	if t.Cond != nil && !t.Cond.Check(event) {
+		return event, nil
+	}
+
+	t.checkReload()
+
+	value, err := event.GetValue(t.config.Field)
+	if err != nil || value == nil {
+		return event, nil
+	}
+
+	t.mutex.RLock()
+	entry, found := t.dict[fmt.Sprint(value)]
+	t.mutex.RUnlock()
+	if !found {
+		return event, nil
+	}
+
+	if fields, ok := entry.(common.MapStr); ok {
+		for k, v := range fields {
+			if err := t.put(event, t.config.Target+"."+k, v); err != nil {
+				return event, err
+			}
+		}
+		return event, nil
+	}
+
+	return event, t.put(event, t.config.Target, entry)
+}
+
+func (t *Translate) put(event common.MapStr, key string, value interface{}) error {
+	if !t.config.Override {
+		if exists, _ := event.HasKey(key); exists {
+			return fmt.Errorf("target field %s already exists", key)
+		}
+	}
+	_, err := event.Put(key, copyDictValue(value))
+	return err
+}
+
+// checkReload reloads the dictionary if the reload period has passed and the
+// dictionary file has been modified since it was last loaded.
+func (t *Translate) checkReload() {
+	if t.config.ReloadPeriod <= 0 {
+		return
+	}
+
+	now := time.Now()
+	t.mutex.RLock()
+	due := now.Sub(t.lastCheck) >= t.config.ReloadPeriod
+	t.mutex.RUnlock()
+	if !due {
+		return
+	}
+
+	info, err := os.Stat(t.config.DictionaryPath)
+	t.mutex.Lock()
+	t.lastCheck = now
+	modified := err == nil && !info.ModTime().Equal(t.modTime)
+	t.mutex.Unlock()
+
+	if err != nil {
+		logp.Warn("translate: failed to stat dictionary %s: %v", t.config.DictionaryPath, err)
+		return
+	}
+	if !modified {
+		return
+	}
+
+	if err := t.load(); err != nil {
+		logp.Err("translate: failed to reload dictionary, keeping old version: %v", err)
+	}
+}
+
+func (t *Translate) load() error {
+	path := t.config.DictionaryPath
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to open translate dictionary %s: %v", path, err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read translate dictionary %s: %v", path, err)
+	}
+
+	var dict map[string]interface{}
+	switch t.config.Format {
+	case "csv":
+		dict, err = parseCSVDictionary(raw, t.config.KeyColumn)
+	case "json":
+		dict, err = parseJSONDictionary(raw)
+	default:
+		dict, err = parseYAMLDictionary(raw)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse translate dictionary %s: %v", path, err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.dict = dict
+	t.modTime = info.ModTime()
+	t.lastCheck = time.Now()
+
+	logp.Debug("processors", "translate: loaded %d entries from %s", len(dict), path)
+	return nil
+}
+
+// parseCSVDictionary parses a CSV file with a header row. Each row is indexed by
+// the value of keyColumn (the first column if empty), with the remaining
+// columns stored as fields named after the header.
+func parseCSVDictionary(raw []byte, keyColumn string) (map[string]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(string(raw))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	header := records[0]
+	keyIdx := 0
+	if keyColumn != "" {
+		keyIdx = -1
+		for i, name := range header {
+			if name == keyColumn {
+				keyIdx = i
+				break
+			}
+		}
+		if keyIdx < 0 {
+			return nil, fmt.Errorf("key column '%s' not found in header", keyColumn)
+		}
+	}
+
+	dict := make(map[string]interface{}, len(records)-1)
+	for _, record := range records[1:] {
+		fields := common.MapStr{}
+		for i, value := range record {
+			if i != keyIdx && i < len(header) {
+				fields[header[i]] = value
+			}
+		}
+		dict[record[keyIdx]] = fields
+	}
+	return dict, nil
+}
+
+func parseJSONDictionary(raw []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		dict[k] = normalizeDictValue(v)
+	}
+	return dict, nil
+}
+
+func parseYAMLDictionary(raw []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+
+	dict := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		dict[k] = normalizeDictValue(v)
+	}
+	return dict, nil
+}
+
+// normalizeDictValue converts nested maps as returned by the JSON and YAML
+// decoders into common.MapStr, including the maps in lists.
+func normalizeDictValue(v interface{}) interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		out := common.MapStr{}
+		for k, val := range m {
+			out[k] = normalizeDictValue(val)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := common.MapStr{}
+		for k, val := range m {
+			out[fmt.Sprint(k)] = normalizeDictValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(m))
+		for i, val := range m {
+			out[i] = normalizeDictValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// copyDictValue returns a deep copy of a normalized dictionary value, such
+// that events modified by later processors or outputs do not modify the
+// dictionary shared by all events.
+func copyDictValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case common.MapStr:
+		out := make(common.MapStr, len(v))
+		for k, val := range v {
+			out[k] = copyDictValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = copyDictValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func (t *Translate) String() string {
+	s := fmt.Sprintf("translate=[field=%s, target=%s, dictionary=%s]",
+		t.config.Field, t.config.Target, t.config.DictionaryPath)
+	if t.Cond != nil {
+		s += ", condition=" + t.Cond.String()
+	}
+	return s
+}
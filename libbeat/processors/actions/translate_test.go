@@ -0,0 +1,170 @@
+// +build !integration
+
+package actions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTranslate(t *testing.T, dir, name, content string, cfg map[string]interface{}) *Translate {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg["dictionary_path"] = path
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := newTranslate(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*Translate)
+}
+
+func TestTranslateCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csv := "ip,datacenter,rack\n10.0.0.1,dc1,r1\n10.0.0.2,dc2,r7\n"
+	p := newTestTranslate(t, dir, "hosts.csv", csv, map[string]interface{}{
+		"field":  "client_ip",
+		"target": "client",
+	})
+
+	event, err := p.Run(common.MapStr{"client_ip": "10.0.0.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{
+		"client_ip": "10.0.0.2",
+		"client":    common.MapStr{"datacenter": "dc2", "rack": "r7"},
+	}, event)
+
+	event, err = p.Run(common.MapStr{"client_ip": "10.0.0.3"})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"client_ip": "10.0.0.3"}, event)
+}
+
+func TestTranslateYAMLScalar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := newTestTranslate(t, dir, "users.yml", "alice: engineering\nbob: sales\n", map[string]interface{}{
+		"field":  "user.name",
+		"target": "user.department",
+	})
+
+	event, err := p.Run(common.MapStr{"user": common.MapStr{"name": "bob"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "sales", event["user"].(common.MapStr)["department"])
+}
+
+// Modifying a translated event must not modify the dictionary.
+func TestTranslateCopiesValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := "bob:\n  team: {name: sales}\n  tags: [eu, {site: paris}]\nalice: [eu, {site: paris}]\n"
+	p := newTestTranslate(t, dir, "users.yml", content, map[string]interface{}{
+		"field":  "user.name",
+		"target": "user.info",
+	})
+
+	event, err := p.Run(common.MapStr{"user": common.MapStr{"name": "bob"}})
+	assert.Nil(t, err)
+	info := event["user"].(common.MapStr)["info"].(common.MapStr)
+	info["team"].(common.MapStr)["name"] = "changed"
+	tags := info["tags"].([]interface{})
+	tags[0] = "changed"
+	tags[1].(common.MapStr)["site"] = "changed"
+
+	event, err = p.Run(common.MapStr{"user": common.MapStr{"name": "bob"}})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{
+		"team": common.MapStr{"name": "sales"},
+		"tags": []interface{}{"eu", common.MapStr{"site": "paris"}},
+	}, event["user"].(common.MapStr)["info"])
+
+	event, err = p.Run(common.MapStr{"user": common.MapStr{"name": "alice"}})
+	assert.Nil(t, err)
+	tags = event["user"].(common.MapStr)["info"].([]interface{})
+	tags[1].(common.MapStr)["site"] = "changed"
+
+	event, err = p.Run(common.MapStr{"user": common.MapStr{"name": "alice"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"eu", common.MapStr{"site": "paris"}},
+		event["user"].(common.MapStr)["info"])
+}
+
+func TestTranslateOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := newTestTranslate(t, dir, "users.json", `{"alice": "engineering"}`, map[string]interface{}{
+		"field":  "user",
+		"target": "department",
+	})
+
+	_, err = p.Run(common.MapStr{"user": "alice", "department": "hr"})
+	assert.NotNil(t, err)
+
+	p.config.Override = true
+	event, err := p.Run(common.MapStr{"user": "alice", "department": "hr"})
+	assert.Nil(t, err)
+	assert.Equal(t, "engineering", event["department"])
+}
+
+func TestTranslateReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := newTestTranslate(t, dir, "users.json", `{"alice": "engineering"}`, map[string]interface{}{
+		"field":         "user",
+		"target":        "department",
+		"reload_period": "1ms",
+	})
+
+	path := filepath.Join(dir, "users.json")
+	err = ioutil.WriteFile(path, []byte(`{"alice": "marketing"}`), 0644)
+	assert.Nil(t, err)
+	future := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(path, future, future))
+	time.Sleep(5 * time.Millisecond)
+
+	event, err := p.Run(common.MapStr{"user": "alice"})
+	assert.Nil(t, err)
+	assert.Equal(t, "marketing", event["department"])
+}
+
+func TestTranslateInvalidFormat(t *testing.T) {
+	c, _ := common.NewConfigFrom(map[string]interface{}{
+		"field":           "user",
+		"dictionary_path": "/tmp/users.txt",
+	})
+	_, err := newTranslate(*c)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,88 @@
+package cisco
+
+// Address patterns of ASA messages. Addresses are logged as
+// interface:ip/port, optionally followed by the identity of the user in
+// parentheses.
+const (
+	asaSrc  = `(?P<src_interface>[^:\s]+):(?P<src_ip>[^/\s]+)/(?P<src_port>\d+)`
+	asaDst  = `(?P<dst_interface>[^:\s]+):(?P<dst_ip>[^/\s]+)/(?P<dst_port>\d+)`
+	asaUser = `(?:\s?\([^)\s]*\))?`
+	vpnUser = `^Group <(?P<group>[^>]*)> User <(?P<user>[^>]*)> IP <(?P<src_ip>[^>]*)> `
+)
+
+// asaParsers are the parsers of ASA messages by message ID.
+var asaParsers = map[string][]*parser{}
+
+func init() {
+	add := func(p *parser, ids ...string) {
+		for _, id := range ids {
+			asaParsers[id] = append(asaParsers[id], p)
+		}
+	}
+
+	// Connections
+	add(newParser("built", `^Built (?P<direction>inbound|outbound) (?P<protocol>TCP|UDP|SCTP) connection (?P<connection_id>\d+) for `+
+		asaSrc+` \((?P<src_mapped_ip>[^/\s]+)/(?P<src_mapped_port>\d+)\)`+asaUser+` to `+
+		asaDst+` \((?P<dst_mapped_ip>[^/\s]+)/(?P<dst_mapped_port>\d+)\)`+asaUser).withPost(swapEndpoints), "302013", "302015")
+
+	add(newParser("teardown", `^Teardown (?P<protocol>TCP|UDP|SCTP) connection (?P<connection_id>\d+) for `+
+		asaSrc+asaUser+` to `+asaDst+asaUser+
+		` duration (?P<duration>\d+:\d+:\d+) bytes (?P<bytes>\d+)(?: (?P<reason>.+?))?$`), "302014", "302016")
+
+	add(newParser("built", `^Built (?P<direction>inbound|outbound) (?P<protocol>ICMP) connection for `+
+		`faddr (?P<src_ip>[^/\s]+)/\d+`+asaUser+` gaddr (?P<dst_mapped_ip>[^/\s]+)/\d+ laddr (?P<dst_ip>[^/\s]+)/\d+`).
+		withPost(swapEndpoints), "302020")
+
+	add(newParser("teardown", `^Teardown (?P<protocol>ICMP) connection for `+
+		`faddr (?P<src_ip>[^/\s]+)/\d+`+asaUser+` gaddr (?P<dst_mapped_ip>[^/\s]+)/\d+ laddr (?P<dst_ip>[^/\s]+)/\d+`), "302021")
+
+	// Denies
+	add(newParser("deny", `^(?P<direction>Inbound|Outbound) (?P<protocol>\S+) connection denied from `+
+		`(?P<src_ip>[^/\s]+)/(?P<src_port>\d+) to (?P<dst_ip>[^/\s]+)/(?P<dst_port>\d+) `+
+		`flags (?P<flags>.*?) ?on interface (?P<src_interface>\S+)`), "106001")
+
+	add(newParser("deny", `^Deny (?P<direction>inbound|outbound) (?P<protocol>\S+) from `+
+		`(?P<src_ip>[^/\s]+)/(?P<src_port>\d+) to (?P<dst_ip>[^/\s]+)/(?P<dst_port>\d+)`+
+		`(?: due to (?P<reason>[^.]+?))?(?: on interface (?P<src_interface>[^\s.]+))?\.?$`), "106006", "106007")
+
+	add(newParser("deny", `^Deny (?P<direction>inbound|outbound) (?P<protocol>icmp) `+
+		`src (?P<src_interface>[^:\s]+):(?P<src_ip>[^\s(]+)`+asaUser+` `+
+		`dst (?P<dst_interface>[^:\s]+):(?P<dst_ip>[^\s(]+)`+asaUser+` `+
+		`\(type (?P<icmp_type>\d+), code (?P<icmp_code>\d+)\)`), "106014")
+
+	add(newParser("deny", `^Deny (?P<protocol>\S+) \((?P<reason>no connection)\) from `+
+		`(?P<src_ip>[^/\s]+)/(?P<src_port>\d+) to (?P<dst_ip>[^/\s]+)/(?P<dst_port>\d+) `+
+		`flags (?P<flags>.*?) ?on interface (?P<src_interface>\S+)`), "106015")
+
+	add(newParser("deny", `^Deny (?P<protocol>\S+) `+
+		`src (?P<src_interface>[^:\s]+):(?P<src_ip>[^/\s(]+)(?:/(?P<src_port>\d+))?`+asaUser+` `+
+		`dst (?P<dst_interface>[^:\s]+):(?P<dst_ip>[^/\s(]+)(?:/(?P<dst_port>\d+))?`+asaUser+` `+
+		`(?:\(type (?P<icmp_type>\d+), code (?P<icmp_code>\d+)\) )?by access-group "(?P<acl>[^"]+)"`), "106023")
+
+	add(newParser("", `^access-list (?P<acl>\S+) (?P<action>permitted|denied|est-allowed) (?P<protocol>\S+) `+
+		`(?P<src_interface>[^/\s]+)/(?P<src_ip>[^(\s]+)\((?P<src_port>\d+)\)`+asaUser+` -> `+
+		`(?P<dst_interface>[^/\s]+)/(?P<dst_ip>[^(\s]+)\((?P<dst_port>\d+)\)`+asaUser+` hit-cnt (?P<hits>\d+)`), "106100")
+
+	add(newParser("deny", `^(?P<protocol>\S+) access denied by ACL from `+
+		`(?P<src_ip>[^/\s]+)/(?P<src_port>\d+) to `+asaDst), "710003")
+
+	// Authentication
+	add(newParser("login-success", `^AAA user (?:authentication|authorization) Successful : `+
+		`server = +(?P<server_ip>\S+) : user = (?P<user>.+)$`), "113004")
+
+	add(newParser("login-failure", `^AAA user (?:authentication|authorization) Rejected : `+
+		`reason = (?P<reason>.+?) : server = +(?P<server_ip>\S+) : user = (?P<user>.+?)`+
+		`(?: : user IP = (?P<src_ip>\S+))?$`), "113005")
+
+	// VPN sessions
+	add(newParser("session-start", vpnUser+`AnyConnect parent session started`), "113039")
+
+	add(newParser("session-end", `^Group = (?P<group>[^,]+), Username = (?P<user>[^,]*), IP = (?P<src_ip>[^,]+), `+
+		`Session disconnected\. Session Type: (?P<session_type>[^,]+), Duration: (?P<duration>[^,]+), `+
+		`Bytes xmt: (?P<bytes_out>\d+), Bytes rcv: (?P<bytes_in>\d+), Reason: (?P<reason>.+)$`), "113019")
+
+	add(newParser("session-start", vpnUser+`(?P<protocol>TCP|UDP) SVC connection established`), "722022")
+	add(newParser("session-end", vpnUser+`(?P<protocol>TCP|UDP) SVC connection terminated`), "722023")
+
+	add(newParser("", vpnUser+`WebVPN session (?P<action>started|terminated)(?:: (?P<reason>[^.]+))?`), "716001", "716002")
+}
@@ -0,0 +1,164 @@
+// Package cisco provides the cisco processor. The processor parses syslog
+// messages of Cisco ASA firewalls and IOS devices. Common messages, like
+// connections being built and torn down, denied packets and VPN sessions, are
+// parsed into normalized fields describing the action, source and
+// destination.
+package cisco
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+var debugf = logp.MakeDebug("cisco")
+
+// Platforms
+const (
+	ASA = "asa"
+	IOS = "ios"
+)
+
+var (
+	// asaHeader matches the header of ASA messages, like %ASA-6-302013:. PIX,
+	// FWSM and FTD devices use the same message IDs.
+	asaHeader = regexp.MustCompile(`%(ASA|PIX|FWSM|FTD)-(?:[A-Za-z]+-)?([0-7])-(\d{6}): ?`)
+
+	// iosHeader matches the header of IOS messages, like
+	// %SEC-6-IPACCESSLOGP:.
+	iosHeader = regexp.MustCompile(`%([A-Z][A-Z0-9_]*(?:-[A-Z][A-Z0-9_]*)?)-([0-7])-([A-Z][A-Z0-9_]*): ?`)
+)
+
+type config struct {
+	Field         string                      `config:"field"`
+	Target        string                      `config:"target"`
+	IgnoreMissing bool                        `config:"ignore_missing"`
+	Cond          *processors.ConditionConfig `config:"when"`
+}
+
+var defaultConfig = config{
+	Field:  "message",
+	Target: "cisco",
+}
+
+type cisco struct {
+	config config
+	cond   *processors.Condition
+}
+
+func init() {
+	if err := processors.RegisterPlugin("cisco", newCisco); err != nil {
+		panic(err)
+	}
+}
+
+func newCisco(c common.Config) (processors.Processor, error) {
+	config := defaultConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the cisco configuration: %s", err)
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cisco{config: config, cond: cond}, nil
+}
+
+// Run parses the Cisco message stored in the configured field and adds the
+// parsed fields to the event under the target field. Messages without a
+// parser only get the header fields.
+func (p *cisco) Run(event common.MapStr) (common.MapStr, error) {
+	if p.cond != nil && !p.cond.Check(event) {
+		return event, nil
+	}
+
+	value, err := event.GetValue(p.config.Field)
+	if err != nil {
+		if p.config.IgnoreMissing {
+			return event, nil
+		}
+		return event, fmt.Errorf("field %s not found", p.config.Field)
+	}
+
+	msg, ok := value.(string)
+	if !ok {
+		return event, fmt.Errorf("field %s is not a string", p.config.Field)
+	}
+
+	fields, err := parse(msg)
+	if err != nil {
+		return event, err
+	}
+
+	if _, err := event.Put(p.config.Target, fields); err != nil {
+		return event, fmt.Errorf("failed to set field %s: %v", p.config.Target, err)
+	}
+	return event, nil
+}
+
+// parse parses the header of msg and, if a parser for the message ID exists,
+// the message text.
+func parse(msg string) (common.MapStr, error) {
+	var (
+		fields  common.MapStr
+		parsers []*parser
+		text    string
+	)
+
+	if m := asaHeader.FindStringSubmatchIndex(msg); m != nil {
+		id := msg[m[6]:m[7]]
+		fields = common.MapStr{
+			"platform":   ASA,
+			"facility":   msg[m[2]:m[3]],
+			"severity":   int(msg[m[4]] - '0'),
+			"message_id": id,
+		}
+		parsers = asaParsers[id]
+		text = msg[m[1]:]
+	} else if m := iosHeader.FindStringSubmatchIndex(msg); m != nil {
+		facility, mnemonic := msg[m[2]:m[3]], msg[m[6]:m[7]]
+		fields = common.MapStr{
+			"platform":   IOS,
+			"facility":   facility,
+			"severity":   int(msg[m[4]] - '0'),
+			"message_id": mnemonic,
+		}
+		parsers = iosParsers[facility+"-"+mnemonic]
+		text = msg[m[1]:]
+	} else {
+		return nil, fmt.Errorf("no Cisco message header found")
+	}
+
+	for _, p := range parsers {
+		if p.parse(text, fields) {
+			return fields, nil
+		}
+	}
+	if len(parsers) > 0 {
+		debugf("no parser matched message %s: %s", fields["message_id"], text)
+	}
+	return fields, nil
+}
+
+func (p *cisco) String() string {
+	s := fmt.Sprintf("cisco=[field=%s, target=%s]", p.config.Field, p.config.Target)
+	if p.cond != nil {
+		s += ", condition=" + p.cond.String()
+	}
+	return s
+}
+
+// toInt converts numeric message fields. Values that are no numbers are kept
+// as string.
+func toInt(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	return s
+}
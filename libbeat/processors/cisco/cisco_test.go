@@ -0,0 +1,221 @@
+// +build !integration
+
+package cisco
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseASA(t *testing.T) {
+	tests := []struct {
+		msg      string
+		expected common.MapStr
+	}{
+		{
+			`Oct 10 2016 10:00:00 fw01 : %ASA-6-302013: Built inbound TCP connection 4711 for outside:203.0.113.5/51234 (203.0.113.5/51234) to inside:10.0.0.10/443 (198.51.100.10/443)`,
+			common.MapStr{
+				"facility": "ASA", "severity": 6, "message_id": "302013",
+				"action": "built", "direction": "inbound", "protocol": "tcp", "connection_id": int64(4711),
+				"src": common.MapStr{"interface": "outside", "ip": "203.0.113.5", "port": int64(51234), "mapped_ip": "203.0.113.5", "mapped_port": int64(51234)},
+				"dst": common.MapStr{"interface": "inside", "ip": "10.0.0.10", "port": int64(443), "mapped_ip": "198.51.100.10", "mapped_port": int64(443)},
+			},
+		},
+		{
+			`%ASA-6-302015: Built outbound UDP connection 99 for outside:8.8.8.8/53 (8.8.8.8/53) to inside:10.0.0.10/5353 (198.51.100.10/5353)`,
+			common.MapStr{
+				"facility": "ASA", "severity": 6, "message_id": "302015",
+				"action": "built", "direction": "outbound", "protocol": "udp", "connection_id": int64(99),
+				"src": common.MapStr{"interface": "inside", "ip": "10.0.0.10", "port": int64(5353), "mapped_ip": "198.51.100.10", "mapped_port": int64(5353)},
+				"dst": common.MapStr{"interface": "outside", "ip": "8.8.8.8", "port": int64(53), "mapped_ip": "8.8.8.8", "mapped_port": int64(53)},
+			},
+		},
+		{
+			`%ASA-6-302014: Teardown TCP connection 4711 for outside:203.0.113.5/51234 to inside:10.0.0.10/443 duration 0:01:30 bytes 10240 TCP FINs`,
+			common.MapStr{
+				"facility": "ASA", "severity": 6, "message_id": "302014",
+				"action": "teardown", "protocol": "tcp", "connection_id": int64(4711),
+				"duration": int64(90), "bytes": int64(10240), "reason": "TCP FINs",
+				"src": common.MapStr{"interface": "outside", "ip": "203.0.113.5", "port": int64(51234)},
+				"dst": common.MapStr{"interface": "inside", "ip": "10.0.0.10", "port": int64(443)},
+			},
+		},
+		{
+			`%ASA-4-106023: Deny tcp src outside:203.0.113.5/4444 dst inside:10.0.0.10/22 by access-group "outside_in" [0x0, 0x0]`,
+			common.MapStr{
+				"facility": "ASA", "severity": 4, "message_id": "106023",
+				"action": "deny", "protocol": "tcp", "acl": "outside_in",
+				"src": common.MapStr{"interface": "outside", "ip": "203.0.113.5", "port": int64(4444)},
+				"dst": common.MapStr{"interface": "inside", "ip": "10.0.0.10", "port": int64(22)},
+			},
+		},
+		{
+			`%ASA-4-106023: Deny icmp src outside:203.0.113.5 dst inside:10.0.0.10 (type 8, code 0) by access-group "outside_in" [0x0, 0x0]`,
+			common.MapStr{
+				"facility": "ASA", "severity": 4, "message_id": "106023",
+				"action": "deny", "protocol": "icmp", "acl": "outside_in",
+				"src":  common.MapStr{"interface": "outside", "ip": "203.0.113.5"},
+				"dst":  common.MapStr{"interface": "inside", "ip": "10.0.0.10"},
+				"icmp": common.MapStr{"type": int64(8), "code": int64(0)},
+			},
+		},
+		{
+			`%ASA-2-106001: Inbound TCP connection denied from 203.0.113.5/4444 to 10.0.0.10/80 flags SYN  on interface outside`,
+			common.MapStr{
+				"facility": "ASA", "severity": 2, "message_id": "106001",
+				"action": "deny", "direction": "inbound", "protocol": "tcp", "flags": "SYN ",
+				"src": common.MapStr{"interface": "outside", "ip": "203.0.113.5", "port": int64(4444)},
+				"dst": common.MapStr{"ip": "10.0.0.10", "port": int64(80)},
+			},
+		},
+		{
+			`%ASA-6-106100: access-list inside_out permitted udp inside/10.0.0.10(5353) -> outside/8.8.8.8(53) hit-cnt 1 first hit [0x1, 0x0]`,
+			common.MapStr{
+				"facility": "ASA", "severity": 6, "message_id": "106100",
+				"action": "permit", "protocol": "udp", "acl": "inside_out", "hits": int64(1),
+				"src": common.MapStr{"interface": "inside", "ip": "10.0.0.10", "port": int64(5353)},
+				"dst": common.MapStr{"interface": "outside", "ip": "8.8.8.8", "port": int64(53)},
+			},
+		},
+		{
+			`%ASA-4-113019: Group = RemoteAccess, Username = alice, IP = 203.0.113.5, Session disconnected. Session Type: SSL, Duration: 1d 2h:03m:04s, Bytes xmt: 1000, Bytes rcv: 2000, Reason: User Requested`,
+			common.MapStr{
+				"facility": "ASA", "severity": 4, "message_id": "113019",
+				"action": "session-end", "group": "RemoteAccess", "user": "alice", "session_type": "SSL",
+				"duration": int64(93784), "bytes_out": int64(1000), "bytes_in": int64(2000), "reason": "User Requested",
+				"src": common.MapStr{"ip": "203.0.113.5"},
+			},
+		},
+		{
+			`%ASA-6-722022: Group <GroupPolicy> User <alice> IP <203.0.113.5> TCP SVC connection established without compression`,
+			common.MapStr{
+				"facility": "ASA", "severity": 6, "message_id": "722022",
+				"action": "session-start", "group": "GroupPolicy", "user": "alice", "protocol": "tcp",
+				"src": common.MapStr{"ip": "203.0.113.5"},
+			},
+		},
+		{
+			`%ASA-2-113005: AAA user authentication Rejected : reason = AAA failure : server = 10.0.0.5 : user = bob : user IP = 203.0.113.7`,
+			common.MapStr{
+				"facility": "ASA", "severity": 2, "message_id": "113005",
+				"action": "login-failure", "reason": "AAA failure", "server_ip": "10.0.0.5", "user": "bob",
+				"src": common.MapStr{"ip": "203.0.113.7"},
+			},
+		},
+		{
+			// messages without parser only get the header fields
+			`%FTD-6-430002: EventPriority: Low, ConnectionID: 1`,
+			common.MapStr{"facility": "FTD", "severity": 6, "message_id": "430002"},
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := parse(test.msg)
+		if !assert.NoError(t, err, test.msg) {
+			continue
+		}
+		test.expected["platform"] = ASA
+		assert.Equal(t, test.expected, fields, test.msg)
+	}
+}
+
+func TestParseIOS(t *testing.T) {
+	tests := []struct {
+		msg      string
+		expected common.MapStr
+	}{
+		{
+			`*Mar  1 00:01:02.345: %SEC-6-IPACCESSLOGP: list 101 denied tcp 203.0.113.5(4444) (GigabitEthernet0/1 0011.2233.4455) -> 10.0.0.10(23), 1 packet`,
+			common.MapStr{
+				"facility": "SEC", "severity": 6, "message_id": "IPACCESSLOGP",
+				"action": "deny", "protocol": "tcp", "acl": "101", "hits": int64(1),
+				"src": common.MapStr{"ip": "203.0.113.5", "port": int64(4444), "interface": "GigabitEthernet0/1"},
+				"dst": common.MapStr{"ip": "10.0.0.10", "port": int64(23)},
+			},
+		},
+		{
+			`%SEC-6-IPACCESSLOGDP: list OUTSIDE permitted icmp 203.0.113.5 -> 10.0.0.10 (8/0), 5 packets`,
+			common.MapStr{
+				"facility": "SEC", "severity": 6, "message_id": "IPACCESSLOGDP",
+				"action": "permit", "protocol": "icmp", "acl": "OUTSIDE", "hits": int64(5),
+				"src":  common.MapStr{"ip": "203.0.113.5"},
+				"dst":  common.MapStr{"ip": "10.0.0.10"},
+				"icmp": common.MapStr{"type": int64(8), "code": int64(0)},
+			},
+		},
+		{
+			`%SEC_LOGIN-4-LOGIN_FAILED: Login failed [user: admin] [Source: 203.0.113.5] [localport: 22] [Reason: Login Authentication Failed] at 10:00:00 UTC Mon Oct 10 2016`,
+			common.MapStr{
+				"facility": "SEC_LOGIN", "severity": 4, "message_id": "LOGIN_FAILED",
+				"action": "login-failure", "user": "admin", "reason": "Login Authentication Failed",
+				"src": common.MapStr{"ip": "203.0.113.5"},
+				"dst": common.MapStr{"port": int64(22)},
+			},
+		},
+		{
+			`%LINK-5-CHANGED: Interface GigabitEthernet0/2, changed state to administratively down`,
+			common.MapStr{
+				"facility": "LINK", "severity": 5, "message_id": "CHANGED",
+				"action": "down", "interface": "GigabitEthernet0/2",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := parse(test.msg)
+		if !assert.NoError(t, err, test.msg) {
+			continue
+		}
+		test.expected["platform"] = IOS
+		assert.Equal(t, test.expected, fields, test.msg)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for s, expected := range map[string]int64{
+		"0:00:30":       30,
+		"12:01:02":      43262,
+		"0h:10m:00s":    600,
+		"1d 2h:03m:04s": 93784,
+	} {
+		seconds, err := parseDuration(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, seconds, s)
+	}
+
+	_, err := parseDuration("10s")
+	assert.Error(t, err)
+}
+
+func TestCiscoProcessor(t *testing.T) {
+	c, err := common.NewConfigFrom(map[string]interface{}{
+		"target":         "firewall",
+		"ignore_missing": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newCisco(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := p.Run(common.MapStr{
+		"message": `%ASA-4-710003: TCP access denied by ACL from 203.0.113.5/4444 to outside:198.51.100.1/22`,
+	})
+	assert.NoError(t, err)
+	action, _ := event.GetValue("firewall.action")
+	assert.Equal(t, "deny", action)
+	port, _ := event.GetValue("firewall.dst.port")
+	assert.Equal(t, int64(22), port)
+
+	event, err = p.Run(common.MapStr{})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{}, event)
+
+	event, err = p.Run(common.MapStr{"message": "not a cisco message"})
+	assert.Error(t, err)
+	assert.Equal(t, common.MapStr{"message": "not a cisco message"}, event)
+}
@@ -0,0 +1,43 @@
+package cisco
+
+// iosInterface matches the optional input interface, and MAC address, logged
+// after the source address of IOS access list messages.
+const iosInterface = `(?: \((?P<src_interface>[^\s)]+)(?: [^)]*)?\))?`
+
+// iosParsers are the parsers of IOS messages by facility and mnemonic, e.g.
+// SEC-IPACCESSLOGP.
+var iosParsers = map[string][]*parser{}
+
+func init() {
+	add := func(p *parser, ids ...string) {
+		for _, id := range ids {
+			iosParsers[id] = append(iosParsers[id], p)
+		}
+	}
+
+	// Access lists
+	add(newParser("", `^list (?P<acl>\S+) (?P<action>denied|permitted) (?P<protocol>tcp|udp) `+
+		`(?P<src_ip>[^(\s]+)\((?P<src_port>\d+)\)`+iosInterface+` -> `+
+		`(?P<dst_ip>[^(\s]+)\((?P<dst_port>\d+)\), (?P<hits>\d+) packets?`), "SEC-IPACCESSLOGP")
+
+	add(newParser("", `^list (?P<acl>\S+) (?P<action>denied|permitted) (?P<protocol>icmp) `+
+		`(?P<src_ip>[^(\s]+)`+iosInterface+` -> (?P<dst_ip>\S+) `+
+		`\((?P<icmp_type>\d+)/(?P<icmp_code>\d+)\), (?P<hits>\d+) packets?`), "SEC-IPACCESSLOGDP")
+
+	add(newParser("", `^list (?P<acl>\S+) (?P<action>denied|permitted) (?P<protocol>\S+) `+
+		`(?P<src_ip>[^(\s]+)`+iosInterface+` -> (?P<dst_ip>[^,\s]+), (?P<hits>\d+) packets?`), "SEC-IPACCESSLOGNP", "SEC-IPACCESSLOGRP")
+
+	add(newParser("", `^list (?P<acl>\S+) (?P<action>denied|permitted) `+
+		`(?P<src_ip>[^(\s]+)`+iosInterface+` (?P<hits>\d+) packets?`), "SEC-IPACCESSLOGS")
+
+	// Logins
+	add(newParser("login-success", `^Login Success \[user: (?P<user>[^\]]*)\] \[Source: (?P<src_ip>[^\]]*)\] `+
+		`\[localport: (?P<dst_port>\d+)\]`), "SEC_LOGIN-LOGIN_SUCCESS")
+
+	add(newParser("login-failure", `^Login failed \[user: (?P<user>[^\]]*)\] \[Source: (?P<src_ip>[^\]]*)\] `+
+		`\[localport: (?P<dst_port>\d+)\](?: \[Reason: (?P<reason>[^\]]*)\])?`), "SEC_LOGIN-LOGIN_FAILED")
+
+	// Interface state
+	add(newParser("", `^Interface (?P<interface>[^,]+), changed state to (?P<action>up|down|administratively down)`), "LINK-UPDOWN", "LINK-CHANGED")
+	add(newParser("", `^Line protocol on Interface (?P<interface>[^,]+), changed state to (?P<action>up|down)`), "LINEPROTO-UPDOWN")
+}
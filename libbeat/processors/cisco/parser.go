@@ -0,0 +1,157 @@
+package cisco
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// parser parses the text of a message into fields. The fields are named after
+// the named groups of the regular expression. Groups prefixed by src_, dst_ or
+// icmp_ are stored as nested fields, e.g. src_ip is stored as src.ip.
+type parser struct {
+	re *regexp.Regexp
+
+	// action is set as action field if the action is not captured.
+	action string
+
+	// post, if set, is called with the parsed fields.
+	post func(fields common.MapStr)
+}
+
+func newParser(action string, expr string) *parser {
+	return &parser{re: regexp.MustCompile(expr), action: action}
+}
+
+// withPost sets the function called with the parsed fields.
+func (p *parser) withPost(post func(fields common.MapStr)) *parser {
+	p.post = post
+	return p
+}
+
+// nestedPrefixes are the group name prefixes stored as nested fields.
+var nestedPrefixes = []string{"src_", "dst_", "icmp_"}
+
+// intFields are converted to numbers.
+var intFields = map[string]bool{
+	"port":          true,
+	"mapped_port":   true,
+	"connection_id": true,
+	"bytes":         true,
+	"bytes_in":      true,
+	"bytes_out":     true,
+	"hits":          true,
+	"type":          true,
+	"code":          true,
+}
+
+// actions normalizes the captured actions.
+var actions = map[string]string{
+	"denied":                "deny",
+	"permitted":             "permit",
+	"est-allowed":           "permit",
+	"started":               "session-start",
+	"terminated":            "session-end",
+	"administratively down": "down",
+}
+
+func (p *parser) parse(text string, fields common.MapStr) bool {
+	m := p.re.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+
+	if p.action != "" {
+		fields["action"] = p.action
+	}
+
+	for i, name := range p.re.SubexpNames() {
+		if name == "" || m[i] == "" {
+			continue
+		}
+
+		var value interface{} = m[i]
+		switch name {
+		case "action":
+			action := strings.ToLower(m[i])
+			if normalized, ok := actions[action]; ok {
+				action = normalized
+			}
+			value = action
+		case "direction", "protocol":
+			value = strings.ToLower(m[i])
+		case "duration":
+			if seconds, err := parseDuration(m[i]); err == nil {
+				value = seconds
+			}
+		}
+
+		key := name
+		for _, prefix := range nestedPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				key = strings.TrimSuffix(prefix, "_") + "." + name[len(prefix):]
+				name = name[len(prefix):]
+				break
+			}
+		}
+		if intFields[name] {
+			value = toInt(m[i])
+		}
+
+		fields.Put(key, value)
+	}
+
+	if p.post != nil {
+		p.post(fields)
+	}
+	return true
+}
+
+// parseDuration parses the durations logged by ASA devices, like 1:02:03 or
+// 1d 2h:03m:04s, into seconds.
+func parseDuration(s string) (int64, error) {
+	var days int64
+	if i := strings.Index(s, "d "); i >= 0 {
+		d, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		days, s = d, s[i+2:]
+	}
+
+	parts := strings.Split(strings.NewReplacer("h", "", "m", "", "s", "").Replace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	var seconds int64
+	for _, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		seconds = seconds*60 + n
+	}
+	return days*24*3600 + seconds, nil
+}
+
+// swapEndpoints swaps the src and dst fields. The ASA logs the foreign
+// address first, which is the destination of outbound connections.
+func swapEndpoints(fields common.MapStr) {
+	if fields["direction"] != "outbound" {
+		return
+	}
+	src, hasSrc := fields["src"]
+	dst, hasDst := fields["dst"]
+	delete(fields, "src")
+	delete(fields, "dst")
+	if hasSrc {
+		fields["dst"] = src
+	}
+	if hasDst {
+		fields["src"] = dst
+	}
+}
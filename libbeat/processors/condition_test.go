@@ -534,3 +534,47 @@ func TestCombinedCondition(t *testing.T) {
 	assert.True(t, conds[0].Check(event))
 
 }
+
+func TestMetadataCondition(t *testing.T) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"and": []interface{}{
+			map[string]interface{}{
+				"equals": map[string]interface{}{
+					"@metadata.input": "tcp",
+				},
+			},
+			map[string]interface{}{
+				"regexp": map[string]interface{}{
+					"@metadata": map[string]interface{}{"host": "^web-"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := ConditionConfig{}
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	cond, err := NewCondition(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := common.MapStr{
+		"message": "hello",
+		common.MetadataKey: common.MapStr{
+			"input": "tcp",
+			"host":  "web-01",
+		},
+	}
+	assert.True(t, cond.Check(event))
+
+	event[common.MetadataKey] = common.MapStr{"input": "udp", "host": "web-01"}
+	assert.False(t, cond.Check(event))
+
+	assert.False(t, cond.Check(common.MapStr{"message": "hello"}))
+}
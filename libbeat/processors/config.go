@@ -25,7 +25,7 @@ type ConditionFields struct {
 type PluginConfig []map[string]common.Config
 
 // fields that should be always exported
-var MandatoryExportedFields = []string{"@timestamp", "type"}
+var MandatoryExportedFields = []string{"@timestamp", "type", common.MetadataKey}
 
 func (f *ConditionFields) Unpack(to interface{}) error {
 	m, ok := to.(map[string]interface{})
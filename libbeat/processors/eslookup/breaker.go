@@ -0,0 +1,68 @@
+package eslookup
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a simple circuit breaker. After maxFailures consecutive failures
+// the breaker opens and rejects all requests for resetAfter. Once resetAfter
+// has passed, a single trial request is allowed. The breaker is closed again
+// on the first successful request.
+type breaker struct {
+	mutex       sync.Mutex
+	maxFailures int
+	resetAfter  time.Duration
+	now         func() time.Time
+
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(maxFailures int, resetAfter time.Duration) *breaker {
+	return &breaker{
+		maxFailures: maxFailures,
+		resetAfter:  resetAfter,
+		now:         time.Now,
+	}
+}
+
+// Allow reports whether a request may be executed.
+func (b *breaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.maxFailures {
+		return true
+	}
+
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+
+	// half-open: allow one trial request and re-arm the timer in case it fails
+	b.openUntil = now.Add(b.resetAfter)
+	return true
+}
+
+// Success reports a successful request, closing the breaker.
+func (b *breaker) Success() {
+	b.mutex.Lock()
+	b.failures = 0
+	b.mutex.Unlock()
+}
+
+// Failure reports a failed request. Returns true if the breaker has been
+// opened by this failure.
+func (b *breaker) Failure() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.failures == b.maxFailures {
+		b.openUntil = b.now().Add(b.resetAfter)
+		return true
+	}
+	return false
+}
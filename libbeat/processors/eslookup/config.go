@@ -0,0 +1,62 @@
+package eslookup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+type config struct {
+	Hosts    []string           `config:"hosts" validate:"required"`
+	Protocol string             `config:"protocol"`
+	Path     string             `config:"path"`
+	Username string             `config:"username"`
+	Password string             `config:"password"`
+	TLS      *outputs.TLSConfig `config:"tls"`
+	Timeout  time.Duration      `config:"timeout"`
+
+	Index       string   `config:"index" validate:"required"`
+	Field       string   `config:"field" validate:"required"`
+	LookupField string   `config:"lookup_field" validate:"required"`
+	Fields      []string `config:"fields"`
+	Target      string   `config:"target"`
+
+	Cache          cacheConfig                 `config:"cache"`
+	CircuitBreaker breakerConfig               `config:"circuit_breaker"`
+	Cond           *processors.ConditionConfig `config:"when"`
+}
+
+type cacheConfig struct {
+	TTL         time.Duration `config:"ttl"`
+	InitialSize int           `config:"initial_size" validate:"min=0"`
+}
+
+type breakerConfig struct {
+	MaxFailures int           `config:"max_failures" validate:"min=1"`
+	ResetAfter  time.Duration `config:"reset_after"`
+}
+
+var defaultConfig = config{
+	Timeout: 5 * time.Second,
+	Target:  "lookup",
+	Cache: cacheConfig{
+		TTL:         5 * time.Minute,
+		InitialSize: 1000,
+	},
+	CircuitBreaker: breakerConfig{
+		MaxFailures: 5,
+		ResetAfter:  30 * time.Second,
+	},
+}
+
+func (c *config) Validate() error {
+	if c.Cache.TTL <= 0 {
+		return fmt.Errorf("cache.ttl must be greater than 0")
+	}
+	if c.CircuitBreaker.ResetAfter <= 0 {
+		return fmt.Errorf("circuit_breaker.reset_after must be greater than 0")
+	}
+	return nil
+}
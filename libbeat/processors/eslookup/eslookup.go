@@ -0,0 +1,294 @@
+// Package eslookup provides the elasticsearch_lookup processor. The processor
+// enriches events with fields from a document stored in Elasticsearch, which is
+// selected by a term query on the value of an event field.
+package eslookup
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/outputs/elasticsearch"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	cacheHits      = expvar.NewInt("libbeat.processors.elasticsearch_lookup.cache_hits")
+	cacheMisses    = expvar.NewInt("libbeat.processors.elasticsearch_lookup.cache_misses")
+	lookupErrors   = expvar.NewInt("libbeat.processors.elasticsearch_lookup.errors")
+	lookupsSkipped = expvar.NewInt("libbeat.processors.elasticsearch_lookup.skipped")
+)
+
+var errBreakerOpen = errors.New("circuit breaker open, lookup skipped")
+
+var debugf = logp.MakeDebug("eslookup")
+
+type searcher interface {
+	Search(
+		index string, docType string,
+		params map[string]string,
+		body interface{},
+	) (int, *elasticsearch.SearchResults, error)
+}
+
+type lookup struct {
+	config    config
+	cond      *processors.Condition
+	breaker   *breaker
+	clients   []*lookupClient
+	closeOnce sync.Once
+
+	// mutex protects the cache, the queries in flight and the active client.
+	// It is never held while querying Elasticsearch.
+	mutex  sync.Mutex
+	cache  *common.Cache
+	calls  map[string]*call
+	active int
+}
+
+// lookupClient serializes the requests sent with a client, as connections are
+// not safe for concurrent use.
+type lookupClient struct {
+	mutex sync.Mutex
+	searcher
+}
+
+// call is a query in flight. Concurrent lookups of the same key wait for the
+// result of the call instead of querying Elasticsearch themselves.
+type call struct {
+	done   chan struct{}
+	fields common.MapStr
+	err    error
+}
+
+// cacheEntry holds a lookup result. Entries for missing documents are cached
+// too (fields == nil) to not query Elasticsearch for every unknown key.
+type cacheEntry struct {
+	fields  common.MapStr
+	expires time.Time
+}
+
+func init() {
+	if err := processors.RegisterPlugin("elasticsearch_lookup", newLookup); err != nil {
+		panic(err)
+	}
+}
+
+func newLookup(c common.Config) (processors.Processor, error) {
+	config := defaultConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the elasticsearch_lookup configuration: %s", err)
+	}
+
+	tlsConfig, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []searcher
+	for _, host := range config.Hosts {
+		esURL, err := elasticsearch.MakeURL(config.Protocol, config.Path, host)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host '%s' in elasticsearch_lookup: %v", host, err)
+		}
+
+		client, err := elasticsearch.NewClient(
+			esURL, config.Index, nil, tlsConfig,
+			config.Username, config.Password,
+			nil, config.Timeout, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	return newLookupWithClients(config, clients)
+}
+
+func newLookupWithClients(config config, clients []searcher) (*lookup, error) {
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+
+	var lookupClients []*lookupClient
+	for _, client := range clients {
+		lookupClients = append(lookupClients, &lookupClient{searcher: client})
+	}
+
+	cache := common.NewCache(config.Cache.TTL, config.Cache.InitialSize)
+	cache.StartJanitor(config.Cache.TTL)
+
+	return &lookup{
+		config:  config,
+		cond:    cond,
+		breaker: newBreaker(config.CircuitBreaker.MaxFailures, config.CircuitBreaker.ResetAfter),
+		clients: lookupClients,
+		cache:   cache,
+		calls:   map[string]*call{},
+	}, nil
+}
+
+// Close stops the janitor of the cache.
+func (l *lookup) Close() error {
+	l.closeOnce.Do(l.cache.StopJanitor)
+	return nil
+}
+
+func (l *lookup) Run(event common.MapStr) (common.MapStr, error) {
+	if l.cond != nil && !l.cond.Check(event) {
+		return event, nil
+	}
+
+	value, err := event.GetValue(l.config.Field)
+	if err != nil || value == nil {
+		return event, nil
+	}
+	key := fmt.Sprint(value)
+
+	fields, err := l.get(key)
+	if err != nil {
+		return event, err
+	}
+	if fields == nil {
+		return event, nil
+	}
+
+	_, err = event.Put(l.config.Target, fields.Clone())
+	return event, err
+}
+
+// get returns the fields of the document of key, from the cache if possible.
+// The fields returned must not be modified.
+func (l *lookup) get(key string) (common.MapStr, error) {
+	now := time.Now()
+
+	l.mutex.Lock()
+	if v := l.cache.Get(key); v != nil {
+		entry := v.(*cacheEntry)
+		if now.Before(entry.expires) {
+			l.mutex.Unlock()
+			cacheHits.Add(1)
+			return entry.fields, nil
+		}
+	}
+	cacheMisses.Add(1)
+
+	if c, found := l.calls[key]; found {
+		l.mutex.Unlock()
+		<-c.done
+		return c.fields, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	l.calls[key] = c
+	l.mutex.Unlock()
+
+	c.fields, c.err = l.fetch(key)
+
+	l.mutex.Lock()
+	if c.err == nil {
+		l.cache.Put(key, &cacheEntry{fields: c.fields, expires: now.Add(l.config.Cache.TTL)})
+	}
+	delete(l.calls, key)
+	l.mutex.Unlock()
+
+	close(c.done)
+	return c.fields, c.err
+}
+
+// fetch queries the document of key, unless the circuit breaker is open.
+func (l *lookup) fetch(key string) (common.MapStr, error) {
+	if !l.breaker.Allow() {
+		lookupsSkipped.Add(1)
+		return nil, errBreakerOpen
+	}
+
+	fields, err := l.query(key)
+	if err != nil {
+		lookupErrors.Add(1)
+		if l.breaker.Failure() {
+			logp.Warn("elasticsearch_lookup: %d consecutive failures, pausing lookups for %v",
+				l.config.CircuitBreaker.MaxFailures, l.config.CircuitBreaker.ResetAfter)
+		}
+		return nil, err
+	}
+	l.breaker.Success()
+	return fields, nil
+}
+
+// query executes the term query for key. If a request fails, the next
+// configured host is used for subsequent requests.
+func (l *lookup) query(key string) (common.MapStr, error) {
+	body := common.MapStr{
+		"size": 1,
+		"query": common.MapStr{
+			"term": common.MapStr{
+				l.config.LookupField: key,
+			},
+		},
+	}
+	if len(l.config.Fields) > 0 {
+		body["_source"] = l.config.Fields
+	}
+
+	l.mutex.Lock()
+	active := l.active
+	l.mutex.Unlock()
+
+	client := l.clients[active]
+	client.mutex.Lock()
+	_, result, err := client.Search(l.config.Index, "", nil, body)
+	client.mutex.Unlock()
+
+	if err != nil {
+		l.mutex.Lock()
+		if l.active == active {
+			l.active = (active + 1) % len(l.clients)
+		}
+		l.mutex.Unlock()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch_lookup query failed: %v", err)
+	}
+	if len(result.Hits.Hits) == 0 {
+		debugf("no document found for %s=%s", l.config.LookupField, key)
+		return nil, nil
+	}
+
+	var hit struct {
+		Source common.MapStr `json:"_source"`
+	}
+	if err := json.Unmarshal(result.Hits.Hits[0], &hit); err != nil {
+		return nil, fmt.Errorf("failed to decode elasticsearch_lookup result: %v", err)
+	}
+	return normalize(hit.Source), nil
+}
+
+// normalize converts nested JSON objects into common.MapStr.
+func normalize(m common.MapStr) common.MapStr {
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			m[k] = normalize(common.MapStr(nested))
+		}
+	}
+	return m
+}
+
+func (l *lookup) String() string {
+	s := fmt.Sprintf("elasticsearch_lookup=[hosts=%s, index=%s, field=%s, lookup_field=%s, target=%s]",
+		strings.Join(l.config.Hosts, ","), l.config.Index, l.config.Field,
+		l.config.LookupField, l.config.Target)
+	if l.cond != nil {
+		s += ", condition=" + l.cond.String()
+	}
+	return s
+}
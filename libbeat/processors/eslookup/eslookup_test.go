@@ -0,0 +1,184 @@
+// +build !integration
+
+package eslookup
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/outputs/elasticsearch"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSearcher struct {
+	calls int
+	docs  map[string]common.MapStr
+	err   error
+}
+
+func (m *mockSearcher) Search(
+	index string, docType string,
+	params map[string]string,
+	body interface{},
+) (int, *elasticsearch.SearchResults, error) {
+	m.calls++
+	if m.err != nil {
+		return 500, nil, m.err
+	}
+
+	query := body.(common.MapStr)["query"].(common.MapStr)["term"].(common.MapStr)
+	result := &elasticsearch.SearchResults{}
+	for _, v := range query {
+		if doc, ok := m.docs[v.(string)]; ok {
+			raw, _ := json.Marshal(common.MapStr{"_source": doc})
+			result.Hits.Hits = append(result.Hits.Hits, raw)
+		}
+	}
+	return 200, result, nil
+}
+
+// blockingSearcher blocks all searches until release is closed.
+type blockingSearcher struct {
+	mockSearcher
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingSearcher(docs map[string]common.MapStr) *blockingSearcher {
+	return &blockingSearcher{
+		mockSearcher: mockSearcher{docs: docs},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+}
+
+func (m *blockingSearcher) Search(
+	index string, docType string,
+	params map[string]string,
+	body interface{},
+) (int, *elasticsearch.SearchResults, error) {
+	select {
+	case m.started <- struct{}{}:
+	default:
+	}
+	<-m.release
+	return m.mockSearcher.Search(index, docType, params, body)
+}
+
+func newTestLookup(t *testing.T, s searcher) *lookup {
+	c := defaultConfig
+	c.Index = "assets"
+	c.Field = "host.ip"
+	c.LookupField = "ip"
+	c.Target = "asset"
+	c.CircuitBreaker.MaxFailures = 2
+	c.CircuitBreaker.ResetAfter = time.Hour
+
+	l, err := newLookupWithClients(c, []searcher{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestLookupEnrichesAndCaches(t *testing.T) {
+	s := &mockSearcher{docs: map[string]common.MapStr{
+		"10.0.0.1": {"owner": "ops", "criticality": "high"},
+	}}
+	l := newTestLookup(t, s)
+
+	for i := 0; i < 3; i++ {
+		event, err := l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.1"}})
+		assert.Nil(t, err)
+		assert.Equal(t, common.MapStr{"owner": "ops", "criticality": "high"}, event["asset"])
+	}
+	assert.Equal(t, 1, s.calls)
+
+	// missing documents are cached too
+	for i := 0; i < 2; i++ {
+		event, err := l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.2"}})
+		assert.Nil(t, err)
+		assert.Nil(t, event["asset"])
+	}
+	assert.Equal(t, 2, s.calls)
+}
+
+// Concurrent lookups of the same key send a single query, and cached keys are
+// looked up while the query is in flight.
+func TestLookupConcurrentMisses(t *testing.T) {
+	s := newBlockingSearcher(map[string]common.MapStr{
+		"10.0.0.1": {"owner": "ops"},
+		"10.0.0.2": {"owner": "dev"},
+	})
+	l := newTestLookup(t, s)
+	defer l.Close()
+
+	close(s.release)
+	_, err := l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.2"}})
+	assert.Nil(t, err)
+	<-s.started
+	s.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	events := make([]common.MapStr, 5)
+	for i := range events {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			events[i], _ = l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.1"}})
+		}(i)
+	}
+	<-s.started
+
+	event, err := l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.2"}})
+	assert.Nil(t, err)
+	assert.Equal(t, common.MapStr{"owner": "dev"}, event["asset"])
+
+	time.Sleep(50 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	assert.Equal(t, 2, s.calls)
+	for _, event := range events {
+		assert.Equal(t, common.MapStr{"owner": "ops"}, event["asset"])
+	}
+}
+
+func TestLookupClose(t *testing.T) {
+	l := newTestLookup(t, &mockSearcher{})
+	assert.Nil(t, l.Close())
+	assert.Nil(t, l.Close())
+}
+
+func TestLookupCircuitBreaker(t *testing.T) {
+	s := &mockSearcher{err: errors.New("unavailable")}
+	l := newTestLookup(t, s)
+
+	for i := 0; i < 5; i++ {
+		event, err := l.Run(common.MapStr{"host": common.MapStr{"ip": "10.0.0.1"}})
+		assert.NotNil(t, err)
+		assert.NotNil(t, event)
+	}
+	assert.Equal(t, 2, s.calls)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	now := time.Now()
+	b := newBreaker(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.Allow())
+	assert.True(t, b.Failure())
+	assert.False(t, b.Allow())
+
+	now = now.Add(2 * time.Second)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	b.Success()
+	assert.True(t, b.Allow())
+}
@@ -0,0 +1,167 @@
+// Package grok provides the grok processor. The processor parses unstructured
+// text, like log lines, into fields using grok patterns. The standard pattern
+// library is bundled, such that common formats can be parsed without an
+// ingest node.
+package grok
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	parseFailures = expvar.NewInt("libbeat.processors.grok.failures")
+)
+
+var debugf = logp.MakeDebug("grok")
+
+// defaultLibrary is the bundled pattern library, shared by all processors.
+var defaultLibrary = library{}
+
+func init() {
+	if err := defaultLibrary.parsePatterns(strings.NewReader(defaultPatterns)); err != nil {
+		panic(err)
+	}
+
+	if err := processors.RegisterPlugin("grok", newGrok); err != nil {
+		panic(err)
+	}
+}
+
+type config struct {
+	Field              string                      `config:"field"`
+	Patterns           []string                    `config:"patterns" validate:"required"`
+	PatternDefinitions map[string]string           `config:"pattern_definitions"`
+	PatternFiles       []string                    `config:"pattern_files"`
+	Target             string                      `config:"target"`
+	IgnoreMissing      bool                        `config:"ignore_missing"`
+	TagOnFailure       []string                    `config:"tag_on_failure"`
+	Cond               *processors.ConditionConfig `config:"when"`
+}
+
+var defaultConfig = config{
+	Field:        "message",
+	TagOnFailure: []string{"_grokparsefailure"},
+}
+
+type grok struct {
+	config   config
+	patterns []*pattern
+	cond     *processors.Condition
+}
+
+func newGrok(c common.Config) (processors.Processor, error) {
+	config := defaultConfig
+	if err := c.Unpack(&config); err != nil {
+		return nil, fmt.Errorf("fail to unpack the grok configuration: %s", err)
+	}
+
+	// Custom patterns extend the bundled library and take precedence over
+	// bundled patterns of the same name. Inline definitions are applied last.
+	lib := library{}
+	for name, def := range defaultLibrary {
+		lib[name] = def
+	}
+	for _, path := range config.PatternFiles {
+		if err := lib.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	for name, def := range config.PatternDefinitions {
+		lib[name] = def
+	}
+
+	g := &grok{config: config}
+	for _, expr := range config.Patterns {
+		p, err := lib.compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		g.patterns = append(g.patterns, p)
+	}
+
+	cond, err := processors.NewCondition(config.Cond)
+	if err != nil {
+		return nil, err
+	}
+	g.cond = cond
+
+	return g, nil
+}
+
+// Run matches the patterns in order against the configured field and adds the
+// fields captured by the first matching pattern to the event. Captured fields
+// overwrite existing fields. If no pattern matches, the event is tagged with
+// the tag_on_failure tags.
+func (g *grok) Run(event common.MapStr) (common.MapStr, error) {
+	if g.cond != nil && !g.cond.Check(event) {
+		return event, nil
+	}
+
+	value, err := event.GetValue(g.config.Field)
+	if err != nil {
+		if g.config.IgnoreMissing {
+			return event, nil
+		}
+		return event, g.fail(event, fmt.Errorf("field %s not found", g.config.Field))
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return event, g.fail(event, fmt.Errorf("field %s is not a string", g.config.Field))
+	}
+
+	for _, p := range g.patterns {
+		fields, matched, err := p.match(s)
+		if err != nil {
+			return event, g.fail(event, err)
+		}
+		if !matched {
+			continue
+		}
+
+		for field, value := range fields {
+			if g.config.Target != "" {
+				field = g.config.Target + "." + field
+			}
+			if _, err := event.Put(field, value); err != nil {
+				return event, fmt.Errorf("failed to set field %s: %v", field, err)
+			}
+		}
+		return event, nil
+	}
+
+	return event, g.fail(event, fmt.Errorf("no grok pattern matched field %s", g.config.Field))
+}
+
+// fail tags the event as not parsed and returns err.
+func (g *grok) fail(event common.MapStr, err error) error {
+	parseFailures.Add(1)
+	debugf("grok failed: %v", err)
+
+	tags := make([]string, len(g.config.TagOnFailure))
+	copy(tags, g.config.TagOnFailure)
+	if tagErr := common.AddTags(event, tags); tagErr != nil {
+		return fmt.Errorf("%v, failed to add tags: %v", err, tagErr)
+	}
+	return err
+}
+
+func (g *grok) String() string {
+	sources := make([]string, len(g.patterns))
+	for i, p := range g.patterns {
+		sources[i] = p.source
+	}
+
+	s := fmt.Sprintf("grok=[field=%s, patterns=%s]", g.config.Field, strings.Join(sources, ", "))
+	if g.cond != nil {
+		s += ", condition=" + g.cond.String()
+	}
+	return s
+}
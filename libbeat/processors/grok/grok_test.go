@@ -0,0 +1,154 @@
+// +build !integration
+
+package grok
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGrok(t *testing.T, cfg map[string]interface{}) *grok {
+	c, err := common.NewConfigFrom(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := newGrok(*c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*grok)
+}
+
+func TestDefaultLibraryCompiles(t *testing.T) {
+	for name := range defaultLibrary {
+		_, err := defaultLibrary.compile("%{" + name + "}")
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestGrokCombinedApacheLog(t *testing.T) {
+	g := newTestGrok(t, map[string]interface{}{
+		"patterns": []string{"%{COMBINEDAPACHELOG}"},
+	})
+
+	event, err := g.Run(common.MapStr{
+		"message": `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 ` +
+			`"http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", event["clientip"])
+	assert.Equal(t, "frank", event["auth"])
+	assert.Equal(t, "10/Oct/2000:13:55:36 -0700", event["timestamp"])
+	assert.Equal(t, "GET", event["verb"])
+	assert.Equal(t, "/apache_pb.gif", event["request"])
+	assert.Equal(t, "1.0", event["httpversion"])
+	assert.Equal(t, "200", event["response"])
+	assert.Equal(t, "2326", event["bytes"])
+	assert.Equal(t, `"http://www.example.com/start.html"`, event["referrer"])
+	assert.Equal(t, `"Mozilla/4.08 [en] (Win98; I ;Nav)"`, event["agent"])
+	assert.NotContains(t, event, "rawrequest")
+}
+
+func TestGrokSyslogLine(t *testing.T) {
+	g := newTestGrok(t, map[string]interface{}{
+		"patterns": []string{"%{SYSLOGLINE}"},
+		"target":   "syslog",
+	})
+
+	event, err := g.Run(common.MapStr{
+		"message": "Oct 11 22:14:15 mymachine sshd[2534]: Accepted publickey for root from 10.0.0.1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"timestamp": "Oct 11 22:14:15",
+		"logsource": "mymachine",
+		"program":   "sshd",
+		"pid":       "2534",
+		"message":   "Accepted publickey for root from 10.0.0.1",
+	}, event["syslog"])
+}
+
+func TestGrokCustomPatterns(t *testing.T) {
+	f, err := ioutil.TempFile("", "grok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("# custom patterns\nDURATION %{NUMBER}\n")
+	f.Close()
+
+	g := newTestGrok(t, map[string]interface{}{
+		"field": "line",
+		"patterns": []string{
+			`^%{WORD:[http][method]} %{URIPATHPARAM:[http][path]} %{STATUS:[http][status]:int} %{DURATION:duration:float}ms$`,
+			`^(?<http.method>[A-Z]+) %{GREEDYDATA:rest}$`,
+		},
+		"pattern_files":       []string{f.Name()},
+		"pattern_definitions": map[string]interface{}{"STATUS": "[1-5][0-9]{2}"},
+	})
+
+	event, err := g.Run(common.MapStr{"line": "GET /index.html?q=1 404 12.5ms"})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"line":     "GET /index.html?q=1 404 12.5ms",
+		"http":     common.MapStr{"method": "GET", "path": "/index.html?q=1", "status": int64(404)},
+		"duration": 12.5,
+	}, event)
+
+	// the second pattern is tried if the first one does not match
+	event, err = g.Run(common.MapStr{"line": "POST something else"})
+	assert.NoError(t, err)
+	method, _ := event.GetValue("http.method")
+	assert.Equal(t, "POST", method)
+	assert.Equal(t, "something else", event["rest"])
+}
+
+func TestGrokFailure(t *testing.T) {
+	g := newTestGrok(t, map[string]interface{}{
+		"patterns": []string{"^%{IPV4:ip}$"},
+	})
+
+	event, err := g.Run(common.MapStr{"message": "no ip", "tags": []string{"web"}})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"web", "_grokparsefailure"}, event["tags"])
+	assert.NotContains(t, event, "ip")
+
+	_, err = g.Run(common.MapStr{})
+	assert.Error(t, err)
+
+	g = newTestGrok(t, map[string]interface{}{
+		"patterns":       []string{"^%{IPV4:ip}$"},
+		"ignore_missing": true,
+		"tag_on_failure": []string{},
+	})
+	event, err = g.Run(common.MapStr{})
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{}, event)
+
+	event, err = g.Run(common.MapStr{"message": "256.0.0.1"})
+	assert.Error(t, err)
+	assert.NotContains(t, event, "tags")
+}
+
+func TestGrokInvalidConfig(t *testing.T) {
+	for _, patterns := range [][]string{
+		{"%{UNKNOWN}"},
+		{"%{IP:ip:bool}"},
+		{"(unbalanced"},
+	} {
+		c, _ := common.NewConfigFrom(map[string]interface{}{"patterns": patterns})
+		_, err := newGrok(*c)
+		assert.Error(t, err, "%v", patterns)
+	}
+
+	c, _ := common.NewConfigFrom(map[string]interface{}{
+		"patterns":            []string{"%{A}"},
+		"pattern_definitions": map[string]interface{}{"A": "%{B}", "B": "%{A}"},
+	})
+	_, err := newGrok(*c)
+	assert.Error(t, err)
+}
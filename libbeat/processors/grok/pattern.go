@@ -0,0 +1,186 @@
+package grok
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// library maps pattern names to grok expressions.
+type library map[string]string
+
+// grokRef matches pattern references of the form %{NAME}, %{NAME:field} and
+// %{NAME:field:type}.
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([\w.@\[\]-]+))?(?::(\w+))?\}`)
+
+// namedGroup matches named groups in the Oniguruma syntax used by grok
+// patterns, which is not supported by Go regular expressions.
+var namedGroup = regexp.MustCompile(`\(\?<([A-Za-z_][\w.@\[\]-]*)>`)
+
+// maxDepth limits the nesting of pattern references, such that recursive
+// definitions are reported as error.
+const maxDepth = 32
+
+// parsePatterns adds the patterns read from r to lib. Each line defines one
+// pattern by its name followed by its expression. Empty lines and lines
+// starting with # are ignored.
+func (lib library) parsePatterns(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("invalid pattern definition in line %d", n)
+		}
+		lib[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	return scanner.Err()
+}
+
+func (lib library) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open grok pattern file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if err := lib.parsePatterns(f); err != nil {
+		return fmt.Errorf("failed to read grok pattern file %s: %v", path, err)
+	}
+	return nil
+}
+
+// capture is a named group of a compiled pattern.
+type capture struct {
+	field string
+	typ   string
+}
+
+type pattern struct {
+	source   string
+	re       *regexp.Regexp
+	captures []capture // indexed by group, the field is empty for unnamed groups
+}
+
+// compile expands the pattern references of expr and compiles the result.
+func (lib library) compile(expr string) (*pattern, error) {
+	c := &compiler{lib: lib, captures: map[string]capture{}}
+	expanded, err := c.expand(expr, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile grok pattern '%s': %v", expr, err)
+	}
+
+	p := &pattern{source: expr, re: re, captures: make([]capture, re.NumSubexp()+1)}
+	for i, name := range re.SubexpNames() {
+		p.captures[i] = c.captures[name]
+	}
+	return p, nil
+}
+
+type compiler struct {
+	lib      library
+	captures map[string]capture // indexed by group name
+}
+
+func (c *compiler) expand(expr string, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", fmt.Errorf("grok pattern nesting exceeds %d levels", maxDepth)
+	}
+
+	var err error
+	expr = namedGroup.ReplaceAllStringFunc(expr, func(m string) string {
+		field := namedGroup.FindStringSubmatch(m)[1]
+		return "(?P<" + c.addCapture(field, "") + ">"
+	})
+	expr = grokRef.ReplaceAllStringFunc(expr, func(m string) string {
+		if err != nil {
+			return ""
+		}
+
+		ref := grokRef.FindStringSubmatch(m)
+		name, field, typ := ref[1], ref[2], ref[3]
+
+		def, found := c.lib[name]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		switch typ {
+		case "", "string", "int", "float":
+		default:
+			err = fmt.Errorf("unsupported type %s in grok pattern %s", typ, m)
+			return ""
+		}
+
+		var sub string
+		sub, err = c.expand(def, depth+1)
+		if field == "" {
+			return "(?:" + sub + ")"
+		}
+		return "(?P<" + c.addCapture(field, typ) + ">" + sub + ")"
+	})
+	return expr, err
+}
+
+// addCapture registers a named group capturing field and returns the name of
+// the group. Field names in the [a][b] syntax are converted to a.b.
+func (c *compiler) addCapture(field, typ string) string {
+	if strings.HasPrefix(field, "[") {
+		field = strings.Replace(strings.Trim(field, "[]"), "][", ".", -1)
+	}
+	name := "grok" + strconv.Itoa(len(c.captures))
+	c.captures[name] = capture{field: field, typ: typ}
+	return name
+}
+
+// match returns the fields captured from s. Groups that did not participate
+// in the match or matched an empty string are left out. If a field is captured
+// by multiple groups, the first one wins.
+func (p *pattern) match(s string) (map[string]interface{}, bool, error) {
+	loc := p.re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, false, nil
+	}
+
+	fields := map[string]interface{}{}
+	for i, capture := range p.captures {
+		start, end := loc[2*i], loc[2*i+1]
+		if capture.field == "" || start < 0 || start == end {
+			continue
+		}
+		if _, exists := fields[capture.field]; exists {
+			continue
+		}
+
+		value, err := convert(s[start:end], capture.typ)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to convert field %s: %v", capture.field, err)
+		}
+		fields[capture.field] = value
+	}
+	return fields, true, nil
+}
+
+func convert(s, typ string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.ParseInt(s, 10, 64)
+	case "float":
+		return strconv.ParseFloat(s, 64)
+	default:
+		return s, nil
+	}
+}
@@ -57,10 +57,14 @@ func (procs *Processors) Run(event common.MapStr) common.MapStr {
 	}
 
 	// clone the event at first, before starting filtering
-	filtered := event.Clone()
+	return procs.runFrom(0, event.Clone())
+}
+
+// runFrom applies the processors starting at index start to the event.
+func (procs *Processors) runFrom(start int, filtered common.MapStr) common.MapStr {
 	var err error
 
-	for _, p := range procs.list {
+	for _, p := range procs.list[start:] {
 		filtered, err = p.Run(filtered)
 		if err != nil {
 			logp.Debug("filter", "fail to apply processor %s: %s", p, err)
@@ -74,6 +78,42 @@ func (procs *Processors) Run(event common.MapStr) common.MapStr {
 	return filtered
 }
 
+// Start starts all generator processors. Events created by a generator are
+// passed through the processors following the generator before being handed
+// to publish.
+func (procs *Processors) Start(publish func(event common.MapStr)) {
+	for i, p := range procs.list {
+		g, ok := p.(Generator)
+		if !ok {
+			continue
+		}
+
+		next := i + 1
+		g.Start(func(event common.MapStr) {
+			if event = procs.runFrom(next, event); event != nil {
+				publish(event)
+			}
+		})
+	}
+}
+
+// Stop stops all generator processors, flushing any pending events, and
+// closes the processors implementing Closer.
+func (procs *Processors) Stop() {
+	for _, p := range procs.list {
+		if g, ok := p.(Generator); ok {
+			g.Stop()
+		}
+	}
+	for _, p := range procs.list {
+		if c, ok := p.(Closer); ok {
+			if err := c.Close(); err != nil {
+				logp.Err("Failed to close processor %s: %v", p, err)
+			}
+		}
+	}
+}
+
 func (procs Processors) String() string {
 	s := []string{}
 
@@ -12,6 +12,27 @@ type Processor interface {
 	String() string
 }
 
+// Generator is implemented by processors that create new events
+// asynchronously, independent of the events passing through them (e.g. when
+// flushing aggregated state).
+type Generator interface {
+	Processor
+
+	// Start is called once the publisher pipeline is available. Generated
+	// events must be passed to out.
+	Start(out func(event common.MapStr))
+
+	// Stop flushes any pending state and stops event generation.
+	Stop()
+}
+
+// Closer is implemented by processors holding resources, like goroutines or
+// connections, that are released when the processors are stopped.
+type Closer interface {
+	Processor
+	Close() error
+}
+
 type Constructor func(config common.Config) (Processor, error)
 
 var constructors = map[string]Constructor{}
@@ -0,0 +1,69 @@
+package publisher
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common/op"
+)
+
+// ackQueue calls the callbacks of the batches published by a client in
+// publish order. A callback is called once its batch has been acknowledged or
+// failed by the outputs, and the callbacks of all batches published before
+// have been called.
+type ackQueue struct {
+	mutex    sync.Mutex
+	pending  []*ackEntry
+	flushing bool // a goroutine is calling the callbacks of completed batches
+}
+
+type ackEntry struct {
+	fn    func(acked int)
+	acked int
+	done  bool
+}
+
+// add registers fn for a batch of n events. The returned signaler must be
+// signaled exactly once when the batch is acknowledged, failed or canceled.
+// Only acknowledged batches count as acked events.
+func (q *ackQueue) add(fn func(acked int), n int) op.Signaler {
+	e := &ackEntry{fn: fn}
+
+	q.mutex.Lock()
+	q.pending = append(q.pending, e)
+	q.mutex.Unlock()
+
+	return op.SignalCallback(func(sig op.SignalResponse) {
+		acked := 0
+		if sig == op.SignalCompleted {
+			acked = n
+		}
+		q.done(e, acked)
+	})
+}
+
+func (q *ackQueue) done(e *ackEntry, acked int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	e.done = true
+	e.acked = acked
+
+	// Only one goroutine calls the callbacks at a time, such that they are
+	// called in order. Batches completed meanwhile are picked up by the
+	// running loop.
+	if q.flushing {
+		return
+	}
+	q.flushing = true
+	for len(q.pending) > 0 && q.pending[0].done {
+		next := q.pending[0]
+		q.pending = q.pending[1:]
+
+		// Callbacks are called without holding the lock, so they can
+		// publish more events.
+		q.mutex.Unlock()
+		next.fn(next.acked)
+		q.mutex.Lock()
+	}
+	q.flushing = false
+}
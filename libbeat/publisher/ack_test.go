@@ -0,0 +1,96 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/processors"
+	_ "github.com/elastic/beats/libbeat/processors/actions"
+	"github.com/stretchr/testify/assert"
+)
+
+// Verify that callbacks are called in publish order, even if later batches
+// complete first.
+func TestAckQueueOrder(t *testing.T) {
+	var q ackQueue
+	var acked []int
+
+	s1 := q.add(func(n int) { acked = append(acked, n) }, 1)
+	s2 := q.add(func(n int) { acked = append(acked, n) }, 2)
+	s3 := q.add(func(n int) { acked = append(acked, n) }, 3)
+
+	s3.Completed()
+	s2.Failed()
+	assert.Empty(t, acked)
+
+	s1.Completed()
+	assert.Equal(t, []int{1, 0, 3}, acked)
+}
+
+func TestAckQueuePublishFromCallback(t *testing.T) {
+	var q ackQueue
+	var acked []int
+
+	var s2 op.Signaler
+	s1 := q.add(func(n int) {
+		acked = append(acked, n)
+		s2 = q.add(func(n int) { acked = append(acked, n) }, 2)
+		s2.Completed()
+	}, 1)
+
+	s1.Completed()
+	assert.Equal(t, []int{1, 2}, acked)
+}
+
+func TestPublishWithCallbackCompleted(t *testing.T) {
+	testPub := newTestPublisherNoBulk(CompletedResponse)
+	testPub.pub.Processors = &processors.Processors{}
+	defer testPub.Stop()
+
+	acked := make(chan int, 1)
+	events := []common.MapStr{testEvent(), testEvent()}
+	ok := testPub.client.PublishWithCallback(events, func(n int) { acked <- n })
+	assert.True(t, ok)
+
+	_, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, <-acked)
+}
+
+func TestPublishWithCallbackFailed(t *testing.T) {
+	testPub := newTestPublisherNoBulk(FailedResponse)
+	testPub.pub.Processors = &processors.Processors{}
+	defer testPub.Stop()
+
+	acked := make(chan int, 1)
+	events := []common.MapStr{testEvent()}
+	ok := testPub.client.PublishWithCallback(events, func(n int) { acked <- n }, Sync)
+	assert.False(t, ok)
+	assert.Equal(t, 0, <-acked)
+}
+
+// Verify that events dropped by processors are reported as acked, such that
+// the state of the dropped events can be updated.
+func TestPublishWithCallbackFiltered(t *testing.T) {
+	testPub := newTestPublisherNoBulk(CompletedResponse)
+	defer testPub.Stop()
+
+	procs, err := processors.New(processors.PluginConfig{
+		{"drop_event": *common.NewConfig()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testPub.pub.Processors = procs
+
+	acked := make(chan int, 1)
+	events := []common.MapStr{testEvent(), testEvent()}
+	ok := testPub.client.PublishWithCallback(events, func(n int) { acked <- n })
+	assert.True(t, ok)
+	assert.Equal(t, 2, <-acked)
+}
@@ -1,17 +1,24 @@
 package publisher
 
 import (
+	"time"
+
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
 )
 
 type asyncPipeline struct {
-	outputs []worker
+	outputs *outputSet
 	pub     *Publisher
+
+	// batch assembles the events into batches before they are sent to the
+	// outputs, nil if batching is disabled.
+	batch worker
 }
 
 const (
-	defaultBulkSize = 2048
+	defaultBulkSize     = 2048
+	defaultFlushTimeout = 1 * time.Second
 )
 
 func newAsyncPipeline(
@@ -21,12 +28,22 @@ func newAsyncPipeline(
 ) *asyncPipeline {
 	p := &asyncPipeline{pub: pub}
 
-	var outputs []worker
-	for _, out := range pub.Output {
-		outputs = append(outputs, makeAsyncOutput(ws, hwm, bulkHWM, out))
-	}
+	p.outputs = pub.newOutputSet(func(out *outputWorker) worker {
+		return makeAsyncOutput(ws, hwm, bulkHWM, pub.overflow, out)
+	})
+
+	if flush := pub.flush; flush.MinEvents > 0 {
+		timeout := flush.Timeout
+		if timeout <= 0 {
+			timeout = defaultFlushTimeout
+		}
+		logp.Info("Publisher batches of %v events (flush timeout %v)",
+			flush.MinEvents, timeout)
 
-	p.outputs = outputs
+		batch := newBulkWorker(ws, hwm, bulkHWM, p.outputs, timeout, flush.MinEvents)
+		batch.overflow = pub.overflow
+		p.batch = batch
+	}
 	return p
 }
 
@@ -38,15 +55,18 @@ func (p *asyncPipeline) publish(m message) bool {
 	}
 
 	if m.context.Signal != nil {
-		s := op.CancelableSignaler(m.client.canceler, m.context.Signal)
-		if len(p.outputs) > 1 {
-			s = op.SplitSignaler(s, len(p.outputs))
-		}
-		m.context.Signal = s
+		m.context.Signal = op.CancelableSignaler(m.client.canceler, m.context.Signal)
+	}
+
+	if !p.pub.limiter.wait(m, m.client.canceler.Done()) {
+		op.SignalCanceled.Apply(m.context.Signal)
+		return false
 	}
 
-	for _, o := range p.outputs {
-		o.send(m)
+	if p.batch != nil {
+		p.batch.send(m)
+	} else {
+		p.outputs.send(m)
 	}
 	return true
 }
@@ -54,6 +74,7 @@ func (p *asyncPipeline) publish(m message) bool {
 func makeAsyncOutput(
 	ws *workerSignal,
 	hwm, bulkHWM int,
+	overflow overflowPolicy,
 	worker *outputWorker,
 ) worker {
 	config := worker.config
@@ -65,10 +86,15 @@ func makeAsyncOutput(
 
 	// batching disabled
 	if flushInterval <= 0 || maxBulkSize <= 0 {
+		worker.overflow = overflow
 		return worker
 	}
 
 	debug("create bulk processing worker (interval=%v, bulk size=%v)",
 		flushInterval, maxBulkSize)
-	return newBulkWorker(ws, hwm, bulkHWM, worker, flushInterval, maxBulkSize)
+	// The overflow policy only applies to the queues of the bulk worker.
+	// Batches forwarded to the output worker are never dropped.
+	bulk := newBulkWorker(ws, hwm, bulkHWM, worker, flushInterval, maxBulkSize)
+	bulk.overflow = overflow
+	return bulk
 }
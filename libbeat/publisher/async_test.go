@@ -4,6 +4,7 @@ package publisher
 
 import (
 	"testing"
+	"time"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
@@ -128,3 +129,48 @@ func TestBulkAsyncShutdownPublishEvents(t *testing.T) {
 	assert.Equal(t, events[0], msg.events[0])
 	assert.Equal(t, events[1], msg.events[1])
 }
+
+// newTestPublisherFlush returns a new testPublisher batching the events in
+// the async pipeline.
+func newTestPublisherFlush(flush FlushConfig) *testPublisher {
+	testPub := newTestPublisherNoBulk(CompletedResponse)
+	pub := testPub.pub
+	pub.flush = flush
+	pub.pipelines.async = newAsyncPipeline(pub, defaultChanSize, defaultBulkChanSize, &pub.wsPublisher)
+	return testPub
+}
+
+func TestAsyncFlushMinEvents(t *testing.T) {
+	testPub := newTestPublisherFlush(FlushConfig{MinEvents: 3, Timeout: time.Hour})
+	defer testPub.Stop()
+
+	events := []common.MapStr{testEvent(), testEvent(), testEvent()}
+	for _, event := range events {
+		assert.True(t, testPub.asyncPublishEvent(event))
+	}
+
+	// the events are sent to the output as one batch
+	msgs, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, events, msgs[0].events)
+}
+
+func TestAsyncFlushTimeout(t *testing.T) {
+	testPub := newTestPublisherFlush(FlushConfig{
+		MinEvents: 10,
+		Timeout:   10 * time.Millisecond,
+	})
+	defer testPub.Stop()
+
+	event := testEvent()
+	assert.True(t, testPub.asyncPublishEvent(event))
+
+	// the incomplete batch is sent to the output after the timeout
+	msgs, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []common.MapStr{event}, msgs[0].events)
+}
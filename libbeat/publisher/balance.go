@@ -0,0 +1,162 @@
+package publisher
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	publishWorkersBlocked = expvar.NewInt("libbeat.publisher.output_workers.blocked")
+)
+
+// loadBalanceMode selects the worker that publishes a message if an output
+// is published by multiple workers.
+type loadBalanceMode uint8
+
+const (
+	// loadBalanceRoundRobin selects the workers in turn.
+	loadBalanceRoundRobin loadBalanceMode = iota
+
+	// loadBalanceLeastLoaded selects the worker with the fewest messages
+	// queued or being published.
+	loadBalanceLeastLoaded
+)
+
+var loadBalanceModes = map[string]loadBalanceMode{
+	"round_robin":  loadBalanceRoundRobin,
+	"least_loaded": loadBalanceLeastLoaded,
+}
+
+func parseLoadBalanceMode(name string) (loadBalanceMode, error) {
+	if name == "" {
+		return loadBalanceRoundRobin, nil
+	}
+
+	mode, found := loadBalanceModes[name]
+	if !found {
+		return loadBalanceRoundRobin, fmt.Errorf("unsupported loadbalance mode '%v'", name)
+	}
+	return mode, nil
+}
+
+func (m loadBalanceMode) String() string {
+	for name, mode := range loadBalanceModes {
+		if mode == m {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// publishWorkerQueueSize is the queue size of a publish worker. Queues are
+// kept short, such that messages are only assigned to a worker shortly before
+// it can publish them.
+const publishWorkerQueueSize = 1
+
+// publishWorker is one of the workers publishing the messages of an output.
+type publishWorker struct {
+	queue   chan message
+	pending int32 // messages queued or being published
+}
+
+// balancer distributes the messages of an output worker to multiple publish
+// workers, which call the output concurrently. Every publish worker has its
+// own queue, so a worker blocked by the output only holds back the messages
+// assigned to it, while the other workers continue to publish.
+type balancer struct {
+	mode    loadBalanceMode
+	workers []*publishWorker
+	next    uint32
+	done    <-chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newBalancer(
+	ws *workerSignal,
+	n int,
+	mode loadBalanceMode,
+	publish func(m message),
+) *balancer {
+	b := &balancer{mode: mode, done: ws.done}
+	for i := 0; i < n; i++ {
+		w := &publishWorker{queue: make(chan message, publishWorkerQueueSize)}
+		b.workers = append(b.workers, w)
+
+		b.wg.Add(1)
+		go b.run(w, publish)
+	}
+	return b
+}
+
+func (b *balancer) run(w *publishWorker, publish func(m message)) {
+	defer b.wg.Done()
+	defer crash.Recover()
+	for {
+		select {
+		case <-b.done:
+			return
+		case m := <-w.queue:
+			publish(m)
+			atomic.AddInt32(&w.pending, -1)
+		}
+	}
+}
+
+// send assigns m to the selected worker. If the queue of the selected
+// worker is full, m is assigned to the next worker with space. send blocks on
+// the selected worker only if the queues of all workers are full.
+func (b *balancer) send(m message) {
+	n := len(b.workers)
+	selected := b.selectWorker()
+	for i := 0; i < n; i++ {
+		w := b.workers[(selected+i)%n]
+		atomic.AddInt32(&w.pending, 1)
+		select {
+		case w.queue <- m:
+			return
+		default:
+			atomic.AddInt32(&w.pending, -1)
+		}
+	}
+
+	publishWorkersBlocked.Add(1)
+	w := b.workers[selected]
+	atomic.AddInt32(&w.pending, 1)
+	select {
+	case w.queue <- m:
+	case <-b.done:
+		atomic.AddInt32(&w.pending, -1)
+		op.SigFailed(m.context.Signal, nil)
+	}
+}
+
+func (b *balancer) selectWorker() int {
+	if b.mode == loadBalanceLeastLoaded {
+		selected, min := 0, atomic.LoadInt32(&b.workers[0].pending)
+		for i, w := range b.workers[1:] {
+			if pending := atomic.LoadInt32(&w.pending); pending < min {
+				selected, min = i+1, pending
+			}
+		}
+		return selected
+	}
+	return int((atomic.AddUint32(&b.next, 1) - 1) % uint32(len(b.workers)))
+}
+
+// stop waits for the workers to return on shutdown and fails the messages
+// left in their queues.
+func (b *balancer) stop() {
+	b.wg.Wait()
+	for _, w := range b.workers {
+		for len(w.queue) > 0 {
+			m := <-w.queue
+			op.SigFailed(m.context.Signal, nil)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// +build !integration
+
+package publisher
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLoadBalanceMode(t *testing.T) {
+	mode, err := parseLoadBalanceMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, loadBalanceRoundRobin, mode)
+
+	mode, err = parseLoadBalanceMode("least_loaded")
+	assert.NoError(t, err)
+	assert.Equal(t, loadBalanceLeastLoaded, mode)
+
+	_, err = parseLoadBalanceMode("random")
+	assert.Error(t, err)
+}
+
+// Verify that a worker blocked by the output does not hold back the messages
+// assigned to the other workers.
+func TestBalancerBlockedWorker(t *testing.T) {
+	for _, mode := range []loadBalanceMode{loadBalanceRoundRobin, loadBalanceLeastLoaded} {
+		ws := newWorkerSignal()
+
+		block := make(chan struct{})
+		published := make(chan common.MapStr, 10)
+		var once sync.Once
+		b := newBalancer(ws, 3, mode, func(m message) {
+			blocked := false
+			once.Do(func() { blocked = true })
+			if blocked {
+				<-block
+			}
+			published <- m.event
+		})
+
+		for i := 0; i < 6; i++ {
+			b.send(message{event: common.MapStr{"n": i}})
+		}
+		// at most two messages are held back by the blocked worker
+		for i := 0; i < 4; i++ {
+			<-published
+		}
+
+		close(block)
+		for i := 0; i < 2; i++ {
+			<-published
+		}
+
+		close(ws.done)
+		b.stop()
+	}
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	ws := newWorkerSignal()
+	defer close(ws.done)
+
+	b := newBalancer(ws, 3, loadBalanceRoundRobin, func(m message) {})
+	var selected []int
+	for i := 0; i < 4; i++ {
+		selected = append(selected, b.selectWorker())
+	}
+	assert.Equal(t, []int{0, 1, 2, 0}, selected)
+}
+
+func TestBalancerLeastLoaded(t *testing.T) {
+	ws := newWorkerSignal()
+	defer close(ws.done)
+
+	b := newBalancer(ws, 3, loadBalanceLeastLoaded, func(m message) {})
+	b.workers[0].pending = 2
+	b.workers[1].pending = 1
+	b.workers[2].pending = 3
+	assert.Equal(t, 1, b.selectWorker())
+}
+
+func TestOutputWorkerLoadBalance(t *testing.T) {
+	outputer := &testOutputer{events: make(chan common.MapStr, 10)}
+	ws := newWorkerSignal()
+	ow := newOutputWorker(common.NewConfig(), outputer, ws, 1, 0, 2, loadBalanceRoundRobin)
+	assert.NotNil(t, ow.balancer)
+
+	sig := newTestSignaler()
+	ow.send(testMessage(sig, testEvent()))
+	assert.True(t, sig.wait())
+	<-outputer.events
+
+	ws.stop()
+}
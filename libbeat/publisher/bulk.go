@@ -5,16 +5,19 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
 )
 
 type bulkWorker struct {
 	output worker
 	ws     *workerSignal
 
-	queue       chan message
-	bulkQueue   chan message
-	guaranteed  bool
-	flushTicker *time.Ticker
+	queue           chan message
+	bulkQueue       chan message
+	guaranteedQueue chan message // guaranteed messages if overflow drops messages
+	overflow        overflowPolicy
+	guaranteed      bool
+	flushTicker     *time.Ticker
 
 	maxBatchSize int
 	events       []common.MapStr // batched events
@@ -28,14 +31,15 @@ func newBulkWorker(
 	maxBatchSize int,
 ) *bulkWorker {
 	b := &bulkWorker{
-		output:       output,
-		ws:           ws,
-		queue:        make(chan message, hwm),
-		bulkQueue:    make(chan message, bulkHWM),
-		flushTicker:  time.NewTicker(flushInterval),
-		maxBatchSize: maxBatchSize,
-		events:       make([]common.MapStr, 0, maxBatchSize),
-		pending:      nil,
+		output:          output,
+		ws:              ws,
+		queue:           make(chan message, hwm),
+		bulkQueue:       make(chan message, bulkHWM),
+		guaranteedQueue: make(chan message, hwm),
+		flushTicker:     time.NewTicker(flushInterval),
+		maxBatchSize:    maxBatchSize,
+		events:          make([]common.MapStr, 0, maxBatchSize),
+		pending:         nil,
 	}
 
 	b.ws.wg.Add(1)
@@ -44,11 +48,12 @@ func newBulkWorker(
 }
 
 func (b *bulkWorker) send(m message) {
-	send(b.queue, b.bulkQueue, m)
+	send(b.queue, b.bulkQueue, b.guaranteedQueue, b.overflow, m)
 }
 
 func (b *bulkWorker) run() {
 	defer b.shutdown()
+	defer crash.Recover()
 
 	for {
 		select {
@@ -58,6 +63,12 @@ func (b *bulkWorker) run() {
 			b.onEvent(&m.context, m.event)
 		case m := <-b.bulkQueue:
 			b.onEvents(&m.context, m.events)
+		case m := <-b.guaranteedQueue:
+			if m.event != nil {
+				b.onEvent(&m.context, m.event)
+			} else {
+				b.onEvents(&m.context, m.events)
+			}
 		case <-b.flushTicker.C:
 			b.flush()
 		}
@@ -135,5 +146,6 @@ func (b *bulkWorker) shutdown() {
 	b.flushTicker.Stop()
 	stopQueue(b.queue)
 	stopQueue(b.bulkQueue)
+	stopQueue(b.guaranteedQueue)
 	b.ws.wg.Done()
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
 	"github.com/elastic/beats/libbeat/logp"
+	"golang.org/x/net/context"
 )
 
 // Metrics that can retrieved through the expvar web interface.
@@ -57,19 +58,45 @@ type Client interface {
 	// option is set, PublishEvent will block until output plugins report
 	// success or failure state being returned by this method.
 	PublishEvents(events []common.MapStr, opts ...ClientOption) bool
+
+	// PublishWithCallback publishes multiple events like PublishEvents and
+	// calls fn once the outputs have acknowledged or failed the events. The
+	// number of acked events is 0 if the events failed or were dropped
+	// because the client was closed. Events dropped by processors count as
+	// acked. The callbacks of a client are called in publish order, so fn can
+	// be used to update state, like the registrar, only after delivery.
+	PublishWithCallback(events []common.MapStr, fn func(acked int), opts ...ClientOption) bool
+
+	// Inflight returns the number of events published by the client that have
+	// not yet been acknowledged by the outputs.
+	Inflight() int
+
+	// Wait blocks until at most n events published by the client are in
+	// flight, the context is done or the client is closed. It can be used to
+	// bound the number of events read ahead of the outputs.
+	Wait(ctx context.Context, n int) error
 }
 
+// clientID is the ID of the last connected client.
+var clientID uint64
+
 type client struct {
 	canceler *op.Canceler
+	id       uint64
+	inflight *inflight
+	acks     ackQueue
 
 	publisher           *Publisher
 	beatMeta            common.MapStr        // Beat metadata that is added to all events.
 	globalEventMetadata common.EventMetadata // Fields and tags that are added to all events.
+	bypassProcessors    bool                 // Do not apply the configured processors.
 }
 
 func newClient(pub *Publisher) *client {
 	c := &client{
 		canceler: op.NewCanceler(),
+		id:       atomic.AddUint64(&clientID, 1),
+		inflight: newInflight(),
 
 		publisher: pub,
 		beatMeta: common.MapStr{
@@ -78,11 +105,14 @@ func newClient(pub *Publisher) *client {
 		},
 		globalEventMetadata: pub.globalEventMetadata,
 	}
+	registerInflight(c.id, c.inflight)
 	return c
 }
 
 func (c *client) Close() error {
 	c.canceler.Cancel()
+	c.inflight.close()
+	unregisterInflight(c.id)
 
 	// atomic decrement clients counter
 	atomic.AddUint32(&c.publisher.numClients, ^uint32(0))
@@ -98,6 +128,11 @@ func (c *client) PublishEvent(event common.MapStr, opts ...ClientOption) bool {
 	}
 
 	ctx, pipeline := c.getPipeline(opts)
+	signal, ok := c.publisher.accept(1, ctx.Signal)
+	if !ok {
+		return false
+	}
+	ctx.Signal = c.inflight.track(1, signal)
 	publishedEvents.Add(1)
 	return pipeline.publish(message{client: c, context: ctx, event: *publishEvent})
 }
@@ -118,13 +153,28 @@ func (c *client) PublishEvents(events []common.MapStr, opts ...ClientOption) boo
 	ctx, pipeline := c.getPipeline(opts)
 	if len(publishEvents) == 0 {
 		logp.Debug("filter", "No events to publish")
+		op.SigCompleted(ctx.Signal)
 		return true
 	}
 
+	signal, ok := c.publisher.accept(len(publishEvents), ctx.Signal)
+	if !ok {
+		return false
+	}
+	ctx.Signal = c.inflight.track(len(publishEvents), signal)
 	publishedEvents.Add(int64(len(publishEvents)))
 	return pipeline.publish(message{client: c, context: ctx, events: publishEvents})
 }
 
+func (c *client) PublishWithCallback(
+	events []common.MapStr,
+	fn func(acked int),
+	opts ...ClientOption,
+) bool {
+	signal := c.acks.add(fn, len(events))
+	return c.PublishEvents(events, append(opts, Signal(signal))...)
+}
+
 // annotateEvent adds fields that are common to all events. This adds the 'beat'
 // field that contains name and hostname. It also adds 'tags' and 'fields'. See
 // the documentation for Client for more information.
@@ -144,6 +194,9 @@ func (c *client) annotateEvent(event common.MapStr) {
 	// Add the global tags and fields defined under shipper.
 	common.AddTags(event, c.globalEventMetadata.Tags)
 	common.MergeFields(event, c.globalEventMetadata.Fields, c.globalEventMetadata.FieldsUnderRoot)
+	if c.globalEventMetadata.Namespace != "" {
+		common.SetNamespace(event, c.globalEventMetadata.Namespace)
+	}
 
 	// Add the event specific fields last so that they precedence over globals.
 	if metaIfc, ok := event[common.EventMetadataKey]; ok {
@@ -151,10 +204,18 @@ func (c *client) annotateEvent(event common.MapStr) {
 		if ok {
 			common.AddTags(event, eventMetadata.Tags)
 			common.MergeFields(event, eventMetadata.Fields, eventMetadata.FieldsUnderRoot)
+			if eventMetadata.Namespace != "" {
+				common.SetNamespace(event, eventMetadata.Namespace)
+			}
 		}
 		delete(event, common.EventMetadataKey)
 	}
 
+	// Events originate from the host of the beat, unless the beat reports
+	// another source host.
+	if meta, ok := event[common.MetadataKey].(common.MapStr); !ok || meta[common.MetadataHostKey] == nil {
+		common.SetMetadata(event, common.MetadataHostKey, c.publisher.hostname)
+	}
 }
 
 func (c *client) filterEvent(event common.MapStr) *common.MapStr {
@@ -166,7 +227,10 @@ func (c *client) filterEvent(event common.MapStr) *common.MapStr {
 	}
 
 	// process the event by applying the configured actions
-	publishEvent := c.publisher.Processors.Run(event)
+	publishEvent := event
+	if !c.bypassProcessors {
+		publishEvent = c.publisher.Processors.Run(event)
+	}
 	if publishEvent == nil {
 		// the event is dropped
 		logp.Debug("publish", "Drop event %s", event.StringToPrint())
@@ -178,6 +242,14 @@ func (c *client) filterEvent(event common.MapStr) *common.MapStr {
 	return &publishEvent
 }
 
+func (c *client) Inflight() int {
+	return c.inflight.get()
+}
+
+func (c *client) Wait(ctx context.Context, n int) error {
+	return c.inflight.wait(ctx, n)
+}
+
 func (c *client) getPipeline(opts []ClientOption) (Context, pipeline) {
 	ctx := MakeContext(opts)
 	if ctx.Sync {
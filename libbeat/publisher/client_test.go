@@ -41,3 +41,16 @@ func TestGetClient(t *testing.T) {
 		assert.Equal(t, expected.Pointer(), actual.Pointer())
 	}
 }
+
+func TestConnectIndex(t *testing.T) {
+	pub := &Publisher{name: "test", hostname: "localhost"}
+
+	c := pub.ConnectIndex("test-section").(*client)
+	defer c.Close()
+	assert.Equal(t, "test-section", c.beatMeta["index"])
+
+	c = pub.Connect().(*client)
+	defer c.Close()
+	_, ok := c.beatMeta["index"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,199 @@
+package publisher
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/feature"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	deadLetterPublished = expvar.NewInt("libbeat.publisher.dead_letter.published_events")
+	deadLetterFailed    = expvar.NewInt("libbeat.publisher.dead_letter.failed_events")
+	deadLetterDropped   = expvar.NewInt("libbeat.publisher.dead_letter.dropped_events")
+)
+
+const (
+	defaultDeadLetterQueueSize = 1000
+	deadLetterBatchSize        = 50
+
+	// deadLetterSuffix is appended to the Beat name to derive the default
+	// index, or file name, of the dead letter output.
+	deadLetterSuffix = "-dead-letter"
+)
+
+var deadLetterFeature = feature.New("dead_letter",
+	"publish events rejected by the outputs to the dead letter output",
+	feature.Experimental)
+
+// DeadLetterConfig configures the output receiving the events rejected
+// permanently by the outputs.
+type DeadLetterConfig struct {
+	Output    map[string]*common.Config `config:"output"`
+	QueueSize int                       `config:"queue_size" validate:"min=0"`
+}
+
+// deadLetterQueue publishes the events rejected permanently by the outputs
+// to the dead letter output. Rejected events are queued, such that the
+// outputs are not blocked by the dead letter output. Events are dropped if the
+// queue is full.
+type deadLetterQueue struct {
+	name   string // name of the dead letter output
+	output outputs.BulkOutputer
+	queue  chan common.MapStr
+	done   chan struct{}
+	closed chan struct{}
+}
+
+func newDeadLetterQueue(
+	beatName string,
+	config DeadLetterConfig,
+) (*deadLetterQueue, error) {
+	plugins, err := outputs.InitOutputs(beatName+deadLetterSuffix, config.Output, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dead letter output: %v", err)
+	}
+	if len(plugins) != 1 {
+		return nil, fmt.Errorf("dead_letter requires exactly one output, %d configured",
+			len(plugins))
+	}
+
+	size := config.QueueSize
+	if size == 0 {
+		size = defaultDeadLetterQueueSize
+	}
+
+	q := &deadLetterQueue{
+		name:   plugins[0].Name,
+		output: outputs.CastBulkOutputer(plugins[0].Output),
+		queue:  make(chan common.MapStr, size),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go q.run()
+
+	logp.Info("Publishing events rejected by the outputs to the %s dead letter output", q.name)
+	return q, nil
+}
+
+// handler returns the function receiving the events rejected by the given
+// output.
+func (q *deadLetterQueue) handler(output string) outputs.DeadLetter {
+	return func(event common.MapStr, reason string) {
+		q.add(makeDeadLetterEvent(output, event, reason, time.Now()))
+	}
+}
+
+func (q *deadLetterQueue) add(event common.MapStr) {
+	select {
+	case <-q.done:
+		deadLetterDropped.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case q.queue <- event:
+	default:
+		deadLetterDropped.Add(1)
+		logp.Warn("Dead letter queue is full, dropping rejected event")
+	}
+}
+
+// makeDeadLetterEvent creates the event published to the dead letter output.
+// The rejected event is stored JSON encoded, as the reason it has been
+// rejected might prevent indexing it in the dead letter output too.
+func makeDeadLetterEvent(
+	output string,
+	event common.MapStr,
+	reason string,
+	ts time.Time,
+) common.MapStr {
+	info := common.MapStr{
+		"output": output,
+		"reason": reason,
+	}
+	if raw, err := json.Marshal(event); err == nil {
+		info["event"] = string(raw)
+	} else {
+		logp.Err("Failed to encode rejected event: %v", err)
+	}
+
+	dl := common.MapStr{
+		"@timestamp":  common.Time(ts),
+		"type":        "dead_letter",
+		"dead_letter": info,
+	}
+	if beat, exists := event["beat"]; exists {
+		dl["beat"] = beat
+	}
+	return dl
+}
+
+func (q *deadLetterQueue) run() {
+	defer close(q.closed)
+	defer crash.Recover()
+
+	for {
+		select {
+		case event := <-q.queue:
+			q.publish(q.collect(event))
+		case <-q.done:
+			// publish the events queued before stop
+			for len(q.queue) > 0 {
+				q.publish(q.collect(<-q.queue))
+			}
+			return
+		}
+	}
+}
+
+// collect returns a batch of the given event and the events already queued.
+func (q *deadLetterQueue) collect(event common.MapStr) []common.MapStr {
+	batch := []common.MapStr{event}
+	for len(batch) < deadLetterBatchSize {
+		select {
+		case event := <-q.queue:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+func (q *deadLetterQueue) publish(batch []common.MapStr) {
+	n := int64(len(batch))
+
+	sig := op.NewSignalChannel()
+	err := q.output.BulkPublish(sig, outputs.Options{}, batch)
+	if err == nil && sig.Wait() == op.SignalCompleted {
+		deadLetterPublished.Add(n)
+		return
+	}
+
+	deadLetterFailed.Add(n)
+	if err != nil {
+		logp.Err("Failed to publish %d events to the dead letter output: %v", n, err)
+	} else {
+		logp.Err("Failed to publish %d events to the dead letter output", n)
+	}
+}
+
+// stop publishes the queued events and closes the dead letter output. It must
+// be called after the outputs reporting rejected events have been stopped.
+func (q *deadLetterQueue) stop() {
+	close(q.done)
+	<-q.closed
+	if err := q.output.Close(); err != nil {
+		logp.Err("Failed to close the dead letter output: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// +build !integration
+
+package publisher
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeDeadLetterEvent(t *testing.T) {
+	ts := time.Date(2016, 11, 2, 10, 0, 0, 0, time.UTC)
+	event := common.MapStr{
+		"beat":    common.MapStr{"name": "host"},
+		"message": "hello",
+	}
+
+	dl := makeDeadLetterEvent("elasticsearch", event, "mapping_conflict", ts)
+	assert.Equal(t, common.Time(ts), dl["@timestamp"])
+	assert.Equal(t, "dead_letter", dl["type"])
+	assert.Equal(t, event["beat"], dl["beat"])
+	assert.Equal(t, common.MapStr{
+		"output": "elasticsearch",
+		"reason": "mapping_conflict",
+		"event":  `{"beat":{"name":"host"},"message":"hello"}`,
+	}, dl["dead_letter"])
+}
+
+func TestDeadLetterQueueRequiresOneOutput(t *testing.T) {
+	_, err := newDeadLetterQueue("mybeat", DeadLetterConfig{})
+	assert.Error(t, err)
+}
+
+func TestDeadLetterQueueFileOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"path": dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := newDeadLetterQueue("mybeat", DeadLetterConfig{
+		Output: map[string]*common.Config{"file": fileConfig},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadLetter := q.handler("elasticsearch")
+	deadLetter(common.MapStr{"message": "first"}, "rejected")
+	deadLetter(common.MapStr{"message": "second"}, "rejected")
+	q.stop()
+
+	// events published after stop are dropped
+	dropped := deadLetterDropped.Value()
+	deadLetter(common.MapStr{"message": "third"}, "rejected")
+	assert.Equal(t, dropped+1, deadLetterDropped.Value())
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "mybeat"+deadLetterSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []common.MapStr
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for dec.More() {
+		var event common.MapStr
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if assert.Len(t, events, 2) {
+		info := events[1]["dead_letter"].(map[string]interface{})
+		assert.Equal(t, "elasticsearch", info["output"])
+		assert.Equal(t, "rejected", info["reason"])
+		assert.Equal(t, `{"message":"second"}`, info["event"])
+	}
+}
+
+func TestDeadLetterRequiresFeature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead_letter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"path": dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, feature.Configure(nil))
+	_, err = New("mybeat", map[string]*common.Config{"file": fileConfig}, ShipperConfig{
+		DeadLetter: &DeadLetterConfig{
+			Output: map[string]*common.Config{"file": fileConfig},
+		},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "features.dead_letter: true")
+	}
+}
@@ -0,0 +1,342 @@
+package publisher
+
+import (
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/feature"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	deliveryDeferred  = expvar.NewInt("libbeat.publisher.delivery.deferred_events")
+	deliveryForwarded = expvar.NewInt("libbeat.publisher.delivery.forwarded_events")
+)
+
+var deliveryWindowsFeature = feature.New("delivery_windows",
+	"defer best-effort events to the time windows configured with delivery.windows",
+	feature.Experimental)
+
+const defaultDeliverySpoolPath = "deferred"
+
+// DeliveryConfig configures the time windows best-effort events are published
+// in. Best-effort events published outside of the windows are written to a
+// disk-backed spool and published once a window opens.
+type DeliveryConfig struct {
+	Windows []DeliveryWindowConfig `config:"windows" validate:"required"`
+	Spool   spool.Config           `config:"spool"`
+}
+
+// DeliveryWindowConfig configures a daily time window in local time. A window
+// ending before its start time ends on the next day. The window is open on all
+// days of the week if no days are configured.
+type DeliveryWindowConfig struct {
+	Days  []string `config:"days"`
+	Start string   `config:"start" validate:"required"`
+	End   string   `config:"end" validate:"required"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type deliveryWindow struct {
+	days       [7]bool
+	start, end time.Duration // offsets from midnight
+}
+
+type deliveryWindows []deliveryWindow
+
+func (c *DeliveryWindowConfig) Validate() error {
+	_, err := newDeliveryWindow(*c)
+	return err
+}
+
+func newDeliveryWindows(configs []DeliveryWindowConfig) (deliveryWindows, error) {
+	var windows deliveryWindows
+	for i, config := range configs {
+		w, err := newDeliveryWindow(config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delivery window %d: %v", i, err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func newDeliveryWindow(config DeliveryWindowConfig) (deliveryWindow, error) {
+	var w deliveryWindow
+	var err error
+
+	if w.start, err = parseTimeOfDay(config.Start); err != nil {
+		return w, err
+	}
+	if w.end, err = parseTimeOfDay(config.End); err != nil {
+		return w, err
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("start and end of window are both %v", config.Start)
+	}
+
+	if len(config.Days) == 0 {
+		for i := range w.days {
+			w.days[i] = true
+		}
+	}
+	for _, name := range config.Days {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return w, fmt.Errorf("invalid day '%v', use one of mon, tue, wed, thu, fri, sat, sun", name)
+		}
+		w.days[day] = true
+	}
+	return w, nil
+}
+
+// parseTimeOfDay parses a time of day in the format HH:MM.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%v', use HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// at returns the time of day offset d on the day of t, d days later.
+func at(t time.Time, days int, d time.Duration) time.Time {
+	y, m, day := t.Date()
+	return time.Date(y, m, day+days, 0, 0, 0, 0, t.Location()).Add(d)
+}
+
+// open reports if the window is open at t. A window crossing midnight is open
+// on the next day until its end if it started on one of its days.
+func (w *deliveryWindow) open(t time.Time) bool {
+	offset := t.Sub(at(t, 0, 0))
+	day := t.Weekday()
+	if w.start < w.end {
+		return w.days[day] && w.start <= offset && offset < w.end
+	}
+
+	yesterday := (day + 6) % 7
+	return (w.days[day] && offset >= w.start) || (w.days[yesterday] && offset < w.end)
+}
+
+// open reports if any window is open at t.
+func (ws deliveryWindows) open(t time.Time) bool {
+	for i := range ws {
+		if ws[i].open(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextChange returns the next time after t a window opens or closes.
+func (ws deliveryWindows) nextChange(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range ws {
+		for days := -1; days <= 7; days++ {
+			start := at(t, days, w.start)
+			if !w.days[start.Weekday()] {
+				continue
+			}
+
+			end := at(t, days, w.end)
+			if w.end < w.start {
+				end = at(t, days+1, w.end)
+			}
+			for _, change := range []time.Time{start, end} {
+				if change.After(t) && (next.IsZero() || change.Before(next)) {
+					next = change
+				}
+			}
+		}
+	}
+	return next
+}
+
+// deliveryScheduler defers best-effort events published outside of the
+// delivery windows. Deferred events are written to a disk-backed spool and
+// published by a forwarder to the outputs of the async pipeline while a window
+// is open. Guaranteed events are never deferred.
+type deliveryScheduler struct {
+	pub     *Publisher
+	windows deliveryWindows
+	queue   *spool.Spool
+	async   *asyncPipeline
+	now     func() time.Time
+
+	forwarder *client
+	wg        sync.WaitGroup
+}
+
+// deferPipeline publishes events to the next pipeline, unless the events are
+// deferred by the scheduler.
+type deferPipeline struct {
+	scheduler *deliveryScheduler
+	next      pipeline
+}
+
+func newDeliveryScheduler(
+	pub *Publisher,
+	windows deliveryWindows,
+	queue *spool.Spool,
+	async *asyncPipeline,
+) *deliveryScheduler {
+	s := &deliveryScheduler{
+		pub:       pub,
+		windows:   windows,
+		queue:     queue,
+		async:     async,
+		now:       time.Now,
+		forwarder: &client{canceler: op.NewCanceler(), publisher: pub},
+	}
+
+	s.wg.Add(1)
+	go s.forward()
+	return s
+}
+
+func (p *deferPipeline) publish(m message) bool {
+	s := p.scheduler
+	if m.context.Guaranteed || s.windows.open(s.now()) {
+		return p.next.publish(m)
+	}
+
+	events := m.events
+	if m.event != nil {
+		events = []common.MapStr{m.event}
+	}
+
+	var err error
+	if s.pub.overflow != overflowBlock {
+		err = s.queue.TryAppend(events)
+		if err == spool.ErrFull {
+			overflowDroppedNewest.Add(m.size())
+		}
+	} else {
+		err = s.queue.Append(events, m.client.canceler.Done())
+	}
+
+	if err != nil {
+		debug("failed to defer events: %v", err)
+		op.SigFailed(m.context.Signal, err)
+		return false
+	}
+
+	deliveryDeferred.Add(int64(len(events)))
+	op.SigCompleted(m.context.Signal)
+	return true
+}
+
+// forward publishes the deferred events while a delivery window is open,
+// until the scheduler is stopped.
+func (s *deliveryScheduler) forward() {
+	defer s.wg.Done()
+	defer crash.Recover()
+
+	done := s.forwarder.canceler.Done()
+	for {
+		now := s.now()
+		open := s.windows.open(now)
+		timer := time.NewTimer(s.windows.nextChange(now).Sub(now))
+
+		if !open {
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		// Stop reading from the spool once the window closes.
+		windowDone := make(chan struct{})
+		go func() {
+			select {
+			case <-done:
+			case <-timer.C:
+			}
+			close(windowDone)
+		}()
+
+		if !s.forwardWindow(windowDone) {
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// forwardWindow publishes deferred events until windowDone is closed. It
+// returns false if the scheduler has been stopped.
+func (s *deliveryScheduler) forwardWindow(windowDone <-chan struct{}) bool {
+	done := s.forwarder.canceler.Done()
+	for {
+		batch, err := s.queue.Read(defaultBulkSize, windowDone)
+		if err == spool.ErrClosed {
+			select {
+			case <-done:
+				return false
+			case <-windowDone:
+				return true
+			default:
+				// spool closed
+				return false
+			}
+		}
+		if err != nil {
+			logp.Err("Failed to read deferred events: %v", err)
+			select {
+			case <-windowDone:
+				return true
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		m := message{
+			client: s.forwarder,
+			events: batch.Events,
+		}
+		if !s.pub.limiter.wait(m, done) {
+			return false
+		}
+		m.context.Signal = op.SignalCallback(s.makeACK(batch))
+		deliveryForwarded.Add(int64(len(batch.Events)))
+		s.async.outputs.send(m)
+	}
+}
+
+// makeACK removes the batch from the spool once the outputs are done with the
+// events. Like all best-effort events, events failed by the outputs are
+// dropped.
+func (s *deliveryScheduler) makeACK(batch *spool.Batch) func(op.SignalResponse) {
+	return func(sig op.SignalResponse) {
+		if sig != op.SignalCompleted {
+			debug("failed to publish %v deferred events", len(batch.Events))
+		}
+		s.queue.ACK(batch)
+	}
+}
+
+// stop stops the forwarder. Deferred events not yet read are published once a
+// window opens after a restart.
+func (s *deliveryScheduler) stop() {
+	s.forwarder.canceler.Cancel()
+	s.wg.Wait()
+}
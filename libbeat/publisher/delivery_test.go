@@ -0,0 +1,150 @@
+// +build !integration
+
+package publisher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDeliveryWindows(t *testing.T, configs ...DeliveryWindowConfig) deliveryWindows {
+	windows, err := newDeliveryWindows(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
+
+func localTime(day, hour, min int) time.Time {
+	// 2017-01-02 is a Monday
+	return time.Date(2017, 1, 2+day, hour, min, 0, 0, time.Local)
+}
+
+func TestDeliveryWindowOpen(t *testing.T) {
+	windows := mustDeliveryWindows(t,
+		DeliveryWindowConfig{Start: "22:00", End: "06:00", Days: []string{"mon", "Tue"}},
+		DeliveryWindowConfig{Start: "12:00", End: "13:00"},
+	)
+
+	tests := []struct {
+		t    time.Time
+		open bool
+	}{
+		{localTime(0, 21, 59), false},
+		{localTime(0, 22, 0), true},
+		{localTime(1, 5, 59), true},
+		{localTime(1, 6, 0), false},
+		{localTime(2, 1, 0), true}, // window started on tuesday
+		{localTime(2, 23, 0), false},
+		{localTime(3, 1, 0), false},
+		{localTime(5, 12, 30), true},
+		{localTime(5, 13, 0), false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.open, windows.open(test.t), "%v", test.t)
+	}
+}
+
+func TestDeliveryWindowNextChange(t *testing.T) {
+	windows := mustDeliveryWindows(t,
+		DeliveryWindowConfig{Start: "22:00", End: "06:00", Days: []string{"fri"}},
+	)
+
+	tests := []struct {
+		t, next time.Time
+	}{
+		{localTime(0, 10, 0), localTime(4, 22, 0)},
+		{localTime(4, 22, 0), localTime(5, 6, 0)},
+		{localTime(5, 3, 0), localTime(5, 6, 0)},
+		{localTime(5, 6, 0), localTime(11, 22, 0)},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.next, windows.nextChange(test.t), "%v", test.t)
+	}
+}
+
+func TestDeliveryWindowConfigInvalid(t *testing.T) {
+	for _, invalid := range []DeliveryWindowConfig{
+		{Start: "22", End: "06:00"},
+		{Start: "22:00", End: "24:00"},
+		{Start: "22:00", End: "22:00"},
+		{Start: "22:00", End: "06:00", Days: []string{"monday"}},
+	} {
+		_, err := newDeliveryWindows([]DeliveryWindowConfig{invalid})
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func newTestDeliveryPublisher(t *testing.T, dir string, open bool) *testPublisher {
+	testPub := newTestPublisherNoBulk(CompletedResponse)
+	queue, err := spool.Open(spool.Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A single window is open all day on one day of the week only.
+	now := localTime(0, 12, 0)
+	if !open {
+		now = localTime(1, 12, 0)
+	}
+	windows := mustDeliveryWindows(t,
+		DeliveryWindowConfig{Start: "00:00", End: "23:59", Days: []string{"mon"}})
+
+	pub := testPub.pub
+	s := &deliveryScheduler{
+		pub:       pub,
+		windows:   windows,
+		queue:     queue,
+		async:     pub.pipelines.async.(*asyncPipeline),
+		now:       func() time.Time { return now },
+		forwarder: &client{canceler: op.NewCanceler(), publisher: pub},
+	}
+	s.wg.Add(1)
+	go s.forward()
+
+	pub.delivery = s
+	pub.pipelines.async = &deferPipeline{s, pub.pipelines.async}
+	pub.pipelines.sync = &deferPipeline{s, pub.pipelines.sync}
+	return testPub
+}
+
+func TestDeliveryDeferEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deferred")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testPub := newTestDeliveryPublisher(t, dir, false)
+
+	// Best-effort events are acknowledged once deferred.
+	assert.True(t, testPub.asyncPublishEvent(common.MapStr{"@timestamp": common.Time{}, "n": 1}))
+	assert.Len(t, testPub.outputMsgHandler.msgs, 0)
+
+	// Guaranteed events are published right away.
+	assert.True(t, testPub.syncPublishEvent(common.MapStr{"@timestamp": common.Time{}, "n": 2}))
+	msgs, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, msgs[0].context.Guaranteed)
+	testPub.Stop()
+
+	// The deferred event is published once the window is open.
+	testPub = newTestDeliveryPublisher(t, dir, true)
+	defer testPub.Stop()
+
+	msgs, err = testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, msgs[0].context.Guaranteed)
+	assert.Len(t, msgs[0].events, 1)
+}
@@ -0,0 +1,239 @@
+package publisher
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	failoverRerouted = expvar.NewInt("libbeat.publisher.failover.rerouted_events")
+	failoverSwitches = expvar.NewInt("libbeat.publisher.failover.switches")
+)
+
+// failoverRetryBackoff is the time to wait before guaranteed events are sent
+// to the failover group again after all outputs failed.
+const failoverRetryBackoff = 1 * time.Second
+
+// failoverConfig configures an output as member of the failover group. The
+// output with the lowest priority value is the primary output.
+type failoverConfig struct {
+	Priority        int           `config:"priority"`
+	MaxFailures     int           `config:"max_failures" validate:"min=1"`
+	RecheckInterval time.Duration `config:"recheck_interval" validate:"min=0"`
+}
+
+var defaultFailoverConfig = failoverConfig{
+	MaxFailures:     3,
+	RecheckInterval: 30 * time.Second,
+}
+
+// readFailoverConfig returns the failover settings of an output, or nil if
+// the output is not member of the failover group.
+func readFailoverConfig(cfg *common.Config) (*failoverConfig, error) {
+	if !cfg.HasField("failover") {
+		return nil, nil
+	}
+
+	sub, err := cfg.Child("failover", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultFailoverConfig
+	if err := sub.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// outputHealth tracks the publish results of an output in the failover group.
+// An output is unhealthy after max_failures consecutive failures. Unhealthy
+// outputs are skipped, until recheck_interval has passed since the last
+// failure.
+type outputHealth struct {
+	name   string
+	config failoverConfig
+
+	mutex    sync.Mutex
+	failures int
+	failedAt time.Time
+}
+
+func newOutputHealth(name string, config failoverConfig) *outputHealth {
+	return &outputHealth{name: name, config: config}
+}
+
+// track returns a signaler recording the publish result before forwarding
+// it to s.
+func (h *outputHealth) track(s op.Signaler) op.Signaler {
+	return op.SignalCallback(func(sig op.SignalResponse) {
+		switch sig {
+		case op.SignalCompleted:
+			h.success()
+		case op.SignalFailed:
+			h.failure()
+		}
+		sig.Apply(s)
+	})
+}
+
+func (h *outputHealth) success() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.failures >= h.config.MaxFailures {
+		logp.Info("Output %s recovered", h.name)
+	}
+	h.failures = 0
+}
+
+func (h *outputHealth) failure() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.failures++
+	if h.failures == h.config.MaxFailures {
+		logp.Warn("Output %s is unhealthy after %d failed publish attempts",
+			h.name, h.failures)
+	}
+	h.failedAt = time.Now()
+}
+
+// available reports if events can be sent to the output, because it is
+// healthy or because it is due to be checked again.
+func (h *outputHealth) available(now time.Time) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.failures < h.config.MaxFailures ||
+		now.Sub(h.failedAt) >= h.config.RecheckInterval
+}
+
+type failoverMember struct {
+	output *outputWorker
+	worker worker // worker queueing messages for the output
+}
+
+// failoverGroup sends messages to the output with the highest priority that
+// is available. If an output fails to publish a message, the message is
+// rerouted to the next available output. Guaranteed messages that failed on
+// all outputs are sent to the group again after a backoff.
+type failoverGroup struct {
+	members []failoverMember
+	done    <-chan struct{}
+
+	mutex  sync.Mutex
+	active int // member that was selected last
+}
+
+func newFailoverGroup(ws *workerSignal, members []failoverMember) *failoverGroup {
+	sort.Sort(byPriority(members))
+
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.output.health.name
+	}
+	logp.Info("Failover group of outputs (in order of priority): %v", names)
+
+	return &failoverGroup{members: members, done: ws.done}
+}
+
+func (g *failoverGroup) send(m message) {
+	g.sendTo(g.selectMember(0), m, m.context.Signal)
+}
+
+func (g *failoverGroup) sendTo(i int, m message, signal op.Signaler) {
+	m.context.Signal = op.SignalCallback(func(sig op.SignalResponse) {
+		if sig == op.SignalFailed {
+			g.onFailed(i, m, signal)
+			return
+		}
+		sig.Apply(signal)
+	})
+	g.members[i].worker.send(m)
+}
+
+// onFailed reroutes m after the output i failed. It is called by the output
+// and does not block, such that the output can continue.
+func (g *failoverGroup) onFailed(i int, m message, signal op.Signaler) {
+	select {
+	case <-g.done:
+		op.SigFailed(signal, nil)
+		return
+	default:
+	}
+
+	if next := g.selectMember(i + 1); next >= 0 {
+		failoverRerouted.Add(m.size())
+		go g.sendTo(next, m, signal)
+		return
+	}
+
+	if !m.context.Guaranteed {
+		op.SigFailed(signal, nil)
+		return
+	}
+
+	go func() {
+		select {
+		case <-g.done:
+			op.SigFailed(signal, nil)
+		case <-time.After(failoverRetryBackoff):
+			g.sendTo(g.selectMember(0), m, signal)
+		}
+	}()
+}
+
+// selectMember returns the first available member starting at index from. If
+// no member is available, -1 is returned, or the member with the lowest
+// priority if the search starts at the primary output.
+func (g *failoverGroup) selectMember(from int) int {
+	now := time.Now()
+	selected := -1
+	for i := from; i < len(g.members); i++ {
+		if g.members[i].output.health.available(now) {
+			selected = i
+			break
+		}
+	}
+	if selected < 0 {
+		if from > 0 {
+			return -1
+		}
+		selected = len(g.members) - 1
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if selected != g.active {
+		if selected == 0 {
+			logp.Info("Switching back to primary output %s",
+				g.members[selected].output.health.name)
+		} else {
+			logp.Warn("Switching to failover output %s",
+				g.members[selected].output.health.name)
+		}
+		failoverSwitches.Add(1)
+		g.active = selected
+	}
+	return selected
+}
+
+type byPriority []failoverMember
+
+func (m byPriority) Len() int      { return len(m) }
+func (m byPriority) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+func (m byPriority) Less(i, j int) bool {
+	hi, hj := m[i].output.health, m[j].output.health
+	if hi.config.Priority != hj.config.Priority {
+		return hi.config.Priority < hj.config.Priority
+	}
+	return hi.name < hj.name
+}
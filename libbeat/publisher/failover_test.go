@@ -0,0 +1,135 @@
+// +build !integration
+
+package publisher
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/stretchr/testify/assert"
+)
+
+// failoverTestWorker completes or fails all messages, tracking the results
+// in the health of its output like the output worker.
+type failoverTestWorker struct {
+	health *outputHealth
+	fail   int32
+	sent   int32
+}
+
+func (w *failoverTestWorker) send(m message) {
+	atomic.AddInt32(&w.sent, 1)
+	signal := w.health.track(m.context.Signal)
+	if atomic.LoadInt32(&w.fail) != 0 {
+		op.SigFailed(signal, nil)
+	} else {
+		op.SigCompleted(signal)
+	}
+}
+
+func newTestFailoverGroup(
+	config failoverConfig,
+	names ...string,
+) (*failoverGroup, []*failoverTestWorker) {
+	var members []failoverMember
+	var workers []*failoverTestWorker
+	for i, name := range names {
+		config.Priority = len(names) - i
+		o := &outputWorker{health: newOutputHealth(name, config)}
+		w := &failoverTestWorker{health: o.health}
+		members = append(members, failoverMember{output: o, worker: w})
+		workers = append(workers, w)
+	}
+
+	// members are sorted by priority, so the workers must be too
+	g := newFailoverGroup(newWorkerSignal(), members)
+	for i, m := range g.members {
+		workers[i] = m.worker.(*failoverTestWorker)
+	}
+	return g, workers
+}
+
+func TestReadFailoverConfig(t *testing.T) {
+	config, err := readFailoverConfig(common.NewConfig())
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"failover.priority": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err = readFailoverConfig(cfg)
+	assert.NoError(t, err)
+	if assert.NotNil(t, config) {
+		assert.Equal(t, 1, config.Priority)
+		assert.Equal(t, defaultFailoverConfig.MaxFailures, config.MaxFailures)
+	}
+}
+
+func TestFailoverGroupPrimary(t *testing.T) {
+	g, workers := newTestFailoverGroup(defaultFailoverConfig, "secondary", "primary")
+	assert.Equal(t, "primary", g.members[0].output.health.name)
+
+	sig := newTestSignaler()
+	g.send(testMessage(sig, testEvent()))
+	assert.True(t, sig.wait())
+	assert.Equal(t, int32(1), workers[0].sent)
+	assert.Equal(t, int32(0), workers[1].sent)
+}
+
+func TestFailoverGroupReroute(t *testing.T) {
+	config := defaultFailoverConfig
+	config.MaxFailures = 2
+	config.RecheckInterval = time.Hour
+	g, workers := newTestFailoverGroup(config, "secondary", "primary")
+	primary, secondary := workers[0], workers[1]
+	primary.fail = 1
+
+	for i := 0; i < 3; i++ {
+		sig := newTestSignaler()
+		g.send(testMessage(sig, testEvent()))
+		assert.True(t, sig.wait())
+	}
+
+	// the primary output is skipped after two failures
+	assert.Equal(t, int32(2), primary.sent)
+	assert.Equal(t, int32(3), secondary.sent)
+
+	// the primary output is checked again after the recheck interval
+	primary.health.config.RecheckInterval = 0
+	atomic.StoreInt32(&primary.fail, 0)
+	sig := newTestSignaler()
+	g.send(testMessage(sig, testEvent()))
+	assert.True(t, sig.wait())
+	assert.Equal(t, int32(3), primary.sent)
+	assert.Equal(t, 0, g.active)
+}
+
+func TestFailoverGroupAllFailed(t *testing.T) {
+	g, workers := newTestFailoverGroup(defaultFailoverConfig, "secondary", "primary")
+	for _, w := range workers {
+		w.fail = 1
+	}
+
+	sig := newTestSignaler()
+	g.send(testMessage(sig, testEvent()))
+	assert.False(t, sig.wait())
+}
+
+// Verify that outputs in the failover group are not asked to retry
+// guaranteed events infinitely, such that events can be rerouted.
+func TestOutputWorkerFailoverOptions(t *testing.T) {
+	ctx := &Context{publishOptions: publishOptions{Guaranteed: true}}
+
+	o := &outputWorker{}
+	assert.Equal(t, outputs.Options{Guaranteed: true}, o.options(ctx))
+
+	o.health = newOutputHealth("test", defaultFailoverConfig)
+	assert.Equal(t, outputs.Options{Guaranteed: false}, o.options(ctx))
+}
@@ -0,0 +1,190 @@
+package publisher
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"golang.org/x/net/context"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	inflightEvents = expvar.NewInt("libbeat.publisher.inflight_events")
+)
+
+// clients contains the inflight trackers of all connected clients, indexed
+// by client ID.
+var clients = struct {
+	sync.Mutex
+	m map[uint64]*inflight
+}{m: map[uint64]*inflight{}}
+
+func init() {
+	expvar.Publish("libbeat.publisher.clients", expvar.Func(func() interface{} {
+		return clientStats(time.Now())
+	}))
+}
+
+// inflight tracks the events published by a client that have not yet been
+// acknowledged by the outputs. Events are acknowledged once the outputs report
+// success, failure or cancellation, or once they are dropped.
+type inflight struct {
+	mutex   sync.Mutex
+	count   int
+	seq     uint64
+	batches map[uint64]time.Time // publish time of the unacknowledged batches
+	changed chan struct{}        // closed and replaced on every acknowledgment
+	closed  chan struct{}
+}
+
+func newInflight() *inflight {
+	return &inflight{
+		batches: map[uint64]time.Time{},
+		changed: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+func registerInflight(id uint64, f *inflight) {
+	clients.Lock()
+	defer clients.Unlock()
+	clients.m[id] = f
+}
+
+func unregisterInflight(id uint64) {
+	clients.Lock()
+	defer clients.Unlock()
+	delete(clients.m, id)
+}
+
+// track adds n events to the inflight count and returns a signaler that
+// removes them when the batch is acknowledged. The signaler combines the
+// returned signaler with s.
+func (f *inflight) track(n int, s op.Signaler) op.Signaler {
+	f.mutex.Lock()
+	f.seq++
+	id := f.seq
+	f.count += n
+	f.batches[id] = time.Now()
+	f.mutex.Unlock()
+	inflightEvents.Add(int64(n))
+
+	ack := op.SignalCallback(func(op.SignalResponse) {
+		f.ack(id, n)
+	})
+	if s == nil {
+		return ack
+	}
+	return op.CombineSignalers(s, ack)
+}
+
+func (f *inflight) ack(id uint64, n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.batches[id]; !exists {
+		return
+	}
+	delete(f.batches, id)
+	f.count -= n
+	inflightEvents.Add(int64(-n))
+
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *inflight) get() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.count
+}
+
+// lag returns the time since the oldest unacknowledged batch was published.
+func (f *inflight) lag(now time.Time) time.Duration {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var lag time.Duration
+	for _, published := range f.batches {
+		if d := now.Sub(published); d > lag {
+			lag = d
+		}
+	}
+	return lag
+}
+
+// wait blocks until at most n events are in flight. It fails with
+// ErrClientClosed if the client is closed.
+func (f *inflight) wait(ctx context.Context, n int) error {
+	for {
+		f.mutex.Lock()
+		count, changed := f.count, f.changed
+		f.mutex.Unlock()
+
+		select {
+		case <-f.closed:
+			return ErrClientClosed
+		default:
+		}
+
+		if count <= n {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.closed:
+			return ErrClientClosed
+		case <-changed:
+		}
+	}
+}
+
+// close unblocks all waiting calls. Events of a closed client are no longer
+// acknowledged, such that they are removed from the inflight count.
+func (f *inflight) close() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	close(f.closed)
+	inflightEvents.Add(int64(-f.count))
+	f.count = 0
+	f.batches = map[uint64]time.Time{}
+}
+
+// clientStats returns the inflight count and lag of all connected clients.
+func clientStats(now time.Time) []common.MapStr {
+	clients.Lock()
+	ids := make([]uint64, 0, len(clients.m))
+	for id := range clients.m {
+		ids = append(ids, id)
+	}
+	trackers := make(map[uint64]*inflight, len(clients.m))
+	for id, f := range clients.m {
+		trackers[id] = f
+	}
+	clients.Unlock()
+
+	sort.Sort(uint64s(ids))
+	stats := make([]common.MapStr, 0, len(ids))
+	for _, id := range ids {
+		f := trackers[id]
+		stats = append(stats, common.MapStr{
+			"id":       id,
+			"inflight": f.get(),
+			"lag_ms":   int64(f.lag(now) / time.Millisecond),
+		})
+	}
+	return stats
+}
+
+type uint64s []uint64
+
+func (s uint64s) Len() int           { return len(s) }
+func (s uint64s) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64s) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
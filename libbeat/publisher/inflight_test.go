@@ -0,0 +1,95 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+type capturePipeline struct {
+	messages []message
+}
+
+func (p *capturePipeline) publish(m message) bool {
+	p.messages = append(p.messages, m)
+	return true
+}
+
+func TestClientInflight(t *testing.T) {
+	pipeline := &capturePipeline{}
+	pub := &Publisher{}
+	pub.pipelines.async = pipeline
+	pub.pipelines.sync = pipeline
+
+	c := newClient(pub)
+	c.bypassProcessors = true
+	defer c.Close()
+
+	var signaled []op.SignalResponse
+	signal := op.SignalCallback(func(r op.SignalResponse) {
+		signaled = append(signaled, r)
+	})
+
+	c.PublishEvents([]common.MapStr{{"a": 1}, {"a": 2}}, Signal(signal))
+	c.PublishEvent(common.MapStr{"a": 3})
+	assert.Equal(t, 3, c.Inflight())
+
+	stats := clientStats(time.Now().Add(time.Second))
+	found := false
+	for _, s := range stats {
+		if s["id"] == c.id {
+			found = true
+			assert.Equal(t, 3, s["inflight"])
+			assert.True(t, s["lag_ms"].(int64) >= 1000)
+		}
+	}
+	assert.True(t, found)
+
+	// Wait returns once at most n events are in flight.
+	done := make(chan error)
+	go func() {
+		done <- c.Wait(context.Background(), 1)
+	}()
+
+	op.SigCompleted(pipeline.messages[0].context.Signal)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, c.Inflight())
+	assert.Equal(t, []op.SignalResponse{op.SignalCompleted}, signaled)
+
+	// Dropped events are acknowledged as well.
+	op.SigFailed(pipeline.messages[1].context.Signal, nil)
+	assert.Equal(t, 0, c.Inflight())
+	assert.NoError(t, c.Wait(context.Background(), 0))
+}
+
+func TestClientWaitCanceled(t *testing.T) {
+	pipeline := &capturePipeline{}
+	pub := &Publisher{}
+	pub.pipelines.async = pipeline
+
+	c := newClient(pub)
+	c.bypassProcessors = true
+	c.PublishEvent(common.MapStr{"a": 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, c.Wait(ctx, 0))
+
+	done := make(chan error)
+	go func() {
+		done <- c.Wait(context.Background(), 0)
+	}()
+	c.Close()
+	assert.Equal(t, ErrClientClosed, <-done)
+	assert.Equal(t, 0, c.Inflight())
+
+	// Late acknowledgments of a closed client are ignored.
+	op.SigCompleted(pipeline.messages[0].context.Signal)
+	assert.Equal(t, 0, c.Inflight())
+}
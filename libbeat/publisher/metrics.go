@@ -0,0 +1,114 @@
+package publisher
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/op"
+)
+
+// Metrics that can retrieved through the expvar web interface. The metrics
+// of every output are published in a map named by the output.
+var (
+	outputsMetrics = expvar.NewMap("libbeat.publisher.outputs")
+)
+
+// outputMetrics collects the metrics of an output. The metrics are updated by
+// the meteredWorker wrapping the workers of the output in the async and sync
+// pipelines.
+type outputMetrics struct {
+	published expvar.Int // events sent to the output
+	acked     expvar.Int // events published successfully
+	failed    expvar.Int // events the output failed to publish or dropped
+	canceled  expvar.Int // events canceled by the client
+
+	// latency between sending events to the output and their acknowledgement
+	latencyCount int64
+	latencyTotal int64 // nanoseconds
+	latencyMax   int64 // nanoseconds
+}
+
+// newOutputMetrics creates the metrics of the output worker and publishes
+// them under the name of the output.
+func newOutputMetrics(o *outputWorker) *outputMetrics {
+	m := &outputMetrics{}
+
+	stats := new(expvar.Map).Init()
+	stats.Set("published_events", &m.published)
+	stats.Set("acked_events", &m.acked)
+	stats.Set("failed_events", &m.failed)
+	stats.Set("canceled_events", &m.canceled)
+	stats.Set("latency", expvar.Func(m.latencyStats))
+	stats.Set("queue", expvar.Func(func() interface{} {
+		return map[string]int{
+			"events":        len(o.queue),
+			"events_limit":  cap(o.queue),
+			"batches":       len(o.bulkQueue),
+			"batches_limit": cap(o.bulkQueue),
+		}
+	}))
+	outputsMetrics.Set(o.name, stats)
+	return m
+}
+
+// track counts n events sent to the output and returns a signaler updating
+// the metrics once the events are acknowledged, before forwarding the
+// response to s.
+func (m *outputMetrics) track(n int64, s op.Signaler) op.Signaler {
+	m.published.Add(n)
+	start := time.Now()
+	return op.SignalCallback(func(sig op.SignalResponse) {
+		switch sig {
+		case op.SignalCompleted:
+			m.acked.Add(n)
+		case op.SignalFailed:
+			m.failed.Add(n)
+		case op.SignalCanceled:
+			m.canceled.Add(n)
+		}
+		m.observeLatency(time.Since(start))
+		sig.Apply(s)
+	})
+}
+
+func (m *outputMetrics) observeLatency(d time.Duration) {
+	atomic.AddInt64(&m.latencyCount, 1)
+	atomic.AddInt64(&m.latencyTotal, int64(d))
+	for {
+		max := atomic.LoadInt64(&m.latencyMax)
+		if int64(d) <= max || atomic.CompareAndSwapInt64(&m.latencyMax, max, int64(d)) {
+			return
+		}
+	}
+}
+
+// latencyStats reports the number of acknowledged messages and their average
+// and maximum latency in milliseconds.
+func (m *outputMetrics) latencyStats() interface{} {
+	count := atomic.LoadInt64(&m.latencyCount)
+	total := atomic.LoadInt64(&m.latencyTotal)
+	max := atomic.LoadInt64(&m.latencyMax)
+
+	stats := map[string]interface{}{
+		"count":  count,
+		"avg_ms": 0.0,
+		"max_ms": float64(max) / float64(time.Millisecond),
+	}
+	if count > 0 {
+		stats["avg_ms"] = float64(total) / float64(count) / float64(time.Millisecond)
+	}
+	return stats
+}
+
+// meteredWorker updates the metrics of an output for all messages sent to the
+// worker of the output.
+type meteredWorker struct {
+	worker
+	metrics *outputMetrics
+}
+
+func (w *meteredWorker) send(m message) {
+	m.context.Signal = w.metrics.track(m.size(), m.context.Signal)
+	w.worker.send(m)
+}
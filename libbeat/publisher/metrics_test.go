@@ -0,0 +1,51 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+)
+
+// signalTestWorker acknowledges all messages with the configured response.
+type signalTestWorker struct {
+	response op.SignalResponse
+}
+
+func (w *signalTestWorker) send(m message) {
+	w.response.Apply(m.context.Signal)
+}
+
+func TestMeteredWorker(t *testing.T) {
+	o := &outputWorker{name: "metrics_test"}
+	o.messageWorker.queue = make(chan message, 10)
+	o.messageWorker.bulkQueue = make(chan message, 5)
+	metrics := newOutputMetrics(o)
+
+	target := &signalTestWorker{response: op.SignalCompleted}
+	w := &meteredWorker{worker: target, metrics: metrics}
+
+	sig := newTestSignaler()
+	w.send(testBulkMessage(sig, []common.MapStr{testEvent(), testEvent()}))
+	assert.True(t, sig.wait())
+
+	target.response = op.SignalFailed
+	sig = newTestSignaler()
+	w.send(testMessage(sig, testEvent()))
+	assert.False(t, sig.wait())
+
+	assert.Equal(t, int64(3), metrics.published.Value())
+	assert.Equal(t, int64(2), metrics.acked.Value())
+	assert.Equal(t, int64(1), metrics.failed.Value())
+
+	latency := metrics.latencyStats().(map[string]interface{})
+	assert.Equal(t, int64(2), latency["count"])
+
+	stats := outputsMetrics.Get("metrics_test")
+	if assert.NotNil(t, stats) {
+		assert.Contains(t, stats.String(), `"events_limit":10`)
+	}
+}
@@ -12,9 +12,25 @@ import (
 
 type outputWorker struct {
 	messageWorker
+	name        string // name of the output plugin
 	out         outputs.BulkOutputer
 	config      outputConfig
 	maxBulkSize int
+
+	// balancer distributes the messages to multiple publish workers, nil
+	// if the messages are published by the output worker itself.
+	balancer *balancer
+
+	// health tracks the publish results if the output is member of the
+	// failover group, nil otherwise.
+	health *outputHealth
+
+	// retry sends guaranteed messages to the output again after the output
+	// failed to publish them, nil if the output retries them infinitely.
+	retry *retryPolicy
+
+	// metrics collects the metrics of the output, nil if not published.
+	metrics *outputMetrics
 }
 
 type outputConfig struct {
@@ -39,6 +55,8 @@ func newOutputWorker(
 	ws *workerSignal,
 	hwm int,
 	bulkHWM int,
+	workers int,
+	mode loadBalanceMode,
 ) *outputWorker {
 	config := defaultConfig
 	err := cfg.Unpack(&config)
@@ -52,11 +70,22 @@ func newOutputWorker(
 		config:      config,
 		maxBulkSize: config.BulkMaxSize,
 	}
+	if workers > 1 {
+		o.balancer = newBalancer(ws, workers, mode, o.publish)
+	}
 	o.messageWorker.init(ws, hwm, bulkHWM, o)
 	return o
 }
 
 func (o *outputWorker) onStop() {
+	// the retries send to the queues closed once onStop returns
+	if o.retry != nil {
+		o.retry.stop()
+	}
+	if o.balancer != nil {
+		o.balancer.stop()
+	}
+
 	err := o.out.Close()
 	if err != nil {
 		logp.Info("Failed to close outputer: %s", err)
@@ -64,6 +93,21 @@ func (o *outputWorker) onStop() {
 }
 
 func (o *outputWorker) onMessage(m message) {
+	if o.balancer != nil {
+		o.balancer.send(m)
+		return
+	}
+	o.publish(m)
+}
+
+func (o *outputWorker) publish(m message) {
+	if o.retry != nil && m.context.Guaranteed {
+		m.context.Signal = o.retry.track(m, m.context.Signal, o.sendUntil)
+	}
+	if o.health != nil {
+		m.context.Signal = o.health.track(m.context.Signal)
+	}
+
 	if m.event != nil {
 		o.onEvent(&m.context, m.event)
 	} else {
@@ -73,7 +117,7 @@ func (o *outputWorker) onMessage(m message) {
 
 func (o *outputWorker) onEvent(ctx *Context, event common.MapStr) {
 	debug("output worker: publish single event")
-	o.out.PublishEvent(ctx.Signal, outputs.Options{Guaranteed: ctx.Guaranteed}, event)
+	o.out.PublishEvent(ctx.Signal, o.options(ctx), event)
 }
 
 func (o *outputWorker) onBulk(ctx *Context, events []common.MapStr) {
@@ -107,9 +151,18 @@ func (o *outputWorker) sendBulk(
 ) {
 	debug("output worker: publish %v events", len(events))
 
-	opts := outputs.Options{Guaranteed: ctx.Guaranteed}
-	err := o.out.BulkPublish(ctx.Signal, opts, events)
+	err := o.out.BulkPublish(ctx.Signal, o.options(ctx), events)
 	if err != nil {
 		logp.Info("Error bulk publishing events: %s", err)
 	}
 }
+
+// options returns the publish options of the output. Outputs in the failover
+// group never retry guaranteed events infinitely, such that events can be
+// rerouted once the retries are exhausted. Guaranteed events are retried by
+// the failover group or the retry policy instead.
+func (o *outputWorker) options(ctx *Context) outputs.Options {
+	return outputs.Options{
+		Guaranteed: ctx.Guaranteed && o.health == nil && o.retry == nil,
+	}
+}
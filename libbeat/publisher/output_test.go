@@ -38,7 +38,7 @@ func TestOutputWorker(t *testing.T) {
 		common.NewConfig(),
 		outputer,
 		newWorkerSignal(),
-		1, 0)
+		1, 0, 1, loadBalanceRoundRobin)
 
 	ow.onStop() // Noop
 
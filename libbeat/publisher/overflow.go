@@ -0,0 +1,109 @@
+package publisher
+
+import (
+	"expvar"
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common/op"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	overflowBlocked       = expvar.NewInt("libbeat.publisher.queue.overflow_blocked")
+	overflowDroppedOldest = expvar.NewInt("libbeat.publisher.queue.overflow_dropped_oldest")
+	overflowDroppedNewest = expvar.NewInt("libbeat.publisher.queue.overflow_dropped_newest")
+)
+
+// overflowPolicy decides what happens to best-effort events if a publisher
+// queue is full. Guaranteed events always block until the queue has space.
+// With the drop policies, guaranteed events are queued in a separate queue
+// of the worker, such that only best-effort events are ever dropped.
+type overflowPolicy uint8
+
+const (
+	// overflowBlock blocks the publisher until the queue has space.
+	overflowBlock overflowPolicy = iota
+
+	// overflowDropOldest drops the oldest message in the queue to make space
+	// for the new one.
+	overflowDropOldest
+
+	// overflowDropNewest drops the new message.
+	overflowDropNewest
+)
+
+var overflowPolicies = map[string]overflowPolicy{
+	"block":       overflowBlock,
+	"drop_oldest": overflowDropOldest,
+	"drop_newest": overflowDropNewest,
+}
+
+func parseOverflowPolicy(name string) (overflowPolicy, error) {
+	if name == "" {
+		return overflowBlock, nil
+	}
+
+	policy, found := overflowPolicies[name]
+	if !found {
+		return overflowBlock, fmt.Errorf("unsupported queue_overflow policy '%v'", name)
+	}
+	return policy, nil
+}
+
+func (p overflowPolicy) String() string {
+	for name, policy := range overflowPolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// trySend pushes m into ch without blocking. If ch is full, m is dropped or
+// the oldest message is dropped, depending on the policy. It returns false
+// if the message must be pushed blocking, because the policy blocks or
+// because the message is guaranteed. Drop policies require ch to be
+// buffered, see minOverflowBulkChanSize, and ch must not contain guaranteed
+// messages, see send.
+func (p overflowPolicy) trySend(ch chan message, m message) bool {
+	select {
+	case ch <- m:
+		messagesInWorkerQueues.Add(1)
+		return true
+	default:
+	}
+
+	if p == overflowBlock || m.context.Guaranteed {
+		overflowBlocked.Add(m.size())
+		return false
+	}
+
+	if p == overflowDropOldest {
+		select {
+		case old := <-ch:
+			messagesInWorkerQueues.Add(-1)
+			overflowDroppedOldest.Add(old.size())
+			op.SigFailed(old.context.Signal, nil)
+		default:
+		}
+
+		select {
+		case ch <- m:
+			messagesInWorkerQueues.Add(1)
+			return true
+		default:
+		}
+	}
+
+	overflowDroppedNewest.Add(m.size())
+	op.SigFailed(m.context.Signal, nil)
+	return true
+}
+
+// size returns the number of events in the message.
+func (m *message) size() int64 {
+	if m.event != nil {
+		return 1
+	}
+	return int64(len(m.events))
+}
@@ -0,0 +1,196 @@
+// +build !integration
+
+package publisher
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOverflowPolicy(t *testing.T) {
+	tests := map[string]overflowPolicy{
+		"":            overflowBlock,
+		"block":       overflowBlock,
+		"drop_oldest": overflowDropOldest,
+		"drop_newest": overflowDropNewest,
+	}
+	for name, expected := range tests {
+		policy, err := parseOverflowPolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := parseOverflowPolicy("drop")
+	assert.Error(t, err)
+}
+
+func overflowMessage(guaranteed bool) (message, *op.SignalChannel) {
+	s := op.NewSignalChannel()
+	m := message{
+		client:  &client{canceler: op.NewCanceler()},
+		context: Context{Signal: s},
+		event:   testEvent(),
+	}
+	m.context.Guaranteed = guaranteed
+	return m, s
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	ch := make(chan message, 1)
+	m1, s1 := overflowMessage(false)
+	m2, s2 := overflowMessage(false)
+
+	assert.True(t, overflowDropNewest.trySend(ch, m1))
+	assert.True(t, overflowDropNewest.trySend(ch, m2))
+
+	assert.Equal(t, op.SignalFailed, <-s2.C)
+	assert.Equal(t, m1, <-ch)
+	assert.Len(t, s1.C, 0)
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	ch := make(chan message, 1)
+	m1, s1 := overflowMessage(false)
+	m2, s2 := overflowMessage(false)
+
+	assert.True(t, overflowDropOldest.trySend(ch, m1))
+	assert.True(t, overflowDropOldest.trySend(ch, m2))
+
+	assert.Equal(t, op.SignalFailed, <-s1.C)
+	assert.Equal(t, m2, <-ch)
+	assert.Len(t, s2.C, 0)
+}
+
+func TestOverflowDropOldestKeepsGuaranteed(t *testing.T) {
+	qu, guaranteedQu := make(chan message, 1), make(chan message, 1)
+	m1, s1 := overflowMessage(true)
+	m2, _ := overflowMessage(false)
+	m3, s3 := overflowMessage(false)
+
+	send(qu, nil, guaranteedQu, overflowDropOldest, m1)
+	send(qu, nil, guaranteedQu, overflowDropOldest, m2)
+	send(qu, nil, guaranteedQu, overflowDropOldest, m3)
+
+	assert.Equal(t, m1, <-guaranteedQu)
+	assert.Equal(t, m3, <-qu)
+	assert.Len(t, s1.C, 0)
+	assert.Len(t, s3.C, 0)
+}
+
+func TestOverflowGuaranteedBlocks(t *testing.T) {
+	ch := make(chan message, 1)
+	m1, _ := overflowMessage(false)
+	m2, _ := overflowMessage(true)
+
+	for _, policy := range []overflowPolicy{overflowBlock, overflowDropOldest, overflowDropNewest} {
+		assert.True(t, policy.trySend(ch, m1))
+		assert.False(t, policy.trySend(ch, m2), "%v", policy)
+		<-ch
+	}
+
+	// blocked message is delivered once the queue has space
+	m3, _ := overflowMessage(true)
+	ch <- m2
+	done := make(chan struct{})
+	go func() {
+		send(nil, nil, ch, overflowDropOldest, m3)
+		close(done)
+	}()
+	assert.Equal(t, m2, <-ch)
+	<-done
+	assert.Equal(t, m3, <-ch)
+}
+
+// blockingHandler records the messages of a worker, blocking until a
+// message is read from its release channel.
+type blockingHandler struct {
+	mutex   sync.Mutex
+	msgs    []message
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) onMessage(m message) {
+	select {
+	case h.started <- struct{}{}:
+	default:
+	}
+	<-h.release
+	h.mutex.Lock()
+	h.msgs = append(h.msgs, m)
+	h.mutex.Unlock()
+	op.SigCompleted(m.context.Signal)
+}
+
+func (h *blockingHandler) onStop() {}
+
+// guaranteed returns the events of the guaranteed messages handled.
+func (h *blockingHandler) guaranteed() []common.MapStr {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var events []common.MapStr
+	for _, m := range h.msgs {
+		if m.context.Guaranteed {
+			events = append(events, m.event)
+		}
+	}
+	return events
+}
+
+func TestOverflowGuaranteedOrderOnStop(t *testing.T) {
+	for _, policy := range []overflowPolicy{overflowDropOldest, overflowDropNewest} {
+		ws := newWorkerSignal()
+		h := &blockingHandler{
+			started: make(chan struct{}, 1),
+			release: make(chan struct{}),
+		}
+		w := newMessageWorker(ws, 4, 0, h)
+		w.overflow = policy
+
+		// block the worker on the first message
+		first, _ := overflowMessage(false)
+		w.send(first)
+		<-h.started
+
+		// overflow the queue of best-effort messages
+		var guaranteed []common.MapStr
+		var signals []*op.SignalChannel
+		for i := 0; i < 12; i++ {
+			m, s := overflowMessage(i%3 == 0)
+			m.event["i"] = i
+			if m.context.Guaranteed {
+				guaranteed = append(guaranteed, m.event)
+				signals = append(signals, s)
+			}
+			w.send(m)
+		}
+
+		// handle some messages, then stop the worker with messages left
+		for i := 0; i < 4; i++ {
+			h.release <- struct{}{}
+			<-h.started
+		}
+		stopped := make(chan struct{})
+		go func() {
+			ws.stop()
+			close(stopped)
+		}()
+		close(h.release)
+		<-stopped
+
+		// guaranteed events are handled in order, the others are failed
+		handled := h.guaranteed()
+		assert.Equal(t, guaranteed[:len(handled)], handled, "%v", policy)
+		for i, s := range signals {
+			expected := op.SignalCompleted
+			if i >= len(handled) {
+				expected = op.SignalFailed
+			}
+			assert.Equal(t, expected, <-s.C, "%v", policy)
+		}
+	}
+}
@@ -3,24 +3,25 @@ package publisher
 import (
 	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/dbfile"
 	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/envtags"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/outputs"
 	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher/spool"
 	"github.com/nranchev/go-libGeoIP"
 
 	// load supported output plugins
-	_ "github.com/elastic/beats/libbeat/outputs/console"
-	_ "github.com/elastic/beats/libbeat/outputs/elasticsearch"
-	_ "github.com/elastic/beats/libbeat/outputs/fileout"
-	_ "github.com/elastic/beats/libbeat/outputs/kafka"
-	_ "github.com/elastic/beats/libbeat/outputs/logstash"
-	_ "github.com/elastic/beats/libbeat/outputs/redis"
+	_ "github.com/elastic/beats/libbeat/outputs/include"
 )
 
 // command line flags
@@ -47,8 +48,12 @@ type TransactionalEventPublisher interface {
 }
 
 type Publisher struct {
+	// pending counts the events accepted by the publisher that have not been
+	// acknowledged yet. It is accessed atomically and must be 64-bit aligned.
+	pending int64
+
 	shipperName    string // Shipper name as set in the configuration file
-	hostname       string // Host name as returned by the operation system
+	hostname       string // Host name reported in beat.hostname, see resolveHostname
 	name           string // The shipperName if configured, the hostname otherwise
 	IpAddrs        []string
 	disabled       bool
@@ -56,11 +61,19 @@ type Publisher struct {
 	Output         []*outputWorker
 	TopologyOutput outputs.TopologyOutputer
 	IgnoreOutgoing bool
-	GeoLite        *libgeo.GeoIP
+	geoLite        *dbfile.File
 	Processors     *processors.Processors
 
 	globalEventMetadata common.EventMetadata // Fields and tags to add to each event.
 
+	overflow overflowPolicy // behavior on full queues for best-effort events
+	limiter  *rateLimiter   // limits the rate of events sent to the outputs
+	flush    FlushConfig    // batching of events in the async pipeline
+
+	routes []routeRule // rules selecting the outputs of an event
+
+	deadLetter *deadLetterQueue // receives events rejected by the outputs, nil if disabled
+
 	RefreshTopologyTimer <-chan time.Time
 
 	// On shutdown the publisher is finished first and the outputers next,
@@ -74,23 +87,73 @@ type Publisher struct {
 		async pipeline
 	}
 
+	spool *spoolPipeline // optional disk-backed queue in front of the outputs
+
+	delivery *deliveryScheduler // optional deferral of best-effort events
+
 	// keep count of clients connected to publisher. A publisher is allowed to
 	// Stop only if all clients have been disconnected
 	numClients uint32
+
+	closing  uint32 // set once Shutdown has been called
+	stopOnce sync.Once
 }
 
 type ShipperConfig struct {
 	common.EventMetadata `config:",inline"` // Fields and tags to add to each event.
 	Name                 string             `config:"name"`
+	Hostname             string             `config:"hostname"`
+	FQDN                 bool               `config:"fqdn"`
 	RefreshTopologyFreq  time.Duration      `config:"refresh_topology_freq"`
 	Ignore_outgoing      bool               `config:"ignore_outgoing"`
 	Topology_expire      int                `config:"topology_expire"`
 	Geoip                common.Geoip       `config:"geoip"`
+	EnvTags              envtags.Config     `config:"env_tags"`
 
 	// internal publisher queue sizes
-	QueueSize     *int `config:"queue_size"`
-	BulkQueueSize *int `config:"bulk_queue_size"`
-	MaxProcs      *int `config:"max_procs"`
+	QueueSize     *int   `config:"queue_size"`
+	BulkQueueSize *int   `config:"bulk_queue_size"`
+	QueueOverflow string `config:"queue_overflow"`
+	MaxProcs      *int   `config:"max_procs"`
+
+	// publisher workers per output
+	Worker      *int   `config:"worker"`
+	LoadBalance string `config:"loadbalance"`
+
+	// rate limits of the events published to the outputs, 0 for no limit
+	MaxEventsPerSec int `config:"max_events_per_sec" validate:"min=0"`
+	MaxEventsBurst  int `config:"max_events_burst" validate:"min=0"`
+	MaxBytesPerSec  int `config:"max_bytes_per_sec" validate:"min=0"`
+	MaxBytesBurst   int `config:"max_bytes_burst" validate:"min=0"`
+
+	// time to wait for pending events on shutdown
+	ShutdownTimeout time.Duration `config:"shutdown_timeout"`
+
+	Queue QueueConfig `config:"queue"`
+
+	// batching of the events before they are sent to the outputs
+	Flush FlushConfig `config:"flush"`
+
+	// rules selecting the outputs of an event
+	Routes []RouteConfig `config:"routes"`
+
+	// output receiving the events rejected permanently by the outputs
+	DeadLetter *DeadLetterConfig `config:"dead_letter"`
+
+	// time windows best-effort events are published in
+	Delivery *DeliveryConfig `config:"delivery"`
+}
+
+type QueueConfig struct {
+	// Spool enables the disk-backed spool if set.
+	Spool *spool.Config `config:"spool"`
+}
+
+// FlushConfig configures the batches assembled by the async pipeline. Batching
+// is disabled if MinEvents is 0.
+type FlushConfig struct {
+	MinEvents int           `config:"min_events" validate:"min=0"`
+	Timeout   time.Duration `config:"timeout" validate:"min=0"`
 }
 
 type Topology struct {
@@ -101,6 +164,12 @@ type Topology struct {
 const (
 	defaultChanSize     = 1000
 	defaultBulkChanSize = 0
+
+	// minOverflowBulkChanSize is the minimum size of the bulk queues if
+	// best-effort messages are dropped on full queues. Without a buffer,
+	// every bulk would be dropped that is not picked up by the worker
+	// right away.
+	minOverflowBulkChanSize = 16
 )
 
 func init() {
@@ -137,12 +206,35 @@ func (publisher *Publisher) GetServerName(ip string) string {
 	return ""
 }
 
+// GeoLite returns the current GeoIP database, or nil if GeoIP lookups are
+// disabled.
+func (publisher *Publisher) GeoLite() *libgeo.GeoIP {
+	if publisher.geoLite == nil {
+		return nil
+	}
+	geoLite, _ := publisher.geoLite.Get().(*libgeo.GeoIP)
+	return geoLite
+}
+
 func (publisher *Publisher) Connect() Client {
+	return publisher.ConnectIndex("")
+}
+
+// ConnectIndex connects a client whose events are published to the given
+// index instead of the index of the output, unless an event sets beat.index
+// itself. The default index is used if index is empty.
+func (publisher *Publisher) ConnectIndex(index string) Client {
 	atomic.AddUint32(&publisher.numClients, 1)
-	return newClient(publisher)
+	c := newClient(publisher)
+	if index != "" {
+		c.beatMeta["index"] = index
+	}
+	return c
 }
 
 func (publisher *Publisher) UpdateTopologyPeriodically() {
+	defer crash.Recover()
+
 	for range publisher.RefreshTopologyTimer {
 		_ = publisher.PublishTopology() // ignore errors
 	}
@@ -175,6 +267,15 @@ func (publisher *Publisher) PublishTopology(params ...string) error {
 func (publisher *Publisher) RegisterProcessors(list *processors.Processors) error {
 
 	publisher.Processors = list
+
+	// Events created by processors have already passed the processors
+	// pipeline. The client does not count as connected client, as it is
+	// owned by the publisher.
+	client := newClient(publisher)
+	client.bypassProcessors = true
+	list.Start(func(event common.MapStr) {
+		client.PublishEvent(event)
+	})
 	return nil
 }
 
@@ -216,7 +317,27 @@ func (publisher *Publisher) init(
 		bulkHWM = *shipper.BulkQueueSize
 	}
 
-	publisher.GeoLite = common.LoadGeoIPData(shipper.Geoip)
+	publisher.overflow, err = parseOverflowPolicy(shipper.QueueOverflow)
+	if err != nil {
+		return err
+	}
+	if publisher.overflow != overflowBlock {
+		logp.Info("Best-effort events are dropped on full queues (%v)", publisher.overflow)
+		if bulkHWM < minOverflowBulkChanSize {
+			bulkHWM = minOverflowBulkChanSize
+		}
+	}
+
+	workers := 1
+	if shipper.Worker != nil && *shipper.Worker > 1 {
+		workers = *shipper.Worker
+	}
+	loadBalance, err := parseLoadBalanceMode(shipper.LoadBalance)
+	if err != nil {
+		return err
+	}
+
+	publisher.geoLite = common.OpenGeoIPData(shipper.Geoip)
 
 	publisher.wsPublisher.Init()
 	publisher.wsOutput.Init()
@@ -227,21 +348,77 @@ func (publisher *Publisher) init(
 			return err
 		}
 
+		if shipper.DeadLetter != nil {
+			if err := deadLetterFeature.Require("dead_letter"); err != nil {
+				return err
+			}
+			publisher.deadLetter, err = newDeadLetterQueue(beatName, *shipper.DeadLetter)
+			if err != nil {
+				return err
+			}
+		}
+
 		var outputers []*outputWorker
+		var outputNames []string
+		deadLetterOutputs := 0
 		var topoOutput outputs.TopologyOutputer
+		failoverOutputs := 0
 		for _, plugin := range plugins {
 			output := plugin.Output
 			config := plugin.Config
 
 			debug("Create output worker")
 
-			outputers = append(outputers,
-				newOutputWorker(
-					config,
-					output,
-					&publisher.wsOutput,
-					hwm,
-					bulkHWM))
+			outputWorkers := workers
+			if outputWorkers > 1 && !outputs.IsConcurrent(output) {
+				logp.Warn("Output %s does not support concurrent publishing, "+
+					"using one publisher worker instead of %d", plugin.Name, workers)
+				outputWorkers = 1
+			} else if outputWorkers > 1 {
+				logp.Info("Publishing to %s with %d workers (loadbalance=%v)",
+					plugin.Name, outputWorkers, loadBalance)
+			}
+
+			ow := newOutputWorker(
+				config,
+				output,
+				&publisher.wsOutput,
+				hwm,
+				bulkHWM,
+				outputWorkers,
+				loadBalance)
+			ow.name = plugin.Name
+			ow.metrics = newOutputMetrics(ow)
+			outputNames = append(outputNames, plugin.Name)
+
+			if dl, ok := output.(outputs.DeadLetterOutputer); ok && publisher.deadLetter != nil {
+				dl.SetDeadLetter(publisher.deadLetter.handler(plugin.Name))
+				deadLetterOutputs++
+			}
+
+			failover, err := readFailoverConfig(config)
+			if err != nil {
+				return fmt.Errorf("invalid failover settings of output %s: %v",
+					plugin.Name, err)
+			}
+			if failover != nil {
+				ow.health = newOutputHealth(plugin.Name, *failover)
+				failoverOutputs++
+			}
+
+			retry, err := readRetryConfig(config)
+			if err != nil {
+				return fmt.Errorf("invalid retry settings of output %s: %v",
+					plugin.Name, err)
+			}
+			if retry != nil {
+				if failover != nil {
+					return fmt.Errorf("output %s: retry and failover settings "+
+						"can not be combined", plugin.Name)
+				}
+				ow.retry = newRetryPolicy(plugin.Name, *retry, publisher.wsOutput.done)
+			}
+			outputers = append(outputers, ow)
 
 			if ok, _ := config.Bool("save_topology", 0); !ok {
 				continue
@@ -264,6 +441,21 @@ func (publisher *Publisher) init(
 			logp.Info("Using %s to store the topology", plugin.Name)
 		}
 
+		if publisher.deadLetter != nil && deadLetterOutputs == 0 {
+			logp.Warn("None of the configured outputs reports rejected events, " +
+				"the dead letter output will not receive any events")
+		}
+
+		if failoverOutputs == 1 {
+			logp.Warn("Only one output is configured with failover settings, " +
+				"events can not be rerouted")
+		}
+
+		publisher.routes, err = newRouteRules(shipper.Routes, outputNames)
+		if err != nil {
+			return err
+		}
+
 		publisher.Output = outputers
 		publisher.TopologyOutput = topoOutput
 	}
@@ -280,7 +472,7 @@ func (publisher *Publisher) init(
 	}
 
 	publisher.shipperName = shipper.Name
-	publisher.hostname, err = os.Hostname()
+	publisher.hostname, err = resolveHostname(shipper)
 	if err != nil {
 		return err
 	}
@@ -292,6 +484,13 @@ func (publisher *Publisher) init(
 	logp.Info("Publisher name: %s", publisher.name)
 
 	publisher.globalEventMetadata = shipper.EventMetadata
+	if shipper.EnvTags.Enabled() {
+		tags, err := envtags.Load(shipper.EnvTags)
+		if err != nil {
+			return err
+		}
+		publisher.globalEventMetadata.Fields = mergeEnvTags(tags, shipper.Fields)
+	}
 
 	//Store the publisher's IP addresses
 	publisher.IpAddrs, err = common.LocalIpAddrsAsStrings(false)
@@ -319,16 +518,115 @@ func (publisher *Publisher) init(
 		go publisher.UpdateTopologyPeriodically()
 	}
 
-	publisher.pipelines.async = newAsyncPipeline(publisher, hwm, bulkHWM, &publisher.wsPublisher)
+	publisher.limiter = newRateLimiter(shipper)
+	publisher.flush = shipper.Flush
+	async := newAsyncPipeline(publisher, hwm, bulkHWM, &publisher.wsPublisher)
+	publisher.pipelines.async = async
 	publisher.pipelines.sync = newSyncPipeline(publisher, hwm, bulkHWM)
+
+	if !publisher.disabled && shipper.Queue.Spool != nil {
+		if err := spoolQueueFeature.Require("queue.spool"); err != nil {
+			return err
+		}
+		queue, err := spool.Open(*shipper.Queue.Spool)
+		if err != nil {
+			return err
+		}
+
+		// All events are written to the spool, no matter if published
+		// sync or async.
+		publisher.spool = newSpoolPipeline(publisher, queue, async)
+		publisher.pipelines.async = publisher.spool
+		publisher.pipelines.sync = publisher.spool
+	}
+
+	if !publisher.disabled && shipper.Delivery != nil {
+		if err := deliveryWindowsFeature.Require("delivery.windows"); err != nil {
+			return err
+		}
+		windows, err := newDeliveryWindows(shipper.Delivery.Windows)
+		if err != nil {
+			return err
+		}
+
+		config := shipper.Delivery.Spool
+		if config.Path == "" {
+			config.Path = defaultDeliverySpoolPath
+		}
+		queue, err := spool.Open(config)
+		if err != nil {
+			return err
+		}
+
+		publisher.delivery = newDeliveryScheduler(publisher, windows, queue, async)
+		publisher.pipelines.async = &deferPipeline{publisher.delivery, publisher.pipelines.async}
+		publisher.pipelines.sync = &deferPipeline{publisher.delivery, publisher.pipelines.sync}
+	}
 	return nil
 }
 
+// mergeEnvTags returns the environment tags merged with the fields configured.
+// Configured fields override tags of the same name.
+func mergeEnvTags(tags, fields common.MapStr) common.MapStr {
+	merged := common.MapStr{}
+	merged.Update(tags)
+	merged.Update(fields)
+	return merged
+}
+
+// resolveHostname returns the hostname reported in beat.hostname. The
+// hostname setting takes precedence over the fully qualified domain name, if
+// enabled by the fqdn setting, and the hostname of the operating system.
+func resolveHostname(shipper ShipperConfig) (string, error) {
+	if shipper.Hostname != "" {
+		return shipper.Hostname, nil
+	}
+
+	if shipper.FQDN {
+		fqdn, err := common.FQDN()
+		if err == nil {
+			return fqdn, nil
+		}
+		logp.Warn("Failed to look up the fully qualified domain name, "+
+			"using the hostname instead: %v", err)
+	}
+
+	return os.Hostname()
+}
+
 func (publisher *Publisher) Stop() {
 	if atomic.LoadUint32(&publisher.numClients) > 0 {
 		panic("All clients must disconnect before shutting down publisher pipeline")
 	}
+	publisher.stop()
+}
+
+func (publisher *Publisher) stop() {
+	publisher.stopOnce.Do(publisher.doStop)
+}
+
+func (publisher *Publisher) doStop() {
+	if publisher.delivery != nil {
+		publisher.delivery.stop()
+	}
+	if publisher.spool != nil {
+		publisher.spool.stop()
+	}
 
 	publisher.wsPublisher.stop()
 	publisher.wsOutput.stop()
+
+	if publisher.deadLetter != nil {
+		publisher.deadLetter.stop()
+	}
+
+	if publisher.spool != nil {
+		publisher.spool.queue.Close()
+	}
+	if publisher.delivery != nil {
+		publisher.delivery.queue.Close()
+	}
+	if publisher.geoLite != nil {
+		publisher.geoLite.Close()
+	}
 }
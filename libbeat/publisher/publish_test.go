@@ -3,6 +3,7 @@
 package publisher
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -72,3 +73,19 @@ func TestPublisherTypeUpdateTopologyPeriodically(t *testing.T) {
 	assert.Equal(t, shipperName, <-topo.publishName)
 	assert.True(t, len(<-topo.publishLocalAddrs) > 0)
 }
+
+func TestResolveHostname(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := resolveHostname(ShipperConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, hostname, resolved)
+
+	// the hostname setting takes precedence over the FQDN lookup
+	resolved, err = resolveHostname(ShipperConfig{Hostname: "web1.example.com", FQDN: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "web1.example.com", resolved)
+}
@@ -0,0 +1,150 @@
+package publisher
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	rateLimitThrottled = expvar.NewInt("libbeat.publisher.ratelimit.throttled")
+	rateLimitWaitMs    = expvar.NewInt("libbeat.publisher.ratelimit.wait_ms")
+)
+
+// rateLimiter delays messages exceeding the configured rates, such that
+// publishers are slowed down instead of overloading the outputs.
+type rateLimiter struct {
+	events *tokenBucket
+	bytes  *tokenBucket
+}
+
+// newRateLimiter creates a rate limiter for the max_events_per_sec and
+// max_bytes_per_sec settings, or returns nil if no limit is configured.
+// Bursts default to the limit per second.
+func newRateLimiter(config ShipperConfig) *rateLimiter {
+	if config.MaxEventsPerSec == 0 && config.MaxBytesPerSec == 0 {
+		return nil
+	}
+
+	l := &rateLimiter{}
+	if config.MaxEventsPerSec > 0 {
+		burst := config.MaxEventsBurst
+		if burst == 0 {
+			burst = config.MaxEventsPerSec
+		}
+		l.events = newTokenBucket(float64(config.MaxEventsPerSec), float64(burst))
+		logp.Info("Publishing at most %d events per second (burst %d)",
+			config.MaxEventsPerSec, burst)
+	}
+	if config.MaxBytesPerSec > 0 {
+		burst := config.MaxBytesBurst
+		if burst == 0 {
+			burst = config.MaxBytesPerSec
+		}
+		l.bytes = newTokenBucket(float64(config.MaxBytesPerSec), float64(burst))
+		logp.Info("Publishing at most %d bytes per second (burst %d)",
+			config.MaxBytesPerSec, burst)
+	}
+	return l
+}
+
+// wait blocks until m can be published without exceeding the rates. It
+// returns false if done is closed while waiting.
+func (l *rateLimiter) wait(m message, done <-chan struct{}) bool {
+	if l == nil {
+		return true
+	}
+
+	var delay time.Duration
+	if l.events != nil {
+		delay = l.events.reserve(float64(m.size()))
+	}
+	if l.bytes != nil {
+		if d := l.bytes.reserve(float64(messageBytes(m))); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return true
+	}
+
+	rateLimitThrottled.Add(1)
+	rateLimitWaitMs.Add(int64(delay / time.Millisecond))
+	debug("rate limit exceeded, delaying %v events by %v", m.size(), delay)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// messageBytes estimates the size of the events in m by their JSON encoding.
+func messageBytes(m message) int {
+	size := func(v interface{}) int {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return 0
+		}
+		return len(b)
+	}
+
+	if m.event != nil {
+		return size(m.event)
+	}
+	total := 0
+	for _, event := range m.events {
+		total += size(event)
+	}
+	return total
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second, holding at
+// most burst tokens. Reservations larger than the available tokens put the
+// bucket into debt, such that batches larger than burst can be published and
+// the following reservations are delayed until the debt is paid.
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes n tokens and returns the time to wait until they are
+// available.
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,54 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(100, 50)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	// burst is available immediately
+	assert.Equal(t, time.Duration(0), b.reserve(50))
+
+	// reservations exceeding the tokens wait until the debt is paid
+	assert.Equal(t, 100*time.Millisecond, b.reserve(10))
+
+	// tokens are refilled at rate, but never beyond burst
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, time.Duration(0), b.reserve(50))
+	assert.Equal(t, 10*time.Millisecond, b.reserve(1))
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newRateLimiter(ShipperConfig{}))
+
+	var l *rateLimiter
+	assert.True(t, l.wait(message{event: testEvent()}, nil))
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(ShipperConfig{MaxEventsPerSec: 100, MaxEventsBurst: 2})
+	events := []common.MapStr{testEvent(), testEvent()}
+
+	start := time.Now()
+	assert.True(t, l.wait(message{events: events}, nil))
+	assert.True(t, l.wait(message{events: events}, nil))
+	assert.True(t, time.Since(start) >= 15*time.Millisecond)
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	l := newRateLimiter(ShipperConfig{MaxBytesPerSec: 1})
+	done := make(chan struct{})
+	close(done)
+
+	assert.False(t, l.wait(message{event: testEvent()}, done))
+}
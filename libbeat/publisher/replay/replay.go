@@ -0,0 +1,203 @@
+// Package replay publishes events read back from files, such as the files
+// written by the file output, through the publisher pipeline again.
+package replay
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+)
+
+// DefaultBatchSize is the default number of events published at once.
+const DefaultBatchSize = 1024
+
+// maxLineSize is the maximum size of an event read from a file.
+const maxLineSize = 10 * 1024 * 1024
+
+var (
+	ErrStopped = errors.New("replay stopped")
+	ErrNoFiles = errors.New("no files to replay")
+)
+
+var debugf = logp.MakeDebug("replay")
+
+// Stats summarizes a replay.
+type Stats struct {
+	Files   int // files read
+	Events  int // events published
+	Acked   int // events acknowledged by the outputs
+	Invalid int // lines that could not be decoded into an event
+}
+
+// Replayer reads events encoded as JSON, one event per line, and publishes
+// them with the guaranteed option. Lines that can not be decoded are logged
+// and skipped.
+type Replayer struct {
+	client    publisher.Client
+	batchSize int
+	done      chan struct{}
+	stopOnce  sync.Once
+
+	wg    sync.WaitGroup
+	acked int64
+}
+
+// New creates a Replayer publishing events in batches of batchSize events
+// using client.
+func New(client publisher.Client, batchSize int) *Replayer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Replayer{
+		client:    client,
+		batchSize: batchSize,
+		done:      make(chan struct{}),
+	}
+}
+
+// Run publishes the events of all files matching the glob patterns, in the
+// order given, and waits for the outputs to acknowledge them. Run returns
+// ErrStopped if Stop is called before all events have been acknowledged. The
+// events still in flight are dropped once the client is closed.
+func (r *Replayer) Run(patterns []string) (Stats, error) {
+	var stats Stats
+
+	files, err := expand(patterns)
+	if err != nil {
+		return stats, err
+	}
+
+	for _, path := range files {
+		if err := r.replayFile(path, &stats); err != nil {
+			return r.finish(stats), err
+		}
+		stats.Files++
+	}
+
+	acked := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(acked)
+	}()
+
+	select {
+	case <-acked:
+		return r.finish(stats), nil
+	case <-r.done:
+		return r.finish(stats), ErrStopped
+	}
+}
+
+// Stop stops reading events and returns from Run without waiting for the
+// outputs.
+func (r *Replayer) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
+
+func (r *Replayer) finish(stats Stats) Stats {
+	stats.Acked = int(atomic.LoadInt64(&r.acked))
+	return stats
+}
+
+func (r *Replayer) replayFile(path string, stats *Stats) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	logp.Info("Replaying events from %v", path)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+
+	batch := make([]common.MapStr, 0, r.batchSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		event, err := spool.DecodeEvent(data)
+		if err != nil {
+			logp.Warn("Skipping invalid event at %v:%v: %v", path, line, err)
+			stats.Invalid++
+			continue
+		}
+
+		batch = append(batch, event)
+		if len(batch) == r.batchSize {
+			if err := r.publish(batch); err != nil {
+				return err
+			}
+			stats.Events += len(batch)
+			batch = make([]common.MapStr, 0, r.batchSize)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %v: %v", path, err)
+	}
+
+	if len(batch) > 0 {
+		if err := r.publish(batch); err != nil {
+			return err
+		}
+		stats.Events += len(batch)
+	}
+	return nil
+}
+
+func (r *Replayer) publish(batch []common.MapStr) error {
+	select {
+	case <-r.done:
+		return ErrStopped
+	default:
+	}
+
+	debugf("Publish %v events", len(batch))
+	r.wg.Add(1)
+	r.client.PublishWithCallback(batch, func(acked int) {
+		atomic.AddInt64(&r.acked, int64(acked))
+		r.wg.Done()
+	}, publisher.Guaranteed)
+	return nil
+}
+
+// expand returns the files matching the glob patterns. Files matching
+// multiple patterns are only returned once.
+func expand(patterns []string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern '%v': %v", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files matching '%v'", pattern)
+		}
+
+		for _, path := range matches {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	return files, nil
+}
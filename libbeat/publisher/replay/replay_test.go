@@ -0,0 +1,81 @@
+// +build !integration
+
+package replay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	pubtest "github.com/elastic/beats/libbeat/publisher/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplayFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "beat.1",
+		`{"@timestamp":"2016-09-01T10:00:00.000Z","message":"first"}`+"\n"+
+			`not json`+"\n"+
+			"\n"+
+			`{"@timestamp":"2016-09-01T10:00:01.000Z","message":"second"}`+"\n")
+	writeFile(t, dir, "beat",
+		`{"@timestamp":"2016-09-01T10:00:02.000Z","message":"third","count":1}`+"\n")
+
+	client := pubtest.NewChanClient(10)
+	r := New(client, 2)
+	stats, err := r.Run([]string{
+		filepath.Join(dir, "beat.1"),
+		filepath.Join(dir, "beat*"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Files: 2, Events: 3, Acked: 3, Invalid: 1}, stats)
+
+	var messages []string
+	for len(client.Channel) > 0 {
+		msg := <-client.Channel
+		assert.True(t, msg.Context.Guaranteed)
+		for _, event := range msg.Events {
+			assert.IsType(t, common.Time{}, event["@timestamp"])
+			messages = append(messages, event["message"].(string))
+		}
+	}
+	assert.Equal(t, []string{"first", "second", "third"}, messages)
+}
+
+func TestReplayNoMatch(t *testing.T) {
+	client := pubtest.NewChanClient(1)
+	_, err := New(client, 1).Run([]string{"/nonexistent/beat*"})
+	assert.Error(t, err)
+}
+
+func TestReplayStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "beat", `{"message":"first"}`+"\n")
+
+	client := pubtest.NewChanClient(0)
+	r := New(client, 1)
+	r.Stop()
+	stats, err := r.Run([]string{path})
+	assert.Equal(t, ErrStopped, err)
+	assert.Equal(t, 0, stats.Events)
+}
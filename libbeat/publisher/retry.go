@@ -0,0 +1,182 @@
+package publisher
+
+import (
+	"errors"
+	"expvar"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	retriedEvents   = expvar.NewInt("libbeat.publisher.retry.retried_events")
+	exhaustedEvents = expvar.NewInt("libbeat.publisher.retry.exhausted_events")
+)
+
+// retryConfig configures how often and when guaranteed events are sent to an
+// output again after the output failed to publish them. A negative
+// max_retries retries infinitely.
+type retryConfig struct {
+	MaxRetries int           `config:"max_retries" validate:"min=-1"`
+	Backoff    backoffConfig `config:"backoff"`
+}
+
+// backoffConfig configures the exponential backoff between retries. The
+// backoff starts at init and is doubled on every retry, up to max. Jitter is
+// the fraction of the backoff by which the backoff is randomly reduced, such
+// that multiple Beats do not retry at the same time.
+type backoffConfig struct {
+	Init   time.Duration `config:"init" validate:"min=0"`
+	Max    time.Duration `config:"max" validate:"min=0"`
+	Jitter float64       `config:"jitter"`
+}
+
+var (
+	errInvalidJitter     = errors.New("backoff.jitter must be between 0 and 1")
+	errInvalidMaxBackoff = errors.New("backoff.max must not be less than backoff.init")
+)
+
+var defaultRetryConfig = retryConfig{
+	MaxRetries: 3,
+	Backoff: backoffConfig{
+		Init:   1 * time.Second,
+		Max:    60 * time.Second,
+		Jitter: 0.1,
+	},
+}
+
+func (c *backoffConfig) Validate() error {
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return errInvalidJitter
+	}
+	if c.Max < c.Init {
+		return errInvalidMaxBackoff
+	}
+	return nil
+}
+
+// readRetryConfig returns the retry settings of an output, or nil if the
+// output retries guaranteed events infinitely.
+func readRetryConfig(cfg *common.Config) (*retryConfig, error) {
+	if !cfg.HasField("retry") {
+		return nil, nil
+	}
+
+	sub, err := cfg.Child("retry", -1)
+	if err != nil {
+		return nil, err
+	}
+
+	config := defaultRetryConfig
+	if err := sub.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// retryPolicy decides if and when a message is sent to the output again after
+// the output failed to publish it.
+type retryPolicy struct {
+	name   string
+	config retryConfig
+	done   <-chan struct{}
+
+	mutex   sync.Mutex
+	stopped bool           // no new retries are scheduled once stopped
+	pending sync.WaitGroup // scheduled retries
+}
+
+func newRetryPolicy(name string, config retryConfig, done <-chan struct{}) *retryPolicy {
+	return &retryPolicy{name: name, config: config, done: done}
+}
+
+// backoff returns the time to wait before the retry with the given number,
+// starting at 1. False is returned if the retries are exhausted.
+func (p *retryPolicy) backoff(retry int) (time.Duration, bool) {
+	if p.config.MaxRetries >= 0 && retry > p.config.MaxRetries {
+		return 0, false
+	}
+
+	init, max := p.config.Backoff.Init, p.config.Backoff.Max
+	backoff := init
+	for i := 1; i < retry && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	if jitter := p.config.Backoff.Jitter; jitter > 0 {
+		backoff -= time.Duration(jitter * rand.Float64() * float64(backoff))
+	}
+	return backoff, true
+}
+
+// track returns a signaler that sends m to the output again using send, if
+// the output fails to publish it. Signal is failed once the retries are
+// exhausted, such that the caller can decide what to do with the events.
+// send must give up and return false once done is closed.
+func (p *retryPolicy) track(
+	m message,
+	signal op.Signaler,
+	send func(m message, done <-chan struct{}) bool,
+) op.Signaler {
+	return op.SignalCallback(func(sig op.SignalResponse) {
+		if sig != op.SignalFailed {
+			sig.Apply(signal)
+			return
+		}
+
+		backoff, ok := p.backoff(m.retries + 1)
+		if !ok {
+			logp.Warn("Output %s failed to publish %d events after %d retries",
+				p.name, m.size(), m.retries)
+			exhaustedEvents.Add(m.size())
+			op.SigFailed(signal, nil)
+			return
+		}
+
+		p.mutex.Lock()
+		if p.stopped {
+			p.mutex.Unlock()
+			op.SigFailed(signal, nil)
+			return
+		}
+		p.pending.Add(1)
+		p.mutex.Unlock()
+
+		m.retries++
+		m.context.Signal = signal
+		retriedEvents.Add(m.size())
+		debug("output %s: retry %v in %v", p.name, m.retries, backoff)
+		go func() {
+			defer p.pending.Done()
+
+			timer := time.NewTimer(backoff)
+			defer timer.Stop()
+			select {
+			case <-p.done:
+				op.SigFailed(signal, nil)
+			case <-timer.C:
+				if !send(m, p.done) {
+					op.SigFailed(signal, nil)
+				}
+			}
+		}()
+	})
+}
+
+// stop waits for the scheduled retries to return, after done has been
+// closed. Retries scheduled after stop fail right away, such that no
+// messages are sent to the queues of the output worker once it is stopped.
+func (p *retryPolicy) stop() {
+	p.mutex.Lock()
+	p.stopped = true
+	p.mutex.Unlock()
+	p.pending.Wait()
+}
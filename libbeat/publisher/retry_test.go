@@ -0,0 +1,189 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRetryConfig(t *testing.T) {
+	config, err := readRetryConfig(common.NewConfig())
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"retry.max_retries": 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err = readRetryConfig(cfg)
+	assert.NoError(t, err)
+	if assert.NotNil(t, config) {
+		assert.Equal(t, 5, config.MaxRetries)
+		assert.Equal(t, defaultRetryConfig.Backoff, config.Backoff)
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"retry.max_retries": -2},
+		{"retry.backoff.jitter": 1.5},
+		{"retry.backoff.init": "10s", "retry.backoff.max": "1s"},
+	} {
+		cfg, err := common.NewConfigFrom(invalid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = readRetryConfig(cfg)
+		assert.Error(t, err, "%v", invalid)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	config := retryConfig{
+		MaxRetries: 5,
+		Backoff:    backoffConfig{Init: time.Second, Max: 5 * time.Second},
+	}
+	p := newRetryPolicy("test", config, nil)
+
+	expected := []time.Duration{1, 2, 4, 5, 5}
+	for i, backoff := range expected {
+		d, ok := p.backoff(i + 1)
+		assert.True(t, ok)
+		assert.Equal(t, backoff*time.Second, d)
+	}
+
+	_, ok := p.backoff(len(expected) + 1)
+	assert.False(t, ok)
+
+	// negative max_retries retries infinitely
+	p.config.MaxRetries = -1
+	d, ok := p.backoff(100)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, d)
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	config := retryConfig{
+		MaxRetries: -1,
+		Backoff: backoffConfig{
+			Init:   time.Second,
+			Max:    time.Second,
+			Jitter: 0.5,
+		},
+	}
+	p := newRetryPolicy("test", config, nil)
+
+	for i := 0; i < 100; i++ {
+		d, _ := p.backoff(1)
+		assert.True(t, d > 500*time.Millisecond && d <= time.Second, "%v", d)
+	}
+}
+
+// retryTestOutput fails the first failures publish attempts of a message,
+// and retries them using the retry policy like the output worker.
+func retryTestOutput(p *retryPolicy, failures int, sent *int) func(message) {
+	var publish func(message)
+	send := func(m message, done <-chan struct{}) bool {
+		publish(m)
+		return true
+	}
+	publish = func(m message) {
+		*sent++
+		signal := p.track(m, m.context.Signal, send)
+		if *sent <= failures {
+			op.SigFailed(signal, nil)
+		} else {
+			op.SigCompleted(signal)
+		}
+	}
+	return publish
+}
+
+func TestRetryPolicySuccess(t *testing.T) {
+	config := retryConfig{MaxRetries: 3}
+	p := newRetryPolicy("test", config, nil)
+
+	sent := 0
+	sig := newTestSignaler()
+	retryTestOutput(p, 2, &sent)(testMessage(sig, testEvent()))
+	assert.True(t, sig.wait())
+	assert.Equal(t, 3, sent)
+}
+
+// Verify that the signal of the message is failed once the retries are
+// exhausted.
+func TestRetryPolicyExhausted(t *testing.T) {
+	config := retryConfig{MaxRetries: 3}
+	p := newRetryPolicy("test", config, nil)
+
+	sent := 0
+	sig := newTestSignaler()
+	retryTestOutput(p, 10, &sent)(testMessage(sig, testEvent()))
+	assert.False(t, sig.wait())
+	assert.Equal(t, 4, sent)
+}
+
+func TestRetryPolicyStopped(t *testing.T) {
+	done := make(chan struct{})
+	config := retryConfig{
+		MaxRetries: -1,
+		Backoff:    backoffConfig{Init: time.Hour, Max: time.Hour},
+	}
+	p := newRetryPolicy("test", config, done)
+
+	sent := 0
+	sig := newTestSignaler()
+	retryTestOutput(p, 1, &sent)(testMessage(sig, testEvent()))
+	close(done)
+	assert.False(t, sig.wait())
+	assert.Equal(t, 1, sent)
+}
+
+// Verify that stop waits for the retries blocked on a full queue, and that
+// no retries are sent once stopped.
+func TestRetryPolicyStopWaitsForRetries(t *testing.T) {
+	done := make(chan struct{})
+	p := newRetryPolicy("test", retryConfig{MaxRetries: -1}, done)
+
+	blocked := make(chan struct{})
+	sent := 0
+	send := func(m message, done <-chan struct{}) bool {
+		sent++
+		close(blocked)
+		<-done
+		return false
+	}
+
+	sig := newTestSignaler()
+	m := testMessage(sig, testEvent())
+	op.SigFailed(p.track(m, m.context.Signal, send), nil)
+	<-blocked
+
+	close(done)
+	p.stop()
+	assert.False(t, sig.wait())
+
+	sig = newTestSignaler()
+	m = testMessage(sig, testEvent())
+	op.SigFailed(p.track(m, m.context.Signal, send), nil)
+	assert.False(t, sig.wait())
+	assert.Equal(t, 1, sent)
+}
+
+// Verify that outputs with a retry policy are not asked to retry guaranteed
+// events infinitely.
+func TestOutputWorkerRetryOptions(t *testing.T) {
+	ctx := &Context{publishOptions: publishOptions{Guaranteed: true}}
+
+	o := &outputWorker{}
+	assert.Equal(t, outputs.Options{Guaranteed: true}, o.options(ctx))
+
+	o.retry = newRetryPolicy("test", defaultRetryConfig, nil)
+	assert.Equal(t, outputs.Options{Guaranteed: false}, o.options(ctx))
+}
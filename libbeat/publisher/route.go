@@ -0,0 +1,172 @@
+package publisher
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/processors"
+)
+
+// RouteConfig configures a routing rule. Events matching the condition are
+// only published to the listed outputs. Events without condition match all
+// events.
+type RouteConfig struct {
+	Outputs []string                    `config:"outputs" validate:"required"`
+	When    *processors.ConditionConfig `config:"when"`
+}
+
+// routeRule is a routing rule with its condition compiled.
+type routeRule struct {
+	outputs []string
+	cond    *processors.Condition
+}
+
+// newRouteRules compiles the routing rules and checks that all outputs
+// referenced by the rules are configured.
+func newRouteRules(configs []RouteConfig, outputNames []string) ([]routeRule, error) {
+	known := map[string]bool{}
+	for _, name := range outputNames {
+		known[name] = true
+	}
+
+	var rules []routeRule
+	for i, config := range configs {
+		for _, name := range config.Outputs {
+			if !known[name] {
+				return nil, fmt.Errorf("route %d references output '%v', "+
+					"which is not configured", i, name)
+			}
+		}
+
+		cond, err := processors.NewCondition(config.When)
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition of route %d: %v", i, err)
+		}
+		rules = append(rules, routeRule{outputs: config.Outputs, cond: cond})
+	}
+	return rules, nil
+}
+
+// outputSet sends the messages of a pipeline to the output workers. If
+// routing rules are configured, the events are only sent to the workers
+// selected by the first matching rule. Events matching no rule are sent to
+// all workers.
+type outputSet struct {
+	workers []worker
+	all     []int
+	routes  []route
+}
+
+// route is a routing rule resolved to the indices of the workers.
+type route struct {
+	cond    *processors.Condition
+	targets []int
+}
+
+// newOutputSet creates the worker for every output by calling fn. The workers
+// of the outputs in the failover group are combined into one worker. Workers
+// of outputs with metrics are wrapped to update the metrics.
+func (publisher *Publisher) newOutputSet(fn func(o *outputWorker) worker) *outputSet {
+	s := &outputSet{}
+	index := map[string]int{}
+
+	var group []failoverMember
+	var groupNames []string
+	for _, o := range publisher.Output {
+		w := fn(o)
+		if o.metrics != nil {
+			w = &meteredWorker{worker: w, metrics: o.metrics}
+		}
+		if o.health == nil {
+			index[o.name] = len(s.workers)
+			s.workers = append(s.workers, w)
+			continue
+		}
+		group = append(group, failoverMember{output: o, worker: w})
+		groupNames = append(groupNames, o.name)
+	}
+	if len(group) > 0 {
+		for _, name := range groupNames {
+			index[name] = len(s.workers)
+		}
+		s.workers = append(s.workers, newFailoverGroup(&publisher.wsOutput, group))
+	}
+
+	for i := range s.workers {
+		s.all = append(s.all, i)
+	}
+
+	for _, rule := range publisher.routes {
+		seen := map[int]bool{}
+		r := route{cond: rule.cond}
+		for _, name := range rule.outputs {
+			i := index[name]
+			if !seen[i] {
+				seen[i] = true
+				r.targets = append(r.targets, i)
+			}
+		}
+		s.routes = append(s.routes, r)
+	}
+	return s
+}
+
+// targets returns the indices of the workers to send event to.
+func (s *outputSet) targets(event common.MapStr) []int {
+	for _, r := range s.routes {
+		if r.cond == nil || r.cond.Check(event) {
+			return r.targets
+		}
+	}
+	return s.all
+}
+
+// send sends m to the selected workers. The signal of m is split between
+// the workers.
+func (s *outputSet) send(m message) {
+	if len(s.routes) == 0 || (m.event == nil && len(m.events) == 0) {
+		s.sendTo(s.all, m)
+		return
+	}
+
+	if m.event != nil {
+		s.sendTo(s.targets(m.event), m)
+		return
+	}
+
+	// Partition the batch by worker. The order of the events is kept.
+	events := make([][]common.MapStr, len(s.workers))
+	sends := 0
+	for _, event := range m.events {
+		for _, i := range s.targets(event) {
+			if len(events[i]) == 0 {
+				sends++
+			}
+			events[i] = append(events[i], event)
+		}
+	}
+
+	signal := m.context.Signal
+	if sends > 1 {
+		signal = op.SplitSignaler(signal, sends)
+	}
+	for i, batch := range events {
+		if len(batch) == 0 {
+			continue
+		}
+		routed := m
+		routed.events = batch
+		routed.context.Signal = signal
+		s.workers[i].send(routed)
+	}
+}
+
+func (s *outputSet) sendTo(targets []int, m message) {
+	if len(targets) > 1 {
+		m.context.Signal = op.SplitSignaler(m.context.Signal, len(targets))
+	}
+	for _, i := range targets {
+		s.workers[i].send(m)
+	}
+}
@@ -0,0 +1,126 @@
+// +build !integration
+
+package publisher
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/stretchr/testify/assert"
+)
+
+// routeTestWorker records the messages sent to it and completes them.
+type routeTestWorker struct {
+	mutex    sync.Mutex
+	messages []message
+}
+
+func (w *routeTestWorker) send(m message) {
+	w.mutex.Lock()
+	w.messages = append(w.messages, m)
+	w.mutex.Unlock()
+	op.SigCompleted(m.context.Signal)
+}
+
+func (w *routeTestWorker) events() []common.MapStr {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var events []common.MapStr
+	for _, m := range w.messages {
+		if m.event != nil {
+			events = append(events, m.event)
+		}
+		events = append(events, m.events...)
+	}
+	return events
+}
+
+func readRouteConfigs(t *testing.T, routes ...map[string]interface{}) []RouteConfig {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{"routes": routes})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var config struct {
+		Routes []RouteConfig `config:"routes"`
+	}
+	if err := cfg.Unpack(&config); err != nil {
+		t.Fatal(err)
+	}
+	return config.Routes
+}
+
+// newTestOutputSet creates an output set for the outputs "elasticsearch" and
+// "kafka". Audit events are routed to kafka.
+func newTestOutputSet(t *testing.T) (*outputSet, *routeTestWorker, *routeTestWorker) {
+	configs := readRouteConfigs(t, map[string]interface{}{
+		"outputs":     []string{"kafka"},
+		"when.equals": map[string]interface{}{"type": "audit"},
+	})
+	names := []string{"elasticsearch", "kafka"}
+	rules, err := newRouteRules(configs, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := &Publisher{routes: rules}
+	for _, name := range names {
+		pub.Output = append(pub.Output, &outputWorker{name: name})
+	}
+
+	workers := map[string]*routeTestWorker{}
+	s := pub.newOutputSet(func(o *outputWorker) worker {
+		w := &routeTestWorker{}
+		workers[o.name] = w
+		return w
+	})
+	return s, workers["elasticsearch"], workers["kafka"]
+}
+
+func TestNewRouteRulesUnknownOutput(t *testing.T) {
+	configs := readRouteConfigs(t, map[string]interface{}{
+		"outputs": []string{"kafka"},
+	})
+	_, err := newRouteRules(configs, []string{"elasticsearch"})
+	assert.Error(t, err)
+}
+
+func TestOutputSetRouteEvent(t *testing.T) {
+	s, es, kafka := newTestOutputSet(t)
+
+	audit := common.MapStr{"type": "audit"}
+	sig := newTestSignaler()
+	s.send(testMessage(sig, audit))
+	assert.True(t, sig.wait())
+	assert.Equal(t, []common.MapStr{audit}, kafka.events())
+	assert.Empty(t, es.events())
+}
+
+func TestOutputSetDefaultRoute(t *testing.T) {
+	s, es, kafka := newTestOutputSet(t)
+
+	event := common.MapStr{"type": "log"}
+	sig := newTestSignaler()
+	s.send(testMessage(sig, event))
+	assert.True(t, sig.wait())
+	assert.Equal(t, []common.MapStr{event}, es.events())
+	assert.Equal(t, []common.MapStr{event}, kafka.events())
+}
+
+func TestOutputSetPartitionBatch(t *testing.T) {
+	s, es, kafka := newTestOutputSet(t)
+
+	audit1 := common.MapStr{"type": "audit", "n": 1}
+	log := common.MapStr{"type": "log"}
+	audit2 := common.MapStr{"type": "audit", "n": 2}
+
+	sig := newTestSignaler()
+	s.send(testBulkMessage(sig, []common.MapStr{audit1, log, audit2}))
+	assert.True(t, sig.wait())
+	assert.Equal(t, []common.MapStr{log}, es.events())
+	assert.Equal(t, []common.MapStr{audit1, log, audit2}, kafka.events())
+	assert.Len(t, kafka.messages, 1)
+}
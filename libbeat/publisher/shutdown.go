@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var (
+	ErrShutdown = errors.New("publisher is shutting down")
+)
+
+// shutdownPollInterval is the interval at which Shutdown checks if all
+// pending events have been acknowledged.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// accept counts n events as pending until they are acknowledged by the
+// outputs. It returns false if the publisher is shutting down and the events
+// must be rejected. The events are counted before checking for shutdown, such
+// that Shutdown waits for all events accepted.
+func (publisher *Publisher) accept(n int, s op.Signaler) (op.Signaler, bool) {
+	atomic.AddInt64(&publisher.pending, int64(n))
+	var ack op.Signaler = op.SignalCallback(func(op.SignalResponse) {
+		atomic.AddInt64(&publisher.pending, int64(-n))
+	})
+	if s != nil {
+		ack = op.CombineSignalers(s, ack)
+	}
+
+	if atomic.LoadUint32(&publisher.closing) != 0 {
+		op.SigFailed(ack, ErrShutdown)
+		return nil, false
+	}
+	return ack, true
+}
+
+// Shutdown stops the publisher gracefully. Events published after Shutdown
+// has been called are rejected. Shutdown waits up to timeout for the events
+// accepted before to be acknowledged by the outputs, then stops the pipelines
+// and the outputs. Unlike Stop, Shutdown can be called while clients are
+// still connected. It returns the number of events that have not been
+// acknowledged in time and have been dropped.
+func (publisher *Publisher) Shutdown(timeout time.Duration) int {
+	atomic.StoreUint32(&publisher.closing, 1)
+
+	pending := atomic.LoadInt64(&publisher.pending)
+	if pending > 0 {
+		logp.Info("Waiting up to %v for %d pending events to be published", timeout, pending)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for pending > 0 && time.Now().Before(deadline) {
+		time.Sleep(shutdownPollInterval)
+		pending = atomic.LoadInt64(&publisher.pending)
+	}
+
+	if pending > 0 {
+		logp.Warn("Shutdown timeout reached, dropping %d pending events", pending)
+	}
+	publisher.stop()
+	return int(pending)
+}
@@ -0,0 +1,62 @@
+// +build !integration
+
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShutdownTestPublisher() *Publisher {
+	pub := &Publisher{}
+	pub.wsOutput.Init()
+	pub.wsPublisher.Init()
+	return pub
+}
+
+func TestShutdownWaitsForPendingEvents(t *testing.T) {
+	pub := newShutdownTestPublisher()
+
+	sig, ok := pub.accept(3, nil)
+	assert.True(t, ok)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		op.SigCompleted(sig)
+	}()
+
+	assert.Equal(t, 0, pub.Shutdown(5*time.Second))
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	pub := newShutdownTestPublisher()
+
+	_, ok := pub.accept(2, nil)
+	assert.True(t, ok)
+	assert.Equal(t, 2, pub.Shutdown(20*time.Millisecond))
+}
+
+func TestShutdownRejectsEvents(t *testing.T) {
+	testPub := newTestPublisherWithBulk(CompletedResponse)
+	testPub.pub.Processors = &processors.Processors{}
+
+	assert.True(t, testPub.client.PublishEvent(testEvent()))
+	_, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// clients may still be connected
+	assert.Equal(t, 0, testPub.pub.Shutdown(time.Second))
+
+	sig := newTestSignaler()
+	assert.False(t, testPub.client.PublishEvent(testEvent(), Signal(sig)))
+	assert.False(t, sig.wait())
+
+	// stopping again is a no-op
+	testPub.client.Close()
+	testPub.pub.Stop()
+}
@@ -0,0 +1,184 @@
+package publisher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
+	"github.com/elastic/beats/libbeat/feature"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+)
+
+// spoolRetryBackoff is the time to wait before spooled events that failed to
+// publish are sent to the outputs again.
+const spoolRetryBackoff = 1 * time.Second
+
+var spoolQueueFeature = feature.New("spool_queue",
+	"write all events to the disk-backed spool configured with queue.spool",
+	feature.Experimental)
+
+// spoolPipeline writes published events to a disk-backed spool. Events are
+// acknowledged to the clients once they have been written to the spool. A
+// forwarder reads the events from the spool and publishes them to the
+// outputs of the async pipeline. Events are removed from the spool once all
+// outputs have acknowledged them, such that events not yet published are
+// replayed after a restart.
+type spoolPipeline struct {
+	pub   *Publisher
+	queue *spool.Spool
+	async *asyncPipeline
+
+	// forwarder owns a client only to be able to cancel blocked sends to the
+	// output workers on shutdown.
+	forwarder *client
+	wg        sync.WaitGroup // forwarder and pending retries of failed batches
+
+	mutex   sync.Mutex
+	stopped bool // failed batches are not retried once stopped
+}
+
+func newSpoolPipeline(
+	pub *Publisher,
+	queue *spool.Spool,
+	async *asyncPipeline,
+) *spoolPipeline {
+	p := &spoolPipeline{
+		pub:       pub,
+		queue:     queue,
+		async:     async,
+		forwarder: &client{canceler: op.NewCanceler(), publisher: pub},
+	}
+
+	p.wg.Add(1)
+	go p.forward()
+	return p
+}
+
+func (p *spoolPipeline) publish(m message) bool {
+	if p.pub.disabled {
+		debug("publisher disabled")
+		op.SigCompleted(m.context.Signal)
+		return true
+	}
+
+	events := m.events
+	if m.event != nil {
+		events = []common.MapStr{m.event}
+	}
+
+	var err error
+	if p.pub.overflow != overflowBlock && !m.context.Guaranteed {
+		err = p.queue.TryAppend(events)
+		if err == spool.ErrFull {
+			overflowDroppedNewest.Add(m.size())
+		}
+	} else {
+		err = p.queue.Append(events, m.client.canceler.Done())
+	}
+
+	if err != nil {
+		debug("failed to spool events: %v", err)
+		op.SigFailed(m.context.Signal, err)
+		return false
+	}
+
+	op.SigCompleted(m.context.Signal)
+	return true
+}
+
+// forward publishes the spooled events to the outputs until the pipeline is
+// stopped.
+func (p *spoolPipeline) forward() {
+	defer p.wg.Done()
+	defer crash.Recover()
+
+	done := p.forwarder.canceler.Done()
+	for {
+		batch, err := p.queue.Read(defaultBulkSize, done)
+		if err == spool.ErrClosed {
+			return
+		}
+		if err != nil {
+			logp.Err("Failed to read events from spool: %v", err)
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		m := message{client: p.forwarder, events: batch.Events}
+		if !p.pub.limiter.wait(m, done) {
+			return
+		}
+		p.send(batch)
+	}
+}
+
+// send publishes the events of the batch to the outputs.
+func (p *spoolPipeline) send(batch *spool.Batch) {
+	p.async.outputs.send(message{
+		client: p.forwarder,
+		context: Context{
+			publishOptions: publishOptions{Guaranteed: true},
+			Signal:         op.SignalCallback(p.makeACK(batch)),
+		},
+		events: batch.Events,
+	})
+}
+
+func (p *spoolPipeline) makeACK(batch *spool.Batch) func(op.SignalResponse) {
+	return func(sig op.SignalResponse) {
+		if sig == op.SignalCompleted {
+			p.queue.ACK(batch)
+			return
+		}
+
+		// The spool removes events in order only, so a failed batch would
+		// hold back the acknowledgement of all batches read later. The
+		// batch is sent to the outputs again after a backoff. On shutdown
+		// the events are kept in the spool and published again after a
+		// restart.
+		debug("failed to publish %v spooled events", len(batch.Events))
+		p.mutex.Lock()
+		if p.stopped {
+			p.mutex.Unlock()
+			return
+		}
+		p.wg.Add(1)
+		p.mutex.Unlock()
+
+		go p.retry(batch)
+	}
+}
+
+// retry sends the batch to the outputs again after the backoff. Sending is
+// canceled with the forwarder, and stop waits for retry to return, such that
+// the batch is never sent to the queues of stopped output workers.
+func (p *spoolPipeline) retry(batch *spool.Batch) {
+	defer p.wg.Done()
+	defer crash.Recover()
+
+	timer := time.NewTimer(spoolRetryBackoff)
+	defer timer.Stop()
+	select {
+	case <-p.forwarder.canceler.Done():
+	case <-timer.C:
+		p.send(batch)
+	}
+}
+
+// stop stops the forwarder and the pending retries. Events read, but not yet
+// published, are failed by the output workers on shutdown.
+func (p *spoolPipeline) stop() {
+	p.mutex.Lock()
+	p.stopped = true
+	p.mutex.Unlock()
+
+	p.forwarder.canceler.Cancel()
+	p.wg.Wait()
+}
@@ -0,0 +1,56 @@
+package spool
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+func encodeEvent(event common.MapStr) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// DecodeEvent decodes an event encoded as JSON, like the events written to the
+// spool or by the file output. Objects are decoded into common.MapStr and the
+// @timestamp field into common.Time, as expected by the outputs. Numbers are
+// kept as json.Number, such that integers do not lose precision.
+func DecodeEvent(data []byte) (common.MapStr, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	event := toMapStr(m)
+	if ts, ok := event["@timestamp"].(string); ok {
+		if t, err := common.ParseTime(ts); err == nil {
+			event["@timestamp"] = t
+		}
+	}
+	return event, nil
+}
+
+func toMapStr(m map[string]interface{}) common.MapStr {
+	event := common.MapStr(m)
+	for k, v := range event {
+		event[k] = normalize(v)
+	}
+	return event
+}
+
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return toMapStr(v)
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = normalize(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,148 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// Events are stored in segment files. Every event is written as one record,
+// consisting of the length and CRC32 checksum of the payload, followed by the
+// JSON encoded event. Segments are named after their ID, which increases by
+// one for every new segment.
+const (
+	segmentExt = ".seg"
+	headerSize = 8
+
+	// maxRecordSize protects against allocating huge buffers for corrupted
+	// length fields.
+	maxRecordSize = 1 << 30
+)
+
+var errCorrupted = errors.New("corrupted record")
+
+type segment struct {
+	id   uint64
+	size int64 // size of the completely written records
+}
+
+func segmentPath(dir string, id uint64) string {
+	return filepath.Join(dir, strconv.FormatUint(id, 10)+segmentExt)
+}
+
+// listSegments returns the segments found in dir, ordered by ID.
+func listSegments(dir string) ([]segment, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{id: id, size: f.Size()})
+	}
+
+	sort.Sort(segmentsByID(segments))
+	return segments, nil
+}
+
+type segmentsByID []segment
+
+func (s segmentsByID) Len() int           { return len(s) }
+func (s segmentsByID) Less(i, j int) bool { return s[i].id < s[j].id }
+func (s segmentsByID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// appendRecord appends the record for payload to buf.
+func appendRecord(buf []byte, payload []byte) []byte {
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	buf = append(buf, header[:]...)
+	return append(buf, payload...)
+}
+
+// readRecord reads the next record from r. It returns io.EOF if r is at the
+// end, and errCorrupted if the record is incomplete or its checksum does not
+// match.
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, errCorrupted
+		}
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(header[:4])
+	if size > maxRecordSize {
+		return nil, errCorrupted
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errCorrupted
+		}
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, errCorrupted
+	}
+	return payload, nil
+}
+
+// repairSegment truncates the segment after the last complete record. Records
+// can be incomplete if the beat crashed while writing them. It returns the
+// size of the repaired segment.
+func repairSegment(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var valid int64
+	r := bufio.NewReader(f)
+	for {
+		payload, err := readRecord(r)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err == errCorrupted {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		valid += int64(headerSize + len(payload))
+	}
+
+	logp.Warn("Removing incomplete records from spool segment %s at offset %d", path, valid)
+	if err := f.Truncate(valid); err != nil {
+		return 0, fmt.Errorf("failed to truncate spool segment %s: %v", path, err)
+	}
+	return valid, f.Sync()
+}
@@ -0,0 +1,575 @@
+// Package spool provides a disk-backed FIFO queue of events. Events are
+// appended to segment files and synced to disk before Append returns, such
+// that they survive restarts of the beat and long output outages. Events are
+// read in order and removed once they have been acknowledged. Events that
+// have been read, but not acknowledged, before a restart are read again.
+package spool
+
+import (
+	"bufio"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+	"github.com/elastic/beats/libbeat/statestore"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	spoolBytes         = expvar.NewInt("libbeat.publisher.spool.bytes")
+	spoolWrittenEvents = expvar.NewInt("libbeat.publisher.spool.written_events")
+	spoolAckedEvents   = expvar.NewInt("libbeat.publisher.spool.acked_events")
+)
+
+var (
+	ErrClosed   = errors.New("spool closed")
+	ErrCanceled = errors.New("spool append canceled")
+	ErrFull     = errors.New("spool full")
+)
+
+// Config configures the spool.
+type Config struct {
+	// Path is the directory of the spool. A relative path is resolved in the
+	// data path.
+	Path string `config:"path"`
+
+	// Size is the maximum number of bytes of events kept in the spool.
+	// Publishers block if the spool is full.
+	Size int64 `config:"size"`
+
+	// SegmentSize is the size at which a new segment file is started.
+	SegmentSize int64 `config:"segment_size"`
+
+	// ReadAhead is the maximum number of events read from the spool, but not
+	// yet acknowledged.
+	ReadAhead int `config:"read_ahead"`
+}
+
+const (
+	defaultPath        = "spool"
+	defaultSize        = 1 << 30
+	defaultSegmentSize = 16 << 20
+	defaultReadAhead   = 4096
+
+	positionFile = "position"
+	positionKey  = "position"
+)
+
+func (c *Config) Validate() error {
+	if c.Size < 0 || c.SegmentSize < 0 || c.ReadAhead < 0 {
+		return errors.New("spool sizes must not be negative")
+	}
+	return nil
+}
+
+// position is the position of an event in the spool.
+type position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// Spool is a disk-backed event queue. Append can be called concurrently,
+// Read must be called by one reader only.
+type Spool struct {
+	config Config
+	dir    string
+	state  *statestore.Store
+
+	writeMutex sync.Mutex // serializes appends
+	file       *os.File   // segment being written
+
+	mutex       sync.Mutex
+	segments    []segment     // segments not yet completely acknowledged, the last one is written
+	size        int64         // bytes of events not yet acknowledged
+	acked       position      // position of the first event not yet acknowledged
+	readSegment uint64        // segment being read
+	pending     []*Batch      // batches read but not yet acknowledged, in read order
+	inflight    int           // events in pending batches
+	written     chan struct{} // closed and replaced when events have been appended
+	freed       chan struct{} // closed and replaced when events have been acknowledged
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// state of the reader
+	read     position
+	readFile *os.File
+	reader   *bufio.Reader
+}
+
+// Batch is a batch of events read from the spool. Batches must be
+// acknowledged by calling ACK once the events have been published.
+type Batch struct {
+	Events []common.MapStr
+
+	end   position // position after the last event
+	bytes int64
+	acked bool
+}
+
+// Open opens the spool in the configured directory. Events not acknowledged
+// before the spool was closed are read again.
+func Open(config Config) (*Spool, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Path == "" {
+		config.Path = defaultPath
+	}
+	if config.Size == 0 {
+		config.Size = defaultSize
+	}
+	if config.SegmentSize == 0 {
+		config.SegmentSize = defaultSegmentSize
+	}
+	if config.ReadAhead == 0 {
+		config.ReadAhead = defaultReadAhead
+	}
+
+	dir := paths.Resolve(paths.Data, config.Path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory %s: %v", dir, err)
+	}
+
+	backend, err := statestore.NewLocalBackend(filepath.Join(dir, positionFile))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		config:  config,
+		dir:     dir,
+		state:   statestore.New(backend),
+		written: make(chan struct{}),
+		freed:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.init(); err != nil {
+		s.state.Close()
+		return nil, err
+	}
+
+	logp.Info("Spool opened at %s with %d bytes of pending events", dir, s.size)
+	return s, nil
+}
+
+func (s *Spool) init() error {
+	var acked position
+	found := false
+	err := s.state.View(func(tx *statestore.Tx) error {
+		var err error
+		found, err = tx.Get(positionKey, &acked)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+
+	// remove segments acknowledged before the last shutdown
+	for found && len(segments) > 0 && segments[0].id < acked.Segment {
+		if err := os.Remove(segmentPath(s.dir, segments[0].id)); err != nil {
+			return err
+		}
+		segments = segments[1:]
+	}
+
+	if len(segments) == 0 {
+		id := acked.Segment
+		if id == 0 {
+			id = 1
+		}
+		segments = []segment{{id: id}}
+	} else {
+		last := &segments[len(segments)-1]
+		last.size, err = repairSegment(segmentPath(s.dir, last.id))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found || acked.Segment < segments[0].id {
+		acked = position{Segment: segments[0].id}
+	}
+	if acked.Offset > segments[0].size {
+		logp.Warn("Spool position %d exceeds the size of segment %d, starting at the end of the segment",
+			acked.Offset, acked.Segment)
+		acked.Offset = segments[0].size
+	}
+
+	s.file, err = openSegment(s.dir, segments[len(segments)-1].id)
+	if err != nil {
+		return err
+	}
+
+	s.segments = segments
+	s.acked = acked
+	s.read = acked
+	s.readSegment = acked.Segment
+	for _, seg := range segments {
+		s.size += seg.size
+	}
+	s.size -= acked.Offset
+	spoolBytes.Set(s.size)
+	return nil
+}
+
+func openSegment(dir string, id uint64) (*os.File, error) {
+	path := segmentPath(dir, id)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool segment %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// Append writes the events to the spool. It blocks while the spool is full,
+// until cancel or the spool is closed.
+func (s *Spool) Append(events []common.MapStr, cancel <-chan struct{}) error {
+	return s.append(events, cancel, true)
+}
+
+// TryAppend writes the events to the spool. It returns ErrFull if the spool
+// is full.
+func (s *Spool) TryAppend(events []common.MapStr) error {
+	return s.append(events, nil, false)
+}
+
+func (s *Spool) append(events []common.MapStr, cancel <-chan struct{}, block bool) error {
+	var buf []byte
+	count := 0
+	for _, event := range events {
+		payload, err := encodeEvent(event)
+		if err != nil {
+			logp.Err("Dropping event that can not be spooled: %v", err)
+			continue
+		}
+		buf = appendRecord(buf, payload)
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	n := int64(len(buf))
+	if err := s.waitSpace(n, cancel, block); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	last := s.segments[len(s.segments)-1]
+	s.mutex.Unlock()
+
+	if last.size > 0 && last.size+n > s.config.SegmentSize {
+		if err := s.roll(last.id + 1); err != nil {
+			return err
+		}
+		last = segment{id: last.id + 1}
+	}
+
+	if _, err := s.file.Write(buf); err != nil {
+		// remove the partially written records
+		s.file.Truncate(last.size)
+		return fmt.Errorf("failed to write to spool: %v", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		s.file.Truncate(last.size)
+		return fmt.Errorf("failed to sync spool: %v", err)
+	}
+
+	s.mutex.Lock()
+	s.segments[len(s.segments)-1].size += n
+	s.size += n
+	spoolBytes.Set(s.size)
+	close(s.written)
+	s.written = make(chan struct{})
+	s.mutex.Unlock()
+
+	spoolWrittenEvents.Add(int64(count))
+	return nil
+}
+
+func (s *Spool) waitSpace(n int64, cancel <-chan struct{}, block bool) error {
+	for {
+		select {
+		case <-s.done:
+			return ErrClosed
+		default:
+		}
+
+		s.mutex.Lock()
+		full := s.size > 0 && s.size+n > s.config.Size
+		freed := s.freed
+		s.mutex.Unlock()
+
+		if !full {
+			return nil
+		}
+		if !block {
+			return ErrFull
+		}
+
+		select {
+		case <-freed:
+		case <-cancel:
+			return ErrCanceled
+		case <-s.done:
+			return ErrClosed
+		}
+	}
+}
+
+// roll starts a new segment. All events of the current segment have already
+// been synced to disk.
+func (s *Spool) roll(id uint64) error {
+	f, err := openSegment(s.dir, id)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = f
+
+	s.mutex.Lock()
+	s.segments = append(s.segments, segment{id: id})
+	s.mutex.Unlock()
+	return nil
+}
+
+// Read returns the next batch of at most max events. It blocks until events
+// are available and the number of events read, but not yet acknowledged, is
+// below the read ahead limit, or until done or the spool is closed.
+func (s *Spool) Read(max int, done <-chan struct{}) (*Batch, error) {
+	for {
+		s.mutex.Lock()
+		avail := s.config.ReadAhead - s.inflight
+		current, hasNext := s.segmentInfo(s.read.Segment)
+		written, freed := s.written, s.freed
+		s.mutex.Unlock()
+
+		var wait <-chan struct{}
+		switch {
+		case avail <= 0:
+			wait = freed
+		case s.read.Offset < current.size:
+			if max > avail {
+				max = avail
+			}
+			batch, err := s.readBatch(max, current.size)
+			if err != nil {
+				return nil, err
+			}
+			if len(batch.Events) == 0 {
+				s.ACK(batch)
+				continue
+			}
+			return batch, nil
+		case hasNext:
+			s.nextSegment()
+			continue
+		default:
+			wait = written
+		}
+
+		select {
+		case <-wait:
+		case <-done:
+			return nil, ErrClosed
+		case <-s.done:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// segmentInfo returns the segment with the given ID and whether newer
+// segments exist. The mutex must be held.
+func (s *Spool) segmentInfo(id uint64) (segment, bool) {
+	for i, seg := range s.segments {
+		if seg.id == id {
+			return seg, i < len(s.segments)-1
+		}
+	}
+	return segment{id: id}, false
+}
+
+func (s *Spool) readBatch(max int, limit int64) (*Batch, error) {
+	if s.reader == nil {
+		if err := s.openReader(); err != nil {
+			return nil, err
+		}
+	}
+
+	batch := &Batch{}
+	start := s.read.Offset
+	for len(batch.Events) < max && s.read.Offset < limit {
+		payload, err := readRecord(s.reader)
+		if err == errCorrupted {
+			logp.Err("Spool segment %d is corrupted at offset %d, skipping %d bytes",
+				s.read.Segment, s.read.Offset, limit-s.read.Offset)
+			s.closeReader()
+			s.read.Offset = limit
+			break
+		}
+		if err != nil {
+			s.closeReader()
+			if len(batch.Events) > 0 {
+				break
+			}
+			return nil, fmt.Errorf("failed to read spool segment %d: %v", s.read.Segment, err)
+		}
+
+		s.read.Offset += int64(headerSize + len(payload))
+		event, err := DecodeEvent(payload)
+		if err != nil {
+			logp.Err("Dropping spooled event that can not be decoded: %v", err)
+			continue
+		}
+		batch.Events = append(batch.Events, event)
+	}
+	batch.end = s.read
+	batch.bytes = s.read.Offset - start
+
+	s.mutex.Lock()
+	s.pending = append(s.pending, batch)
+	s.inflight += len(batch.Events)
+	s.mutex.Unlock()
+	return batch, nil
+}
+
+func (s *Spool) openReader() error {
+	path := segmentPath(s.dir, s.read.Segment)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment %s: %v", path, err)
+	}
+	if _, err := f.Seek(s.read.Offset, os.SEEK_SET); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to seek spool segment %s: %v", path, err)
+	}
+	s.readFile = f
+	s.reader = bufio.NewReader(f)
+	return nil
+}
+
+func (s *Spool) closeReader() {
+	if s.readFile != nil {
+		s.readFile.Close()
+	}
+	s.readFile = nil
+	s.reader = nil
+}
+
+func (s *Spool) nextSegment() {
+	s.closeReader()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, seg := range s.segments {
+		if seg.id > s.read.Segment {
+			s.read = position{Segment: seg.id}
+			break
+		}
+	}
+	s.readSegment = s.read.Segment
+	s.removeAcked()
+}
+
+// ACK acknowledges the events of the batch. Acknowledged events are removed
+// from the spool once all events read before have been acknowledged too.
+func (s *Spool) ACK(b *Batch) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	b.acked = true
+
+	var events int
+	var bytes int64
+	advanced := false
+	for len(s.pending) > 0 && s.pending[0].acked {
+		batch := s.pending[0]
+		s.pending = s.pending[1:]
+		s.acked = batch.end
+		events += len(batch.Events)
+		bytes += batch.bytes
+		advanced = true
+	}
+	if !advanced {
+		return
+	}
+
+	s.inflight -= events
+	s.size -= bytes
+	spoolBytes.Set(s.size)
+	spoolAckedEvents.Add(int64(events))
+
+	// move to the next segment if the current one has been acknowledged
+	// completely
+	for i, seg := range s.segments[:len(s.segments)-1] {
+		if seg.id == s.acked.Segment && s.acked.Offset >= seg.size {
+			s.acked = position{Segment: s.segments[i+1].id}
+		}
+	}
+
+	err := s.state.Update(func(tx *statestore.Tx) error {
+		return tx.Put(positionKey, s.acked)
+	})
+	if err != nil {
+		logp.Err("Failed to store spool position: %v", err)
+	}
+
+	s.removeAcked()
+	close(s.freed)
+	s.freed = make(chan struct{})
+}
+
+// removeAcked removes the segments that have been read and acknowledged
+// completely. The mutex must be held.
+func (s *Spool) removeAcked() {
+	for len(s.segments) > 1 {
+		seg := s.segments[0]
+		if seg.id >= s.acked.Segment || seg.id >= s.readSegment {
+			return
+		}
+
+		path := segmentPath(s.dir, seg.id)
+		if err := os.Remove(path); err != nil {
+			logp.Err("Failed to remove spool segment %s: %v", path, err)
+			return
+		}
+		s.segments = s.segments[1:]
+	}
+}
+
+// Close closes the spool. Blocked Append and Read calls return ErrClosed.
+// Close must not be called before Read has returned.
+func (s *Spool) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	s.closeReader()
+	s.file.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state.Close()
+}
@@ -0,0 +1,202 @@
+// +build !integration
+
+package spool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func openSpool(t *testing.T, config Config) *Spool {
+	s, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func events(from, to int) []common.MapStr {
+	var events []common.MapStr
+	for i := from; i < to; i++ {
+		events = append(events, common.MapStr{"n": i, "@timestamp": common.Time(time.Unix(int64(i), 0).UTC())})
+	}
+	return events
+}
+
+func numbers(t *testing.T, b *Batch) []int {
+	var n []int
+	for _, event := range b.Events {
+		v, err := event["n"].(interface {
+			Int64() (int64, error)
+		}).Int64()
+		assert.NoError(t, err)
+		n = append(n, int(v))
+	}
+	return n
+}
+
+func TestAppendReadACK(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	s := openSpool(t, Config{Path: dir, SegmentSize: 256})
+	defer s.Close()
+
+	assert.NoError(t, s.Append(events(0, 10), nil))
+	assert.NoError(t, s.Append(events(10, 20), nil))
+
+	var read []int
+	var batches []*Batch
+	for len(read) < 20 {
+		b, err := s.Read(7, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, len(b.Events) <= 7)
+		_, isTime := b.Events[0]["@timestamp"].(common.Time)
+		assert.True(t, isTime)
+		read = append(read, numbers(t, b)...)
+		batches = append(batches, b)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19}, read)
+
+	segments, _ := listSegments(dir)
+	assert.True(t, len(segments) > 1)
+
+	// out of order ACKs free events in order only
+	s.ACK(batches[1])
+	assert.Equal(t, position{Segment: segments[0].id}, s.acked)
+
+	for _, b := range batches {
+		s.ACK(b)
+	}
+	assert.Equal(t, int64(0), s.size)
+	remaining, _ := listSegments(dir)
+	assert.Len(t, remaining, 1)
+}
+
+func TestReplayAfterRestart(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	config := Config{Path: dir, SegmentSize: 128}
+	s := openSpool(t, config)
+	assert.NoError(t, s.Append(events(0, 6), nil))
+
+	b, err := s.Read(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, numbers(t, b))
+	s.ACK(b)
+
+	// read, but not acknowledged
+	b, err = s.Read(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, numbers(t, b))
+	assert.NoError(t, s.Close())
+
+	s = openSpool(t, config)
+	defer s.Close()
+
+	var read []int
+	for len(read) < 4 {
+		b, err := s.Read(10, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		read = append(read, numbers(t, b)...)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5}, read)
+}
+
+func TestRepairTornWrite(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	s := openSpool(t, Config{Path: dir})
+	assert.NoError(t, s.Append(events(0, 3), nil))
+	assert.NoError(t, s.Close())
+
+	// simulate a crash while writing a record
+	path := filepath.Join(dir, "1"+segmentExt)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := appendRecord(nil, []byte(`{"n":3}`))
+	f.Write(partial[:len(partial)-2])
+	f.Close()
+
+	s = openSpool(t, Config{Path: dir})
+	defer s.Close()
+	assert.NoError(t, s.Append(events(4, 5), nil))
+
+	var read []int
+	for len(read) < 4 {
+		b, err := s.Read(10, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		read = append(read, numbers(t, b)...)
+	}
+	assert.Equal(t, []int{0, 1, 2, 4}, read)
+}
+
+func TestFull(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	s := openSpool(t, Config{Path: dir, Size: 100})
+	defer s.Close()
+
+	assert.NoError(t, s.TryAppend(events(0, 1)))
+	assert.Equal(t, ErrFull, s.TryAppend(events(1, 10)))
+
+	cancel := make(chan struct{})
+	close(cancel)
+	assert.Equal(t, ErrCanceled, s.Append(events(1, 10), cancel))
+
+	// blocked appends continue once events have been acknowledged
+	appended := make(chan error)
+	go func() {
+		appended <- s.Append(events(1, 3), nil)
+	}()
+
+	b, err := s.Read(10, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, numbers(t, b))
+	s.ACK(b)
+
+	assert.NoError(t, <-appended)
+	b, err = s.Read(10, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, numbers(t, b))
+}
+
+func TestReadClosed(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	s := openSpool(t, Config{Path: dir})
+
+	done := make(chan struct{})
+	close(done)
+	_, err := s.Read(10, done)
+	assert.Equal(t, ErrClosed, err)
+
+	assert.NoError(t, s.Close())
+	assert.Equal(t, ErrClosed, s.Append(events(0, 1), nil))
+}
@@ -0,0 +1,120 @@
+// +build !integration
+
+package publisher
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/publisher/spool"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSpoolPublisher(t *testing.T, dir string, response OutputResponse) *testPublisher {
+	testPub := newTestPublisherNoBulk(response)
+	queue, err := spool.Open(spool.Config{Path: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := testPub.pub
+	pub.spool = newSpoolPipeline(pub, queue, pub.pipelines.async.(*asyncPipeline))
+	pub.pipelines.async = pub.spool
+	pub.pipelines.sync = pub.spool
+	return testPub
+}
+
+func TestSpoolPublishEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testPub := newTestSpoolPublisher(t, dir, CompletedResponse)
+	defer testPub.Stop()
+
+	// Events are acknowledged once spooled.
+	assert.True(t, testPub.syncPublishEvents([]common.MapStr{
+		{"@timestamp": common.Time{}, "n": 1},
+		{"@timestamp": common.Time{}, "n": 2},
+	}))
+
+	msgs, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, msgs[0].context.Guaranteed)
+	assert.Len(t, msgs[0].events, 2)
+	assert.Equal(t, "1", msgs[0].events[0]["n"].(interface {
+		String() string
+	}).String())
+}
+
+func TestSpoolRetryFailedEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testPub := newTestSpoolPublisher(t, dir, FailedResponse)
+	defer testPub.Stop()
+
+	// The failed batch is sent to the outputs again, such that it does not
+	// hold back the acknowledgement of later batches.
+	assert.True(t, testPub.asyncPublishEvent(common.MapStr{"@timestamp": common.Time{}, "n": 1}))
+	msgs, err := testPub.outputMsgHandler.waitForMessages(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, msgs[0].events, msgs[1].events)
+}
+
+// Verify that stopping the pipeline cancels the pending retry of a failed
+// batch, such that it is not sent to the stopped output workers.
+func TestSpoolStopCancelsRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testPub := newTestSpoolPublisher(t, dir, FailedResponse)
+	assert.True(t, testPub.asyncPublishEvent(common.MapStr{"@timestamp": common.Time{}, "n": 1}))
+	if _, err := testPub.outputMsgHandler.waitForMessages(1); err != nil {
+		t.Fatal(err)
+	}
+	testPub.Stop()
+
+	time.Sleep(spoolRetryBackoff + 100*time.Millisecond)
+	assert.Len(t, testPub.outputMsgHandler.msgs, 0)
+}
+
+func TestSpoolReplayUnpublishedEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testPub := newTestSpoolPublisher(t, dir, FailedResponse)
+	assert.True(t, testPub.asyncPublishEvent(common.MapStr{"@timestamp": common.Time{}, "n": 1}))
+	if _, err := testPub.outputMsgHandler.waitForMessages(1); err != nil {
+		t.Fatal(err)
+	}
+	testPub.Stop()
+
+	// The failed event is published again after a restart.
+	testPub = newTestSpoolPublisher(t, dir, CompletedResponse)
+	defer testPub.Stop()
+
+	msgs, err := testPub.outputMsgHandler.waitForMessages(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, msgs[0].events, 1)
+}
@@ -3,11 +3,16 @@ package publisher
 import "github.com/elastic/beats/libbeat/common/op"
 
 type syncPipeline struct {
-	pub *Publisher
+	outputs *outputSet
+	pub     *Publisher
 }
 
 func newSyncPipeline(pub *Publisher, hwm, bulkHWM int) *syncPipeline {
-	return &syncPipeline{pub: pub}
+	p := &syncPipeline{pub: pub}
+	p.outputs = pub.newOutputSet(func(out *outputWorker) worker {
+		return out
+	})
+	return p
 }
 
 func (p *syncPipeline) publish(m message) bool {
@@ -20,20 +25,14 @@ func (p *syncPipeline) publish(m message) bool {
 	client := m.client
 	signal := m.context.Signal
 	sync := op.NewSignalChannel()
-	if len(p.pub.Output) > 1 {
-		m.context.Signal = op.SplitSignaler(sync, len(p.pub.Output))
-	} else {
-		m.context.Signal = sync
-	}
-
-	for _, o := range p.pub.Output {
-		o.send(m)
-	}
+	m.context.Signal = sync
+	p.outputs.send(m)
 
 	// Await completion signal from output plugin. If client has been disconnected
 	// ignore any signal and drop events no matter if send or not.
 	select {
 	case <-client.canceler.Done():
+		op.SignalCanceled.Apply(signal)
 		return true
 	case sig := <-sync.C:
 		sig.Apply(signal)
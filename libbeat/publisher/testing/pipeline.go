@@ -0,0 +1,288 @@
+// Package testing provides helpers to test Beats and processors against the
+// publisher pipeline. Pipeline runs the real publisher with an in-memory output
+// capturing all events, and simulates output acknowledgements. ChanClient
+// replaces the publisher client by a channel.
+package testing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+)
+
+// outputName is the name the capturing output is registered with.
+const outputName = "pubtest"
+
+// ACKMode selects how the capturing output responds to published batches.
+type ACKMode uint8
+
+const (
+	// AutoACK acknowledges all batches once they have been captured.
+	AutoACK ACKMode = iota
+
+	// AutoFail fails all batches once they have been captured. Failed events
+	// are only published again if a retry policy is configured in the output
+	// settings.
+	AutoFail
+
+	// ManualACK keeps batches pending until they are acknowledged or failed
+	// with Batch.ACK or Batch.Fail.
+	ManualACK
+)
+
+// ErrSimulated is the error batches are failed with in the AutoFail mode.
+var ErrSimulated = errors.New("simulated output failure")
+
+var errTimeout = errors.New("timeout waiting for events")
+
+var (
+	outputsMutex sync.Mutex
+	outputsByID  = map[int]*Output{}
+	nextOutputID = 0
+)
+
+func init() {
+	err := outputs.RegisterOutputPlugin(outputName,
+		func(config *common.Config, _ int) (outputs.Outputer, error) {
+			id, err := config.Int("id", -1)
+			if err != nil {
+				return nil, err
+			}
+
+			outputsMutex.Lock()
+			defer outputsMutex.Unlock()
+			out, ok := outputsByID[int(id)]
+			if !ok {
+				return nil, fmt.Errorf("no test output with id %v", id)
+			}
+			return out, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Pipeline is a publisher pipeline publishing all events to an in-memory
+// output. Events pass the same processing as in a Beat, including queues,
+// batching, processors, and the guaranteed and sync publishing semantics,
+// such that Beats and processors can be tested without running any output.
+type Pipeline struct {
+	*publisher.Publisher
+
+	// Output captures the events published by the pipeline.
+	Output *Output
+}
+
+// defaultFlushInterval is the flush interval of the output worker, such that
+// events published async are captured without delaying tests.
+const defaultFlushInterval = 10 * time.Millisecond
+
+// NewPipeline creates a pipeline with the given publisher settings. The
+// pipeline must be stopped with Stop after all clients have been closed.
+func NewPipeline(shipper publisher.ShipperConfig) (*Pipeline, error) {
+	return NewPipelineWith(shipper, nil)
+}
+
+// NewPipelineWith creates a pipeline with the given publisher settings and
+// settings of the capturing output, like bulk_max_size, flush_interval or
+// retry. The flush interval defaults to 10ms.
+func NewPipelineWith(
+	shipper publisher.ShipperConfig,
+	settings map[string]interface{},
+) (*Pipeline, error) {
+	out := newOutput()
+
+	outputsMutex.Lock()
+	id := nextOutputID
+	nextOutputID++
+	outputsByID[id] = out
+	outputsMutex.Unlock()
+
+	defer func() {
+		outputsMutex.Lock()
+		delete(outputsByID, id)
+		outputsMutex.Unlock()
+	}()
+
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"flush_interval": defaultFlushInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil {
+		if err := config.Merge(settings); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.SetInt("id", -1, int64(id)); err != nil {
+		return nil, err
+	}
+
+	pub, err := publisher.New("pubtest", map[string]*common.Config{
+		outputName: config,
+	}, shipper)
+	if err != nil {
+		return nil, err
+	}
+
+	// Processors can be replaced by calling RegisterProcessors.
+	procs, err := processors.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := pub.RegisterProcessors(procs); err != nil {
+		return nil, err
+	}
+	return &Pipeline{Publisher: pub, Output: out}, nil
+}
+
+// Stop stops the pipeline. Batches pending in the ManualACK mode are failed
+// by the publisher.
+func (p *Pipeline) Stop() {
+	p.Publisher.Stop()
+}
+
+// Batch is a batch of events received by the capturing output.
+type Batch struct {
+	Events     []common.MapStr
+	Guaranteed bool
+
+	signal op.Signaler
+	once   sync.Once
+}
+
+// ACK acknowledges the batch to the publisher.
+func (b *Batch) ACK() {
+	b.once.Do(func() { op.SigCompleted(b.signal) })
+}
+
+// Fail reports the batch as failed to the publisher, with err as reason.
+func (b *Batch) Fail(err error) {
+	b.once.Do(func() { op.SigFailed(b.signal, err) })
+}
+
+// Output is an output capturing all events published. Batches are
+// acknowledged according to the ACKMode set.
+type Output struct {
+	mutex   sync.Mutex
+	mode    ACKMode
+	events  []common.MapStr
+	pending []*Batch
+	changed chan struct{}
+}
+
+func newOutput() *Output {
+	return &Output{changed: make(chan struct{})}
+}
+
+// SetACKMode changes how batches received from now on are acknowledged.
+func (o *Output) SetACKMode(mode ACKMode) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.mode = mode
+}
+
+// PublishEvent captures the event.
+func (o *Output) PublishEvent(sig op.Signaler, opts outputs.Options, event common.MapStr) error {
+	return o.BulkPublish(sig, opts, []common.MapStr{event})
+}
+
+// BulkPublish captures the events.
+func (o *Output) BulkPublish(sig op.Signaler, opts outputs.Options, events []common.MapStr) error {
+	batch := &Batch{
+		Events:     events,
+		Guaranteed: opts.Guaranteed,
+		signal:     sig,
+	}
+
+	o.mutex.Lock()
+	o.events = append(o.events, events...)
+	mode := o.mode
+	if mode == ManualACK {
+		o.pending = append(o.pending, batch)
+	}
+	close(o.changed)
+	o.changed = make(chan struct{})
+	o.mutex.Unlock()
+
+	switch mode {
+	case AutoACK:
+		batch.ACK()
+	case AutoFail:
+		batch.Fail(ErrSimulated)
+	}
+	return nil
+}
+
+// Close does nothing, the captured events are kept.
+func (o *Output) Close() error {
+	return nil
+}
+
+// Events returns all events captured so far, including events of failed
+// batches.
+func (o *Output) Events() []common.MapStr {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]common.MapStr(nil), o.events...)
+}
+
+// WaitEvents waits until at least n events have been captured and returns
+// all events captured. An error is returned if the timeout expires first.
+func (o *Output) WaitEvents(n int, timeout time.Duration) ([]common.MapStr, error) {
+	var events []common.MapStr
+	err := o.wait(timeout, func() bool {
+		if len(o.events) < n {
+			return false
+		}
+		events = append([]common.MapStr(nil), o.events...)
+		return true
+	})
+	return events, err
+}
+
+// NextBatch waits for the next batch kept pending in the ManualACK mode. An
+// error is returned if the timeout expires first.
+func (o *Output) NextBatch(timeout time.Duration) (*Batch, error) {
+	var batch *Batch
+	err := o.wait(timeout, func() bool {
+		if len(o.pending) == 0 {
+			return false
+		}
+		batch = o.pending[0]
+		o.pending = o.pending[1:]
+		return true
+	})
+	return batch, err
+}
+
+// wait calls done with the mutex held whenever the output has changed, until
+// done returns true or the timeout expires.
+func (o *Output) wait(timeout time.Duration, done func() bool) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		o.mutex.Lock()
+		if done() {
+			o.mutex.Unlock()
+			return nil
+		}
+		changed := o.changed
+		o.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return errTimeout
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// +build !integration
+
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/processors"
+	"github.com/elastic/beats/libbeat/publisher"
+	"github.com/stretchr/testify/assert"
+
+	_ "github.com/elastic/beats/libbeat/processors/actions"
+)
+
+func newTestPipeline(t *testing.T) *Pipeline {
+	p, err := NewPipeline(publisher.ShipperConfig{Name: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func pipelineEvent(n int) common.MapStr {
+	return common.MapStr{
+		"@timestamp": common.Time(time.Now()),
+		"type":       "test",
+		"n":          n,
+	}
+}
+
+func TestPipelineCapturesEvents(t *testing.T) {
+	p := newTestPipeline(t)
+	defer p.Stop()
+
+	client := p.Connect()
+	defer client.Close()
+
+	assert.True(t, client.PublishEvent(pipelineEvent(1)))
+	assert.True(t, client.PublishEvents([]common.MapStr{pipelineEvent(2), pipelineEvent(3)}))
+
+	events, err := p.Output.WaitEvents(3, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, events, 3)
+	name, _ := events[0].GetValue("beat.name")
+	assert.Equal(t, "test", name)
+}
+
+func TestPipelineProcessors(t *testing.T) {
+	p := newTestPipeline(t)
+	defer p.Stop()
+
+	config, err := common.NewConfigFrom(map[string]interface{}{
+		"when.equals.n": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	procs, err := processors.New(processors.PluginConfig{
+		{"drop_event": *config},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.RegisterProcessors(procs)
+
+	client := p.Connect()
+	defer client.Close()
+
+	client.PublishEvent(pipelineEvent(1), publisher.Sync, publisher.Guaranteed)
+	client.PublishEvent(pipelineEvent(2), publisher.Sync, publisher.Guaranteed)
+
+	events := p.Output.Events()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, 2, events[0]["n"])
+	}
+}
+
+func TestPipelineManualACK(t *testing.T) {
+	p := newTestPipeline(t)
+	defer p.Stop()
+	p.Output.SetACKMode(ManualACK)
+
+	client := p.Connect()
+	defer client.Close()
+
+	published := make(chan bool, 1)
+	go func() {
+		published <- client.PublishEvent(pipelineEvent(1), publisher.Sync, publisher.Guaranteed)
+	}()
+
+	batch, err := p.Output.NextBatch(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, batch.Guaranteed)
+	select {
+	case <-published:
+		t.Fatal("event acknowledged before ACK")
+	default:
+	}
+
+	batch.ACK()
+	assert.True(t, <-published)
+
+	// failed events are reported to sync publishers
+	go func() {
+		published <- client.PublishEvent(pipelineEvent(2), publisher.Sync, publisher.Guaranteed)
+	}()
+	batch, err = p.Output.NextBatch(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch.Fail(ErrSimulated)
+	assert.False(t, <-published)
+}
+
+func TestPipelineRetry(t *testing.T) {
+	p, err := NewPipelineWith(publisher.ShipperConfig{}, map[string]interface{}{
+		"retry.backoff.init": "1ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+	p.Output.SetACKMode(ManualACK)
+
+	client := p.Connect()
+	defer client.Close()
+
+	published := make(chan bool, 1)
+	go func() {
+		published <- client.PublishEvent(pipelineEvent(1), publisher.Sync, publisher.Guaranteed)
+	}()
+
+	// failed guaranteed events are retried by the retry policy
+	batch, err := p.Output.NextBatch(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch.Fail(ErrSimulated)
+
+	batch, err = p.Output.NextBatch(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch.ACK()
+	assert.True(t, <-published)
+	assert.Len(t, p.Output.Events(), 2)
+}
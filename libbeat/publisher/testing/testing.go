@@ -4,6 +4,7 @@ package testing
 import (
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/publisher"
+	"golang.org/x/net/context"
 )
 
 // given channel only.
@@ -57,6 +58,32 @@ func (c *ChanClient) PublishEvents(events []common.MapStr, opts ...publisher.Cli
 	}
 }
 
+// PublishWithCallback publishes the events on the configured channel and
+// calls fn with all events acked once they are in the channel, or with 0 if
+// the client is closed. Options will be ignored.
+func (c *ChanClient) PublishWithCallback(
+	events []common.MapStr,
+	fn func(acked int),
+	opts ...publisher.ClientOption,
+) bool {
+	if !c.PublishEvents(events, opts...) {
+		fn(0)
+		return false
+	}
+	fn(len(events))
+	return true
+}
+
+// Inflight always returns 0, events are not acknowledged by the channel.
+func (c *ChanClient) Inflight() int {
+	return 0
+}
+
+// Wait returns immediately.
+func (c *ChanClient) Wait(ctx context.Context, n int) error {
+	return nil
+}
+
 func (c *ChanClient) ReceiveEvent() common.MapStr {
 	if len(c.recvBuf) > 0 {
 		evt := c.recvBuf[0]
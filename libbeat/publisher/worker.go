@@ -6,6 +6,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/op"
+	"github.com/elastic/beats/libbeat/crash"
 )
 
 // Metrics that can retrieved through the expvar web interface.
@@ -18,10 +19,12 @@ type worker interface {
 }
 
 type messageWorker struct {
-	queue     chan message
-	bulkQueue chan message
-	ws        *workerSignal
-	handler   messageHandler
+	queue           chan message
+	bulkQueue       chan message
+	guaranteedQueue chan message // guaranteed messages if overflow drops messages
+	overflow        overflowPolicy
+	ws              *workerSignal
+	handler         messageHandler
 }
 
 type workerSignal struct {
@@ -34,6 +37,7 @@ type message struct {
 	context Context
 	event   common.MapStr
 	events  []common.MapStr
+	retries int // number of times the message was sent to the output again
 }
 
 type messageHandler interface {
@@ -50,6 +54,7 @@ func newMessageWorker(ws *workerSignal, hwm, bulkHWM int, h messageHandler) *mes
 func (p *messageWorker) init(ws *workerSignal, hwm, bulkHWM int, h messageHandler) {
 	p.queue = make(chan message, hwm)
 	p.bulkQueue = make(chan message, bulkHWM)
+	p.guaranteedQueue = make(chan message, hwm)
 	p.ws = ws
 	p.handler = h
 
@@ -59,6 +64,7 @@ func (p *messageWorker) init(ws *workerSignal, hwm, bulkHWM int, h messageHandle
 
 func (p *messageWorker) run() {
 	defer p.shutdown()
+	defer crash.Recover()
 	for {
 		select {
 		case <-p.ws.done:
@@ -67,6 +73,8 @@ func (p *messageWorker) run() {
 			p.onEvent(m)
 		case m := <-p.bulkQueue:
 			p.onEvent(m)
+		case m := <-p.guaranteedQueue:
+			p.onEvent(m)
 		}
 	}
 }
@@ -75,6 +83,7 @@ func (p *messageWorker) shutdown() {
 	p.handler.onStop()
 	stopQueue(p.queue)
 	stopQueue(p.bulkQueue)
+	stopQueue(p.guaranteedQueue)
 	p.ws.wg.Done()
 }
 
@@ -84,7 +93,7 @@ func (p *messageWorker) onEvent(m message) {
 }
 
 func (p *messageWorker) send(m message) {
-	send(p.queue, p.bulkQueue, m)
+	send(p.queue, p.bulkQueue, p.guaranteedQueue, p.overflow, m)
 }
 
 func (ws *workerSignal) stop() {
@@ -110,14 +119,54 @@ func stopQueue(qu chan message) {
 
 }
 
-func send(qu, bulkQu chan message, m message) {
-	var ch chan message
-	if m.event != nil {
-		ch = qu
-	} else {
-		ch = bulkQu
+// send pushes m into the queue of single events or bulks, applying the
+// overflow policy. If the policy drops messages, guaranteed messages are
+// pushed into guaranteedQu instead, such that they are never evicted from
+// the queues and keep their order.
+func send(qu, bulkQu, guaranteedQu chan message, overflow overflowPolicy, m message) {
+	ch := selectQueue(qu, bulkQu, guaranteedQu, overflow, m)
+	if overflow.trySend(ch, m) {
+		return
+	}
+	sendBlocking(ch, m)
+}
+
+func selectQueue(qu, bulkQu, guaranteedQu chan message, overflow overflowPolicy, m message) chan message {
+	switch {
+	case m.context.Guaranteed && overflow != overflowBlock:
+		return guaranteedQu
+	case m.event != nil:
+		return qu
+	default:
+		return bulkQu
+	}
+}
+
+// sendUntil pushes m into the queues of the worker, waiting until the queue
+// has space. It gives up and returns false once done is closed. The signal of
+// m is failed if the client of the message is closed.
+func (p *messageWorker) sendUntil(m message, done <-chan struct{}) bool {
+	ch := selectQueue(p.queue, p.bulkQueue, p.guaranteedQueue, p.overflow, m)
+
+	var clientDone <-chan struct{}
+	if m.client != nil {
+		clientDone = m.client.canceler.Done()
+	}
+
+	select {
+	case <-done:
+		return false
+	case <-clientDone: // blocks if nil
+		op.SigFailed(m.context.Signal, ErrClientClosed)
+	case ch <- m:
+		messagesInWorkerQueues.Add(1)
 	}
+	return true
+}
 
+// sendBlocking pushes m into ch, waiting until ch has space or the client
+// of the message is closed.
+func sendBlocking(ch chan message, m message) {
 	var done <-chan struct{}
 	if m.client != nil {
 		done = m.client.canceler.Done()
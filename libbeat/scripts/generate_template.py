@@ -7,8 +7,15 @@ the etc/fields.yml file.
 Example usage:
 
    python generate_template.py filebeat/ filebeat
+
+The fields of other Beats can be added with --include, for example to generate
+the template of Agentbeat, which publishes the events of several Beats:
+
+   python generate_template.py --include filebeat/ --include metricbeat/ \
+       agentbeat/ agentbeat
 """
 
+import os
 import yaml
 import json
 import argparse
@@ -65,11 +72,14 @@ def fields_to_es_template(args, input, output, index):
 
     properties = {}
     dynamic_templates = []
+    conflicts = set()
     for section in docs["fields"]:
         prop, dynamic = fill_section_properties(args, section,
                                                 defaults, "")
-        properties.update(prop)
-        dynamic_templates.extend(dynamic)
+        merge_properties(properties, prop, "", conflicts)
+        for dynamic_template in dynamic:
+            if dynamic_template not in dynamic_templates:
+                dynamic_templates.append(dynamic_template)
 
     template["mappings"]["_default_"]["properties"] = properties
     if len(dynamic_templates) > 0:
@@ -81,6 +91,32 @@ def fields_to_es_template(args, input, output, index):
               sort_keys=True)
 
 
+def merge_properties(properties, prop, path, conflicts):
+    """
+    Merges the properties of prop into properties. Sections of different Beats
+    can define the same group, like the mysql group of Metricbeat and
+    Packetbeat, so the properties of groups are merged. Fields defined with
+    different mappings, like the source field of Filebeat and Packetbeat
+    flows, are removed and added to conflicts, such that their mapping is
+    left to the index the events are stored in.
+    """
+    for name, mapping in prop.items():
+        field = path + name
+        existing = properties.get(name)
+        if field in conflicts:
+            continue
+        elif existing is None:
+            properties[name] = mapping
+        elif "properties" in existing and "properties" in mapping:
+            merge_properties(existing["properties"], mapping["properties"],
+                             field + ".", conflicts)
+        elif existing != mapping:
+            print("Conflicting mappings of field {}, removing it".format(
+                field))
+            del properties[name]
+            conflicts.add(field)
+
+
 def dedot(group):
     """
     Walk the tree and transform keys like "beat.name" and "beat.hostname" into
@@ -268,6 +304,9 @@ if __name__ == "__main__":
     parser.add_argument("path", help="Path to the beat folder")
     parser.add_argument("beatname", help="The beat fname")
     parser.add_argument("es_beats", help="The path to the general beats folder")
+    parser.add_argument("--include", action="append", default=[],
+                        help="Path to another beat folder whose fields are " +
+                        "added to the template.")
 
     args = parser.parse_args()
 
@@ -276,12 +315,18 @@ if __name__ == "__main__":
         target += "-es2x"
     target += ".json"
 
-    with open(args.path + "/etc/fields.yml", 'r') as f:
+    # Beat fields from libbeat come first
+    with open(args.es_beats + "/libbeat/_meta/fields.yml") as f:
         fields = f.read()
 
-        # Prepend beat fields from libbeat
-        with open(args.es_beats + "/libbeat/_meta/fields.yml") as f:
-            fields = f.read() + fields
-
-        with open(target, 'w') as output:
-            fields_to_es_template(args, fields, output, args.beatname + "-*")
+    for path in [args.path] + args.include:
+        fields_path = path + "/etc/fields.yml"
+        if path == args.path and not os.path.exists(fields_path):
+            # the beat only publishes the fields of the included beats
+            continue
+        with open(fields_path, 'r') as f:
+            # sections of all files are appended to the fields list
+            fields += "\n" + f.read()
+
+    with open(target, 'w') as output:
+        fields_to_es_template(args, fields, output, args.beatname + "-*")
@@ -0,0 +1,10 @@
+// +build !windows
+
+package statestore
+
+import "os"
+
+// safeFileRotate replaces the file under path with tempfile.
+func safeFileRotate(path, tempfile string) error {
+	return os.Rename(tempfile, path)
+}
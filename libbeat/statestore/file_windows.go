@@ -0,0 +1,23 @@
+package statestore
+
+import (
+	"os"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// safeFileRotate replaces the file under path with tempfile.
+func safeFileRotate(path, tempfile string) error {
+	old := path + ".old"
+
+	// In Windows, one cannot rename a file if the destination already exists.
+	// Move the existing file into an old file first and only do the move
+	// after that.
+	if err := os.Remove(old); err != nil {
+		logp.Debug("statestore", "delete old: %v", err)
+	}
+	if err := os.Rename(path, old); err != nil {
+		logp.Debug("statestore", "rotate to old: %v", err)
+	}
+	return os.Rename(tempfile, path)
+}
@@ -0,0 +1,220 @@
+package statestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var compactions = expvar.NewInt("libbeat.statestore.compactions")
+
+// defaultCompactAfter is the number of transactions after which the log of a
+// local backend is compacted.
+const defaultCompactAfter = 1000
+
+// localBackend persists the key/value pairs in a local file. Every committed
+// transaction is appended to the file as one record and synced to disk before
+// the commit returns. A record that was not written completely, for example
+// on a crash, is discarded when the file is loaded, such that a transaction is
+// either persisted completely or not at all. Once the file contains
+// compactAfter records, it is replaced by a single record with all key/value
+// pairs.
+type localBackend struct {
+	*memoryBackend
+
+	path         string
+	file         *os.File
+	records      int
+	compactAfter int
+	dirty        bool // last append failed, file must be rewritten
+}
+
+// record is one transaction in the file of a local backend.
+type record struct {
+	Put    map[string]json.RawMessage `json:"put,omitempty"`
+	Delete []string                   `json:"delete,omitempty"`
+}
+
+// NewLocalBackend opens the backend persisted in the file under path. The
+// file and its directory are created if they do not exist.
+func NewLocalBackend(path string) (Backend, error) {
+	return newLocalBackend(path, defaultCompactAfter)
+}
+
+func newLocalBackend(path string, compactAfter int) (*localBackend, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create state store dir %s: %v", dir, err)
+	}
+
+	b := &localBackend{
+		memoryBackend: newMemoryBackend(),
+		path:          path,
+		compactAfter:  compactAfter,
+	}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	// Start with a compacted file, which also removes an incomplete record
+	// at the end of the file.
+	if err := b.compact(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *localBackend) load() error {
+	f, err := os.Open(b.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				logp.Warn("Discarding incomplete transaction at the end of state store %s", b.path)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return fmt.Errorf("state store %s is corrupted in line %d: %v", b.path, n, err)
+		}
+		b.apply(r.changes())
+	}
+}
+
+func (b *localBackend) Commit(changes map[string]json.RawMessage) error {
+	if b.file == nil {
+		return ErrClosed
+	}
+
+	if b.dirty {
+		if err := b.compact(); err != nil {
+			return err
+		}
+	}
+
+	line, err := json.Marshal(newRecord(changes))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := b.file.Write(line); err != nil {
+		b.dirty = true
+		return err
+	}
+	if err := b.file.Sync(); err != nil {
+		b.dirty = true
+		return err
+	}
+
+	b.apply(changes)
+	b.records++
+	if b.records >= b.compactAfter {
+		if err := b.compact(); err != nil {
+			logp.Err("Failed to compact state store %s: %v", b.path, err)
+		}
+	}
+	return nil
+}
+
+// compact atomically replaces the file with a single record holding all
+// key/value pairs.
+func (b *localBackend) compact() error {
+	b.mutex.RLock()
+	snapshot := record{Put: b.values}
+	line, err := json.Marshal(snapshot)
+	b.mutex.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tempfile := b.path + ".new"
+	f, err := os.OpenFile(tempfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	// Directly close file because of windows
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if b.file != nil {
+		b.file.Close()
+		b.file = nil
+	}
+	if err := safeFileRotate(b.path, tempfile); err != nil {
+		return err
+	}
+
+	b.file, err = os.OpenFile(b.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	b.records = 1
+	b.dirty = false
+	compactions.Add(1)
+	return nil
+}
+
+func (b *localBackend) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+func newRecord(changes map[string]json.RawMessage) record {
+	var r record
+	for key, raw := range changes {
+		if raw == nil {
+			r.Delete = append(r.Delete, key)
+			continue
+		}
+		if r.Put == nil {
+			r.Put = map[string]json.RawMessage{}
+		}
+		r.Put[key] = raw
+	}
+	return r
+}
+
+func (r record) changes() map[string]json.RawMessage {
+	changes := make(map[string]json.RawMessage, len(r.Put)+len(r.Delete))
+	for key, raw := range r.Put {
+		changes[key] = raw
+	}
+	for _, key := range r.Delete {
+		changes[key] = nil
+	}
+	return changes
+}
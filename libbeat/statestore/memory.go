@@ -0,0 +1,63 @@
+package statestore
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// memoryBackend keeps the key/value pairs in memory only. Its state is lost on
+// restart.
+type memoryBackend struct {
+	mutex  sync.RWMutex
+	values map[string]json.RawMessage
+}
+
+// NewMemoryBackend creates a backend that does not persist its state.
+func NewMemoryBackend() Backend {
+	return newMemoryBackend()
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{values: map[string]json.RawMessage{}}
+}
+
+func (b *memoryBackend) Get(key string) (json.RawMessage, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	raw, found := b.values[key]
+	return raw, found
+}
+
+func (b *memoryBackend) Keys() []string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	keys := make([]string, 0, len(b.values))
+	for key := range b.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (b *memoryBackend) Commit(changes map[string]json.RawMessage) error {
+	b.apply(changes)
+	return nil
+}
+
+func (b *memoryBackend) apply(changes map[string]json.RawMessage) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for key, raw := range changes {
+		if raw == nil {
+			delete(b.values, key)
+		} else {
+			b.values[key] = raw
+		}
+	}
+}
+
+func (b *memoryBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package statestore
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/paths"
+)
+
+// Backend types
+const (
+	LocalType  = "local"
+	MemoryType = "memory"
+)
+
+// Config configures the backend of the stores of a Registry.
+type Config struct {
+	// Type is the backend type of the stores, local or memory. The default is
+	// local.
+	Type string `config:"type"`
+
+	// Path is the directory of the local stores. A relative path is resolved
+	// in the data path. The default is the state directory.
+	Path string `config:"path"`
+}
+
+const defaultPath = "state"
+
+func (c *Config) Validate() error {
+	switch c.Type {
+	case "", LocalType, MemoryType:
+		return nil
+	default:
+		return fmt.Errorf("invalid state store type '%v'", c.Type)
+	}
+}
+
+// Registry hands out named stores, which are shared by all users of the same
+// name. Stores are opened on first use and closed when the registry is
+// closed.
+type Registry struct {
+	mutex  sync.Mutex
+	config Config
+	stores map[string]*Store
+}
+
+// NewRegistry creates a registry opening stores with the configured backend.
+func NewRegistry(config Config) (*Registry, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Type == "" {
+		config.Type = LocalType
+	}
+	if config.Path == "" {
+		config.Path = defaultPath
+	}
+
+	return &Registry{
+		config: config,
+		stores: map[string]*Store{},
+	}, nil
+}
+
+// Get returns the store with the given name. The store must not be closed by
+// the caller.
+func (r *Registry) Get(name string) (*Store, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid state store name '%v'", name)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if store, exists := r.stores[name]; exists {
+		return store, nil
+	}
+
+	var backend Backend
+	switch r.config.Type {
+	case MemoryType:
+		backend = NewMemoryBackend()
+	default:
+		path := filepath.Join(paths.Resolve(paths.Data, r.config.Path), name)
+		logp.Info("Opening state store %s", path)
+
+		var err error
+		backend, err = NewLocalBackend(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store := New(backend)
+	r.stores[name] = store
+	return store, nil
+}
+
+// Close closes all stores.
+func (r *Registry) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var errs []string
+	for name, store := range r.stores {
+		if err := store.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%v: %v", name, err))
+		}
+	}
+	r.stores = map[string]*Store{}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("error closing state stores: %v", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package statestore provides durable key/value stores for the state of inputs
+// and modules, for example the read positions of files or partitions. Changes
+// are written in transactions, which are persisted atomically by the backend
+// of the store.
+package statestore
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metrics that can retrieved through the expvar web interface.
+var (
+	commits      = expvar.NewInt("libbeat.statestore.commits")
+	commitErrors = expvar.NewInt("libbeat.statestore.commit_errors")
+)
+
+var (
+	// ErrTxReadOnly indicates a write in a read-only transaction.
+	ErrTxReadOnly = errors.New("transaction is read-only")
+
+	// ErrClosed indicates a transaction on a closed store.
+	ErrClosed = errors.New("store is closed")
+)
+
+// Backend stores the key/value pairs of a Store. Values are JSON encoded.
+type Backend interface {
+	// Get returns the value of key.
+	Get(key string) (json.RawMessage, bool)
+
+	// Keys returns all keys.
+	Keys() []string
+
+	// Commit applies the changes of a transaction atomically. A nil value
+	// deletes the key.
+	Commit(changes map[string]json.RawMessage) error
+
+	Close() error
+}
+
+// Store is a key/value store of JSON encodable values. All access is done in
+// transactions. Transactions run serially with respect to writes, while
+// read-only transactions can run concurrently.
+type Store struct {
+	mutex   sync.RWMutex
+	backend Backend
+	closed  bool
+}
+
+// Tx is a transaction of a Store. The changes of a transaction are buffered
+// and committed at once when the transaction function returns without error.
+type Tx struct {
+	backend  Backend
+	writable bool
+	changes  map[string]json.RawMessage
+}
+
+// New creates a store on top of backend.
+func New(backend Backend) *Store {
+	return &Store{backend: backend}
+}
+
+// View runs fn in a read-only transaction.
+func (s *Store) View(fn func(tx *Tx) error) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return fn(&Tx{backend: s.backend})
+}
+
+// Update runs fn in a read-write transaction. If fn returns nil, all changes
+// of the transaction are committed atomically. If fn returns an error, the
+// changes are discarded and the error is returned.
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	tx := &Tx{
+		backend:  s.backend,
+		writable: true,
+		changes:  map[string]json.RawMessage{},
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	if err := s.backend.Commit(tx.changes); err != nil {
+		commitErrors.Add(1)
+		return err
+	}
+	commits.Add(1)
+	return nil
+}
+
+// Close closes the backend of the store.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.backend.Close()
+}
+
+// Get decodes the value of key into value. It returns false if the key does
+// not exist.
+func (tx *Tx) Get(key string, value interface{}) (bool, error) {
+	raw, found := tx.get(key)
+	if !found {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, value)
+}
+
+// Has returns true if the key exists.
+func (tx *Tx) Has(key string) bool {
+	_, found := tx.get(key)
+	return found
+}
+
+func (tx *Tx) get(key string) (json.RawMessage, bool) {
+	if raw, changed := tx.changes[key]; changed {
+		return raw, raw != nil
+	}
+	return tx.backend.Get(key)
+}
+
+// Put sets the value of key. The value is encoded when Put is called.
+func (tx *Tx) Put(key string, value interface{}) error {
+	if !tx.writable {
+		return ErrTxReadOnly
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	tx.changes[key] = raw
+	return nil
+}
+
+// Delete removes key.
+func (tx *Tx) Delete(key string) error {
+	if !tx.writable {
+		return ErrTxReadOnly
+	}
+	tx.changes[key] = nil
+	return nil
+}
+
+// Keys returns the sorted keys starting with prefix.
+func (tx *Tx) Keys(prefix string) []string {
+	keys := map[string]bool{}
+	for _, key := range tx.backend.Keys() {
+		keys[key] = true
+	}
+	for key, raw := range tx.changes {
+		keys[key] = raw != nil
+	}
+
+	var matches []string
+	for key, exists := range keys {
+		if exists && strings.HasPrefix(key, prefix) {
+			matches = append(matches, key)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,212 @@
+// +build !integration
+
+package statestore
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cursor struct {
+	Offset int64 `json:"offset"`
+}
+
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "statestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func getOffset(t *testing.T, s *Store, key string) int64 {
+	var c cursor
+	found := false
+	err := s.View(func(tx *Tx) error {
+		var err error
+		found, err = tx.Get(key, &c)
+		return err
+	})
+	assert.NoError(t, err)
+	if !found {
+		return -1
+	}
+	return c.Offset
+}
+
+func testTransactions(t *testing.T, s *Store) {
+	err := s.Update(func(tx *Tx) error {
+		if err := tx.Put("file::a", cursor{10}); err != nil {
+			return err
+		}
+		if err := tx.Put("file::b", cursor{20}); err != nil {
+			return err
+		}
+		// reads see the changes of the transaction
+		assert.True(t, tx.Has("file::a"))
+		assert.Equal(t, []string{"file::a", "file::b"}, tx.Keys("file::"))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), getOffset(t, s, "file::a"))
+	assert.Equal(t, int64(20), getOffset(t, s, "file::b"))
+
+	// failed transactions are discarded
+	errFail := errors.New("fail")
+	err = s.Update(func(tx *Tx) error {
+		tx.Put("file::a", cursor{30})
+		tx.Delete("file::b")
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+	assert.Equal(t, int64(10), getOffset(t, s, "file::a"))
+	assert.Equal(t, int64(20), getOffset(t, s, "file::b"))
+
+	err = s.Update(func(tx *Tx) error {
+		tx.Put("file::a", cursor{30})
+		tx.Delete("file::b")
+		assert.Equal(t, []string{"file::a"}, tx.Keys(""))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(30), getOffset(t, s, "file::a"))
+	assert.Equal(t, int64(-1), getOffset(t, s, "file::b"))
+
+	err = s.View(func(tx *Tx) error {
+		return tx.Put("file::a", cursor{40})
+	})
+	assert.Equal(t, ErrTxReadOnly, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := New(NewMemoryBackend())
+	testTransactions(t, s)
+
+	assert.NoError(t, s.Close())
+	assert.Equal(t, ErrClosed, s.View(func(*Tx) error { return nil }))
+}
+
+func TestLocalStore(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "state", "test")
+
+	backend, err := NewLocalBackend(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s := New(backend)
+	testTransactions(t, s)
+	assert.NoError(t, s.Close())
+
+	backend, err = NewLocalBackend(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s = New(backend)
+	defer s.Close()
+	assert.Equal(t, int64(30), getOffset(t, s, "file::a"))
+	assert.Equal(t, int64(-1), getOffset(t, s, "file::b"))
+}
+
+func TestLocalStoreIncompleteTransaction(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "test")
+
+	backend, err := newLocalBackend(path, 100)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s := New(backend)
+	assert.NoError(t, s.Update(func(tx *Tx) error { return tx.Put("a", cursor{1}) }))
+	assert.NoError(t, s.Update(func(tx *Tx) error { return tx.Put("a", cursor{2}) }))
+	s.Close()
+
+	// simulate a crash while writing a transaction
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if !assert.NoError(t, err) {
+		return
+	}
+	f.WriteString(`{"put":{"a":{"offs`)
+	f.Close()
+
+	backend, err = newLocalBackend(path, 100)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s = New(backend)
+	defer s.Close()
+	assert.Equal(t, int64(2), getOffset(t, s, "a"))
+
+	// the incomplete transaction has been removed from the file
+	assert.NoError(t, s.Update(func(tx *Tx) error { return tx.Put("a", cursor{3}) }))
+	s.Close()
+	backend, err = newLocalBackend(path, 100)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), getOffset(t, New(backend), "a"))
+		backend.Close()
+	}
+}
+
+func TestLocalStoreCompaction(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "test")
+
+	backend, err := newLocalBackend(path, 5)
+	if !assert.NoError(t, err) {
+		return
+	}
+	s := New(backend)
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Update(func(tx *Tx) error {
+			return tx.Put("a", cursor{int64(i)})
+		}))
+	}
+	assert.Equal(t, 3, backend.records)
+
+	content, err := ioutil.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"put":{"a":{"offset":7}}}
+{"put":{"a":{"offset":8}}}
+{"put":{"a":{"offset":9}}}
+`, string(content))
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	r, err := NewRegistry(Config{Type: LocalType, Path: dir})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	s, err := r.Get("inputs")
+	if !assert.NoError(t, err) {
+		return
+	}
+	shared, err := r.Get("inputs")
+	assert.NoError(t, err)
+	assert.True(t, s == shared)
+
+	_, err = r.Get("../inputs")
+	assert.Error(t, err)
+
+	assert.NoError(t, s.Update(func(tx *Tx) error { return tx.Put("a", cursor{1}) }))
+	assert.NoError(t, r.Close())
+	_, err = os.Stat(filepath.Join(dir, "inputs"))
+	assert.NoError(t, err)
+
+	_, err = NewRegistry(Config{Type: "bolt"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const defaultTimeout = 5 * time.Minute
+
+// maxSignatureSize limits the size of downloaded signatures.
+const maxSignatureSize = 64 * 1024
+
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func get(client *http.Client, url string) (*http.Response, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %v: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %v: %v", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// download writes the content of url to w and returns its SHA-256 digest.
+func download(client *http.Client, url string, w io.Writer) ([]byte, error) {
+	resp, err := get(client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to download %v: %v", url, err)
+	}
+	return hash.Sum(nil), nil
+}
+
+func downloadBytes(client *http.Client, url string) ([]byte, error) {
+	resp, err := get(client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
+
+// extractBinary writes the file name found in the top level directory of the
+// artifact to target. The format of the artifact is derived from its url. The
+// binaries of zip artifacts have the .exe extension, which name may already
+// have when taken from the running binary on Windows.
+func extractBinary(artifact, url, name, target string) error {
+	var err error
+	if strings.HasSuffix(url, ".zip") {
+		err = extractZip(artifact, strings.TrimSuffix(name, ".exe")+".exe", target)
+	} else {
+		err = extractTarGz(artifact, name, target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract %v from %v: %v", name, url, err)
+	}
+	return nil
+}
+
+// isBinary reports if the archive entry is the binary, either in the top
+// level directory of the archive or at its root.
+func isBinary(entry, name string) bool {
+	entry = strings.TrimPrefix(entry, "./")
+	dir, file := path.Split(entry)
+	return file == name && strings.Count(dir, "/") <= 1
+}
+
+func extractTarGz(artifact, name, target string) error {
+	f, err := os.Open(artifact)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	r := tar.NewReader(gz)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return fmt.Errorf("binary not found in artifact")
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg && isBinary(hdr.Name, name) {
+			return writeFile(target, r)
+		}
+	}
+}
+
+func extractZip(artifact, name, target string) error {
+	r, err := zip.OpenReader(artifact)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if !isBinary(f.Name, name) || f.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return writeFile(target, rc)
+	}
+	return fmt.Errorf("binary not found in artifact")
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0700)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
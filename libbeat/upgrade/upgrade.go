@@ -0,0 +1,208 @@
+// Package upgrade replaces the binary of a Beat with the binary of another
+// release, for hosts where the Beat is not installed by a package manager.
+//
+// The release artifact is downloaded together with its detached signature.
+// The signature is verified with the configured public key before the
+// binary is extracted from the artifact and swapped with the running binary.
+// The previous binary is kept with the .old suffix, such that an upgrade can
+// be rolled back manually.
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// DefaultURL is the URL template of the release artifacts. The official
+// release artifacts are published with GPG signatures (.asc) and checksums
+// only, not with the signatures verified by the upgrade, so the artifacts
+// must be served with their signatures from a self-hosted mirror, configured
+// by the URL of the Config.
+const DefaultURL = "https://artifacts.elastic.co/downloads/beats/{beat}/{beat}-{version}-{os}-{arch}.{ext}"
+
+// SignatureSuffix is appended to the artifact URL to download the detached
+// signature of the artifact.
+const SignatureSuffix = ".sig"
+
+// Config configures an upgrade.
+type Config struct {
+	Beat    string // name of the Beat, e.g. filebeat
+	Version string // version to upgrade to
+
+	// URL is the URL template of the artifact. The placeholders {beat},
+	// {version}, {os}, {arch} and {ext} are replaced.
+	URL string
+
+	// PublicKey is the path of the PEM encoded public key (RSA or ECDSA) the
+	// artifact signature is verified with.
+	PublicKey string
+
+	// Binary is the path of the binary to replace, the running binary if
+	// empty.
+	Binary string
+
+	// Restart is the command restarting the service after the binary has
+	// been replaced. The service is not restarted if empty.
+	Restart []string
+
+	Timeout time.Duration // timeout of the downloads
+}
+
+// Run downloads the artifact of the configured version, verifies its
+// signature, replaces the binary and restarts the service.
+func Run(config Config) error {
+	if config.Version == "" {
+		return errors.New("no version given")
+	}
+	if config.PublicKey == "" {
+		return errors.New("no public key to verify the artifact signature given")
+	}
+
+	key, err := loadPublicKey(config.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	binary := config.Binary
+	if binary == "" {
+		if binary, err = os.Executable(); err != nil {
+			return fmt.Errorf("failed to locate the binary: %v", err)
+		}
+	}
+	if binary, err = filepath.EvalSymlinks(binary); err != nil {
+		return fmt.Errorf("failed to locate the binary: %v", err)
+	}
+
+	url := ArtifactURL(config.URL, config.Beat, config.Version)
+	logp.Info("Downloading %v", url)
+
+	// The artifact is downloaded next to the binary, such that the new binary
+	// can be renamed in place.
+	dir := filepath.Dir(binary)
+	artifact, err := ioutil.TempFile(dir, ".upgrade-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(artifact.Name())
+	defer artifact.Close()
+
+	client := newHTTPClient(config.Timeout)
+	digest, err := download(client, url, artifact)
+	if err != nil {
+		return err
+	}
+
+	signature, err := downloadBytes(client, url+SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("%v (the artifacts must be served with their %v "+
+			"signatures, for example from a self-hosted mirror)", err, SignatureSuffix)
+	}
+	if err := verify(key, digest, signature); err != nil {
+		return fmt.Errorf("artifact %v: %v", url, err)
+	}
+	logp.Info("Signature of %v verified", url)
+
+	name := filepath.Base(binary)
+	newBinary := binary + ".new"
+	if err := extractBinary(artifact.Name(), url, name, newBinary); err != nil {
+		os.Remove(newBinary)
+		return err
+	}
+
+	if err := swap(binary, newBinary); err != nil {
+		os.Remove(newBinary)
+		return err
+	}
+	logp.Info("Replaced %v with version %v, previous binary kept as %v.old",
+		binary, config.Version, binary)
+
+	if len(config.Restart) == 0 {
+		return nil
+	}
+	logp.Info("Restarting service: %v", strings.Join(config.Restart, " "))
+	out, err := exec.Command(config.Restart[0], config.Restart[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to restart the service: %v: %s", err, out)
+	}
+	return nil
+}
+
+// ArtifactURL returns the URL of the artifact of the given Beat and version
+// for the platform the Beat runs on.
+func ArtifactURL(template, beat, version string) string {
+	if template == "" {
+		template = DefaultURL
+	}
+
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+
+	return strings.NewReplacer(
+		"{beat}", beat,
+		"{version}", version,
+		"{os}", runtime.GOOS,
+		"{arch}", artifactArch(runtime.GOARCH),
+		"{ext}", ext,
+	).Replace(template)
+}
+
+// artifactArch returns the name of the architecture used in artifact names.
+func artifactArch(arch string) string {
+	switch arch {
+	case "amd64":
+		return "x86_64"
+	case "386":
+		return "x86"
+	}
+	return arch
+}
+
+// RestartCommand returns the command restarting the service of the Beat on
+// this platform, or nil if unknown.
+func RestartCommand(beat string) []string {
+	switch runtime.GOOS {
+	case "linux":
+		if _, err := os.Stat("/run/systemd/system"); err == nil {
+			return []string{"systemctl", "restart", beat}
+		}
+		return []string{"service", beat, "restart"}
+	case "windows":
+		return []string{"powershell", "-Command", "Restart-Service", beat}
+	}
+	return nil
+}
+
+// swap replaces binary with newBinary. The previous binary is kept with the
+// .old suffix.
+func swap(binary, newBinary string) error {
+	info, err := os.Stat(binary)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(newBinary, info.Mode()); err != nil {
+		return err
+	}
+
+	old := binary + ".old"
+	os.Remove(old)
+	if err := os.Rename(binary, old); err != nil {
+		return fmt.Errorf("failed to move the binary: %v", err)
+	}
+	if err := os.Rename(newBinary, binary); err != nil {
+		// restore the previous binary
+		os.Rename(old, binary)
+		return fmt.Errorf("failed to replace the binary: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+// +build !integration
+
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestKey(t *testing.T, dir string) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, content []byte) []byte {
+	digest := sha256.Sum256(content)
+	sig, err := key.Sign(rand.Reader, digest[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func newTestArtifact(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArtifactURL(t *testing.T) {
+	url := ArtifactURL("http://localhost/{beat}/{beat}-{version}-{os}-{arch}.{ext}",
+		"filebeat", "5.1.0")
+
+	ext := "tar.gz"
+	if runtime.GOOS == "windows" {
+		ext = "zip"
+	}
+	expected := "http://localhost/filebeat/filebeat-5.1.0-" + runtime.GOOS + "-" +
+		artifactArch(runtime.GOARCH) + "." + ext
+	assert.Equal(t, expected, url)
+
+	assert.Equal(t, "x86_64", artifactArch("amd64"))
+	assert.Equal(t, "x86", artifactArch("386"))
+	assert.Equal(t, "arm", artifactArch("arm"))
+}
+
+func TestVerify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	privateKey, path := newTestKey(t, dir)
+	key, err := loadPublicKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("artifact")
+	digest := sha256.Sum256(content)
+	sig := sign(t, privateKey, content)
+
+	assert.NoError(t, verify(key, digest[:], sig))
+	assert.NoError(t, verify(key, digest[:],
+		[]byte(base64.StdEncoding.EncodeToString(sig)+"\n")))
+
+	other := sha256.Sum256([]byte("modified"))
+	assert.Equal(t, errInvalidSignature, verify(key, other[:], sig))
+	assert.Equal(t, errInvalidSignature, verify(key, digest[:], []byte("invalid")))
+}
+
+func TestLoadPublicKeyInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("no key")
+	f.Close()
+
+	_, err = loadPublicKey(f.Name())
+	assert.Error(t, err)
+}
+
+func TestExtractTarGz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifact := filepath.Join(dir, "artifact.tar.gz")
+	content := newTestArtifact(t, map[string]string{
+		"mybeat-5.1.0/mybeat.yml":         "config",
+		"mybeat-5.1.0/scripts/lib/mybeat": "other",
+		"mybeat-5.1.0/mybeat":             "binary",
+	})
+	if err := ioutil.WriteFile(artifact, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(dir, "mybeat.new")
+	err = extractBinary(artifact, "http://localhost/mybeat.tar.gz", "mybeat", target)
+	if assert.NoError(t, err) {
+		data, _ := ioutil.ReadFile(target)
+		assert.Equal(t, "binary", string(data))
+	}
+
+	err = extractBinary(artifact, "http://localhost/mybeat.tar.gz", "otherbeat", target)
+	assert.Error(t, err)
+}
+
+func TestExtractZip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	files := map[string]string{
+		"mybeat-5.1.0-windows-x86_64/mybeat.yml": "config",
+		"mybeat-5.1.0-windows-x86_64/mybeat.exe": "binary",
+	}
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	artifact := filepath.Join(dir, "artifact.zip")
+	if err := ioutil.WriteFile(artifact, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the name of the running binary has the .exe extension on Windows
+	for _, name := range []string{"mybeat.exe", "mybeat"} {
+		target := filepath.Join(dir, "mybeat.new")
+		err = extractBinary(artifact, "http://localhost/mybeat.zip", name, target)
+		if assert.NoError(t, err, name) {
+			data, _ := ioutil.ReadFile(target)
+			assert.Equal(t, "binary", string(data))
+		}
+		os.Remove(target)
+	}
+
+	err = extractBinary(artifact, "http://localhost/mybeat.zip", "otherbeat.exe",
+		filepath.Join(dir, "mybeat.new"))
+	assert.Error(t, err)
+}
+
+func TestSwap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := filepath.Join(dir, "mybeat")
+	ioutil.WriteFile(binary, []byte("old"), 0755)
+	ioutil.WriteFile(binary+".new", []byte("new"), 0600)
+
+	if assert.NoError(t, swap(binary, binary+".new")) {
+		data, _ := ioutil.ReadFile(binary)
+		assert.Equal(t, "new", string(data))
+		data, _ = ioutil.ReadFile(binary + ".old")
+		assert.Equal(t, "old", string(data))
+
+		info, _ := os.Stat(binary)
+		assert.Equal(t, os.FileMode(0755), info.Mode())
+	}
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("artifacts are zip files on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	privateKey, keyPath := newTestKey(t, dir)
+	artifact := newTestArtifact(t, map[string]string{
+		"mybeat-5.1.0/mybeat": "new binary",
+	})
+	signature := sign(t, privateKey, artifact)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch filepath.Ext(r.URL.Path) {
+		case ".gz":
+			w.Write(artifact)
+		case ".sig":
+			w.Write(signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	binary := filepath.Join(dir, "mybeat")
+	ioutil.WriteFile(binary, []byte("old binary"), 0755)
+
+	config := Config{
+		Beat:      "mybeat",
+		Version:   "5.1.0",
+		URL:       server.URL + "/{beat}-{version}.{ext}",
+		PublicKey: keyPath,
+		Binary:    binary,
+	}
+	if assert.NoError(t, Run(config)) {
+		data, _ := ioutil.ReadFile(binary)
+		assert.Equal(t, "new binary", string(data))
+	}
+
+	// artifacts with invalid signatures are rejected
+	signature = sign(t, privateKey, []byte("other"))
+	ioutil.WriteFile(binary, []byte("old binary"), 0755)
+	assert.Error(t, Run(config))
+	data, _ := ioutil.ReadFile(binary)
+	assert.Equal(t, "old binary", string(data))
+
+	// unknown versions fail
+	config.URL = server.URL + "/unknown"
+	assert.Error(t, Run(config))
+}
@@ -0,0 +1,71 @@
+package upgrade
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+)
+
+var errInvalidSignature = errors.New("invalid signature")
+
+// loadPublicKey reads a PEM encoded RSA or ECDSA public key.
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded public key found in %v", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %v: %v", path, err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T in %v", key, path)
+}
+
+// verify checks the signature of the SHA-256 digest of the artifact. The
+// signature is either raw, as created by `openssl dgst -sha256 -sign`, or
+// base64 encoded.
+func verify(key crypto.PublicKey, digest, signature []byte) error {
+	if verifyRaw(key, digest, signature) {
+		return nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err == nil && verifyRaw(key, digest, decoded) {
+		return nil
+	}
+	return errInvalidSignature
+}
+
+func verifyRaw(key crypto.PublicKey, digest, signature []byte) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) > 0 {
+			return false
+		}
+		return ecdsa.Verify(k, digest, sig.R, sig.S)
+	}
+	return false
+}
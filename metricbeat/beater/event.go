@@ -59,6 +59,10 @@ func (b eventBuilder) build() (common.MapStr, error) {
 			"name":   b.metricSetName,
 			"rtt":    b.fetchDuration.Nanoseconds() / int64(time.Microsecond),
 		},
+		common.MetadataKey: common.MapStr{
+			common.MetadataInputKey:  b.metricSetName,
+			common.MetadataModuleKey: b.moduleName,
+		},
 	}
 
 	// Overwrite default index if set.
@@ -74,6 +78,7 @@ func (b eventBuilder) build() (common.MapStr, error) {
 		// TODO (akroh): allow metricset to specify this value so that
 		// a proper URL can be specified and passwords be redacted.
 		event["metricset"].(common.MapStr)["host"] = b.host
+		common.SetMetadata(event, common.MetadataHostKey, b.host)
 	}
 
 	// Adds error to event in case error happened
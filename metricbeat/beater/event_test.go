@@ -55,6 +55,12 @@ func TestEventBuilder(t *testing.T) {
 	assert.Equal(t, host, metricset["host"])
 	assert.Equal(t, common.MapStr{}, event[moduleName].(common.MapStr)[metricSetName])
 	assert.Nil(t, event["error"])
+
+	assert.Equal(t, common.MapStr{
+		"input":  metricSetName,
+		"module": moduleName,
+		"host":   host,
+	}, event[common.MetadataKey])
 }
 
 func TestEventBuilderError(t *testing.T) {
@@ -61,11 +61,16 @@ func ExampleModuleWrapper() {
 
 	// Output:
 	// {
+	//   "@metadata": {
+	//     "input": "status",
+	//     "module": "fake"
+	//   },
 	//   "@timestamp": "2016-05-10T23:27:58.485Z",
 	//   "_event_metadata": {
 	//     "Fields": null,
 	//     "FieldsUnderRoot": false,
-	//     "Tags": null
+	//     "Tags": null,
+	//     "Namespace": ""
 	//   },
 	//   "fake": {
 	//     "status": {
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/elastic/beats/libbeat/beat"
+	"github.com/elastic/beats/libbeat/cfgfile"
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/libbeat/publisher"
@@ -33,6 +34,11 @@ func (bt *Metricbeat) Config(b *beat.Beat) error {
 
 	bt.config = &Config{}
 	err := b.RawConfig.Unpack(bt.config)
+	if err != nil {
+		return errors.Wrap(cfgfile.AnnotateError(err), "error reading configuration file")
+	}
+
+	bt.config.Modules, err = cfgfile.ExpandVars(b.Config.Vars, bt.config.Modules)
 	if err != nil {
 		return errors.Wrap(err, "error reading configuration file")
 	}
@@ -40,6 +46,12 @@ func (bt *Metricbeat) Config(b *beat.Beat) error {
 	return nil
 }
 
+// ConfigSchema returns the configuration structure of metricbeat, used to
+// detect unknown settings.
+func (bt *Metricbeat) ConfigSchema() interface{} {
+	return Config{}
+}
+
 // Setup initializes the Modules and MetricSets that are defined in the
 // Metricbeat configuration.
 func (bt *Metricbeat) Setup(b *beat.Beat) error {
@@ -65,7 +77,7 @@ func (bt *Metricbeat) Run(b *beat.Beat) error {
 	// Consume data from all modules and publish it. When the modules stop they
 	// close their output channels. When all the modules' channels are closed
 	// PublishChannels exit.
-	bt.client = b.Publisher.Connect()
+	bt.client = b.Connect()
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
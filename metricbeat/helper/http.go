@@ -0,0 +1,169 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// HTTPConfig contains the options of MetricSets reading metrics from a REST
+// API. The options are read from the module config.
+type HTTPConfig struct {
+	Username string             `config:"username"`
+	Password string             `config:"password"`
+	TLS      *outputs.TLSConfig `config:"tls"`
+}
+
+// HTTP reads metrics from the REST API of a host. The host of the MetricSet
+// is either a URL or host:port, in which case http is used. A path of the
+// host URL is prepended to the paths of all requests.
+type HTTP struct {
+	client   *http.Client
+	base     *url.URL
+	username string
+	password string
+}
+
+// NewHTTP creates the HTTP client of the MetricSet. The requests time out
+// after the timeout of the module.
+func NewHTTP(base mb.BaseMetricSet) (*HTTP, error) {
+	config := HTTPConfig{}
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	u, err := parseHostURL(base.Host())
+	if err != nil {
+		return nil, err
+	}
+
+	tls, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	username, password := config.Username, config.Password
+	if u.User != nil {
+		// credentials given in the host take precedence
+		username = u.User.Username()
+		password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	return &HTTP{
+		client: &http.Client{
+			Timeout: base.Module().Config().Timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tls,
+			},
+		},
+		base:     u,
+		username: username,
+		password: password,
+	}, nil
+}
+
+func parseHostURL(host string) (*url.URL, error) {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing host '%v': %v", host, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("error parsing host '%v': empty host", host)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
+
+// URL returns the URL of the path on the host, without credentials.
+func (h *HTTP) URL(path string) string {
+	u := *h.base
+	ref, err := url.Parse(path)
+	if err != nil {
+		u.Path += path
+		return u.String()
+	}
+	u.Path += ref.Path
+	u.RawQuery = ref.RawQuery
+	return u.String()
+}
+
+// FetchContent returns the body of a GET request of the path. If the host
+// responds with an HTTP error status, the body is returned with the error.
+func (h *HTTP) FetchContent(path string) ([]byte, error) {
+	return h.do("GET", path, nil)
+}
+
+// FetchJSON decodes the JSON document returned by a GET request of the path
+// into v.
+func (h *HTTP) FetchJSON(path string, v interface{}) error {
+	body, err := h.do("GET", path, nil)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(path, body, v)
+}
+
+// PostJSON posts in as JSON document to the path, and decodes the JSON
+// document returned into out.
+func (h *HTTP) PostJSON(path string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	body, err := h.do("POST", path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return decodeJSON(path, body, out)
+}
+
+func (h *HTTP) do(method, path string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, h.URL(path), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response of %v: %v", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return content, fmt.Errorf("HTTP error %d in %v: %s", resp.StatusCode, path, resp.Status)
+	}
+	return content, nil
+}
+
+func decodeJSON(path string, body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error decoding response of %v: %v", path, err)
+	}
+	return nil
+}
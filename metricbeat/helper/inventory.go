@@ -0,0 +1,91 @@
+package helper
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Inventory keeps the state of a set of items, like installed packages, and
+// turns it into events. The full state is reported on the first update and
+// then once per state period, all other updates only report the items that
+// were added, removed or changed.
+type Inventory struct {
+	statePeriod time.Duration
+	items       map[string]common.MapStr
+	lastState   time.Time
+}
+
+// NewInventory returns an Inventory reporting the full state once per state
+// period. If the period is 0, the full state is only reported on the first
+// update.
+func NewInventory(statePeriod time.Duration) *Inventory {
+	return &Inventory{statePeriod: statePeriod}
+}
+
+// Update replaces the items by the given items, indexed by a unique key, and
+// returns the events to report. The action field of the events is set to
+// "state", "added", "removed" or "changed". Events of changed items contain
+// the previous values of the changed fields in the previous field. Items
+// removed since the previous update are also reported with the full state.
+func (inv *Inventory) Update(items map[string]common.MapStr, now time.Time) []common.MapStr {
+	old := inv.items
+	inv.items = items
+
+	var events []common.MapStr
+	state := old == nil || (inv.statePeriod > 0 && now.Sub(inv.lastState) >= inv.statePeriod)
+	if state {
+		inv.lastState = now
+	}
+
+	for _, key := range sortedKeys(old, items) {
+		before, inOld := old[key]
+		after, inNew := items[key]
+
+		switch {
+		case state && inNew:
+			events = append(events, event(after, "state"))
+		case !inOld:
+			events = append(events, event(after, "added"))
+		case !inNew:
+			events = append(events, event(before, "removed"))
+		default:
+			previous := common.MapStr{}
+			for k, v := range before {
+				if !reflect.DeepEqual(v, after[k]) {
+					previous[k] = v
+				}
+			}
+			if len(previous) == 0 {
+				continue
+			}
+			e := event(after, "changed")
+			e["previous"] = previous
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func event(item common.MapStr, action string) common.MapStr {
+	e := item.Clone()
+	e["action"] = action
+	return e
+}
+
+func sortedKeys(maps ...map[string]common.MapStr) []string {
+	set := map[string]struct{}{}
+	for _, m := range maps {
+		for key := range m {
+			set[key] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+// +build !integration
+
+package helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInventory(t *testing.T) {
+	start := time.Now()
+	inv := NewInventory(time.Hour)
+
+	events := inv.Update(map[string]common.MapStr{
+		"b": {"name": "b", "version": "1.0"},
+		"a": {"name": "a", "version": "2.0"},
+	}, start)
+	assert.Equal(t, []common.MapStr{
+		{"name": "a", "version": "2.0", "action": "state"},
+		{"name": "b", "version": "1.0", "action": "state"},
+	}, events)
+
+	events = inv.Update(map[string]common.MapStr{
+		"b": {"name": "b", "version": "1.1"},
+		"c": {"name": "c", "version": "1.0"},
+	}, start.Add(time.Minute))
+	assert.Equal(t, []common.MapStr{
+		{"name": "a", "version": "2.0", "action": "removed"},
+		{"name": "b", "version": "1.1", "action": "changed", "previous": common.MapStr{"version": "1.0"}},
+		{"name": "c", "version": "1.0", "action": "added"},
+	}, events)
+
+	events = inv.Update(map[string]common.MapStr{
+		"b": {"name": "b", "version": "1.1"},
+		"c": {"name": "c", "version": "1.0"},
+	}, start.Add(2*time.Minute))
+	assert.Empty(t, events)
+
+	events = inv.Update(map[string]common.MapStr{
+		"c": {"name": "c", "version": "1.0"},
+	}, start.Add(time.Hour))
+	assert.Equal(t, []common.MapStr{
+		{"name": "b", "version": "1.1", "action": "removed"},
+		{"name": "c", "version": "1.0", "action": "state"},
+	}, events)
+}
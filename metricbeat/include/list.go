@@ -10,12 +10,27 @@ import (
 	// This list is automatically generated by `make imports`
 	_ "github.com/elastic/beats/metricbeat/module/apache"
 	_ "github.com/elastic/beats/metricbeat/module/apache/status"
+	_ "github.com/elastic/beats/metricbeat/module/ceph"
+	_ "github.com/elastic/beats/metricbeat/module/ceph/cluster_disk"
+	_ "github.com/elastic/beats/metricbeat/module/ceph/cluster_health"
+	_ "github.com/elastic/beats/metricbeat/module/ceph/pool_disk"
+	_ "github.com/elastic/beats/metricbeat/module/couchbase"
+	_ "github.com/elastic/beats/metricbeat/module/couchbase/bucket"
+	_ "github.com/elastic/beats/metricbeat/module/couchbase/cluster"
+	_ "github.com/elastic/beats/metricbeat/module/couchbase/node"
+	_ "github.com/elastic/beats/metricbeat/module/etcd"
+	_ "github.com/elastic/beats/metricbeat/module/etcd/health"
+	_ "github.com/elastic/beats/metricbeat/module/etcd/metrics"
 	_ "github.com/elastic/beats/metricbeat/module/mongodb"
 	_ "github.com/elastic/beats/metricbeat/module/mongodb/status"
 	_ "github.com/elastic/beats/metricbeat/module/mysql"
 	_ "github.com/elastic/beats/metricbeat/module/mysql/status"
 	_ "github.com/elastic/beats/metricbeat/module/nginx"
 	_ "github.com/elastic/beats/metricbeat/module/nginx/stubstatus"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/node"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/overview"
+	_ "github.com/elastic/beats/metricbeat/module/rabbitmq/queue"
 	_ "github.com/elastic/beats/metricbeat/module/redis"
 	_ "github.com/elastic/beats/metricbeat/module/redis/info"
 	_ "github.com/elastic/beats/metricbeat/module/redis/keyspace"
@@ -25,9 +40,20 @@ import (
 	_ "github.com/elastic/beats/metricbeat/module/system/diskio"
 	_ "github.com/elastic/beats/metricbeat/module/system/filesystem"
 	_ "github.com/elastic/beats/metricbeat/module/system/fsstat"
+	_ "github.com/elastic/beats/metricbeat/module/system/kernel_module"
+	_ "github.com/elastic/beats/metricbeat/module/system/login"
 	_ "github.com/elastic/beats/metricbeat/module/system/memory"
 	_ "github.com/elastic/beats/metricbeat/module/system/network"
+	_ "github.com/elastic/beats/metricbeat/module/system/package"
 	_ "github.com/elastic/beats/metricbeat/module/system/process"
+	_ "github.com/elastic/beats/metricbeat/module/system/socket"
+	_ "github.com/elastic/beats/metricbeat/module/vsphere"
+	_ "github.com/elastic/beats/metricbeat/module/vsphere/datastore"
+	_ "github.com/elastic/beats/metricbeat/module/vsphere/host"
+	_ "github.com/elastic/beats/metricbeat/module/vsphere/virtualmachine"
+	_ "github.com/elastic/beats/metricbeat/module/windows"
+	_ "github.com/elastic/beats/metricbeat/module/windows/registry"
+	_ "github.com/elastic/beats/metricbeat/module/windows/scheduled_task"
 	_ "github.com/elastic/beats/metricbeat/module/zookeeper"
 	_ "github.com/elastic/beats/metricbeat/module/zookeeper/mntr"
 )
@@ -0,0 +1,131 @@
+package ceph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+const (
+	// APIRest is the ceph-rest-api, which serves the commands under
+	// /api/v0.1.
+	APIRest = "rest-api"
+
+	// APIMgr is the restful module of the Ceph manager, which runs the
+	// commands posted to /request.
+	APIMgr = "mgr"
+)
+
+// Config contains the options of the Ceph module.
+type Config struct {
+	API string `config:"api"`
+}
+
+// Client runs read-only Ceph commands, like `health` or `df`, through the
+// configured API.
+type Client struct {
+	http *helper.HTTP
+	api  string
+}
+
+// NewClient creates the client of the MetricSet.
+func NewClient(base mb.BaseMetricSet) (*Client, error) {
+	config := Config{API: APIRest}
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+	switch config.API {
+	case APIRest, APIMgr:
+	default:
+		return nil, fmt.Errorf("unknown ceph api '%v'", config.API)
+	}
+
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{http: http, api: config.API}, nil
+}
+
+// URL returns the URL the command is sent to.
+func (c *Client) URL(command string) string {
+	if c.api == APIMgr {
+		return c.http.URL("/request")
+	}
+	return c.http.URL(restPath(command))
+}
+
+// Command runs the command and decodes its JSON output into v.
+func (c *Client) Command(command string, v interface{}) error {
+	var output []byte
+	var err error
+	if c.api == APIMgr {
+		output, err = c.mgrCommand(command)
+	} else {
+		output, err = c.restCommand(command)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("error decoding output of ceph command '%v': %v", command, err)
+	}
+	return nil
+}
+
+// restCommand runs the command through the ceph-rest-api, which wraps the
+// output in a status document.
+func (c *Client) restCommand(command string) ([]byte, error) {
+	var resp struct {
+		Status string          `json:"status"`
+		Output json.RawMessage `json:"output"`
+	}
+	if err := c.http.FetchJSON(restPath(command), &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status != "OK" {
+		return nil, fmt.Errorf("ceph command '%v' failed with status '%v'", command, resp.Status)
+	}
+	return resp.Output, nil
+}
+
+// mgrCommand runs the command through the restful module of the Ceph
+// manager, waiting for the command to finish. The output of finished commands
+// is returned as string.
+func (c *Client) mgrCommand(command string) ([]byte, error) {
+	var resp struct {
+		HasFailed bool `json:"has_failed"`
+		Failed    []struct {
+			Outs string `json:"outs"`
+		} `json:"failed"`
+		Finished []struct {
+			Outb string `json:"outb"`
+		} `json:"finished"`
+	}
+	req := map[string]string{"prefix": command, "format": "json"}
+	if err := c.http.PostJSON("/request?wait=1", req, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.HasFailed || len(resp.Failed) > 0 {
+		msg := ""
+		if len(resp.Failed) > 0 {
+			msg = resp.Failed[0].Outs
+		}
+		return nil, fmt.Errorf("ceph command '%v' failed: %v", command, msg)
+	}
+	if len(resp.Finished) == 0 {
+		return nil, fmt.Errorf("ceph command '%v' did not finish", command)
+	}
+	return []byte(resp.Finished[0].Outb), nil
+}
+
+// restPath returns the path of the command in the ceph-rest-api, where the
+// words of the command are path elements, like /api/v0.1/osd/tree.
+func restPath(command string) string {
+	return "/api/v0.1/" + strings.Join(strings.Fields(command), "/")
+}
@@ -0,0 +1,60 @@
+// Package cluster_disk reads the disk usage of a Ceph cluster with the df
+// command.
+package cluster_disk
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/ceph"
+)
+
+var (
+	debugf = logp.MakeDebug("ceph-cluster_disk")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("ceph", "cluster_disk", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the Ceph cluster disk usage.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *ceph.Client
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := ceph.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("ceph-cluster_disk URL=%s", client.URL("df"))
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch fetches the disk usage of the cluster.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	var df struct {
+		Stats struct {
+			TotalBytes      int64 `json:"total_bytes"`
+			TotalUsedBytes  int64 `json:"total_used_bytes"`
+			TotalAvailBytes int64 `json:"total_avail_bytes"`
+		} `json:"stats"`
+	}
+	if err := m.client.Command("df", &df); err != nil {
+		return nil, err
+	}
+
+	return common.MapStr{
+		"total":     common.MapStr{"bytes": df.Stats.TotalBytes},
+		"used":      common.MapStr{"bytes": df.Stats.TotalUsedBytes},
+		"available": common.MapStr{"bytes": df.Stats.TotalAvailBytes},
+	}, nil
+}
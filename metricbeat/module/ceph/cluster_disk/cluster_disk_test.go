@@ -0,0 +1,53 @@
+// +build !integration
+
+package cluster_disk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a response of /api/v0.1/df of the ceph-rest-api of Ceph Jewel.
+const response = `{
+  "status": "OK",
+  "output": {
+    "pools": [
+      {"name": "rbd", "id": 0, "stats": {"kb_used": 1, "bytes_used": 1024, "max_avail": 5003444224, "objects": 3}}
+    ],
+    "stats": {"total_used_bytes": 1428520960, "total_bytes": 6431965184, "total_avail_bytes": 5003444224}
+  }
+}`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v0.1/df" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, map[string]interface{}{
+		"module":     "ceph",
+		"metricsets": []string{"cluster_disk"},
+		"hosts":      []string{server.URL},
+	})
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.EqualValues(t, 6431965184, event["total"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 1428520960, event["used"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 5003444224, event["available"].(common.MapStr)["bytes"])
+}
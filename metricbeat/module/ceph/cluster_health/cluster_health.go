@@ -0,0 +1,49 @@
+// Package cluster_health reads the health of a Ceph cluster with the health
+// command.
+package cluster_health
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/ceph"
+)
+
+var (
+	debugf = logp.MakeDebug("ceph-cluster_health")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("ceph", "cluster_health", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the Ceph cluster health.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *ceph.Client
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := ceph.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("ceph-cluster_health URL=%s", client.URL("health"))
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch fetches the health of the cluster.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	var h health
+	if err := m.client.Command("health", &h); err != nil {
+		return nil, err
+	}
+	return eventMapping(h), nil
+}
@@ -0,0 +1,96 @@
+// +build !integration
+
+package cluster_health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// restResponse is a shortened response of /api/v0.1/health of the
+// ceph-rest-api of Ceph Jewel.
+const restResponse = `{
+  "status": "OK",
+  "output": {
+    "overall_status": "HEALTH_WARN",
+    "summary": [{"severity": "HEALTH_WARN", "summary": "too many PGs per OSD (480 > max 300)"}],
+    "timechecks": {"epoch": 3, "round": 0, "round_status": "finished"},
+    "detail": []
+  }
+}`
+
+// mgrResponse is a shortened response of /request?wait=1 of the restful
+// module of the Ceph manager of Ceph Luminous.
+const mgrResponse = `{
+  "has_failed": false,
+  "is_finished": true,
+  "failed": [],
+  "finished": [
+    {
+      "command": "health format=json",
+      "outb": "{\"checks\":{\"OSD_DOWN\":{\"severity\":\"HEALTH_WARN\",\"summary\":{\"message\":\"1 osds down\"}}},\"status\":\"HEALTH_WARN\"}",
+      "outs": ""
+    }
+  ]
+}`
+
+func TestFetchRestAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v0.1/health" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(restResponse))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, map[string]interface{}{
+		"module":     "ceph",
+		"metricsets": []string{"cluster_health"},
+		"hosts":      []string{server.URL},
+	})
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "HEALTH_WARN", event["overall_status"])
+	assert.Equal(t, []string{"too many PGs per OSD (480 > max 300)"}, event["messages"])
+	assert.EqualValues(t, 3, event["timechecks"].(common.MapStr)["epoch"])
+}
+
+func TestFetchMgr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/request" || r.URL.Query().Get("wait") != "1" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(mgrResponse))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, map[string]interface{}{
+		"module":     "ceph",
+		"metricsets": []string{"cluster_health"},
+		"hosts":      []string{server.URL},
+		"api":        "mgr",
+	})
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "HEALTH_WARN", event["overall_status"])
+	assert.Equal(t, []string{"1 osds down"}, event["messages"])
+	assert.Nil(t, event["timechecks"])
+}
@@ -0,0 +1,63 @@
+package cluster_health
+
+import (
+	"sort"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// health is the output of the health command. Ceph Jewel reports the status
+// in overall_status and the messages in summary, later versions report the
+// status in status and the messages in checks.
+type health struct {
+	Status        string `json:"status"`
+	OverallStatus string `json:"overall_status"`
+	Summary       []struct {
+		Summary string `json:"summary"`
+	} `json:"summary"`
+	Checks map[string]struct {
+		Summary struct {
+			Message string `json:"message"`
+		} `json:"summary"`
+	} `json:"checks"`
+	Timechecks *struct {
+		Epoch       int64  `json:"epoch"`
+		Round       int64  `json:"round"`
+		RoundStatus string `json:"round_status"`
+	} `json:"timechecks"`
+}
+
+func eventMapping(h health) common.MapStr {
+	status := h.Status
+	if status == "" {
+		status = h.OverallStatus
+	}
+
+	messages := []string{}
+	for _, s := range h.Summary {
+		messages = append(messages, s.Summary)
+	}
+	var checks []string
+	for name := range h.Checks {
+		checks = append(checks, name)
+	}
+	sort.Strings(checks)
+	for _, name := range checks {
+		messages = append(messages, h.Checks[name].Summary.Message)
+	}
+
+	event := common.MapStr{
+		"overall_status": status,
+		"messages":       messages,
+	}
+	if h.Timechecks != nil {
+		event["timechecks"] = common.MapStr{
+			"epoch": h.Timechecks.Epoch,
+			"round": common.MapStr{
+				"value":  h.Timechecks.Round,
+				"status": h.Timechecks.RoundStatus,
+			},
+		}
+	}
+	return event
+}
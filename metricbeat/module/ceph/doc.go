@@ -0,0 +1,5 @@
+/*
+Package ceph is a Metricbeat module for Ceph clusters. The metrics are read
+from the ceph-rest-api or the restful module of the Ceph manager.
+*/
+package ceph
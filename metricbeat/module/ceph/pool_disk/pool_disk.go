@@ -0,0 +1,78 @@
+// Package pool_disk reads the disk usage of the pools of a Ceph cluster with
+// the df command.
+package pool_disk
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/ceph"
+)
+
+var (
+	debugf = logp.MakeDebug("ceph-pool_disk")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("ceph", "pool_disk", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the disk usage of Ceph pools.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *ceph.Client
+}
+
+type pool struct {
+	Name  string `json:"name"`
+	ID    int64  `json:"id"`
+	Stats struct {
+		BytesUsed int64 `json:"bytes_used"`
+		KBUsed    int64 `json:"kb_used"`
+		MaxAvail  int64 `json:"max_avail"`
+		Objects   int64 `json:"objects"`
+	} `json:"stats"`
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := ceph.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("ceph-pool_disk URL=%s", client.URL("df"))
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch returns one event per pool.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var df struct {
+		Pools []pool `json:"pools"`
+	}
+	if err := m.client.Command("df", &df); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(df.Pools))
+	for _, p := range df.Pools {
+		events = append(events, common.MapStr{
+			"name": p.Name,
+			"id":   p.ID,
+			"stats": common.MapStr{
+				"used": common.MapStr{
+					"bytes": p.Stats.BytesUsed,
+					"kb":    p.Stats.KBUsed,
+				},
+				"available": common.MapStr{"bytes": p.Stats.MaxAvail},
+				"objects":   p.Stats.Objects,
+			},
+		})
+	}
+	return events, nil
+}
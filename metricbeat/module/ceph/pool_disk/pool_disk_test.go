@@ -0,0 +1,56 @@
+// +build !integration
+
+package pool_disk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a response of /api/v0.1/df of the ceph-rest-api of Ceph Jewel.
+const response = `{
+  "status": "OK",
+  "output": {
+    "pools": [
+      {"name": "rbd", "id": 0, "stats": {"kb_used": 1, "bytes_used": 1024, "max_avail": 5003444224, "objects": 3}},
+      {"name": "images", "id": 1, "stats": {"kb_used": 0, "bytes_used": 0, "max_avail": 5003444224, "objects": 0}}
+    ],
+    "stats": {"total_used_bytes": 1428520960, "total_bytes": 6431965184, "total_avail_bytes": 5003444224}
+  }
+}`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventsFetcher(t, map[string]interface{}{
+		"module":     "ceph",
+		"metricsets": []string{"pool_disk"},
+		"hosts":      []string{server.URL},
+	})
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, events, 2) {
+		t.FailNow()
+	}
+
+	event := events[0]
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "rbd", event["name"])
+	stats := event["stats"].(common.MapStr)
+	assert.EqualValues(t, 1024, stats["used"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 3, stats["objects"])
+	assert.Equal(t, "images", events[1]["name"])
+}
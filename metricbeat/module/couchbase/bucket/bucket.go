@@ -0,0 +1,57 @@
+// Package bucket reads the basic stats of the buckets of a Couchbase cluster
+// from the /pools/default/buckets endpoint of the REST API.
+package bucket
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the buckets of the default pool.
+const defaultPath = "/pools/default/buckets"
+
+var (
+	debugf = logp.MakeDebug("couchbase-bucket")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("couchbase", "bucket", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching Couchbase bucket metrics.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("couchbase-bucket URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch returns one event per bucket.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var buckets []bucket
+	if err := m.http.FetchJSON(defaultPath, &buckets); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(buckets))
+	for _, b := range buckets {
+		events = append(events, eventMapping(b))
+	}
+	return events, nil
+}
@@ -0,0 +1,88 @@
+// +build !integration
+
+package bucket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /pools/default/buckets endpoint of
+// Couchbase Server 4.5.
+const response = `[
+  {
+    "name": "beer-sample",
+    "bucketType": "membase",
+    "quota": {"ram": 104857600, "rawRAM": 104857600},
+    "basicStats": {
+      "quotaPercentUsed": 34.82557296752930,
+      "opsPerSec": 0,
+      "diskFetches": 0,
+      "itemCount": 7303,
+      "diskUsed": 16379075,
+      "dataUsed": 9982464,
+      "memUsed": 36517192
+    }
+  },
+  {
+    "name": "sessions",
+    "bucketType": "memcached",
+    "quota": {"ram": 209715200, "rawRAM": 209715200},
+    "basicStats": {
+      "quotaPercentUsed": 0,
+      "opsPerSec": 12.5,
+      "itemCount": 10
+    }
+  }
+]`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pools/default/buckets" {
+			w.WriteHeader(404)
+			return
+		}
+		user, password, _ := r.BasicAuth()
+		if user != "admin" || password != "secret" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "couchbase",
+		"metricsets": []string{"bucket"},
+		"hosts":      []string{server.URL},
+		"username":   "admin",
+		"password":   "secret",
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, events, 2) {
+		t.FailNow()
+	}
+
+	event := events[0]
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "beer-sample", event["name"])
+	assert.Equal(t, "membase", event["type"])
+	assert.EqualValues(t, 7303, event["item_count"])
+	assert.EqualValues(t, 16379075, event["disk"].(common.MapStr)["used"].(common.MapStr)["bytes"])
+	assert.InDelta(t, 0.3482, event["quota"].(common.MapStr)["use"].(common.MapStr)["pct"], 0.0001)
+
+	assert.Equal(t, 12.5, events[1]["ops_per_sec"])
+}
@@ -0,0 +1,47 @@
+package bucket
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type bucket struct {
+	Name       string `json:"name"`
+	BucketType string `json:"bucketType"`
+	Quota      struct {
+		RAM    int64 `json:"ram"`
+		RawRAM int64 `json:"rawRAM"`
+	} `json:"quota"`
+	BasicStats struct {
+		QuotaPercentUsed float64 `json:"quotaPercentUsed"`
+		OpsPerSec        float64 `json:"opsPerSec"`
+		DiskFetches      float64 `json:"diskFetches"`
+		ItemCount        int64   `json:"itemCount"`
+		DiskUsed         int64   `json:"diskUsed"`
+		DataUsed         int64   `json:"dataUsed"`
+		MemUsed          int64   `json:"memUsed"`
+	} `json:"basicStats"`
+}
+
+func eventMapping(b bucket) common.MapStr {
+	stats := b.BasicStats
+	return common.MapStr{
+		"name": b.Name,
+		"type": b.BucketType,
+		"quota": common.MapStr{
+			"ram": common.MapStr{"bytes": b.Quota.RAM},
+			"use": common.MapStr{"pct": stats.QuotaPercentUsed / 100},
+		},
+		"ops_per_sec": stats.OpsPerSec,
+		"disk": common.MapStr{
+			"fetches": int64(stats.DiskFetches),
+			"used":    common.MapStr{"bytes": stats.DiskUsed},
+		},
+		"data": common.MapStr{
+			"used": common.MapStr{"bytes": stats.DataUsed},
+		},
+		"memory": common.MapStr{
+			"used": common.MapStr{"bytes": stats.MemUsed},
+		},
+		"item_count": stats.ItemCount,
+	}
+}
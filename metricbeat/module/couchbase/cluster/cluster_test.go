@@ -0,0 +1,80 @@
+// +build !integration
+
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /pools/default endpoint of
+// Couchbase Server 4.5.
+const response = `{
+  "name": "default",
+  "balanced": true,
+  "rebalanceStatus": "none",
+  "maxBucketCount": 10,
+  "nodes": [{"hostname": "172.17.0.2:8091"}],
+  "storageTotals": {
+    "ram": {
+      "total": 8359174144,
+      "quotaTotal": 314572800,
+      "quotaUsed": 104857600,
+      "used": 8004751360,
+      "usedByData": 53962016,
+      "quotaUsedPerNode": 104857600,
+      "quotaTotalPerNode": 314572800
+    },
+    "hdd": {
+      "total": 63278391296,
+      "quotaTotal": 63278391296,
+      "used": 26575010324,
+      "usedByData": 32967085,
+      "free": 36703466952
+    }
+  }
+}`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pools/default" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "couchbase",
+		"metricsets": []string{"cluster"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, true, event["balanced"])
+	assert.Equal(t, 1, event["nodes"])
+	assert.EqualValues(t, 10, event["max_bucket_count"])
+
+	ram := event["ram"].(common.MapStr)
+	assert.EqualValues(t, 8004751360, ram["used"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 314572800, ram["quota"].(common.MapStr)["total"].(common.MapStr)["bytes"])
+
+	hdd := event["hdd"].(common.MapStr)
+	assert.EqualValues(t, 36703466952, hdd["free"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 32967085, hdd["used"].(common.MapStr)["by_data"].(common.MapStr)["bytes"])
+}
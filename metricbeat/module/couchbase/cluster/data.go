@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type pool struct {
+	Name            string `json:"name"`
+	Balanced        bool   `json:"balanced"`
+	RebalanceStatus string `json:"rebalanceStatus"`
+	MaxBucketCount  int64  `json:"maxBucketCount"`
+	StorageTotals   struct {
+		RAM struct {
+			Total             int64 `json:"total"`
+			QuotaTotal        int64 `json:"quotaTotal"`
+			QuotaUsed         int64 `json:"quotaUsed"`
+			Used              int64 `json:"used"`
+			UsedByData        int64 `json:"usedByData"`
+			QuotaTotalPerNode int64 `json:"quotaTotalPerNode"`
+			QuotaUsedPerNode  int64 `json:"quotaUsedPerNode"`
+		} `json:"ram"`
+		HDD struct {
+			Total      int64 `json:"total"`
+			QuotaTotal int64 `json:"quotaTotal"`
+			Used       int64 `json:"used"`
+			UsedByData int64 `json:"usedByData"`
+			Free       int64 `json:"free"`
+		} `json:"hdd"`
+	} `json:"storageTotals"`
+	Nodes []struct{} `json:"nodes"`
+}
+
+func eventMapping(p pool) common.MapStr {
+	ram := p.StorageTotals.RAM
+	hdd := p.StorageTotals.HDD
+
+	return common.MapStr{
+		"name":             p.Name,
+		"balanced":         p.Balanced,
+		"rebalance_status": p.RebalanceStatus,
+		"max_bucket_count": p.MaxBucketCount,
+		"nodes":            len(p.Nodes),
+		"ram": common.MapStr{
+			"total": common.MapStr{"bytes": ram.Total},
+			"used": common.MapStr{
+				"bytes":   ram.Used,
+				"by_data": common.MapStr{"bytes": ram.UsedByData},
+			},
+			"quota": common.MapStr{
+				"total": common.MapStr{
+					"bytes":    ram.QuotaTotal,
+					"per_node": common.MapStr{"bytes": ram.QuotaTotalPerNode},
+				},
+				"used": common.MapStr{
+					"bytes":    ram.QuotaUsed,
+					"per_node": common.MapStr{"bytes": ram.QuotaUsedPerNode},
+				},
+			},
+		},
+		"hdd": common.MapStr{
+			"total": common.MapStr{"bytes": hdd.Total},
+			"free":  common.MapStr{"bytes": hdd.Free},
+			"quota": common.MapStr{
+				"total": common.MapStr{"bytes": hdd.QuotaTotal},
+			},
+			"used": common.MapStr{
+				"bytes":   hdd.Used,
+				"by_data": common.MapStr{"bytes": hdd.UsedByData},
+			},
+		},
+	}
+}
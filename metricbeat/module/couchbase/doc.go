@@ -0,0 +1,5 @@
+/*
+Package couchbase is a Metricbeat module for Couchbase Server. The metrics are
+read from the REST API of a cluster node.
+*/
+package couchbase
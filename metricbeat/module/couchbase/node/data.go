@@ -0,0 +1,104 @@
+package node
+
+import (
+	"strconv"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// megabyte converts the memcached memory, which is reported in megabytes, to bytes.
+const megabyte = 1024 * 1024
+
+type pool struct {
+	Nodes []node `json:"nodes"`
+}
+
+type node struct {
+	Hostname          string `json:"hostname"`
+	Status            string `json:"status"`
+	ClusterMembership string `json:"clusterMembership"`
+	Version           string `json:"version"`
+	Uptime            string `json:"uptime"`
+	MemoryTotal       int64  `json:"memoryTotal"`
+	MemoryFree        int64  `json:"memoryFree"`
+	MCDMemoryReserved int64  `json:"mcdMemoryReserved"`
+	MCDMemoryAlloc    int64  `json:"mcdMemoryAllocated"`
+	SystemStats       struct {
+		CPUUtilizationRate float64 `json:"cpu_utilization_rate"`
+		SwapTotal          int64   `json:"swap_total"`
+		SwapUsed           int64   `json:"swap_used"`
+	} `json:"systemStats"`
+	InterestingStats struct {
+		CmdGet                   float64 `json:"cmd_get"`
+		CouchDocsActualDiskSize  int64   `json:"couch_docs_actual_disk_size"`
+		CouchDocsDataSize        int64   `json:"couch_docs_data_size"`
+		CouchSpatialDataSize     int64   `json:"couch_spatial_data_size"`
+		CouchSpatialDiskSize     int64   `json:"couch_spatial_disk_size"`
+		CouchViewsActualDiskSize int64   `json:"couch_views_actual_disk_size"`
+		CouchViewsDataSize       int64   `json:"couch_views_data_size"`
+		CurrItems                int64   `json:"curr_items"`
+		CurrItemsTot             int64   `json:"curr_items_tot"`
+		EpBgFetched              float64 `json:"ep_bg_fetched"`
+		GetHits                  float64 `json:"get_hits"`
+		MemUsed                  int64   `json:"mem_used"`
+		Ops                      float64 `json:"ops"`
+		VbReplicaCurrItems       int64   `json:"vb_replica_curr_items"`
+	} `json:"interestingStats"`
+}
+
+func eventMapping(n node) common.MapStr {
+	// the uptime is reported as string
+	uptime, err := strconv.ParseInt(n.Uptime, 10, 64)
+	if err != nil {
+		logp.Err("Error converting the uptime '%v' of couchbase node %v", n.Uptime, n.Hostname)
+	}
+
+	stats := n.InterestingStats
+	return common.MapStr{
+		"hostname":           n.Hostname,
+		"status":             n.Status,
+		"cluster_membership": n.ClusterMembership,
+		"version":            n.Version,
+		"uptime":             common.MapStr{"sec": uptime},
+		"cpu_utilization_rate": common.MapStr{
+			"pct": n.SystemStats.CPUUtilizationRate / 100,
+		},
+		"memory": common.MapStr{
+			"total": common.MapStr{"bytes": n.MemoryTotal},
+			"free":  common.MapStr{"bytes": n.MemoryFree},
+			"used":  common.MapStr{"bytes": stats.MemUsed},
+		},
+		"swap": common.MapStr{
+			"total": common.MapStr{"bytes": n.SystemStats.SwapTotal},
+			"used":  common.MapStr{"bytes": n.SystemStats.SwapUsed},
+		},
+		"mcd_memory": common.MapStr{
+			"reserved":  common.MapStr{"bytes": n.MCDMemoryReserved * megabyte},
+			"allocated": common.MapStr{"bytes": n.MCDMemoryAlloc * megabyte},
+		},
+		"couch": common.MapStr{
+			"docs": common.MapStr{
+				"disk_size": common.MapStr{"bytes": stats.CouchDocsActualDiskSize},
+				"data_size": common.MapStr{"bytes": stats.CouchDocsDataSize},
+			},
+			"spatial": common.MapStr{
+				"disk_size": common.MapStr{"bytes": stats.CouchSpatialDiskSize},
+				"data_size": common.MapStr{"bytes": stats.CouchSpatialDataSize},
+			},
+			"views": common.MapStr{
+				"disk_size": common.MapStr{"bytes": stats.CouchViewsActualDiskSize},
+				"data_size": common.MapStr{"bytes": stats.CouchViewsDataSize},
+			},
+		},
+		"current_items": common.MapStr{
+			"value": stats.CurrItems,
+			"total": stats.CurrItemsTot,
+		},
+		"vb_replica_curr_items": stats.VbReplicaCurrItems,
+		"cmd_get":               int64(stats.CmdGet),
+		"get_hits":              int64(stats.GetHits),
+		"ep_bg_fetched":         int64(stats.EpBgFetched),
+		"ops":                   stats.Ops,
+	}
+}
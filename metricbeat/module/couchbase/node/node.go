@@ -0,0 +1,57 @@
+// Package node reads the metrics of every node of a Couchbase cluster from
+// the /pools/default endpoint of the REST API.
+package node
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the cluster details of the default pool.
+const defaultPath = "/pools/default"
+
+var (
+	debugf = logp.MakeDebug("couchbase-node")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("couchbase", "node", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching Couchbase node metrics.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("couchbase-node URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch returns one event per node of the cluster.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var pool pool
+	if err := m.http.FetchJSON(defaultPath, &pool); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(pool.Nodes))
+	for _, n := range pool.Nodes {
+		events = append(events, eventMapping(n))
+	}
+	return events, nil
+}
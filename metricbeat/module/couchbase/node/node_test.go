@@ -0,0 +1,103 @@
+// +build !integration
+
+package node
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /pools/default endpoint of a
+// Couchbase Server 4.5 cluster with two nodes.
+const response = `{
+  "name": "default",
+  "nodes": [
+    {
+      "systemStats": {
+        "cpu_utilization_rate": 29.64705882352941,
+        "swap_total": 4189057024,
+        "swap_used": 135168,
+        "mem_total": 8359174144,
+        "mem_free": 4678283264
+      },
+      "interestingStats": {
+        "cmd_get": 2,
+        "couch_docs_actual_disk_size": 13563791,
+        "couch_docs_data_size": 9792512,
+        "couch_spatial_data_size": 0,
+        "couch_spatial_disk_size": 0,
+        "couch_views_actual_disk_size": 2805219,
+        "couch_views_data_size": 2805219,
+        "curr_items": 7303,
+        "curr_items_tot": 7303,
+        "ep_bg_fetched": 0,
+        "get_hits": 1,
+        "mem_used": 36517192,
+        "ops": 3.5,
+        "vb_replica_curr_items": 0
+      },
+      "uptime": "7260",
+      "memoryTotal": 8359174144,
+      "memoryFree": 4678283264,
+      "mcdMemoryReserved": 6377,
+      "mcdMemoryAllocated": 6377,
+      "clusterMembership": "active",
+      "status": "healthy",
+      "hostname": "172.17.0.2:8091",
+      "version": "4.5.1-2844-enterprise"
+    },
+    {
+      "systemStats": {},
+      "interestingStats": {},
+      "uptime": "120",
+      "clusterMembership": "inactiveAdded",
+      "status": "warmup",
+      "hostname": "172.17.0.3:8091",
+      "version": "4.5.1-2844-enterprise"
+    }
+  ]
+}`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "couchbase",
+		"metricsets": []string{"node"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, events, 2) {
+		t.FailNow()
+	}
+
+	event := events[0]
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "172.17.0.2:8091", event["hostname"])
+	assert.Equal(t, "healthy", event["status"])
+	assert.EqualValues(t, 7260, event["uptime"].(common.MapStr)["sec"])
+	assert.InDelta(t, 0.2964, event["cpu_utilization_rate"].(common.MapStr)["pct"], 0.0001)
+	assert.EqualValues(t, 7303, event["current_items"].(common.MapStr)["value"])
+	assert.EqualValues(t, 2, event["cmd_get"])
+
+	couch := event["couch"].(common.MapStr)
+	assert.EqualValues(t, 13563791, couch["docs"].(common.MapStr)["disk_size"].(common.MapStr)["bytes"])
+
+	assert.Equal(t, "warmup", events[1]["status"])
+}
@@ -0,0 +1,5 @@
+/*
+Package etcd is a Metricbeat module for etcd v3 servers. The metrics are read
+from the /health and /metrics endpoints of the client URL.
+*/
+package etcd
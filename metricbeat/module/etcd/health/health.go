@@ -0,0 +1,93 @@
+// Package health reads the health of an etcd member from the /health
+// endpoint.
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the health endpoint of etcd.
+const defaultPath = "/health"
+
+var (
+	debugf = logp.MakeDebug("etcd-health")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("etcd", "health", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the etcd member health.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("etcd-health URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch fetches the health of the member. Unhealthy members respond with
+// status 503 and the health document.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	body, httpErr := m.http.FetchContent(defaultPath)
+	if body == nil {
+		return nil, httpErr
+	}
+
+	var h struct {
+		Health json.RawMessage `json:"health"`
+		Reason string          `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &h); err != nil || h.Health == nil {
+		if httpErr != nil {
+			return nil, httpErr
+		}
+		return nil, fmt.Errorf("error decoding etcd health: %v", err)
+	}
+
+	healthy, err := parseHealth(h.Health)
+	if err != nil {
+		return nil, err
+	}
+
+	event := common.MapStr{"healthy": healthy}
+	if h.Reason != "" {
+		event["reason"] = h.Reason
+	}
+	return event, nil
+}
+
+// parseHealth parses the health, which is reported as string, like "true",
+// by etcd 3.3 and earlier, and as boolean by later versions.
+func parseHealth(raw json.RawMessage) (bool, error) {
+	var healthy bool
+	if err := json.Unmarshal(raw, &healthy); err == nil {
+		return healthy, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return false, fmt.Errorf("invalid etcd health '%s'", raw)
+	}
+	return strconv.ParseBool(s)
+}
@@ -0,0 +1,64 @@
+// +build !integration
+
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+func TestFetchEventContents(t *testing.T) {
+	tests := []struct {
+		status   int
+		response string
+		healthy  bool
+		reason   interface{}
+	}{
+		{200, `{"health": "true"}`, true, nil},
+		{503, `{"health": "false"}`, false, nil},
+		{503, `{"health": false, "reason": "RAFT NO LEADER"}`, false, "RAFT NO LEADER"},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.response))
+		}))
+
+		f := mbtest.NewEventFetcher(t, map[string]interface{}{
+			"module":     "etcd",
+			"metricsets": []string{"health"},
+			"hosts":      []string{server.URL},
+		})
+		event, err := f.Fetch()
+		server.Close()
+		if !assert.NoError(t, err, test.response) {
+			continue
+		}
+
+		assert.Equal(t, test.healthy, event["healthy"], test.response)
+		assert.Equal(t, test.reason, event["reason"], test.response)
+	}
+}
+
+func TestFetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("404 page not found"))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, map[string]interface{}{
+		"module":     "etcd",
+		"metricsets": []string{"health"},
+		"hosts":      []string{server.URL},
+	})
+	_, err := f.Fetch()
+	assert.Error(t, err)
+}
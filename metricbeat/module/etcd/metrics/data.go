@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// parseMetrics parses metrics in the Prometheus text format. The values of
+// the label sets of a metric are added up.
+func parseMetrics(r io.Reader) (map[string]float64, error) {
+	metrics := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// metric_name{label="value",...} value [timestamp]
+		var name, rest string
+		if i := strings.IndexByte(line, '{'); i >= 0 {
+			end := strings.LastIndex(line, "}")
+			if end < i {
+				return nil, fmt.Errorf("invalid metric line '%v'", line)
+			}
+			name, rest = line[:i], line[end+1:]
+		} else {
+			fields := strings.SplitN(line, " ", 2)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("invalid metric line '%v'", line)
+			}
+			name, rest = fields[0], fields[1]
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("metric '%v' has no value", name)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of metric '%v': %v", name, err)
+		}
+		metrics[name] += value
+	}
+	return metrics, scanner.Err()
+}
+
+// eventMapping selects the metrics of the event. Metrics missing in the
+// response, for example because they were added in a later etcd version, are
+// not reported.
+func eventMapping(metrics map[string]float64) common.MapStr {
+	event := common.MapStr{}
+	put := func(key string, names ...string) {
+		for _, name := range names {
+			if value, found := metrics[name]; found {
+				event.Put(key, int64(value))
+				return
+			}
+		}
+	}
+	putSeconds := func(key, name string) {
+		if value, found := metrics[name]; found {
+			event.Put(key, value)
+		}
+	}
+
+	if value, found := metrics["etcd_server_has_leader"]; found {
+		event.Put("server.has_leader", value == 1)
+	}
+	put("server.leader_changes", "etcd_server_leader_changes_seen_total")
+	put("server.proposals.committed", "etcd_server_proposals_committed_total")
+	put("server.proposals.applied", "etcd_server_proposals_applied_total")
+	put("server.proposals.pending", "etcd_server_proposals_pending")
+	put("server.proposals.failed", "etcd_server_proposals_failed_total")
+
+	// the mvcc metrics were debugging metrics before etcd 3.4
+	put("mvcc.db_size.bytes", "etcd_mvcc_db_total_size_in_bytes", "etcd_debugging_mvcc_db_total_size_in_bytes")
+	put("mvcc.keys", "etcd_debugging_mvcc_keys_total")
+
+	put("disk.wal_fsync.count", "etcd_disk_wal_fsync_duration_seconds_count")
+	putSeconds("disk.wal_fsync.sum.sec", "etcd_disk_wal_fsync_duration_seconds_sum")
+	put("disk.backend_commit.count", "etcd_disk_backend_commit_duration_seconds_count")
+	putSeconds("disk.backend_commit.sum.sec", "etcd_disk_backend_commit_duration_seconds_sum")
+
+	put("network.client_grpc.received.bytes", "etcd_network_client_grpc_received_bytes_total")
+	put("network.client_grpc.sent.bytes", "etcd_network_client_grpc_sent_bytes_total")
+	put("network.peer.received.bytes", "etcd_network_peer_received_bytes_total")
+	put("network.peer.sent.bytes", "etcd_network_peer_sent_bytes_total")
+
+	return event
+}
@@ -0,0 +1,59 @@
+// Package metrics reads the server, disk and network metrics of an etcd v3
+// member from the Prometheus /metrics endpoint.
+package metrics
+
+import (
+	"bytes"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the Prometheus metrics of etcd.
+const defaultPath = "/metrics"
+
+var (
+	debugf = logp.MakeDebug("etcd-metrics")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("etcd", "metrics", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching etcd v3 metrics.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("etcd-metrics URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch fetches the metrics of the member.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	content, err := m.http.FetchContent(defaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := parseMetrics(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return eventMapping(metrics), nil
+}
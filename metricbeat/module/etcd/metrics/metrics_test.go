@@ -0,0 +1,104 @@
+// +build !integration
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /metrics endpoint of etcd 3.2.
+const response = `# HELP etcd_debugging_mvcc_db_total_size_in_bytes Total size of the underlying database in bytes.
+# TYPE etcd_debugging_mvcc_db_total_size_in_bytes gauge
+etcd_debugging_mvcc_db_total_size_in_bytes 24576
+# HELP etcd_debugging_mvcc_keys_total Total number of keys.
+# TYPE etcd_debugging_mvcc_keys_total gauge
+etcd_debugging_mvcc_keys_total 12
+# HELP etcd_disk_wal_fsync_duration_seconds The latency distributions of fsync called by wal.
+# TYPE etcd_disk_wal_fsync_duration_seconds histogram
+etcd_disk_wal_fsync_duration_seconds_bucket{le="0.001"} 2
+etcd_disk_wal_fsync_duration_seconds_bucket{le="+Inf"} 5
+etcd_disk_wal_fsync_duration_seconds_sum 0.0215
+etcd_disk_wal_fsync_duration_seconds_count 5
+# HELP etcd_network_client_grpc_received_bytes_total The total number of bytes received from grpc clients.
+# TYPE etcd_network_client_grpc_received_bytes_total counter
+etcd_network_client_grpc_received_bytes_total 3045
+# HELP etcd_network_peer_sent_bytes_total The total number of bytes sent to peers.
+# TYPE etcd_network_peer_sent_bytes_total counter
+etcd_network_peer_sent_bytes_total{To="729934363faa4a24"} 100
+etcd_network_peer_sent_bytes_total{To="b548c2511513015"} 50
+# HELP etcd_server_has_leader Whether or not a leader exists. 1 is existence, 0 is not.
+# TYPE etcd_server_has_leader gauge
+etcd_server_has_leader 1
+# HELP etcd_server_leader_changes_seen_total The number of leader changes seen.
+# TYPE etcd_server_leader_changes_seen_total counter
+etcd_server_leader_changes_seen_total 1
+# HELP etcd_server_proposals_committed_total The total number of consensus proposals committed.
+# TYPE etcd_server_proposals_committed_total gauge
+etcd_server_proposals_committed_total 45
+etcd_server_proposals_applied_total 45
+etcd_server_proposals_pending 0
+etcd_server_proposals_failed_total 0
+`
+
+func TestParseMetrics(t *testing.T) {
+	metrics, err := parseMetrics(strings.NewReader(response))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 24576.0, metrics["etcd_debugging_mvcc_db_total_size_in_bytes"])
+	assert.Equal(t, 7.0, metrics["etcd_disk_wal_fsync_duration_seconds_bucket"])
+	assert.Equal(t, 150.0, metrics["etcd_network_peer_sent_bytes_total"])
+
+	_, err = parseMetrics(strings.NewReader("etcd_server_has_leader"))
+	assert.Error(t, err)
+	_, err = parseMetrics(strings.NewReader(`etcd_server_has_leader{a="b" 1`))
+	assert.Error(t, err)
+}
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventFetcher(t, map[string]interface{}{
+		"module":     "etcd",
+		"metricsets": []string{"metrics"},
+		"hosts":      []string{server.URL},
+	})
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	serverMetrics := event["server"].(common.MapStr)
+	assert.Equal(t, true, serverMetrics["has_leader"])
+	assert.EqualValues(t, 45, serverMetrics["proposals"].(common.MapStr)["committed"])
+
+	mvcc := event["mvcc"].(common.MapStr)
+	assert.EqualValues(t, 24576, mvcc["db_size"].(common.MapStr)["bytes"])
+	assert.EqualValues(t, 12, mvcc["keys"])
+
+	fsync := event["disk"].(common.MapStr)["wal_fsync"].(common.MapStr)
+	assert.EqualValues(t, 5, fsync["count"])
+	assert.Equal(t, 0.0215, fsync["sum"].(common.MapStr)["sec"])
+
+	network := event["network"].(common.MapStr)
+	assert.EqualValues(t, 150, network["peer"].(common.MapStr)["sent"].(common.MapStr)["bytes"])
+	assert.Nil(t, network["peer"].(common.MapStr)["received"])
+}
@@ -0,0 +1,5 @@
+/*
+Package rabbitmq is a Metricbeat module for RabbitMQ servers. The metrics are
+read from the HTTP API of the management plugin.
+*/
+package rabbitmq
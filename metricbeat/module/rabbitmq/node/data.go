@@ -0,0 +1,63 @@
+package node
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type node struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Running       bool   `json:"running"`
+	Uptime        int64  `json:"uptime"`
+	Processors    int64  `json:"processors"`
+	RunQueue      int64  `json:"run_queue"`
+	MemUsed       int64  `json:"mem_used"`
+	MemLimit      int64  `json:"mem_limit"`
+	MemAlarm      bool   `json:"mem_alarm"`
+	DiskFree      int64  `json:"disk_free"`
+	DiskFreeLimit int64  `json:"disk_free_limit"`
+	DiskFreeAlarm bool   `json:"disk_free_alarm"`
+	FDUsed        int64  `json:"fd_used"`
+	FDTotal       int64  `json:"fd_total"`
+	SocketsUsed   int64  `json:"sockets_used"`
+	SocketsTotal  int64  `json:"sockets_total"`
+	ProcUsed      int64  `json:"proc_used"`
+	ProcTotal     int64  `json:"proc_total"`
+}
+
+func eventMapping(n node) common.MapStr {
+	return common.MapStr{
+		"name":       n.Name,
+		"type":       n.Type,
+		"running":    n.Running,
+		"uptime":     common.MapStr{"ms": n.Uptime},
+		"processors": n.Processors,
+		"run_queue":  n.RunQueue,
+		"mem": common.MapStr{
+			"used":  common.MapStr{"bytes": n.MemUsed},
+			"limit": common.MapStr{"bytes": n.MemLimit},
+			"alarm": n.MemAlarm,
+		},
+		"disk": common.MapStr{
+			"free": common.MapStr{
+				"bytes": n.DiskFree,
+				"limit": common.MapStr{"bytes": n.DiskFreeLimit},
+			},
+			"alarm": n.DiskFreeAlarm,
+		},
+		"fd": common.MapStr{
+			"used":  n.FDUsed,
+			"total": n.FDTotal,
+			// RabbitMQ refuses connections once all file descriptors are used
+			"alarm": n.FDTotal > 0 && n.FDUsed >= n.FDTotal,
+		},
+		"sockets": common.MapStr{
+			"used":  n.SocketsUsed,
+			"total": n.SocketsTotal,
+		},
+		"proc": common.MapStr{
+			"used":  n.ProcUsed,
+			"total": n.ProcTotal,
+		},
+	}
+}
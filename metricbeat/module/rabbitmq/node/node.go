@@ -0,0 +1,57 @@
+// Package node reads the memory, disk and file descriptor usage and alarms of
+// every node of a RabbitMQ cluster from the /api/nodes endpoint.
+package node
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the nodes of the management API.
+const defaultPath = "/api/nodes"
+
+var (
+	debugf = logp.MakeDebug("rabbitmq-node")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("rabbitmq", "node", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching RabbitMQ node metrics.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("rabbitmq-node URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch returns one event per node of the cluster.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var nodes []node
+	if err := m.http.FetchJSON(defaultPath, &nodes); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(nodes))
+	for _, n := range nodes {
+		events = append(events, eventMapping(n))
+	}
+	return events, nil
+}
@@ -0,0 +1,117 @@
+// +build !integration
+
+package node
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /api/nodes endpoint of a RabbitMQ
+// 3.6 cluster with two nodes, one of them raising alarms.
+const response = `[
+  {
+    "name": "rabbit@rabbit1",
+    "type": "disc",
+    "running": true,
+    "uptime": 1352380,
+    "processors": 4,
+    "run_queue": 0,
+    "mem_used": 58413216,
+    "mem_limit": 3344604364,
+    "mem_alarm": false,
+    "disk_free": 48618881024,
+    "disk_free_limit": 50000000,
+    "disk_free_alarm": false,
+    "fd_used": 27,
+    "fd_total": 1048576,
+    "sockets_used": 2,
+    "sockets_total": 943626,
+    "proc_used": 237,
+    "proc_total": 1048576
+  },
+  {
+    "name": "rabbit@rabbit2",
+    "type": "ram",
+    "running": true,
+    "mem_used": 3400000000,
+    "mem_limit": 3344604364,
+    "mem_alarm": true,
+    "disk_free_alarm": false,
+    "fd_used": 1024,
+    "fd_total": 1024
+  }
+]`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultPath {
+			w.WriteHeader(404)
+			return
+		}
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "guest" || pass != "guest" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"node"},
+		"hosts":      []string{server.URL},
+		"username":   "guest",
+		"password":   "guest",
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, events, 2) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), events[0].StringToPrint())
+
+	event := events[0]
+	assert.Equal(t, "rabbit@rabbit1", event["name"])
+	assert.EqualValues(t, 1352380, event["uptime"].(common.MapStr)["ms"])
+	mem := event["mem"].(common.MapStr)
+	assert.EqualValues(t, 58413216, mem["used"].(common.MapStr)["bytes"])
+	assert.Equal(t, false, mem["alarm"])
+	assert.Equal(t, false, event["fd"].(common.MapStr)["alarm"])
+	free := event["disk"].(common.MapStr)["free"].(common.MapStr)
+	assert.EqualValues(t, 50000000, free["limit"].(common.MapStr)["bytes"])
+
+	event = events[1]
+	assert.Equal(t, "ram", event["type"])
+	assert.Equal(t, true, event["mem"].(common.MapStr)["alarm"])
+	assert.Equal(t, true, event["fd"].(common.MapStr)["alarm"])
+}
+
+func TestFetchUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"error":"not_authorised","reason":"Login failed"}`))
+	}))
+	defer server.Close()
+
+	f := mbtest.NewEventsFetcher(t, map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"node"},
+		"hosts":      []string{server.URL},
+	})
+	_, err := f.Fetch()
+	assert.Error(t, err)
+}
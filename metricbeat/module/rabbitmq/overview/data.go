@@ -0,0 +1,59 @@
+package overview
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type rate struct {
+	Rate float64 `json:"rate"`
+}
+
+type overview struct {
+	Version      string `json:"rabbitmq_version"`
+	ClusterName  string `json:"cluster_name"`
+	Node         string `json:"node"`
+	ObjectTotals struct {
+		Connections int64 `json:"connections"`
+		Channels    int64 `json:"channels"`
+		Exchanges   int64 `json:"exchanges"`
+		Queues      int64 `json:"queues"`
+		Consumers   int64 `json:"consumers"`
+	} `json:"object_totals"`
+	QueueTotals struct {
+		Messages               int64 `json:"messages"`
+		MessagesReady          int64 `json:"messages_ready"`
+		MessagesUnacknowledged int64 `json:"messages_unacknowledged"`
+	} `json:"queue_totals"`
+	MessageStats struct {
+		PublishDetails    rate `json:"publish_details"`
+		DeliverGetDetails rate `json:"deliver_get_details"`
+		AckDetails        rate `json:"ack_details"`
+		ConfirmDetails    rate `json:"confirm_details"`
+		RedeliverDetails  rate `json:"redeliver_details"`
+	} `json:"message_stats"`
+}
+
+func eventMapping(o overview) common.MapStr {
+	totals := o.ObjectTotals
+	stats := o.MessageStats
+	return common.MapStr{
+		"version":      o.Version,
+		"cluster_name": o.ClusterName,
+		"node":         o.Node,
+		"connections":  common.MapStr{"count": totals.Connections},
+		"channels":     common.MapStr{"count": totals.Channels},
+		"exchanges":    common.MapStr{"count": totals.Exchanges},
+		"queues":       common.MapStr{"count": totals.Queues},
+		"consumers":    common.MapStr{"count": totals.Consumers},
+		"messages": common.MapStr{
+			"total":          common.MapStr{"count": o.QueueTotals.Messages},
+			"ready":          common.MapStr{"count": o.QueueTotals.MessagesReady},
+			"unacknowledged": common.MapStr{"count": o.QueueTotals.MessagesUnacknowledged},
+			"publish":        common.MapStr{"rate": stats.PublishDetails.Rate},
+			"deliver_get":    common.MapStr{"rate": stats.DeliverGetDetails.Rate},
+			"ack":            common.MapStr{"rate": stats.AckDetails.Rate},
+			"confirm":        common.MapStr{"rate": stats.ConfirmDetails.Rate},
+			"redeliver":      common.MapStr{"rate": stats.RedeliverDetails.Rate},
+		},
+	}
+}
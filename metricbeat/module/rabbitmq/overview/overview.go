@@ -0,0 +1,52 @@
+// Package overview reads the object totals and message rates of a RabbitMQ
+// cluster from the /api/overview endpoint.
+package overview
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the overview of the management API.
+const defaultPath = "/api/overview"
+
+var (
+	debugf = logp.MakeDebug("rabbitmq-overview")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("rabbitmq", "overview", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the RabbitMQ cluster overview.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("rabbitmq-overview URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch fetches the overview of the cluster.
+func (m *MetricSet) Fetch() (common.MapStr, error) {
+	var o overview
+	if err := m.http.FetchJSON(defaultPath, &o); err != nil {
+		return nil, err
+	}
+	return eventMapping(o), nil
+}
@@ -0,0 +1,84 @@
+// +build !integration
+
+package overview
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /api/overview endpoint of
+// RabbitMQ 3.6.
+const response = `{
+  "management_version": "3.6.6",
+  "rabbitmq_version": "3.6.6",
+  "cluster_name": "rabbit@rabbit1",
+  "erlang_version": "19.2",
+  "message_stats": {
+    "ack": 1204,
+    "ack_details": {"rate": 12.4},
+    "confirm": 1500,
+    "confirm_details": {"rate": 15.0},
+    "deliver_get": 1210,
+    "deliver_get_details": {"rate": 12.6},
+    "publish": 1500,
+    "publish_details": {"rate": 15.2},
+    "redeliver": 6,
+    "redeliver_details": {"rate": 0.2}
+  },
+  "queue_totals": {
+    "messages": 296,
+    "messages_details": {"rate": 2.8},
+    "messages_ready": 290,
+    "messages_unacknowledged": 6
+  },
+  "object_totals": {
+    "consumers": 2,
+    "queues": 3,
+    "exchanges": 8,
+    "connections": 4,
+    "channels": 5
+  },
+  "node": "rabbit@rabbit1"
+}`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultPath {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"overview"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventFetcher(t, config)
+	event, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), event.StringToPrint())
+
+	assert.Equal(t, "3.6.6", event["version"])
+	assert.EqualValues(t, 4, event["connections"].(common.MapStr)["count"])
+	assert.EqualValues(t, 5, event["channels"].(common.MapStr)["count"])
+	assert.EqualValues(t, 3, event["queues"].(common.MapStr)["count"])
+
+	messages := event["messages"].(common.MapStr)
+	assert.EqualValues(t, 296, messages["total"].(common.MapStr)["count"])
+	assert.Equal(t, 15.0, messages["confirm"].(common.MapStr)["rate"])
+}
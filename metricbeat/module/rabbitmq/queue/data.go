@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type rate struct {
+	Rate float64 `json:"rate"`
+}
+
+type queue struct {
+	Name                   string `json:"name"`
+	VHost                  string `json:"vhost"`
+	Node                   string `json:"node"`
+	State                  string `json:"state"`
+	Durable                bool   `json:"durable"`
+	AutoDelete             bool   `json:"auto_delete"`
+	Exclusive              bool   `json:"exclusive"`
+	Consumers              int64  `json:"consumers"`
+	Memory                 int64  `json:"memory"`
+	Messages               int64  `json:"messages"`
+	MessagesReady          int64  `json:"messages_ready"`
+	MessagesUnacknowledged int64  `json:"messages_unacknowledged"`
+	MessagesPersistent     int64  `json:"messages_persistent"`
+	MessageStats           struct {
+		PublishDetails    rate `json:"publish_details"`
+		DeliverGetDetails rate `json:"deliver_get_details"`
+		AckDetails        rate `json:"ack_details"`
+		RedeliverDetails  rate `json:"redeliver_details"`
+	} `json:"message_stats"`
+}
+
+func eventMapping(q queue) common.MapStr {
+	stats := q.MessageStats
+	return common.MapStr{
+		"name":        q.Name,
+		"vhost":       q.VHost,
+		"node":        q.Node,
+		"state":       q.State,
+		"durable":     q.Durable,
+		"auto_delete": q.AutoDelete,
+		"exclusive":   q.Exclusive,
+		"consumers":   common.MapStr{"count": q.Consumers},
+		"memory":      common.MapStr{"bytes": q.Memory},
+		"messages": common.MapStr{
+			"total":          common.MapStr{"count": q.Messages},
+			"ready":          common.MapStr{"count": q.MessagesReady},
+			"unacknowledged": common.MapStr{"count": q.MessagesUnacknowledged},
+			"persistent":     common.MapStr{"count": q.MessagesPersistent},
+			"publish":        common.MapStr{"rate": stats.PublishDetails.Rate},
+			"deliver_get":    common.MapStr{"rate": stats.DeliverGetDetails.Rate},
+			"ack":            common.MapStr{"rate": stats.AckDetails.Rate},
+			"redeliver":      common.MapStr{"rate": stats.RedeliverDetails.Rate},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+// Package queue reads the depth and message rates of every queue of a
+// RabbitMQ cluster from the /api/queues endpoint.
+package queue
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+// defaultPath is the path to the queues of all virtual hosts.
+const defaultPath = "/api/queues"
+
+var (
+	debugf = logp.MakeDebug("rabbitmq-queue")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("rabbitmq", "queue", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching RabbitMQ queue metrics.
+type MetricSet struct {
+	mb.BaseMetricSet
+	http *helper.HTTP
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	http, err := helper.NewHTTP(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("rabbitmq-queue URL=%s", http.URL(defaultPath))
+	return &MetricSet{
+		BaseMetricSet: base,
+		http:          http,
+	}, nil
+}
+
+// Fetch returns one event per queue.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var queues []queue
+	if err := m.http.FetchJSON(defaultPath, &queues); err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(queues))
+	for _, q := range queues {
+		events = append(events, eventMapping(q))
+	}
+	return events, nil
+}
@@ -0,0 +1,99 @@
+// +build !integration
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	mbtest "github.com/elastic/beats/metricbeat/mb/testing"
+)
+
+// response is a shortened response of the /api/queues endpoint of RabbitMQ
+// 3.6. The message stats of idle queues are not reported.
+const response = `[
+  {
+    "message_stats": {
+      "ack": 1204,
+      "ack_details": {"rate": 12.4},
+      "deliver_get": 1210,
+      "deliver_get_details": {"rate": 12.6},
+      "publish": 1500,
+      "publish_details": {"rate": 15.2},
+      "redeliver": 6,
+      "redeliver_details": {"rate": 0.2}
+    },
+    "messages": 296,
+    "messages_ready": 290,
+    "messages_unacknowledged": 6,
+    "messages_persistent": 296,
+    "consumers": 2,
+    "memory": 232720,
+    "state": "running",
+    "name": "orders",
+    "vhost": "/",
+    "durable": true,
+    "auto_delete": false,
+    "exclusive": false,
+    "node": "rabbit@rabbit1"
+  },
+  {
+    "messages": 0,
+    "consumers": 0,
+    "memory": 13872,
+    "state": "idle",
+    "name": "amq.gen-JzTY20BRgKO-HjmUJj0wLg",
+    "vhost": "staging",
+    "durable": false,
+    "auto_delete": true,
+    "exclusive": true,
+    "node": "rabbit@rabbit2"
+  }
+]`
+
+func TestFetchEventContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultPath {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	config := map[string]interface{}{
+		"module":     "rabbitmq",
+		"metricsets": []string{"queue"},
+		"hosts":      []string{server.URL},
+	}
+
+	f := mbtest.NewEventsFetcher(t, config)
+	events, err := f.Fetch()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, events, 2) {
+		t.FailNow()
+	}
+
+	t.Logf("%s/%s event: %+v", f.Module().Name(), f.Name(), events[0].StringToPrint())
+
+	event := events[0]
+	assert.Equal(t, "orders", event["name"])
+	assert.Equal(t, "/", event["vhost"])
+	messages := event["messages"].(common.MapStr)
+	assert.EqualValues(t, 290, messages["ready"].(common.MapStr)["count"])
+	assert.EqualValues(t, 6, messages["unacknowledged"].(common.MapStr)["count"])
+	assert.Equal(t, 15.2, messages["publish"].(common.MapStr)["rate"])
+	assert.Equal(t, 12.4, messages["ack"].(common.MapStr)["rate"])
+
+	event = events[1]
+	assert.Equal(t, "idle", event["state"])
+	assert.Equal(t, true, event["exclusive"])
+	assert.Equal(t, 0.0, event["messages"].(common.MapStr)["publish"].(common.MapStr)["rate"])
+}
@@ -0,0 +1,4 @@
+/*
+Package kernel_module reports the modules loaded into the Linux kernel.
+*/
+package kernel_module
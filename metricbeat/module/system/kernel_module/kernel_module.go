@@ -0,0 +1,60 @@
+// +build linux
+
+package kernel_module
+
+import (
+	"os"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "kernel_module", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that reports the loaded kernel modules.
+type MetricSet struct {
+	mb.BaseMetricSet
+	inventory *helper.Inventory
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		StatePeriod time.Duration `config:"state_period"`
+	}{
+		StatePeriod: 12 * time.Hour,
+	}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		inventory:     helper.NewInventory(config.StatePeriod),
+	}, nil
+}
+
+// Fetch reads the loaded kernel modules and returns their state or the
+// changes since the previous fetch.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	f, err := os.Open("/proc/modules")
+	if err != nil {
+		return nil, errors.Wrap(err, "kernel modules")
+	}
+	defer f.Close()
+
+	modules, err := parseModules(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "kernel modules")
+	}
+	return m.inventory.Update(modules, time.Now()), nil
+}
@@ -0,0 +1,41 @@
+package kernel_module
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// parseModules parses /proc/modules and returns the modules indexed by their
+// name.
+func parseModules(r io.Reader) (map[string]common.MapStr, error) {
+	modules := map[string]common.MapStr{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// name size instances dependents state address [taints]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		module := common.MapStr{
+			"name":  fields[0],
+			"state": strings.ToLower(fields[4]),
+		}
+		if size, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			module["size"] = size
+		}
+		if fields[3] != "-" {
+			module["used_by"] = strings.Split(strings.TrimSuffix(fields[3], ","), ",")
+		}
+		if len(fields) > 6 {
+			module["taints"] = strings.Trim(fields[6], "()")
+		}
+		modules[fields[0]] = module
+	}
+	return modules, scanner.Err()
+}
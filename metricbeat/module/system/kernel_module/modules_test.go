@@ -0,0 +1,41 @@
+// +build !integration
+
+package kernel_module
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const procModules = `nf_conntrack 106496 3 xt_conntrack,nf_nat,nf_conntrack_ipv4, Live 0xffffffffc0343000
+vboxdrv 454656 3 vboxnetadp,vboxnetflt, Live 0xffffffffc0a1d000 (OE)
+loop 28672 0 - Live 0xffffffffc0213000
+`
+
+func TestParseModules(t *testing.T) {
+	modules, err := parseModules(strings.NewReader(procModules))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]common.MapStr{
+		"nf_conntrack": {
+			"name":    "nf_conntrack",
+			"size":    uint64(106496),
+			"state":   "live",
+			"used_by": []string{"xt_conntrack", "nf_nat", "nf_conntrack_ipv4"},
+		},
+		"vboxdrv": {
+			"name":    "vboxdrv",
+			"size":    uint64(454656),
+			"state":   "live",
+			"used_by": []string{"vboxnetadp", "vboxnetflt"},
+			"taints":  "OE",
+		},
+		"loop": {
+			"name":  "loop",
+			"size":  uint64(28672),
+			"state": "live",
+		},
+	}, modules)
+}
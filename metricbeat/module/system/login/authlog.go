@@ -0,0 +1,93 @@
+package login
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var debugf = logp.MakeDebug("system-login")
+
+var (
+	// syslogRegexp matches the sshd messages of the auth log. The timestamp
+	// is either in the traditional syslog format or in RFC 3339.
+	syslogRegexp = regexp.MustCompile(`^(\w{3} +\d{1,2} \d{2}:\d{2}:\d{2}|\d{4}-\d{2}-\d{2}T\S+) \S+ sshd\[(\d+)\]: (.*)$`)
+
+	// sshdRegexp matches the messages sshd logs for accepted and failed
+	// authentication attempts.
+	sshdRegexp = regexp.MustCompile(`^(Accepted|Failed) (\S+) for (invalid user )?(\S*) from (\S+) port (\d+)`)
+)
+
+// splitLines returns the length of the complete lines at the start of b.
+func splitLines(b []byte) int {
+	return bytes.LastIndexByte(b, '\n') + 1
+}
+
+// authLogEvents returns the login events of the sshd messages of the auth
+// log. The year of traditional syslog timestamps is guessed based on now.
+func authLogEvents(b []byte, now time.Time) []common.MapStr {
+	var events []common.MapStr
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		m := syslogRegexp.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		sshd := sshdRegexp.FindSubmatch(m[3])
+		if sshd == nil {
+			continue
+		}
+
+		ts, err := parseSyslogTime(string(m[1]), now)
+		if err != nil {
+			debugf("Failed to parse timestamp of auth log line '%s': %v", line, err)
+			continue
+		}
+
+		event := common.MapStr{
+			"@timestamp": common.Time(ts),
+			"action":     "login",
+			"outcome":    "success",
+			"source":     "auth",
+			"method":     string(sshd[2]),
+			"user":       string(sshd[4]),
+			"ip":         string(sshd[5]),
+		}
+		if string(sshd[1]) == "Failed" {
+			event["outcome"] = "failure"
+		}
+		if len(sshd[3]) > 0 {
+			event["invalid_user"] = true
+		}
+		if pid, err := strconv.ParseInt(string(m[2]), 10, 32); err == nil {
+			event["pid"] = int32(pid)
+		}
+		if port, err := strconv.ParseUint(string(sshd[6]), 10, 16); err == nil {
+			event["port"] = uint16(port)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// parseSyslogTime parses a timestamp in RFC 3339 or in the traditional syslog
+// format, which has no year. The year is set so that the time is not more
+// than a day after now.
+func parseSyslogTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.Stamp, s, now.Location())
+	if err != nil {
+		return t, err
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, nil
+}
@@ -0,0 +1,6 @@
+/*
+Package login reports the logins to the host. Successful logins and logouts
+are read from the wtmp file, failed logins from the btmp file and the logins
+authenticated by sshd from the auth log.
+*/
+package login
@@ -0,0 +1,95 @@
+// +build linux
+
+package login
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "login", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that reports the logins to the host.
+type MetricSet struct {
+	mb.BaseMetricSet
+	wtmpFiles []string
+	btmpFiles []string
+	authFiles []string
+	reader    *tailReader
+	ttys      map[string]string
+	started   bool
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		WtmpFiles []string `config:"login_wtmp_files"`
+		BtmpFiles []string `config:"login_btmp_files"`
+		AuthFiles []string `config:"login_auth_files"`
+	}{
+		WtmpFiles: []string{"/var/log/wtmp"},
+		BtmpFiles: []string{"/var/log/btmp"},
+		AuthFiles: []string{"/var/log/auth.log", "/var/log/secure"},
+	}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		wtmpFiles:     config.WtmpFiles,
+		btmpFiles:     config.BtmpFiles,
+		authFiles:     config.AuthFiles,
+		reader:        newTailReader(),
+		ttys:          map[string]string{},
+	}, nil
+}
+
+// Fetch returns an event for each login, failed login and logout since the
+// previous fetch. The first fetch only records the current size of the files.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	if !m.started {
+		m.started = true
+		for _, files := range [][]string{m.wtmpFiles, m.btmpFiles, m.authFiles} {
+			for _, path := range files {
+				if err := m.reader.skip(path); err != nil {
+					return nil, errors.Wrapf(err, "reading '%s'", path)
+				}
+			}
+		}
+		return nil, nil
+	}
+
+	var events []common.MapStr
+	for _, path := range m.wtmpFiles {
+		b, err := m.reader.read(path, splitUtmp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading '%s'", path)
+		}
+		events = append(events, utmpEvents(parseUtmp(b), false, m.ttys)...)
+	}
+	for _, path := range m.btmpFiles {
+		b, err := m.reader.read(path, splitUtmp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading '%s'", path)
+		}
+		events = append(events, utmpEvents(parseUtmp(b), true, m.ttys)...)
+	}
+	for _, path := range m.authFiles {
+		b, err := m.reader.read(path, splitLines)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading '%s'", path)
+		}
+		events = append(events, authLogEvents(b, time.Now())...)
+	}
+	return events, nil
+}
@@ -0,0 +1,195 @@
+// +build !integration
+
+package login
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func utmp(typ int16, pid int32, line, user, host string, sec int32, addr net.IP) []byte {
+	b := make([]byte, utmpSize)
+	le := binary.LittleEndian
+	le.PutUint16(b[offType:], uint16(typ))
+	le.PutUint32(b[offPid:], uint32(pid))
+	copy(b[offLine:offLine+lineLen], line)
+	copy(b[offUser:offUser+userLen], user)
+	copy(b[offHost:offHost+hostLen], host)
+	le.PutUint32(b[offTvSec:], uint32(sec))
+	if ip4 := addr.To4(); ip4 != nil {
+		copy(b[offAddr:], ip4)
+	} else {
+		copy(b[offAddr:], addr)
+	}
+	return b
+}
+
+func TestUtmpEvents(t *testing.T) {
+	var wtmp []byte
+	wtmp = append(wtmp, utmp(utmpUserProcess, 100, "pts/0", "alice", "10.0.0.1", 1464000000, net.ParseIP("10.0.0.1"))...)
+	wtmp = append(wtmp, utmp(utmpUserProcess, 101, "pts/1", "bob", "host.example.com", 1464000001, net.ParseIP("2001:db8::1"))...)
+	wtmp = append(wtmp, utmp(utmpDeadProcess, 100, "pts/0", "", "", 1464000002, nil)...)
+	wtmp = append(wtmp, utmp(utmpBootTime, 0, "~", "reboot", "4.4.0", 1464000003, nil)...)
+
+	// The partial record is not parsed.
+	assert.Equal(t, len(wtmp), splitUtmp(append(wtmp, 0, 0, 0)))
+
+	ttys := map[string]string{}
+	events := utmpEvents(parseUtmp(wtmp), false, ttys)
+	assert.Equal(t, []common.MapStr{
+		{
+			"@timestamp": common.Time(time.Unix(1464000000, 0)),
+			"action":     "login",
+			"outcome":    "success",
+			"source":     "wtmp",
+			"pid":        int32(100),
+			"user":       "alice",
+			"tty":        "pts/0",
+			"hostname":   "10.0.0.1",
+			"ip":         "10.0.0.1",
+		},
+		{
+			"@timestamp": common.Time(time.Unix(1464000001, 0)),
+			"action":     "login",
+			"outcome":    "success",
+			"source":     "wtmp",
+			"pid":        int32(101),
+			"user":       "bob",
+			"tty":        "pts/1",
+			"hostname":   "host.example.com",
+			"ip":         "2001:db8::1",
+		},
+		{
+			"@timestamp": common.Time(time.Unix(1464000002, 0)),
+			"action":     "logout",
+			"outcome":    "success",
+			"source":     "wtmp",
+			"pid":        int32(100),
+			"user":       "alice",
+			"tty":        "pts/0",
+		},
+	}, events)
+	assert.Empty(t, ttys)
+
+	btmp := utmp(utmpLoginProcess, 200, "ssh:notty", "root", "192.0.2.7", 1464000004, net.ParseIP("192.0.2.7"))
+	events = utmpEvents(parseUtmp(btmp), true, ttys)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "login", events[0]["action"])
+		assert.Equal(t, "failure", events[0]["outcome"])
+		assert.Equal(t, "btmp", events[0]["source"])
+		assert.Equal(t, "root", events[0]["user"])
+		assert.Equal(t, "192.0.2.7", events[0]["ip"])
+	}
+}
+
+func TestAuthLogEvents(t *testing.T) {
+	log := []byte(`May 23 08:05:34 host sshd[1234]: Accepted publickey for alice from 10.0.0.1 port 52144 ssh2: RSA SHA256:abc
+May 23 08:05:40 host sshd[1240]: Invalid user admin from 192.0.2.7 port 40022
+May 23 08:05:40 host sshd[1240]: Failed password for invalid user admin from 192.0.2.7 port 40022 ssh2
+2016-05-23T08:06:00.123+02:00 host sshd[1250]: Failed password for root from 2001:db8::7 port 40100 ssh2
+May 23 08:07:00 host CRON[1300]: pam_unix(cron:session): session opened for user root by (uid=0)
+`)
+	now := time.Date(2016, 5, 23, 9, 0, 0, 0, time.UTC)
+
+	events := authLogEvents(log, now)
+	if !assert.Len(t, events, 3) {
+		return
+	}
+
+	assert.Equal(t, common.MapStr{
+		"@timestamp": common.Time(time.Date(2016, 5, 23, 8, 5, 34, 0, time.UTC)),
+		"action":     "login",
+		"outcome":    "success",
+		"source":     "auth",
+		"method":     "publickey",
+		"user":       "alice",
+		"ip":         "10.0.0.1",
+		"port":       uint16(52144),
+		"pid":        int32(1234),
+	}, events[0])
+
+	assert.Equal(t, "failure", events[1]["outcome"])
+	assert.Equal(t, "password", events[1]["method"])
+	assert.Equal(t, "admin", events[1]["user"])
+	assert.Equal(t, true, events[1]["invalid_user"])
+
+	assert.Equal(t, "failure", events[2]["outcome"])
+	assert.Equal(t, "2001:db8::7", events[2]["ip"])
+	assert.Equal(t, common.Time(time.Date(2016, 5, 23, 6, 6, 0, 123000000, time.UTC)), common.Time(time.Time(events[2]["@timestamp"].(common.Time)).UTC()))
+}
+
+func TestParseSyslogTime(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 30, 0, 0, time.UTC)
+
+	ts, err := parseSyslogTime("Dec 31 23:59:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2015, 12, 31, 23, 59, 0, 0, time.UTC), ts)
+
+	ts, err = parseSyslogTime("Jan  1 00:10:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2016, 1, 1, 0, 10, 0, 0, time.UTC), ts)
+}
+
+func TestTailReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "auth.log")
+	write := func(data string, flag int) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newTailReader()
+	assert.NoError(t, r.skip(path))
+	b, err := r.read(path, splitLines)
+	assert.NoError(t, err)
+	assert.Empty(t, b)
+
+	// Files created after the first fetch are read from the start.
+	write("old\n", os.O_APPEND)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "old\n", string(b))
+
+	write("first\nsec", os.O_APPEND)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "first\n", string(b))
+
+	write("ond\n", os.O_APPEND)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "second\n", string(b))
+
+	// Truncated files are read from the start.
+	write("new\n", os.O_TRUNC)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "new\n", string(b))
+
+	// Rotated files are read from the start.
+	assert.NoError(t, os.Rename(path, path+".1"))
+	write("rotated\n", os.O_APPEND)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "rotated\n", string(b))
+
+	r = newTailReader()
+	assert.NoError(t, r.skip(path))
+	write("appended\n", os.O_APPEND)
+	b, _ = r.read(path, splitLines)
+	assert.Equal(t, "appended\n", string(b))
+}
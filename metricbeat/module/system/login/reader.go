@@ -0,0 +1,78 @@
+package login
+
+import (
+	"io"
+	"os"
+)
+
+type fileState struct {
+	info   os.FileInfo
+	offset int64
+}
+
+// tailReader reads the data appended to files since the previous read. Files
+// that were truncated or replaced, for example by logrotate, are read from
+// the start.
+type tailReader struct {
+	files map[string]*fileState
+}
+
+func newTailReader() *tailReader {
+	return &tailReader{files: map[string]*fileState{}}
+}
+
+// skip sets the offset of the file to its current size, so that only data
+// appended after the call is read. Files that do not exist are read from the
+// start once they are created.
+func (r *tailReader) skip(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	r.files[path] = &fileState{info: info, offset: info.Size()}
+	return nil
+}
+
+// read returns the data appended to the file since the previous read. split
+// returns the number of bytes at the start of the data that form complete
+// records, the remaining bytes are read again by the next call. Files that
+// do not exist are not an error.
+func (r *tailReader) read(path string, split func([]byte) int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	state := r.files[path]
+	if state == nil || !os.SameFile(state.info, info) || info.Size() < state.offset {
+		state = &fileState{}
+	}
+	state.info = info
+	r.files[path] = state
+
+	if info.Size() == state.offset {
+		return nil, nil
+	}
+
+	buf := make([]byte, info.Size()-state.offset)
+	n, err := f.ReadAt(buf, state.offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buf = buf[:split(buf[:n])]
+	state.offset += int64(len(buf))
+	return buf, nil
+}
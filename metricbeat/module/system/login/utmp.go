@@ -0,0 +1,152 @@
+package login
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Types of utmp records.
+const (
+	utmpBootTime     = 2
+	utmpLoginProcess = 6
+	utmpUserProcess  = 7
+	utmpDeadProcess  = 8
+)
+
+// Layout of struct utmp on Linux. The layout is the same on 32 and 64-bit
+// architectures. Integers are in host byte order, which is assumed to be
+// little-endian.
+const (
+	offType    = 0   // int16
+	offPid     = 4   // int32
+	offLine    = 8   // [32]byte
+	offUser    = 44  // [32]byte
+	offHost    = 76  // [256]byte
+	offTvSec   = 340 // int32
+	offTvUsec  = 344 // int32
+	offAddr    = 348 // [4]int32
+	utmpSize   = 384
+	lineLen    = 32
+	userLen    = 32
+	hostLen    = 256
+	addrLength = 16
+)
+
+// utmpRecord is a record of the wtmp or btmp file.
+type utmpRecord struct {
+	Type int16
+	Pid  int32
+	Line string
+	User string
+	Host string
+	Time time.Time
+	Addr net.IP
+}
+
+// splitUtmp returns the length of the complete records at the start of b.
+func splitUtmp(b []byte) int {
+	return len(b) - len(b)%utmpSize
+}
+
+func parseUtmp(b []byte) []utmpRecord {
+	le := binary.LittleEndian
+
+	var records []utmpRecord
+	for ; len(b) >= utmpSize; b = b[utmpSize:] {
+		r := utmpRecord{
+			Type: int16(le.Uint16(b[offType:])),
+			Pid:  int32(le.Uint32(b[offPid:])),
+			Line: cString(b[offLine : offLine+lineLen]),
+			User: cString(b[offUser : offUser+userLen]),
+			Host: cString(b[offHost : offHost+hostLen]),
+			Time: time.Unix(int64(int32(le.Uint32(b[offTvSec:]))), int64(int32(le.Uint32(b[offTvUsec:])))*int64(time.Microsecond)),
+			Addr: utmpAddr(b[offAddr : offAddr+addrLength]),
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// utmpAddr returns the IPv4 or IPv6 address of the remote host or nil if it
+// is not set. IPv4 addresses are stored in the first 4 bytes.
+func utmpAddr(b []byte) net.IP {
+	if bytes.Equal(b, make([]byte, addrLength)) {
+		return nil
+	}
+	if bytes.Equal(b[4:], make([]byte, addrLength-4)) {
+		return net.IPv4(b[0], b[1], b[2], b[3])
+	}
+	ip := make(net.IP, addrLength)
+	copy(ip, b)
+	return ip
+}
+
+// utmpEvents returns the login events of the records of the wtmp file, or of
+// the btmp file if failed is set. The users of logout records, which are not
+// set in wtmp, are looked up by the terminal in ttys, which is updated by
+// the login records.
+func utmpEvents(records []utmpRecord, failed bool, ttys map[string]string) []common.MapStr {
+	var events []common.MapStr
+	for _, r := range records {
+		event := common.MapStr{
+			"@timestamp": common.Time(r.Time),
+			"pid":        r.Pid,
+		}
+
+		switch {
+		case failed && (r.Type == utmpLoginProcess || r.Type == utmpUserProcess):
+			event["action"] = "login"
+			event["outcome"] = "failure"
+			event["source"] = "btmp"
+		case failed:
+			continue
+		case r.Type == utmpUserProcess:
+			event["action"] = "login"
+			event["outcome"] = "success"
+			event["source"] = "wtmp"
+			ttys[r.Line] = r.User
+		case r.Type == utmpDeadProcess && r.Line != "":
+			event["action"] = "logout"
+			event["outcome"] = "success"
+			event["source"] = "wtmp"
+			if r.User == "" {
+				r.User = ttys[r.Line]
+			}
+			delete(ttys, r.Line)
+		case r.Type == utmpBootTime:
+			// All sessions end when the host reboots.
+			for tty := range ttys {
+				delete(ttys, tty)
+			}
+			continue
+		default:
+			continue
+		}
+
+		if r.User != "" {
+			event["user"] = r.User
+		}
+		if r.Line != "" {
+			event["tty"] = r.Line
+		}
+		if r.Host != "" {
+			event["hostname"] = r.Host
+		}
+		if r.Addr != nil {
+			event["ip"] = r.Addr.String()
+		}
+		events = append(events, event)
+	}
+	return events
+}
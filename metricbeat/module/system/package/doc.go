@@ -0,0 +1,5 @@
+/*
+Package pkg reports the packages installed by the dpkg, rpm and Homebrew
+package managers.
+*/
+package pkg
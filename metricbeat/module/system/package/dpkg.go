@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+const dpkgStatusFile = "/var/lib/dpkg/status"
+
+// parseDpkgStatus returns the installed packages of the dpkg status file.
+func parseDpkgStatus(r io.Reader) ([]common.MapStr, error) {
+	var packages []common.MapStr
+
+	fields := map[string]string{}
+	flush := func() {
+		if strings.HasSuffix(fields["Status"], " installed") && fields["Package"] != "" {
+			p := common.MapStr{
+				"manager": "dpkg",
+				"name":    fields["Package"],
+				"version": fields["Version"],
+				"arch":    fields["Architecture"],
+			}
+			if kb, err := strconv.ParseUint(fields["Installed-Size"], 10, 64); err == nil {
+				p["size"] = kb * 1024
+			}
+			packages = append(packages, p)
+		}
+		fields = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		// Continuation lines of multi-line fields start with a space.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			fields[line[:i]] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	flush()
+
+	return packages, scanner.Err()
+}
+
+func listDpkg() ([]common.MapStr, error) {
+	f, err := os.Open(dpkgStatusFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDpkgStatus(f)
+}
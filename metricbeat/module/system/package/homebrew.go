@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+var debugf = logp.MakeDebug("system-package")
+
+// homebrewCellars are the directories Homebrew installs the packages to.
+var homebrewCellars = []string{"/usr/local/Cellar", "/opt/homebrew/Cellar"}
+
+// listHomebrew returns the packages installed in the cellar. Each package is
+// a directory containing a directory per installed version. If multiple
+// versions are installed, the last version in lexical order is reported.
+func listHomebrew(cellar string) ([]common.MapStr, error) {
+	entries, err := ioutil.ReadDir(cellar)
+	if err != nil {
+		return nil, err
+	}
+
+	var packages []common.MapStr
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		versions, err := ioutil.ReadDir(filepath.Join(cellar, entry.Name()))
+		if err != nil {
+			debugf("Failed to read versions of Homebrew package '%s': %v", entry.Name(), err)
+			continue
+		}
+		var names []string
+		for _, v := range versions {
+			if v.IsDir() {
+				names = append(names, v.Name())
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		packages = append(packages, common.MapStr{
+			"manager": "homebrew",
+			"name":    entry.Name(),
+			"version": names[len(names)-1],
+		})
+	}
+	return packages, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
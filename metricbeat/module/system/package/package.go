@@ -0,0 +1,88 @@
+// +build darwin linux
+
+package pkg
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "package", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that reports the installed packages.
+type MetricSet struct {
+	mb.BaseMetricSet
+	inventory *helper.Inventory
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		StatePeriod time.Duration `config:"state_period"`
+	}{
+		StatePeriod: 12 * time.Hour,
+	}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		inventory:     helper.NewInventory(config.StatePeriod),
+	}, nil
+}
+
+// Fetch lists the packages of all package managers found on the host and
+// returns the state of the packages or the changes since the previous fetch.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	var packages []common.MapStr
+
+	if exists(dpkgStatusFile) {
+		p, err := listDpkg()
+		if err != nil {
+			return nil, errors.Wrap(err, "dpkg packages")
+		}
+		packages = append(packages, p...)
+	}
+
+	if rpm, err := exec.LookPath("rpm"); err == nil && exists("/var/lib/rpm") {
+		p, err := listRPM(rpm)
+		if err != nil {
+			return nil, errors.Wrap(err, "rpm packages")
+		}
+		packages = append(packages, p...)
+	}
+
+	for _, cellar := range homebrewCellars {
+		if !exists(cellar) {
+			continue
+		}
+		p, err := listHomebrew(cellar)
+		if err != nil {
+			return nil, errors.Wrap(err, "Homebrew packages")
+		}
+		packages = append(packages, p...)
+	}
+
+	items := make(map[string]common.MapStr, len(packages))
+	for _, p := range packages {
+		key := []string{p["manager"].(string), p["name"].(string)}
+		if arch, ok := p["arch"].(string); ok {
+			key = append(key, arch)
+		}
+		items[strings.Join(key, "/")] = p
+	}
+	return m.inventory.Update(items, time.Now()), nil
+}
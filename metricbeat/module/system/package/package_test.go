@@ -0,0 +1,80 @@
+// +build !integration
+
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const dpkgStatus = `Package: libc6
+Status: install ok installed
+Priority: required
+Installed-Size: 10680
+Architecture: amd64
+Version: 2.23-0ubuntu3
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries that are used by nearly all programs on
+ the system.
+
+Package: telnet
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 0.17-40
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2016d-0ubuntu0.16.04`
+
+func TestParseDpkgStatus(t *testing.T) {
+	packages, err := parseDpkgStatus(strings.NewReader(dpkgStatus))
+	assert.NoError(t, err)
+	assert.Equal(t, []common.MapStr{
+		{"manager": "dpkg", "name": "libc6", "version": "2.23-0ubuntu3", "arch": "amd64", "size": uint64(10680 * 1024)},
+		{"manager": "dpkg", "name": "tzdata", "version": "2016d-0ubuntu0.16.04", "arch": "all"},
+	}, packages)
+}
+
+func TestParseRPM(t *testing.T) {
+	output := "bash\t4.2.46-19.el7\tx86_64\t3663714\n" +
+		"openssl\t1:1.0.1e-51.el7_2.5\tx86_64\t1603378\n" +
+		"gpg-pubkey\tf4a80eb5-53a7ff4b\t(none)\t0\n" +
+		"invalid\n"
+
+	assert.Equal(t, []common.MapStr{
+		{"manager": "rpm", "name": "bash", "version": "4.2.46-19.el7", "arch": "x86_64", "size": uint64(3663714)},
+		{"manager": "rpm", "name": "openssl", "version": "1:1.0.1e-51.el7_2.5", "arch": "x86_64", "size": uint64(1603378)},
+		{"manager": "rpm", "name": "gpg-pubkey", "version": "f4a80eb5-53a7ff4b", "size": uint64(0)},
+	}, parseRPM([]byte(output)))
+}
+
+func TestListHomebrew(t *testing.T) {
+	cellar, err := ioutil.TempDir("", "Cellar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cellar)
+
+	for _, dir := range []string{"git/2.8.2", "git/2.8.3", "wget/1.17.1"} {
+		if err := os.MkdirAll(filepath.Join(cellar, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(cellar, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	packages, err := listHomebrew(cellar)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.MapStr{
+		{"manager": "homebrew", "name": "git", "version": "2.8.3"},
+		{"manager": "homebrew", "name": "wget", "version": "1.17.1"},
+	}, packages)
+}
@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+const rpmQueryFormat = `%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\t%{ARCH}\t%{SIZE}\n`
+
+// parseRPM parses the output of rpm -qa with the rpmQueryFormat.
+func parseRPM(output []byte) []common.MapStr {
+	var packages []common.MapStr
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 {
+			continue
+		}
+
+		p := common.MapStr{
+			"manager": "rpm",
+			"name":    fields[0],
+			"version": fields[1],
+		}
+		if fields[2] != "(none)" {
+			p["arch"] = fields[2]
+		}
+		if size, err := strconv.ParseUint(fields[3], 10, 64); err == nil {
+			p["size"] = size
+		}
+		packages = append(packages, p)
+	}
+	return packages
+}
+
+func listRPM(rpm string) ([]common.MapStr, error) {
+	output, err := exec.Command(rpm, "-qa", "--queryformat", rpmQueryFormat).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseRPM(output), nil
+}
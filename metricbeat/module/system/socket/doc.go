@@ -0,0 +1,5 @@
+/*
+Package socket reports the TCP and UDP sockets listening on the host and the
+processes they belong to.
+*/
+package socket
@@ -0,0 +1,187 @@
+package socket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// States of the sockets in /proc/net.
+const (
+	tcpListen = "0A"
+	udpClose  = "07"
+)
+
+// socket is a listening socket read from /proc/net.
+type socket struct {
+	Transport string
+	IP        net.IP
+	Port      uint16
+	UID       uint32
+	Inode     uint64
+}
+
+// parseProcNet parses the sockets of /proc/net/{tcp,tcp6,udp,udp6} and
+// returns the TCP sockets in the listen state and the unconnected UDP
+// sockets.
+func parseProcNet(r io.Reader, transport string) ([]socket, error) {
+	var sockets []socket
+
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		state := fields[3]
+		switch {
+		case transport == "tcp" && state != tcpListen:
+			continue
+		case transport == "udp" && (state != udpClose || !strings.HasSuffix(fields[2], ":0000")):
+			continue
+		}
+
+		ip, port, err := parseAddr(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		uid, _ := strconv.ParseUint(fields[7], 10, 32)
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		sockets = append(sockets, socket{
+			Transport: transport,
+			IP:        ip,
+			Port:      port,
+			UID:       uint32(uid),
+			Inode:     inode,
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// parseAddr parses an address of /proc/net. The address is written as 32-bit
+// words in host byte order, which is assumed to be little-endian, the port in
+// network byte order.
+func parseAddr(s string) (net.IP, uint16, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("invalid address '%s'", s)
+	}
+
+	b, err := hex.DecodeString(parts[0])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address '%s'", s)
+	}
+	ip := make(net.IP, len(b))
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.LittleEndian.Uint32(b[i:]))
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid address '%s'", s)
+	}
+	return ip, uint16(port), nil
+}
+
+// process is the process a socket belongs to.
+type process struct {
+	Pid  int
+	Name string
+}
+
+// socketProcesses returns the processes owning the sockets, indexed by the
+// inode of the socket. Processes that exit while the file descriptors are
+// read are skipped.
+func socketProcesses(procfs string) map[uint64]process {
+	procs := map[uint64]process{}
+
+	dirs, err := ioutil.ReadDir(procfs)
+	if err != nil {
+		return procs
+	}
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(dir.Name())
+		if err != nil {
+			continue
+		}
+
+		fds, err := ioutil.ReadDir(filepath.Join(procfs, dir.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+
+		var name string
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(procfs, dir.Name(), "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(link[len("socket:["):], "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if name == "" {
+				comm, _ := ioutil.ReadFile(filepath.Join(procfs, dir.Name(), "comm"))
+				name = strings.TrimSpace(string(comm))
+			}
+			procs[inode] = process{Pid: pid, Name: name}
+		}
+	}
+	return procs
+}
+
+// listSockets returns the listening sockets of the host, indexed by the
+// transport, address and port.
+func listSockets(procfs string) (map[string]common.MapStr, error) {
+	var sockets []socket
+	for _, file := range []struct{ name, transport string }{
+		{"tcp", "tcp"}, {"tcp6", "tcp"}, {"udp", "udp"}, {"udp6", "udp"},
+	} {
+		f, err := os.Open(filepath.Join(procfs, "net", file.name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// IPv6 is disabled.
+				continue
+			}
+			return nil, err
+		}
+		s, err := parseProcNet(f, file.transport)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		sockets = append(sockets, s...)
+	}
+
+	procs := socketProcesses(procfs)
+
+	items := make(map[string]common.MapStr, len(sockets))
+	for _, s := range sockets {
+		item := common.MapStr{
+			"transport": s.Transport,
+			"ip":        s.IP.String(),
+			"port":      s.Port,
+			"uid":       s.UID,
+		}
+		if p, found := procs[s.Inode]; found {
+			item["pid"] = p.Pid
+			item["process"] = p.Name
+		}
+		items[fmt.Sprintf("%s/%s/%d", s.Transport, s.IP, s.Port)] = item
+	}
+	return items, nil
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package socket
+
+import (
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/helper"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("system", "socket", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that reports the listening sockets.
+type MetricSet struct {
+	mb.BaseMetricSet
+	inventory *helper.Inventory
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		StatePeriod time.Duration `config:"state_period"`
+	}{
+		StatePeriod: 12 * time.Hour,
+	}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		inventory:     helper.NewInventory(config.StatePeriod),
+	}, nil
+}
+
+// Fetch reads the listening sockets and returns their state or the changes
+// since the previous fetch.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	items, err := listSockets("/proc")
+	if err != nil {
+		return nil, errors.Wrap(err, "listening sockets")
+	}
+	return m.inventory.Update(items, time.Now()), nil
+}
@@ -0,0 +1,84 @@
+// +build !integration
+
+package socket
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000   120        0 17395 1 0000000000000000 100 0 0 10 0
+   1: 0F02000A:0016 0202000A:D1C4 01 00000000:00000000 02:000A7A5B 00000000     0        0 18741 4 0000000000000000 20 4 31 10 -1
+`
+
+const procNetUDP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000000000000000000000000000:0035 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 12345 2 0000000000000000 0
+  101: B80D0120000000000000000001000000:1F90 B80D0120000000000000000002000000:0050 01 00000000:00000000 00:00000000 00000000     0        0 12346 2 0000000000000000 0
+`
+
+func TestListSockets(t *testing.T) {
+	procfs, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(procfs)
+
+	mustWrite := func(path, data string) {
+		path = filepath.Join(procfs, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite("net/tcp", procNetTCP)
+	mustWrite("net/udp6", procNetUDP6)
+	mustWrite("42/comm", "named\n")
+	if err := os.MkdirAll(filepath.Join(procfs, "42", "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("socket:[12345]", filepath.Join(procfs, "42", "fd", "3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/dev/null", filepath.Join(procfs, "42", "fd", "0")); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := listSockets(procfs)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]common.MapStr{
+		"tcp/127.0.0.1/3306": {
+			"transport": "tcp",
+			"ip":        "127.0.0.1",
+			"port":      uint16(3306),
+			"uid":       uint32(120),
+		},
+		"udp/::/53": {
+			"transport": "udp",
+			"ip":        "::",
+			"port":      uint16(53),
+			"uid":       uint32(0),
+			"pid":       42,
+			"process":   "named",
+		},
+	}, items)
+}
+
+func TestParseAddr(t *testing.T) {
+	ip, port, err := parseAddr("B80D0120000000000000000001000000:1F90")
+	assert.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", ip.String())
+	assert.Equal(t, uint16(8080), port)
+
+	_, _, err = parseAddr("0100007F")
+	assert.Error(t, err)
+	_, _, err = parseAddr("01007F:0016")
+	assert.Error(t, err)
+}
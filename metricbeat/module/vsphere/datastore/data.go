@@ -0,0 +1,38 @@
+package datastore
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// eventMapping creates the event of the datastore. The capacity is not
+// reported while the datastore is inaccessible.
+func eventMapping(datastore vsphere.Object) common.MapStr {
+	event := common.MapStr{
+		"name":   datastore.Name(),
+		"type":   datastore.Properties["summary.type"],
+		"status": datastore.Properties["overallStatus"],
+	}
+
+	accessible, _ := datastore.Bool("summary.accessible")
+	event["accessible"] = accessible
+	if !accessible {
+		return event
+	}
+
+	total, hasTotal := datastore.Int("summary.capacity")
+	free, hasFree := datastore.Int("summary.freeSpace")
+	if hasTotal && hasFree {
+		used := total - free
+		event.Put("total.bytes", total)
+		event.Put("free.bytes", free)
+		event.Put("used.bytes", used)
+		if total > 0 {
+			event.Put("used.pct", float64(used)/float64(total))
+		}
+	}
+	if uncommitted, found := datastore.Int("summary.uncommitted"); found {
+		event.Put("uncommitted.bytes", uncommitted)
+	}
+	return event
+}
@@ -0,0 +1,65 @@
+// Package datastore reads the capacity and usage of the datastores of a
+// vCenter Server or ESXi host.
+package datastore
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// properties are the properties of the datastores read from the inventory.
+var properties = []string{
+	"name",
+	"overallStatus",
+	"summary.type",
+	"summary.accessible",
+	"summary.capacity",
+	"summary.freeSpace",
+	"summary.uncommitted",
+}
+
+var (
+	debugf = logp.MakeDebug("vsphere-datastore")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("vsphere", "datastore", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the metrics of the datastores.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *vsphere.Client
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := vsphere.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("vsphere-datastore URL=%s", client.URL())
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch fetches one event per datastore.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	datastores, err := m.client.RetrieveObjects("Datastore", properties)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(datastores))
+	for _, datastore := range datastores {
+		events = append(events, eventMapping(datastore))
+	}
+	return events, nil
+}
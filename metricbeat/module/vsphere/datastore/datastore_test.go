@@ -0,0 +1,63 @@
+// +build !integration
+
+package datastore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+func TestEventMapping(t *testing.T) {
+	tests := []struct {
+		properties map[string]string
+		event      common.MapStr
+	}{
+		{
+			map[string]string{
+				"name":                "datastore1",
+				"overallStatus":       "green",
+				"summary.type":        "VMFS",
+				"summary.accessible":  "true",
+				"summary.capacity":    "1000000000",
+				"summary.freeSpace":   "250000000",
+				"summary.uncommitted": "100000000",
+			},
+			common.MapStr{
+				"name":        "datastore1",
+				"status":      "green",
+				"type":        "VMFS",
+				"accessible":  true,
+				"total":       common.MapStr{"bytes": int64(1000000000)},
+				"free":        common.MapStr{"bytes": int64(250000000)},
+				"used":        common.MapStr{"bytes": int64(750000000), "pct": 0.75},
+				"uncommitted": common.MapStr{"bytes": int64(100000000)},
+			},
+		},
+		{
+			// the capacity of inaccessible datastores is outdated
+			map[string]string{
+				"name":               "nfs1",
+				"overallStatus":      "red",
+				"summary.type":       "NFS",
+				"summary.accessible": "false",
+				"summary.capacity":   "1000000000",
+				"summary.freeSpace":  "250000000",
+			},
+			common.MapStr{
+				"name":       "nfs1",
+				"status":     "red",
+				"type":       "NFS",
+				"accessible": false,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		event := eventMapping(vsphere.Object{Properties: test.properties})
+		assert.Equal(t, test.event, event)
+	}
+}
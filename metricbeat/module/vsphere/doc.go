@@ -0,0 +1,6 @@
+/*
+Package vsphere is a Metricbeat module for VMware vSphere. The inventory and
+performance metrics of hosts, virtual machines and datastores are read from
+the vSphere Web Services API of a vCenter Server or ESXi host.
+*/
+package vsphere
@@ -0,0 +1,32 @@
+package host
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// eventsMapping creates the event of the host, followed by one event per
+// instance, like a CPU, disk or network adapter, if the performance metrics
+// were queried by instance.
+func eventsMapping(host vsphere.Object, perf vsphere.PerfValues) []common.MapStr {
+	event := common.MapStr{
+		"name":             host.Name(),
+		"connection_state": host.Properties["runtime.connectionState"],
+		"power_state":      host.Properties["runtime.powerState"],
+		"status":           host.Properties["overallStatus"],
+	}
+
+	cores, hasCores := host.Int("summary.hardware.numCpuCores")
+	if hasCores {
+		event.Put("cpu.cores", cores)
+	}
+	if mhz, found := host.Int("summary.hardware.cpuMhz"); found && hasCores {
+		event.Put("cpu.total.mhz", cores*mhz)
+	}
+	if memory, found := host.Int("summary.hardware.memorySize"); found {
+		event.Put("memory.total.bytes", memory)
+	}
+	perf.PutInstance(event, "")
+
+	return append([]common.MapStr{event}, perf.InstanceEvents(host.Name())...)
+}
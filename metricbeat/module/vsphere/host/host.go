@@ -0,0 +1,77 @@
+// Package host reads the state and the performance metrics of the ESXi hosts
+// managed by a vCenter Server, or of a single ESXi host.
+package host
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// properties are the properties of the hosts read from the inventory.
+var properties = []string{
+	"name",
+	"runtime.connectionState",
+	"runtime.powerState",
+	"overallStatus",
+	"summary.hardware.numCpuCores",
+	"summary.hardware.cpuMhz",
+	"summary.hardware.memorySize",
+}
+
+var (
+	debugf = logp.MakeDebug("vsphere-host")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("vsphere", "host", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the metrics of the ESXi hosts.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *vsphere.Client
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := vsphere.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("vsphere-host URL=%s", client.URL())
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch fetches one event per host. The performance metrics are only
+// reported for connected hosts.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	hosts, err := m.client.RetrieveObjects("HostSystem", properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var connected []vsphere.ManagedObjectReference
+	for _, host := range hosts {
+		if host.Properties["runtime.connectionState"] == "connected" {
+			connected = append(connected, host.Ref)
+		}
+	}
+	perf, err := m.client.PerfMetrics(connected)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(hosts))
+	for _, host := range hosts {
+		events = append(events, eventsMapping(host, perf[host.Ref])...)
+	}
+	return events, nil
+}
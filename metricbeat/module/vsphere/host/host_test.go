@@ -0,0 +1,80 @@
+// +build !integration
+
+package host
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+func TestEventsMapping(t *testing.T) {
+	host := vsphere.Object{
+		Ref: vsphere.ManagedObjectReference{Type: "HostSystem", Value: "host-10"},
+		Properties: map[string]string{
+			"name":                         "esx1.example.com",
+			"runtime.connectionState":      "connected",
+			"runtime.powerState":           "poweredOn",
+			"overallStatus":                "green",
+			"summary.hardware.numCpuCores": "8",
+			"summary.hardware.cpuMhz":      "2400",
+			"summary.hardware.memorySize":  "68719476736",
+		},
+	}
+	perf := vsphere.PerfValues{
+		"":       {"cpu.usage.pct": 0.2534, "memory.usage.pct": 0.5},
+		"vmnic0": {"network.usage.bytes_per_sec": int64(12288)},
+		"0":      {"cpu.usage.pct": 0.5},
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":             "esx1.example.com",
+			"connection_state": "connected",
+			"power_state":      "poweredOn",
+			"status":           "green",
+			"cpu": common.MapStr{
+				"cores": int64(8),
+				"total": common.MapStr{"mhz": int64(19200)},
+				"usage": common.MapStr{"pct": 0.2534},
+			},
+			"memory": common.MapStr{
+				"total": common.MapStr{"bytes": int64(68719476736)},
+				"usage": common.MapStr{"pct": 0.5},
+			},
+		},
+		{
+			"name":     "esx1.example.com",
+			"instance": "0",
+			"cpu":      common.MapStr{"usage": common.MapStr{"pct": 0.5}},
+		},
+		{
+			"name":     "esx1.example.com",
+			"instance": "vmnic0",
+			"network":  common.MapStr{"usage": common.MapStr{"bytes_per_sec": int64(12288)}},
+		},
+	}, eventsMapping(host, perf))
+}
+
+func TestEventsMappingDisconnected(t *testing.T) {
+	host := vsphere.Object{
+		Properties: map[string]string{
+			"name":                    "esx2.example.com",
+			"runtime.connectionState": "notResponding",
+			"runtime.powerState":      "unknown",
+			"overallStatus":           "gray",
+		},
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":             "esx2.example.com",
+			"connection_state": "notResponding",
+			"power_state":      "unknown",
+			"status":           "gray",
+		},
+	}, eventsMapping(host, nil))
+}
@@ -0,0 +1,211 @@
+package vsphere
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// perfBatchSize is the maximum number of entities queried per request.
+const perfBatchSize = 50
+
+// perfMetric maps a performance counter, named group.name.rollup, to the
+// field of the event.
+type perfMetric struct {
+	counter string
+	field   string
+	convert func(int64) interface{}
+}
+
+// perfMetrics are the realtime performance counters of hosts and virtual
+// machines reported by the module. The counters of CPU usage are reported
+// per CPU, the disk counters per disk and the network counters per network
+// adapter instance.
+var perfMetrics = []perfMetric{
+	{"cpu.usage.average", "cpu.usage.pct", percent},
+	{"cpu.usagemhz.average", "cpu.used.mhz", number},
+	{"mem.usage.average", "memory.usage.pct", percent},
+	{"mem.consumed.average", "memory.consumed.bytes", kilobytes},
+	{"disk.usage.average", "disk.usage.bytes_per_sec", kilobytes},
+	{"disk.read.average", "disk.read.bytes_per_sec", kilobytes},
+	{"disk.write.average", "disk.write.bytes_per_sec", kilobytes},
+	{"net.usage.average", "network.usage.bytes_per_sec", kilobytes},
+	{"net.received.average", "network.received.bytes_per_sec", kilobytes},
+	{"net.transmitted.average", "network.transmitted.bytes_per_sec", kilobytes},
+}
+
+// percent converts a percentage in hundredths of a percent to a fraction.
+func percent(v int64) interface{} {
+	return float64(v) / 10000
+}
+
+func kilobytes(v int64) interface{} {
+	return v * 1024
+}
+
+func number(v int64) interface{} {
+	return v
+}
+
+// PerfValues contains the performance metrics of an entity by instance, as
+// values by field of the event. The metrics aggregated for the entity are
+// stored under the instance "".
+type PerfValues map[string]map[string]interface{}
+
+// PutInstance puts the metrics of the instance into the event.
+func (v PerfValues) PutInstance(event common.MapStr, instance string) {
+	for field, value := range v[instance] {
+		event.Put(field, value)
+	}
+}
+
+// InstanceEvents returns one event per instance, sorted by instance, with
+// the name of the entity and the metrics of the instance.
+func (v PerfValues) InstanceEvents(name string) []common.MapStr {
+	var instances []string
+	for instance := range v {
+		if instance != "" {
+			instances = append(instances, instance)
+		}
+	}
+	sort.Strings(instances)
+
+	events := make([]common.MapStr, 0, len(instances))
+	for _, instance := range instances {
+		event := common.MapStr{
+			"name":     name,
+			"instance": instance,
+		}
+		v.PutInstance(event, instance)
+		events = append(events, event)
+	}
+	return events
+}
+
+type perfCounterInfo struct {
+	Key      int32 `xml:"key"`
+	NameInfo struct {
+		Key string `xml:"key"`
+	} `xml:"nameInfo"`
+	GroupInfo struct {
+		Key string `xml:"key"`
+	} `xml:"groupInfo"`
+	RollupType string `xml:"rollupType"`
+}
+
+func (i perfCounterInfo) name() string {
+	return i.GroupInfo.Key + "." + i.NameInfo.Key + "." + i.RollupType
+}
+
+type queryPerfResult struct {
+	Returnval []struct {
+		Entity ManagedObjectReference `xml:"entity"`
+		Value  []struct {
+			ID    perfMetricID `xml:"id"`
+			Value []int64      `xml:"value"`
+		} `xml:"value"`
+	} `xml:"returnval"`
+}
+
+// PerfMetrics queries the latest realtime sample of the performance metrics
+// of the entities, which must be connected hosts or running virtual
+// machines. The metrics of the instances are only queried with the instance
+// granularity.
+func (c *Client) PerfMetrics(entities []ManagedObjectReference) (map[ManagedObjectReference]PerfValues, error) {
+	if c.counters == nil {
+		if err := c.loadCounters(); err != nil {
+			return nil, err
+		}
+	}
+
+	instance := ""
+	if c.granularity == GranularityInstance {
+		instance = "*"
+	}
+	var ids []perfMetricID
+	metrics := map[int32]perfMetric{}
+	for _, metric := range perfMetrics {
+		id, found := c.counters[metric.counter]
+		if !found {
+			continue
+		}
+		ids = append(ids, perfMetricID{CounterID: id, Instance: instance})
+		metrics[id] = metric
+	}
+
+	values := map[ManagedObjectReference]PerfValues{}
+	if len(ids) == 0 {
+		return values, nil
+	}
+	for len(entities) > 0 {
+		batch := entities
+		if len(batch) > perfBatchSize {
+			batch = batch[:perfBatchSize]
+		}
+		entities = entities[len(batch):]
+
+		var result queryPerfResult
+		err := c.call(func() string {
+			return queryPerfRequest(c.content.PerfManager, batch, ids)
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entity := range result.Returnval {
+			perf := PerfValues{}
+			for _, series := range entity.Value {
+				metric, found := metrics[series.ID.CounterID]
+				// -1 is reported for samples without value
+				if !found || len(series.Value) == 0 || series.Value[0] < 0 {
+					continue
+				}
+				fields, found := perf[series.ID.Instance]
+				if !found {
+					fields = map[string]interface{}{}
+					perf[series.ID.Instance] = fields
+				}
+				fields[metric.field] = metric.convert(series.Value[0])
+			}
+			values[entity.Entity] = perf
+		}
+	}
+	return values, nil
+}
+
+// loadCounters reads the ids of the performance counters, which differ
+// between servers.
+func (c *Client) loadCounters() error {
+	if !c.session {
+		if err := c.login(); err != nil {
+			return err
+		}
+	}
+
+	contents, err := c.retrieveProperties(c.content.PerfManager, "PerformanceManager", []string{"perfCounter"})
+	if err != nil {
+		return err
+	}
+
+	counters := map[string]int32{}
+	for _, content := range contents {
+		for _, prop := range content.PropSet {
+			var infos struct {
+				Info []perfCounterInfo `xml:"PerfCounterInfo"`
+			}
+			val := append(append([]byte("<val>"), prop.Val.Inner...), "</val>"...)
+			if err := xml.Unmarshal(val, &infos); err != nil {
+				return fmt.Errorf("error decoding vsphere performance counters: %v", err)
+			}
+			for _, info := range infos.Info {
+				if _, found := counters[info.name()]; !found {
+					counters[info.name()] = info.Key
+				}
+			}
+		}
+	}
+	c.counters = counters
+	return nil
+}
@@ -0,0 +1,177 @@
+package vsphere
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// soapAction is the SOAPAction of the requests. vSphere 5.5 and later accept
+// requests of API version 5.5.
+const soapAction = "urn:vim25/5.5"
+
+const envelopeStart = `<?xml version="1.0" encoding="UTF-8"?>` +
+	`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" ` +
+	`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+	`<soapenv:Body>`
+
+const envelopeEnd = `</soapenv:Body></soapenv:Envelope>`
+
+// ManagedObjectReference references a managed object, like a host or a
+// virtual machine, on the server.
+type ManagedObjectReference struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (r ManagedObjectReference) String() string {
+	return r.Type + ":" + r.Value
+}
+
+// encode writes the reference as element with the given name.
+func (r ManagedObjectReference) encode(name string) string {
+	return fmt.Sprintf(`<%s type="%s">%s</%s>`, name, escape(r.Type), escape(r.Value), name)
+}
+
+// soapFault is the fault returned by failed requests. The detail contains
+// the vSphere fault, like NotAuthenticatedFault.
+type soapFault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Detail struct {
+		Fault string `xml:",innerxml"`
+	} `xml:"detail"`
+}
+
+func (f *soapFault) Error() string {
+	return fmt.Sprintf("vsphere fault %v: %v", f.Code, f.String)
+}
+
+// notAuthenticated returns true if the session expired or was terminated on
+// the server.
+func (f *soapFault) notAuthenticated() bool {
+	return strings.Contains(f.Detail.Fault, "NotAuthenticated")
+}
+
+// envelope is the SOAP envelope of the responses. The content of the body is
+// decoded into the response of the request.
+type envelope struct {
+	Body struct {
+		Fault   *soapFault `xml:"Fault"`
+		Content []byte     `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+// decodeResponse decodes the body of a SOAP response into v. A fault in the
+// body is returned as error.
+func decodeResponse(body []byte, v interface{}) error {
+	var env envelope
+	if err := xml.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("error decoding soap response: %v", err)
+	}
+	if env.Body.Fault != nil {
+		return env.Body.Fault
+	}
+	if v == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(env.Body.Content, v); err != nil {
+		return fmt.Errorf("error decoding soap response: %v", err)
+	}
+	return nil
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func retrieveServiceContentRequest() string {
+	return `<RetrieveServiceContent xmlns="urn:vim25">` +
+		`<_this type="ServiceInstance">ServiceInstance</_this>` +
+		`</RetrieveServiceContent>`
+}
+
+func loginRequest(sessionManager ManagedObjectReference, username, password string) string {
+	return `<Login xmlns="urn:vim25">` +
+		sessionManager.encode("_this") +
+		`<userName>` + escape(username) + `</userName>` +
+		`<password>` + escape(password) + `</password>` +
+		`</Login>`
+}
+
+func createContainerViewRequest(viewManager, container ManagedObjectReference, objType string) string {
+	return `<CreateContainerView xmlns="urn:vim25">` +
+		viewManager.encode("_this") +
+		container.encode("container") +
+		`<type>` + escape(objType) + `</type>` +
+		`<recursive>true</recursive>` +
+		`</CreateContainerView>`
+}
+
+func destroyViewRequest(view ManagedObjectReference) string {
+	return `<DestroyView xmlns="urn:vim25">` + view.encode("_this") + `</DestroyView>`
+}
+
+// retrievePropertiesRequest retrieves the properties of the objects of the
+// given type. If the object is a container view, the objects in the view are
+// traversed instead of the view itself.
+func retrievePropertiesRequest(
+	propertyCollector, obj ManagedObjectReference,
+	objType string,
+	props []string,
+) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<RetrievePropertiesEx xmlns="urn:vim25">`)
+	buf.WriteString(propertyCollector.encode("_this"))
+	buf.WriteString(`<specSet><propSet><type>` + escape(objType) + `</type>`)
+	for _, prop := range props {
+		buf.WriteString(`<pathSet>` + escape(prop) + `</pathSet>`)
+	}
+	buf.WriteString(`</propSet><objectSet>`)
+	buf.WriteString(obj.encode("obj"))
+	if obj.Type == "ContainerView" {
+		buf.WriteString(`<skip>true</skip>` +
+			`<selectSet xsi:type="TraversalSpec">` +
+			`<name>traverseView</name><type>ContainerView</type><path>view</path><skip>false</skip>` +
+			`</selectSet>`)
+	}
+	buf.WriteString(`</objectSet></specSet><options></options></RetrievePropertiesEx>`)
+	return buf.String()
+}
+
+func continueRetrievePropertiesRequest(propertyCollector ManagedObjectReference, token string) string {
+	return `<ContinueRetrievePropertiesEx xmlns="urn:vim25">` +
+		propertyCollector.encode("_this") +
+		`<token>` + escape(token) + `</token>` +
+		`</ContinueRetrievePropertiesEx>`
+}
+
+// perfMetricID selects a performance counter and its instance. The instance
+// "" selects the aggregated value, "*" all instances.
+type perfMetricID struct {
+	CounterID int32  `xml:"counterId"`
+	Instance  string `xml:"instance"`
+}
+
+// queryPerfRequest queries the latest sample of the counters of the
+// entities in the realtime interval of 20 seconds.
+func queryPerfRequest(perfManager ManagedObjectReference, entities []ManagedObjectReference, metrics []perfMetricID) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<QueryPerf xmlns="urn:vim25">`)
+	buf.WriteString(perfManager.encode("_this"))
+	for _, entity := range entities {
+		buf.WriteString(`<querySpec>`)
+		buf.WriteString(entity.encode("entity"))
+		buf.WriteString(`<maxSample>1</maxSample>`)
+		for _, metric := range metrics {
+			fmt.Fprintf(&buf, `<metricId><counterId>%d</counterId><instance>%s</instance></metricId>`,
+				metric.CounterID, escape(metric.Instance))
+		}
+		buf.WriteString(`<intervalId>20</intervalId></querySpec>`)
+	}
+	buf.WriteString(`</QueryPerf>`)
+	return buf.String()
+}
@@ -0,0 +1,30 @@
+package virtualmachine
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// eventsMapping creates the event of the virtual machine, followed by one
+// event per instance, like a virtual CPU, disk or network adapter, if the
+// performance metrics were queried by instance.
+func eventsMapping(vm vsphere.Object, perf vsphere.PerfValues) []common.MapStr {
+	event := common.MapStr{
+		"name":        vm.Name(),
+		"power_state": vm.Properties["runtime.powerState"],
+		"status":      vm.Properties["overallStatus"],
+	}
+
+	if hostname := vm.Properties["guest.hostName"]; hostname != "" {
+		event.Put("guest.hostname", hostname)
+	}
+	if cpus, found := vm.Int("summary.config.numCpu"); found {
+		event.Put("cpu.count", cpus)
+	}
+	if memory, found := vm.Int("summary.config.memorySizeMB"); found {
+		event.Put("memory.total.bytes", memory*1024*1024)
+	}
+	perf.PutInstance(event, "")
+
+	return append([]common.MapStr{event}, perf.InstanceEvents(vm.Name())...)
+}
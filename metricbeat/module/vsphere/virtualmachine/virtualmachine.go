@@ -0,0 +1,77 @@
+// Package virtualmachine reads the state and the performance metrics of the
+// virtual machines of a vCenter Server or ESXi host.
+package virtualmachine
+
+import (
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+// properties are the properties of the virtual machines read from the
+// inventory.
+var properties = []string{
+	"name",
+	"runtime.powerState",
+	"overallStatus",
+	"summary.config.numCpu",
+	"summary.config.memorySizeMB",
+	"guest.hostName",
+}
+
+var (
+	debugf = logp.MakeDebug("vsphere-virtualmachine")
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("vsphere", "virtualmachine", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet for fetching the metrics of the virtual machines.
+type MetricSet struct {
+	mb.BaseMetricSet
+	client *vsphere.Client
+}
+
+// New creates new instance of MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	client, err := vsphere.NewClient(base)
+	if err != nil {
+		return nil, err
+	}
+
+	debugf("vsphere-virtualmachine URL=%s", client.URL())
+	return &MetricSet{
+		BaseMetricSet: base,
+		client:        client,
+	}, nil
+}
+
+// Fetch fetches one event per virtual machine. The performance metrics are
+// only reported for powered on virtual machines.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	vms, err := m.client.RetrieveObjects("VirtualMachine", properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var poweredOn []vsphere.ManagedObjectReference
+	for _, vm := range vms {
+		if vm.Properties["runtime.powerState"] == "poweredOn" {
+			poweredOn = append(poweredOn, vm.Ref)
+		}
+	}
+	perf, err := m.client.PerfMetrics(poweredOn)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]common.MapStr, 0, len(vms))
+	for _, vm := range vms {
+		events = append(events, eventsMapping(vm, perf[vm.Ref])...)
+	}
+	return events, nil
+}
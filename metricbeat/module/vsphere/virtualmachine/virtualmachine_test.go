@@ -0,0 +1,75 @@
+// +build !integration
+
+package virtualmachine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/module/vsphere"
+)
+
+func TestEventsMapping(t *testing.T) {
+	vm := vsphere.Object{
+		Ref: vsphere.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"},
+		Properties: map[string]string{
+			"name":                        "web-1",
+			"runtime.powerState":          "poweredOn",
+			"overallStatus":               "green",
+			"summary.config.numCpu":       "2",
+			"summary.config.memorySizeMB": "4096",
+			"guest.hostName":              "web-1.example.com",
+		},
+	}
+	perf := vsphere.PerfValues{
+		"": {
+			"cpu.usage.pct":         0.1,
+			"memory.consumed.bytes": int64(1073741824),
+		},
+		"scsi0:0": {"disk.read.bytes_per_sec": int64(2048)},
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":        "web-1",
+			"power_state": "poweredOn",
+			"status":      "green",
+			"guest":       common.MapStr{"hostname": "web-1.example.com"},
+			"cpu": common.MapStr{
+				"count": int64(2),
+				"usage": common.MapStr{"pct": 0.1},
+			},
+			"memory": common.MapStr{
+				"total":    common.MapStr{"bytes": int64(4294967296)},
+				"consumed": common.MapStr{"bytes": int64(1073741824)},
+			},
+		},
+		{
+			"name":     "web-1",
+			"instance": "scsi0:0",
+			"disk":     common.MapStr{"read": common.MapStr{"bytes_per_sec": int64(2048)}},
+		},
+	}, eventsMapping(vm, perf))
+}
+
+func TestEventsMappingPoweredOff(t *testing.T) {
+	vm := vsphere.Object{
+		Properties: map[string]string{
+			"name":                  "template",
+			"runtime.powerState":    "poweredOff",
+			"overallStatus":         "green",
+			"summary.config.numCpu": "1",
+		},
+	}
+
+	assert.Equal(t, []common.MapStr{
+		{
+			"name":        "template",
+			"power_state": "poweredOff",
+			"status":      "green",
+			"cpu":         common.MapStr{"count": int64(1)},
+		},
+	}, eventsMapping(vm, nil))
+}
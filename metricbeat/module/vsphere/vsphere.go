@@ -0,0 +1,325 @@
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/libbeat/outputs"
+	"github.com/elastic/beats/metricbeat/mb"
+)
+
+const (
+	// GranularityAggregate reports the performance metrics aggregated per
+	// host or virtual machine.
+	GranularityAggregate = "aggregate"
+
+	// GranularityInstance additionally reports the performance metrics of
+	// every instance, like a CPU, disk or network adapter.
+	GranularityInstance = "instance"
+)
+
+// defaultPath is the path of the vSphere Web Services API.
+const defaultPath = "/sdk"
+
+var (
+	debugf = logp.MakeDebug("vsphere")
+)
+
+// Config contains the options of the vSphere module.
+type Config struct {
+	Username    string             `config:"username"`
+	Password    string             `config:"password"`
+	TLS         *outputs.TLSConfig `config:"tls"`
+	Granularity string             `config:"granularity"`
+}
+
+// Object is a managed object with its retrieved properties. The values of
+// properties of simple types, like strings, numbers and enums, are given as
+// text. Unset properties are missing.
+type Object struct {
+	Ref        ManagedObjectReference
+	Properties map[string]string
+}
+
+// Name returns the name of the object.
+func (o Object) Name() string {
+	return o.Properties["name"]
+}
+
+// Int returns the value of the numeric property. The second return value is
+// false if the property is unset or not a number.
+func (o Object) Int(name string) (int64, bool) {
+	value, err := strconv.ParseInt(o.Properties[name], 10, 64)
+	return value, err == nil
+}
+
+// Bool returns the value of the boolean property. The second return value is
+// false if the property is unset or not a boolean.
+func (o Object) Bool(name string) (bool, bool) {
+	value, err := strconv.ParseBool(o.Properties[name])
+	return value, err == nil
+}
+
+// Client reads the inventory and performance metrics from the vSphere Web
+// Services API of a vCenter Server or ESXi host. The session is reused
+// between fetches, and a new session is created if it expired. A Client
+// must not be used concurrently.
+type Client struct {
+	http        *http.Client
+	url         string
+	username    string
+	password    string
+	granularity string
+
+	content  *serviceContent
+	session  bool
+	counters map[string]int32
+}
+
+type serviceContent struct {
+	RootFolder        ManagedObjectReference `xml:"rootFolder"`
+	PropertyCollector ManagedObjectReference `xml:"propertyCollector"`
+	ViewManager       ManagedObjectReference `xml:"viewManager"`
+	SessionManager    ManagedObjectReference `xml:"sessionManager"`
+	PerfManager       ManagedObjectReference `xml:"perfManager"`
+}
+
+type objectContent struct {
+	Obj     ManagedObjectReference `xml:"obj"`
+	PropSet []struct {
+		Name string `xml:"name"`
+		Val  struct {
+			Text  string `xml:",chardata"`
+			Inner []byte `xml:",innerxml"`
+		} `xml:"val"`
+	} `xml:"propSet"`
+}
+
+type retrieveResult struct {
+	Returnval *struct {
+		Token   string          `xml:"token"`
+		Objects []objectContent `xml:"objects"`
+	} `xml:"returnval"`
+}
+
+// NewClient creates the client of the MetricSet. The host of the MetricSet
+// is either a URL or a host name, in which case the API is accessed over
+// HTTPS on the /sdk path.
+func NewClient(base mb.BaseMetricSet) (*Client, error) {
+	config := Config{Granularity: GranularityAggregate}
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+	switch config.Granularity {
+	case GranularityAggregate, GranularityInstance:
+	default:
+		return nil, fmt.Errorf("unknown vsphere granularity '%v'", config.Granularity)
+	}
+
+	u, err := parseHostURL(base.Host())
+	if err != nil {
+		return nil, err
+	}
+
+	tls, err := outputs.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	username, password := config.Username, config.Password
+	if u.User != nil {
+		// credentials given in the host take precedence
+		username = u.User.Username()
+		password, _ = u.User.Password()
+		u.User = nil
+	}
+
+	// the session is kept in the vmware_soap_session cookie
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		http: &http.Client{
+			Timeout: base.Module().Config().Timeout,
+			Jar:     jar,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tls,
+			},
+		},
+		url:         u.String(),
+		username:    username,
+		password:    password,
+		granularity: config.Granularity,
+	}, nil
+}
+
+func parseHostURL(host string) (*url.URL, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing host '%v': %v", host, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("error parsing host '%v': empty host", host)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultPath
+	}
+	return u, nil
+}
+
+// URL returns the URL of the API, without credentials.
+func (c *Client) URL() string {
+	return c.url
+}
+
+// RetrieveObjects retrieves the properties of all objects of the type, like
+// HostSystem, VirtualMachine or Datastore, in the inventory.
+func (c *Client) RetrieveObjects(objType string, props []string) ([]Object, error) {
+	var view struct {
+		Returnval ManagedObjectReference `xml:"returnval"`
+	}
+	err := c.call(func() string {
+		return createContainerViewRequest(c.content.ViewManager, c.content.RootFolder, objType)
+	}, &view)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// views are kept in the session until they are destroyed
+		err := c.call(func() string { return destroyViewRequest(view.Returnval) }, nil)
+		if err != nil {
+			logp.Warn("Failed to destroy vsphere view %v: %v", view.Returnval, err)
+		}
+	}()
+
+	contents, err := c.retrieveProperties(view.Returnval, objType, props)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]Object, 0, len(contents))
+	for _, content := range contents {
+		obj := Object{Ref: content.Obj, Properties: map[string]string{}}
+		for _, prop := range content.PropSet {
+			obj.Properties[prop.Name] = prop.Val.Text
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// retrieveProperties retrieves the properties of the object, or of the
+// objects in the view, and continues the retrieval until all pages of the
+// result are read.
+func (c *Client) retrieveProperties(obj ManagedObjectReference, objType string, props []string) ([]objectContent, error) {
+	var result retrieveResult
+	err := c.call(func() string {
+		return retrievePropertiesRequest(c.content.PropertyCollector, obj, objType, props)
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []objectContent
+	for result.Returnval != nil {
+		contents = append(contents, result.Returnval.Objects...)
+
+		token := result.Returnval.Token
+		if token == "" {
+			break
+		}
+		result = retrieveResult{}
+		err := c.call(func() string {
+			return continueRetrievePropertiesRequest(c.content.PropertyCollector, token)
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// call sends the request, creating a session first if there is none. If the
+// session expired, a new session is created and the request is sent again.
+// The request is built after login, as it may reference the service content.
+func (c *Client) call(request func() string, v interface{}) error {
+	if !c.session {
+		if err := c.login(); err != nil {
+			return err
+		}
+	}
+
+	err := c.do(request(), v)
+	if fault, ok := err.(*soapFault); ok && fault.notAuthenticated() {
+		debugf("vsphere session of %v expired, logging in again", c.url)
+		c.session = false
+		if err := c.login(); err != nil {
+			return err
+		}
+		err = c.do(request(), v)
+	}
+	return err
+}
+
+func (c *Client) login() error {
+	if c.content == nil {
+		var content struct {
+			Returnval serviceContent `xml:"returnval"`
+		}
+		if err := c.do(retrieveServiceContentRequest(), &content); err != nil {
+			return err
+		}
+		c.content = &content.Returnval
+	}
+
+	err := c.do(loginRequest(c.content.SessionManager, c.username, c.password), nil)
+	if err != nil {
+		return fmt.Errorf("vsphere login as '%v' failed: %v", c.username, err)
+	}
+	debugf("vsphere session of %v created", c.url)
+	c.session = true
+	return nil
+}
+
+func (c *Client) do(request string, v interface{}) error {
+	req, err := http.NewRequest("POST", c.url, strings.NewReader(envelopeStart+request+envelopeEnd))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", soapAction)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making http request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response of %v: %v", c.url, err)
+	}
+
+	// faults are returned with status 500
+	err = decodeResponse(body, v)
+	if _, ok := err.(*soapFault); ok {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP error %d in %v: %s", resp.StatusCode, c.url, resp.Status)
+	}
+	return err
+}
@@ -0,0 +1,284 @@
+//go:build !integration
+// +build !integration
+
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var operationRegexp = regexp.MustCompile(`<soapenv:Body><(\w+)`)
+
+const serviceContentResponse = `<RetrieveServiceContentResponse xmlns="urn:vim25"><returnval>
+<rootFolder type="Folder">group-d1</rootFolder>
+<propertyCollector type="PropertyCollector">propertyCollector</propertyCollector>
+<viewManager type="ViewManager">ViewManager</viewManager>
+<sessionManager type="SessionManager">SessionManager</sessionManager>
+<perfManager type="PerformanceManager">PerfMgr</perfManager>
+</returnval></RetrieveServiceContentResponse>`
+
+const hostsPage1Response = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<token>1</token>
+<objects><obj type="HostSystem">host-10</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">esx1.example.com</val></propSet>
+<propSet><name>runtime.connectionState</name><val xsi:type="HostSystemConnectionState">connected</val></propSet>
+<propSet><name>summary.hardware.numCpuCores</name><val xsi:type="xsd:short">8</val></propSet>
+</objects>
+</returnval></RetrievePropertiesExResponse>`
+
+const hostsPage2Response = `<ContinueRetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="HostSystem">host-11</obj>
+<propSet><name>name</name><val xsi:type="xsd:string">esx2.example.com</val></propSet>
+<propSet><name>runtime.connectionState</name><val xsi:type="HostSystemConnectionState">disconnected</val></propSet>
+</objects>
+</returnval></ContinueRetrievePropertiesExResponse>`
+
+const countersResponse = `<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+<objects><obj type="PerformanceManager">PerfMgr</obj>
+<propSet><name>perfCounter</name><val xsi:type="ArrayOfPerfCounterInfo">
+<PerfCounterInfo><key>1</key><nameInfo><label>Usage</label><key>usage</key></nameInfo><groupInfo><label>CPU</label><key>cpu</key></groupInfo><rollupType>none</rollupType></PerfCounterInfo>
+<PerfCounterInfo><key>2</key><nameInfo><label>Usage</label><key>usage</key></nameInfo><groupInfo><label>CPU</label><key>cpu</key></groupInfo><rollupType>average</rollupType></PerfCounterInfo>
+<PerfCounterInfo><key>24</key><nameInfo><label>Usage</label><key>usage</key></nameInfo><groupInfo><label>Memory</label><key>mem</key></groupInfo><rollupType>average</rollupType></PerfCounterInfo>
+<PerfCounterInfo><key>143</key><nameInfo><label>Usage</label><key>usage</key></nameInfo><groupInfo><label>Network</label><key>net</key></groupInfo><rollupType>average</rollupType></PerfCounterInfo>
+</val></propSet>
+</objects>
+</returnval></RetrievePropertiesExResponse>`
+
+const queryPerfResponse = `<QueryPerfResponse xmlns="urn:vim25"><returnval xsi:type="PerfEntityMetric">
+<entity type="HostSystem">host-10</entity>
+<sampleInfo><timestamp>2016-11-01T10:00:00Z</timestamp><interval>20</interval></sampleInfo>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance></instance></id><value>2534</value></value>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance>0</instance></id><value>5000</value></value>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>24</counterId><instance></instance></id><value>-1</value></value>
+<value xsi:type="PerfMetricIntSeries"><id><counterId>143</counterId><instance>vmnic0</instance></id><value>12</value></value>
+</returnval></QueryPerfResponse>`
+
+const notAuthenticatedFault = `<soapenv:Fault><faultcode>ServerFaultCode</faultcode>
+<faultstring>The session is not authenticated.</faultstring>
+<detail><NotAuthenticatedFault xmlns="urn:vim25" xsi:type="NotAuthenticated"></NotAuthenticatedFault></detail>
+</soapenv:Fault>`
+
+const invalidLoginFault = `<soapenv:Fault><faultcode>ServerFaultCode</faultcode>
+<faultstring>Cannot complete login due to an incorrect user name or password.</faultstring>
+<detail><InvalidLoginFault xmlns="urn:vim25" xsi:type="InvalidLogin"></InvalidLoginFault></detail>
+</soapenv:Fault>`
+
+// fakeServer simulates the vSphere Web Services API, requiring a session
+// for all requests but RetrieveServiceContent and Login.
+type fakeServer struct {
+	*httptest.Server
+	session      int
+	logins       int
+	destroyViews int
+	requests     []string
+}
+
+func newFakeServer() *fakeServer {
+	s := &fakeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// expire terminates the current session.
+func (s *fakeServer) expire() {
+	s.session++
+}
+
+func (s *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := string(body)
+	s.requests = append(s.requests, request)
+
+	operation := ""
+	if m := operationRegexp.FindStringSubmatch(request); m != nil {
+		operation = m[1]
+	}
+
+	reply := func(status int, content string) {
+		w.Header().Set("Content-Type", "text/xml")
+		w.WriteHeader(status)
+		fmt.Fprint(w, envelopeStart+content+envelopeEnd)
+	}
+
+	switch operation {
+	case "RetrieveServiceContent":
+		reply(200, serviceContentResponse)
+		return
+	case "Login":
+		if !strings.Contains(request, "<password>secret</password>") {
+			reply(500, invalidLoginFault)
+			return
+		}
+		s.logins++
+		s.session++
+		http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: fmt.Sprint(s.session)})
+		reply(200, `<LoginResponse xmlns="urn:vim25"><returnval><key>session</key></returnval></LoginResponse>`)
+		return
+	}
+
+	cookie, err := r.Cookie("vmware_soap_session")
+	if err != nil || cookie.Value != fmt.Sprint(s.session) {
+		reply(500, notAuthenticatedFault)
+		return
+	}
+
+	switch operation {
+	case "CreateContainerView":
+		reply(200, `<CreateContainerViewResponse xmlns="urn:vim25"><returnval type="ContainerView">session[1]view-1</returnval></CreateContainerViewResponse>`)
+	case "DestroyView":
+		s.destroyViews++
+		reply(200, `<DestroyViewResponse xmlns="urn:vim25"></DestroyViewResponse>`)
+	case "RetrievePropertiesEx":
+		if strings.Contains(request, "<pathSet>perfCounter</pathSet>") {
+			reply(200, countersResponse)
+		} else {
+			reply(200, hostsPage1Response)
+		}
+	case "ContinueRetrievePropertiesEx":
+		reply(200, hostsPage2Response)
+	case "QueryPerf":
+		reply(200, queryPerfResponse)
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+func newTestClient(t *testing.T, url, password, granularity string) *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		http:        &http.Client{Jar: jar, Timeout: 10 * time.Second},
+		url:         url + defaultPath,
+		username:    "metricbeat",
+		password:    password,
+		granularity: granularity,
+	}
+}
+
+func TestRetrieveObjects(t *testing.T) {
+	server := newFakeServer()
+	defer server.Close()
+	client := newTestClient(t, server.URL, "secret", GranularityAggregate)
+
+	for i := 0; i < 2; i++ {
+		hosts, err := client.RetrieveObjects("HostSystem", []string{"name", "runtime.connectionState"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []Object{
+			{
+				Ref: ManagedObjectReference{Type: "HostSystem", Value: "host-10"},
+				Properties: map[string]string{
+					"name":                         "esx1.example.com",
+					"runtime.connectionState":      "connected",
+					"summary.hardware.numCpuCores": "8",
+				},
+			},
+			{
+				Ref: ManagedObjectReference{Type: "HostSystem", Value: "host-11"},
+				Properties: map[string]string{
+					"name":                    "esx2.example.com",
+					"runtime.connectionState": "disconnected",
+				},
+			},
+		}, hosts)
+	}
+
+	// the session is reused, and the views are destroyed
+	assert.Equal(t, 1, server.logins)
+	assert.Equal(t, 2, server.destroyViews)
+}
+
+func TestSessionExpired(t *testing.T) {
+	server := newFakeServer()
+	defer server.Close()
+	client := newTestClient(t, server.URL, "secret", GranularityAggregate)
+
+	_, err := client.RetrieveObjects("HostSystem", []string{"name"})
+	assert.NoError(t, err)
+
+	server.expire()
+	hosts, err := client.RetrieveObjects("HostSystem", []string{"name"})
+	assert.NoError(t, err)
+	assert.Len(t, hosts, 2)
+	assert.Equal(t, 2, server.logins)
+}
+
+func TestLoginFailed(t *testing.T) {
+	server := newFakeServer()
+	defer server.Close()
+	client := newTestClient(t, server.URL, "wrong", GranularityAggregate)
+
+	_, err := client.RetrieveObjects("HostSystem", []string{"name"})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "incorrect user name or password")
+	}
+	assert.Equal(t, 0, server.logins)
+}
+
+func TestPerfMetrics(t *testing.T) {
+	server := newFakeServer()
+	defer server.Close()
+
+	host := ManagedObjectReference{Type: "HostSystem", Value: "host-10"}
+	tests := []struct {
+		granularity string
+		instance    string
+	}{
+		{GranularityAggregate, "<instance></instance>"},
+		{GranularityInstance, "<instance>*</instance>"},
+	}
+
+	for _, test := range tests {
+		client := newTestClient(t, server.URL, "secret", test.granularity)
+		perf, err := client.PerfMetrics([]ManagedObjectReference{host})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		request := server.requests[len(server.requests)-1]
+		assert.Contains(t, request, `<entity type="HostSystem">host-10</entity>`)
+		assert.Contains(t, request, "<metricId><counterId>2</counterId>"+test.instance+"</metricId>")
+		assert.Contains(t, request, "<metricId><counterId>143</counterId>"+test.instance+"</metricId>")
+		assert.NotContains(t, request, "<counterId>1</counterId>")
+
+		// samples without value are not reported
+		assert.Equal(t, map[ManagedObjectReference]PerfValues{
+			host: {
+				"":       {"cpu.usage.pct": 0.2534},
+				"0":      {"cpu.usage.pct": 0.5},
+				"vmnic0": {"network.usage.bytes_per_sec": int64(12 * 1024)},
+			},
+		}, perf)
+	}
+}
+
+func TestParseHostURL(t *testing.T) {
+	tests := []struct {
+		host string
+		url  string
+	}{
+		{"vcenter.example.com", "https://vcenter.example.com/sdk"},
+		{"https://vcenter.example.com/", "https://vcenter.example.com/sdk"},
+		{"http://localhost:8989/sdk", "http://localhost:8989/sdk"},
+	}
+
+	for _, test := range tests {
+		u, err := parseHostURL(test.host)
+		if assert.NoError(t, err) {
+			assert.Equal(t, test.url, u.String())
+		}
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Package windows is a Metricbeat module that contains MetricSets that watch the
+configuration of Windows hosts, like registry keys and scheduled tasks, for
+changes.
+*/
+package windows
@@ -0,0 +1,7 @@
+/*
+Package registry watches registry keys for changes. On each fetch the values
+of the configured keys are compared to the values read by the previous fetch
+and an event is reported for each key or value that was added, removed or
+modified.
+*/
+package registry
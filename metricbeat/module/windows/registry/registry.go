@@ -0,0 +1,194 @@
+// +build windows
+
+package registry
+
+import (
+	"encoding/hex"
+	"strconv"
+	"syscall"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows/registry"
+)
+
+var debugf = logp.MakeDebug("windows-registry")
+
+var roots = map[string]registry.Key{
+	"HKLM": registry.LOCAL_MACHINE,
+	"HKCU": registry.CURRENT_USER,
+	"HKU":  registry.USERS,
+	"HKCR": registry.CLASSES_ROOT,
+	"HKCC": registry.CURRENT_CONFIG,
+}
+
+var valueTypes = map[uint32]string{
+	registry.NONE:                       "NONE",
+	registry.SZ:                         "SZ",
+	registry.EXPAND_SZ:                  "EXPAND_SZ",
+	registry.BINARY:                     "BINARY",
+	registry.DWORD:                      "DWORD",
+	registry.DWORD_BIG_ENDIAN:           "DWORD_BIG_ENDIAN",
+	registry.LINK:                       "LINK",
+	registry.MULTI_SZ:                   "MULTI_SZ",
+	registry.RESOURCE_LIST:              "RESOURCE_LIST",
+	registry.FULL_RESOURCE_DESCRIPTOR:   "FULL_RESOURCE_DESCRIPTOR",
+	registry.RESOURCE_REQUIREMENTS_LIST: "RESOURCE_REQUIREMENTS_LIST",
+	registry.QWORD:                      "QWORD",
+}
+
+func init() {
+	if err := mb.Registry.AddMetricSet("windows", "registry", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that watches registry keys for changes.
+type MetricSet struct {
+	mb.BaseMetricSet
+	keys      []string
+	recursive bool
+	last      snapshot
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		Keys      []string `config:"registry_keys" validate:"required"`
+		Recursive bool     `config:"registry_recursive"`
+	}{}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	for _, key := range config.Keys {
+		if _, _, err := splitKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		keys:          config.Keys,
+		recursive:     config.Recursive,
+	}, nil
+}
+
+// Fetch reads the configured keys and returns an event for each change since
+// the previous fetch. The first fetch only records the current values.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	current := snapshot{}
+	for _, path := range m.keys {
+		root, subkey, _ := splitKey(path)
+		if err := readKey(current, roots[root], root, subkey, m.recursive); err != nil {
+			return nil, errors.Wrapf(err, "reading registry key '%s'", path)
+		}
+	}
+
+	last := m.last
+	m.last = current
+	if last == nil {
+		debugf("Read %d registry keys", len(current))
+		return nil, nil
+	}
+	return diff(last, current), nil
+}
+
+// readKey adds the values of the key, and of its subkeys if recursive is set,
+// to the snapshot. Keys that do not exist are not added.
+func readKey(s snapshot, root registry.Key, rootName, path string, recursive bool) error {
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+	if err != nil {
+		if err == syscall.ERROR_FILE_NOT_FOUND {
+			return nil
+		}
+		return err
+	}
+	defer k.Close()
+
+	name := rootName
+	if path != "" {
+		name += `\` + path
+	}
+
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]value, len(names))
+	for _, n := range names {
+		v, err := readValue(k, n)
+		if err != nil {
+			// The value may have been removed after the names were read.
+			debugf("Failed to read value '%s' of key '%s': %v", n, name, err)
+			continue
+		}
+		values[n] = v
+	}
+	s[name] = values
+
+	if !recursive {
+		return nil
+	}
+
+	subkeys, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return err
+	}
+	for _, subkey := range subkeys {
+		subpath := subkey
+		if path != "" {
+			subpath = path + `\` + subkey
+		}
+		if err := readKey(s, root, rootName, subpath, true); err != nil {
+			debugf("Failed to read key '%s\\%s': %v", name, subkey, err)
+		}
+	}
+	return nil
+}
+
+func readValue(k registry.Key, name string) (value, error) {
+	_, typ, err := k.GetValue(name, nil)
+	if err != nil {
+		return value{}, err
+	}
+
+	v := value{Type: valueTypes[typ]}
+	if v.Type == "" {
+		v.Type = strconv.FormatUint(uint64(typ), 10)
+	}
+
+	switch typ {
+	case registry.SZ, registry.EXPAND_SZ:
+		v.Data, _, err = k.GetStringValue(name)
+	case registry.MULTI_SZ:
+		v.Data, _, err = k.GetStringsValue(name)
+	case registry.DWORD, registry.QWORD:
+		var n uint64
+		n, _, err = k.GetIntegerValue(name)
+		v.Data = strconv.FormatUint(n, 10)
+	default:
+		var b []byte
+		b, err = readRaw(k, name)
+		v.Data = hex.EncodeToString(b)
+	}
+	return v, err
+}
+
+// readRaw reads the data of a value of any type as bytes.
+func readRaw(k registry.Key, name string) ([]byte, error) {
+	n, _, err := k.GetValue(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	n, _, err = k.GetValue(name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
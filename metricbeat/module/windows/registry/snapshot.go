@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Short names of the root keys.
+var rootKeys = map[string]string{
+	"HKLM":                "HKLM",
+	"HKEY_LOCAL_MACHINE":  "HKLM",
+	"HKCU":                "HKCU",
+	"HKEY_CURRENT_USER":   "HKCU",
+	"HKU":                 "HKU",
+	"HKEY_USERS":          "HKU",
+	"HKCR":                "HKCR",
+	"HKEY_CLASSES_ROOT":   "HKCR",
+	"HKCC":                "HKCC",
+	"HKEY_CURRENT_CONFIG": "HKCC",
+}
+
+// splitKey splits the path of a key into the short name of its root key and
+// the path of the subkey.
+func splitKey(path string) (root, subkey string, err error) {
+	path = strings.Trim(path, `\`)
+	parts := strings.SplitN(path, `\`, 2)
+
+	root, found := rootKeys[strings.ToUpper(parts[0])]
+	if !found {
+		return "", "", fmt.Errorf("invalid registry key '%s': unknown root key '%s'", path, parts[0])
+	}
+	if len(parts) == 2 {
+		subkey = strings.Trim(parts[1], `\`)
+	}
+	return root, subkey, nil
+}
+
+// value is the type and the data of a registry value. The data is formatted
+// as string, except for multi-string values.
+type value struct {
+	Type string
+	Data interface{}
+}
+
+func (v value) equal(o value) bool {
+	return v.Type == o.Type && fmt.Sprint(v.Data) == fmt.Sprint(o.Data)
+}
+
+func (v value) toMapStr() common.MapStr {
+	return common.MapStr{
+		"type": v.Type,
+		"data": v.Data,
+	}
+}
+
+// snapshot contains the values of the keys that existed when the snapshot
+// was taken, indexed by the path of the key and the name of the value.
+type snapshot map[string]map[string]value
+
+// diff returns an event for each key and value that was added, removed or
+// modified between the old and the new snapshot.
+func diff(old, new snapshot) []common.MapStr {
+	var events []common.MapStr
+
+	for _, key := range sortedKeys(old, new) {
+		oldValues, inOld := old[key]
+		newValues, inNew := new[key]
+
+		switch {
+		case !inOld:
+			events = append(events, keyEvent(key, "added"))
+		case !inNew:
+			events = append(events, keyEvent(key, "removed"))
+		}
+
+		for _, name := range sortedNames(oldValues, newValues) {
+			before, inBefore := oldValues[name]
+			after, inAfter := newValues[name]
+
+			event := keyEvent(key, "")
+			event["value"] = name
+			switch {
+			case !inBefore:
+				event["action"] = "added"
+				event["after"] = after.toMapStr()
+			case !inAfter:
+				event["action"] = "removed"
+				event["before"] = before.toMapStr()
+			case !before.equal(after):
+				event["action"] = "modified"
+				event["before"] = before.toMapStr()
+				event["after"] = after.toMapStr()
+			default:
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func keyEvent(key, action string) common.MapStr {
+	return common.MapStr{
+		"key":    key,
+		"action": action,
+	}
+}
+
+func sortedKeys(snapshots ...snapshot) []string {
+	set := map[string]struct{}{}
+	for _, s := range snapshots {
+		for key := range s {
+			set[key] = struct{}{}
+		}
+	}
+	return sortedSet(set)
+}
+
+func sortedNames(values ...map[string]value) []string {
+	set := map[string]struct{}{}
+	for _, v := range values {
+		for name := range v {
+			set[name] = struct{}{}
+		}
+	}
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]struct{}) []string {
+	list := make([]string, 0, len(set))
+	for s := range set {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return list
+}
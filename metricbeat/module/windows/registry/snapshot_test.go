@@ -0,0 +1,85 @@
+// +build !integration
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitKey(t *testing.T) {
+	root, subkey, err := splitKey(`HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\Run\`)
+	assert.NoError(t, err)
+	assert.Equal(t, "HKLM", root)
+	assert.Equal(t, `SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, subkey)
+
+	root, subkey, err = splitKey(`hkcu`)
+	assert.NoError(t, err)
+	assert.Equal(t, "HKCU", root)
+	assert.Equal(t, "", subkey)
+
+	_, _, err = splitKey(`SOFTWARE\Microsoft`)
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	old := snapshot{
+		`HKLM\Run`: {
+			"updater": {Type: "SZ", Data: `C:\updater.exe`},
+			"agent":   {Type: "SZ", Data: `C:\agent.exe`},
+			"paths":   {Type: "MULTI_SZ", Data: []string{"a", "b"}},
+		},
+		`HKLM\Run\Old`: {},
+	}
+	new := snapshot{
+		`HKLM\Run`: {
+			"updater": {Type: "SZ", Data: `C:\Users\Public\updater.exe`},
+			"paths":   {Type: "MULTI_SZ", Data: []string{"a", "b"}},
+			"level":   {Type: "DWORD", Data: "1"},
+		},
+		`HKLM\Run\New`: {
+			"": {Type: "SZ", Data: "default"},
+		},
+	}
+
+	events := diff(old, new)
+	assert.Equal(t, []common.MapStr{
+		{
+			"key":    `HKLM\Run`,
+			"value":  "agent",
+			"action": "removed",
+			"before": common.MapStr{"type": "SZ", "data": `C:\agent.exe`},
+		},
+		{
+			"key":    `HKLM\Run`,
+			"value":  "level",
+			"action": "added",
+			"after":  common.MapStr{"type": "DWORD", "data": "1"},
+		},
+		{
+			"key":    `HKLM\Run`,
+			"value":  "updater",
+			"action": "modified",
+			"before": common.MapStr{"type": "SZ", "data": `C:\updater.exe`},
+			"after":  common.MapStr{"type": "SZ", "data": `C:\Users\Public\updater.exe`},
+		},
+		{
+			"key":    `HKLM\Run\New`,
+			"action": "added",
+		},
+		{
+			"key":    `HKLM\Run\New`,
+			"value":  "",
+			"action": "added",
+			"after":  common.MapStr{"type": "SZ", "data": "default"},
+		},
+		{
+			"key":    `HKLM\Run\Old`,
+			"action": "removed",
+		},
+	}, events)
+
+	assert.Empty(t, diff(new, new))
+}
@@ -0,0 +1,7 @@
+/*
+Package scheduled_task watches the scheduled tasks of the Task Scheduler for
+changes. On each fetch the task definitions stored in the tasks directory are
+compared to the definitions read by the previous fetch and an event is
+reported for each task that was added, removed or modified.
+*/
+package scheduled_task
@@ -0,0 +1,68 @@
+// +build windows
+
+package scheduled_task
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/metricbeat/mb"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	if err := mb.Registry.AddMetricSet("windows", "scheduled_task", New); err != nil {
+		panic(err)
+	}
+}
+
+// MetricSet that watches the scheduled tasks for changes.
+type MetricSet struct {
+	mb.BaseMetricSet
+	path string
+	last snapshot
+}
+
+// New creates and returns a new MetricSet.
+func New(base mb.BaseMetricSet) (mb.MetricSet, error) {
+	config := struct {
+		Path string `config:"scheduled_task_path"`
+	}{
+		Path: filepath.Join(systemRoot(), "System32", "Tasks"),
+	}
+
+	if err := base.Module().UnpackConfig(&config); err != nil {
+		return nil, err
+	}
+
+	return &MetricSet{
+		BaseMetricSet: base,
+		path:          config.Path,
+	}, nil
+}
+
+// Fetch reads the task definitions and returns an event for each change since
+// the previous fetch. The first fetch only records the current definitions.
+func (m *MetricSet) Fetch() ([]common.MapStr, error) {
+	current, err := readTasks(m.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading scheduled tasks")
+	}
+
+	last := m.last
+	m.last = current
+	if last == nil {
+		debugf("Read %d scheduled tasks", len(current))
+		return nil, nil
+	}
+	return diff(last, current), nil
+}
+
+func systemRoot() string {
+	if root := os.Getenv("SystemRoot"); root != "" {
+		return root
+	}
+	return `C:\Windows`
+}
@@ -0,0 +1,231 @@
+package scheduled_task
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var debugf = logp.MakeDebug("windows-scheduled_task")
+
+// task is the definition of a scheduled task as stored in the XML files of
+// the tasks directory.
+type task struct {
+	Author      string
+	Description string
+	UserID      string
+	RunLevel    string
+	Enabled     bool
+	Hidden      bool
+	Actions     []string
+	Triggers    []string
+}
+
+// taskXML is the subset of the task XML schema the task is read from.
+type taskXML struct {
+	RegistrationInfo struct {
+		Author      string `xml:"Author"`
+		Description string `xml:"Description"`
+	} `xml:"RegistrationInfo"`
+	Triggers struct {
+		Triggers []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"Triggers"`
+	Principals struct {
+		Principal []struct {
+			UserID   string `xml:"UserId"`
+			GroupID  string `xml:"GroupId"`
+			RunLevel string `xml:"RunLevel"`
+		} `xml:"Principal"`
+	} `xml:"Principals"`
+	Settings struct {
+		Enabled *bool `xml:"Enabled"`
+		Hidden  bool  `xml:"Hidden"`
+	} `xml:"Settings"`
+	Actions struct {
+		Exec []struct {
+			Command   string `xml:"Command"`
+			Arguments string `xml:"Arguments"`
+		} `xml:"Exec"`
+		ComHandler []struct {
+			ClassID string `xml:"ClassId"`
+		} `xml:"ComHandler"`
+	} `xml:"Actions"`
+}
+
+// parseTask parses a task definition. The files written by the Task Scheduler
+// are encoded in UTF-16 with a byte order mark, files without a byte order
+// mark are read as UTF-8.
+func parseTask(r io.Reader) (*task, error) {
+	decoder := unicode.BOMOverride(transform.Nop)
+	dec := xml.NewDecoder(transform.NewReader(r, decoder))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		// The input has already been converted to UTF-8.
+		return input, nil
+	}
+
+	var x taskXML
+	if err := dec.Decode(&x); err != nil {
+		return nil, err
+	}
+
+	t := &task{
+		Author:      x.RegistrationInfo.Author,
+		Description: x.RegistrationInfo.Description,
+		Enabled:     x.Settings.Enabled == nil || *x.Settings.Enabled,
+		Hidden:      x.Settings.Hidden,
+	}
+	if p := x.Principals.Principal; len(p) > 0 {
+		t.UserID = p[0].UserID
+		if t.UserID == "" {
+			t.UserID = p[0].GroupID
+		}
+		t.RunLevel = p[0].RunLevel
+	}
+	for _, e := range x.Actions.Exec {
+		t.Actions = append(t.Actions, strings.TrimSpace(e.Command+" "+e.Arguments))
+	}
+	for _, c := range x.Actions.ComHandler {
+		t.Actions = append(t.Actions, c.ClassID)
+	}
+	for _, trigger := range x.Triggers.Triggers {
+		name := strings.TrimSuffix(trigger.XMLName.Local, "Trigger")
+		t.Triggers = append(t.Triggers, strings.ToLower(name))
+	}
+	return t, nil
+}
+
+func (t *task) toMapStr() common.MapStr {
+	m := common.MapStr{
+		"enabled": t.Enabled,
+		"hidden":  t.Hidden,
+	}
+	for k, v := range map[string]string{
+		"author":      t.Author,
+		"description": t.Description,
+		"user_id":     t.UserID,
+		"run_level":   t.RunLevel,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if len(t.Actions) > 0 {
+		m["actions"] = t.Actions
+	}
+	if len(t.Triggers) > 0 {
+		m["triggers"] = t.Triggers
+	}
+	return m
+}
+
+// changes returns the names of the fields that differ between the tasks.
+func (t *task) changes(o *task) []string {
+	var changes []string
+	before, after := t.toMapStr(), o.toMapStr()
+	for _, name := range []string{"author", "description", "user_id", "run_level", "enabled", "hidden", "actions", "triggers"} {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, name)
+		}
+	}
+	return changes
+}
+
+// snapshot contains the tasks indexed by their name.
+type snapshot map[string]*task
+
+// readTasks reads the task definitions of the tasks directory. The name of a
+// task is its path relative to the directory, for example
+// `\Microsoft\Windows\Defrag\ScheduledDefrag`. Files that are not valid task
+// definitions are skipped.
+func readTasks(dir string) (snapshot, error) {
+	s := snapshot{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			debugf("Failed to read '%s': %v", path, err)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := `\` + strings.Replace(filepath.ToSlash(rel), "/", `\`, -1)
+
+		t, err := readTask(path)
+		if err != nil {
+			debugf("Failed to read task '%s': %v", name, err)
+			return nil
+		}
+		s[name] = t
+		return nil
+	})
+	return s, err
+}
+
+func readTask(path string) (*task, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseTask(f)
+}
+
+// diff returns an event for each task that was added, removed or modified
+// between the old and the new snapshot.
+func diff(old, new snapshot) []common.MapStr {
+	var names []string
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, found := old[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var events []common.MapStr
+	for _, name := range names {
+		before, after := old[name], new[name]
+
+		event := common.MapStr{"name": name}
+		switch {
+		case before == nil:
+			event["action"] = "added"
+			event["after"] = after.toMapStr()
+		case after == nil:
+			event["action"] = "removed"
+			event["before"] = before.toMapStr()
+		default:
+			changes := before.changes(after)
+			if len(changes) == 0 {
+				continue
+			}
+			event["action"] = "modified"
+			event["changes"] = changes
+			event["before"] = before.toMapStr()
+			event["after"] = after.toMapStr()
+		}
+		events = append(events, event)
+	}
+	return events
+}
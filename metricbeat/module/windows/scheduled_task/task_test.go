@@ -0,0 +1,158 @@
+// +build !integration
+
+package scheduled_task
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const taskDefinition = `<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo>
+    <Author>CONTOSO\admin</Author>
+    <Description>Updates the agent.</Description>
+    <URI>\Agent\Update</URI>
+  </RegistrationInfo>
+  <Triggers>
+    <LogonTrigger>
+      <Enabled>true</Enabled>
+    </LogonTrigger>
+    <CalendarTrigger>
+      <StartBoundary>2016-01-01T03:00:00</StartBoundary>
+      <ScheduleByDay>
+        <DaysInterval>1</DaysInterval>
+      </ScheduleByDay>
+    </CalendarTrigger>
+  </Triggers>
+  <Principals>
+    <Principal id="Author">
+      <UserId>S-1-5-18</UserId>
+      <RunLevel>HighestAvailable</RunLevel>
+    </Principal>
+  </Principals>
+  <Settings>
+    <Enabled>ENABLED</Enabled>
+    <Hidden>true</Hidden>
+  </Settings>
+  <Actions Context="Author">
+    <Exec>
+      <Command>C:\Program Files\Agent\update.exe</Command>
+      <Arguments>/quiet</Arguments>
+    </Exec>
+    <ComHandler>
+      <ClassId>{A6BA00FE-40E8-477C-B713-C64A14F18ADB}</ClassId>
+    </ComHandler>
+  </Actions>
+</Task>
+`
+
+func utf16(t *testing.T, s string) []byte {
+	enc := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder()
+	b, _, err := transform.Bytes(enc, []byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseTask(t *testing.T) {
+	definition := strings.Replace(taskDefinition, "ENABLED", "false", 1)
+	task, err := parseTask(strings.NewReader(string(utf16(t, definition))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, common.MapStr{
+		"author":      `CONTOSO\admin`,
+		"description": "Updates the agent.",
+		"user_id":     "S-1-5-18",
+		"run_level":   "HighestAvailable",
+		"enabled":     false,
+		"hidden":      true,
+		"actions": []string{
+			`C:\Program Files\Agent\update.exe /quiet`,
+			"{A6BA00FE-40E8-477C-B713-C64A14F18ADB}",
+		},
+		"triggers": []string{"logon", "calendar"},
+	}, task.toMapStr())
+
+	_, err = parseTask(strings.NewReader("not a task"))
+	assert.Error(t, err)
+}
+
+func TestParseTaskDefaults(t *testing.T) {
+	task, err := parseTask(strings.NewReader(`<Task><Actions><Exec><Command>cmd.exe</Command></Exec></Actions></Task>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, task.Enabled)
+	assert.False(t, task.Hidden)
+	assert.Equal(t, []string{"cmd.exe"}, task.Actions)
+}
+
+func TestReadTasksAndDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, definition string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, utf16(t, definition), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join("Agent", "Update"), strings.Replace(taskDefinition, "ENABLED", "true", 1))
+	write("Backup", `<Task><Actions><Exec><Command>backup.exe</Command></Exec></Actions></Task>`)
+	write("Invalid", "invalid")
+
+	old, err := readTasks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, old, 2)
+	assert.Contains(t, old, `\Agent\Update`)
+	assert.Empty(t, diff(old, old))
+
+	modified := strings.Replace(taskDefinition, "ENABLED", "false", 1)
+	write(filepath.Join("Agent", "Update"), strings.Replace(modified, "/quiet", "/quiet /force", 1))
+	write("Persist", `<Task><Actions><Exec><Command>C:\Users\Public\p.exe</Command></Exec></Actions></Task>`)
+	os.Remove(filepath.Join(dir, "Backup"))
+
+	new, err := readTasks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := diff(old, new)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, `\Agent\Update`, events[0]["name"])
+		assert.Equal(t, "modified", events[0]["action"])
+		assert.Equal(t, []string{"enabled", "actions"}, events[0]["changes"])
+
+		assert.Equal(t, `\Backup`, events[1]["name"])
+		assert.Equal(t, "removed", events[1]["action"])
+		assert.NotNil(t, events[1]["before"])
+
+		assert.Equal(t, `\Persist`, events[2]["name"])
+		assert.Equal(t, "added", events[2]["action"])
+		assert.Equal(t, []string{`C:\Users\Public\p.exe`}, events[2]["after"].(common.MapStr)["actions"])
+	}
+
+	_, err = readTasks(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -3,17 +3,34 @@ package beater
 import (
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/elastic/beats/libbeat/beat"
 
 	"github.com/elastic/beats/packetbeat/sniffer"
 )
 
+// devicesCommand lists the capture devices with their friendly names,
+// descriptions and addresses, which can be used in the device setting.
+const devicesCommand = "devices"
+
 func init() {
 	printDevices := flag.Bool("devices", false, "Print the list of devices and exit")
 
 	beat.AddFlagsCallback(func(_ *beat.Beat) error {
+		if args := flag.Args(); len(args) > 0 && args[0] == devicesCommand {
+			if len(args) != 2 || args[1] != "list" {
+				return fmt.Errorf("Usage: %s devices list", os.Args[0])
+			}
+			if err := listDevices(); err != nil {
+				return err
+			}
+			return beat.GracefulExit
+		}
+
 		if *printDevices == false {
 			return nil
 		}
@@ -23,12 +40,7 @@ func init() {
 			return fmt.Errorf("Error getting devices list: %v\n", err)
 		}
 		if len(devs) == 0 {
-			fmt.Printf("No devices found.")
-			if runtime.GOOS != "windows" {
-				fmt.Printf(" You might need sudo?\n")
-			} else {
-				fmt.Printf("\n")
-			}
+			printNoDevices()
 		}
 
 		for i, dev := range devs {
@@ -37,3 +49,41 @@ func init() {
 		return beat.GracefulExit
 	})
 }
+
+// listDevices prints a table of the devices. Each device can be configured
+// by its index, name, friendly name or description.
+func listDevices() error {
+	devs, err := sniffer.ListDevices()
+	if err != nil {
+		return fmt.Errorf("Error getting devices list: %v", err)
+	}
+	if len(devs) == 0 {
+		printNoDevices()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tNAME\tFRIENDLY NAME\tDESCRIPTION\tADDRESSES\tLOOPBACK")
+	for i, dev := range devs {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%v\n", i, dev.Name,
+			orDash(dev.FriendlyName), orDash(dev.Description),
+			orDash(strings.Join(dev.Addresses, ", ")), dev.Loopback)
+	}
+	return w.Flush()
+}
+
+func printNoDevices() {
+	fmt.Printf("No devices found.")
+	if runtime.GOOS != "windows" {
+		fmt.Printf(" You might need sudo?\n")
+	} else {
+		fmt.Printf("\n")
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -3,6 +3,7 @@ package beater
 import (
 	"flag"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 
 	"github.com/elastic/beats/packetbeat/config"
 	"github.com/elastic/beats/packetbeat/decoder"
+	"github.com/elastic/beats/packetbeat/ebpf"
 	"github.com/elastic/beats/packetbeat/flows"
 	"github.com/elastic/beats/packetbeat/procs"
 	"github.com/elastic/beats/packetbeat/protos"
@@ -21,6 +23,7 @@ import (
 	"github.com/elastic/beats/packetbeat/protos/tcp"
 	"github.com/elastic/beats/packetbeat/protos/udp"
 	"github.com/elastic/beats/packetbeat/publish"
+	"github.com/elastic/beats/packetbeat/sflow"
 	"github.com/elastic/beats/packetbeat/sniffer"
 )
 
@@ -31,6 +34,7 @@ type Packetbeat struct {
 	Pub         *publish.PacketbeatPublisher
 	Sniff       *sniffer.SnifferSetup
 
+	done     chan struct{}
 	services []interface {
 		Start()
 		Stop()
@@ -66,7 +70,7 @@ func init() {
 
 func New() *Packetbeat {
 
-	pb := &Packetbeat{}
+	pb := &Packetbeat{done: make(chan struct{})}
 	pb.CmdLineArgs = cmdLineArgs
 
 	return pb
@@ -121,7 +125,7 @@ func (pb *Packetbeat) Setup(b *beat.Beat) error {
 	if b.Config.Shipper.BulkQueueSize != nil {
 		bulkQueueSize = *b.Config.Shipper.BulkQueueSize
 	}
-	pb.Pub = publish.NewPublisher(b.Publisher, queueSize, bulkQueueSize)
+	pb.Pub = publish.NewPublisher(b.Publisher, b.Connect(), queueSize, bulkQueueSize)
 	pb.Pub.Start()
 
 	logp.Debug("main", "Initializing protocol plugins")
@@ -130,12 +134,34 @@ func (pb *Packetbeat) Setup(b *beat.Beat) error {
 		return fmt.Errorf("Initializing protocol analyzers failed: %v", err)
 	}
 
-	logp.Debug("main", "Initializing sniffer")
-	if err := pb.setupSniffer(); err != nil {
-		return fmt.Errorf("Initializing sniffer failed: %v", err)
+	// Packet capture is not required if only the eBPF source or the sFlow
+	// collector are configured.
+	if len(cfg.Protocols) > 0 || cfg.Flows != nil || (cfg.Ebpf == nil && cfg.Sflow == nil) {
+		logp.Debug("main", "Initializing sniffer")
+		if err := pb.setupSniffer(); err != nil {
+			return fmt.Errorf("Initializing sniffer failed: %v", err)
+		}
+	}
+
+	if cfg.Ebpf != nil {
+		logp.Debug("main", "Initializing eBPF source")
+		source, err := ebpf.New(pb.Pub, cfg.Ebpf)
+		if err != nil {
+			return fmt.Errorf("Initializing eBPF source failed: %v", err)
+		}
+		pb.services = append(pb.services, source)
+	}
+
+	if cfg.Sflow != nil {
+		logp.Debug("main", "Initializing sFlow collector")
+		collector, err := sflow.New(pb.Pub, cfg.Sflow)
+		if err != nil {
+			return fmt.Errorf("Initializing sFlow collector failed: %v", err)
+		}
+		pb.services = append(pb.services, collector)
 	}
 
-	// This needs to be after the sniffer Init but before the sniffer Run.
+	// This needs to be after the sniffer, eBPF and sFlow Init but before the sniffer Run.
 	if err := droppriv.DropPrivileges(cfg.RunOptions); err != nil {
 		return err
 	}
@@ -154,7 +180,21 @@ func (pb *Packetbeat) setupSniffer() error {
 	}
 
 	pb.Sniff = &sniffer.SnifferSetup{}
-	return pb.Sniff.Init(false, pb.makeWorkerFactory(filter), &pb.PbConfig.Packetbeat.Interfaces)
+	err := pb.Sniff.Init(false, pb.makeWorkerFactory(filter), &pb.PbConfig.Packetbeat.Interfaces)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Interfaces.StatsPeriod > 0 && cfg.Interfaces.File == "" {
+		var protocols []string
+		for name := range cfg.Protocols {
+			protocols = append(protocols, name)
+		}
+		sort.Strings(protocols)
+		pb.services = append(pb.services, sniffer.NewStatsReporter(
+			pb.Pub, pb.Sniff, protocols, cfg.Interfaces.StatsPeriod))
+	}
+	return nil
 }
 
 func (pb *Packetbeat) makeWorkerFactory(filter string) sniffer.WorkerFactory {
@@ -217,6 +257,11 @@ func (pb *Packetbeat) Run(b *beat.Beat) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		if pb.Sniff == nil {
+			// Only the services are running, wait for Stop
+			<-pb.done
+			return
+		}
 		err := pb.Sniff.Run()
 		if err != nil {
 			errC <- fmt.Errorf("Sniffer main loop failed: %v", err)
@@ -261,5 +306,9 @@ func (pb *Packetbeat) Cleanup(b *beat.Beat) error {
 // Called by the Beat stop function
 func (pb *Packetbeat) Stop() {
 	logp.Info("Packetbeat send stop signal")
-	pb.Sniff.Stop()
+	if pb.Sniff != nil {
+		pb.Sniff.Stop()
+	} else {
+		close(pb.done)
+	}
 }
@@ -15,6 +15,8 @@ type Config struct {
 type PacketbeatConfig struct {
 	Interfaces InterfacesConfig
 	Flows      *Flows
+	Ebpf       *Ebpf
+	Sflow      *Sflow
 	Protocols  map[string]*common.Config
 	Procs      procs.ProcsConfig
 	RunOptions droppriv.RunOptions
@@ -32,6 +34,7 @@ type InterfacesConfig struct {
 	Dumpfile       string
 	OneAtATime     bool
 	Loop           int
+	StatsPeriod    time.Duration `config:"stats_period"`
 }
 
 type Flows struct {
@@ -39,6 +42,22 @@ type Flows struct {
 	Period  string
 }
 
+type Ebpf struct {
+	Events      []string
+	BufferPages int `config:"buffer_pages"`
+}
+
+type Sflow struct {
+	Host       string
+	Interfaces []SflowInterface
+}
+
+type SflowInterface struct {
+	Agent string
+	Index uint32
+	Name  string
+}
+
 type ProtocolCommon struct {
 	Ports              []int         `config:"ports"`
 	SendRequest        bool          `config:"send_request"`
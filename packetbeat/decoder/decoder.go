@@ -49,6 +49,10 @@ type DecoderStruct struct {
 	flowIDBufferBacking [flows.SizeFlowIDMax]byte
 }
 
+// protocolFamilyIPv6Windows is the IPv6 family in the loopback header of
+// packets captured by Npcap on Windows, which is unknown to gopacket.
+const protocolFamilyIPv6Windows layers.ProtocolFamily = 23
+
 const (
 	netPacketsTotalCounter = "net_packets_total"
 	netBytesTotalCounter   = "net_bytes_total"
@@ -87,7 +91,7 @@ func NewDecoder(
 	defaultLayerTypes := []gopacket.DecodingLayer{
 		&d.sll,             // LinuxSLL
 		&d.eth,             // Ethernet
-		&d.lo,              // loopback on OS X
+		&d.lo,              // loopback on OS X and Npcap loopback on Windows
 		&d.stD1Q,           // VLAN
 		&d.stIP4, &d.stIP6, // IP
 		&d.icmp4, &d.icmp6, // ICMP
@@ -104,7 +108,7 @@ func NewDecoder(
 	case layers.LinkTypeEthernet:
 		d.linkLayerDecoder = &d.eth
 		d.linkLayerType = layers.LayerTypeEthernet
-	case layers.LinkTypeNull: // loopback on OSx
+	case layers.LinkTypeNull: // loopback on OSx and Npcap loopback on Windows
 		d.linkLayerDecoder = &d.lo
 		d.linkLayerType = layers.LayerTypeLoopback
 	default:
@@ -159,6 +163,9 @@ func (d *DecoderStruct) OnPacket(data []byte, ci *gopacket.CaptureInfo) {
 		}
 
 		nextType := current.NextLayerType()
+		if currentType == layers.LayerTypeLoopback && d.lo.Family == protocolFamilyIPv6Windows {
+			nextType = layers.LayerTypeIPv6
+		}
 		data = current.LayerPayload()
 
 		processed, err = d.process(&packet, currentType)
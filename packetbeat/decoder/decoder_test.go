@@ -182,6 +182,27 @@ func TestDecodePacketData_ipv6Udp(t *testing.T) {
 	assert.NotEqual(t, -1, strings.Index(string(p.Data()), string(udp.pkt.Payload)))
 }
 
+// Test that packets captured on the Npcap loopback adapter, whose loopback
+// header uses the IPv6 family of Windows, are decoded.
+func TestDecodePacketData_npcapLoopbackIpv6(t *testing.T) {
+	// replace the ethernet header by the loopback header
+	data := append([]byte{23, 0, 0, 0}, ipv6UdpDns[14:]...)
+
+	icmp4Layer := &TestIcmp4Processor{}
+	icmp6Layer := &TestIcmp6Processor{}
+	udp := &TestUdpProcessor{}
+	d, err := NewDecoder(nil, layers.LinkTypeNull, icmp4Layer, icmp6Layer, &TestTcpProcessor{}, udp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.OnPacket(data, &gopacket.CaptureInfo{CaptureLength: len(data), Length: len(data)})
+
+	if assert.NotNil(t, udp.pkt, "UDP packet not received") {
+		assert.Equal(t, "3ffe:507:0:1:200:86ff:fe05:80da", udp.pkt.Tuple.Src_ip.String())
+		assert.Equal(t, uint16(53), udp.pkt.Tuple.Dst_port)
+	}
+}
+
 // Creates a new TestDecoder that handles ethernet packets.
 func newTestDecoder(t *testing.T) (*DecoderStruct, *TestTcpProcessor, *TestUdpProcessor) {
 	icmp4Layer := &TestIcmp4Processor{}
@@ -0,0 +1,255 @@
+package ebpf
+
+import "encoding/binary"
+
+// BPF instruction classes, sizes, modes and operations used by the probes.
+const (
+	classLdx   = 0x01
+	classSt    = 0x02
+	classStx   = 0x03
+	classJmp   = 0x05
+	classAlu   = 0x04
+	classAlu64 = 0x07
+
+	sizeW  = 0x00
+	sizeDW = 0x18
+
+	modeImm = 0x00
+	modeMem = 0x60
+
+	opAdd  = 0x00
+	opRsh  = 0x70
+	opMov  = 0xb0
+	opJeq  = 0x10
+	opCall = 0x80
+	opExit = 0x90
+
+	srcK = 0x00
+	srcX = 0x08
+
+	pseudoMapFD = 1
+)
+
+// Registers. r0 holds return values, r1-r5 are arguments and clobbered by
+// calls, r6-r9 are preserved across calls and r10 is the frame pointer.
+const (
+	r0 uint8 = iota
+	r1
+	r2
+	r3
+	r4
+	r5
+	r6
+	r7
+	r8
+	r9
+	r10
+)
+
+// Kernel helper functions.
+const (
+	helperProbeRead         = 4
+	helperGetCurrentPidTgid = 14
+	helperGetCurrentUIDGid  = 15
+	helperGetCurrentComm    = 16
+	helperPerfEventOutput   = 25
+)
+
+// currentCPU is BPF_F_CURRENT_CPU, selecting the perf buffer of the CPU the
+// program is running on.
+const currentCPU = -1
+
+// insn is a single BPF instruction.
+type insn struct {
+	code uint8
+	dst  uint8
+	src  uint8
+	off  int16
+	imm  int32
+}
+
+func movReg(dst, src uint8) insn {
+	return insn{code: classAlu64 | opMov | srcX, dst: dst, src: src}
+}
+
+func movImm(dst uint8, imm int32) insn {
+	return insn{code: classAlu64 | opMov | srcK, dst: dst, imm: imm}
+}
+
+// movImm32 sets the lower 32 bits of dst and zeroes the upper 32 bits.
+func movImm32(dst uint8, imm int32) insn {
+	return insn{code: classAlu | opMov | srcK, dst: dst, imm: imm}
+}
+
+func addImm(dst uint8, imm int32) insn {
+	return insn{code: classAlu64 | opAdd | srcK, dst: dst, imm: imm}
+}
+
+func rshImm(dst uint8, imm int32) insn {
+	return insn{code: classAlu64 | opRsh | srcK, dst: dst, imm: imm}
+}
+
+func loadDW(dst, src uint8, off int16) insn {
+	return insn{code: classLdx | modeMem | sizeDW, dst: dst, src: src, off: off}
+}
+
+func storeW(dst uint8, off int16, src uint8) insn {
+	return insn{code: classStx | modeMem | sizeW, dst: dst, src: src, off: off}
+}
+
+func storeImmW(dst uint8, off int16, imm int32) insn {
+	return insn{code: classSt | modeMem | sizeW, dst: dst, off: off, imm: imm}
+}
+
+func storeImmDW(dst uint8, off int16, imm int32) insn {
+	return insn{code: classSt | modeMem | sizeDW, dst: dst, off: off, imm: imm}
+}
+
+// jeqImm skips the next off instructions if dst equals imm.
+func jeqImm(dst uint8, imm int32, off int16) insn {
+	return insn{code: classJmp | opJeq | srcK, dst: dst, off: off, imm: imm}
+}
+
+func call(helper int32) insn {
+	return insn{code: classJmp | opCall, imm: helper}
+}
+
+func exit() insn {
+	return insn{code: classJmp | opExit}
+}
+
+// loadMapFD loads a reference to the map fd into dst. The instruction uses
+// two instruction slots.
+func loadMapFD(dst uint8, fd int) []insn {
+	return []insn{
+		{code: modeImm | sizeDW, dst: dst, src: pseudoMapFD, imm: int32(fd)},
+		{},
+	}
+}
+
+// program is a sequence of BPF instructions.
+type program []insn
+
+func (p program) bytes() []byte {
+	b := make([]byte, 8*len(p))
+	for i, in := range p {
+		buf := b[8*i:]
+		buf[0] = in.code
+		buf[1] = in.src<<4 | in.dst&0x0f
+		binary.LittleEndian.PutUint16(buf[2:], uint16(in.off))
+		binary.LittleEndian.PutUint32(buf[4:], uint32(in.imm))
+	}
+	return b
+}
+
+// stackOffset returns the offset relative to the frame pointer of a field of
+// the event that is built on the stack.
+func stackOffset(field int) int16 {
+	return int16(field - eventSize)
+}
+
+// ptRegs contains the offsets of the function arguments and the return value
+// in struct pt_regs, the context of kprobe programs.
+type ptRegs struct {
+	arg2 int16
+	ret  int16
+}
+
+// probeBuilder builds the programs attached to the probes. All programs fill
+// an event on the stack and write it to the perf event array.
+type probeBuilder struct {
+	regs  ptRegs // Offsets of the arguments in the probe context.
+	mapFD int    // File descriptor of the perf event array.
+}
+
+// prologue zeroes the event and sets the kind and the process of the event.
+func (b probeBuilder) prologue(kind eventKind) program {
+	p := program{movReg(r6, r1)}
+	for off := 0; off < eventSize; off += 8 {
+		p = append(p, storeImmDW(r10, stackOffset(off), 0))
+	}
+
+	return append(p,
+		storeImmW(r10, stackOffset(offKind), int32(kind)),
+		call(helperGetCurrentPidTgid),
+		storeW(r10, stackOffset(offTid), r0),
+		rshImm(r0, 32),
+		storeW(r10, stackOffset(offPid), r0),
+		call(helperGetCurrentUIDGid),
+		storeW(r10, stackOffset(offUID), r0),
+		movReg(r1, r10),
+		addImm(r1, int32(stackOffset(offComm))),
+		movImm(r2, commLen),
+		call(helperGetCurrentComm),
+	)
+}
+
+// probeRead copies size bytes at src+off of the kernel memory into field.
+func (b probeBuilder) probeRead(field, size int, src uint8, off int32) program {
+	return program{
+		movReg(r1, r10),
+		addImm(r1, int32(stackOffset(field))),
+		movImm(r2, int32(size)),
+		movReg(r3, src),
+		addImm(r3, off),
+		call(helperProbeRead),
+	}
+}
+
+// epilogue writes the event to the perf event array and returns.
+func (b probeBuilder) epilogue() program {
+	p := program{movReg(r1, r6)}
+	p = append(p, loadMapFD(r2, b.mapFD)...)
+	return append(p,
+		movImm32(r3, currentCPU),
+		movReg(r4, r10),
+		addImm(r4, int32(stackOffset(0))),
+		movImm(r5, eventSize),
+		call(helperPerfEventOutput),
+		movImm(r0, 0),
+		exit(),
+	)
+}
+
+// connect4 builds the program for the entry of tcp_v4_connect(sk, uaddr,
+// addr_len). It reports the destination address of the sockaddr_in.
+func (b probeBuilder) connect4() program {
+	p := b.prologue(kindConnect)
+	p = append(p, loadDW(r7, r6, b.regs.arg2))
+	p = append(p, b.probeRead(offFamily, 4, r7, 0)...) // sin_family, sin_port
+	p = append(p, b.probeRead(offDaddr, 4, r7, 4)...)  // sin_addr
+	return append(p, b.epilogue()...)
+}
+
+// connect6 builds the program for the entry of tcp_v6_connect(sk, uaddr,
+// addr_len). It reports the destination address of the sockaddr_in6.
+func (b probeBuilder) connect6() program {
+	p := b.prologue(kindConnect)
+	p = append(p, loadDW(r7, r6, b.regs.arg2))
+	p = append(p, b.probeRead(offFamily, 4, r7, 0)...) // sin6_family, sin6_port
+	p = append(p, b.probeRead(offDaddr, 16, r7, 8)...) // sin6_addr
+	return append(p, b.epilogue()...)
+}
+
+// accept builds the program for the return of inet_csk_accept. It reads the
+// addresses from the struct sock_common at the start of the returned socket.
+// The layout of the IPv4 part of sock_common is stable across kernel versions.
+func (b probeBuilder) accept() program {
+	var body program
+	body = append(body, b.probeRead(offDaddr, 4, r7, 0)...)   // skc_daddr
+	body = append(body, b.probeRead(offSaddr, 4, r7, 4)...)   // skc_rcv_saddr
+	body = append(body, b.probeRead(offDport, 4, r7, 12)...)  // skc_dport, skc_num
+	body = append(body, b.probeRead(offFamily, 2, r7, 16)...) // skc_family
+
+	p := b.prologue(kindAccept)
+	p = append(p, loadDW(r7, r6, b.regs.ret))
+	p = append(p, jeqImm(r7, 0, int16(len(body)+len(b.epilogue())-2)))
+	p = append(p, body...)
+	return append(p, b.epilogue()...)
+}
+
+// exec builds the program for the sched_process_exec tracepoint.
+func (b probeBuilder) exec() program {
+	p := b.prologue(kindExec)
+	return append(p, b.epilogue()...)
+}
@@ -0,0 +1,52 @@
+// +build !integration
+
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramBytes(t *testing.T) {
+	p := program{
+		movReg(r6, r1),
+		storeImmW(r10, -72, 3),
+		call(helperGetCurrentPidTgid),
+		movImm32(r3, currentCPU),
+		exit(),
+	}
+	p = append(p, loadMapFD(r2, 7)...)
+
+	assert.Equal(t, []byte{
+		0xbf, 0x16, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // r6 = r1
+		0x62, 0x0a, 0xb8, 0xff, 0x03, 0x00, 0x00, 0x00, // *(u32 *)(r10 - 72) = 3
+		0x85, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, // call 14
+		0xb4, 0x03, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, // w3 = -1
+		0x95, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // exit
+		0x18, 0x12, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, // r2 = map fd 7
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, p.bytes())
+}
+
+func TestAcceptJumpsToExit(t *testing.T) {
+	p := probeBuilder{regs: ptRegs{arg2: 104, ret: 80}, mapFD: 3}.accept()
+
+	for i, in := range p {
+		if in.code == classJmp|opJeq|srcK {
+			target := p[i+1+int(in.off)]
+			assert.Equal(t, movImm(r0, 0), target)
+			assert.Equal(t, exit(), p[i+2+int(in.off)])
+			return
+		}
+	}
+	t.Fatal("jump not found")
+}
+
+func TestProgramsEndWithExit(t *testing.T) {
+	b := probeBuilder{regs: ptRegs{arg2: 8, ret: 0}, mapFD: 3}
+	for _, p := range []program{b.connect4(), b.connect6(), b.accept(), b.exec()} {
+		assert.Equal(t, exit(), p[len(p)-1])
+		assert.Equal(t, movReg(r6, r1), p[0])
+	}
+}
@@ -0,0 +1,311 @@
+// +build linux,amd64 linux,arm64
+
+package ebpf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpf(2) commands, map types and program types.
+const (
+	bpfMapCreate     = 0
+	bpfMapUpdateElem = 2
+	bpfProgLoad      = 5
+
+	bpfMapTypePerfEventArray = 4
+
+	bpfProgTypeKprobe     = 2
+	bpfProgTypeTracepoint = 5
+)
+
+// perf_event_open(2) types, configs, flags and ioctls.
+const (
+	perfTypeSoftware   = 1
+	perfTypeTracepoint = 2
+
+	perfCountSWBPFOutput = 10
+	perfSampleRaw        = 1 << 10
+	perfFlagFDCloexec    = 1 << 3
+
+	perfEventIocEnable  = 0x2400
+	perfEventIocDisable = 0x2401
+	perfEventIocSetBPF  = 0x40042408
+)
+
+// logSize is the size of the buffer receiving the verifier log if a program
+// is rejected.
+const logSize = 64 * 1024
+
+type bpfMapCreateAttr struct {
+	mapType    uint32
+	keySize    uint32
+	valueSize  uint32
+	maxEntries uint32
+	mapFlags   uint32
+}
+
+type bpfMapUpdateAttr struct {
+	mapFD uint32
+	_     uint32
+	key   uint64
+	value uint64
+	flags uint64
+}
+
+type bpfProgLoadAttr struct {
+	progType    uint32
+	insnCnt     uint32
+	insns       uint64
+	license     uint64
+	logLevel    uint32
+	logSize     uint32
+	logBuf      uint64
+	kernVersion uint32
+	progFlags   uint32
+}
+
+type perfEventAttr struct {
+	typ              uint32
+	size             uint32
+	config           uint64
+	samplePeriod     uint64
+	sampleType       uint64
+	readFormat       uint64
+	flags            uint64
+	wakeupEvents     uint32
+	bpType           uint32
+	config1          uint64
+	config2          uint64
+	branchSampleType uint64
+	sampleRegsUser   uint64
+	sampleStackUser  uint32
+	clockID          int32
+	sampleRegsIntr   uint64
+	auxWatermark     uint32
+	sampleMaxStack   uint16
+	_                uint16
+}
+
+func bpf(cmd int, attr unsafe.Pointer, size uintptr) (int, error) {
+	fd, _, errno := unix.Syscall(sysBPF, uintptr(cmd), uintptr(attr), size)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// createPerfEventArray creates the map the programs write the events to.
+func createPerfEventArray(cpus int) (int, error) {
+	attr := bpfMapCreateAttr{
+		mapType:    bpfMapTypePerfEventArray,
+		keySize:    4,
+		valueSize:  4,
+		maxEntries: uint32(cpus),
+	}
+	fd, err := bpf(bpfMapCreate, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	if err != nil {
+		return -1, fmt.Errorf("failed to create perf event array: %v", err)
+	}
+	return fd, nil
+}
+
+// updateElem sets the perf event fd of the CPU in the perf event array.
+func updateElem(mapFD, cpu, fd int) error {
+	key, value := uint32(cpu), uint32(fd)
+	attr := bpfMapUpdateAttr{
+		mapFD: uint32(mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key))),
+		value: uint64(uintptr(unsafe.Pointer(&value))),
+	}
+	_, err := bpf(bpfMapUpdateElem, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(&key)
+	runtime.KeepAlive(&value)
+	return err
+}
+
+// loadProgram loads the program into the kernel. The verifier log is
+// returned in the error if the program is rejected.
+func loadProgram(progType uint32, p program, kernVersion uint32) (int, error) {
+	insns := p.bytes()
+	license := []byte("GPL\x00")
+	log := make([]byte, logSize)
+
+	attr := bpfProgLoadAttr{
+		progType:    progType,
+		insnCnt:     uint32(len(p)),
+		insns:       uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		license:     uint64(uintptr(unsafe.Pointer(&license[0]))),
+		logLevel:    1,
+		logSize:     uint32(len(log)),
+		logBuf:      uint64(uintptr(unsafe.Pointer(&log[0]))),
+		kernVersion: kernVersion,
+	}
+	fd, err := bpf(bpfProgLoad, unsafe.Pointer(&attr), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	runtime.KeepAlive(log)
+	if err != nil {
+		return -1, fmt.Errorf("failed to load program: %v: %s", err, cString(log))
+	}
+	return fd, nil
+}
+
+func perfEventOpen(attr *perfEventAttr, pid, cpu int) (int, error) {
+	attr.size = uint32(unsafe.Sizeof(*attr))
+	fd, _, errno := unix.Syscall6(unix.SYS_PERF_EVENT_OPEN,
+		uintptr(unsafe.Pointer(attr)), uintptr(pid), uintptr(cpu),
+		^uintptr(0), perfFlagFDCloexec, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func ioctl(fd int, req, arg uintptr) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// kernelVersion returns the version of the running kernel in the format of
+// LINUX_VERSION_CODE. Kernels before 5.0 reject kprobe programs that are not
+// loaded with the version of the running kernel.
+func kernelVersion() (uint32, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return 0, err
+	}
+
+	release := make([]byte, 0, len(uts.Release))
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	return parseKernelVersion(string(release))
+}
+
+// tracefsDirs are the mount points of tracefs, tried in order.
+var tracefsDirs = []string{"/sys/kernel/debug/tracing", "/sys/kernel/tracing"}
+
+func findTracefs() (string, error) {
+	for _, dir := range tracefsDirs {
+		if _, err := os.Stat(filepath.Join(dir, "events")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("tracefs not found in %v", tracefsDirs)
+}
+
+func writeKprobeEvents(tracefs, line string) error {
+	f, err := os.OpenFile(filepath.Join(tracefs, "kprobe_events"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(line + "\n")
+	return err
+}
+
+func readEventID(tracefs, group, name string) (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(tracefs, "events", group, name, "id"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// attachment is a program attached to a kprobe or tracepoint.
+type attachment struct {
+	fd     int    // perf event fd
+	kprobe string // name of the kprobe event, empty for tracepoints
+}
+
+// attachKprobe creates a kprobe (or kretprobe) event for the kernel function
+// and attaches the program to it.
+func attachKprobe(tracefs, name, function string, ret bool, progFD int) (*attachment, error) {
+	kind := "p"
+	if ret {
+		kind = "r"
+	}
+
+	if err := writeKprobeEvents(tracefs, fmt.Sprintf("%s:kprobes/%s %s", kind, name, function)); err != nil {
+		return nil, fmt.Errorf("failed to create kprobe for %s: %v", function, err)
+	}
+	a := &attachment{fd: -1, kprobe: name}
+
+	id, err := readEventID(tracefs, "kprobes", name)
+	if err == nil {
+		a.fd, err = attachTracepointID(id, progFD)
+	}
+	if err != nil {
+		a.close(tracefs)
+		return nil, fmt.Errorf("failed to attach kprobe for %s: %v", function, err)
+	}
+	return a, nil
+}
+
+// attachTracepoint attaches the program to the tracepoint.
+func attachTracepoint(tracefs, group, name string, progFD int) (*attachment, error) {
+	id, err := readEventID(tracefs, group, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID of tracepoint %s/%s: %v", group, name, err)
+	}
+
+	fd, err := attachTracepointID(id, progFD)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach tracepoint %s/%s: %v", group, name, err)
+	}
+	return &attachment{fd: fd}, nil
+}
+
+func attachTracepointID(id uint64, progFD int) (int, error) {
+	attr := perfEventAttr{
+		typ:          perfTypeTracepoint,
+		config:       id,
+		samplePeriod: 1,
+		sampleType:   perfSampleRaw,
+		wakeupEvents: 1,
+	}
+
+	// The program runs on all CPUs, even if it is attached on CPU 0 only.
+	fd, err := perfEventOpen(&attr, -1, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := ioctl(fd, perfEventIocSetBPF, uintptr(progFD)); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	if err := ioctl(fd, perfEventIocEnable, 0); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// close detaches the program and removes the kprobe event.
+func (a *attachment) close(tracefs string) error {
+	if a.fd >= 0 {
+		ioctl(a.fd, perfEventIocDisable, 0)
+		unix.Close(a.fd)
+	}
+	if a.kprobe != "" {
+		return writeKprobeEvents(tracefs, "-:kprobes/"+a.kprobe)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Package ebpf reports TCP connections and process executions using eBPF
+programs attached to kprobes and tracepoints of the Linux kernel. It is a
+lower-overhead alternative to packet capture for auditing which processes
+and containers open connections.
+
+The programs are attached to:
+
+  - the entry of tcp_v4_connect and tcp_v6_connect (outgoing connections)
+  - the return of inet_csk_accept (accepted connections)
+  - the sched_process_exec tracepoint (process executions)
+
+The events are written to per-CPU perf ring buffers and enriched with the
+executable, arguments, cgroup and container ID of the process read from
+procfs.
+*/
+package ebpf
+
+import (
+	"expvar"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/packetbeat/config"
+)
+
+// defaultBufferPages is the default number of pages of the perf ring buffer
+// of each CPU.
+const defaultBufferPages = 64
+
+var (
+	eventsReceived = expvar.NewInt("ebpf.events_received")
+	eventsLost     = expvar.NewInt("ebpf.events_lost")
+	decodeErrors   = expvar.NewInt("ebpf.decode_errors")
+)
+
+var debugf = logp.MakeDebug("ebpf")
+
+// parseConfig returns the kinds of events to report and the number of pages
+// of the perf ring buffers.
+func parseConfig(cfg *config.Ebpf) (map[eventKind]bool, int, error) {
+	kinds := map[eventKind]bool{}
+	if len(cfg.Events) == 0 {
+		for kind := range kindNames {
+			kinds[kind] = true
+		}
+	}
+
+	for _, name := range cfg.Events {
+		found := false
+		for kind, kindName := range kindNames {
+			if strings.ToLower(name) == kindName {
+				kinds[kind] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, 0, fmt.Errorf("invalid ebpf event '%s', must be one of "+
+				"connect, accept or exec", name)
+		}
+	}
+
+	pages := cfg.BufferPages
+	if pages == 0 {
+		pages = defaultBufferPages
+	}
+	if pages < 0 || pages&(pages-1) != 0 {
+		return nil, 0, fmt.Errorf("ebpf buffer_pages must be a power of 2, "+
+			"but is %d", pages)
+	}
+
+	return kinds, pages, nil
+}
+
+// parseKernelVersion converts a kernel release (e.g. 4.9.0-8-amd64) into the
+// format of LINUX_VERSION_CODE.
+func parseKernelVersion(release string) (uint32, error) {
+	end := strings.IndexFunc(release, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	})
+	if end >= 0 {
+		release = release[:end]
+	}
+
+	parts := strings.SplitN(release, ".", 3)
+	if len(parts) < 2 {
+		return 0, fmt.Errorf("invalid kernel release '%s'", release)
+	}
+
+	var version [3]uint32
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid kernel release '%s'", release)
+		}
+		version[i] = uint32(n)
+	}
+
+	// The sublevel is capped like in the kernel's KERNEL_VERSION macro.
+	if version[2] > 255 {
+		version[2] = 255
+	}
+	return version[0]<<16 | version[1]<<8 | version[2], nil
+}
+
+// parseCPUList parses a list of CPUs in the format of
+// /sys/devices/system/cpu/possible (e.g. 0-3,5,7-8).
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, r := range strings.Split(strings.TrimSpace(list), ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list '%s'", list)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU list '%s'", list)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
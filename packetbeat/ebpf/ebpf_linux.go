@@ -0,0 +1,254 @@
+// +build linux,amd64 linux,arm64
+
+package ebpf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+const (
+	procfs       = "/proc"
+	possibleCPUs = "/sys/devices/system/cpu/possible"
+
+	// pollTimeout is the time to wait for new events before checking if the
+	// source is stopped.
+	pollTimeout = 100 // ms
+)
+
+// Source loads the eBPF programs, attaches them to the kernel and publishes
+// the events written by the programs.
+type Source struct {
+	pub     publish.Events
+	tracefs string
+
+	mapFD    int
+	epollFD  int
+	progFDs  []int
+	attached []*attachment
+	rings    []*perfRing
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// probe is a program and the kernel function or tracepoint it is attached to.
+type probe struct {
+	kind     eventKind
+	name     string
+	function string // kernel function for kprobes
+	ret      bool   // attach to the return of the function
+	group    string // tracepoint group, the name is the tracepoint name
+	optional bool   // the function might not exist
+	build    func(probeBuilder) program
+}
+
+var probes = []probe{
+	{kind: kindConnect, name: "connect4", function: "tcp_v4_connect", build: probeBuilder.connect4},
+	{kind: kindConnect, name: "connect6", function: "tcp_v6_connect", optional: true, build: probeBuilder.connect6},
+	{kind: kindAccept, name: "accept", function: "inet_csk_accept", ret: true, build: probeBuilder.accept},
+	{kind: kindExec, name: "sched_process_exec", group: "sched", build: probeBuilder.exec},
+}
+
+// New loads the programs and attaches them to the kernel. It requires
+// CAP_SYS_ADMIN, so it must be called before privileges are dropped.
+func New(pub publish.Events, cfg *config.Ebpf) (*Source, error) {
+	kinds, pages, err := parseConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := kernelVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(possibleCPUs)
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := parseCPUList(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		pub:     pub,
+		mapFD:   -1,
+		epollFD: -1,
+		done:    make(chan struct{}),
+	}
+
+	if err := s.init(kinds, pages, version, cpus); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Source) init(kinds map[eventKind]bool, pages int, version uint32, cpus []int) error {
+	var err error
+	if s.tracefs, err = findTracefs(); err != nil {
+		return err
+	}
+
+	if s.mapFD, err = createPerfEventArray(cpus[len(cpus)-1] + 1); err != nil {
+		return err
+	}
+
+	if s.epollFD, err = unix.EpollCreate1(unix.EPOLL_CLOEXEC); err != nil {
+		return err
+	}
+
+	for _, cpu := range cpus {
+		ring, err := newPerfRing(cpu, pages)
+		if err == unix.ENODEV {
+			// CPU is offline
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open perf ring buffer of CPU %d: %v", cpu, err)
+		}
+		s.rings = append(s.rings, ring)
+
+		if err := updateElem(s.mapFD, cpu, ring.fd); err != nil {
+			return fmt.Errorf("failed to add perf ring buffer of CPU %d: %v", cpu, err)
+		}
+
+		event := unix.EpollEvent{Events: unix.EPOLLIN, Fd: int32(len(s.rings) - 1)}
+		if err := unix.EpollCtl(s.epollFD, unix.EPOLL_CTL_ADD, ring.fd, &event); err != nil {
+			return err
+		}
+	}
+
+	builder := probeBuilder{regs: regs, mapFD: s.mapFD}
+	for _, p := range probes {
+		if !kinds[p.kind] {
+			continue
+		}
+
+		progType := uint32(bpfProgTypeKprobe)
+		if p.group != "" {
+			progType = bpfProgTypeTracepoint
+		}
+
+		progFD, err := loadProgram(progType, p.build(builder), version)
+		if err != nil {
+			return fmt.Errorf("%s probe: %v", p.name, err)
+		}
+		s.progFDs = append(s.progFDs, progFD)
+
+		var a *attachment
+		if p.group != "" {
+			a, err = attachTracepoint(s.tracefs, p.group, p.name, progFD)
+		} else {
+			name := fmt.Sprintf("packetbeat_%d_%s", os.Getpid(), p.name)
+			a, err = attachKprobe(s.tracefs, name, p.function, p.ret, progFD)
+		}
+		if err != nil {
+			if p.optional {
+				logp.Warn("ebpf: %s probe not attached: %v", p.name, err)
+				continue
+			}
+			return err
+		}
+		s.attached = append(s.attached, a)
+		debugf("Attached %s probe", p.name)
+	}
+
+	return nil
+}
+
+// Start starts publishing the events.
+func (s *Source) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Stop detaches the programs and stops publishing the events.
+func (s *Source) Stop() {
+	close(s.done)
+	s.wg.Wait()
+	s.close()
+}
+
+func (s *Source) run() {
+	defer s.wg.Done()
+
+	events := make([]unix.EpollEvent, len(s.rings))
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		n, err := unix.EpollWait(s.epollFD, events, pollTimeout)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			logp.Err("ebpf: failed to wait for events: %v", err)
+			return
+		}
+
+		for _, event := range events[:n] {
+			s.rings[event.Fd].read(s.onSample, s.onLost)
+		}
+	}
+}
+
+func (s *Source) onSample(raw []byte) {
+	e, err := decodeEvent(raw)
+	if err != nil {
+		decodeErrors.Add(1)
+		debugf("Failed to decode event: %v", err)
+		return
+	}
+	eventsReceived.Add(1)
+
+	proc := readProcess(procfs, e.Pid)
+	s.pub.PublishEvent(e.toMapStr(time.Now(), proc))
+}
+
+func (s *Source) onLost(count uint64) {
+	eventsLost.Add(int64(count))
+	debugf("Kernel dropped %d events, the perf ring buffer was full", count)
+}
+
+func (s *Source) close() {
+	for _, a := range s.attached {
+		if err := a.close(s.tracefs); err != nil {
+			logp.Warn("ebpf: failed to detach probe: %v", err)
+		}
+	}
+	s.attached = nil
+
+	for _, fd := range s.progFDs {
+		unix.Close(fd)
+	}
+	s.progFDs = nil
+
+	for _, ring := range s.rings {
+		ring.close()
+	}
+	s.rings = nil
+
+	if s.epollFD >= 0 {
+		unix.Close(s.epollFD)
+		s.epollFD = -1
+	}
+	if s.mapFD >= 0 {
+		unix.Close(s.mapFD)
+		s.mapFD = -1
+	}
+}
@@ -0,0 +1,20 @@
+// +build !linux !amd64,!arm64
+
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+type Source struct {
+}
+
+func New(pub publish.Events, cfg *config.Ebpf) (*Source, error) {
+	return nil, fmt.Errorf("eBPF is only available on Linux (amd64, arm64)")
+}
+
+func (s *Source) Start() {}
+func (s *Source) Stop()  {}
@@ -0,0 +1,68 @@
+// +build !integration
+
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfig(t *testing.T) {
+	kinds, pages, err := parseConfig(&config.Ebpf{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[eventKind]bool{kindConnect: true, kindAccept: true, kindExec: true}, kinds)
+		assert.Equal(t, defaultBufferPages, pages)
+	}
+
+	kinds, pages, err = parseConfig(&config.Ebpf{Events: []string{"Connect"}, BufferPages: 8})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[eventKind]bool{kindConnect: true}, kinds)
+		assert.Equal(t, 8, pages)
+	}
+
+	_, _, err = parseConfig(&config.Ebpf{Events: []string{"open"}})
+	assert.Error(t, err)
+
+	_, _, err = parseConfig(&config.Ebpf{BufferPages: 6})
+	assert.Error(t, err)
+}
+
+func TestParseKernelVersion(t *testing.T) {
+	var tests = []struct {
+		release string
+		version uint32
+	}{
+		{"4.9.0-8-amd64", 4<<16 | 9<<8},
+		{"3.10.0-957.el7.x86_64", 3<<16 | 10<<8},
+		{"4.4.302", 4<<16 | 4<<8 | 255},
+		{"5.4", 5<<16 | 4<<8},
+		{"4.15.0-rc1", 4<<16 | 15<<8},
+	}
+
+	for _, test := range tests {
+		version, err := parseKernelVersion(test.release)
+		if assert.NoError(t, err, test.release) {
+			assert.Equal(t, test.version, version, test.release)
+		}
+	}
+
+	_, err := parseKernelVersion("linux")
+	assert.Error(t, err)
+}
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,5,7-8\n")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{0, 1, 2, 3, 5, 7, 8}, cpus)
+	}
+
+	cpus, err = parseCPUList("0")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{0}, cpus)
+	}
+
+	_, err = parseCPUList("3-1")
+	assert.Error(t, err)
+}
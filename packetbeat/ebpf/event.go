@@ -0,0 +1,183 @@
+package ebpf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+type eventKind uint32
+
+// Kinds of events reported by the probes.
+const (
+	kindConnect eventKind = iota + 1
+	kindAccept
+	kindExec
+)
+
+var kindNames = map[eventKind]string{
+	kindConnect: "connect",
+	kindAccept:  "accept",
+	kindExec:    "exec",
+}
+
+func (k eventKind) String() string {
+	if name, found := kindNames[k]; found {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", uint32(k))
+}
+
+// Layout of the event written by the probes. Integers are in host byte order
+// except the destination port, which is copied from the socket in network
+// byte order.
+const (
+	offKind   = 0  // uint32
+	offPid    = 4  // uint32, thread group ID
+	offTid    = 8  // uint32
+	offUID    = 12 // uint32
+	offComm   = 16 // [16]byte
+	offFamily = 32 // uint16
+	offDport  = 34 // uint16, network byte order
+	offSport  = 36 // uint16
+	offDaddr  = 40 // [16]byte
+	offSaddr  = 56 // [16]byte
+	eventSize = 72
+
+	commLen = 16
+)
+
+const (
+	afInet  = 2
+	afInet6 = 10
+)
+
+// event is a decoded event of a probe.
+type event struct {
+	Kind   eventKind
+	Pid    uint32
+	Tid    uint32
+	UID    uint32
+	Comm   string
+	Family uint16
+	Dport  uint16
+	Sport  uint16
+	Daddr  net.IP
+	Saddr  net.IP
+}
+
+// decodeEvent decodes the raw sample written by a probe. The probes are only
+// supported on little-endian architectures.
+func decodeEvent(raw []byte) (*event, error) {
+	if len(raw) < eventSize {
+		return nil, fmt.Errorf("event is too short (%d bytes)", len(raw))
+	}
+
+	le := binary.LittleEndian
+	e := &event{
+		Kind:   eventKind(le.Uint32(raw[offKind:])),
+		Pid:    le.Uint32(raw[offPid:]),
+		Tid:    le.Uint32(raw[offTid:]),
+		UID:    le.Uint32(raw[offUID:]),
+		Comm:   cString(raw[offComm : offComm+commLen]),
+		Family: le.Uint16(raw[offFamily:]),
+		Dport:  binary.BigEndian.Uint16(raw[offDport:]),
+		Sport:  le.Uint16(raw[offSport:]),
+	}
+
+	// The accept probe only reads the IPv4 addresses of the socket, which
+	// are also set for IPv4 connections accepted on IPv6 sockets.
+	switch {
+	case e.Family == afInet || e.Kind == kindAccept:
+		e.Daddr = ipv4(raw[offDaddr:])
+		e.Saddr = ipv4(raw[offSaddr:])
+	case e.Family == afInet6:
+		e.Daddr = ipv6(raw[offDaddr:])
+		e.Saddr = ipv6(raw[offSaddr:])
+	}
+
+	if _, found := kindNames[e.Kind]; !found {
+		return nil, fmt.Errorf("unknown event kind %d", uint32(e.Kind))
+	}
+	return e, nil
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// ipv4 returns the address at the start of b or nil if it is not set.
+func ipv4(b []byte) net.IP {
+	ip := net.IP(b[:net.IPv4len])
+	if ip.Equal(net.IPv4zero) {
+		return nil
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], ip[3])
+}
+
+// ipv6 returns the address at the start of b or nil if it is not set.
+func ipv6(b []byte) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, b)
+	if ip.Equal(net.IPv6zero) {
+		return nil
+	}
+	return ip
+}
+
+// endpoint returns the address and port in the format of the source and dest
+// fields of flow events.
+func endpoint(ip net.IP, port uint16) common.MapStr {
+	m := common.MapStr{}
+	if ip != nil {
+		if ip.To4() != nil {
+			m["ip"] = ip.String()
+		} else {
+			m["ipv6"] = ip.String()
+		}
+	}
+	if port != 0 {
+		m["port"] = port
+	}
+	return m
+}
+
+// toMapStr returns the event to be published. Connect events report the
+// destination of the connection attempt, accept events report both
+// endpoints of the accepted connection.
+func (e *event) toMapStr(ts time.Time, proc *process) common.MapStr {
+	m := common.MapStr{
+		"@timestamp": common.Time(ts),
+		"action":     e.Kind.String(),
+		"process":    proc.toMapStr(e),
+	}
+
+	if proc.ContainerID != "" {
+		m["container"] = common.MapStr{"id": proc.ContainerID}
+	}
+
+	switch e.Kind {
+	case kindConnect:
+		m["type"] = "socket"
+		m["transport"] = "tcp"
+		m["direction"] = "out"
+		m["dest"] = endpoint(e.Daddr, e.Dport)
+	case kindAccept:
+		m["type"] = "socket"
+		m["transport"] = "tcp"
+		m["direction"] = "in"
+		m["source"] = endpoint(e.Daddr, e.Dport)
+		m["dest"] = endpoint(e.Saddr, e.Sport)
+	case kindExec:
+		m["type"] = "process"
+	}
+
+	return m
+}
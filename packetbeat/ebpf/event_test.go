@@ -0,0 +1,121 @@
+// +build !integration
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func rawEvent(kind eventKind, family uint16, dport, sport uint16, daddr, saddr net.IP) []byte {
+	raw := make([]byte, eventSize+4)
+	le := binary.LittleEndian
+	le.PutUint32(raw[offKind:], uint32(kind))
+	le.PutUint32(raw[offPid:], 1234)
+	le.PutUint32(raw[offTid:], 1235)
+	le.PutUint32(raw[offUID:], 1000)
+	copy(raw[offComm:], "curl")
+	le.PutUint16(raw[offFamily:], family)
+	binary.BigEndian.PutUint16(raw[offDport:], dport)
+	le.PutUint16(raw[offSport:], sport)
+	if ip := daddr.To4(); ip != nil && family == afInet {
+		daddr = ip
+	}
+	if ip := saddr.To4(); ip != nil {
+		saddr = ip
+	}
+	copy(raw[offDaddr:], daddr)
+	copy(raw[offSaddr:], saddr)
+	return raw
+}
+
+func TestDecodeConnect4(t *testing.T) {
+	raw := rawEvent(kindConnect, afInet, 443, 0, net.ParseIP("93.184.216.34"), nil)
+
+	e, err := decodeEvent(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, kindConnect, e.Kind)
+	assert.Equal(t, uint32(1234), e.Pid)
+	assert.Equal(t, uint32(1235), e.Tid)
+	assert.Equal(t, uint32(1000), e.UID)
+	assert.Equal(t, "curl", e.Comm)
+	assert.Equal(t, uint16(443), e.Dport)
+	assert.Equal(t, "93.184.216.34", e.Daddr.String())
+	assert.Nil(t, e.Saddr)
+
+	ts := time.Now()
+	m := e.toMapStr(ts, &process{Exe: "/usr/bin/curl", ContainerID: "abc"})
+	assert.Equal(t, common.MapStr{
+		"@timestamp": common.Time(ts),
+		"type":       "socket",
+		"action":     "connect",
+		"transport":  "tcp",
+		"direction":  "out",
+		"dest":       common.MapStr{"ip": "93.184.216.34", "port": uint16(443)},
+		"container":  common.MapStr{"id": "abc"},
+		"process": common.MapStr{
+			"pid":  uint32(1234),
+			"tid":  uint32(1235),
+			"uid":  uint32(1000),
+			"name": "curl",
+			"exe":  "/usr/bin/curl",
+		},
+	}, m)
+}
+
+func TestDecodeConnect6(t *testing.T) {
+	raw := rawEvent(kindConnect, afInet6, 80, 0, net.ParseIP("2001:db8::1"), nil)
+
+	e, err := decodeEvent(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, common.MapStr{"ipv6": "2001:db8::1", "port": uint16(80)},
+		endpoint(e.Daddr, e.Dport))
+}
+
+func TestDecodeAccept(t *testing.T) {
+	// IPv4 connection accepted on an IPv6 socket
+	raw := rawEvent(kindAccept, afInet6, 51234, 8080,
+		net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1"))
+	copy(raw[offDaddr:], net.ParseIP("10.0.0.2").To4())
+
+	e, err := decodeEvent(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m := e.toMapStr(time.Now(), &process{})
+	assert.Equal(t, "in", m["direction"])
+	assert.Equal(t, common.MapStr{"ip": "10.0.0.2", "port": uint16(51234)}, m["source"])
+	assert.Equal(t, common.MapStr{"ip": "10.0.0.1", "port": uint16(8080)}, m["dest"])
+	assert.NotContains(t, m, "container")
+}
+
+func TestDecodeExec(t *testing.T) {
+	e, err := decodeEvent(rawEvent(kindExec, 0, 0, 0, nil, nil))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m := e.toMapStr(time.Now(), &process{Args: []string{"curl", "-v"}})
+	assert.Equal(t, "process", m["type"])
+	assert.Equal(t, "exec", m["action"])
+	assert.NotContains(t, m, "dest")
+	assert.Equal(t, []string{"curl", "-v"}, m["process"].(common.MapStr)["args"])
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := decodeEvent(make([]byte, 10))
+	assert.Error(t, err)
+
+	_, err = decodeEvent(rawEvent(eventKind(42), 0, 0, 0, nil, nil))
+	assert.Error(t, err)
+}
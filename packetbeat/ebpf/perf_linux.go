@@ -0,0 +1,125 @@
+// +build linux,amd64 linux,arm64
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"os"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Record types in the perf ring.
+const (
+	perfRecordLost   = 2
+	perfRecordSample = 9
+)
+
+// Offsets of data_head and data_tail in struct perf_event_mmap_page.
+const (
+	dataHeadOffset = 1024
+	dataTailOffset = 1032
+)
+
+// perfRing is the ring buffer the events of one CPU are written to.
+type perfRing struct {
+	fd   int
+	mem  []byte // metadata page followed by the data pages
+	data []byte
+}
+
+// newPerfRing opens a ring buffer of the given number of pages for the CPU.
+// The number of pages must be a power of 2.
+func newPerfRing(cpu, pages int) (*perfRing, error) {
+	attr := perfEventAttr{
+		typ:          perfTypeSoftware,
+		config:       perfCountSWBPFOutput,
+		samplePeriod: 1,
+		sampleType:   perfSampleRaw,
+		wakeupEvents: 1,
+	}
+	fd, err := perfEventOpen(&attr, -1, cpu)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := os.Getpagesize()
+	mem, err := unix.Mmap(fd, 0, pageSize*(1+pages), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	if err := ioctl(fd, perfEventIocEnable, 0); err != nil {
+		unix.Munmap(mem)
+		unix.Close(fd)
+		return nil, err
+	}
+
+	return &perfRing{fd: fd, mem: mem, data: mem[pageSize:]}, nil
+}
+
+func (r *perfRing) head() uint64 {
+	return atomic.LoadUint64((*uint64)(unsafe.Pointer(&r.mem[dataHeadOffset])))
+}
+
+func (r *perfRing) tail() *uint64 {
+	return (*uint64)(unsafe.Pointer(&r.mem[dataTailOffset]))
+}
+
+// copyAt copies n bytes at the position of the ring, which may wrap around
+// the end of the data pages.
+func (r *perfRing) copyAt(pos, n uint64) []byte {
+	size := uint64(len(r.data))
+	out := make([]byte, n)
+	start := pos % size
+	if c := copy(out, r.data[start:]); uint64(c) < n {
+		copy(out[c:], r.data)
+	}
+	return out
+}
+
+// read passes the raw samples written since the last call to onSample and
+// the number of events the kernel dropped because the ring was full to
+// onLost.
+func (r *perfRing) read(onSample func([]byte), onLost func(uint64)) {
+	le := binary.LittleEndian
+	head := r.head()
+	tail := atomic.LoadUint64(r.tail())
+
+	for tail < head {
+		// struct perf_event_header
+		header := r.copyAt(tail, 8)
+		typ, size := le.Uint32(header), uint64(le.Uint16(header[6:]))
+		if size < 8 {
+			break
+		}
+
+		record := r.copyAt(tail+8, size-8)
+		switch typ {
+		case perfRecordSample:
+			if len(record) >= 4 {
+				raw := record[4:]
+				if n := le.Uint32(record); int(n) <= len(raw) {
+					raw = raw[:n]
+				}
+				onSample(raw)
+			}
+		case perfRecordLost:
+			if len(record) >= 16 {
+				onLost(le.Uint64(record[8:]))
+			}
+		}
+		tail += size
+	}
+
+	atomic.StoreUint64(r.tail(), head)
+}
+
+func (r *perfRing) close() {
+	ioctl(r.fd, perfEventIocDisable, 0)
+	unix.Munmap(r.mem)
+	unix.Close(r.fd)
+}
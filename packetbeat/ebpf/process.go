@@ -0,0 +1,116 @@
+package ebpf
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// containerIDRegexp matches the container IDs in the cgroup paths created by
+// Docker, containerd, CRI-O and Kubernetes.
+var containerIDRegexp = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// process contains the information read from procfs about the process that
+// triggered an event.
+type process struct {
+	Exe         string
+	Args        []string
+	Cgroup      string
+	ContainerID string
+}
+
+// readProcess reads the information about the process from procfs. The
+// process can exit before the event is processed, so missing information is
+// not an error.
+func readProcess(procfs string, pid uint32) *process {
+	dir := filepath.Join(procfs, strconv.FormatUint(uint64(pid), 10))
+	p := &process{}
+
+	p.Exe, _ = os.Readlink(filepath.Join(dir, "exe"))
+
+	if cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.Args = parseCmdline(cmdline)
+	}
+
+	if cgroup, err := ioutil.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		p.Cgroup, p.ContainerID = parseCgroup(cgroup)
+	}
+
+	return p
+}
+
+func parseCmdline(cmdline []byte) []string {
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) == 0 {
+		return nil
+	}
+	return strings.Split(string(cmdline), "\x00")
+}
+
+// parseCgroup returns the cgroup path of the process and the ID of the
+// container the process belongs to. The path of the unified hierarchy is
+// preferred over the path of the cpu controller and the first hierarchy.
+func parseCgroup(data []byte) (path, containerID string) {
+	var first, cpu, unified string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if first == "" {
+			first = fields[2]
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			unified = fields[2]
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == "cpu" {
+				cpu = fields[2]
+			}
+		}
+	}
+
+	switch {
+	case unified != "":
+		path = unified
+	case cpu != "":
+		path = cpu
+	default:
+		path = first
+	}
+
+	if ids := containerIDRegexp.FindAllString(path, -1); len(ids) > 0 {
+		containerID = ids[len(ids)-1]
+	}
+	return path, containerID
+}
+
+func (p *process) toMapStr(e *event) common.MapStr {
+	m := common.MapStr{
+		"pid":  e.Pid,
+		"tid":  e.Tid,
+		"uid":  e.UID,
+		"name": e.Comm,
+	}
+	if p.Exe != "" {
+		m["exe"] = p.Exe
+	}
+	if len(p.Args) > 0 {
+		m["args"] = p.Args
+	}
+	if p.Cgroup != "" {
+		m["cgroup"] = p.Cgroup
+	}
+	return m
+}
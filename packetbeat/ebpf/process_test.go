@@ -0,0 +1,61 @@
+// +build !integration
+
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const containerID = "3f4e8d6c5b2a1908f7e6d5c4b3a29180f7e6d5c4b3a29180f7e6d5c4b3a29180"
+
+func TestParseCgroup(t *testing.T) {
+	var tests = []struct {
+		cgroup      string
+		path        string
+		containerID string
+	}{
+		{
+			cgroup:      "0::/system.slice/docker-" + containerID + ".scope\n",
+			path:        "/system.slice/docker-" + containerID + ".scope",
+			containerID: containerID,
+		},
+		{
+			cgroup: "12:pids:/docker/" + containerID + "\n" +
+				"4:cpu,cpuacct:/docker/" + containerID + "\n" +
+				"1:name=systemd:/docker/" + containerID + "\n",
+			path:        "/docker/" + containerID,
+			containerID: containerID,
+		},
+		{
+			cgroup:      "3:cpu:/kubepods/burstable/pod0b2c6b0e-2b9a-11e7-8a9e-42010a800002/" + containerID + "\n",
+			path:        "/kubepods/burstable/pod0b2c6b0e-2b9a-11e7-8a9e-42010a800002/" + containerID,
+			containerID: containerID,
+		},
+		{
+			cgroup: "1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n",
+			path:   "/user.slice/user-1000.slice/session-2.scope",
+		},
+		{
+			cgroup: "",
+		},
+	}
+
+	for _, test := range tests {
+		path, id := parseCgroup([]byte(test.cgroup))
+		assert.Equal(t, test.path, path)
+		assert.Equal(t, test.containerID, id)
+	}
+}
+
+func TestParseCmdline(t *testing.T) {
+	assert.Equal(t, []string{"curl", "-v", "http://localhost"},
+		parseCmdline([]byte("curl\x00-v\x00http://localhost\x00")))
+	assert.Nil(t, parseCmdline([]byte{}))
+}
+
+func TestReadProcessMissing(t *testing.T) {
+	p := readProcess("/nonexistent", 1)
+	assert.Equal(t, &process{}, p)
+}
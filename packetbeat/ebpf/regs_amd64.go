@@ -0,0 +1,10 @@
+// +build linux
+
+package ebpf
+
+const sysBPF = 321
+
+var regs = ptRegs{
+	arg2: 104, // si
+	ret:  80,  // ax
+}
@@ -0,0 +1,10 @@
+// +build linux
+
+package ebpf
+
+const sysBPF = 280
+
+var regs = ptRegs{
+	arg2: 8, // regs[1]
+	ret:  0, // regs[0]
+}
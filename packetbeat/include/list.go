@@ -0,0 +1,23 @@
+/*
+Package include imports all protocol packages so that they register with the
+global registry. This package can be imported in the main package to
+automatically register all of the supported Packetbeat protocols.
+*/
+package include
+
+import (
+	_ "github.com/elastic/beats/packetbeat/protos/amqp"
+	_ "github.com/elastic/beats/packetbeat/protos/dns"
+	_ "github.com/elastic/beats/packetbeat/protos/ftp"
+	_ "github.com/elastic/beats/packetbeat/protos/http"
+	_ "github.com/elastic/beats/packetbeat/protos/ldap"
+	_ "github.com/elastic/beats/packetbeat/protos/memcache"
+	_ "github.com/elastic/beats/packetbeat/protos/modbus"
+	_ "github.com/elastic/beats/packetbeat/protos/mongodb"
+	_ "github.com/elastic/beats/packetbeat/protos/mysql"
+	_ "github.com/elastic/beats/packetbeat/protos/nfs"
+	_ "github.com/elastic/beats/packetbeat/protos/pgsql"
+	_ "github.com/elastic/beats/packetbeat/protos/redis"
+	_ "github.com/elastic/beats/packetbeat/protos/smtp"
+	_ "github.com/elastic/beats/packetbeat/protos/thrift"
+)
@@ -37,6 +37,7 @@ type Amqp struct {
 var (
 	unmatchedRequests  = expvar.NewInt("amqp.unmatched_requests")
 	unmatchedResponses = expvar.NewInt("amqp.unmatched_responses")
+	parseErrors        = expvar.NewInt("amqp.parse_errors")
 )
 
 func init() {
@@ -141,6 +142,16 @@ func (amqp *Amqp) GetPorts() []int {
 	return amqp.Ports
 }
 
+// Detect reports if the sample starts with the protocol header sent by the
+// client, "AMQP" followed by the version 0-9-1.
+func (amqp *Amqp) Detect(sample []byte, dir uint8) bool {
+	if len(sample) < 8 {
+		return false
+	}
+	isHeader, version := isProtocolHeader(sample)
+	return isHeader && version == "\x00\x09\x01"
+}
+
 func (amqp *Amqp) setFromConfig(config *amqpConfig) {
 	amqp.Ports = config.Ports
 	amqp.SendRequest = config.SendRequest
@@ -213,6 +224,7 @@ func (amqp *Amqp) Parse(pkt *protos.Packet, tcptuple *common.TcpTuple,
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			priv.Data[dir] = nil
 			return priv
 		}
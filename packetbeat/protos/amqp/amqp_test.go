@@ -1180,3 +1180,10 @@ func TestAmqp_MultipleBodyFrames(t *testing.T) {
 	assert.Equal(t, "basic.publish", trans["method"])
 	assert.Equal(t, "***hello I like to publish big messages***", trans["request"])
 }
+
+func TestAmqp_Detect(t *testing.T) {
+	amqp := Amqp{}
+	assert.True(t, amqp.Detect([]byte("AMQP\x00\x00\x09\x01"), 0))
+	assert.False(t, amqp.Detect([]byte("AMQP\x00\x00"), 0))
+	assert.False(t, amqp.Detect([]byte("AMQP\x01\x01\x00\x0a"), 0))
+}
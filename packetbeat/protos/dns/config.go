@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"github.com/elastic/beats/libbeat/common/dbfile"
 	"github.com/elastic/beats/packetbeat/config"
 	"github.com/elastic/beats/packetbeat/protos"
 )
@@ -9,6 +10,10 @@ type dnsConfig struct {
 	config.ProtocolCommon `config:",inline"`
 	Include_authorities   bool `config:"include_authorities"`
 	Include_additionals   bool `config:"include_additionals"`
+
+	// PublicSuffixList replaces the built-in public suffix list used to
+	// compute dns.question.etld_plus_one.
+	PublicSuffixList *dbfile.Config `config:"public_suffix_list"`
 }
 
 var (
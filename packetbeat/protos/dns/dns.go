@@ -18,6 +18,8 @@ import (
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/common/dbfile"
+	"github.com/elastic/beats/libbeat/common/suffixlist"
 	"github.com/elastic/beats/libbeat/logp"
 
 	"github.com/elastic/beats/packetbeat/protos"
@@ -45,6 +47,7 @@ type Transport uint8
 var (
 	unmatchedRequests  = expvar.NewInt("dns.unmatched_requests")
 	unmatchedResponses = expvar.NewInt("dns.unmatched_responses")
+	parseErrors        = expvar.NewInt("dns.parse_errors")
 )
 
 const (
@@ -163,6 +166,9 @@ type Dns struct {
 	Include_authorities bool
 	Include_additionals bool
 
+	// Public suffix list loaded from a file, nil if the built-in list is used.
+	publicSuffixList *dbfile.File
+
 	// Cache of active DNS transactions. The map key is the HashableDnsTuple
 	// associated with the request.
 	transactions       *common.Cache
@@ -221,6 +227,18 @@ func New(
 
 func (dns *Dns) init(results publish.Transactions, config *dnsConfig) error {
 	dns.setFromConfig(config)
+
+	if config.PublicSuffixList != nil {
+		list, err := dbfile.Open("public suffix list", *config.PublicSuffixList,
+			func(path string) (interface{}, error) {
+				return suffixlist.Load(path)
+			})
+		if err != nil {
+			return err
+		}
+		dns.publicSuffixList = list
+	}
+
 	dns.transactions = common.NewCacheWithRemovalListener(
 		dns.transactionTimeout,
 		protos.DefaultTransactionHashSize,
@@ -383,7 +401,7 @@ func (dns *Dns) publishTransaction(t *DnsTransaction) {
 			event["resource"] = t.Request.Data.Question[0].Name
 		}
 		addDnsToMapStr(dnsEvent, t.Response.Data, dns.Include_authorities,
-			dns.Include_additionals)
+			dns.Include_additionals, dns.suffixList())
 
 		if t.Response.Data.Rcode == 0 {
 			event["status"] = common.OK_STATUS
@@ -403,7 +421,7 @@ func (dns *Dns) publishTransaction(t *DnsTransaction) {
 			event["resource"] = t.Request.Data.Question[0].Name
 		}
 		addDnsToMapStr(dnsEvent, t.Request.Data, dns.Include_authorities,
-			dns.Include_additionals)
+			dns.Include_additionals, dns.suffixList())
 
 		if dns.Send_request {
 			event["request"] = dnsToString(t.Request.Data)
@@ -416,7 +434,7 @@ func (dns *Dns) publishTransaction(t *DnsTransaction) {
 			event["resource"] = t.Response.Data.Question[0].Name
 		}
 		addDnsToMapStr(dnsEvent, t.Response.Data, dns.Include_authorities,
-			dns.Include_additionals)
+			dns.Include_additionals, dns.suffixList())
 		if dns.Send_response {
 			event["response"] = dnsToString(t.Response.Data)
 		}
@@ -433,7 +451,25 @@ func (dns *Dns) expireTransaction(t *DnsTransaction) {
 }
 
 // Adds the DNS message data to the supplied MapStr.
-func addDnsToMapStr(m common.MapStr, dns *mkdns.Msg, authority bool, additional bool) {
+// suffixList returns the configured public suffix list, or nil if the
+// built-in list is used.
+func (dns *Dns) suffixList() *suffixlist.List {
+	if dns.publicSuffixList == nil {
+		return nil
+	}
+	return dns.publicSuffixList.Get().(*suffixlist.List)
+}
+
+// addDnsToMapStr adds the fields of the DNS message to m. The eTLD+1 of the
+// question is computed with suffixes, or with the built-in public suffix list
+// if suffixes is nil.
+func addDnsToMapStr(
+	m common.MapStr,
+	dns *mkdns.Msg,
+	authority bool,
+	additional bool,
+	suffixes *suffixlist.List,
+) {
 	m["id"] = dns.Id
 	m["op_code"] = dnsOpCodeToString(dns.Opcode)
 
@@ -456,7 +492,14 @@ func addDnsToMapStr(m common.MapStr, dns *mkdns.Msg, authority bool, additional
 		}
 		m["question"] = qMapStr
 
-		eTLDPlusOne, err := publicsuffix.EffectiveTLDPlusOne(strings.TrimRight(q.Name, "."))
+		var eTLDPlusOne string
+		var err error
+		name := strings.TrimRight(q.Name, ".")
+		if suffixes != nil {
+			eTLDPlusOne, err = suffixes.EffectiveTLDPlusOne(name)
+		} else {
+			eTLDPlusOne, err = publicsuffix.EffectiveTLDPlusOne(name)
+		}
 		if err == nil {
 			qMapStr["etld_plus_one"] = eTLDPlusOne + "."
 		}
@@ -108,6 +108,7 @@ func (dns *Dns) doParse(conn *dnsConnectionData, pkt *protos.Packet, tcpTuple *c
 
 		// This means that malformed requests or responses are being sent...
 		// TODO: publish the situation also if Request
+		parseErrors.Add(1)
 		conn.Data[dir] = nil
 		return conn
 	}
@@ -211,6 +212,7 @@ func (dns *Dns) GapInStream(tcpTuple *common.TcpTuple, dir uint8, nbytes int, pr
 	debugf("%s addresses %s, length %d", err.Error(),
 		tcpTuple.String(), len(stream.rawData))
 	debugf("Dropping the stream %s", tcpTuple.String())
+	parseErrors.Add(1)
 
 	// drop the stream because it is binary Data and it would be unexpected to have a decodable message later
 	return private, true
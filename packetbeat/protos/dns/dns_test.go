@@ -6,7 +6,9 @@ package dns
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -261,3 +263,38 @@ func assertAddress(t testing.TB, expected common.IpPortTuple, endpoint interface
 	assert.Equal(t, expected.Src_ip.String(), e.Ip)
 	assert.Equal(t, expected.Src_port, e.Port)
 }
+
+// Verify that the eTLD+1 is computed with the configured public suffix list.
+func TestPublicSuffixList(t *testing.T) {
+	f, err := ioutil.TempFile("", "public_suffix_list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("// elastic.co is a public suffix in this list\nco\nelastic.co\n")
+	f.Close()
+
+	results := &publish.ChanTransactions{Channel: make(chan common.MapStr, 100)}
+	cfg, _ := common.NewConfigFrom(map[string]interface{}{
+		"ports":              []int{ServerPort},
+		"public_suffix_list": map[string]interface{}{"path": f.Name()},
+	})
+	plugin, err := New(false, results, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dns := plugin.(*Dns)
+
+	msg, err := decodeDnsData(TransportUdp, elasticA.request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := common.MapStr{}
+	addDnsToMapStr(m, msg, false, false, dns.suffixList())
+	_, found := m["question"].(common.MapStr)["etld_plus_one"]
+	assert.False(t, found)
+
+	m = common.MapStr{}
+	addDnsToMapStr(m, msg, false, false, nil)
+	assert.Equal(t, "elastic.co.", m["question"].(common.MapStr)["etld_plus_one"])
+}
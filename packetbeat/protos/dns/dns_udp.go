@@ -23,6 +23,7 @@ func (dns *Dns) ParseUdp(pkt *protos.Packet) {
 		// that someone is attempting to the DNS port for non-DNS traffic. Both
 		// are issues that a monitoring system should report.
 		debugf("%s", err.Error())
+		parseErrors.Add(1)
 		return
 	}
 
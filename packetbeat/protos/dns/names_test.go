@@ -93,7 +93,7 @@ func assertDnsMessage(t testing.TB, q DnsTestMsg) {
 	}
 
 	mapStr := common.MapStr{}
-	addDnsToMapStr(mapStr, dns, true, true)
+	addDnsToMapStr(mapStr, dns, true, true, nil)
 	if q.question != nil {
 		for k, v := range q.question {
 			assert.NotNil(t, mapStr["question"].(common.MapStr)[k])
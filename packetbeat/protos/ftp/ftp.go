@@ -0,0 +1,520 @@
+package ftp
+
+import (
+	"bytes"
+	"errors"
+	"expvar"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+
+	"github.com/elastic/beats/packetbeat/procs"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/protos/textproto"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+type stream struct {
+	tcptuple *common.TcpTuple
+	data     []byte
+
+	// set once the first line of the stream tells if the server or the
+	// client is sending
+	classified bool
+	isServer   bool
+}
+
+type request struct {
+	ts      time.Time
+	tuple   common.TcpTuple
+	dir     uint8
+	cmdline *common.CmdlineTuple
+	verb    string
+	arg     string
+	query   string
+	size    int
+
+	// size of the preliminary replies, like 150 opening the data connection
+	replySize int
+
+	// size of the transferred file, if announced in a preliminary reply
+	transferSize int64
+}
+
+type ftpConnectionData struct {
+	streams  [2]*stream
+	requests []*request
+
+	// set once the greeting of the server is seen
+	greeted bool
+
+	// set after a successful AUTH TLS, the rest of the connection is
+	// encrypted and can not be analyzed
+	encrypted bool
+
+	user string
+
+	// data connection negotiated with PASV, EPSV, PORT or EPRT
+	passive  bool
+	dataPort int
+}
+
+// Ftp protocol plugin
+type Ftp struct {
+	// config
+	Ports        []int
+	SendRequest  bool
+	SendResponse bool
+
+	transactionTimeout time.Duration
+
+	results publish.Transactions
+}
+
+var (
+	debugf = logp.MakeDebug("ftp")
+)
+
+var (
+	unmatchedResponses = expvar.NewInt("ftp.unmatched_responses")
+	parseErrors        = expvar.NewInt("ftp.parse_errors")
+)
+
+var errInvalidCommand = errors.New("invalid command")
+
+// commands taking a path name as argument
+var pathCommands = map[string]bool{
+	"CWD": true, "XCWD": true, "SMNT": true,
+	"RETR": true, "STOR": true, "STOU": true, "APPE": true,
+	"RNFR": true, "RNTO": true, "DELE": true,
+	"MKD": true, "XMKD": true, "RMD": true, "XRMD": true,
+	"LIST": true, "NLST": true, "MLSD": true, "MLST": true,
+	"SIZE": true, "MDTM": true, "STAT": true,
+}
+
+// commands transferring data over the data connection, and the direction of
+// the transfer as seen from the client
+var transferCommands = map[string]string{
+	"RETR": "download",
+	"LIST": "download",
+	"NLST": "download",
+	"MLSD": "download",
+	"STOR": "upload",
+	"STOU": "upload",
+	"APPE": "upload",
+}
+
+var (
+	// size of the file announced when opening the data connection, like
+	// "150 Opening BINARY mode data connection for a.txt (1024 bytes)"
+	transferSizeRe = regexp.MustCompile(`\((\d+) bytes\)`)
+
+	// address of the data connection, like
+	// "227 Entering Passive Mode (192,168,0,2,195,80)"
+	hostPortRe = regexp.MustCompile(`(\d+),(\d+),(\d+),(\d+),(\d+),(\d+)`)
+)
+
+func init() {
+	protos.Register("ftp", New)
+}
+
+func New(
+	testMode bool,
+	results publish.Transactions,
+	cfg *common.Config,
+) (protos.Plugin, error) {
+	p := &Ftp{}
+	config := defaultConfig
+	if !testMode {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.init(results, &config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (ftp *Ftp) init(results publish.Transactions, config *ftpConfig) error {
+	ftp.setFromConfig(config)
+	ftp.results = results
+	return nil
+}
+
+func (ftp *Ftp) setFromConfig(config *ftpConfig) {
+	ftp.Ports = config.Ports
+	ftp.SendRequest = config.SendRequest
+	ftp.SendResponse = config.SendResponse
+	ftp.transactionTimeout = config.TransactionTimeout
+}
+
+func (ftp *Ftp) GetPorts() []int {
+	return ftp.Ports
+}
+
+// Detect reports if the sample starts with the greeting of an FTP server.
+func (ftp *Ftp) Detect(sample []byte, dir uint8) bool {
+	line, n := textproto.ReadLine(sample)
+	if n == 0 || !textproto.IsReply(sample) {
+		return false
+	}
+	return string(line[:3]) == "220" &&
+		strings.Contains(strings.ToUpper(string(line)), "FTP")
+}
+
+func (ftp *Ftp) ConnectionTimeout() time.Duration {
+	return ftp.transactionTimeout
+}
+
+func (ftp *Ftp) Parse(
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+	private protos.ProtocolData,
+) protos.ProtocolData {
+	defer logp.Recover("ParseFtp exception")
+
+	conn := ensureFtpConnection(private)
+	return ftp.doParse(conn, pkt, tcptuple, dir)
+}
+
+func ensureFtpConnection(private protos.ProtocolData) *ftpConnectionData {
+	if private == nil {
+		return &ftpConnectionData{}
+	}
+
+	priv, ok := private.(*ftpConnectionData)
+	if !ok {
+		logp.Warn("ftp connection data type error, create new one")
+		return &ftpConnectionData{}
+	}
+	if priv == nil {
+		logp.Warn("Unexpected: ftp connection data not set, create new one")
+		return &ftpConnectionData{}
+	}
+
+	return priv
+}
+
+func (ftp *Ftp) doParse(
+	conn *ftpConnectionData,
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) *ftpConnectionData {
+	if conn.encrypted {
+		return conn
+	}
+
+	st := conn.streams[dir]
+	if st == nil {
+		st = &stream{tcptuple: tcptuple}
+		conn.streams[dir] = st
+		debugf("new stream: %p (dir=%v, len=%v)", st, dir, len(pkt.Payload))
+	}
+
+	st.data = append(st.data, pkt.Payload...)
+	if len(st.data) > tcp.TCP_MAX_DATA_IN_STREAM {
+		debugf("Stream data too large, dropping TCP stream")
+		conn.streams[dir] = nil
+		return conn
+	}
+
+	for len(st.data) > 0 && !conn.encrypted {
+		if !st.classified {
+			if _, n := textproto.ReadLine(st.data); n == 0 && len(st.data) < 4 {
+				break
+			}
+			st.isServer = textproto.IsReply(st.data)
+			st.classified = true
+		}
+
+		var complete bool
+		var err error
+		if st.isServer {
+			complete, err = ftp.parseReply(conn, st, pkt.Ts)
+		} else {
+			complete, err = ftp.parseCommand(conn, st, pkt.Ts, tcptuple, dir)
+		}
+		if err != nil {
+			// drop this tcp stream. Will retry parsing with the next
+			// segment in it
+			parseErrors.Add(1)
+			conn.streams[dir] = nil
+			debugf("Ignore FTP message (%v). Drop tcp stream.", err)
+			return conn
+		}
+
+		if !complete {
+			// wait for more data
+			break
+		}
+	}
+
+	return conn
+}
+
+func (ftp *Ftp) parseCommand(
+	conn *ftpConnectionData,
+	st *stream,
+	ts time.Time,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) (bool, error) {
+	line, n := textproto.ReadLine(st.data)
+	if n == 0 {
+		if len(st.data) > textproto.MaxLineLength {
+			return false, textproto.ErrLineTooLong
+		}
+		return false, nil
+	}
+	st.data = st.data[n:]
+
+	// Telnet interrupt and synch signals can precede ABOR
+	line = bytes.TrimLeft(line, "\xff\xf4\xf2")
+
+	verb, arg := textproto.ParseCommand(line)
+	if !validVerb(verb) {
+		return false, errInvalidCommand
+	}
+
+	query := string(line)
+	switch verb {
+	case "PASS", "ACCT":
+		// never report credentials
+		query = verb
+	case "USER":
+		conn.user = arg
+	case "PORT", "EPRT":
+		conn.passive = false
+		conn.dataPort = dataPort(arg)
+	}
+
+	conn.requests = append(conn.requests, &request{
+		ts:      ts,
+		tuple:   *tcptuple,
+		dir:     dir,
+		cmdline: procs.ProcWatcher.FindProcessesTuple(tcptuple.IpPort()),
+		verb:    verb,
+		arg:     arg,
+		query:   query,
+		size:    n,
+	})
+	return true, nil
+}
+
+func (ftp *Ftp) parseReply(
+	conn *ftpConnectionData,
+	st *stream,
+	ts time.Time,
+) (bool, error) {
+	reply, n, err := textproto.ParseReply(st.data)
+	if err != nil {
+		return false, err
+	}
+	if reply == nil {
+		return false, nil
+	}
+	st.data = st.data[n:]
+
+	ftp.handleReply(conn, reply, ts)
+	return true, nil
+}
+
+func (ftp *Ftp) handleReply(
+	conn *ftpConnectionData,
+	reply *textproto.Reply,
+	ts time.Time,
+) {
+	if len(conn.requests) == 0 {
+		if !conn.greeted && !reply.Preliminary() {
+			debugf("FTP greeting: %d %s", reply.Code, reply.Text())
+			conn.greeted = true
+			return
+		}
+		if reply.Preliminary() {
+			// "120 Service ready in nnn minutes" before the greeting
+			return
+		}
+		debugf("Response from unknown transaction. Ignoring")
+		unmatchedResponses.Add(1)
+		return
+	}
+	conn.greeted = true
+
+	req := conn.requests[0]
+	if reply.Preliminary() {
+		// the final reply follows, like after the data transfer
+		req.replySize += reply.Size
+		if m := transferSizeRe.FindStringSubmatch(reply.Text()); m != nil {
+			req.transferSize, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		return
+	}
+
+	conn.requests = conn.requests[1:]
+	event := ftp.newTransaction(conn, req, reply, ts)
+	if ftp.results != nil {
+		ftp.results.PublishTransaction(event)
+	}
+}
+
+func (ftp *Ftp) newTransaction(
+	conn *ftpConnectionData,
+	req *request,
+	reply *textproto.Reply,
+	ts time.Time,
+) common.MapStr {
+	status := common.OK_STATUS
+	if reply.Failed() {
+		status = common.ERROR_STATUS
+	}
+
+	fields := common.MapStr{
+		"code":    reply.Code,
+		"message": reply.Text(),
+	}
+	if conn.user != "" {
+		fields["user"] = conn.user
+	}
+
+	switch req.verb {
+	case "PASV":
+		if reply.Code == 227 {
+			conn.passive = true
+			conn.dataPort = dataPort(reply.Text())
+		}
+	case "EPSV":
+		if reply.Code == 229 {
+			conn.passive = true
+			conn.dataPort = extendedDataPort(reply.Text())
+		}
+	case "AUTH":
+		if reply.Code == 234 {
+			// TLS handshake follows, stop analyzing the connection
+			fields["auth_tls"] = true
+			conn.encrypted = true
+			conn.streams = [2]*stream{}
+			conn.requests = nil
+		}
+	}
+
+	if direction, ok := transferCommands[req.verb]; ok {
+		transfer := common.MapStr{
+			"direction": direction,
+		}
+		if conn.passive {
+			transfer["mode"] = "passive"
+		} else {
+			transfer["mode"] = "active"
+		}
+		if conn.dataPort > 0 {
+			transfer["data_port"] = conn.dataPort
+		}
+		if req.transferSize > 0 {
+			transfer["size"] = req.transferSize
+		}
+		fields["transfer"] = transfer
+	}
+
+	src := &common.Endpoint{
+		Ip:   req.tuple.Src_ip.String(),
+		Port: req.tuple.Src_port,
+		Proc: string(req.cmdline.Src),
+	}
+	dst := &common.Endpoint{
+		Ip:   req.tuple.Dst_ip.String(),
+		Port: req.tuple.Dst_port,
+		Proc: string(req.cmdline.Dst),
+	}
+	if req.dir == tcp.TcpDirectionReverse {
+		src, dst = dst, src
+	}
+
+	// resp_time in milliseconds
+	responseTime := int32(ts.Sub(req.ts).Nanoseconds() / 1e6)
+
+	event := common.MapStr{
+		"@timestamp":   common.Time(req.ts),
+		"type":         "ftp",
+		"status":       status,
+		"responsetime": responseTime,
+		"ftp":          fields,
+		"method":       req.verb,
+		"query":        req.query,
+		"bytes_in":     uint64(req.size),
+		"bytes_out":    uint64(req.replySize + reply.Size),
+		"src":          src,
+		"dst":          dst,
+	}
+	if pathCommands[req.verb] && req.arg != "" {
+		event["resource"] = req.arg
+	}
+	if ftp.SendRequest {
+		event["request"] = req.query
+	}
+	if ftp.SendResponse {
+		event["response"] = reply.Text()
+	}
+
+	return event
+}
+
+// dataPort returns the port of the host-port argument of PORT, or of the
+// reply to PASV, like "192,168,0,2,195,80". It also accepts the
+// "|1|192.168.0.2|50000|" argument of EPRT.
+func dataPort(s string) int {
+	if m := hostPortRe.FindStringSubmatch(s); m != nil {
+		hi, _ := strconv.Atoi(m[5])
+		lo, _ := strconv.Atoi(m[6])
+		return hi<<8 | lo
+	}
+	return extendedDataPort(s)
+}
+
+// extendedDataPort returns the port of the reply to EPSV, like
+// "Entering Extended Passive Mode (|||50000|)", or of the argument of EPRT.
+func extendedDataPort(s string) int {
+	start := strings.IndexAny(s, "|!#$")
+	if start < 0 {
+		return 0
+	}
+	fields := strings.Split(s[start+1:], s[start:start+1])
+	if len(fields) < 3 {
+		return 0
+	}
+	port, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+func validVerb(verb string) bool {
+	if len(verb) == 0 || len(verb) > 16 {
+		return false
+	}
+	for _, c := range verb {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (ftp *Ftp) GapInStream(tcptuple *common.TcpTuple, dir uint8,
+	nbytes int, private protos.ProtocolData) (priv protos.ProtocolData, drop bool) {
+	return private, true
+}
+
+func (ftp *Ftp) ReceivedFin(tcptuple *common.TcpTuple, dir uint8,
+	private protos.ProtocolData) protos.ProtocolData {
+	return private
+}
@@ -0,0 +1,180 @@
+// +build !integration
+
+package ftp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+func newTestFtp() *Ftp {
+	ftp := &Ftp{}
+	results := &publish.ChanTransactions{Channel: make(chan common.MapStr, 20)}
+	config := defaultConfig
+	ftp.init(results, &config)
+	return ftp
+}
+
+func testTcpTuple() *common.TcpTuple {
+	t := &common.TcpTuple{
+		Ip_length: 4,
+		Src_ip:    net.IPv4(192, 168, 0, 1), Dst_ip: net.IPv4(192, 168, 0, 2),
+		Src_port: 51234, Dst_port: 21,
+	}
+	t.ComputeHashebles()
+	return t
+}
+
+// session replays the segments alternating between the client and the
+// server, starting with the server.
+func session(ftp *Ftp, segments ...string) protos.ProtocolData {
+	tuple := testTcpTuple()
+	var private protos.ProtocolData
+	ts := time.Now()
+	for i, segment := range segments {
+		dir := uint8(tcp.TcpDirectionReverse)
+		if i%2 == 1 {
+			dir = tcp.TcpDirectionOriginal
+		}
+		ts = ts.Add(time.Millisecond)
+		pkt := &protos.Packet{Ts: ts, Payload: []byte(segment)}
+		private = ftp.Parse(pkt, tuple, dir, private)
+	}
+	return private
+}
+
+func events(ftp *Ftp) []common.MapStr {
+	client := ftp.results.(*publish.ChanTransactions)
+	var events []common.MapStr
+	for {
+		select {
+		case event := <-client.Channel:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestFtpSession(t *testing.T) {
+	ftp := newTestFtp()
+	session(ftp,
+		"220 (vsFTPd 3.0.3)\r\n",
+		"USER alice\r\n",
+		"331 Please specify the password.\r\n",
+		"PASS secret\r\n",
+		"230 Login successful.\r\n",
+		"PASV\r\n",
+		"227 Entering Passive Mode (192,168,0,2,195,80).\r\n",
+		"RETR report.pdf\r\n",
+		"150 Opening BINARY mode data connection for report.pdf (48213 bytes).\r\n",
+		"",
+		"226 Transfer complete.\r\n",
+		"PORT 192,168,0,1,200,10\r\nSTOR upload.txt\r\n",
+		"200 PORT command successful.\r\n150 Ok to send data.\r\n",
+		"",
+		"226 Transfer complete.\r\n",
+		"RETR missing.txt\r\n",
+		"550 Failed to open file.\r\n",
+	)
+
+	trans := events(ftp)
+	if !assert.Len(t, trans, 7) {
+		return
+	}
+
+	user := trans[0]
+	assert.Equal(t, "ftp", user["type"])
+	assert.Equal(t, "USER", user["method"])
+	assert.Equal(t, "alice", user["ftp"].(common.MapStr)["user"])
+	assert.Equal(t, 331, user["ftp"].(common.MapStr)["code"])
+	assert.Equal(t, "192.168.0.1", user["src"].(*common.Endpoint).Ip)
+	assert.Equal(t, uint16(21), user["dst"].(*common.Endpoint).Port)
+
+	assert.Equal(t, "PASS", trans[1]["query"])
+
+	retr := trans[3]
+	fields := retr["ftp"].(common.MapStr)
+	assert.Equal(t, "RETR report.pdf", retr["query"])
+	assert.Equal(t, "report.pdf", retr["resource"])
+	assert.Equal(t, common.OK_STATUS, retr["status"])
+	assert.Equal(t, 226, fields["code"])
+	assert.Equal(t, common.MapStr{
+		"direction": "download",
+		"mode":      "passive",
+		"data_port": 195<<8 | 80,
+		"size":      int64(48213),
+	}, fields["transfer"])
+	assert.Equal(t, int32(3), retr["responsetime"])
+
+	stor := trans[5]["ftp"].(common.MapStr)
+	assert.Equal(t, common.MapStr{
+		"direction": "upload",
+		"mode":      "active",
+		"data_port": 200<<8 | 10,
+	}, stor["transfer"])
+
+	assert.Equal(t, common.ERROR_STATUS, trans[6]["status"])
+}
+
+func TestFtpMultilineReply(t *testing.T) {
+	ftp := newTestFtp()
+	session(ftp,
+		"220-Welcome\r\n220 FTP server ready\r\n",
+		"FEAT\r\n",
+		"211-Features:\r\n EPSV\r\n MDTM\r\n211 End\r\n",
+		"EPSV\r\n",
+		"229 Entering Extended Passive Mode (|||50000|)\r\n",
+		"LIST\r\n",
+		"150 Here comes the directory listing.\r\n226 Directory send OK.\r\n",
+	)
+
+	trans := events(ftp)
+	if assert.Len(t, trans, 3) {
+		assert.Equal(t, "Features:\n EPSV\n MDTM\nEnd", trans[0]["ftp"].(common.MapStr)["message"])
+		transfer := trans[2]["ftp"].(common.MapStr)["transfer"].(common.MapStr)
+		assert.Equal(t, 50000, transfer["data_port"])
+		assert.Nil(t, trans[2]["resource"])
+	}
+}
+
+func TestFtpAuthTLS(t *testing.T) {
+	ftp := newTestFtp()
+	private := session(ftp,
+		"220 FTP server ready\r\n",
+		"AUTH TLS\r\n",
+		"234 Proceed with negotiation.\r\n",
+		"\x16\x03\x01\x00\xa5\x01\x00\x00\xa1",
+	)
+
+	trans := events(ftp)
+	if assert.Len(t, trans, 1) {
+		assert.Equal(t, true, trans[0]["ftp"].(common.MapStr)["auth_tls"])
+	}
+	assert.True(t, private.(*ftpConnectionData).encrypted)
+	assert.Equal(t, int64(0), parseErrors.Value())
+}
+
+func TestFtpDataPort(t *testing.T) {
+	assert.Equal(t, 195<<8|80, dataPort("Entering Passive Mode (10,0,0,1,195,80)"))
+	assert.Equal(t, 6275, dataPort("|1|132.235.1.2|6275|"))
+	assert.Equal(t, 5282, extendedDataPort("|2|1080::8:800:200C:417A|5282|"))
+	assert.Equal(t, 0, extendedDataPort("Entering Extended Passive Mode"))
+}
+
+func TestFtpDetect(t *testing.T) {
+	ftp := newTestFtp()
+
+	assert.True(t, ftp.Detect([]byte("220 ProFTPD Server ready.\r\n"), 0))
+	assert.True(t, ftp.Detect([]byte("220 (vsFTPd 3.0.3)\r\n"), 0))
+	assert.False(t, ftp.Detect([]byte("220 mx.example.com ESMTP\r\n"), 0))
+	assert.False(t, ftp.Detect([]byte("USER alice\r\n"), 1))
+}
@@ -34,6 +34,7 @@ const (
 
 var (
 	unmatchedResponses = expvar.NewInt("http.unmatched_responses")
+	parseErrors        = expvar.NewInt("http.parse_errors")
 )
 
 type stream struct {
@@ -145,6 +146,43 @@ func (http *HTTP) GetPorts() []int {
 	return http.Ports
 }
 
+// Detect reports if the sample starts with an HTTP/1.x request or status
+// line.
+func (http *HTTP) Detect(sample []byte, dir uint8) bool {
+	i := bytes.Index(sample, []byte("\r\n"))
+	if i == -1 {
+		return false
+	}
+	fields := bytes.Fields(sample[:i])
+	if len(fields) < 2 {
+		return false
+	}
+
+	if bytes.HasPrefix(fields[0], []byte("HTTP/1.")) {
+		// response
+		code := fields[1]
+		if len(code) != 3 {
+			return false
+		}
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(fields) != 3 || !bytes.HasPrefix(fields[2], []byte("HTTP/1.")) {
+		return false
+	}
+	for _, c := range fields[0] {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 // messageGap is called when a gap of size `nbytes` is found in the
 // tcp stream. Decides if we can ignore the gap or it's a parser error
 // and we need to drop the stream.
@@ -291,6 +329,7 @@ func (http *HTTP) doParse(
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			conn.Streams[dir] = nil
 			return conn
 		}
@@ -1221,3 +1221,13 @@ func BenchmarkHttpSimpleTransaction(b *testing.B) {
 		}
 	}
 }
+
+func TestHttpDetect(t *testing.T) {
+	http := HTTP{}
+	assert.True(t, http.Detect([]byte("GET /index.html HTTP/1.1\r\nHost: localhost\r\n"), 0))
+	assert.True(t, http.Detect([]byte("HTTP/1.0 404 Not Found\r\n"), 1))
+	assert.False(t, http.Detect([]byte("GET /index.html HTTP/1.1"), 0))
+	assert.False(t, http.Detect([]byte("get /index.html HTTP/1.1\r\n"), 0))
+	assert.False(t, http.Detect([]byte("SSH-2.0-OpenSSH_7.4\r\n"), 0))
+	assert.False(t, http.Detect([]byte("HTTP/1.1 OK\r\n"), 1))
+}
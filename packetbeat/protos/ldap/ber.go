@@ -0,0 +1,157 @@
+package ldap
+
+import (
+	"errors"
+)
+
+// Classes of BER tags.
+const (
+	classUniversal   = 0
+	classApplication = 1
+	classContext     = 2
+	classPrivate     = 3
+)
+
+// Universal tags used by LDAP.
+const (
+	tagBoolean     = 1
+	tagInteger     = 2
+	tagOctetString = 4
+	tagEnumerated  = 10
+	tagSequence    = 16
+	tagSet         = 17
+)
+
+var (
+	// errIncomplete is returned if more data is required to decode the
+	// element.
+	errIncomplete = errors.New("incomplete BER element")
+
+	errInvalidElement = errors.New("invalid BER element")
+	errUnexpectedType = errors.New("unexpected BER element type")
+)
+
+// element is a BER encoded data element. LDAP only uses the definite length
+// form (RFC 4511, section 5.1).
+type element struct {
+	class       uint8
+	constructed bool
+	tag         int
+	data        []byte // content octets
+}
+
+// readElement decodes the element at the start of data and returns the
+// number of bytes it takes.
+func readElement(data []byte) (element, int, error) {
+	var e element
+	if len(data) < 2 {
+		return e, 0, errIncomplete
+	}
+
+	e.class = data[0] >> 6
+	e.constructed = data[0]&0x20 != 0
+	e.tag = int(data[0] & 0x1f)
+	offset := 1
+	if e.tag == 0x1f {
+		// high tag number form
+		e.tag = 0
+		for {
+			if offset >= len(data) {
+				return e, 0, errIncomplete
+			}
+			b := data[offset]
+			offset++
+			e.tag = e.tag<<7 | int(b&0x7f)
+			if b&0x80 == 0 {
+				break
+			}
+			if offset > 4 {
+				return e, 0, errInvalidElement
+			}
+		}
+	}
+
+	if offset >= len(data) {
+		return e, 0, errIncomplete
+	}
+	length := int(data[offset])
+	offset++
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			// indefinite form or unsupported size
+			return e, 0, errInvalidElement
+		}
+		if offset+n > len(data) {
+			return e, 0, errIncomplete
+		}
+		length = 0
+		for _, b := range data[offset : offset+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+		if length < 0 {
+			return e, 0, errInvalidElement
+		}
+	}
+
+	if len(data)-offset < length {
+		return e, 0, errIncomplete
+	}
+	e.data = data[offset : offset+length]
+	return e, offset + length, nil
+}
+
+// is reports if the element has the class and tag.
+func (e element) is(class uint8, tag int) bool {
+	return e.class == class && e.tag == tag
+}
+
+// children decodes the elements of a constructed element.
+func (e element) children() ([]element, error) {
+	if !e.constructed {
+		return nil, errUnexpectedType
+	}
+
+	var elems []element
+	for data := e.data; len(data) > 0; {
+		child, n, err := readElement(data)
+		if err == errIncomplete {
+			return nil, errInvalidElement
+		}
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, child)
+		data = data[n:]
+	}
+	return elems, nil
+}
+
+// int decodes the element as two's complement integer, as used by INTEGER
+// and ENUMERATED.
+func (e element) int() (int64, error) {
+	if e.constructed || len(e.data) == 0 || len(e.data) > 8 {
+		return 0, errInvalidElement
+	}
+
+	v := int64(int8(e.data[0]))
+	for _, b := range e.data[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// bool decodes the element as BOOLEAN.
+func (e element) bool() (bool, error) {
+	if e.constructed || len(e.data) != 1 {
+		return false, errInvalidElement
+	}
+	return e.data[0] != 0, nil
+}
+
+// str returns the content octets of a primitive element, as used by OCTET
+// STRING and LDAPString.
+func (e element) str() string {
+	return string(e.data)
+}
@@ -0,0 +1,19 @@
+package ldap
+
+import (
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/elastic/beats/packetbeat/protos"
+)
+
+type ldapConfig struct {
+	config.ProtocolCommon `config:",inline"`
+	RedactValues          bool `config:"redact_values"`
+}
+
+var (
+	defaultConfig = ldapConfig{
+		ProtocolCommon: config.ProtocolCommon{
+			TransactionTimeout: protos.DefaultTransactionExpiration,
+		},
+	}
+)
@@ -0,0 +1,403 @@
+package ldap
+
+import (
+	"expvar"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+
+	"github.com/elastic/beats/packetbeat/procs"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+// maximum number of requests waiting for their response per connection,
+// older requests are dropped
+const maxPendingRequests = 64
+
+type stream struct {
+	tcptuple *common.TcpTuple
+	data     []byte
+}
+
+// message is a decoded LDAPMessage.
+type message struct {
+	ts      time.Time
+	tuple   common.TcpTuple
+	dir     uint8
+	cmdline *common.CmdlineTuple
+	size    int
+
+	id        int64
+	op        int
+	isRequest bool
+
+	// request
+	dn        string
+	query     string
+	fields    common.MapStr // operation specific fields
+	oid       string        // name of extended requests
+	abandonID int64
+
+	// response
+	result *result
+}
+
+// transaction is a request waiting for its final response. Searches receive
+// the found entries and references before the final response.
+type transaction struct {
+	request    *message
+	entries    int
+	references int
+	respSize   int
+}
+
+type ldapConnectionData struct {
+	streams      [2]*stream
+	transactions []*transaction
+
+	// set after a successful StartTLS, the rest of the connection is
+	// encrypted and can not be analyzed
+	encrypted bool
+}
+
+// Ldap protocol plugin
+type Ldap struct {
+	// config
+	Ports        []int
+	SendRequest  bool
+	SendResponse bool
+
+	transactionTimeout time.Duration
+
+	decoder decoder
+
+	results publish.Transactions
+}
+
+var (
+	debugf = logp.MakeDebug("ldap")
+)
+
+var (
+	unmatchedRequests  = expvar.NewInt("ldap.unmatched_requests")
+	unmatchedResponses = expvar.NewInt("ldap.unmatched_responses")
+	parseErrors        = expvar.NewInt("ldap.parse_errors")
+)
+
+func init() {
+	protos.Register("ldap", New)
+}
+
+func New(
+	testMode bool,
+	results publish.Transactions,
+	cfg *common.Config,
+) (protos.Plugin, error) {
+	p := &Ldap{}
+	config := defaultConfig
+	if !testMode {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.init(results, &config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (ldap *Ldap) init(results publish.Transactions, config *ldapConfig) error {
+	ldap.setFromConfig(config)
+	ldap.results = results
+	return nil
+}
+
+func (ldap *Ldap) setFromConfig(config *ldapConfig) {
+	ldap.Ports = config.Ports
+	ldap.SendRequest = config.SendRequest
+	ldap.SendResponse = config.SendResponse
+	ldap.transactionTimeout = config.TransactionTimeout
+	ldap.decoder.redactValues = config.RedactValues
+}
+
+func (ldap *Ldap) GetPorts() []int {
+	return ldap.Ports
+}
+
+// Detect reports if the sample starts with a bind, search or extended request,
+// which start most LDAP sessions.
+func (ldap *Ldap) Detect(sample []byte, dir uint8) bool {
+	top, _, err := readElement(sample)
+	if err != nil || !top.is(classUniversal, tagSequence) {
+		return false
+	}
+	elems, err := top.children()
+	if err != nil || len(elems) < 2 ||
+		!elems[0].is(classUniversal, tagInteger) ||
+		elems[1].class != classApplication || !elems[1].constructed {
+		return false
+	}
+
+	switch elems[1].tag {
+	case opBindRequest, opSearchRequest, opExtendedRequest:
+		return true
+	}
+	return false
+}
+
+func (ldap *Ldap) ConnectionTimeout() time.Duration {
+	return ldap.transactionTimeout
+}
+
+func (ldap *Ldap) Parse(
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+	private protos.ProtocolData,
+) protos.ProtocolData {
+	defer logp.Recover("ParseLdap exception")
+
+	conn := ensureLdapConnection(private)
+	return ldap.doParse(conn, pkt, tcptuple, dir)
+}
+
+func ensureLdapConnection(private protos.ProtocolData) *ldapConnectionData {
+	if private == nil {
+		return &ldapConnectionData{}
+	}
+
+	priv, ok := private.(*ldapConnectionData)
+	if !ok {
+		logp.Warn("ldap connection data type error, create new one")
+		return &ldapConnectionData{}
+	}
+	if priv == nil {
+		logp.Warn("Unexpected: ldap connection data not set, create new one")
+		return &ldapConnectionData{}
+	}
+
+	return priv
+}
+
+func (ldap *Ldap) doParse(
+	conn *ldapConnectionData,
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) *ldapConnectionData {
+	if conn.encrypted {
+		return conn
+	}
+
+	st := conn.streams[dir]
+	if st == nil {
+		st = &stream{tcptuple: tcptuple}
+		conn.streams[dir] = st
+		debugf("new stream: %p (dir=%v, len=%v)", st, dir, len(pkt.Payload))
+	}
+
+	st.data = append(st.data, pkt.Payload...)
+	if len(st.data) > tcp.TCP_MAX_DATA_IN_STREAM {
+		debugf("Stream data too large, dropping TCP stream")
+		conn.streams[dir] = nil
+		return conn
+	}
+
+	for len(st.data) > 0 && !conn.encrypted {
+		top, n, err := readElement(st.data)
+		if err == errIncomplete {
+			// wait for more data
+			break
+		}
+
+		msg := &message{
+			ts:      pkt.Ts,
+			tuple:   *tcptuple,
+			dir:     dir,
+			cmdline: procs.ProcWatcher.FindProcessesTuple(tcptuple.IpPort()),
+			size:    n,
+		}
+		if err == nil {
+			err = ldap.decoder.decodeMessage(top, msg)
+		}
+		if err != nil {
+			// drop this tcp stream. Will retry parsing with the next
+			// segment in it
+			parseErrors.Add(1)
+			conn.streams[dir] = nil
+			debugf("Ignore LDAP message (%v). Drop tcp stream.", err)
+			return conn
+		}
+		st.data = st.data[n:]
+
+		if msg.isRequest {
+			ldap.handleRequest(conn, msg)
+		} else {
+			ldap.handleResponse(conn, msg)
+		}
+	}
+
+	return conn
+}
+
+func (ldap *Ldap) handleRequest(conn *ldapConnectionData, msg *message) {
+	switch msg.op {
+	case opUnbindRequest:
+		// no response, the connection is closed
+		return
+	case opAbandonRequest:
+		// the server does not respond to abandoned operations
+		if i := conn.findTransaction(msg.abandonID); i >= 0 {
+			conn.removeTransaction(i)
+		}
+		return
+	}
+
+	if len(conn.transactions) >= maxPendingRequests {
+		debugf("Too many pending requests, dropping the oldest one")
+		unmatchedRequests.Add(1)
+		conn.removeTransaction(0)
+	}
+	conn.transactions = append(conn.transactions, &transaction{request: msg})
+}
+
+func (ldap *Ldap) handleResponse(conn *ldapConnectionData, msg *message) {
+	i := conn.findTransaction(msg.id)
+	if i < 0 || (msg.op != opIntermediateResponse &&
+		conn.transactions[i].request.op != requestOf(msg.op)) {
+		debugf("Response from unknown transaction. Ignoring")
+		unmatchedResponses.Add(1)
+		return
+	}
+
+	trans := conn.transactions[i]
+	trans.respSize += msg.size
+	switch msg.op {
+	case opSearchResultEntry:
+		trans.entries++
+		return
+	case opSearchResultReference:
+		trans.references++
+		return
+	case opIntermediateResponse:
+		return
+	}
+
+	conn.removeTransaction(i)
+	event := ldap.newTransaction(trans, msg)
+	if trans.request.oid == oidStartTLS && msg.result.code == resultSuccess {
+		// TLS handshake follows, stop analyzing the connection
+		conn.encrypted = true
+		conn.streams = [2]*stream{}
+		conn.transactions = nil
+	}
+
+	if ldap.results != nil {
+		ldap.results.PublishTransaction(event)
+	}
+}
+
+func (conn *ldapConnectionData) findTransaction(id int64) int {
+	for i, trans := range conn.transactions {
+		if trans.request.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (conn *ldapConnectionData) removeTransaction(i int) {
+	conn.transactions = append(conn.transactions[:i], conn.transactions[i+1:]...)
+}
+
+func (ldap *Ldap) newTransaction(trans *transaction, resp *message) common.MapStr {
+	requ := trans.request
+	res := resp.result
+	operation := operationNames[requ.op]
+
+	status := common.OK_STATUS
+	if !res.ok() {
+		status = common.ERROR_STATUS
+	}
+
+	fields := common.MapStr{
+		"message_id":  requ.id,
+		"operation":   operation,
+		"result_code": res.code,
+		"result":      res.name(),
+	}
+	if res.matchedDN != "" {
+		fields["matched_dn"] = res.matchedDN
+	}
+	if res.diagnostic != "" {
+		fields["diagnostic_message"] = res.diagnostic
+	}
+	if requ.fields != nil {
+		fields[operation] = requ.fields
+	}
+	if requ.op == opSearchRequest {
+		requ.fields["entries"] = trans.entries
+		requ.fields["references"] = trans.references
+	}
+
+	src := &common.Endpoint{
+		Ip:   requ.tuple.Src_ip.String(),
+		Port: requ.tuple.Src_port,
+		Proc: string(requ.cmdline.Src),
+	}
+	dst := &common.Endpoint{
+		Ip:   requ.tuple.Dst_ip.String(),
+		Port: requ.tuple.Dst_port,
+		Proc: string(requ.cmdline.Dst),
+	}
+	if requ.dir == tcp.TcpDirectionReverse {
+		src, dst = dst, src
+	}
+
+	// resp_time in milliseconds
+	responseTime := int32(resp.ts.Sub(requ.ts).Nanoseconds() / 1e6)
+
+	event := common.MapStr{
+		"@timestamp":   common.Time(requ.ts),
+		"type":         "ldap",
+		"status":       status,
+		"responsetime": responseTime,
+		"ldap":         fields,
+		"method":       strings.ToUpper(operation),
+		"query":        requ.query,
+		"bytes_in":     uint64(requ.size),
+		"bytes_out":    uint64(trans.respSize),
+		"src":          src,
+		"dst":          dst,
+	}
+	if requ.dn != "" {
+		event["resource"] = requ.dn
+	}
+	if ldap.SendRequest {
+		event["request"] = requ.query
+	}
+	if ldap.SendResponse {
+		response := res.name()
+		if res.diagnostic != "" {
+			response += ": " + res.diagnostic
+		}
+		event["response"] = response
+	}
+
+	return event
+}
+
+func (ldap *Ldap) GapInStream(tcptuple *common.TcpTuple, dir uint8,
+	nbytes int, private protos.ProtocolData) (priv protos.ProtocolData, drop bool) {
+	return private, true
+}
+
+func (ldap *Ldap) ReceivedFin(tcptuple *common.TcpTuple, dir uint8,
+	private protos.ProtocolData) protos.ProtocolData {
+	return private
+}
@@ -0,0 +1,346 @@
+//go:build !integration
+// +build !integration
+
+package ldap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+// BER encoding helpers building the test messages.
+
+func ber(tag byte, content ...[]byte) []byte {
+	var data []byte
+	for _, c := range content {
+		data = append(data, c...)
+	}
+
+	n := len(data)
+	out := []byte{tag}
+	switch {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n < 0x100:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, data...)
+}
+
+func berInt(tag byte, v int) []byte {
+	return ber(tag, []byte{byte(v)})
+}
+
+func berStr(tag byte, s string) []byte {
+	return ber(tag, []byte(s))
+}
+
+func ldapMessage(id int, op []byte) []byte {
+	return ber(0x30, berInt(0x02, id), op)
+}
+
+func bindRequest(id int, name, password string) []byte {
+	return ldapMessage(id, ber(0x60, berInt(0x02, 3), berStr(0x04, name), berStr(0x80, password)))
+}
+
+func ldapResult(tag byte, id int, code int, diagnostic string) []byte {
+	return ldapMessage(id, ber(tag, berInt(0x0a, code), berStr(0x04, ""), berStr(0x04, diagnostic)))
+}
+
+func searchRequest(id int, base string, filter []byte, attributes ...string) []byte {
+	var attrs [][]byte
+	for _, a := range attributes {
+		attrs = append(attrs, berStr(0x04, a))
+	}
+	return ldapMessage(id, ber(0x63,
+		berStr(0x04, base),
+		berInt(0x0a, 2),      // sub
+		berInt(0x0a, 0),      // never deref aliases
+		berInt(0x02, 100),    // size limit
+		berInt(0x02, 0),      // time limit
+		ber(0x01, []byte{0}), // types only
+		filter,
+		ber(0x30, attrs...),
+	))
+}
+
+func searchEntry(id int, dn string) []byte {
+	return ldapMessage(id, ber(0x64, berStr(0x04, dn), ber(0x30)))
+}
+
+func partialAttribute(attribute string, values ...string) []byte {
+	var vals [][]byte
+	for _, v := range values {
+		vals = append(vals, berStr(0x04, v))
+	}
+	return ber(0x30, berStr(0x04, attribute), ber(0x31, vals...))
+}
+
+func modifyRequest(id int, dn string, operation int, attribute []byte) []byte {
+	return ldapMessage(id, ber(0x66,
+		berStr(0x04, dn),
+		ber(0x30, ber(0x30, berInt(0x0a, operation), attribute)),
+	))
+}
+
+func newTestLdap() *Ldap {
+	ldap := &Ldap{}
+	results := &publish.ChanTransactions{Channel: make(chan common.MapStr, 10)}
+	config := defaultConfig
+	ldap.init(results, &config)
+	return ldap
+}
+
+func testTcpTuple() *common.TcpTuple {
+	t := &common.TcpTuple{
+		Ip_length: 4,
+		Src_ip:    net.IPv4(192, 168, 0, 1), Dst_ip: net.IPv4(192, 168, 0, 2),
+		Src_port: 51234, Dst_port: 389,
+	}
+	t.ComputeHashebles()
+	return t
+}
+
+// session replays the segments alternating between the client and the
+// server, starting with the client.
+func session(ldap *Ldap, segments ...[]byte) protos.ProtocolData {
+	tuple := testTcpTuple()
+	var private protos.ProtocolData
+	ts := time.Now()
+	for i, segment := range segments {
+		dir := uint8(tcp.TcpDirectionOriginal)
+		if i%2 == 1 {
+			dir = tcp.TcpDirectionReverse
+		}
+		ts = ts.Add(time.Millisecond)
+		pkt := &protos.Packet{Ts: ts, Payload: segment}
+		private = ldap.Parse(pkt, tuple, dir, private)
+	}
+	return private
+}
+
+func events(ldap *Ldap) []common.MapStr {
+	client := ldap.results.(*publish.ChanTransactions)
+	var events []common.MapStr
+	for {
+		select {
+		case event := <-client.Channel:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestLdapBind(t *testing.T) {
+	ldap := newTestLdap()
+	session(ldap,
+		bindRequest(1, "cn=admin,dc=example,dc=com", "secret"),
+		ldapResult(0x61, 1, 49, "invalid credentials"),
+	)
+
+	trans := events(ldap)
+	if assert.Len(t, trans, 1) {
+		event := trans[0]
+		assert.Equal(t, "ldap", event["type"])
+		assert.Equal(t, common.ERROR_STATUS, event["status"])
+		assert.Equal(t, "BIND", event["method"])
+		assert.Equal(t, "cn=admin,dc=example,dc=com", event["resource"])
+		assert.Equal(t, "BIND cn=admin,dc=example,dc=com", event["query"])
+		assert.Equal(t, int32(1), event["responsetime"])
+
+		fields := event["ldap"].(common.MapStr)
+		assert.Equal(t, int64(1), fields["message_id"])
+		assert.Equal(t, "bind", fields["operation"])
+		assert.Equal(t, int64(49), fields["result_code"])
+		assert.Equal(t, "invalidCredentials", fields["result"])
+		assert.Equal(t, "invalid credentials", fields["diagnostic_message"])
+		assert.Equal(t, common.MapStr{"version": int64(3), "auth": "simple"}, fields["bind"])
+		assert.NotContains(t, event.String(), "secret")
+	}
+}
+
+func TestLdapSearch(t *testing.T) {
+	ldap := newTestLdap()
+	filter := ber(0xa0,
+		ber(0xa3, berStr(0x04, "uid"), berStr(0x04, "alice")),
+		berStr(0x87, "mail"),
+		ber(0xa4, berStr(0x04, "cn"), ber(0x30, berStr(0x80, "Al"), berStr(0x82, "ce"))),
+	)
+
+	// the entries and the final response arrive in one segment
+	var responses []byte
+	responses = append(responses, searchEntry(2, "uid=alice,ou=people,dc=example,dc=com")...)
+	responses = append(responses, searchEntry(2, "uid=alice,ou=staff,dc=example,dc=com")...)
+	responses = append(responses, ldapResult(0x65, 2, 0, "")...)
+
+	session(ldap,
+		searchRequest(2, "dc=example,dc=com", filter, "mail", "cn"),
+		responses,
+	)
+
+	trans := events(ldap)
+	if assert.Len(t, trans, 1) {
+		event := trans[0]
+		assert.Equal(t, common.OK_STATUS, event["status"])
+		assert.Equal(t, "SEARCH", event["method"])
+		assert.Equal(t, "dc=example,dc=com", event["resource"])
+		assert.Equal(t, uint64(len(responses)), event["bytes_out"])
+		assert.Equal(t, "SEARCH dc=example,dc=com scope=sub filter=(&(uid=alice)(mail=*)(cn=Al*ce))",
+			event["query"])
+
+		search := event["ldap"].(common.MapStr)["search"].(common.MapStr)
+		assert.Equal(t, "sub", search["scope"])
+		assert.Equal(t, "never", search["deref_aliases"])
+		assert.Equal(t, int64(100), search["size_limit"])
+		assert.Equal(t, []string{"mail", "cn"}, search["attributes"])
+		assert.Equal(t, 2, search["entries"])
+		assert.Equal(t, 0, search["references"])
+	}
+}
+
+func TestLdapModifyRedactValues(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		ldap := newTestLdap()
+		ldap.decoder.redactValues = redact
+		session(ldap,
+			ldapMessage(3, ber(0x66,
+				berStr(0x04, "uid=alice,ou=people,dc=example,dc=com"),
+				ber(0x30,
+					ber(0x30, berInt(0x0a, 2), partialAttribute("mail", "alice@example.com")),
+					ber(0x30, berInt(0x0a, 2), partialAttribute("userPassword", "hunter2")),
+				),
+			)),
+			ldapResult(0x67, 3, 0, ""),
+		)
+
+		trans := events(ldap)
+		if !assert.Len(t, trans, 1) {
+			continue
+		}
+		event := trans[0]
+		assert.Equal(t, "MODIFY", event["method"])
+		assert.Equal(t, "MODIFY uid=alice,ou=people,dc=example,dc=com replace:mail,replace:userPassword",
+			event["query"])
+
+		mail := "alice@example.com"
+		if redact {
+			mail = redacted
+		}
+		changes := event["ldap"].(common.MapStr)["modify"].(common.MapStr)["changes"]
+		assert.Equal(t, []common.MapStr{
+			{"operation": "replace", "attribute": "mail", "values": []string{mail}},
+			{"operation": "replace", "attribute": "userPassword", "values": []string{redacted}},
+		}, changes)
+	}
+}
+
+func TestLdapRedactFilter(t *testing.T) {
+	ldap := newTestLdap()
+	ldap.decoder.redactValues = true
+	filter := ber(0xa3, berStr(0x04, "uid"), berStr(0x04, "alice"))
+	session(ldap,
+		searchRequest(2, "dc=example,dc=com", filter),
+		ldapResult(0x65, 2, 32, ""),
+	)
+
+	trans := events(ldap)
+	if assert.Len(t, trans, 1) {
+		search := trans[0]["ldap"].(common.MapStr)["search"].(common.MapStr)
+		assert.Equal(t, "(uid=<redacted>)", search["filter"])
+		assert.Equal(t, "noSuchObject", trans[0]["ldap"].(common.MapStr)["result"])
+		assert.Equal(t, common.ERROR_STATUS, trans[0]["status"])
+	}
+}
+
+func TestLdapFilterEscaping(t *testing.T) {
+	d := &decoder{}
+	filter := ber(0xa2, ber(0xa3, berStr(0x04, "cn"), berStr(0x04, "a*(b)\\")))
+	elem, _, err := readElement(filter)
+	if assert.NoError(t, err) {
+		s, err := d.filterString(elem)
+		assert.NoError(t, err)
+		assert.Equal(t, `(!(cn=a\2a\28b\29\5c))`, s)
+	}
+}
+
+// Requests are matched by message ID, also if the responses arrive out of
+// order.
+func TestLdapPipelined(t *testing.T) {
+	ldap := newTestLdap()
+	var requests []byte
+	requests = append(requests, ldapMessage(4, berStr(0x4a, "uid=bob,ou=people,dc=example,dc=com"))...)
+	requests = append(requests, modifyRequest(5, "uid=carol,ou=people,dc=example,dc=com", 0,
+		partialAttribute("description", "new"))...)
+
+	// split the responses across segments
+	var responses []byte
+	responses = append(responses, ldapResult(0x67, 5, 0, "")...)
+	responses = append(responses, ldapResult(0x6b, 4, 50, "")...)
+
+	session(ldap, requests, responses[:5], nil, responses[5:])
+
+	trans := events(ldap)
+	if assert.Len(t, trans, 2) {
+		assert.Equal(t, "MODIFY", trans[0]["method"])
+		assert.Equal(t, "DELETE", trans[1]["method"])
+		assert.Equal(t, "uid=bob,ou=people,dc=example,dc=com", trans[1]["resource"])
+		assert.Equal(t, "insufficientAccessRights", trans[1]["ldap"].(common.MapStr)["result"])
+	}
+}
+
+func TestLdapStartTLS(t *testing.T) {
+	ldap := newTestLdap()
+	private := session(ldap,
+		ldapMessage(1, ber(0x77, berStr(0x80, oidStartTLS))),
+		ldapResult(0x78, 1, 0, ""),
+		[]byte{0x16, 0x03, 0x01, 0x00, 0x05},
+	)
+
+	conn := private.(*ldapConnectionData)
+	assert.True(t, conn.encrypted)
+
+	trans := events(ldap)
+	if assert.Len(t, trans, 1) {
+		extended := trans[0]["ldap"].(common.MapStr)["extended"].(common.MapStr)
+		assert.Equal(t, "start_tls", extended["name"])
+	}
+}
+
+func TestLdapAbandon(t *testing.T) {
+	ldap := newTestLdap()
+	private := session(ldap,
+		searchRequest(2, "dc=example,dc=com", berStr(0x87, "objectClass")),
+		searchEntry(2, "dc=example,dc=com"),
+		ldapMessage(3, berInt(0x50, 2)),
+	)
+
+	conn := private.(*ldapConnectionData)
+	assert.Len(t, conn.transactions, 0)
+	assert.Len(t, events(ldap), 0)
+}
+
+func TestLdapDetect(t *testing.T) {
+	ldap := newTestLdap()
+	assert.True(t, ldap.Detect(bindRequest(1, "", ""), tcp.TcpDirectionOriginal))
+	assert.False(t, ldap.Detect([]byte("GET / HTTP/1.1\r\n"), tcp.TcpDirectionOriginal))
+	assert.False(t, ldap.Detect(ldapResult(0x61, 1, 0, ""), tcp.TcpDirectionReverse))
+}
+
+func TestLdapInvalidMessage(t *testing.T) {
+	ldap := newTestLdap()
+	private := session(ldap, []byte{0x30, 0x80, 0x02, 0x01, 0x01})
+
+	conn := private.(*ldapConnectionData)
+	assert.Nil(t, conn.streams[tcp.TcpDirectionOriginal])
+}
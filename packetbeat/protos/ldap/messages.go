@@ -0,0 +1,689 @@
+package ldap
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/libbeat/common"
+)
+
+// Protocol operations, the application tags of the protocolOp choice of
+// LDAPMessage (RFC 4511, section 4.2).
+const (
+	opBindRequest           = 0
+	opBindResponse          = 1
+	opUnbindRequest         = 2
+	opSearchRequest         = 3
+	opSearchResultEntry     = 4
+	opSearchResultDone      = 5
+	opModifyRequest         = 6
+	opModifyResponse        = 7
+	opAddRequest            = 8
+	opAddResponse           = 9
+	opDelRequest            = 10
+	opDelResponse           = 11
+	opModifyDNRequest       = 12
+	opModifyDNResponse      = 13
+	opCompareRequest        = 14
+	opCompareResponse       = 15
+	opAbandonRequest        = 16
+	opSearchResultReference = 19
+	opExtendedRequest       = 23
+	opExtendedResponse      = 24
+	opIntermediateResponse  = 25
+)
+
+// operationNames are the names of the request operations.
+var operationNames = map[int]string{
+	opBindRequest:     "bind",
+	opUnbindRequest:   "unbind",
+	opSearchRequest:   "search",
+	opModifyRequest:   "modify",
+	opAddRequest:      "add",
+	opDelRequest:      "delete",
+	opModifyDNRequest: "modify_dn",
+	opCompareRequest:  "compare",
+	opAbandonRequest:  "abandon",
+	opExtendedRequest: "extended",
+}
+
+// Result codes of LDAPResult (RFC 4511, appendix A).
+const (
+	resultSuccess            = 0
+	resultCompareFalse       = 5
+	resultCompareTrue        = 6
+	resultReferral           = 10
+	resultSaslBindInProgress = 14
+)
+
+var resultNames = map[int64]string{
+	0:  "success",
+	1:  "operationsError",
+	2:  "protocolError",
+	3:  "timeLimitExceeded",
+	4:  "sizeLimitExceeded",
+	5:  "compareFalse",
+	6:  "compareTrue",
+	7:  "authMethodNotSupported",
+	8:  "strongerAuthRequired",
+	10: "referral",
+	11: "adminLimitExceeded",
+	12: "unavailableCriticalExtension",
+	13: "confidentialityRequired",
+	14: "saslBindInProgress",
+	16: "noSuchAttribute",
+	17: "undefinedAttributeType",
+	18: "inappropriateMatching",
+	19: "constraintViolation",
+	20: "attributeOrValueExists",
+	21: "invalidAttributeSyntax",
+	32: "noSuchObject",
+	33: "aliasProblem",
+	34: "invalidDNSyntax",
+	36: "aliasDereferencingProblem",
+	48: "inappropriateAuthentication",
+	49: "invalidCredentials",
+	50: "insufficientAccessRights",
+	51: "busy",
+	52: "unavailable",
+	53: "unwillingToPerform",
+	54: "loopDetect",
+	64: "namingViolation",
+	65: "objectClassViolation",
+	66: "notAllowedOnNonLeaf",
+	67: "notAllowedOnRDN",
+	68: "entryAlreadyExists",
+	69: "objectClassModsProhibited",
+	71: "affectsMultipleDSAs",
+	80: "other",
+}
+
+var (
+	searchScopes  = []string{"base", "one", "sub"}
+	derefAliases  = []string{"never", "searching", "finding", "always"}
+	modifyOps     = []string{"add", "delete", "replace", "increment"}
+	extendedNames = map[string]string{
+		oidStartTLS:               "start_tls",
+		"1.3.6.1.4.1.4203.1.11.1": "password_modify",
+		"1.3.6.1.4.1.4203.1.11.3": "who_am_i",
+		"1.3.6.1.1.8":             "cancel",
+	}
+)
+
+const oidStartTLS = "1.3.6.1.4.1.1466.20037"
+
+// filterOps are the operators of the attribute value assertion filters.
+var filterOps = map[int]string{
+	3: "=",
+	5: ">=",
+	6: "<=",
+	8: "~=",
+}
+
+// redacted replaces attribute values if values are redacted.
+const redacted = "<redacted>"
+
+// passwordAttributes are the attributes whose values are never reported.
+var passwordAttributes = map[string]bool{
+	"userpassword": true,
+	"unicodepwd":   true,
+}
+
+// result is the LDAPResult of a response.
+type result struct {
+	code       int64
+	matchedDN  string
+	diagnostic string
+}
+
+func (r *result) name() string {
+	if name, ok := resultNames[r.code]; ok {
+		return name
+	}
+	return fmt.Sprintf("result_%d", r.code)
+}
+
+// ok reports if the result code does not indicate a failure.
+func (r *result) ok() bool {
+	switch r.code {
+	case resultSuccess, resultCompareFalse, resultCompareTrue,
+		resultReferral, resultSaslBindInProgress:
+		return true
+	}
+	return false
+}
+
+// decoder decodes the protocol operations of LDAP messages.
+type decoder struct {
+	redactValues bool
+}
+
+// decodeMessage decodes an LDAPMessage into msg.
+func (d *decoder) decodeMessage(top element, msg *message) error {
+	if !top.is(classUniversal, tagSequence) {
+		return errUnexpectedType
+	}
+	elems, err := top.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 2 || !elems[0].is(classUniversal, tagInteger) ||
+		elems[1].class != classApplication {
+		return errUnexpectedType
+	}
+
+	if msg.id, err = elems[0].int(); err != nil {
+		return err
+	}
+	msg.op = elems[1].tag
+	msg.isRequest = isRequest(msg.op)
+
+	op := elems[1]
+	switch msg.op {
+	case opBindRequest:
+		return d.decodeBind(op, msg)
+	case opSearchRequest:
+		return d.decodeSearch(op, msg)
+	case opModifyRequest:
+		return d.decodeModify(op, msg)
+	case opAddRequest:
+		return d.decodeAdd(op, msg)
+	case opDelRequest:
+		msg.dn = op.str()
+		msg.query = fmt.Sprintf("DELETE %s", msg.dn)
+	case opModifyDNRequest:
+		return d.decodeModifyDN(op, msg)
+	case opCompareRequest:
+		return d.decodeCompare(op, msg)
+	case opAbandonRequest:
+		msg.abandonID, err = op.int()
+		return err
+	case opExtendedRequest:
+		return d.decodeExtended(op, msg)
+	case opUnbindRequest, opSearchResultEntry, opSearchResultReference,
+		opIntermediateResponse:
+	default:
+		if isResponse(msg.op) {
+			return decodeResult(op, msg)
+		}
+		return errUnexpectedType
+	}
+	return nil
+}
+
+func isRequest(op int) bool {
+	_, ok := operationNames[op]
+	return ok
+}
+
+// isResponse reports if op is the final response of an operation.
+func isResponse(op int) bool {
+	switch op {
+	case opBindResponse, opSearchResultDone, opModifyResponse, opAddResponse,
+		opDelResponse, opModifyDNResponse, opCompareResponse, opExtendedResponse:
+		return true
+	}
+	return false
+}
+
+// requestOf returns the request operation answered by the response
+// operation.
+func requestOf(op int) int {
+	switch op {
+	case opSearchResultEntry, opSearchResultReference, opSearchResultDone:
+		return opSearchRequest
+	case opExtendedResponse:
+		return opExtendedRequest
+	}
+	return op - 1
+}
+
+func decodeResult(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 3 {
+		return errUnexpectedType
+	}
+
+	code, err := elems[0].int()
+	if err != nil {
+		return err
+	}
+	msg.result = &result{
+		code:       code,
+		matchedDN:  elems[1].str(),
+		diagnostic: elems[2].str(),
+	}
+	return nil
+}
+
+func (d *decoder) decodeBind(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 3 {
+		return errUnexpectedType
+	}
+
+	version, err := elems[0].int()
+	if err != nil {
+		return err
+	}
+	msg.dn = elems[1].str()
+
+	// the credentials are never reported
+	fields := common.MapStr{"version": version}
+	auth := elems[2]
+	switch {
+	case auth.is(classContext, 0):
+		fields["auth"] = "simple"
+	case auth.is(classContext, 3):
+		fields["auth"] = "sasl"
+		if sasl, err := auth.children(); err == nil && len(sasl) > 0 {
+			fields["sasl_mechanism"] = sasl[0].str()
+		}
+	default:
+		fields["auth"] = fmt.Sprintf("auth_%d", auth.tag)
+	}
+	msg.fields = fields
+	msg.query = fmt.Sprintf("BIND %s", msg.dn)
+	return nil
+}
+
+func (d *decoder) decodeSearch(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 8 {
+		return errUnexpectedType
+	}
+
+	msg.dn = elems[0].str()
+	scope, err := elems[1].int()
+	if err != nil {
+		return err
+	}
+	deref, err := elems[2].int()
+	if err != nil {
+		return err
+	}
+	sizeLimit, err := elems[3].int()
+	if err != nil {
+		return err
+	}
+	timeLimit, err := elems[4].int()
+	if err != nil {
+		return err
+	}
+	typesOnly, err := elems[5].bool()
+	if err != nil {
+		return err
+	}
+	filter, err := d.filterString(elems[6])
+	if err != nil {
+		return err
+	}
+	attributes, err := stringList(elems[7])
+	if err != nil {
+		return err
+	}
+
+	fields := common.MapStr{
+		"scope":         enumName(searchScopes, scope),
+		"deref_aliases": enumName(derefAliases, deref),
+		"size_limit":    sizeLimit,
+		"time_limit":    timeLimit,
+		"types_only":    typesOnly,
+		"filter":        filter,
+	}
+	if len(attributes) > 0 {
+		fields["attributes"] = attributes
+	}
+	msg.fields = fields
+	msg.query = fmt.Sprintf("SEARCH %s scope=%s filter=%s",
+		msg.dn, fields["scope"], filter)
+	return nil
+}
+
+func (d *decoder) decodeModify(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 2 {
+		return errUnexpectedType
+	}
+	msg.dn = elems[0].str()
+
+	changeElems, err := elems[1].children()
+	if err != nil {
+		return err
+	}
+	var changes []common.MapStr
+	var summary []string
+	for _, changeElem := range changeElems {
+		change, err := changeElem.children()
+		if err != nil {
+			return err
+		}
+		if len(change) < 2 {
+			return errUnexpectedType
+		}
+		operation, err := change[0].int()
+		if err != nil {
+			return err
+		}
+		attribute, err := d.decodeAttribute(change[1])
+		if err != nil {
+			return err
+		}
+		attribute["operation"] = enumName(modifyOps, operation)
+		changes = append(changes, attribute)
+		summary = append(summary, fmt.Sprintf("%s:%s", attribute["operation"], attribute["attribute"]))
+	}
+
+	msg.fields = common.MapStr{"changes": changes}
+	msg.query = fmt.Sprintf("MODIFY %s %s", msg.dn, strings.Join(summary, ","))
+	return nil
+}
+
+func (d *decoder) decodeAdd(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 2 {
+		return errUnexpectedType
+	}
+	msg.dn = elems[0].str()
+
+	attributeElems, err := elems[1].children()
+	if err != nil {
+		return err
+	}
+	var attributes []common.MapStr
+	for _, attributeElem := range attributeElems {
+		attribute, err := d.decodeAttribute(attributeElem)
+		if err != nil {
+			return err
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	msg.fields = common.MapStr{"attributes": attributes}
+	msg.query = fmt.Sprintf("ADD %s", msg.dn)
+	return nil
+}
+
+func (d *decoder) decodeModifyDN(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 3 {
+		return errUnexpectedType
+	}
+	msg.dn = elems[0].str()
+	deleteOld, err := elems[2].bool()
+	if err != nil {
+		return err
+	}
+
+	fields := common.MapStr{
+		"new_rdn":        elems[1].str(),
+		"delete_old_rdn": deleteOld,
+	}
+	if len(elems) > 3 && elems[3].is(classContext, 0) {
+		fields["new_superior"] = elems[3].str()
+	}
+	msg.fields = fields
+	msg.query = fmt.Sprintf("MODIFY_DN %s %s", msg.dn, elems[1].str())
+	return nil
+}
+
+func (d *decoder) decodeCompare(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 2 {
+		return errUnexpectedType
+	}
+	msg.dn = elems[0].str()
+
+	ava, err := elems[1].children()
+	if err != nil {
+		return err
+	}
+	if len(ava) < 2 {
+		return errUnexpectedType
+	}
+	attribute := ava[0].str()
+	msg.fields = common.MapStr{
+		"attribute": attribute,
+		"value":     d.value(attribute, ava[1].data),
+	}
+	msg.query = fmt.Sprintf("COMPARE %s %s", msg.dn, attribute)
+	return nil
+}
+
+func (d *decoder) decodeExtended(op element, msg *message) error {
+	elems, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(elems) < 1 || !elems[0].is(classContext, 0) {
+		return errUnexpectedType
+	}
+
+	// the request value is not reported, it may contain credentials
+	oid := elems[0].str()
+	fields := common.MapStr{"oid": oid}
+	if name, ok := extendedNames[oid]; ok {
+		fields["name"] = name
+	}
+	msg.oid = oid
+	msg.fields = fields
+	msg.query = fmt.Sprintf("EXTENDED %s", oid)
+	return nil
+}
+
+// decodeAttribute decodes a PartialAttribute, an attribute description with
+// a set of values.
+func (d *decoder) decodeAttribute(e element) (common.MapStr, error) {
+	elems, err := e.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) < 2 {
+		return nil, errUnexpectedType
+	}
+
+	attribute := elems[0].str()
+	valueElems, err := elems[1].children()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(valueElems))
+	for _, v := range valueElems {
+		values = append(values, d.value(attribute, v.data))
+	}
+
+	return common.MapStr{
+		"attribute": attribute,
+		"values":    values,
+	}, nil
+}
+
+// value returns the attribute value to report, which is redacted for
+// passwords or if configured.
+func (d *decoder) value(attribute string, v []byte) string {
+	if d.redactValues || passwordAttributes[strings.ToLower(attribute)] {
+		return redacted
+	}
+	return string(v)
+}
+
+// filterString formats the search filter in the string representation of
+// RFC 4515.
+func (d *decoder) filterString(e element) (string, error) {
+	if e.class != classContext {
+		return "", errUnexpectedType
+	}
+
+	switch e.tag {
+	case 0, 1: // and, or
+		elems, err := e.children()
+		if err != nil {
+			return "", err
+		}
+		op := "&"
+		if e.tag == 1 {
+			op = "|"
+		}
+		parts := []string{"(", op}
+		for _, elem := range elems {
+			part, err := d.filterString(elem)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, "") + ")", nil
+
+	case 2: // not
+		elems, err := e.children()
+		if err != nil {
+			return "", err
+		}
+		if len(elems) != 1 {
+			return "", errUnexpectedType
+		}
+		inner, err := d.filterString(elems[0])
+		if err != nil {
+			return "", err
+		}
+		return "(!" + inner + ")", nil
+
+	case 3, 5, 6, 8: // equalityMatch, greaterOrEqual, lessOrEqual, approxMatch
+		elems, err := e.children()
+		if err != nil {
+			return "", err
+		}
+		if len(elems) < 2 {
+			return "", errUnexpectedType
+		}
+		op := filterOps[e.tag]
+		attribute := elems[0].str()
+		return "(" + attribute + op + d.filterValue(attribute, elems[1].data) + ")", nil
+
+	case 4: // substrings
+		elems, err := e.children()
+		if err != nil {
+			return "", err
+		}
+		if len(elems) < 2 {
+			return "", errUnexpectedType
+		}
+		attribute := elems[0].str()
+		substrings, err := elems[1].children()
+		if err != nil {
+			return "", err
+		}
+		var initial, final string
+		middle := []string{""}
+		for _, s := range substrings {
+			value := d.filterValue(attribute, s.data)
+			switch s.tag {
+			case 0:
+				initial = value
+			case 1:
+				middle = append(middle, value)
+			case 2:
+				final = value
+			}
+		}
+		middle = append(middle, "")
+		return "(" + attribute + "=" + initial + strings.Join(middle, "*") + final + ")", nil
+
+	case 7: // present
+		return "(" + e.str() + "=*)", nil
+
+	case 9: // extensibleMatch
+		elems, err := e.children()
+		if err != nil {
+			return "", err
+		}
+		var rule, attribute, value string
+		var dnAttributes bool
+		for _, elem := range elems {
+			switch elem.tag {
+			case 1:
+				rule = elem.str()
+			case 2:
+				attribute = elem.str()
+			case 3:
+				value = d.filterValue(attribute, elem.data)
+			case 4:
+				dnAttributes, _ = elem.bool()
+			}
+		}
+		s := "(" + attribute
+		if dnAttributes {
+			s += ":dn"
+		}
+		if rule != "" {
+			s += ":" + rule
+		}
+		return s + ":=" + value + ")", nil
+	}
+
+	return "", errUnexpectedType
+}
+
+// filterValue escapes the assertion value of a filter. Values which are not
+// valid UTF-8, like binary values, are escaped completely.
+func (d *decoder) filterValue(attribute string, v []byte) string {
+	if d.redactValues || passwordAttributes[strings.ToLower(attribute)] {
+		return redacted
+	}
+
+	binary := !utf8.Valid(v)
+	var b bytes.Buffer
+	for _, c := range v {
+		switch {
+		case c == '*' || c == '(' || c == ')' || c == '\\' || c < 0x20 || c == 0x7f,
+			binary && c > 0x7f:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// stringList decodes a SEQUENCE OF LDAPString.
+func stringList(e element) ([]string, error) {
+	elems, err := e.children()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		list = append(list, elem.str())
+	}
+	return list, nil
+}
+
+func enumName(names []string, v int64) string {
+	if v >= 0 && v < int64(len(names)) {
+		return names[v]
+	}
+	return fmt.Sprintf("%d", v)
+}
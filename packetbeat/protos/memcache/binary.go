@@ -7,6 +7,8 @@ package memcache
 // init function.
 
 import (
+	"encoding/binary"
+
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/common/streambuf"
 )
@@ -20,6 +22,22 @@ const (
 
 const memcacheHeaderSize = 24
 
+// isBinaryRequestHeader reports if the data starts with the header of a
+// request with a known opcode and lengths.
+func isBinaryRequestHeader(data []byte) bool {
+	if len(data) < memcacheHeaderSize || data[0] != MemcacheMagicRequest {
+		return false
+	}
+	if _, known := memcacheBinaryCommandTable[memcacheOpcode(data[1])]; !known {
+		return false
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(data[2:4]))
+	extrasLen := int(data[4])
+	bodyLen := int(binary.BigEndian.Uint32(data[8:12]))
+	return data[5] == 0 && keyLen+extrasLen <= bodyLen
+}
+
 var memcacheBinaryCommandTable = make(map[memcacheOpcode]*commandType)
 
 var binaryUnknownCommand *commandType
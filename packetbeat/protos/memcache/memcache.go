@@ -104,6 +104,7 @@ var (
 	unmatchedRequests      = expvar.NewInt("memcache.unmatched_requests")
 	unmatchedResponses     = expvar.NewInt("memcache.unmatched_responses")
 	unfinishedTransactions = expvar.NewInt("memcache.unfinished_transactions")
+	parseErrors            = expvar.NewInt("memcache.parse_errors")
 )
 
 func init() {
@@ -173,6 +174,18 @@ func (mc *Memcache) GetPorts() []int {
 	return mc.Ports.Ports
 }
 
+// Detect reports if the sample starts with a request of the text protocol,
+// or the header of a request of the binary protocol.
+func (mc *Memcache) Detect(sample []byte, dir uint8) bool {
+	if len(sample) == 0 {
+		return false
+	}
+	if sample[0] == MemcacheMagicRequest {
+		return isBinaryRequestHeader(sample)
+	}
+	return isTextRequest(sample)
+}
+
 func (mc *Memcache) finishTransaction(t *transaction) error {
 	mc.handler.onTransaction(t)
 	return nil
@@ -265,3 +265,20 @@ func Test_TransactionLostRequest(t *testing.T) {
 	assert.Equal(t, "memcache", event["type"])
 	assert.Equal(t, common.CLIENT_ERROR_STATUS, event["status"])
 }
+
+func TestMemcacheDetect(t *testing.T) {
+	mc := Memcache{}
+	assert.True(t, mc.Detect([]byte("get key1 key2\r\n"), 0))
+	assert.True(t, mc.Detect([]byte("set key 0 0 5\r\nvalue\r\n"), 0))
+	assert.False(t, mc.Detect([]byte("STORED\r\n"), 1))
+	assert.False(t, mc.Detect([]byte("get key"), 0))
+
+	get := []byte{
+		0x80, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		'k', 'e', 'y',
+	}
+	assert.True(t, mc.Detect(get, 0))
+	assert.False(t, mc.Detect(get[:20], 0))
+}
@@ -140,6 +140,7 @@ func (mc *Memcache) memcacheParseTCP(
 		if err != nil {
 			// parsing error, drop tcp stream and retry with next segement
 			debug("Ignore Memcache message, drop tcp stream: %v", err)
+			parseErrors.Add(1)
 			mc.pushAllTCPTrans(conn)
 			tcpConn.drop(dir)
 			return nil
@@ -114,6 +114,7 @@ func (mc *Memcache) ParseUdp(pkt *protos.Packet) {
 		msg, err := parseUdp(&mc.config, pkt.Ts, payload)
 		if err != nil {
 			logp.Warn("failed to parse memcached(UDP) message: %s", err)
+			parseErrors.Add(1)
 			connection.killTransaction(trans)
 			return
 		}
@@ -655,6 +655,20 @@ func withInt64Arg(
 	return textArgError(err)
 }
 
+// isTextRequest reports if the data starts with a line of a known request
+// command.
+func isTextRequest(data []byte) bool {
+	i := bytes.Index(data, []byte("\r\n"))
+	if i == -1 {
+		return false
+	}
+	fields := bytes.Fields(data[:i])
+	if len(fields) == 0 {
+		return false
+	}
+	return findTextCommandType(requestCommands, fields[0]) != nil
+}
+
 func findTextCommandType(commands []textCommandType, name []byte) *textCommandType {
 	for _, cmd := range commands {
 		if bytes.Equal(name, cmd.name) {
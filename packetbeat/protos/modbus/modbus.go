@@ -0,0 +1,377 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"expvar"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+
+	"github.com/elastic/beats/packetbeat/procs"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+const (
+	// size of the MBAP header: transaction id, protocol id, length and
+	// unit id
+	mbapHeaderSize = 7
+
+	// maximum value of the length field, for the maximum ADU size of 260
+	// bytes
+	maxLength = 254
+
+	// maximum number of requests waiting for their response per
+	// connection, older requests are dropped
+	maxPendingRequests = 64
+)
+
+var (
+	errInvalidHeader = errors.New("invalid MBAP header")
+	errShortPDU      = errors.New("PDU too short")
+)
+
+type stream struct {
+	tcptuple *common.TcpTuple
+	data     []byte
+}
+
+// message is a Modbus/TCP application data unit.
+type message struct {
+	ts      time.Time
+	tuple   common.TcpTuple
+	dir     uint8
+	cmdline *common.CmdlineTuple
+	size    int
+
+	transactionID uint16
+	unitID        uint8
+	function      uint8 // without the exception flag
+	exception     bool
+	pdu           []byte
+}
+
+type modbusConnectionData struct {
+	streams  [2]*stream
+	requests []*message
+
+	// direction of the server, set once a response was matched
+	serverKnown bool
+	serverDir   uint8
+}
+
+// Modbus protocol plugin
+type Modbus struct {
+	// config
+	Ports        []int
+	SendRequest  bool
+	SendResponse bool
+
+	transactionTimeout time.Duration
+
+	results publish.Transactions
+}
+
+var (
+	debugf = logp.MakeDebug("modbus")
+)
+
+var (
+	unmatchedRequests  = expvar.NewInt("modbus.unmatched_requests")
+	unmatchedResponses = expvar.NewInt("modbus.unmatched_responses")
+	parseErrors        = expvar.NewInt("modbus.parse_errors")
+)
+
+func init() {
+	protos.Register("modbus", New)
+}
+
+func New(
+	testMode bool,
+	results publish.Transactions,
+	cfg *common.Config,
+) (protos.Plugin, error) {
+	p := &Modbus{}
+	config := defaultConfig
+	if !testMode {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.init(results, &config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (modbus *Modbus) init(results publish.Transactions, config *modbusConfig) error {
+	modbus.setFromConfig(config)
+	modbus.results = results
+	return nil
+}
+
+func (modbus *Modbus) setFromConfig(config *modbusConfig) {
+	modbus.Ports = config.Ports
+	modbus.SendRequest = config.SendRequest
+	modbus.SendResponse = config.SendResponse
+	modbus.transactionTimeout = config.TransactionTimeout
+}
+
+func (modbus *Modbus) GetPorts() []int {
+	return modbus.Ports
+}
+
+func (modbus *Modbus) ConnectionTimeout() time.Duration {
+	return modbus.transactionTimeout
+}
+
+func (modbus *Modbus) Parse(
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+	private protos.ProtocolData,
+) protos.ProtocolData {
+	defer logp.Recover("ParseModbus exception")
+
+	conn := ensureModbusConnection(private)
+	return modbus.doParse(conn, pkt, tcptuple, dir)
+}
+
+func ensureModbusConnection(private protos.ProtocolData) *modbusConnectionData {
+	if private == nil {
+		return &modbusConnectionData{}
+	}
+
+	priv, ok := private.(*modbusConnectionData)
+	if !ok {
+		logp.Warn("modbus connection data type error, create new one")
+		return &modbusConnectionData{}
+	}
+	if priv == nil {
+		logp.Warn("Unexpected: modbus connection data not set, create new one")
+		return &modbusConnectionData{}
+	}
+
+	return priv
+}
+
+func (modbus *Modbus) doParse(
+	conn *modbusConnectionData,
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) *modbusConnectionData {
+	st := conn.streams[dir]
+	if st == nil {
+		st = &stream{tcptuple: tcptuple}
+		conn.streams[dir] = st
+		debugf("new stream: %p (dir=%v, len=%v)", st, dir, len(pkt.Payload))
+	}
+	st.data = append(st.data, pkt.Payload...)
+
+	for len(st.data) > 0 {
+		msg, err := parseMessage(st.data)
+		if err != nil {
+			// drop this tcp stream. Will retry parsing with the next
+			// segment in it
+			parseErrors.Add(1)
+			conn.streams[dir] = nil
+			debugf("Ignore Modbus message (%v). Drop tcp stream.", err)
+			return conn
+		}
+		if msg == nil {
+			// wait for more data
+			break
+		}
+		st.data = st.data[msg.size:]
+
+		msg.ts = pkt.Ts
+		msg.tuple = *tcptuple
+		msg.dir = dir
+		msg.cmdline = procs.ProcWatcher.FindProcessesTuple(tcptuple.IpPort())
+		modbus.handleMessage(conn, msg)
+	}
+
+	return conn
+}
+
+// parseMessage parses the ADU at the start of data. If the ADU is
+// incomplete, no message is returned.
+func parseMessage(data []byte) (*message, error) {
+	if len(data) < mbapHeaderSize {
+		return nil, nil
+	}
+
+	protocolID := binary.BigEndian.Uint16(data[2:4])
+	length := int(binary.BigEndian.Uint16(data[4:6]))
+	if protocolID != 0 || length < 2 || length > maxLength {
+		return nil, errInvalidHeader
+	}
+
+	size := 6 + length
+	if len(data) < size {
+		return nil, nil
+	}
+
+	function := data[7]
+	msg := &message{
+		size:          size,
+		transactionID: binary.BigEndian.Uint16(data[0:2]),
+		unitID:        data[6],
+		function:      function &^ exceptionFlag,
+		exception:     function&exceptionFlag != 0,
+		pdu:           data[7:size:size],
+	}
+	return msg, nil
+}
+
+func (modbus *Modbus) handleMessage(conn *modbusConnectionData, msg *message) {
+	if requ := conn.popRequest(msg); requ != nil {
+		conn.serverKnown = true
+		conn.serverDir = msg.dir
+
+		event := modbus.newTransaction(requ, msg)
+		if modbus.results != nil {
+			modbus.results.PublishTransaction(event)
+		}
+		return
+	}
+
+	if msg.exception || (conn.serverKnown && msg.dir == conn.serverDir) {
+		debugf("Response from unknown transaction. Ignoring")
+		unmatchedResponses.Add(1)
+		return
+	}
+
+	if len(conn.requests) >= maxPendingRequests {
+		debugf("Too many pending requests, dropping the oldest one")
+		unmatchedRequests.Add(1)
+		conn.requests = conn.requests[1:]
+	}
+	conn.requests = append(conn.requests, msg)
+}
+
+// popRequest removes and returns the request sent in the other direction
+// with the same transaction id, unit id and function code as the response.
+func (conn *modbusConnectionData) popRequest(resp *message) *message {
+	for i, requ := range conn.requests {
+		if requ.dir != resp.dir &&
+			requ.transactionID == resp.transactionID &&
+			requ.unitID == resp.unitID &&
+			requ.function == resp.function {
+
+			conn.requests = append(conn.requests[:i], conn.requests[i+1:]...)
+			return requ
+		}
+	}
+	return nil
+}
+
+func (modbus *Modbus) newTransaction(requ, resp *message) common.MapStr {
+	status := common.OK_STATUS
+	name := functionName(requ.function)
+
+	fields := common.MapStr{
+		"transaction_id": requ.transactionID,
+		"unit_id":        requ.unitID,
+		"function_code":  requ.function,
+		"function":       name,
+	}
+
+	query := []string{strings.ToUpper(name), fmt.Sprintf("unit=%d", requ.unitID)}
+	var resource string
+
+	read, write, err := decodeRequest(requ.function, requ.pdu[1:])
+	if err != nil {
+		debugf("Failed to decode %s request: %v", name, err)
+		parseErrors.Add(1)
+	}
+	if read != nil {
+		fields["read"] = rangeFields(read)
+		resource = read.String()
+		query = append(query, "read="+read.String())
+	}
+	if write != nil {
+		fields["write"] = rangeFields(write)
+		if resource == "" {
+			resource = write.String()
+		}
+		query = append(query, "write="+write.String())
+	}
+
+	if resp.exception {
+		status = common.ERROR_STATUS
+		if len(resp.pdu) > 1 {
+			code := resp.pdu[1]
+			fields["exception_code"] = code
+			fields["exception"] = exceptionName(code)
+		}
+	}
+
+	src := &common.Endpoint{
+		Ip:   requ.tuple.Src_ip.String(),
+		Port: requ.tuple.Src_port,
+		Proc: string(requ.cmdline.Src),
+	}
+	dst := &common.Endpoint{
+		Ip:   requ.tuple.Dst_ip.String(),
+		Port: requ.tuple.Dst_port,
+		Proc: string(requ.cmdline.Dst),
+	}
+	if requ.dir == tcp.TcpDirectionReverse {
+		src, dst = dst, src
+	}
+
+	// resp_time in milliseconds
+	responseTime := int32(resp.ts.Sub(requ.ts).Nanoseconds() / 1e6)
+
+	event := common.MapStr{
+		"@timestamp":   common.Time(requ.ts),
+		"type":         "modbus",
+		"status":       status,
+		"responsetime": responseTime,
+		"modbus":       fields,
+		"method":       strings.ToUpper(name),
+		"query":        strings.Join(query, " "),
+		"bytes_in":     uint64(requ.size),
+		"bytes_out":    uint64(resp.size),
+		"src":          src,
+		"dst":          dst,
+	}
+	if resource != "" {
+		event["resource"] = resource
+	}
+	if modbus.SendRequest {
+		event["request"] = hex.EncodeToString(requ.pdu)
+	}
+	if modbus.SendResponse {
+		event["response"] = hex.EncodeToString(resp.pdu)
+	}
+
+	return event
+}
+
+func rangeFields(r *registerRange) common.MapStr {
+	return common.MapStr{
+		"table":    r.table,
+		"address":  r.address,
+		"quantity": r.quantity,
+	}
+}
+
+func (modbus *Modbus) GapInStream(tcptuple *common.TcpTuple, dir uint8,
+	nbytes int, private protos.ProtocolData) (priv protos.ProtocolData, drop bool) {
+	return private, true
+}
+
+func (modbus *Modbus) ReceivedFin(tcptuple *common.TcpTuple, dir uint8,
+	private protos.ProtocolData) protos.ProtocolData {
+	return private
+}
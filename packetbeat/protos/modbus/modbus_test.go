@@ -0,0 +1,213 @@
+// +build !integration
+
+package modbus
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+func newTestModbus() *Modbus {
+	modbus := &Modbus{}
+	results := &publish.ChanTransactions{Channel: make(chan common.MapStr, 10)}
+	config := defaultConfig
+	modbus.init(results, &config)
+	return modbus
+}
+
+func testTcpTuple() *common.TcpTuple {
+	t := &common.TcpTuple{
+		Ip_length: 4,
+		Src_ip:    net.IPv4(192, 168, 0, 1), Dst_ip: net.IPv4(192, 168, 0, 2),
+		Src_port: 51234, Dst_port: 502,
+	}
+	t.ComputeHashebles()
+	return t
+}
+
+// session replays the hex encoded segments alternating between the client
+// and the server, starting with the client.
+func session(t *testing.T, modbus *Modbus, segments ...string) protos.ProtocolData {
+	tuple := testTcpTuple()
+	var private protos.ProtocolData
+	ts := time.Now()
+	for i, segment := range segments {
+		dir := uint8(tcp.TcpDirectionOriginal)
+		if i%2 == 1 {
+			dir = tcp.TcpDirectionReverse
+		}
+		payload, err := hex.DecodeString(segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts = ts.Add(time.Millisecond)
+		pkt := &protos.Packet{Ts: ts, Payload: payload}
+		private = modbus.Parse(pkt, tuple, dir, private)
+	}
+	return private
+}
+
+func events(modbus *Modbus) []common.MapStr {
+	client := modbus.results.(*publish.ChanTransactions)
+	var events []common.MapStr
+	for {
+		select {
+		case event := <-client.Channel:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestModbusReadHoldingRegisters(t *testing.T) {
+	modbus := newTestModbus()
+	session(t, modbus,
+		"000100000006010300640002",
+		"00010000000701030400fa0001",
+	)
+
+	trans := events(modbus)
+	if assert.Len(t, trans, 1) {
+		event := trans[0]
+		assert.Equal(t, "modbus", event["type"])
+		assert.Equal(t, common.OK_STATUS, event["status"])
+		assert.Equal(t, "READ_HOLDING_REGISTERS", event["method"])
+		assert.Equal(t, "READ_HOLDING_REGISTERS unit=1 read=holding_registers:100-101", event["query"])
+		assert.Equal(t, "holding_registers:100-101", event["resource"])
+		assert.Equal(t, int32(1), event["responsetime"])
+
+		fields := event["modbus"].(common.MapStr)
+		assert.Equal(t, uint16(1), fields["transaction_id"])
+		assert.Equal(t, uint8(1), fields["unit_id"])
+		assert.Equal(t, uint8(3), fields["function_code"])
+		assert.Equal(t, "read_holding_registers", fields["function"])
+		assert.Equal(t, common.MapStr{
+			"table":    "holding_registers",
+			"address":  uint16(100),
+			"quantity": uint16(2),
+		}, fields["read"])
+		assert.Nil(t, fields["write"])
+	}
+}
+
+func TestModbusException(t *testing.T) {
+	modbus := newTestModbus()
+	session(t, modbus,
+		"002a00000006110500130000",
+		"002a00000003118502",
+	)
+
+	trans := events(modbus)
+	if assert.Len(t, trans, 1) {
+		event := trans[0]
+		assert.Equal(t, common.ERROR_STATUS, event["status"])
+		assert.Equal(t, "coils:19-19", event["resource"])
+
+		fields := event["modbus"].(common.MapStr)
+		assert.Equal(t, uint8(0x11), fields["unit_id"])
+		assert.Equal(t, "write_single_coil", fields["function"])
+		assert.Equal(t, uint8(2), fields["exception_code"])
+		assert.Equal(t, "illegal_data_address", fields["exception"])
+	}
+}
+
+func TestModbusReadWriteMultipleRegisters(t *testing.T) {
+	modbus := newTestModbus()
+	session(t, modbus,
+		"00050000000f0117000a0003001400020400010002",
+		"000500000009011706000100020003",
+	)
+
+	trans := events(modbus)
+	if assert.Len(t, trans, 1) {
+		fields := trans[0]["modbus"].(common.MapStr)
+		assert.Equal(t, common.MapStr{
+			"table":    "holding_registers",
+			"address":  uint16(10),
+			"quantity": uint16(3),
+		}, fields["read"])
+		assert.Equal(t, common.MapStr{
+			"table":    "holding_registers",
+			"address":  uint16(20),
+			"quantity": uint16(2),
+		}, fields["write"])
+		assert.Equal(t, "holding_registers:10-12", trans[0]["resource"])
+	}
+}
+
+// Requests can be pipelined, responses are matched by transaction id.
+func TestModbusPipelined(t *testing.T) {
+	modbus := newTestModbus()
+	tuple := testTcpTuple()
+	ts := time.Now()
+
+	parse := func(private protos.ProtocolData, dir uint8, segment string) protos.ProtocolData {
+		payload, _ := hex.DecodeString(segment)
+		ts = ts.Add(time.Millisecond)
+		return modbus.Parse(&protos.Packet{Ts: ts, Payload: payload}, tuple, dir, private)
+	}
+
+	// both requests in one segment, the responses in reverse order and the
+	// second response split across segments
+	private := parse(nil, tcp.TcpDirectionOriginal,
+		"000100000006010100000008"+"000200000006010400100001")
+	private = parse(private, tcp.TcpDirectionReverse, "0002000000050104020007")
+	private = parse(private, tcp.TcpDirectionReverse, "00010000")
+	parse(private, tcp.TcpDirectionReverse, "0004010101ff")
+
+	trans := events(modbus)
+	if assert.Len(t, trans, 2) {
+		assert.Equal(t, "input_registers:16-16", trans[0]["resource"])
+		assert.Equal(t, "coils:0-7", trans[1]["resource"])
+	}
+}
+
+func TestModbusSendRequestResponse(t *testing.T) {
+	modbus := newTestModbus()
+	modbus.SendRequest = true
+	modbus.SendResponse = true
+	session(t, modbus,
+		"0001000000060106000a00ff",
+		"0001000000060106000a00ff",
+	)
+
+	trans := events(modbus)
+	if assert.Len(t, trans, 1) {
+		assert.Equal(t, "06000a00ff", trans[0]["request"])
+		assert.Equal(t, "06000a00ff", trans[0]["response"])
+		assert.Equal(t, "WRITE_SINGLE_REGISTER unit=1 write=holding_registers:10-10", trans[0]["query"])
+	}
+}
+
+func TestModbusInvalidHeader(t *testing.T) {
+	modbus := newTestModbus()
+	private := session(t, modbus, "000100010006010300640002")
+
+	conn := private.(*modbusConnectionData)
+	assert.Nil(t, conn.streams[tcp.TcpDirectionOriginal])
+	assert.Len(t, events(modbus), 0)
+}
+
+func TestModbusUnmatchedResponse(t *testing.T) {
+	modbus := newTestModbus()
+	private := session(t, modbus,
+		"000100000006010300640002",
+		"00010000000701030400fa0001",
+		"",
+		"00020000000701030400fa0001",
+	)
+
+	assert.Len(t, events(modbus), 1)
+	conn := private.(*modbusConnectionData)
+	assert.Len(t, conn.requests, 0)
+}
@@ -0,0 +1,164 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Function codes of the public functions.
+const (
+	readCoils                  = 1
+	readDiscreteInputs         = 2
+	readHoldingRegisters       = 3
+	readInputRegisters         = 4
+	writeSingleCoil            = 5
+	writeSingleRegister        = 6
+	readExceptionStatus        = 7
+	diagnostics                = 8
+	getCommEventCounter        = 11
+	getCommEventLog            = 12
+	writeMultipleCoils         = 15
+	writeMultipleRegisters     = 16
+	reportServerID             = 17
+	readFileRecord             = 20
+	writeFileRecord            = 21
+	maskWriteRegister          = 22
+	readWriteMultipleRegisters = 23
+	readFIFOQueue              = 24
+	encapsulatedInterface      = 43
+
+	// exceptionFlag is set in the function code of exception responses
+	exceptionFlag = 0x80
+)
+
+var functionNames = map[uint8]string{
+	readCoils:                  "read_coils",
+	readDiscreteInputs:         "read_discrete_inputs",
+	readHoldingRegisters:       "read_holding_registers",
+	readInputRegisters:         "read_input_registers",
+	writeSingleCoil:            "write_single_coil",
+	writeSingleRegister:        "write_single_register",
+	readExceptionStatus:        "read_exception_status",
+	diagnostics:                "diagnostics",
+	getCommEventCounter:        "get_comm_event_counter",
+	getCommEventLog:            "get_comm_event_log",
+	writeMultipleCoils:         "write_multiple_coils",
+	writeMultipleRegisters:     "write_multiple_registers",
+	reportServerID:             "report_server_id",
+	readFileRecord:             "read_file_record",
+	writeFileRecord:            "write_file_record",
+	maskWriteRegister:          "mask_write_register",
+	readWriteMultipleRegisters: "read_write_multiple_registers",
+	readFIFOQueue:              "read_fifo_queue",
+	encapsulatedInterface:      "encapsulated_interface_transport",
+}
+
+var exceptionNames = map[uint8]string{
+	1:  "illegal_function",
+	2:  "illegal_data_address",
+	3:  "illegal_data_value",
+	4:  "server_device_failure",
+	5:  "acknowledge",
+	6:  "server_device_busy",
+	8:  "memory_parity_error",
+	10: "gateway_path_unavailable",
+	11: "gateway_target_device_failed_to_respond",
+}
+
+// functionName returns the name of the function code, or a generic name for
+// user defined and unknown function codes.
+func functionName(code uint8) string {
+	if name, ok := functionNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("function_%d", code)
+}
+
+func exceptionName(code uint8) string {
+	if name, ok := exceptionNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("exception_%d", code)
+}
+
+// registerRange is a range of coils or registers accessed by a request.
+type registerRange struct {
+	table    string
+	address  uint16
+	quantity uint16
+}
+
+// String formats the range like "holding_registers:100-109".
+func (r *registerRange) String() string {
+	last := uint32(r.address) + uint32(r.quantity) - 1
+	if r.quantity <= 1 {
+		last = uint32(r.address)
+	}
+	return fmt.Sprintf("%s:%d-%d", r.table, r.address, last)
+}
+
+// decodeRequest decodes the register ranges read and written by the request
+// PDU data following the function code. Functions not accessing coils or
+// registers have no ranges.
+func decodeRequest(function uint8, data []byte) (read, write *registerRange, err error) {
+	switch function {
+	case readCoils, readDiscreteInputs, readHoldingRegisters, readInputRegisters:
+		if len(data) < 4 {
+			return nil, nil, errShortPDU
+		}
+		read = &registerRange{
+			table:    tableOf(function),
+			address:  binary.BigEndian.Uint16(data[0:2]),
+			quantity: binary.BigEndian.Uint16(data[2:4]),
+		}
+
+	case writeSingleCoil, writeSingleRegister, maskWriteRegister:
+		if len(data) < 2 {
+			return nil, nil, errShortPDU
+		}
+		write = &registerRange{
+			table:    tableOf(function),
+			address:  binary.BigEndian.Uint16(data[0:2]),
+			quantity: 1,
+		}
+
+	case writeMultipleCoils, writeMultipleRegisters:
+		if len(data) < 4 {
+			return nil, nil, errShortPDU
+		}
+		write = &registerRange{
+			table:    tableOf(function),
+			address:  binary.BigEndian.Uint16(data[0:2]),
+			quantity: binary.BigEndian.Uint16(data[2:4]),
+		}
+
+	case readWriteMultipleRegisters:
+		if len(data) < 8 {
+			return nil, nil, errShortPDU
+		}
+		read = &registerRange{
+			table:    "holding_registers",
+			address:  binary.BigEndian.Uint16(data[0:2]),
+			quantity: binary.BigEndian.Uint16(data[2:4]),
+		}
+		write = &registerRange{
+			table:    "holding_registers",
+			address:  binary.BigEndian.Uint16(data[4:6]),
+			quantity: binary.BigEndian.Uint16(data[6:8]),
+		}
+	}
+	return read, write, nil
+}
+
+// tableOf returns the data table accessed by the function.
+func tableOf(function uint8) string {
+	switch function {
+	case readCoils, writeSingleCoil, writeMultipleCoils:
+		return "coils"
+	case readDiscreteInputs:
+		return "discrete_inputs"
+	case readInputRegisters:
+		return "input_registers"
+	}
+	return "holding_registers"
+}
@@ -1,6 +1,7 @@
 package mongodb
 
 import (
+	"encoding/binary"
 	"expvar"
 	"fmt"
 	"strings"
@@ -38,6 +39,7 @@ type transactionKey struct {
 
 var (
 	unmatchedRequests = expvar.NewInt("mongodb.unmatched_requests")
+	parseErrors       = expvar.NewInt("mongodb.parse_errors")
 )
 
 func init() {
@@ -93,6 +95,21 @@ func (mongodb *Mongodb) GetPorts() []int {
 	return mongodb.Ports
 }
 
+// maximum size of a message accepted by the detection, which is the maximum
+// message size of MongoDB servers
+const maxMessageSize = 48 * 1000 * 1000
+
+// Detect reports if the sample starts with a message header of a valid size
+// and opcode.
+func (mongodb *Mongodb) Detect(sample []byte, dir uint8) bool {
+	if len(sample) < 16 {
+		return false
+	}
+	length := int32(binary.LittleEndian.Uint32(sample[0:4]))
+	code := opCode(binary.LittleEndian.Uint32(sample[12:16]))
+	return length >= 16 && length <= maxMessageSize && validOpcode(code)
+}
+
 func (mongodb *Mongodb) ConnectionTimeout() time.Duration {
 	return mongodb.transactionTimeout
 }
@@ -162,6 +179,7 @@ func (mongodb *Mongodb) doParse(
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			conn.Streams[dir] = nil
 			debugf("Ignore Mongodb message. Drop tcp stream. Try parsing with the next segment")
 			return conn
@@ -175,7 +193,11 @@ func (mongodb *Mongodb) doParse(
 
 		// all ok, go to next level and reset stream for new message
 		debugf("MongoDB message complete")
-		mongodb.handleMongodb(conn, st.message, tcptuple, dir)
+		if st.message.ignore {
+			debugf("Ignore MongoDB message that can not be decoded")
+		} else {
+			mongodb.handleMongodb(conn, st.message, tcptuple, dir)
+		}
 		st.PrepareForNewMessage()
 	}
 
@@ -213,7 +235,7 @@ func (mongodb *Mongodb) handleMongodb(
 
 func (mongodb *Mongodb) onRequest(conn *mongodbConnectionData, msg *mongodbMessage) {
 	// publish request only transaction
-	if !awaitsReply(msg.opCode) {
+	if !msg.ExpectsResponse {
 		mongodb.onTransComplete(msg, nil)
 		return
 	}
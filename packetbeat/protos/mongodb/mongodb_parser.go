@@ -1,13 +1,19 @@
 package mongodb
 
 import (
+	"bytes"
+	"compress/zlib"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
 	"strings"
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/elastic/beats/libbeat/logp"
 
+	"github.com/golang/snappy"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -48,33 +54,40 @@ func mongodbMessageParser(s *stream) (bool, bool) {
 
 	// then split depending on operation type
 	s.message.event = common.MapStr{}
+	return parseOperation(d, s.message, opCode)
+}
 
-	switch s.message.opCode {
+func parseOperation(d *decoder, m *mongodbMessage, code opCode) (bool, bool) {
+	switch code {
 	case opReply:
-		s.message.IsResponse = true
-		return opReplyParse(d, s.message)
-	case opMsg:
-		s.message.method = "msg"
-		return opMsgParse(d, s.message)
+		m.IsResponse = true
+		return opReplyParse(d, m)
+	case opMsgLegacy:
+		m.method = "msg"
+		return opMsgLegacyParse(d, m)
 	case opUpdate:
-		s.message.method = "update"
-		return opUpdateParse(d, s.message)
+		m.method = "update"
+		return opUpdateParse(d, m)
 	case opInsert:
-		s.message.method = "insert"
-		return opInsertParse(d, s.message)
+		m.method = "insert"
+		return opInsertParse(d, m)
 	case opQuery:
-		s.message.ExpectsResponse = true
-		return opQueryParse(d, s.message)
+		m.ExpectsResponse = true
+		return opQueryParse(d, m)
 	case opGetMore:
-		s.message.method = "getMore"
-		s.message.ExpectsResponse = true
-		return opGetMoreParse(d, s.message)
+		m.method = "getMore"
+		m.ExpectsResponse = true
+		return opGetMoreParse(d, m)
 	case opDelete:
-		s.message.method = "delete"
-		return opDeleteParse(d, s.message)
+		m.method = "delete"
+		return opDeleteParse(d, m)
 	case opKillCursor:
-		s.message.method = "killCursors"
-		return opKillCursorsParse(d, s.message)
+		m.method = "killCursors"
+		return opKillCursorsParse(d, m)
+	case opCompressed:
+		return opCompressedParse(d, m)
+	case opMsg:
+		return opMsgParse(d, m)
 	}
 
 	return false, false
@@ -118,7 +131,9 @@ func opReplyParse(d *decoder, m *mongodbMessage) (bool, bool) {
 	return true, true
 }
 
-func opMsgParse(d *decoder, m *mongodbMessage) (bool, bool) {
+// see https://docs.mongodb.com/manual/reference/mongodb-wire-protocol/#op-msg
+// (the deprecated OP_MSG of MongoDB 2.x, replaced by the opcode 2013)
+func opMsgLegacyParse(d *decoder, m *mongodbMessage) (bool, bool) {
 	var err error
 	m.event["message"], err = d.readCStr()
 	if err != nil {
@@ -128,6 +143,220 @@ func opMsgParse(d *decoder, m *mongodbMessage) (bool, bool) {
 	return true, true
 }
 
+// OP_MSG is used by MongoDB 3.6 and newer for commands and their replies. It
+// contains a body document and optional sequences of documents, like the
+// documents of an insert command.
+// see https://docs.mongodb.com/manual/reference/mongodb-wire-protocol/#op-msg
+func opMsgParse(d *decoder, m *mongodbMessage) (bool, bool) {
+	flags, err := d.readInt32()
+	if err == nil && flags&msgChecksumPresent != 0 {
+		if len(d.in)-d.i < 4 {
+			err = errors.New("checksum missing")
+		} else {
+			d.truncate(len(d.in) - 4)
+		}
+	}
+
+	var body bson.M
+	var command string
+	sequences := map[string][]interface{}{}
+	for err == nil && d.i < len(d.in) {
+		var kind byte
+		kind, err = d.readByte()
+		if err != nil {
+			break
+		}
+
+		switch kind {
+		case 0:
+			start := d.i
+			body, err = d.readDocument()
+			if err == nil {
+				command = firstElementName(d.in[start:d.i])
+			}
+		case 1:
+			var identifier string
+			var documents []interface{}
+			identifier, documents, err = d.readDocumentSequence()
+			sequences[identifier] = documents
+		default:
+			err = fmt.Errorf("unknown section kind %d", kind)
+		}
+	}
+	if err == nil && body == nil {
+		err = errors.New("body section missing")
+	}
+	if err != nil {
+		logp.Err("An error occured while parsing OP_MSG message: %s", err)
+		return false, false
+	}
+
+	// Replies are OP_MSG messages too, answering to the request id
+	if m.responseTo != 0 {
+		m.IsResponse = true
+		opMsgReply(m, body)
+	} else {
+		m.ExpectsResponse = flags&msgMoreToCome == 0
+		opMsgCommand(m, command, body, sequences)
+	}
+	return true, true
+}
+
+// opMsgCommand takes the method from the command name, the first key of the
+// body, and the resource from the database and the collection the command is
+// applied to.
+func opMsgCommand(m *mongodbMessage, command string, body bson.M, sequences map[string][]interface{}) {
+	m.method = command
+
+	database, _ := body["$db"].(string)
+	m.resource = database
+	if collection, ok := body[command].(string); ok {
+		m.resource = database + "." + collection
+	}
+
+	params := map[string]interface{}{}
+	for key, val := range body {
+		if key != command && key != "$db" {
+			params[key] = val
+		}
+	}
+
+	for identifier, documents := range sequences {
+		params[identifier] = documents
+	}
+
+	numberDocuments := 0
+	found := false
+	for _, key := range []string{"documents", "updates", "deletes"} {
+		if documents, ok := params[key].([]interface{}); ok {
+			numberDocuments += len(documents)
+			found = true
+		}
+	}
+	if found {
+		m.event["numberDocuments"] = numberDocuments
+	}
+
+	m.params = params
+}
+
+// opMsgReply takes the documents from the batch of the cursor returned by
+// queries, or the reply document itself for other commands.
+func opMsgReply(m *mongodbMessage, reply bson.M) {
+	m.documents = []interface{}{reply}
+	if cursor, ok := reply["cursor"].(bson.M); ok {
+		for _, key := range []string{"firstBatch", "nextBatch"} {
+			if batch, ok := cursor[key].([]interface{}); ok {
+				m.documents = batch
+			}
+		}
+		if id, ok := cursor["id"].(int64); ok {
+			m.event["cursorId"] = int(id)
+		}
+	}
+	m.event["numberReturned"] = len(m.documents)
+
+	if !isOK(reply["ok"]) {
+		m.error, _ = reply["errmsg"].(string)
+		if m.error == "" {
+			m.error = "command failed"
+		}
+	}
+	if writeErrors, present := reply["writeErrors"]; present {
+		m.error, _ = doc2str(writeErrors)
+	}
+}
+
+func isOK(v interface{}) bool {
+	switch ok := v.(type) {
+	case float64:
+		return ok == 1
+	case int:
+		return ok == 1
+	case int64:
+		return ok == 1
+	case bool:
+		return ok
+	}
+	return false
+}
+
+// see https://docs.mongodb.com/manual/reference/mongodb-wire-protocol/#op-compressed
+func opCompressedParse(d *decoder, m *mongodbMessage) (bool, bool) {
+	code, err := d.readInt32()
+	originalOpCode := opCode(code)
+	uncompressedSize, err := d.readInt32()
+	compressor, err := d.readByte()
+	if err != nil {
+		logp.Err("An error occured while parsing OP_COMPRESSED message: %s", err)
+		return false, false
+	}
+	if !validOpcode(originalOpCode) || originalOpCode == opCompressed {
+		logp.Err("Unknown operation code in OP_COMPRESSED message: %v", originalOpCode)
+		return false, false
+	}
+	if uncompressedSize < 0 || uncompressedSize > maxMessageSize {
+		logp.Err("Invalid uncompressed size in OP_COMPRESSED message: %d", uncompressedSize)
+		return false, false
+	}
+
+	name, found := compressorNames[compressor]
+	if !found {
+		name = strconv.Itoa(int(compressor))
+	}
+	m.event["compressor"] = name
+
+	data, err := decompress(compressor, d.in[d.i:], uncompressedSize)
+	if err == errUnsupportedCompressor {
+		debugf("Skip message compressed with unsupported compressor %s", name)
+		m.ignore = true
+		return true, true
+	}
+	if err != nil {
+		logp.Err("An error occured while decompressing OP_COMPRESSED message: %s", err)
+		return false, false
+	}
+
+	m.opCode = originalOpCode
+	return parseOperation(newDecoder(data), m, originalOpCode)
+}
+
+var errUnsupportedCompressor = errors.New("unsupported compressor")
+
+func decompress(compressor byte, in []byte, size int) ([]byte, error) {
+	var out []byte
+	switch compressor {
+	case compressorNoop:
+		out = in
+	case compressorSnappy:
+		if n, err := snappy.DecodedLen(in); err != nil || n != size {
+			return nil, errors.New("snappy: invalid decoded length")
+		}
+		var err error
+		out, err = snappy.Decode(nil, in)
+		if err != nil {
+			return nil, err
+		}
+	case compressorZlib:
+		r, err := zlib.NewReader(bytes.NewReader(in))
+		if err != nil {
+			return nil, err
+		}
+		out = make([]byte, size)
+		if _, err := io.ReadFull(r, out); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errUnsupportedCompressor
+	}
+
+	if len(out) != size {
+		return nil, fmt.Errorf("uncompressed size %d does not match the announced size %d",
+			len(out), size)
+	}
+	return out, nil
+}
+
 func opUpdateParse(d *decoder, m *mongodbMessage) (bool, bool) {
 	_, err := d.readInt32() // always ZERO, a slot reserved in the protocol for future use
 	m.event["fullCollectionName"], err = d.readCStr()
@@ -335,10 +564,24 @@ func (d *decoder) readInt64() (int, error) {
 		(uint64(b[7]) << 56)), nil
 }
 
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.readBytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
 func (d *decoder) readDocument() (bson.M, error) {
 	start := d.i
 	documentLength, err := d.readInt32()
+	if err != nil {
+		return nil, err
+	}
 	d.i = start + documentLength
+	if documentLength < 5 || d.i > len(d.in) {
+		return nil, errors.New("document length out of bounds")
+	}
 
 	documentMap := bson.M{}
 
@@ -353,6 +596,52 @@ func (d *decoder) readDocument() (bson.M, error) {
 	return documentMap, err
 }
 
+// firstElementName returns the name of the first element of a BSON document,
+// which is the command name in commands. The element order is lost once the
+// document is unmarshaled into a map.
+func firstElementName(document []byte) string {
+	if len(document) < 6 || document[4] == 0 {
+		return ""
+	}
+	name := document[5:]
+	if end := bytes.IndexByte(name, 0); end >= 0 {
+		return string(name[:end])
+	}
+	return ""
+}
+
+// readDocumentSequence reads a size prefixed sequence of documents, as found
+// in the sections of OP_MSG messages.
+func (d *decoder) readDocumentSequence() (string, []interface{}, error) {
+	start := d.i
+	size, err := d.readInt32()
+	if err != nil {
+		return "", nil, err
+	}
+	end := start + size
+	if size < 5 || end > len(d.in) {
+		return "", nil, errors.New("document sequence length out of bounds")
+	}
+
+	identifier, err := d.readCStr()
+	if err != nil {
+		return "", nil, err
+	}
+
+	documents := []interface{}{}
+	for d.i < end {
+		document, err := d.readDocument()
+		if err != nil {
+			return "", nil, err
+		}
+		documents = append(documents, document)
+	}
+	if d.i != end {
+		return "", nil, errors.New("document sequence length mismatch")
+	}
+	return identifier, documents, nil
+}
+
 func doc2str(documentMap interface{}) (string, error) {
 	document, err := json.Marshal(documentMap)
 	return string(document), err
@@ -17,6 +17,10 @@ type mongodbMessage struct {
 	IsResponse      bool
 	ExpectsResponse bool
 
+	// set for messages that can not be decoded, like messages compressed
+	// with an unsupported compressor, which are skipped
+	ignore bool
+
 	// Standard message header fields from mongodb wire protocol
 	// see http://docs.mongodb.org/meta-driver/latest/legacy/mongodb-wire-protocol/#standard-message-header
 	messageLength int
@@ -87,7 +91,7 @@ type opCode int32
 
 const (
 	opReply      opCode = 1
-	opMsg        opCode = 1000
+	opMsgLegacy  opCode = 1000
 	opUpdate     opCode = 2001
 	opInsert     opCode = 2002
 	opReserved   opCode = 2003
@@ -95,13 +99,15 @@ const (
 	opGetMore    opCode = 2005
 	opDelete     opCode = 2006
 	opKillCursor opCode = 2007
+	opCompressed opCode = 2012
+	opMsg        opCode = 2013
 )
 
 // List of valid mongodb wire protocol operation codes
-// see http://docs.mongodb.org/meta-driver/latest/legacy/mongodb-wire-protocol/#request-opcodes
+// see https://docs.mongodb.com/manual/reference/mongodb-wire-protocol/#request-opcodes
 var opCodeNames = map[opCode]string{
 	1:    "OP_REPLY",
-	1000: "OP_MSG",
+	1000: "OP_MSG_LEGACY",
 	2001: "OP_UPDATE",
 	2002: "OP_INSERT",
 	2003: "RESERVED",
@@ -109,6 +115,29 @@ var opCodeNames = map[opCode]string{
 	2005: "OP_GET_MORE",
 	2006: "OP_DELETE",
 	2007: "OP_KILL_CURSORS",
+	2012: "OP_COMPRESSED",
+	2013: "OP_MSG",
+}
+
+// Flag bits of OP_MSG messages
+const (
+	msgChecksumPresent = 1 << 0
+	msgMoreToCome      = 1 << 1
+)
+
+// Compressors of OP_COMPRESSED messages
+const (
+	compressorNoop   = 0
+	compressorSnappy = 1
+	compressorZlib   = 2
+	compressorZstd   = 3
+)
+
+var compressorNames = map[byte]string{
+	compressorNoop:   "noop",
+	compressorSnappy: "snappy",
+	compressorZlib:   "zlib",
+	compressorZstd:   "zstd",
 }
 
 func validOpcode(o opCode) bool {
@@ -120,10 +149,6 @@ func (o opCode) String() string {
 	return opCodeNames[o]
 }
 
-func awaitsReply(c opCode) bool {
-	return c == opQuery || c == opGetMore
-}
-
 // List of mongodb user commands (send throuwh a query of the legacy protocol)
 // see http://docs.mongodb.org/manual/reference/command/
 //
@@ -3,6 +3,9 @@
 package mongodb
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
 	"encoding/hex"
 	"net"
 	"testing"
@@ -11,7 +14,9 @@ import (
 	"github.com/elastic/beats/libbeat/logp"
 	"github.com/elastic/beats/packetbeat/protos"
 	"github.com/elastic/beats/packetbeat/publish"
+	"github.com/golang/snappy"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
 )
 
 // Helper function returning a Mongodb module that can be used
@@ -333,3 +338,218 @@ func TestMaxDocSize(t *testing.T) {
 
 	assert.Equal(t, "\"1234 ...\n\"123\"\n\"12\"", res["response"])
 }
+
+func TestMongodbDetect(t *testing.T) {
+	mongodb := Mongodb{}
+	query, _ := hex.DecodeString("3a0000000100000000000000d4070000")
+	assert.True(t, mongodb.Detect(query, 0))
+	assert.False(t, mongodb.Detect(query[:12], 0))
+
+	invalidOpcode, _ := hex.DecodeString("3a000000010000000000000063000000")
+	assert.False(t, mongodb.Detect(invalidOpcode, 0))
+}
+
+// Helper function building an OP_MSG message from a body document and
+// optional document sequences.
+func opMsgMessage(t *testing.T, requestID, responseTo int32, body bson.D, sequences map[string][]interface{}) []byte {
+	var payload []byte
+	payload = addInt32(payload, 0) // flags
+	payload = append(payload, 0)   // body section
+	doc, err := bson.Marshal(body)
+	assert.Nil(t, err)
+	payload = append(payload, doc...)
+
+	for identifier, documents := range sequences {
+		var section []byte
+		section = addCStr(section, identifier)
+		for _, document := range documents {
+			doc, err := bson.Marshal(document)
+			assert.Nil(t, err)
+			section = append(section, doc...)
+		}
+		payload = append(payload, 1)
+		payload = addInt32(payload, int32(len(section)+4))
+		payload = append(payload, section...)
+	}
+
+	return messageWithHeader(requestID, responseTo, opMsg, payload)
+}
+
+// Helper function wrapping a message into an OP_COMPRESSED message.
+func compressedMessage(t *testing.T, msg []byte, compressor byte) []byte {
+	original := msg[16:]
+	var compressed []byte
+	switch compressor {
+	case compressorSnappy:
+		compressed = snappy.Encode(nil, original)
+	case compressorZlib:
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		_, err := w.Write(original)
+		assert.Nil(t, err)
+		assert.Nil(t, w.Close())
+		compressed = buf.Bytes()
+	default:
+		compressed = original
+	}
+
+	var payload []byte
+	payload = append(payload, msg[12:16]...) // original opcode
+	payload = addInt32(payload, int32(len(original)))
+	payload = append(payload, compressor)
+	payload = append(payload, compressed...)
+
+	requestID := int32(binary.LittleEndian.Uint32(msg[4:8]))
+	responseTo := int32(binary.LittleEndian.Uint32(msg[8:12]))
+	return messageWithHeader(requestID, responseTo, opCompressed, payload)
+}
+
+func messageWithHeader(requestID, responseTo int32, code opCode, payload []byte) []byte {
+	var msg []byte
+	msg = addInt32(msg, int32(16+len(payload)))
+	msg = addInt32(msg, requestID)
+	msg = addInt32(msg, responseTo)
+	msg = addInt32(msg, int32(code))
+	return append(msg, payload...)
+}
+
+func opMsgFind(t *testing.T) (request, reply []byte) {
+	request = opMsgMessage(t, 7, 0, bson.D{
+		{Name: "find", Value: "restaurants"},
+		{Name: "filter", Value: bson.M{"borough": "Bronx"}},
+		{Name: "$db", Value: "test"},
+	}, nil)
+	reply = opMsgMessage(t, 8, 7, bson.D{
+		{Name: "cursor", Value: bson.M{
+			"id": int64(0),
+			"ns": "test.restaurants",
+			"firstBatch": []interface{}{
+				bson.M{"name": "Morris Park Bake Shop"},
+				bson.M{"name": "Wendy'S"},
+			},
+		}},
+		{Name: "ok", Value: float64(1)},
+	}, nil)
+	return
+}
+
+// Test a find command and its reply sent as OP_MSG messages.
+func TestOpMsgFind(t *testing.T) {
+	mongodb := MongodbModForTests()
+	mongodb.SendResponse = true
+
+	request, reply := opMsgFind(t)
+	tcptuple := testTcpTuple()
+	req := protos.Packet{Payload: request}
+	resp := protos.Packet{Payload: reply}
+
+	private := protos.ProtocolData(new(mongodbConnectionData))
+	private = mongodb.Parse(&req, tcptuple, 0, private)
+	private = mongodb.Parse(&resp, tcptuple, 1, private)
+	trans := expectTransaction(t, mongodb)
+
+	assert.Equal(t, "OK", trans["status"])
+	assert.Equal(t, "find", trans["method"])
+	assert.Equal(t, "test.restaurants", trans["resource"])
+	assert.Equal(t, 2, trans["mongodb"].(common.MapStr)["numberReturned"])
+	assert.Equal(t, "{\"name\":\"Morris Park Bake Shop\"}\n{\"name\":\"Wendy'S\"}", trans["response"])
+}
+
+// Test that the documents of an insert command sent in a document sequence
+// are counted, and that errors in the reply are reported.
+func TestOpMsgInsertError(t *testing.T) {
+	mongodb := MongodbModForTests()
+
+	request := opMsgMessage(t, 3, 0, bson.D{
+		{Name: "insert", Value: "users"},
+		{Name: "ordered", Value: true},
+		{Name: "$db", Value: "app"},
+	}, map[string][]interface{}{
+		"documents": {bson.M{"_id": 1}, bson.M{"_id": 2}, bson.M{"_id": 3}},
+	})
+	reply := opMsgMessage(t, 4, 3, bson.D{
+		{Name: "ok", Value: float64(0)},
+		{Name: "errmsg", Value: "not authorized on app to execute command"},
+		{Name: "code", Value: int32(13)},
+	}, nil)
+
+	tcptuple := testTcpTuple()
+	private := protos.ProtocolData(new(mongodbConnectionData))
+	private = mongodb.Parse(&protos.Packet{Payload: request}, tcptuple, 0, private)
+	private = mongodb.Parse(&protos.Packet{Payload: reply}, tcptuple, 1, private)
+	trans := expectTransaction(t, mongodb)
+
+	assert.Equal(t, "Error", trans["status"])
+	assert.Equal(t, "insert", trans["method"])
+	assert.Equal(t, "app.users", trans["resource"])
+	assert.Equal(t, `app.users.insert({"ordered":true})`, trans["query"])
+	event := trans["mongodb"].(common.MapStr)
+	assert.Equal(t, 3, event["numberDocuments"])
+	assert.Equal(t, "not authorized on app to execute command", event["error"])
+}
+
+// Test that OP_MSG messages with the moreToCome flag are published
+// without waiting for a reply.
+func TestOpMsgMoreToCome(t *testing.T) {
+	mongodb := MongodbModForTests()
+
+	request := opMsgMessage(t, 5, 0, bson.D{
+		{Name: "delete", Value: "sessions"},
+		{Name: "deletes", Value: []interface{}{bson.M{"q": bson.M{}, "limit": 0}}},
+		{Name: "$db", Value: "app"},
+	}, nil)
+	request[16] |= msgMoreToCome
+
+	private := protos.ProtocolData(new(mongodbConnectionData))
+	mongodb.Parse(&protos.Packet{Payload: request}, testTcpTuple(), 0, private)
+	trans := expectTransaction(t, mongodb)
+
+	assert.Equal(t, "delete", trans["method"])
+	assert.Equal(t, 1, trans["mongodb"].(common.MapStr)["numberDocuments"])
+}
+
+// Test messages compressed with the snappy, zlib and noop compressors.
+func TestOpCompressed(t *testing.T) {
+	for _, compressor := range []byte{compressorNoop, compressorSnappy, compressorZlib} {
+		mongodb := MongodbModForTests()
+
+		request, reply := opMsgFind(t)
+		request = compressedMessage(t, request, compressor)
+		reply = compressedMessage(t, reply, compressor)
+
+		tcptuple := testTcpTuple()
+		private := protos.ProtocolData(new(mongodbConnectionData))
+		private = mongodb.Parse(&protos.Packet{Payload: request}, tcptuple, 0, private)
+		private = mongodb.Parse(&protos.Packet{Payload: reply}, tcptuple, 1, private)
+		trans := expectTransaction(t, mongodb)
+		if trans == nil {
+			continue
+		}
+
+		assert.Equal(t, "find", trans["method"])
+		assert.Equal(t, "test.restaurants", trans["resource"])
+		event := trans["mongodb"].(common.MapStr)
+		assert.Equal(t, 2, event["numberReturned"])
+		assert.Equal(t, compressorNames[compressor], event["compressor"])
+	}
+}
+
+// Test that messages compressed with an unsupported compressor are skipped,
+// without dropping the stream.
+func TestOpCompressedUnsupported(t *testing.T) {
+	mongodb := MongodbModForTests()
+
+	request, reply := opMsgFind(t)
+	skipped := compressedMessage(t, request, compressorNoop)
+	skipped[16+8] = compressorZstd
+
+	tcptuple := testTcpTuple()
+	private := protos.ProtocolData(new(mongodbConnectionData))
+	payload := append(skipped, request...)
+	private = mongodb.Parse(&protos.Packet{Payload: payload}, tcptuple, 0, private)
+	private = mongodb.Parse(&protos.Packet{Payload: reply}, tcptuple, 1, private)
+	trans := expectTransaction(t, mongodb)
+
+	assert.Equal(t, "find", trans["method"])
+	assert.Nil(t, trans["mongodb"].(common.MapStr)["compressor"])
+}
@@ -7,8 +7,9 @@ import (
 
 type mysqlConfig struct {
 	config.ProtocolCommon `config:",inline"`
-	MaxRowLength          int `config:"max_row_length"`
-	MaxRows               int `config:"max_rows"`
+	MaxRowLength          int  `config:"max_row_length"`
+	MaxRows               int  `config:"max_rows"`
+	NormalizeQuery        bool `config:"normalize_query"`
 }
 
 var (
@@ -12,6 +12,7 @@ import (
 
 	"github.com/elastic/beats/packetbeat/procs"
 	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/sqlnorm"
 	"github.com/elastic/beats/packetbeat/protos/tcp"
 	"github.com/elastic/beats/packetbeat/publish"
 )
@@ -26,6 +27,7 @@ const MAX_PAYLOAD_SIZE = 100 * 1024
 var (
 	unmatchedRequests  = expvar.NewInt("mysql.unmatched_requests")
 	unmatchedResponses = expvar.NewInt("mysql.unmatched_responses")
+	parseErrors        = expvar.NewInt("mysql.parse_errors")
 )
 
 type MysqlMessage struct {
@@ -125,6 +127,8 @@ type Mysql struct {
 	Send_request  bool
 	Send_response bool
 
+	normalizeQuery bool
+
 	transactions       *common.Cache
 	transactionTimeout time.Duration
 
@@ -178,6 +182,7 @@ func (mysql *Mysql) setFromConfig(config *mysqlConfig) {
 	mysql.Send_request = config.SendRequest
 	mysql.Send_response = config.SendResponse
 	mysql.transactionTimeout = config.TransactionTimeout
+	mysql.normalizeQuery = config.NormalizeQuery
 }
 
 func (mysql *Mysql) getTransaction(k common.HashableTcpTuple) *MysqlTransaction {
@@ -192,6 +197,30 @@ func (mysql *Mysql) GetPorts() []int {
 	return mysql.Ports
 }
 
+// Detect reports if the sample starts with the handshake packet sent by the
+// server, which has the protocol version 10 and a server version string.
+func (mysql *Mysql) Detect(sample []byte, dir uint8) bool {
+	if len(sample) < 6 {
+		return false
+	}
+
+	length := int(sample[0]) | int(sample[1])<<8 | int(sample[2])<<16
+	if sample[3] != 0 || sample[4] != 10 || length < 32 || length > 1024 {
+		return false
+	}
+
+	// the server version is a printable null terminated string
+	for i, c := range sample[5:] {
+		if c == 0 {
+			return i > 0
+		}
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return false
+}
+
 func (stream *MysqlStream) PrepareForNewMessage() {
 	stream.data = stream.data[stream.parseOffset:]
 	stream.parseState = mysqlStateStart
@@ -519,6 +548,7 @@ func (mysql *Mysql) Parse(pkt *protos.Packet, tcptuple *common.TcpTuple,
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			priv.Data[dir] = nil
 			logp.Debug("mysql", "Ignore MySQL message. Drop tcp stream. Try parsing with the next segment")
 			return priv
@@ -860,6 +890,11 @@ func (mysql *Mysql) publishTransaction(t *MysqlTransaction) {
 	}
 	event["method"] = t.Method
 	event["query"] = t.Query
+	if mysql.normalizeQuery {
+		normalized := sqlnorm.Normalize(t.Query, sqlnorm.MySQL)
+		event["query_normalized"] = normalized
+		event["query_hash"] = sqlnorm.Hash(normalized)
+	}
 	event["mysql"] = t.Mysql
 	event["path"] = t.Path
 	event["bytes_out"] = t.BytesOut
@@ -630,3 +630,27 @@ func Test_parseMysqlResponse_invalid(t *testing.T) {
 		assert.Equal(t, [][]string{}, rows)
 	}
 }
+
+func TestMysqlDetect(t *testing.T) {
+	mysql := Mysql{}
+	greeting, _ := hex.DecodeString("4a0000000a352e372e3231000b000000")
+	assert.True(t, mysql.Detect(greeting, 1))
+	assert.False(t, mysql.Detect(greeting[:8], 1))
+
+	query, _ := hex.DecodeString("0f0000000373656c656374202a2066726f6d2074")
+	assert.False(t, mysql.Detect(query, 0))
+}
+
+func TestMysqlNormalizeQuery(t *testing.T) {
+	mysql := MysqlModForTests()
+	mysql.normalizeQuery = true
+
+	mysql.publishTransaction(&MysqlTransaction{
+		Query: "SELECT * FROM test WHERE id IN (1, 2, 3)",
+		Mysql: common.MapStr{"iserror": false},
+	})
+
+	trans := expectTransaction(t, mysql)
+	assert.Equal(t, "select * from test where id in (?)", trans["query_normalized"])
+	assert.NotEmpty(t, trans["query_hash"])
+}
@@ -23,6 +23,7 @@ var ACCEPT_STATUS = [...]string{
 
 var (
 	unmatchedRequests = expvar.NewInt("nfs.unmatched_requests")
+	parseErrors       = expvar.NewInt("nfs.parse_errors")
 )
 
 // called by Cache, when re reply seen within expected time window
@@ -241,6 +241,7 @@ func (rpc *Rpc) handleRpcPacket(xdr *Xdr, ts time.Time, tcptuple *common.TcpTupl
 	case RPC_REPLY:
 		rpc.handleReply(xid, xdr, ts, tcptuple, dir)
 	default:
+		parseErrors.Add(1)
 		logp.Warn("Bad RPC message")
 	}
 }
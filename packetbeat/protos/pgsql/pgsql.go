@@ -1,6 +1,7 @@
 package pgsql
 
 import (
+	"encoding/binary"
 	"errors"
 	"expvar"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/elastic/beats/packetbeat/procs"
 	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/sqlnorm"
 	"github.com/elastic/beats/packetbeat/protos/tcp"
 	"github.com/elastic/beats/packetbeat/publish"
 )
@@ -100,6 +102,7 @@ var (
 
 var (
 	unmatchedResponses = expvar.NewInt("pgsql.unmatched_responses")
+	parseErrors        = expvar.NewInt("pgsql.parse_errors")
 )
 
 type Pgsql struct {
@@ -111,6 +114,8 @@ type Pgsql struct {
 	Send_request  bool
 	Send_response bool
 
+	normalizeQuery bool
+
 	transactions       *common.Cache
 	transactionTimeout time.Duration
 
@@ -164,6 +169,7 @@ func (pgsql *Pgsql) setFromConfig(config *pgsqlConfig) {
 	pgsql.Send_request = config.SendRequest
 	pgsql.Send_response = config.SendResponse
 	pgsql.transactionTimeout = config.TransactionTimeout
+	pgsql.normalizeQuery = config.NormalizeQuery
 }
 
 func (pgsql *Pgsql) getTransaction(k common.HashableTcpTuple) []*PgsqlTransaction {
@@ -178,6 +184,17 @@ func (pgsql *Pgsql) GetPorts() []int {
 	return pgsql.Ports
 }
 
+// Detect reports if the sample starts with a startup message of the protocol
+// version 3.0.
+func (pgsql *Pgsql) Detect(sample []byte, dir uint8) bool {
+	if len(sample) < 8 {
+		return false
+	}
+	length := binary.BigEndian.Uint32(sample[0:4])
+	version := binary.BigEndian.Uint32(sample[4:8])
+	return version == 0x00030000 && length >= 8 && length <= 10000
+}
+
 func (stream *PgsqlStream) PrepareForNewMessage() {
 	stream.data = stream.data[stream.message.end:]
 	stream.parseState = PgsqlStartState
@@ -255,6 +272,7 @@ func (pgsql *Pgsql) Parse(pkt *protos.Packet, tcptuple *common.TcpTuple,
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			priv.Data[dir] = nil
 			debugf("Ignore Postgresql message. Drop tcp stream. Try parsing with the next segment")
 			return priv
@@ -473,6 +491,11 @@ func (pgsql *Pgsql) publishTransaction(t *PgsqlTransaction) {
 		event["response"] = t.Response_raw
 	}
 	event["query"] = t.Query
+	if pgsql.normalizeQuery {
+		normalized := sqlnorm.Normalize(t.Query, sqlnorm.PostgreSQL)
+		event["query_normalized"] = normalized
+		event["query_hash"] = sqlnorm.Hash(normalized)
+	}
 	event["method"] = t.Method
 	event["bytes_out"] = t.BytesOut
 	event["bytes_in"] = t.BytesIn
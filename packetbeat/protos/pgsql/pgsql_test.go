@@ -360,3 +360,27 @@ func Test_gap_in_response(t *testing.T) {
 	assert.NotNil(t, trans)
 	assert.Equal(t, trans["notes"], []string{"Packet loss while capturing the response"})
 }
+
+func TestPgsqlDetect(t *testing.T) {
+	pgsql := Pgsql{}
+	startup, _ := hex.DecodeString("00000029000300007573657200706f737467726573")
+	assert.True(t, pgsql.Detect(startup, 0))
+
+	sslRequest, _ := hex.DecodeString("0000000804d2162f")
+	assert.False(t, pgsql.Detect(sslRequest, 0))
+	assert.False(t, pgsql.Detect(startup[:6], 0))
+}
+
+func TestPgsqlNormalizeQuery(t *testing.T) {
+	pgsql := PgsqlModForTests()
+	pgsql.normalizeQuery = true
+
+	pgsql.publishTransaction(&PgsqlTransaction{
+		Query: "SELECT * FROM test WHERE name = 'x' AND id = $1",
+		Pgsql: common.MapStr{"iserror": false},
+	})
+
+	trans := expectTransaction(t, pgsql)
+	assert.Equal(t, "select * from test where name = ? and id = ?", trans["query_normalized"])
+	assert.NotEmpty(t, trans["query_hash"])
+}
@@ -63,6 +63,7 @@ type Protocols interface {
 	GetAll() map[Protocol]Plugin
 	GetAllTcp() map[Protocol]TcpPlugin
 	GetAllUdp() map[Protocol]UdpPlugin
+	GetTcpDetectors() map[Protocol]TcpDetector
 	// Register(proto Protocol, plugin ProtocolPlugin)
 }
 
@@ -71,13 +72,17 @@ type ProtocolsStruct struct {
 	all map[Protocol]Plugin
 	tcp map[Protocol]TcpPlugin
 	udp map[Protocol]UdpPlugin
+
+	// TCP plugins with detection enabled
+	detectors map[Protocol]TcpDetector
 }
 
 // Singleton of Protocols type.
 var Protos = ProtocolsStruct{
-	all: map[Protocol]Plugin{},
-	tcp: map[Protocol]TcpPlugin{},
-	udp: map[Protocol]UdpPlugin{},
+	all:       map[Protocol]Plugin{},
+	tcp:       map[Protocol]TcpPlugin{},
+	udp:       map[Protocol]UdpPlugin{},
+	detectors: map[Protocol]TcpDetector{},
 }
 
 func (protocols ProtocolsStruct) Init(
@@ -113,6 +118,21 @@ func (protocols ProtocolsStruct) Init(
 		}
 
 		protocols.register(proto, inst)
+
+		var detect struct {
+			Detect bool `config:"detect"`
+		}
+		if err := config.Unpack(&detect); err != nil {
+			return err
+		}
+		if detect.Detect {
+			detector, ok := inst.(TcpDetector)
+			if !ok {
+				return fmt.Errorf("Protocol %s doesn't support detection", name)
+			}
+			logp.Info("Detecting %s streams on all TCP ports", name)
+			protocols.detectors[proto] = detector
+		}
 	}
 
 	return nil
@@ -148,10 +168,16 @@ func (protocols ProtocolsStruct) GetAllUdp() map[Protocol]UdpPlugin {
 	return protocols.udp
 }
 
+// GetTcpDetectors returns the TCP plugins with detection enabled.
+func (protocols ProtocolsStruct) GetTcpDetectors() map[Protocol]TcpDetector {
+	return protocols.detectors
+}
+
 // BpfFilter returns a Berkeley Packer Filter (BFP) expression that
 // will match against packets for the registered protocols. If with_vlans is
 // true the filter will match against both IEEE 802.1Q VLAN encapsulated
-// and unencapsulated packets
+// and unencapsulated packets. If detection is enabled for a protocol, the
+// filter matches all TCP packets.
 func (protocols ProtocolsStruct) BpfFilter(with_vlans bool, with_icmp bool) string {
 	// Sort the protocol IDs so that the return value is consistent.
 	var protos []int
@@ -188,6 +214,10 @@ func (protocols ProtocolsStruct) BpfFilter(with_vlans bool, with_icmp bool) stri
 		}
 	}
 
+	if len(protocols.detectors) > 0 {
+		expressions = append(expressions, "tcp")
+	}
+
 	if with_icmp {
 		expressions = append(expressions, "icmp", "icmp6")
 	}
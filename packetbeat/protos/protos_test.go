@@ -145,6 +145,19 @@ func TestBpfFilterWithVlanWithIcmp(t *testing.T) {
 		"(vlan and (tcp port 80 or udp port 5060 or port 53 or icmp or icmp6))", filter)
 }
 
+type DetectingTcpProtocol struct {
+	TcpProtocol
+}
+
+func (proto *DetectingTcpProtocol) Detect(sample []byte, dir uint8) bool { return false }
+
+func TestBpfFilterWithDetection(t *testing.T) {
+	p := newProtocols().(ProtocolsStruct)
+	p.detectors = map[Protocol]TcpDetector{4: &DetectingTcpProtocol{}}
+	filter := p.BpfFilter(false, false)
+	assert.Equal(t, "tcp port 80 or udp port 5060 or port 53 or tcp", filter)
+}
+
 func TestGetAll(t *testing.T) {
 	p := newProtocols()
 	all := p.GetAll()
@@ -50,6 +50,7 @@ var (
 
 var (
 	unmatchedResponses = expvar.NewInt("redis.unmatched_responses")
+	parseErrors        = expvar.NewInt("redis.parse_errors")
 )
 
 func init() {
@@ -95,6 +96,12 @@ func (redis *Redis) GetPorts() []int {
 	return redis.Ports
 }
 
+// Detect reports if the sample starts with a command sent as an array of
+// bulk strings, like "*2\r\n$3\r\nGET\r\n".
+func (redis *Redis) Detect(sample []byte, dir uint8) bool {
+	return isRedisRequest(sample)
+}
+
 func (s *stream) PrepareForNewMessage() {
 	parser := &s.parser
 	s.Stream.Reset()
@@ -174,6 +181,7 @@ func (redis *Redis) doParse(
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			conn.Streams[dir] = nil
 			if isDebug {
 				debugf("Ignore Redis message. Drop tcp stream. Try parsing with the next segment")
@@ -1,6 +1,8 @@
 package redis
 
 import (
+	"bytes"
+	"strconv"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
@@ -223,6 +225,38 @@ func init() {
 	}
 }
 
+// isRedisRequest reports if the data starts with the array and the bulk
+// string of a known command.
+func isRedisRequest(data []byte) bool {
+	readLine := func(prefix byte) ([]byte, bool) {
+		i := bytes.Index(data, []byte("\r\n"))
+		if i < 2 || data[0] != prefix {
+			return nil, false
+		}
+		line := data[1:i]
+		data = data[i+2:]
+		return line, true
+	}
+
+	count, ok := readLine('*')
+	if !ok {
+		return false
+	}
+	if n, err := strconv.Atoi(string(count)); err != nil || n < 1 {
+		return false
+	}
+
+	length, ok := readLine('$')
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(string(length))
+	if err != nil || n < 1 || n > maxCommandLen || len(data) < n+2 {
+		return false
+	}
+	return isRedisCommand(data[:n])
+}
+
 func isRedisCommand(key common.NetString) bool {
 	if len(key) > maxCommandLen {
 		return false
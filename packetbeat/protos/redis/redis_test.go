@@ -216,3 +216,12 @@ func BenchmarkParserArray2Passes(b *testing.B) {
 		st.parser.parse(&st.Buf)
 	}
 }
+
+func TestRedisDetect(t *testing.T) {
+	redis := Redis{}
+	assert.True(t, redis.Detect(noArgsRequest, 0))
+	assert.True(t, redis.Detect([]byte("*2\r\n$3\r\nget\r\n$3\r\nkey\r\n"), 0))
+	assert.False(t, redis.Detect([]byte("*2\r\n$3\r\nGE"), 0))
+	assert.False(t, redis.Detect([]byte("*2\r\n$3\r\nFOO\r\n"), 0))
+	assert.False(t, redis.Detect([]byte("+OK\r\n"), 1))
+}
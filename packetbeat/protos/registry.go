@@ -40,6 +40,18 @@ type TcpPlugin interface {
 	ConnectionTimeout() time.Duration
 }
 
+// TcpDetector is implemented by TCP plugins that can recognize their protocol
+// in the first bytes of a stream, to analyze streams on ports not configured
+// for any protocol.
+type TcpDetector interface {
+	TcpPlugin
+
+	// Detect reports if the data sampled from the start of the stream in
+	// the direction looks like the protocol. The sample can end in the
+	// middle of a message.
+	Detect(sample []byte, dir uint8) bool
+}
+
 type UdpPlugin interface {
 	Plugin
 
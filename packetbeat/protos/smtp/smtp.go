@@ -0,0 +1,619 @@
+package smtp
+
+import (
+	"errors"
+	"expvar"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+
+	"github.com/elastic/beats/packetbeat/procs"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/protos/textproto"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+// clientMode selects how the next lines sent by the client are parsed.
+type clientMode uint8
+
+const (
+	// commands terminated by CRLF
+	modeCommand clientMode = iota
+
+	// message content following DATA, terminated by a line with a single dot
+	modeData
+
+	// single line answering an AUTH challenge
+	modeAuth
+
+	// message content of the size given to BDAT
+	modeChunk
+)
+
+type stream struct {
+	tcptuple *common.TcpTuple
+	data     []byte
+
+	// set once the first line of the stream tells if the server or the
+	// client is sending
+	classified bool
+	isServer   bool
+
+	mode clientMode
+
+	// command receiving the BDAT chunk, and bytes left in the chunk
+	chunk     *request
+	chunkLeft int
+}
+
+type request struct {
+	ts       time.Time
+	tuple    common.TcpTuple
+	dir      uint8
+	cmdline  *common.CmdlineTuple
+	verb     string
+	arg      string
+	query    string
+	size     int
+	dataSize int
+
+	// size of the intermediate replies, like 354 to DATA
+	replySize int
+}
+
+type smtpConnectionData struct {
+	streams  [2]*stream
+	requests []*request
+
+	// set once the greeting of the server is seen
+	greeted bool
+
+	// set after a successful STARTTLS, the rest of the connection is
+	// encrypted and can not be analyzed
+	encrypted bool
+
+	// envelope of the message being sent
+	mailFrom string
+	rcptTo   []string
+}
+
+// Smtp protocol plugin
+type Smtp struct {
+	// config
+	Ports        []int
+	SendRequest  bool
+	SendResponse bool
+
+	transactionTimeout time.Duration
+
+	results publish.Transactions
+}
+
+var (
+	debugf = logp.MakeDebug("smtp")
+)
+
+var (
+	unmatchedResponses = expvar.NewInt("smtp.unmatched_responses")
+	parseErrors        = expvar.NewInt("smtp.parse_errors")
+)
+
+var errInvalidCommand = errors.New("invalid command")
+
+func init() {
+	protos.Register("smtp", New)
+}
+
+func New(
+	testMode bool,
+	results publish.Transactions,
+	cfg *common.Config,
+) (protos.Plugin, error) {
+	p := &Smtp{}
+	config := defaultConfig
+	if !testMode {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.init(results, &config); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (smtp *Smtp) init(results publish.Transactions, config *smtpConfig) error {
+	smtp.setFromConfig(config)
+	smtp.results = results
+	return nil
+}
+
+func (smtp *Smtp) setFromConfig(config *smtpConfig) {
+	smtp.Ports = config.Ports
+	smtp.SendRequest = config.SendRequest
+	smtp.SendResponse = config.SendResponse
+	smtp.transactionTimeout = config.TransactionTimeout
+}
+
+func (smtp *Smtp) GetPorts() []int {
+	return smtp.Ports
+}
+
+// Detect reports if the sample starts with the greeting of an SMTP server,
+// or with the HELO or EHLO command of a client.
+func (smtp *Smtp) Detect(sample []byte, dir uint8) bool {
+	line, n := textproto.ReadLine(sample)
+	if n == 0 {
+		return false
+	}
+	if textproto.IsReply(sample) {
+		return string(line[:3]) == "220" &&
+			strings.Contains(strings.ToUpper(string(line)), "SMTP")
+	}
+	verb, _ := textproto.ParseCommand(line)
+	return verb == "EHLO" || verb == "HELO"
+}
+
+func (smtp *Smtp) ConnectionTimeout() time.Duration {
+	return smtp.transactionTimeout
+}
+
+func (smtp *Smtp) Parse(
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+	private protos.ProtocolData,
+) protos.ProtocolData {
+	defer logp.Recover("ParseSmtp exception")
+
+	conn := ensureSmtpConnection(private)
+	return smtp.doParse(conn, pkt, tcptuple, dir)
+}
+
+func ensureSmtpConnection(private protos.ProtocolData) *smtpConnectionData {
+	if private == nil {
+		return &smtpConnectionData{}
+	}
+
+	priv, ok := private.(*smtpConnectionData)
+	if !ok {
+		logp.Warn("smtp connection data type error, create new one")
+		return &smtpConnectionData{}
+	}
+	if priv == nil {
+		logp.Warn("Unexpected: smtp connection data not set, create new one")
+		return &smtpConnectionData{}
+	}
+
+	return priv
+}
+
+func (smtp *Smtp) doParse(
+	conn *smtpConnectionData,
+	pkt *protos.Packet,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) *smtpConnectionData {
+	if conn.encrypted {
+		return conn
+	}
+
+	st := conn.streams[dir]
+	if st == nil {
+		st = &stream{tcptuple: tcptuple}
+		conn.streams[dir] = st
+		debugf("new stream: %p (dir=%v, len=%v)", st, dir, len(pkt.Payload))
+	}
+
+	st.data = append(st.data, pkt.Payload...)
+	if len(st.data) > tcp.TCP_MAX_DATA_IN_STREAM {
+		debugf("Stream data too large, dropping TCP stream")
+		conn.streams[dir] = nil
+		return conn
+	}
+
+	for len(st.data) > 0 && !conn.encrypted {
+		if !st.classified {
+			if _, n := textproto.ReadLine(st.data); n == 0 && len(st.data) < 4 {
+				break
+			}
+			st.isServer = textproto.IsReply(st.data)
+			st.classified = true
+		}
+
+		var complete bool
+		var err error
+		if st.isServer {
+			complete, err = smtp.parseReply(conn, st, pkt.Ts)
+		} else {
+			complete, err = smtp.parseCommand(conn, st, pkt.Ts, tcptuple, dir)
+		}
+		if err != nil {
+			// drop this tcp stream. Will retry parsing with the next
+			// segment in it
+			parseErrors.Add(1)
+			conn.streams[dir] = nil
+			debugf("Ignore SMTP message (%v). Drop tcp stream.", err)
+			return conn
+		}
+
+		if !complete {
+			// wait for more data
+			break
+		}
+	}
+
+	return conn
+}
+
+func (smtp *Smtp) parseCommand(
+	conn *smtpConnectionData,
+	st *stream,
+	ts time.Time,
+	tcptuple *common.TcpTuple,
+	dir uint8,
+) (bool, error) {
+	if st.mode == modeChunk {
+		n := len(st.data)
+		if n > st.chunkLeft {
+			n = st.chunkLeft
+		}
+		st.data = st.data[n:]
+		st.chunk.size += n
+		st.chunk.dataSize += n
+		st.chunkLeft -= n
+		if st.chunkLeft > 0 {
+			return false, nil
+		}
+		st.mode = modeCommand
+		st.chunk = nil
+		return true, nil
+	}
+
+	line, n := textproto.ReadLine(st.data)
+
+	if st.mode == modeData {
+		req := conn.pending()
+		if n == 0 {
+			if len(st.data) <= textproto.MaxLineLength {
+				return false, nil
+			}
+			// account for long lines without buffering them
+			n = len(st.data)
+		}
+		st.data = st.data[n:]
+		if req != nil {
+			req.size += n
+		}
+		if string(line) == "." {
+			st.mode = modeCommand
+		} else if req != nil {
+			req.dataSize += n
+		}
+		return true, nil
+	}
+
+	if n == 0 {
+		if len(st.data) > textproto.MaxLineLength {
+			return false, textproto.ErrLineTooLong
+		}
+		return false, nil
+	}
+	st.data = st.data[n:]
+
+	if st.mode == modeAuth {
+		// the client answers the challenge with credentials, which are
+		// accounted for but never reported
+		if req := conn.pending(); req != nil {
+			req.size += n
+		}
+		st.mode = modeCommand
+		return true, nil
+	}
+
+	verb, arg := textproto.ParseCommand(line)
+	if !validVerb(verb) {
+		return false, errInvalidCommand
+	}
+
+	query := string(line)
+	if verb == "AUTH" {
+		query = verb
+		if fields := strings.Fields(arg); len(fields) > 0 {
+			query += " " + strings.ToUpper(fields[0])
+		}
+	}
+
+	req := &request{
+		ts:      ts,
+		tuple:   *tcptuple,
+		dir:     dir,
+		cmdline: procs.ProcWatcher.FindProcessesTuple(tcptuple.IpPort()),
+		verb:    verb,
+		arg:     arg,
+		query:   query,
+		size:    n,
+	}
+	conn.requests = append(conn.requests, req)
+
+	if verb == "BDAT" {
+		// the chunk follows the command without waiting for a reply
+		size, err := chunkSize(arg)
+		if err != nil {
+			return false, err
+		}
+		if size > 0 {
+			st.mode = modeChunk
+			st.chunk = req
+			st.chunkLeft = size
+		}
+	}
+	return true, nil
+}
+
+func (smtp *Smtp) parseReply(
+	conn *smtpConnectionData,
+	st *stream,
+	ts time.Time,
+) (bool, error) {
+	reply, n, err := textproto.ParseReply(st.data)
+	if err != nil {
+		return false, err
+	}
+	if reply == nil {
+		return false, nil
+	}
+	st.data = st.data[n:]
+
+	smtp.handleReply(conn, reply, ts)
+	return true, nil
+}
+
+// pending returns the oldest command waiting for its reply.
+func (conn *smtpConnectionData) pending() *request {
+	if len(conn.requests) == 0 {
+		return nil
+	}
+	return conn.requests[0]
+}
+
+func (smtp *Smtp) handleReply(
+	conn *smtpConnectionData,
+	reply *textproto.Reply,
+	ts time.Time,
+) {
+	req := conn.pending()
+	if req == nil {
+		if !conn.greeted {
+			debugf("SMTP greeting: %d %s", reply.Code, reply.Text())
+			conn.greeted = true
+			return
+		}
+		debugf("Response from unknown transaction. Ignoring")
+		unmatchedResponses.Add(1)
+		return
+	}
+	conn.greeted = true
+
+	if (req.verb == "DATA" && reply.Code == 354) || (req.verb == "AUTH" && reply.Code == 334) {
+		// the client sends more data before the final reply
+		req.replySize += reply.Size
+		if client := conn.streams[req.dir]; client != nil {
+			if req.verb == "DATA" {
+				client.mode = modeData
+			} else {
+				client.mode = modeAuth
+			}
+		}
+		return
+	}
+
+	conn.requests = conn.requests[1:]
+	event := smtp.newTransaction(conn, req, reply, ts)
+	if smtp.results != nil {
+		smtp.results.PublishTransaction(event)
+	}
+}
+
+func (smtp *Smtp) newTransaction(
+	conn *smtpConnectionData,
+	req *request,
+	reply *textproto.Reply,
+	ts time.Time,
+) common.MapStr {
+	status := common.OK_STATUS
+	if reply.Failed() {
+		status = common.ERROR_STATUS
+	}
+
+	fields := common.MapStr{
+		"code":    reply.Code,
+		"message": reply.Text(),
+	}
+
+	switch req.verb {
+	case "HELO", "EHLO", "RSET":
+		conn.resetEnvelope()
+		if req.verb == "EHLO" && !reply.Failed() && len(reply.Lines) > 1 {
+			fields["extensions"] = extensions(reply.Lines[1:])
+		}
+	case "MAIL":
+		from := mailbox(req.arg, "FROM:")
+		fields["mail_from"] = from
+		if !reply.Failed() {
+			conn.resetEnvelope()
+			conn.mailFrom = from
+		}
+	case "RCPT":
+		to := mailbox(req.arg, "TO:")
+		fields["rcpt_to"] = []string{to}
+		if !reply.Failed() {
+			conn.rcptTo = append(conn.rcptTo, to)
+		}
+	case "DATA", "BDAT":
+		fields["data_size"] = req.dataSize
+		if req.verb == "DATA" || isLastChunk(req.arg) {
+			fields["mail_from"] = conn.mailFrom
+			fields["rcpt_to"] = conn.rcptTo
+			conn.resetEnvelope()
+		}
+	case "STARTTLS":
+		if reply.Code == 220 {
+			// TLS handshake follows, stop analyzing the connection
+			fields["starttls"] = true
+			conn.encrypted = true
+			conn.streams = [2]*stream{}
+			conn.requests = nil
+		}
+	}
+
+	src := &common.Endpoint{
+		Ip:   req.tuple.Src_ip.String(),
+		Port: req.tuple.Src_port,
+		Proc: string(req.cmdline.Src),
+	}
+	dst := &common.Endpoint{
+		Ip:   req.tuple.Dst_ip.String(),
+		Port: req.tuple.Dst_port,
+		Proc: string(req.cmdline.Dst),
+	}
+	if req.dir == tcp.TcpDirectionReverse {
+		src, dst = dst, src
+	}
+
+	// resp_time in milliseconds
+	responseTime := int32(ts.Sub(req.ts).Nanoseconds() / 1e6)
+
+	event := common.MapStr{
+		"@timestamp":   common.Time(req.ts),
+		"type":         "smtp",
+		"status":       status,
+		"responsetime": responseTime,
+		"smtp":         fields,
+		"method":       req.verb,
+		"query":        req.query,
+		"bytes_in":     uint64(req.size),
+		"bytes_out":    uint64(req.replySize + reply.Size),
+		"src":          src,
+		"dst":          dst,
+	}
+	if smtp.SendRequest {
+		event["request"] = req.query
+	}
+	if smtp.SendResponse {
+		event["response"] = reply.Text()
+	}
+
+	return event
+}
+
+func (conn *smtpConnectionData) resetEnvelope() {
+	conn.mailFrom = ""
+	conn.rcptTo = nil
+}
+
+// mailbox returns the address of the reverse or forward path argument of
+// MAIL and RCPT, like "FROM:<alice@example.com> SIZE=1024".
+func mailbox(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, "<") {
+		if end := strings.IndexByte(arg, '>'); end > 0 {
+			return arg[1:end]
+		}
+	}
+	if fields := strings.Fields(arg); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// extensions returns the keywords of the extensions announced in the reply
+// to EHLO.
+func extensions(lines []string) []string {
+	var keywords []string
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			keywords = append(keywords, strings.ToUpper(fields[0]))
+		}
+	}
+	return keywords
+}
+
+// chunkSize returns the size of the chunk sent with BDAT, like "BDAT 1024 LAST".
+func chunkSize(arg string) (int, error) {
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		return 0, errInvalidCommand
+	}
+	size, err := strconv.Atoi(fields[0])
+	if err != nil || size < 0 {
+		return 0, errInvalidCommand
+	}
+	return size, nil
+}
+
+func isLastChunk(arg string) bool {
+	fields := strings.Fields(arg)
+	return len(fields) > 1 && strings.EqualFold(fields[1], "LAST")
+}
+
+func validVerb(verb string) bool {
+	if len(verb) == 0 || len(verb) > 16 {
+		return false
+	}
+	for _, c := range verb {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (smtp *Smtp) GapInStream(tcptuple *common.TcpTuple, dir uint8,
+	nbytes int, private protos.ProtocolData) (priv protos.ProtocolData, drop bool) {
+
+	// bytes lost in the message content are accounted for, as the content
+	// is not analyzed
+	conn, ok := private.(*smtpConnectionData)
+	if !ok || conn == nil {
+		return private, true
+	}
+	st := conn.streams[dir]
+	if st == nil {
+		return private, true
+	}
+	switch st.mode {
+	case modeData:
+		if req := conn.pending(); req != nil {
+			req.size += nbytes
+			req.dataSize += nbytes
+		}
+	case modeChunk:
+		if nbytes > st.chunkLeft {
+			return private, true
+		}
+		st.chunk.size += nbytes
+		st.chunk.dataSize += nbytes
+		st.chunkLeft -= nbytes
+		if st.chunkLeft == 0 {
+			st.mode = modeCommand
+			st.chunk = nil
+		}
+	default:
+		return private, true
+	}
+	return private, false
+}
+
+func (smtp *Smtp) ReceivedFin(tcptuple *common.TcpTuple, dir uint8,
+	private protos.ProtocolData) protos.ProtocolData {
+	return private
+}
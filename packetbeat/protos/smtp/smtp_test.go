@@ -0,0 +1,185 @@
+// +build !integration
+
+package smtp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/protos"
+	"github.com/elastic/beats/packetbeat/protos/tcp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+func newTestSmtp() *Smtp {
+	smtp := &Smtp{}
+	results := &publish.ChanTransactions{Channel: make(chan common.MapStr, 20)}
+	config := defaultConfig
+	smtp.init(results, &config)
+	return smtp
+}
+
+func testTcpTuple() *common.TcpTuple {
+	t := &common.TcpTuple{
+		Ip_length: 4,
+		Src_ip:    net.IPv4(192, 168, 0, 1), Dst_ip: net.IPv4(192, 168, 0, 2),
+		Src_port: 51234, Dst_port: 25,
+	}
+	t.ComputeHashebles()
+	return t
+}
+
+// session replays the segments alternating between the client and the
+// server, starting with the server.
+func session(smtp *Smtp, segments ...string) protos.ProtocolData {
+	tuple := testTcpTuple()
+	var private protos.ProtocolData
+	ts := time.Now()
+	for i, segment := range segments {
+		dir := uint8(tcp.TcpDirectionReverse)
+		if i%2 == 1 {
+			dir = tcp.TcpDirectionOriginal
+		}
+		ts = ts.Add(time.Millisecond)
+		pkt := &protos.Packet{Ts: ts, Payload: []byte(segment)}
+		private = smtp.Parse(pkt, tuple, dir, private)
+	}
+	return private
+}
+
+func events(smtp *Smtp) []common.MapStr {
+	client := smtp.results.(*publish.ChanTransactions)
+	var events []common.MapStr
+	for {
+		select {
+		case event := <-client.Channel:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestSmtpSession(t *testing.T) {
+	smtp := newTestSmtp()
+	session(smtp,
+		"220 mx.example.com ESMTP ready\r\n",
+		"EHLO client.example.org\r\n",
+		"250-mx.example.com\r\n250-SIZE 10240000\r\n250-8BITMIME\r\n250 STARTTLS\r\n",
+		"MAIL FROM:<alice@example.org> SIZE=120\r\nRCPT TO:<bob@example.com>\r\nRCPT TO:<eve@example.com>\r\nDATA\r\n",
+		"250 2.1.0 Ok\r\n250 2.1.5 Ok\r\n550 5.1.1 No such user\r\n354 End data with <CR><LF>.<CR><LF>\r\n",
+		"Subject: hello\r\n\r\nHi Bob\r\n..dot\r\n.\r\n",
+		"250 2.0.0 Ok: queued as 12345\r\n",
+		"QUIT\r\n",
+		"221 2.0.0 Bye\r\n",
+	)
+
+	trans := events(smtp)
+	if !assert.Len(t, trans, 6) {
+		return
+	}
+
+	ehlo := trans[0]
+	assert.Equal(t, "smtp", ehlo["type"])
+	assert.Equal(t, "EHLO", ehlo["method"])
+	assert.Equal(t, "EHLO client.example.org", ehlo["query"])
+	assert.Equal(t, common.OK_STATUS, ehlo["status"])
+	assert.Equal(t, []string{"SIZE", "8BITMIME", "STARTTLS"}, ehlo["smtp"].(common.MapStr)["extensions"])
+	assert.Equal(t, "192.168.0.1", ehlo["src"].(*common.Endpoint).Ip)
+	assert.Equal(t, uint16(25), ehlo["dst"].(*common.Endpoint).Port)
+
+	mail := trans[1]["smtp"].(common.MapStr)
+	assert.Equal(t, "alice@example.org", mail["mail_from"])
+	assert.Equal(t, 250, mail["code"])
+
+	rejected := trans[3]
+	assert.Equal(t, common.ERROR_STATUS, rejected["status"])
+	assert.Equal(t, []string{"eve@example.com"}, rejected["smtp"].(common.MapStr)["rcpt_to"])
+
+	data := trans[4]
+	fields := data["smtp"].(common.MapStr)
+	assert.Equal(t, "DATA", data["method"])
+	assert.Equal(t, common.OK_STATUS, data["status"])
+	assert.Equal(t, "alice@example.org", fields["mail_from"])
+	assert.Equal(t, []string{"bob@example.com"}, fields["rcpt_to"])
+	assert.Equal(t, 33, fields["data_size"])
+	assert.Equal(t, "2.0.0 Ok: queued as 12345", fields["message"])
+	assert.Equal(t, uint64(len("DATA\r\n")+36), data["bytes_in"])
+
+	assert.Equal(t, "QUIT", trans[5]["method"])
+}
+
+func TestSmtpStartTLS(t *testing.T) {
+	smtp := newTestSmtp()
+	private := session(smtp,
+		"220 mx.example.com ESMTP\r\n",
+		"STARTTLS\r\n",
+		"220 2.0.0 Ready to start TLS\r\n",
+		"\x16\x03\x01\x00\xa5\x01\x00\x00\xa1",
+	)
+
+	trans := events(smtp)
+	if assert.Len(t, trans, 1) {
+		assert.Equal(t, true, trans[0]["smtp"].(common.MapStr)["starttls"])
+	}
+	assert.True(t, private.(*smtpConnectionData).encrypted)
+	assert.Equal(t, int64(0), parseErrors.Value())
+}
+
+func TestSmtpAuthIsNotReported(t *testing.T) {
+	smtp := newTestSmtp()
+	session(smtp,
+		"220 mx.example.com ESMTP\r\n",
+		"AUTH LOGIN\r\n",
+		"334 VXNlcm5hbWU6\r\n",
+		"YWxpY2U=\r\n",
+		"334 UGFzc3dvcmQ6\r\n",
+		"c2VjcmV0\r\n",
+		"235 2.7.0 Authentication successful\r\n",
+		"AUTH PLAIN AGFsaWNlAHNlY3JldA==\r\n",
+		"235 2.7.0 Authentication successful\r\n",
+	)
+
+	trans := events(smtp)
+	if assert.Len(t, trans, 2) {
+		assert.Equal(t, "AUTH LOGIN", trans[0]["query"])
+		assert.Equal(t, 235, trans[0]["smtp"].(common.MapStr)["code"])
+		assert.Equal(t, "AUTH PLAIN", trans[1]["query"])
+	}
+}
+
+func TestSmtpChunking(t *testing.T) {
+	smtp := newTestSmtp()
+	session(smtp,
+		"220 mx.example.com ESMTP\r\n",
+		"MAIL FROM:<alice@example.org>\r\nRCPT TO:<bob@example.com>\r\nBDAT 10\r\n0123",
+		"250 Ok\r\n250 Ok\r\n",
+		"456789BDAT 4 LAST\r\nabcd",
+		"250 Ok\r\n250 Ok: queued\r\n",
+	)
+
+	trans := events(smtp)
+	if assert.Len(t, trans, 4) {
+		first := trans[2]["smtp"].(common.MapStr)
+		assert.Equal(t, 10, first["data_size"])
+		assert.Nil(t, first["mail_from"])
+
+		last := trans[3]["smtp"].(common.MapStr)
+		assert.Equal(t, 4, last["data_size"])
+		assert.Equal(t, "alice@example.org", last["mail_from"])
+	}
+}
+
+func TestSmtpDetect(t *testing.T) {
+	smtp := newTestSmtp()
+
+	assert.True(t, smtp.Detect([]byte("220 mx.example.com ESMTP Postfix\r\n"), 0))
+	assert.True(t, smtp.Detect([]byte("EHLO client.example.org\r\n"), 1))
+	assert.False(t, smtp.Detect([]byte("220 ftp.example.com FTP server ready\r\n"), 0))
+	assert.False(t, smtp.Detect([]byte("220 mx.example.com ESMTP"), 0))
+	assert.False(t, smtp.Detect([]byte("GET / HTTP/1.1\r\n"), 1))
+}
@@ -0,0 +1,308 @@
+// Package sqlnorm normalizes SQL queries into a fingerprint of the statement,
+// such that queries differing only in their literal values share the same
+// normalized query and hash.
+package sqlnorm
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Dialect selects the lexical rules of the SQL dialect being normalized.
+type Dialect int
+
+const (
+	// MySQL treats double quoted tokens as string literals and `#` as
+	// start of a comment.
+	MySQL Dialect = iota
+
+	// PostgreSQL treats double quoted tokens as identifiers and supports
+	// positional parameters ($1) and dollar quoted strings.
+	PostgreSQL
+)
+
+const placeholder = "?"
+
+// Normalize returns the normalized form of the query. Comments are removed,
+// literals and parameters are replaced by `?`, keywords and identifiers are
+// converted to lowercase, whitespace is collapsed, and lists of values in
+// `IN (...)` and `VALUES (...), (...)` are collapsed into a single entry.
+func Normalize(query string, dialect Dialect) string {
+	tokens := collapseLists(tokenize(query, dialect))
+	return render(tokens)
+}
+
+// Hash returns the hexadecimal FNV-1a 64 bit hash of the normalized query.
+func Hash(normalized string) string {
+	h := fnv.New64a()
+	h.Write([]byte(normalized))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func tokenize(query string, dialect Dialect) []string {
+	var tokens []string
+	s := query
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case isSpace(c):
+			s = s[1:]
+
+		case strings.HasPrefix(s, "--"), c == '#' && dialect == MySQL:
+			s = skipLine(s)
+
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s[2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			s = s[end+4:]
+
+		case c == '\'':
+			tokens = append(tokens, placeholder)
+			s = skipQuoted(s, '\'', dialect == MySQL)
+
+		case c == '"' && dialect == MySQL:
+			tokens = append(tokens, placeholder)
+			s = skipQuoted(s, '"', true)
+
+		case c == '"' || c == '`':
+			end := quotedEnd(s, c, false)
+			tokens = append(tokens, s[:end])
+			s = s[end:]
+
+		case c == '$' && dialect == PostgreSQL:
+			if n := digitsLen(s[1:]); n > 0 {
+				tokens = append(tokens, placeholder)
+				s = s[1+n:]
+			} else if tag, ok := dollarTag(s); ok {
+				tokens = append(tokens, placeholder)
+				end := strings.Index(s[len(tag):], tag)
+				if end < 0 {
+					return tokens
+				}
+				s = s[len(tag)+end+len(tag):]
+			} else {
+				tokens = append(tokens, "$")
+				s = s[1:]
+			}
+
+		case isDigit(c), c == '.' && len(s) > 1 && isDigit(s[1]):
+			tokens = append(tokens, placeholder)
+			s = s[numberLen(s):]
+
+		case isIdentStart(c):
+			n := identLen(s)
+			// Prefixed string literals like X'0f', N'text' or E'\n'.
+			if n == 1 && len(s) > 1 && s[1] == '\'' && strings.IndexByte("xXbBnNeE", c) >= 0 {
+				tokens = append(tokens, placeholder)
+				s = skipQuoted(s[1:], '\'', dialect == MySQL || c == 'e' || c == 'E')
+				continue
+			}
+			tokens = append(tokens, strings.ToLower(s[:n]))
+			s = s[n:]
+
+		case isOperator(c):
+			n := 1
+			for n < len(s) && isOperator(s[n]) && !strings.HasPrefix(s[n:], "--") &&
+				!strings.HasPrefix(s[n:], "/*") {
+				n++
+			}
+			tokens = append(tokens, s[:n])
+			s = s[n:]
+
+		default:
+			tokens = append(tokens, s[:1])
+			s = s[1:]
+		}
+	}
+	return tokens
+}
+
+// collapseLists replaces lists consisting only of placeholders following
+// the `in` keyword with `(?)`, and repeated rows of values following the
+// `values` keyword with the first row.
+func collapseLists(tokens []string) []string {
+	out := tokens[:0]
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		switch tokens[i] {
+		case "in":
+			if end, ok := placeholderList(tokens, i+1); ok {
+				out = append(out, "(", placeholder, ")")
+				i = end - 1
+			}
+		case "values":
+			j := i + 1
+			end, ok := placeholderList(tokens, j)
+			if !ok {
+				continue
+			}
+			out = append(out, tokens[j:end]...)
+			for end < len(tokens) && tokens[end] == "," {
+				next, ok := placeholderList(tokens, end+1)
+				if !ok {
+					break
+				}
+				end = next
+			}
+			i = end - 1
+		}
+	}
+	return out
+}
+
+// placeholderList checks if tokens[start:] begins with a parenthesized list
+// of placeholders, returning the index following the closing parenthesis.
+func placeholderList(tokens []string, start int) (int, bool) {
+	if start >= len(tokens) || tokens[start] != "(" {
+		return 0, false
+	}
+	expectValue := true
+	for i := start + 1; i < len(tokens); i++ {
+		switch {
+		case expectValue && tokens[i] == placeholder:
+			expectValue = false
+		case !expectValue && tokens[i] == ",":
+			expectValue = true
+		case !expectValue && tokens[i] == ")":
+			return i + 1, true
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func render(tokens []string) string {
+	var b bytes.Buffer
+	for i, tok := range tokens {
+		if i > 0 {
+			if spaceBetween(tokens[i-1], tok) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(tok)
+	}
+	return strings.TrimRight(b.String(), " ;")
+}
+
+// spaceBetween reports if the rendered tokens are separated by a space.
+// Qualified names and casts (`a.b`, `a::text`) are rendered without spaces.
+func spaceBetween(prev, tok string) bool {
+	switch {
+	case prev == "(" || prev == "." || prev == "::":
+		return false
+	case tok == "," || tok == ")" || tok == "." || tok == "::" || tok == ";":
+		return false
+	}
+	return true
+}
+
+func skipLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return ""
+}
+
+func skipQuoted(s string, quote byte, backslashEscapes bool) string {
+	return s[quotedEnd(s, quote, backslashEscapes):]
+}
+
+// quotedEnd returns the index following the closing quote of the quoted
+// token at the start of s. Doubled quotes are part of the token.
+func quotedEnd(s string, quote byte, backslashEscapes bool) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// dollarTag returns the opening tag of a dollar quoted string, like `$$` or
+// `$body$`.
+func dollarTag(s string) (string, bool) {
+	n := 1
+	for n < len(s) && (isIdentStart(s[n]) || isDigit(s[n])) {
+		n++
+	}
+	if n < len(s) && s[n] == '$' && (n == 1 || !isDigit(s[1])) {
+		return s[:n+1], true
+	}
+	return "", false
+}
+
+func numberLen(s string) int {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		n := 2
+		for n < len(s) && isHexDigit(s[n]) {
+			n++
+		}
+		return n
+	}
+
+	n := digitsLen(s)
+	if n < len(s) && s[n] == '.' {
+		n++
+		n += digitsLen(s[n:])
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		m := n + 1
+		if m < len(s) && (s[m] == '+' || s[m] == '-') {
+			m++
+		}
+		if d := digitsLen(s[m:]); d > 0 {
+			n = m + d
+		}
+	}
+	return n
+}
+
+func digitsLen(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func identLen(s string) int {
+	n := 0
+	for n < len(s) && (isIdentStart(s[n]) || isDigit(s[n]) || s[n] == '$') {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isOperator(c byte) bool {
+	return strings.IndexByte("<>=!|&:+-*/%^~@", c) >= 0
+}
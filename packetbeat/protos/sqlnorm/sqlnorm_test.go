@@ -0,0 +1,66 @@
+// +build !integration
+
+package sqlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		dialect    Dialect
+		query      string
+		normalized string
+	}{
+		{
+			MySQL,
+			"SELECT * FROM users WHERE id = 42",
+			"select * from users where id = ?",
+		},
+		{
+			MySQL,
+			"select name,  email\n\tfrom users where name='o''brien' and email = \"a\\\"b\";",
+			"select name, email from users where name = ? and email = ?",
+		},
+		{
+			MySQL,
+			"SELECT id FROM t WHERE id IN (1, 2, 3) AND x IN (SELECT y FROM z) # trailing",
+			"select id from t where id in (?) and x in (select y from z)",
+		},
+		{
+			MySQL,
+			"INSERT INTO `Logs` (a, b) VALUES (1, 'x'), (2, 'y'),(3,'z')",
+			"insert into `Logs` (a, b) values (?, ?)",
+		},
+		{
+			MySQL,
+			"/* app:web */ UPDATE t SET v = 1.5e3, h = 0xFF, b = X'0a' -- done",
+			"update t set v = ?, h = ?, b = ?",
+		},
+		{
+			PostgreSQL,
+			`SELECT "UserName" FROM accounts WHERE id = $1 AND note = E'it\'s'`,
+			`select "UserName" from accounts where id = ? and note = ?`,
+		},
+		{
+			PostgreSQL,
+			"SELECT $body$ it's $1 $body$::text, count(*) FROM t WHERE ts > '2016-01-01'::date",
+			"select ?::text, count (*) from t where ts > ?::date",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.normalized, Normalize(test.query, test.dialect), test.query)
+	}
+}
+
+func TestHashEqualForDifferentLiterals(t *testing.T) {
+	a := Normalize("SELECT * FROM t WHERE id IN (1, 2)", MySQL)
+	b := Normalize("select *\nfrom t where id in (7,8,9)", MySQL)
+	c := Normalize("SELECT * FROM u WHERE id IN (1, 2)", MySQL)
+
+	assert.Equal(t, Hash(a), Hash(b))
+	assert.NotEqual(t, Hash(a), Hash(c))
+}
@@ -3,6 +3,7 @@ package tcp
 import (
 	"expvar"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/elastic/beats/libbeat/common"
@@ -16,6 +17,10 @@ import (
 
 const TCP_MAX_DATA_IN_STREAM = 10 * (1 << 20)
 
+// maximum number of bytes sampled from a stream on a port not configured for
+// any protocol, before the stream is ignored
+const maxDetectSample = 4096
+
 const (
 	TcpDirectionReverse  = 0
 	TcpDirectionOriginal = 1
@@ -26,6 +31,13 @@ type Tcp struct {
 	streams   *common.Cache
 	portMap   map[uint16]protos.Protocol
 	protocols protos.Protocols
+	detectors []detector
+}
+
+// detector is a protocol with detection enabled.
+type detector struct {
+	protocol protos.Protocol
+	plugin   protos.TcpDetector
 }
 
 type Processor interface {
@@ -34,6 +46,7 @@ type Processor interface {
 
 var (
 	droppedBecauseOfGaps = expvar.NewInt("tcp.dropped_because_of_gaps")
+	detectedStreams      = expvar.NewInt("tcp.detected_streams")
 )
 
 type seqCompare int
@@ -87,6 +100,18 @@ type TcpConnection struct {
 
 	// protocols private data
 	data protos.ProtocolData
+
+	// The payload of streams on ports not configured for any protocol is
+	// sampled until a protocol is detected.
+	detecting bool
+	samples   []tcpSample
+	sampled   [2][]byte
+}
+
+// tcpSample is a segment received while detecting the protocol of a stream.
+type tcpSample struct {
+	pkt protos.Packet
+	dir uint8
 }
 
 type TcpStream struct {
@@ -101,6 +126,15 @@ func (conn *TcpConnection) String() string {
 
 func (stream *TcpStream) addPacket(pkt *protos.Packet, tcphdr *layers.TCP) {
 	conn := stream.conn
+	if conn.detecting && len(pkt.Payload) > 0 {
+		stream.detect(pkt)
+		if conn.detecting {
+			return
+		}
+		// the sampled payload, including the packet, was parsed
+		pkt = &protos.Packet{Ts: pkt.Ts, Tuple: pkt.Tuple}
+	}
+
 	mod := conn.tcp.protocols.GetTcp(conn.protocol)
 	if mod == nil {
 		if isDebug {
@@ -120,9 +154,62 @@ func (stream *TcpStream) addPacket(pkt *protos.Packet, tcphdr *layers.TCP) {
 	}
 }
 
+// detect adds the payload of the packet to the sample of the stream, and
+// asks the protocols with detection enabled if the sample looks like their
+// protocol. The first protocol claiming the stream parses the sampled
+// payload. If no protocol claims the stream before maxDetectSample bytes are
+// sampled, the stream is ignored.
+func (stream *TcpStream) detect(pkt *protos.Packet) {
+	conn := stream.conn
+	conn.samples = append(conn.samples, tcpSample{pkt: *pkt, dir: stream.dir})
+	conn.sampled[stream.dir] = append(conn.sampled[stream.dir], pkt.Payload...)
+
+	for _, d := range conn.tcp.detectors {
+		if !d.plugin.Detect(conn.sampled[stream.dir], stream.dir) {
+			continue
+		}
+
+		if isDebug {
+			debugf("Detected protocol %s in stream %s", d.protocol, conn.tuple)
+		}
+		detectedStreams.Add(1)
+
+		samples := conn.samples
+		conn.stopDetecting()
+		conn.protocol = d.protocol
+		if timeout := d.plugin.ConnectionTimeout(); timeout > 0 {
+			conn.tcp.streams.PutWithTimeout(conn.tuple.Hashable(), conn, timeout)
+		}
+
+		for i := range samples {
+			sample := &samples[i]
+			conn.data = d.plugin.Parse(&sample.pkt, &conn.tcptuple, sample.dir, conn.data)
+		}
+		return
+	}
+
+	if len(conn.sampled[0])+len(conn.sampled[1]) >= maxDetectSample {
+		if isDebug {
+			debugf("No protocol detected in stream %s, ignoring it", conn.tuple)
+		}
+		conn.stopDetecting()
+	}
+}
+
+func (conn *TcpConnection) stopDetecting() {
+	conn.detecting = false
+	conn.samples = nil
+	conn.sampled = [2][]byte{}
+}
+
 func (stream *TcpStream) gapInStream(nbytes int) (drop bool) {
 	conn := stream.conn
 	mod := conn.tcp.protocols.GetTcp(conn.protocol)
+	if mod == nil {
+		// the protocol of the stream is not detected yet
+		conn.stopDetecting()
+		return false
+	}
 	conn.data, drop = mod.GapInStream(&conn.tcptuple, stream.dir, nbytes, conn.data)
 	return drop
 }
@@ -218,7 +305,7 @@ func (tcp *Tcp) getStream(pkt *protos.Packet) (stream TcpStream, created bool) {
 	}
 
 	protocol := tcp.decideProtocol(&pkt.Tuple)
-	if protocol == protos.UnknownProtocol {
+	if protocol == protos.UnknownProtocol && len(tcp.detectors) == 0 {
 		// don't follow
 		return TcpStream{}, false
 	}
@@ -237,10 +324,12 @@ func (tcp *Tcp) getStream(pkt *protos.Packet) (stream TcpStream, created bool) {
 	}
 
 	conn := &TcpConnection{
-		id:       tcp.getId(),
-		tuple:    &pkt.Tuple,
-		protocol: protocol,
-		tcp:      tcp}
+		id:        tcp.getId(),
+		tuple:     &pkt.Tuple,
+		protocol:  protocol,
+		tcp:       tcp,
+		detecting: protocol == protos.UnknownProtocol,
+	}
 	conn.tcptuple = common.TcpTupleFromIpPort(conn.tuple, conn.id)
 	tcp.streams.PutWithTimeout(pkt.Tuple.Hashable(), conn, timeout)
 	return TcpStream{conn: conn, dir: TcpDirectionOriginal}, true
@@ -286,6 +375,23 @@ func buildPortsMap(plugins map[protos.Protocol]protos.TcpPlugin) (map[uint16]pro
 	return res, nil
 }
 
+// sortedDetectors returns the detectors ordered by protocol, such that the
+// protocol detected in a stream doesn't depend on the map order.
+func sortedDetectors(plugins map[protos.Protocol]protos.TcpDetector) []detector {
+	var ids []int
+	for proto := range plugins {
+		ids = append(ids, int(proto))
+	}
+	sort.Ints(ids)
+
+	var detectors []detector
+	for _, id := range ids {
+		proto := protos.Protocol(id)
+		detectors = append(detectors, detector{protocol: proto, plugin: plugins[proto]})
+	}
+	return detectors
+}
+
 // Creates and returns a new Tcp.
 func NewTcp(p protos.Protocols) (*Tcp, error) {
 	isDebug = logp.IsDebug("tcp")
@@ -298,6 +404,7 @@ func NewTcp(p protos.Protocols) (*Tcp, error) {
 	tcp := &Tcp{
 		protocols: p,
 		portMap:   portMap,
+		detectors: sortedDetectors(p.GetTcpDetectors()),
 		streams: common.NewCache(
 			protos.DefaultTransactionExpiration,
 			protos.DefaultTransactionHashSize),
@@ -3,8 +3,10 @@
 package tcp
 
 import (
+	"bytes"
 	"math/rand"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -171,19 +173,21 @@ func Test_configToPortsMap_negative(t *testing.T) {
 
 // Mock protos.Protocols used for testing the tcp package.
 type protocols struct {
-	tcp map[protos.Protocol]protos.TcpPlugin
+	tcp       map[protos.Protocol]protos.TcpPlugin
+	detectors map[protos.Protocol]protos.TcpDetector
 }
 
 // Verify protocols implements the protos.Protocols interface.
 var _ protos.Protocols = &protocols{}
 
-func (p protocols) BpfFilter(with_vlans bool, with_icmp bool) string     { return "" }
-func (p protocols) GetTcp(proto protos.Protocol) protos.TcpPlugin        { return p.tcp[proto] }
-func (p protocols) GetUdp(proto protos.Protocol) protos.UdpPlugin        { return nil }
-func (p protocols) GetAll() map[protos.Protocol]protos.Plugin            { return nil }
-func (p protocols) GetAllTcp() map[protos.Protocol]protos.TcpPlugin      { return p.tcp }
-func (p protocols) GetAllUdp() map[protos.Protocol]protos.UdpPlugin      { return nil }
-func (p protocols) Register(proto protos.Protocol, plugin protos.Plugin) { return }
+func (p protocols) BpfFilter(with_vlans bool, with_icmp bool) string        { return "" }
+func (p protocols) GetTcp(proto protos.Protocol) protos.TcpPlugin           { return p.tcp[proto] }
+func (p protocols) GetUdp(proto protos.Protocol) protos.UdpPlugin           { return nil }
+func (p protocols) GetAll() map[protos.Protocol]protos.Plugin               { return nil }
+func (p protocols) GetAllTcp() map[protos.Protocol]protos.TcpPlugin         { return p.tcp }
+func (p protocols) GetAllUdp() map[protos.Protocol]protos.UdpPlugin         { return nil }
+func (p protocols) GetTcpDetectors() map[protos.Protocol]protos.TcpDetector { return p.detectors }
+func (p protocols) Register(proto protos.Protocol, plugin protos.Plugin)    { return }
 
 func TestTCSeqPayload(t *testing.T) {
 	type segment struct {
@@ -309,6 +313,81 @@ func TestTCSeqPayload(t *testing.T) {
 	}
 }
 
+// DetectingProtocol is a TestProtocol with detection enabled.
+type DetectingProtocol struct {
+	TestProtocol
+	detect func(sample []byte, dir uint8) bool
+}
+
+func (proto DetectingProtocol) Detect(sample []byte, dir uint8) bool {
+	return proto.detect(sample, dir)
+}
+
+func TestDetectProtocol(t *testing.T) {
+	var state []byte
+	detectWith := func(prefix string) *DetectingProtocol {
+		return &DetectingProtocol{
+			TestProtocol: TestProtocol{
+				parse: makeCollectPayload(&state, true),
+				gap:   makeCountGaps(nil, new(int)),
+			},
+			detect: func(sample []byte, dir uint8) bool {
+				return bytes.HasPrefix(sample, []byte(prefix))
+			},
+		}
+	}
+	mysql := detectWith("MYSQL")
+	redis := detectWith("REDIS")
+	tcp, err := NewTcp(protocols{
+		tcp: map[protos.Protocol]protos.TcpPlugin{
+			mysqlProtocol: mysql,
+			redisProtocol: redis,
+		},
+		detectors: map[protos.Protocol]protos.TcpDetector{
+			mysqlProtocol: mysql,
+			redisProtocol: redis,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tuple := func(port uint16) common.IpPortTuple {
+		return common.NewIpPortTuple(4,
+			net.ParseIP(ServerIp), ServerPort,
+			net.ParseIP(ClientIp), port)
+	}
+	process := func(port uint16, seq uint32, payload string) {
+		tcp.Process(nil, &layers.TCP{Seq: seq}, &protos.Packet{
+			Ts:      time.Now(),
+			Tuple:   tuple(port),
+			Payload: []byte(payload),
+		})
+	}
+
+	// the sampled segments are parsed once the protocol is detected
+	process(1000, 1, "RE")
+	assert.Empty(t, state)
+	process(1000, 3, "DIS GET")
+	process(1000, 10, " key")
+	assert.Equal(t, "REDIS GET key", string(state))
+	addr := tuple(1000)
+	conn := tcp.findStream(addr.Hashable())
+	assert.Equal(t, redisProtocol, conn.protocol)
+	assert.False(t, conn.detecting)
+
+	// streams without protocol detected are ignored
+	state = nil
+	process(2000, 1, strings.Repeat("x", maxDetectSample))
+	process(2000, 1+maxDetectSample, "MYSQL")
+	assert.Empty(t, state)
+
+	// without detectors, streams on unknown ports aren't followed
+	tcp, _ = NewTcp(protocols{tcp: map[protos.Protocol]protos.TcpPlugin{mysqlProtocol: mysql}})
+	process(3000, 1, "MYSQL")
+	assert.Empty(t, state)
+}
+
 // Benchmark that runs with parallelism to help find concurrency related
 // issues. To run with parallelism, the 'go test' cpu flag must be set
 // greater than 1, otherwise it just runs concurrently but not in parallel.
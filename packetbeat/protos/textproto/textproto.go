@@ -0,0 +1,147 @@
+// Package textproto parses the line based protocols, like SMTP and FTP, in
+// which the client sends commands terminated by CRLF and the server answers
+// with replies made of a three digit code followed by a text.
+package textproto
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+// MaxLineLength is the maximum length of a command or reply line. Streams
+// with longer lines are not considered to be line based.
+const MaxLineLength = 4096
+
+var (
+	ErrLineTooLong  = errors.New("line too long")
+	ErrInvalidReply = errors.New("invalid reply")
+)
+
+// Reply is a, possibly multi-line, reply sent by the server.
+type Reply struct {
+	Code  int
+	Lines []string
+	Size  int
+}
+
+// Preliminary reports if the reply announces that the command was accepted,
+// but another reply will follow.
+func (r *Reply) Preliminary() bool {
+	return r.Code < 200
+}
+
+// Intermediate reports if the server waits for more data from the client
+// before sending the final reply of the command.
+func (r *Reply) Intermediate() bool {
+	return r.Code >= 300 && r.Code < 400
+}
+
+// Failed reports if the reply is a transient or permanent failure.
+func (r *Reply) Failed() bool {
+	return r.Code >= 400
+}
+
+// Text returns the text of all reply lines, separated by newlines.
+func (r *Reply) Text() string {
+	return strings.Join(r.Lines, "\n")
+}
+
+// ReadLine returns the first line in data without the line terminator, and
+// the number of bytes consumed. Lines are terminated by CRLF, a single LF is
+// accepted too. If data contains no complete line, n is 0.
+func ReadLine(data []byte) (line []byte, n int) {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil, 0
+	}
+	line = data[:i]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line, i + 1
+}
+
+// ParseCommand splits a command line into the uppercase command verb and
+// its argument.
+func ParseCommand(line []byte) (verb, arg string) {
+	s := string(line)
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		verb, arg = s[:i], strings.TrimSpace(s[i+1:])
+	} else {
+		verb = s
+	}
+	return strings.ToUpper(verb), arg
+}
+
+// IsReply reports if data starts with a reply line: a three digit code
+// followed by a space, a hyphen or the end of the line.
+func IsReply(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	for _, c := range data[:3] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	if data[0] < '1' || data[0] > '5' {
+		return false
+	}
+	switch data[3] {
+	case ' ', '-', '\r', '\n':
+		return true
+	}
+	return false
+}
+
+// ParseReply parses the reply at the start of data, returning the reply and
+// the number of bytes consumed. If the reply is incomplete, n is 0.
+//
+// A multi-line reply starts with the code followed by a hyphen, and ends
+// with a line starting with the same code followed by a space. Lines in
+// between need not start with the code.
+func ParseReply(data []byte) (reply *Reply, n int, err error) {
+	var code []byte
+	for {
+		line, size := ReadLine(data[n:])
+		if size == 0 {
+			if len(data)-n > MaxLineLength {
+				return nil, 0, ErrLineTooLong
+			}
+			return nil, 0, nil
+		}
+		n += size
+
+		if code == nil {
+			if !IsReply(line) && !(len(line) == 3 && IsReply(data[:4])) {
+				return nil, 0, ErrInvalidReply
+			}
+			code = line[:3]
+			reply = &Reply{Code: atoi(code)}
+		}
+
+		continued := true
+		if len(line) >= 3 && bytes.Equal(line[:3], code) && (len(line) == 3 || line[3] == ' ' || line[3] == '-') {
+			continued = len(line) > 3 && line[3] == '-'
+			line = line[3:]
+			if len(line) > 0 {
+				line = line[1:]
+			}
+		}
+
+		reply.Lines = append(reply.Lines, string(line))
+		if !continued {
+			reply.Size = n
+			return reply, n, nil
+		}
+	}
+}
+
+func atoi(digits []byte) int {
+	n := 0
+	for _, c := range digits {
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
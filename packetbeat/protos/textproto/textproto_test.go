@@ -0,0 +1,65 @@
+// +build !integration
+
+package textproto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		data  string
+		code  int
+		lines []string
+		size  int
+	}{
+		{"220 mail.example.com ESMTP\r\n", 220, []string{"mail.example.com ESMTP"}, 28},
+		{"250\r\nQUIT\r\n", 250, []string{""}, 5},
+		{"250-mail.example.com\r\n250-SIZE 1000\r\n250 STARTTLS\r\n", 250,
+			[]string{"mail.example.com", "SIZE 1000", "STARTTLS"}, 51},
+		{"211-Features:\r\n MDTM\r\n UTF8\r\n211 End\r\n", 211,
+			[]string{"Features:", " MDTM", " UTF8", "End"}, 38},
+		{"230 Login successful.\n", 230, []string{"Login successful."}, 22},
+	}
+
+	for _, test := range tests {
+		reply, n, err := ParseReply([]byte(test.data))
+		if assert.NoError(t, err, test.data) && assert.NotNil(t, reply, test.data) {
+			assert.Equal(t, test.code, reply.Code)
+			assert.Equal(t, test.lines, reply.Lines)
+			assert.Equal(t, test.size, n)
+			assert.Equal(t, n, reply.Size)
+		}
+	}
+}
+
+func TestParseReplyIncomplete(t *testing.T) {
+	for _, data := range []string{"", "220 ready", "250-first\r\n250-second\r\n"} {
+		reply, n, err := ParseReply([]byte(data))
+		assert.NoError(t, err, data)
+		assert.Nil(t, reply, data)
+		assert.Equal(t, 0, n, data)
+	}
+}
+
+func TestParseReplyInvalid(t *testing.T) {
+	for _, data := range []string{"EHLO client\r\n", "999 bad\r\n", "25 short\r\n"} {
+		_, _, err := ParseReply([]byte(data))
+		assert.Equal(t, ErrInvalidReply, err, data)
+	}
+
+	_, _, err := ParseReply(make([]byte, MaxLineLength+1))
+	assert.Equal(t, ErrLineTooLong, err)
+}
+
+func TestParseCommand(t *testing.T) {
+	verb, arg := ParseCommand([]byte("mail FROM:<alice@example.com>"))
+	assert.Equal(t, "MAIL", verb)
+	assert.Equal(t, "FROM:<alice@example.com>", arg)
+
+	verb, arg = ParseCommand([]byte("QUIT"))
+	assert.Equal(t, "QUIT", verb)
+	assert.Equal(t, "", arg)
+}
@@ -161,6 +161,7 @@ type Thrift struct {
 var (
 	unmatchedRequests  = expvar.NewInt("thrift.unmatched_requests")
 	unmatchedResponses = expvar.NewInt("thrift.unmatched_responses")
+	parseErrors        = expvar.NewInt("thrift.parse_errors")
 )
 
 func init() {
@@ -947,6 +948,7 @@ func (thrift *Thrift) Parse(pkt *protos.Packet, tcptuple *common.TcpTuple, dir u
 		if !ok {
 			// drop this tcp stream. Will retry parsing with the next
 			// segment in it
+			parseErrors.Add(1)
 			priv.Data[dir] = nil
 			logp.Debug("thrift", "Ignore Thrift message. Drop tcp stream. Try parsing with the next segment")
 			return priv
@@ -60,6 +60,10 @@ func (p TestProtocols) GetAllUdp() map[protos.Protocol]protos.UdpPlugin {
 	return p.udp
 }
 
+func (p TestProtocols) GetTcpDetectors() map[protos.Protocol]protos.TcpDetector {
+	return nil
+}
+
 func (p TestProtocols) Register(proto protos.Protocol, plugin protos.Plugin) {
 	return
 }
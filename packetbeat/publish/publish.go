@@ -18,6 +18,10 @@ type Flows interface {
 	PublishFlows([]common.MapStr) bool
 }
 
+type Events interface {
+	PublishEvent(common.MapStr) bool
+}
+
 type PacketbeatPublisher struct {
 	pub    *publisher.Publisher
 	client publisher.Client
@@ -25,8 +29,9 @@ type PacketbeatPublisher struct {
 	wg   sync.WaitGroup
 	done chan struct{}
 
-	trans chan common.MapStr
-	flows chan []common.MapStr
+	trans  chan common.MapStr
+	flows  chan []common.MapStr
+	events chan common.MapStr
 }
 
 type ChanTransactions struct {
@@ -40,13 +45,20 @@ func (t *ChanTransactions) PublishTransaction(event common.MapStr) bool {
 
 var debugf = logp.MakeDebug("publish")
 
-func NewPublisher(pub *publisher.Publisher, hwm, bulkHWM int) *PacketbeatPublisher {
+// NewPublisher creates a publisher publishing the events with client, which
+// must be connected to pub.
+func NewPublisher(
+	pub *publisher.Publisher,
+	client publisher.Client,
+	hwm, bulkHWM int,
+) *PacketbeatPublisher {
 	return &PacketbeatPublisher{
 		pub:    pub,
-		client: pub.Connect(),
+		client: client,
 		done:   make(chan struct{}),
 		trans:  make(chan common.MapStr, hwm),
 		flows:  make(chan []common.MapStr, bulkHWM),
+		events: make(chan common.MapStr, hwm),
 	}
 }
 
@@ -70,6 +82,16 @@ func (t *PacketbeatPublisher) PublishFlows(event []common.MapStr) bool {
 	}
 }
 
+func (t *PacketbeatPublisher) PublishEvent(event common.MapStr) bool {
+	select {
+	case t.events <- event:
+		return true
+	case <-t.done:
+		// drop event, if worker has been stopped
+		return false
+	}
+}
+
 func (t *PacketbeatPublisher) Start() {
 	t.wg.Add(1)
 	go func() {
@@ -96,6 +118,19 @@ func (t *PacketbeatPublisher) Start() {
 			}
 		}
 	}()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for {
+			select {
+			case <-t.done:
+				return
+			case event := <-t.events:
+				t.onEvent(event)
+			}
+		}
+	}()
 }
 
 func (t *PacketbeatPublisher) Stop() {
@@ -114,6 +149,7 @@ func (t *PacketbeatPublisher) onTransaction(event common.MapStr) {
 		return
 	}
 
+	setMetadata(event)
 	t.client.PublishEvent(event)
 }
 
@@ -129,12 +165,43 @@ func (t *PacketbeatPublisher) onFlow(events []common.MapStr) {
 			continue
 		}
 
+		setMetadata(event)
 		pub = append(pub, event)
 	}
 
 	t.client.PublishEvents(pub)
 }
 
+func (t *PacketbeatPublisher) onEvent(event common.MapStr) {
+	if err := validateEvent(event); err != nil {
+		logp.Warn("Dropping invalid event: %v", err)
+		return
+	}
+
+	setMetadata(event)
+	t.client.PublishEvent(event)
+}
+
+// setMetadata adds the protocol of the event as input and the client address
+// as source host to the event metadata.
+func setMetadata(event common.MapStr) {
+	common.SetMetadata(event, common.MetadataInputKey, event["type"])
+
+	var host string
+	if ip, ok := event["client_ip"].(string); ok {
+		host = ip
+	} else if source, ok := event["source"].(common.MapStr); ok {
+		if ip, ok := source["ip"].(string); ok {
+			host = ip
+		} else if ip, ok := source["ipv6"].(string); ok {
+			host = ip
+		}
+	}
+	if host != "" {
+		common.SetMetadata(event, common.MetadataHostKey, host)
+	}
+}
+
 // filterEvent validates an event for common required fields with types.
 // If event is to be filtered out the reason is returned as error.
 func validateEvent(event common.MapStr) error {
@@ -207,17 +274,17 @@ func normalizeTransAddr(pub *publisher.Publisher, event common.MapStr) bool {
 
 	}
 
-	if pub.GeoLite != nil {
+	if geoLite := pub.GeoLite(); geoLite != nil {
 		realIP, exists := event["real_ip"]
 		if exists && len(realIP.(common.NetString)) > 0 {
-			loc := pub.GeoLite.GetLocationByIP(string(realIP.(common.NetString)))
+			loc := geoLite.GetLocationByIP(string(realIP.(common.NetString)))
 			if loc != nil && loc.Latitude != 0 && loc.Longitude != 0 {
 				loc := fmt.Sprintf("%f, %f", loc.Latitude, loc.Longitude)
 				event["client_location"] = loc
 			}
 		} else {
 			if len(srcServer) == 0 && src != nil { // only for external IP addresses
-				loc := pub.GeoLite.GetLocationByIP(src.Ip)
+				loc := geoLite.GetLocationByIP(src.Ip)
 				if loc != nil && loc.Latitude != 0 && loc.Longitude != 0 {
 					loc := fmt.Sprintf("%f, %f", loc.Latitude, loc.Longitude)
 					event["client_location"] = loc
@@ -230,6 +297,10 @@ func normalizeTransAddr(pub *publisher.Publisher, event common.MapStr) bool {
 }
 
 func addGeoIPToFlow(pub *publisher.Publisher, event common.MapStr) bool {
+	geoLite := pub.GeoLite()
+	if geoLite == nil {
+		return true
+	}
 
 	getLocation := func(host common.MapStr, ip_type string) string {
 
@@ -243,7 +314,7 @@ func addGeoIPToFlow(pub *publisher.Publisher, event common.MapStr) bool {
 			logp.Warn("IP address must be string")
 			return ""
 		}
-		loc := pub.GeoLite.GetLocationByIP(str)
+		loc := geoLite.GetLocationByIP(str)
 		if loc == nil || loc.Latitude == 0 || loc.Longitude == 0 {
 			return ""
 		}
@@ -251,10 +322,6 @@ func addGeoIPToFlow(pub *publisher.Publisher, event common.MapStr) bool {
 		return fmt.Sprintf("%f, %f", loc.Latitude, loc.Longitude)
 	}
 
-	if pub.GeoLite == nil {
-		return true
-	}
-
 	ipFieldNames := [][]string{
 		{"ip", "ip_location"},
 		{"outter_ip", "outter_ip_location"},
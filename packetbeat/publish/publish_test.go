@@ -142,3 +142,16 @@ func TestNoDirection(t *testing.T) {
 	_, ok := event["direction"]
 	assert.False(t, ok)
 }
+
+func TestSetMetadata(t *testing.T) {
+	event := common.MapStr{"type": "http", "client_ip": "192.145.2.4"}
+	setMetadata(event)
+	assert.Equal(t, common.MapStr{"input": "http", "host": "192.145.2.4"}, event[common.MetadataKey])
+
+	event = common.MapStr{
+		"type":   "flow",
+		"source": common.MapStr{"ipv6": "2001:db8::1"},
+	}
+	setMetadata(event)
+	assert.Equal(t, common.MapStr{"input": "flow", "host": "2001:db8::1"}, event[common.MetadataKey])
+}
@@ -0,0 +1,380 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+var errShortRead = errors.New("datagram is truncated")
+
+// Sample and record formats of the standard enterprise (0).
+const (
+	formatFlowSample            = 1
+	formatCounterSample         = 2
+	formatExpandedFlowSample    = 3
+	formatExpandedCounterSample = 4
+
+	formatRawPacketHeader = 1
+	formatEthernetFrame   = 2
+	formatIPv4            = 3
+	formatIPv6            = 4
+	formatExtendedSwitch  = 1001
+
+	formatGenericInterfaceCounters = 1
+)
+
+// Protocols of the raw packet header.
+const (
+	headerProtocolEthernet = 1
+	headerProtocolIPv4     = 11
+	headerProtocolIPv6     = 12
+)
+
+// xdrReader reads the big-endian XDR encoded fields of a datagram. After the
+// first read past the end of the buffer all reads return zero values and err
+// is set.
+type xdrReader struct {
+	buf []byte
+	err error
+}
+
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errShortRead
+		r.buf = nil
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *xdrReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// opaque reads n bytes padded to a multiple of 4 bytes.
+func (r *xdrReader) opaque(n int) []byte {
+	b := r.next(n)
+	r.next((4 - n%4) % 4)
+	return b
+}
+
+func (r *xdrReader) ip(n int) net.IP {
+	b := r.next(n)
+	if b == nil {
+		return nil
+	}
+	ip := make(net.IP, n)
+	copy(ip, b)
+	return ip
+}
+
+// mac reads a MAC address padded to 8 bytes.
+func (r *xdrReader) mac() net.HardwareAddr {
+	b := r.next(8)
+	if b == nil {
+		return nil
+	}
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, b)
+	return mac
+}
+
+// address reads an address preceded by its type (1 = IPv4, 2 = IPv6).
+func (r *xdrReader) address() net.IP {
+	switch typ := r.uint32(); typ {
+	case 1:
+		return r.ip(net.IPv4len)
+	case 2:
+		return r.ip(net.IPv6len)
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown address type %d", typ)
+		}
+		return nil
+	}
+}
+
+// record returns the format of the next record and a reader for its data.
+func (r *xdrReader) record() (uint32, *xdrReader) {
+	format := r.uint32()
+	length := r.uint32()
+	return format, &xdrReader{buf: r.opaque(int(length))}
+}
+
+// datagram is a decoded sFlow v5 datagram.
+type datagram struct {
+	Agent          net.IP
+	SubAgentID     uint32
+	SequenceNumber uint32
+	Uptime         uint32 // milliseconds
+	FlowSamples    []flowSample
+	CounterSamples []counterSample
+}
+
+// dataSource identifies the interface or entity a sample was taken from.
+type dataSource struct {
+	Type  uint32
+	Index uint32
+}
+
+type flowSample struct {
+	SequenceNumber uint32
+	Source         dataSource
+	SamplingRate   uint32
+	SamplePool     uint32
+	Drops          uint32
+	Input          uint32 // ifIndex
+	Output         uint32 // ifIndex
+	Header         *rawPacketHeader
+	Ethernet       *ethernetFrame
+	IP             *ipData
+	Switch         *extendedSwitch
+}
+
+type rawPacketHeader struct {
+	Protocol    uint32
+	FrameLength uint32
+	Stripped    uint32
+	Header      []byte
+}
+
+type ethernetFrame struct {
+	Length uint32
+	Src    net.HardwareAddr
+	Dst    net.HardwareAddr
+	Type   uint32
+}
+
+type ipData struct {
+	Length   uint32
+	Protocol uint32
+	Src      net.IP
+	Dst      net.IP
+	SrcPort  uint32
+	DstPort  uint32
+	TCPFlags uint32
+	TOS      uint32
+}
+
+type extendedSwitch struct {
+	SrcVlan     uint32
+	SrcPriority uint32
+	DstVlan     uint32
+	DstPriority uint32
+}
+
+type counterSample struct {
+	SequenceNumber uint32
+	Source         dataSource
+	Interface      *interfaceCounters
+}
+
+type interfaceCounters struct {
+	Index            uint32
+	Type             uint32
+	Speed            uint64
+	Direction        uint32
+	Status           uint32
+	InOctets         uint64
+	InUcastPkts      uint32
+	InMulticastPkts  uint32
+	InBroadcastPkts  uint32
+	InDiscards       uint32
+	InErrors         uint32
+	InUnknownProtos  uint32
+	OutOctets        uint64
+	OutUcastPkts     uint32
+	OutMulticastPkts uint32
+	OutBroadcastPkts uint32
+	OutDiscards      uint32
+	OutErrors        uint32
+	Promiscuous      uint32
+}
+
+// decodeDatagram decodes an sFlow v5 datagram. Samples and records of
+// unknown formats are skipped.
+func decodeDatagram(b []byte) (*datagram, error) {
+	r := &xdrReader{buf: b}
+
+	if version := r.uint32(); r.err == nil && version != 5 {
+		return nil, fmt.Errorf("unsupported sFlow version %d", version)
+	}
+
+	d := &datagram{
+		Agent:          r.address(),
+		SubAgentID:     r.uint32(),
+		SequenceNumber: r.uint32(),
+		Uptime:         r.uint32(),
+	}
+
+	numSamples := r.uint32()
+	for i := uint32(0); i < numSamples && r.err == nil; i++ {
+		format, sr := r.record()
+		switch format {
+		case formatFlowSample, formatExpandedFlowSample:
+			s := decodeFlowSample(sr, format == formatExpandedFlowSample)
+			if sr.err != nil {
+				return nil, fmt.Errorf("invalid flow sample: %v", sr.err)
+			}
+			d.FlowSamples = append(d.FlowSamples, s)
+		case formatCounterSample, formatExpandedCounterSample:
+			s := decodeCounterSample(sr, format == formatExpandedCounterSample)
+			if sr.err != nil {
+				return nil, fmt.Errorf("invalid counter sample: %v", sr.err)
+			}
+			d.CounterSamples = append(d.CounterSamples, s)
+		default:
+			debugf("Skipping sample of unknown format %d", format)
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return d, nil
+}
+
+func decodeDataSource(r *xdrReader, expanded bool) dataSource {
+	if expanded {
+		return dataSource{Type: r.uint32(), Index: r.uint32()}
+	}
+	id := r.uint32()
+	return dataSource{Type: id >> 24, Index: id & 0x00ffffff}
+}
+
+// decodeInterface decodes the input or output interface of a flow sample.
+// Only the ifIndex of single interfaces is returned.
+func decodeInterface(r *xdrReader, expanded bool) uint32 {
+	if expanded {
+		format, value := r.uint32(), r.uint32()
+		if format != 0 {
+			return 0
+		}
+		return value
+	}
+	v := r.uint32()
+	if v>>30 != 0 {
+		return 0
+	}
+	return v & 0x3fffffff
+}
+
+func decodeFlowSample(r *xdrReader, expanded bool) flowSample {
+	s := flowSample{
+		SequenceNumber: r.uint32(),
+		Source:         decodeDataSource(r, expanded),
+		SamplingRate:   r.uint32(),
+		SamplePool:     r.uint32(),
+		Drops:          r.uint32(),
+		Input:          decodeInterface(r, expanded),
+		Output:         decodeInterface(r, expanded),
+	}
+
+	numRecords := r.uint32()
+	for i := uint32(0); i < numRecords && r.err == nil; i++ {
+		format, rr := r.record()
+		switch format {
+		case formatRawPacketHeader:
+			s.Header = &rawPacketHeader{
+				Protocol:    rr.uint32(),
+				FrameLength: rr.uint32(),
+				Stripped:    rr.uint32(),
+			}
+			s.Header.Header = rr.opaque(int(rr.uint32()))
+		case formatEthernetFrame:
+			s.Ethernet = &ethernetFrame{
+				Length: rr.uint32(),
+				Src:    rr.mac(),
+				Dst:    rr.mac(),
+				Type:   rr.uint32(),
+			}
+		case formatIPv4, formatIPv6:
+			n := net.IPv4len
+			if format == formatIPv6 {
+				n = net.IPv6len
+			}
+			s.IP = &ipData{
+				Length:   rr.uint32(),
+				Protocol: rr.uint32(),
+				Src:      rr.ip(n),
+				Dst:      rr.ip(n),
+				SrcPort:  rr.uint32(),
+				DstPort:  rr.uint32(),
+				TCPFlags: rr.uint32(),
+				TOS:      rr.uint32(),
+			}
+		case formatExtendedSwitch:
+			s.Switch = &extendedSwitch{
+				SrcVlan:     rr.uint32(),
+				SrcPriority: rr.uint32(),
+				DstVlan:     rr.uint32(),
+				DstPriority: rr.uint32(),
+			}
+		default:
+			continue
+		}
+
+		if rr.err != nil {
+			r.err = fmt.Errorf("invalid flow record %d: %v", format, rr.err)
+		}
+	}
+	return s
+}
+
+func decodeCounterSample(r *xdrReader, expanded bool) counterSample {
+	s := counterSample{
+		SequenceNumber: r.uint32(),
+		Source:         decodeDataSource(r, expanded),
+	}
+
+	numRecords := r.uint32()
+	for i := uint32(0); i < numRecords && r.err == nil; i++ {
+		format, rr := r.record()
+		if format != formatGenericInterfaceCounters {
+			continue
+		}
+
+		s.Interface = &interfaceCounters{
+			Index:            rr.uint32(),
+			Type:             rr.uint32(),
+			Speed:            rr.uint64(),
+			Direction:        rr.uint32(),
+			Status:           rr.uint32(),
+			InOctets:         rr.uint64(),
+			InUcastPkts:      rr.uint32(),
+			InMulticastPkts:  rr.uint32(),
+			InBroadcastPkts:  rr.uint32(),
+			InDiscards:       rr.uint32(),
+			InErrors:         rr.uint32(),
+			InUnknownProtos:  rr.uint32(),
+			OutOctets:        rr.uint64(),
+			OutUcastPkts:     rr.uint32(),
+			OutMulticastPkts: rr.uint32(),
+			OutBroadcastPkts: rr.uint32(),
+			OutDiscards:      rr.uint32(),
+			OutErrors:        rr.uint32(),
+			Promiscuous:      rr.uint32(),
+		}
+		if rr.err != nil {
+			r.err = fmt.Errorf("invalid counter record %d: %v", format, rr.err)
+		}
+	}
+	return s
+}
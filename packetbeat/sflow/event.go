@@ -0,0 +1,247 @@
+package sflow
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/tsg/gopacket"
+	"github.com/tsg/gopacket/layers"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/config"
+)
+
+// interfaceNames maps the ifIndex of the interfaces of the agents to names.
+// Names configured without an agent apply to all agents.
+type interfaceNames map[string]map[uint32]string
+
+func newInterfaceNames(cfgs []config.SflowInterface) (interfaceNames, error) {
+	names := interfaceNames{}
+	for _, cfg := range cfgs {
+		if cfg.Index == 0 || cfg.Name == "" {
+			return nil, fmt.Errorf("sflow interface requires an 'index' and a 'name'")
+		}
+
+		agent := ""
+		if cfg.Agent != "" {
+			ip := net.ParseIP(cfg.Agent)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid sflow agent address '%s'", cfg.Agent)
+			}
+			agent = ip.String()
+		}
+
+		if names[agent] == nil {
+			names[agent] = map[uint32]string{}
+		}
+		names[agent][cfg.Index] = cfg.Name
+	}
+	return names, nil
+}
+
+// lookup returns the name of the interface of the agent or an empty string.
+func (n interfaceNames) lookup(agent net.IP, index uint32) string {
+	if name, found := n[agent.String()][index]; found {
+		return name
+	}
+	return n[""][index]
+}
+
+func (n interfaceNames) iface(agent net.IP, index uint32) common.MapStr {
+	m := common.MapStr{"index": index}
+	if name := n.lookup(agent, index); name != "" {
+		m["name"] = name
+	}
+	return m
+}
+
+// events returns an event per sample of the datagram.
+func (d *datagram) events(ts time.Time, names interfaceNames) []common.MapStr {
+	var events []common.MapStr
+	for i := range d.FlowSamples {
+		events = append(events, d.flowEvent(ts, names, &d.FlowSamples[i]))
+	}
+	for i := range d.CounterSamples {
+		events = append(events, d.counterEvent(ts, names, &d.CounterSamples[i]))
+	}
+	return events
+}
+
+func (d *datagram) common(sampleType string, seq uint32, source dataSource) common.MapStr {
+	return common.MapStr{
+		"sample_type":     sampleType,
+		"agent":           d.Agent.String(),
+		"sub_agent_id":    d.SubAgentID,
+		"sequence_number": seq,
+		"uptime":          d.Uptime,
+		"source_id": common.MapStr{
+			"type":  source.Type,
+			"index": source.Index,
+		},
+	}
+}
+
+func (d *datagram) flowEvent(ts time.Time, names interfaceNames, s *flowSample) common.MapStr {
+	sflow := d.common("flow", s.SequenceNumber, s.Source)
+	sflow["sampling_rate"] = s.SamplingRate
+	sflow["sample_pool"] = s.SamplePool
+	sflow["drops"] = s.Drops
+	if s.Input != 0 {
+		sflow["input"] = names.iface(d.Agent, s.Input)
+	}
+	if s.Output != 0 {
+		sflow["output"] = names.iface(d.Agent, s.Output)
+	}
+	if s.Switch != nil {
+		sflow["vlan"] = common.MapStr{
+			"source": s.Switch.SrcVlan,
+			"dest":   s.Switch.DstVlan,
+		}
+	}
+
+	event := common.MapStr{
+		"@timestamp": common.Time(ts),
+		"type":       "sflow",
+		"sflow":      sflow,
+	}
+	source, dest := common.MapStr{}, common.MapStr{}
+
+	if s.Ethernet != nil {
+		sflow["frame_length"] = s.Ethernet.Length
+		source["mac"] = s.Ethernet.Src.String()
+		dest["mac"] = s.Ethernet.Dst.String()
+	}
+	if s.IP != nil {
+		setAddr(source, s.IP.Src, s.IP.SrcPort)
+		setAddr(dest, s.IP.Dst, s.IP.DstPort)
+		if transport := transportName(s.IP.Protocol); transport != "" {
+			event["transport"] = transport
+		}
+	}
+	if s.Header != nil {
+		sflow["frame_length"] = s.Header.FrameLength
+		decodeHeader(s.Header, event, source, dest)
+	}
+
+	if len(source) > 0 {
+		event["source"] = source
+	}
+	if len(dest) > 0 {
+		event["dest"] = dest
+	}
+	return event
+}
+
+func (d *datagram) counterEvent(ts time.Time, names interfaceNames, s *counterSample) common.MapStr {
+	sflow := d.common("counter", s.SequenceNumber, s.Source)
+
+	if c := s.Interface; c != nil {
+		iface := names.iface(d.Agent, c.Index)
+		iface["type"] = c.Type
+		iface["speed"] = c.Speed
+		iface["direction"] = c.Direction
+		iface["status"] = common.MapStr{
+			"admin_up": c.Status&1 != 0,
+			"oper_up":  c.Status&2 != 0,
+		}
+		iface["promiscuous"] = c.Promiscuous == 1
+		iface["in"] = common.MapStr{
+			"bytes":             c.InOctets,
+			"unicast_packets":   c.InUcastPkts,
+			"multicast_packets": c.InMulticastPkts,
+			"broadcast_packets": c.InBroadcastPkts,
+			"discards":          c.InDiscards,
+			"errors":            c.InErrors,
+			"unknown_protocols": c.InUnknownProtos,
+		}
+		iface["out"] = common.MapStr{
+			"bytes":             c.OutOctets,
+			"unicast_packets":   c.OutUcastPkts,
+			"multicast_packets": c.OutMulticastPkts,
+			"broadcast_packets": c.OutBroadcastPkts,
+			"discards":          c.OutDiscards,
+			"errors":            c.OutErrors,
+		}
+		sflow["interface"] = iface
+	}
+
+	return common.MapStr{
+		"@timestamp": common.Time(ts),
+		"type":       "sflow",
+		"sflow":      sflow,
+	}
+}
+
+// setAddr sets the address and port in the format of the source and dest
+// fields of flow events.
+func setAddr(m common.MapStr, ip net.IP, port uint32) {
+	if ip.To4() != nil {
+		m["ip"] = ip.String()
+	} else if ip != nil {
+		m["ipv6"] = ip.String()
+	}
+	if port != 0 {
+		m["port"] = port
+	}
+}
+
+func transportName(protocol uint32) string {
+	switch protocol {
+	case 1, 58:
+		return "icmp"
+	case 6:
+		return "tcp"
+	case 17:
+		return "udp"
+	}
+	return ""
+}
+
+// decodeHeader decodes the sampled packet header. The header is usually
+// truncated, so only the layers up to the transport layer are decoded.
+func decodeHeader(h *rawPacketHeader, event, source, dest common.MapStr) {
+	var first gopacket.Decoder
+	switch h.Protocol {
+	case headerProtocolEthernet:
+		first = layers.LayerTypeEthernet
+	case headerProtocolIPv4:
+		first = layers.LayerTypeIPv4
+	case headerProtocolIPv6:
+		first = layers.LayerTypeIPv6
+	default:
+		debugf("Unsupported header protocol %d", h.Protocol)
+		return
+	}
+
+	packet := gopacket.NewPacket(h.Header, first, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		source["mac"] = eth.SrcMAC.String()
+		dest["mac"] = eth.DstMAC.String()
+	}
+
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		setAddr(source, ip.SrcIP, 0)
+		setAddr(dest, ip.DstIP, 0)
+		if transport := transportName(uint32(ip.Protocol)); transport != "" {
+			event["transport"] = transport
+		}
+	case *layers.IPv6:
+		setAddr(source, ip.SrcIP, 0)
+		setAddr(dest, ip.DstIP, 0)
+		if transport := transportName(uint32(ip.NextHeader)); transport != "" {
+			event["transport"] = transport
+		}
+	}
+
+	switch t := packet.TransportLayer().(type) {
+	case *layers.TCP:
+		source["port"] = uint32(t.SrcPort)
+		dest["port"] = uint32(t.DstPort)
+	case *layers.UDP:
+		source["port"] = uint32(t.SrcPort)
+		dest["port"] = uint32(t.DstPort)
+	}
+}
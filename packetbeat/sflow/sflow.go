@@ -0,0 +1,118 @@
+/*
+Package sflow implements an sFlow v5 collector. It receives the datagrams
+sent by network devices (agents) over UDP and publishes an event per flow
+sample and counter sample. The interfaces referenced by the samples are
+identified by their ifIndex, which can be mapped to names with a table
+configured per agent.
+*/
+package sflow
+
+import (
+	"expvar"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+const (
+	defaultHost = ":6343"
+
+	// maxDatagramSize is the maximum size of a UDP datagram.
+	maxDatagramSize = 65535
+)
+
+var (
+	datagramsReceived = expvar.NewInt("sflow.datagrams_received")
+	samplesReceived   = expvar.NewInt("sflow.samples_received")
+	decodeErrors      = expvar.NewInt("sflow.decode_errors")
+)
+
+var debugf = logp.MakeDebug("sflow")
+
+// Collector receives sFlow datagrams and publishes the samples.
+type Collector struct {
+	pub   publish.Events
+	names interfaceNames
+	conn  *net.UDPConn
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a collector listening on the configured host.
+func New(pub publish.Events, cfg *config.Sflow) (*Collector, error) {
+	names, err := newInterfaceNames(cfg.Interfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	host := cfg.Host
+	if host == "" {
+		host = defaultHost
+	}
+	addr, err := net.ResolveUDPAddr("udp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	logp.Info("sFlow collector listening on %v", conn.LocalAddr())
+
+	return &Collector{
+		pub:   pub,
+		names: names,
+		conn:  conn,
+		done:  make(chan struct{}),
+	}, nil
+}
+
+// Start starts receiving datagrams.
+func (c *Collector) Start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// Stop stops receiving datagrams and closes the socket.
+func (c *Collector) Stop() {
+	close(c.done)
+	c.conn.Close()
+	c.wg.Wait()
+}
+
+func (c *Collector) run() {
+	defer c.wg.Done()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, from, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+			logp.Err("sflow: failed to receive datagram: %v", err)
+			continue
+		}
+		datagramsReceived.Add(1)
+
+		d, err := decodeDatagram(buf[:n])
+		if err != nil {
+			decodeErrors.Add(1)
+			debugf("Failed to decode datagram from %v: %v", from, err)
+			continue
+		}
+
+		for _, event := range d.events(time.Now(), c.names) {
+			samplesReceived.Add(1)
+			c.pub.PublishEvent(event)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// +build !integration
+
+package sflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/packetbeat/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// xdrWriter encodes test datagrams.
+type xdrWriter struct{ bytes.Buffer }
+
+func (w *xdrWriter) u32(v ...uint32) *xdrWriter {
+	for _, x := range v {
+		binary.Write(w, binary.BigEndian, x)
+	}
+	return w
+}
+
+func (w *xdrWriter) u64(v uint64) *xdrWriter {
+	binary.Write(w, binary.BigEndian, v)
+	return w
+}
+
+func (w *xdrWriter) raw(b []byte) *xdrWriter {
+	w.Write(b)
+	w.Write(make([]byte, (4-len(b)%4)%4))
+	return w
+}
+
+// record writes the format and length followed by the data of the record.
+func (w *xdrWriter) record(format uint32, data *xdrWriter) *xdrWriter {
+	w.u32(format, uint32(data.Len()))
+	w.Write(data.Bytes())
+	return w
+}
+
+func datagramHeader(numSamples uint32) *xdrWriter {
+	w := &xdrWriter{}
+	w.u32(5, 1).raw(net.ParseIP("10.0.0.1").To4())
+	w.u32(0, 42, 123456, numSamples)
+	return w
+}
+
+// tcpHeader returns an Ethernet, IPv4 and TCP header from
+// 192.168.1.10:34567 to 192.168.1.20:443.
+func tcpHeader() []byte {
+	eth := []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, // dst
+		0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, // src
+		0x08, 0x00,
+	}
+	ip := []byte{
+		0x45, 0x00, 0x00, 0x28, 0x00, 0x00, 0x40, 0x00, 0x40, 0x06, 0x00, 0x00,
+		192, 168, 1, 10,
+		192, 168, 1, 20,
+	}
+	tcp := []byte{
+		0x87, 0x07, 0x01, 0xbb, // ports
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		0x50, 0x02, 0x72, 0x10, 0x00, 0x00, 0x00, 0x00,
+	}
+	return append(append(eth, ip...), tcp...)
+}
+
+func flowSampleDatagram() []byte {
+	header := tcpHeader()
+	headerRecord := (&xdrWriter{}).u32(headerProtocolEthernet, 1514, 4, uint32(len(header))).raw(header)
+	switchRecord := (&xdrWriter{}).u32(10, 0, 20, 0)
+
+	sample := &xdrWriter{}
+	sample.u32(7, 3, 512, 1024, 0, 3, 5, 2)
+	sample.record(formatRawPacketHeader, headerRecord)
+	sample.record(formatExtendedSwitch, switchRecord)
+
+	return datagramHeader(1).record(formatFlowSample, sample).Bytes()
+}
+
+func TestDecodeFlowSample(t *testing.T) {
+	d, err := decodeDatagram(flowSampleDatagram())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "10.0.0.1", d.Agent.String())
+	assert.Equal(t, uint32(42), d.SequenceNumber)
+	assert.Len(t, d.CounterSamples, 0)
+	if !assert.Len(t, d.FlowSamples, 1) {
+		return
+	}
+
+	s := d.FlowSamples[0]
+	assert.Equal(t, dataSource{Type: 0, Index: 3}, s.Source)
+	assert.Equal(t, uint32(512), s.SamplingRate)
+	assert.Equal(t, uint32(3), s.Input)
+	assert.Equal(t, uint32(5), s.Output)
+	assert.Equal(t, uint32(1514), s.Header.FrameLength)
+	assert.Equal(t, &extendedSwitch{SrcVlan: 10, DstVlan: 20}, s.Switch)
+
+	names := interfaceNames{
+		"10.0.0.1": {3: "ge-0/0/3"},
+		"":         {5: "uplink"},
+	}
+	ts := time.Now()
+	event := d.events(ts, names)[0]
+
+	assert.Equal(t, common.Time(ts), event["@timestamp"])
+	assert.Equal(t, "sflow", event["type"])
+	assert.Equal(t, "tcp", event["transport"])
+	assert.Equal(t, common.MapStr{
+		"mac":  "66:77:88:99:aa:bb",
+		"ip":   "192.168.1.10",
+		"port": uint32(34567),
+	}, event["source"])
+	assert.Equal(t, common.MapStr{
+		"mac":  "00:11:22:33:44:55",
+		"ip":   "192.168.1.20",
+		"port": uint32(443),
+	}, event["dest"])
+
+	sflow := event["sflow"].(common.MapStr)
+	assert.Equal(t, "flow", sflow["sample_type"])
+	assert.Equal(t, "10.0.0.1", sflow["agent"])
+	assert.Equal(t, uint32(7), sflow["sequence_number"])
+	assert.Equal(t, uint32(1514), sflow["frame_length"])
+	assert.Equal(t, common.MapStr{"index": uint32(3), "name": "ge-0/0/3"}, sflow["input"])
+	assert.Equal(t, common.MapStr{"index": uint32(5), "name": "uplink"}, sflow["output"])
+	assert.Equal(t, common.MapStr{"source": uint32(10), "dest": uint32(20)}, sflow["vlan"])
+}
+
+func TestDecodeCounterSample(t *testing.T) {
+	counters := &xdrWriter{}
+	counters.u32(3, 6).u64(1000000000).u32(1, 3)
+	counters.u64(1234).u32(10, 2, 1, 0, 0, 0)
+	counters.u64(5678).u32(20, 3, 2, 0, 1, 0)
+
+	sample := &xdrWriter{}
+	sample.u32(9, 3, 2)
+	sample.record(2, (&xdrWriter{}).u32(1, 2, 3)) // ethernet counters, skipped
+	sample.record(formatGenericInterfaceCounters, counters)
+
+	d, err := decodeDatagram(datagramHeader(1).record(formatCounterSample, sample).Bytes())
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, d.CounterSamples, 1) {
+		return
+	}
+
+	event := d.events(time.Now(), interfaceNames{"": {3: "eth0"}})[0]
+	iface := event["sflow"].(common.MapStr)["interface"].(common.MapStr)
+	assert.Equal(t, "eth0", iface["name"])
+	assert.Equal(t, uint64(1000000000), iface["speed"])
+	assert.Equal(t, common.MapStr{"admin_up": true, "oper_up": true}, iface["status"])
+	assert.Equal(t, false, iface["promiscuous"])
+	assert.Equal(t, uint64(1234), iface["in"].(common.MapStr)["bytes"])
+	assert.Equal(t, uint32(1), iface["out"].(common.MapStr)["errors"])
+}
+
+func TestDecodeSkipsUnknownSamples(t *testing.T) {
+	b := datagramHeader(1).record(1<<12|1, (&xdrWriter{}).u32(1, 2)).Bytes()
+
+	d, err := decodeDatagram(b)
+	if assert.NoError(t, err) {
+		assert.Len(t, d.FlowSamples, 0)
+		assert.Len(t, d.CounterSamples, 0)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := decodeDatagram((&xdrWriter{}).u32(4).Bytes())
+	assert.Error(t, err)
+
+	// truncated at every position
+	b := flowSampleDatagram()
+	for i := 0; i < len(b); i++ {
+		_, err := decodeDatagram(b[:i])
+		assert.Error(t, err, "length %d", i)
+	}
+}
+
+func TestInterfaceNamesConfig(t *testing.T) {
+	cfg, err := common.NewConfigWithYAML([]byte(`
+interfaces:
+  - {index: 1, name: eth0}
+  - {agent: 10.0.0.1, index: 1, name: ge-0/0/1}
+  - {agent: 10.0.0.1, index: 2, name: ge-0/0/2}
+`), "test")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var c config.Sflow
+	if !assert.NoError(t, cfg.Unpack(&c)) {
+		return
+	}
+
+	names, err := newInterfaceNames(c.Interfaces)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ge-0/0/1", names.lookup(net.ParseIP("10.0.0.1"), 1))
+	assert.Equal(t, "ge-0/0/2", names.lookup(net.ParseIP("10.0.0.1"), 2))
+	assert.Equal(t, "eth0", names.lookup(net.ParseIP("10.0.0.2"), 1))
+	assert.Equal(t, "", names.lookup(net.ParseIP("10.0.0.2"), 2))
+
+	_, err = newInterfaceNames([]config.SflowInterface{{Name: "eth0"}})
+	assert.Error(t, err)
+
+	_, err = newInterfaceNames([]config.SflowInterface{{Agent: "switch", Index: 1, Name: "eth0"}})
+	assert.Error(t, err)
+}
+
+type chanEvents chan common.MapStr
+
+func (c chanEvents) PublishEvent(event common.MapStr) bool {
+	c <- event
+	return true
+}
+
+func TestCollector(t *testing.T) {
+	events := make(chanEvents, 1)
+	c, err := New(events, &config.Sflow{Host: "127.0.0.1:0"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	c.Start()
+	defer c.Stop()
+
+	conn, err := net.Dial("udp", c.conn.LocalAddr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	conn.Write(flowSampleDatagram())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "sflow", event["type"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
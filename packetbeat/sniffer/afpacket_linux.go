@@ -1,16 +1,21 @@
-// +build linux
+// +build linux,cgo
 
 package sniffer
 
 import (
 	"time"
 
+	"github.com/elastic/beats/libbeat/logp"
+
 	"github.com/tsg/gopacket"
 	"github.com/tsg/gopacket/afpacket"
 )
 
 type AfpacketHandle struct {
 	TPacket *afpacket.TPacket
+
+	// stats of the socket of the TPacket, nil if the socket wasn't found
+	stats *packetSocketStats
 }
 
 func NewAfpacketHandle(device string, snaplen int, block_size int, num_blocks int,
@@ -34,7 +39,19 @@ func NewAfpacketHandle(device string, snaplen int, block_size int, num_blocks in
 			afpacket.OptPollTimeout(timeout))
 	}
 
-	return &h, err
+	if err != nil {
+		return &h, err
+	}
+
+	// The TPacket doesn't expose its socket, which is found among the open
+	// file descriptors to read the kernel drop counters.
+	if fd, err := findPacketSocket(); err == nil {
+		h.stats = &packetSocketStats{fd: fd, ringed: true}
+	} else {
+		logp.Warn("Kernel drop statistics not available: %v", err)
+	}
+
+	return &h, nil
 }
 
 func (h *AfpacketHandle) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
@@ -45,6 +62,25 @@ func (h *AfpacketHandle) SetBPFFilter(expr string) (_ error) {
 	return h.TPacket.SetBPFFilter(expr)
 }
 
+// Stats returns the counters of the socket. The utilization of the ring buffer
+// isn't reported.
+func (h *AfpacketHandle) Stats() (CaptureStats, error) {
+	if h.stats != nil {
+		return h.stats.update()
+	}
+
+	tpStats, err := h.TPacket.Stats()
+	if err != nil {
+		return CaptureStats{}, err
+	}
+	return CaptureStats{
+		Received:          tpStats.Packets,
+		Dropped:           -1,
+		IfDropped:         -1,
+		BufferUtilization: -1,
+	}, nil
+}
+
 func (h *AfpacketHandle) Close() {
 	h.TPacket.Close()
 }
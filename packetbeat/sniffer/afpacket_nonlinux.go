@@ -26,5 +26,9 @@ func (h *AfpacketHandle) SetBPFFilter(expr string) (_ error) {
 	return fmt.Errorf("Afpacket MMAP sniffing is only available on Linux")
 }
 
+func (h *AfpacketHandle) Stats() (CaptureStats, error) {
+	return CaptureStats{}, fmt.Errorf("Afpacket MMAP sniffing is only available on Linux")
+}
+
 func (h *AfpacketHandle) Close() {
 }
@@ -0,0 +1,155 @@
+// +build !cgo
+
+package sniffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/tsg/gopacket"
+)
+
+// AfpacketHandle receives packets from an AF_PACKET socket in builds without
+// cgo. Packets are read with one system call each instead of through the
+// memory mapped ring buffer, which costs more CPU at high packet rates.
+type AfpacketHandle struct {
+	fd    int
+	buf   []byte
+	stats packetSocketStats
+
+	// packets sent on loopback devices are also received as incoming
+	// packets, so outgoing packets are skipped on these devices
+	loopbacks map[int]bool
+}
+
+// packetMreq is the struct packet_mreq of <linux/if_packet.h>.
+type packetMreq struct {
+	ifindex int32
+	typ     uint16
+	alen    uint16
+	address [8]byte
+}
+
+func NewAfpacketHandle(device string, snaplen int, block_size int, num_blocks int,
+	timeout time.Duration) (*AfpacketHandle, error) {
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(syscall.ETH_P_ALL)))
+	if err != nil {
+		return nil, fmt.Errorf("Creating AF_PACKET socket failed: %v", err)
+	}
+
+	h := &AfpacketHandle{
+		fd:        fd,
+		buf:       make([]byte, snaplen),
+		stats:     packetSocketStats{fd: fd},
+		loopbacks: map[int]bool{},
+	}
+	if err := h.setup(device, block_size*num_blocks, timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *AfpacketHandle) setup(device string, bufferSize int, timeout time.Duration) error {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			h.loopbacks[iface.Index] = true
+		}
+	}
+
+	if device != "any" {
+		iface, err := net.InterfaceByName(device)
+		if err != nil {
+			return err
+		}
+
+		addr := &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ALL), Ifindex: iface.Index}
+		if err := syscall.Bind(h.fd, addr); err != nil {
+			return fmt.Errorf("Binding to device %s failed: %v", device, err)
+		}
+
+		mreq := packetMreq{ifindex: int32(iface.Index), typ: syscall.PACKET_MR_PROMISC}
+		opt := (*[unsafe.Sizeof(mreq)]byte)(unsafe.Pointer(&mreq))[:]
+		err = syscall.SetsockoptString(h.fd, syscall.SOL_PACKET, syscall.PACKET_ADD_MEMBERSHIP, string(opt))
+		if err != nil {
+			return fmt.Errorf("Enabling promiscuous mode on device %s failed: %v", device, err)
+		}
+	}
+
+	// The buffer size computed for the ring buffer is used as socket receive
+	// buffer instead. The kernel caps it to net.core.rmem_max.
+	if err := syscall.SetsockoptInt(h.fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, bufferSize); err != nil {
+		return err
+	}
+
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	return syscall.SetsockoptTimeval(h.fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv)
+}
+
+// ReadPacketData returns the next packet, or an empty packet if no packet was
+// received before the timeout expired.
+func (h *AfpacketHandle) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	n, from, err := syscall.Recvfrom(h.fd, h.buf, syscall.MSG_TRUNC)
+	if err == syscall.EAGAIN {
+		return nil, ci, nil
+	}
+	if err != nil {
+		return nil, ci, err
+	}
+
+	if addr, ok := from.(*syscall.SockaddrLinklayer); ok {
+		if addr.Pkttype == syscall.PACKET_OUTGOING && h.loopbacks[addr.Ifindex] {
+			return nil, ci, nil
+		}
+	}
+
+	// with MSG_TRUNC, n is the length of the packet on the wire
+	captured := n
+	if captured > len(h.buf) {
+		captured = len(h.buf)
+	}
+	data = make([]byte, captured)
+	copy(data, h.buf)
+
+	ci = gopacket.CaptureInfo{
+		Timestamp:     time.Now(),
+		CaptureLength: captured,
+		Length:        n,
+	}
+	return data, ci, nil
+}
+
+// SetBPFFilter fails for non-empty filters, as BPF filters are compiled by
+// libpcap.
+func (h *AfpacketHandle) SetBPFFilter(expr string) (_ error) {
+	if expr == "" {
+		return nil
+	}
+	return fmt.Errorf("BPF filters are not supported, as packetbeat was built without libpcap")
+}
+
+// Stats returns the counters of the socket, and the utilization of its receive
+// buffer.
+func (h *AfpacketHandle) Stats() (CaptureStats, error) {
+	return h.stats.update()
+}
+
+func (h *AfpacketHandle) Close() {
+	syscall.Close(h.fd)
+}
+
+// htons converts a short from host to network byte order.
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return *(*uint16)(unsafe.Pointer(&b[0]))
+}
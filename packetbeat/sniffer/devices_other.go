@@ -0,0 +1,15 @@
+// +build !windows
+
+package sniffer
+
+import "net"
+
+// addInterfaceInfo marks the loopback devices.
+func addInterfaceInfo(devices []Device) {
+	for i := range devices {
+		iface, err := net.InterfaceByName(devices[i].Name)
+		if err == nil {
+			devices[i].Loopback = iface.Flags&net.FlagLoopback != 0
+		}
+	}
+}
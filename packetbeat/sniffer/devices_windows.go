@@ -0,0 +1,92 @@
+package sniffer
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/elastic/beats/libbeat/logp"
+)
+
+// npcapLoopback is the device of Npcap capturing the traffic to and from the
+// local addresses.
+const npcapLoopback = `\Device\NPF_Loopback`
+
+// adapter holds the information of a network adapter that is not available
+// from WinPcap or Npcap.
+type adapter struct {
+	friendlyName string
+	loopback     bool
+}
+
+// addInterfaceInfo sets the friendly names of the devices, which are named
+// after the GUIDs of the adapters, like \Device\NPF_{GUID}.
+func addInterfaceInfo(devices []Device) {
+	adapters, err := adapterInfo()
+	if err != nil {
+		logp.Warn("Failed to get the names of the network adapters: %v", err)
+	}
+
+	for i := range devices {
+		dev := &devices[i]
+		if strings.EqualFold(dev.Name, npcapLoopback) {
+			dev.FriendlyName = "Npcap Loopback"
+			dev.Loopback = true
+			continue
+		}
+
+		// Npcap versions before 0.9983 install a loopback adapter instead.
+		if strings.Contains(dev.Description, "Npcap Loopback Adapter") {
+			dev.Loopback = true
+		}
+
+		guid := dev.Name[strings.LastIndex(dev.Name, "_")+1:]
+		if a, found := adapters[strings.ToUpper(guid)]; found {
+			dev.FriendlyName = a.friendlyName
+			dev.Loopback = dev.Loopback || a.loopback
+		}
+	}
+}
+
+// adapterInfo returns the information of the network adapters by the
+// uppercase GUID of the adapter.
+func adapterInfo() (map[string]adapter, error) {
+	var buf []byte
+	size := uint32(15000) // recommended initial size
+	for {
+		buf = make([]byte, size)
+		err := windows.GetAdaptersAddresses(syscall.AF_UNSPEC, 0, 0,
+			(*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])), &size)
+		if err == nil {
+			break
+		}
+		if err != windows.ERROR_BUFFER_OVERFLOW {
+			return nil, err
+		}
+	}
+
+	adapters := map[string]adapter{}
+	for aa := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0])); aa != nil; aa = aa.Next {
+		name := bytePtrToString(aa.AdapterName)
+		adapters[strings.ToUpper(name)] = adapter{
+			friendlyName: windows.UTF16ToString((*(*[10000]uint16)(unsafe.Pointer(aa.FriendlyName)))[:]),
+			loopback:     aa.IfType == windows.IF_TYPE_SOFTWARE_LOOPBACK,
+		}
+	}
+	return adapters, nil
+}
+
+func bytePtrToString(p *byte) string {
+	if p == nil {
+		return ""
+	}
+	b := (*[10000]byte)(unsafe.Pointer(p))[:]
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,152 @@
+package sniffer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// This file implements the statistics of AF_PACKET sockets, which are used by
+// the af_packet sniffer type with and without cgo.
+
+// packetSocketStats accumulates the counters of an AF_PACKET socket, as the
+// kernel resets them on every read.
+type packetSocketStats struct {
+	fd        int
+	received  int64
+	dropped   int64
+	ringed    bool // packets are received in a memory mapped ring buffer
+	rcvbufLen int
+}
+
+// update reads the counters of the socket and returns the counters since the
+// socket was opened.
+func (s *packetSocketStats) update() (CaptureStats, error) {
+	// struct tpacket_stats has the size of struct ip_mreq, which is the only
+	// socket option of 8 bytes the syscall package can read on all platforms.
+	mreq, err := syscall.GetsockoptIPMreq(s.fd, syscall.SOL_PACKET, syscall.PACKET_STATISTICS)
+	if err != nil {
+		return CaptureStats{}, fmt.Errorf("Reading the AF_PACKET socket statistics failed: %v", err)
+	}
+	s.received += int64(*(*uint32)(unsafe.Pointer(&mreq.Multiaddr[0])))
+	s.dropped += int64(*(*uint32)(unsafe.Pointer(&mreq.Interface[0])))
+
+	stats := CaptureStats{
+		Received:          s.received,
+		Dropped:           s.dropped,
+		IfDropped:         -1,
+		BufferUtilization: -1,
+	}
+
+	// Packets received through the ring buffer are not accounted in the
+	// receive memory of the socket.
+	if s.ringed {
+		return stats, nil
+	}
+	if s.rcvbufLen == 0 {
+		s.rcvbufLen, err = syscall.GetsockoptInt(s.fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		if err != nil {
+			return stats, nil
+		}
+	}
+	if rmem, err := socketRmem(s.fd); err == nil && s.rcvbufLen > 0 {
+		stats.BufferUtilization = float64(rmem) / float64(s.rcvbufLen)
+	}
+	return stats, nil
+}
+
+// socketRmem returns the memory used by the packets queued in the receive
+// buffer of the AF_PACKET socket.
+func socketRmem(fd int) (int64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(fd, &st); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Open("/proc/net/packet")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	return parseProcNetPacket(f, uint64(st.Ino))
+}
+
+// parseProcNetPacket returns the Rmem column of the socket with the inode in
+// the format of /proc/net/packet.
+func parseProcNetPacket(r io.Reader, inode uint64) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	rmemCol, inodeCol := -1, -1
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if rmemCol < 0 {
+			for i, name := range fields {
+				switch name {
+				case "Rmem":
+					rmemCol = i
+				case "Inode":
+					inodeCol = i
+				}
+			}
+			if rmemCol < 0 || inodeCol < 0 {
+				return 0, fmt.Errorf("Unexpected header in /proc/net/packet: %s", scanner.Text())
+			}
+			continue
+		}
+
+		if len(fields) <= inodeCol || len(fields) <= rmemCol {
+			continue
+		}
+		if ino, err := strconv.ParseUint(fields[inodeCol], 10, 64); err != nil || ino != inode {
+			continue
+		}
+		return strconv.ParseInt(fields[rmemCol], 10, 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("Socket inode %d not found in /proc/net/packet", inode)
+}
+
+// findPacketSocket returns the file descriptor of the only AF_PACKET socket
+// opened by the process, for captures that don't expose their socket.
+func findPacketSocket() (int, error) {
+	data, err := ioutil.ReadFile("/proc/net/packet")
+	if err != nil {
+		return -1, err
+	}
+
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1, err
+	}
+
+	found := -1
+	for _, info := range fds {
+		fd, err := strconv.Atoi(info.Name())
+		if err != nil {
+			continue
+		}
+		var st syscall.Stat_t
+		if err := syscall.Fstat(fd, &st); err != nil || st.Mode&syscall.S_IFMT != syscall.S_IFSOCK {
+			continue
+		}
+		if _, err := parseProcNetPacket(strings.NewReader(string(data)), uint64(st.Ino)); err != nil {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("Multiple AF_PACKET sockets are open")
+		}
+		found = fd
+	}
+	if found < 0 {
+		return -1, fmt.Errorf("No AF_PACKET socket is open")
+	}
+	return found, nil
+}
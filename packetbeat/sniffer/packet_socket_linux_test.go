@@ -0,0 +1,26 @@
+// +build !integration
+
+package sniffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcNetPacket(t *testing.T) {
+	procNetPacket := `sk               RefCnt Type Proto  Iface R Rmem   User   Inode
+ffff8a5c7b1c4800 3      3    0003   2     1 4352   0      123456
+ffff8a5c7b1c5000 3      3    0003   0     1 0      0      123457
+`
+	rmem, err := parseProcNetPacket(strings.NewReader(procNetPacket), 123456)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4352), rmem)
+
+	_, err = parseProcNetPacket(strings.NewReader(procNetPacket), 1)
+	assert.Error(t, err)
+
+	_, err = parseProcNetPacket(strings.NewReader("invalid\n"), 123456)
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+// +build cgo
+
+package sniffer
+
+import (
+	"time"
+
+	"github.com/tsg/gopacket/layers"
+	"github.com/tsg/gopacket/pcap"
+)
+
+// This file implements the pcap sniffer type with libpcap.
+
+// autodetectType returns the sniffer type used by default.
+func autodetectType() string {
+	return "pcap"
+}
+
+func openLive(device string, snaplen int, filter string, timeout time.Duration) (pcapHandle, error) {
+	h, err := pcap.OpenLive(device, int32(snaplen), true, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.SetBPFFilter(filter); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func openOffline(file string) (pcapHandle, error) {
+	h, err := pcap.OpenOffline(file)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// pcapStats returns the counters of a live capture. libpcap doesn't report
+// the utilization of the capture buffer.
+func pcapStats(h pcapHandle) (CaptureStats, error) {
+	handle, ok := h.(*pcap.Handle)
+	if !ok {
+		return CaptureStats{}, errNoStats
+	}
+	stats, err := handle.Stats()
+	if err != nil {
+		return CaptureStats{}, err
+	}
+	return CaptureStats{
+		Received:          int64(stats.PacketsReceived),
+		Dropped:           int64(stats.PacketsDropped),
+		IfDropped:         int64(stats.PacketsIfDropped),
+		BufferUtilization: -1,
+	}, nil
+}
+
+func findAllDevs() ([]Device, error) {
+	ifaces, err := pcap.FindAllDevs()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(ifaces))
+	for _, iface := range ifaces {
+		dev := Device{Name: iface.Name, Description: iface.Description}
+		for _, addr := range iface.Addresses {
+			dev.Addresses = append(dev.Addresses, addr.IP.String())
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+func newDumper(file string, linkType layers.LinkType) (dumper, error) {
+	p, err := pcap.OpenDead(linkType, 65535)
+	if err != nil {
+		return nil, err
+	}
+	d, err := p.NewDumper(file)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// isTimeout reports if no packet was received before the read timeout
+// expired.
+func isTimeout(err error) bool {
+	return err == pcap.NextErrorTimeoutExpired
+}
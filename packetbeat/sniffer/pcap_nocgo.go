@@ -0,0 +1,114 @@
+// +build !cgo
+
+package sniffer
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/tsg/gopacket"
+	"github.com/tsg/gopacket/layers"
+	"github.com/tsg/gopacket/pcapgo"
+)
+
+// This file implements the pcap sniffer type for builds without cgo, where
+// libpcap is not available. Files are read and written in pure Go, but live
+// capture is only possible with the af_packet sniffer type on Linux.
+
+var errNoLibpcap = errors.New("Live capture with the pcap sniffer type is not " +
+	"available, as packetbeat was built without libpcap. Use the af_packet " +
+	"sniffer type on Linux, or read a pcap file")
+
+// autodetectType returns the sniffer type used by default. On Linux, the
+// af_packet sniffer type captures packets without libpcap. On other
+// platforms, pcap files can still be read.
+func autodetectType() string {
+	if runtime.GOOS == "linux" {
+		return "af_packet"
+	}
+	return "pcap"
+}
+
+func openLive(device string, snaplen int, filter string, timeout time.Duration) (pcapHandle, error) {
+	return nil, errNoLibpcap
+}
+
+func openOffline(file string) (pcapHandle, error) {
+	return openPcapFile(file)
+}
+
+// pcapStats fails, as only pcap files can be opened without libpcap.
+func pcapStats(h pcapHandle) (CaptureStats, error) {
+	return CaptureStats{}, errNoLibpcap
+}
+
+func findAllDevs() ([]Device, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(ifaces)+1)
+	if runtime.GOOS == "linux" {
+		devices = append(devices, Device{
+			Name:        "any",
+			Description: "Pseudo-device that captures on all interfaces",
+		})
+	}
+	for _, iface := range ifaces {
+		dev := Device{Name: iface.Name}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				if ipnet, ok := addr.(*net.IPNet); ok {
+					dev.Addresses = append(dev.Addresses, ipnet.IP.String())
+				}
+			}
+		}
+		devices = append(devices, dev)
+	}
+	return devices, nil
+}
+
+// fileDumper writes packets to a pcap file with pcapgo.
+type fileDumper struct {
+	file *os.File
+	buf  *bufio.Writer
+	w    *pcapgo.Writer
+}
+
+func newDumper(file string, linkType layers.LinkType) (dumper, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &fileDumper{file: f, buf: bufio.NewWriter(f)}
+	d.w = pcapgo.NewWriter(d.buf)
+	if err := d.w.WriteFileHeader(65535, linkType); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *fileDumper) WritePacketData(data []byte, ci gopacket.CaptureInfo) error {
+	return d.w.WritePacket(ci, data)
+}
+
+func (d *fileDumper) Close() error {
+	if err := d.buf.Flush(); err != nil {
+		d.file.Close()
+		return err
+	}
+	return d.file.Close()
+}
+
+// isTimeout reports if no packet was received before the read timeout
+// expired. The pure Go handles return empty packets on timeouts instead.
+func isTimeout(err error) bool {
+	return false
+}
@@ -0,0 +1,133 @@
+package sniffer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/tsg/gopacket"
+	"github.com/tsg/gopacket/layers"
+)
+
+// Magic numbers of the libpcap file format, as read in little endian.
+const (
+	pcapMagic        = 0xa1b2c3d4
+	pcapMagicSwapped = 0xd4c3b2a1
+	pcapMagicNsec    = 0xa1b23c4d
+	pcapMagicNsecSwp = 0x4d3cb2a1
+	pcapngMagic      = 0x0a0d0d0a
+
+	// maxPcapRecordSize limits the size of a record, so that corrupted
+	// files don't allocate huge buffers.
+	maxPcapRecordSize = 256 * 1024
+)
+
+var errPcapng = errors.New("pcapng files can only be read with libpcap, " +
+	"convert the file to the pcap format first")
+
+// pcapFile reads packets from a file in the libpcap format without
+// libpcap. It is used by builds without cgo.
+type pcapFile struct {
+	r      *bufio.Reader
+	closer io.Closer
+
+	order    binary.ByteOrder
+	nsec     bool // timestamps are in nanoseconds instead of microseconds
+	linkType layers.LinkType
+
+	header [16]byte
+}
+
+func openPcapFile(path string) (*pcapFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPcapFile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Error reading pcap file %s: %v", path, err)
+	}
+	p.closer = f
+	return p, nil
+}
+
+// newPcapFile reads the global header of the file.
+func newPcapFile(r io.Reader) (*pcapFile, error) {
+	p := &pcapFile{r: bufio.NewReader(r)}
+
+	var header [24]byte
+	if _, err := io.ReadFull(p.r, header[:]); err != nil {
+		return nil, err
+	}
+
+	switch binary.LittleEndian.Uint32(header[0:4]) {
+	case pcapMagic:
+		p.order = binary.LittleEndian
+	case pcapMagicSwapped:
+		p.order = binary.BigEndian
+	case pcapMagicNsec:
+		p.order, p.nsec = binary.LittleEndian, true
+	case pcapMagicNsecSwp:
+		p.order, p.nsec = binary.BigEndian, true
+	case pcapngMagic:
+		return nil, errPcapng
+	default:
+		return nil, fmt.Errorf("unknown file format with magic number 0x%x",
+			binary.LittleEndian.Uint32(header[0:4]))
+	}
+
+	p.linkType = layers.LinkType(p.order.Uint32(header[20:24]))
+	return p, nil
+}
+
+func (p *pcapFile) LinkType() layers.LinkType {
+	return p.linkType
+}
+
+// ReadPacketData returns the next packet of the file, or io.EOF at the end
+// of the file.
+func (p *pcapFile) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	if _, err = io.ReadFull(p.r, p.header[:]); err != nil {
+		return nil, ci, err
+	}
+
+	sec := p.order.Uint32(p.header[0:4])
+	frac := p.order.Uint32(p.header[4:8])
+	captured := p.order.Uint32(p.header[8:12])
+	length := p.order.Uint32(p.header[12:16])
+	if captured > maxPcapRecordSize {
+		return nil, ci, fmt.Errorf("pcap record of %d bytes exceeds the maximum of %d bytes",
+			captured, maxPcapRecordSize)
+	}
+
+	nsec := int64(frac)
+	if !p.nsec {
+		nsec *= int64(time.Microsecond)
+	}
+	ci = gopacket.CaptureInfo{
+		Timestamp:     time.Unix(int64(sec), nsec),
+		CaptureLength: int(captured),
+		Length:        int(length),
+	}
+
+	data = make([]byte, captured)
+	if _, err = io.ReadFull(p.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, ci, err
+	}
+	return data, ci, nil
+}
+
+func (p *pcapFile) Close() {
+	if p.closer != nil {
+		p.closer.Close()
+	}
+}
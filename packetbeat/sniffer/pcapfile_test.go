@@ -0,0 +1,100 @@
+// +build !integration
+
+package sniffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tsg/gopacket"
+	"github.com/tsg/gopacket/layers"
+	"github.com/tsg/gopacket/pcapgo"
+)
+
+func TestPcapFileRead(t *testing.T) {
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	if err := w.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1484838000, 123456000)
+	packets := [][]byte{[]byte("first packet"), []byte("second")}
+	for i, packet := range packets {
+		ci := gopacket.CaptureInfo{
+			Timestamp:     ts.Add(time.Duration(i) * time.Second),
+			CaptureLength: len(packet),
+			Length:        len(packet) + i,
+		}
+		if err := w.WritePacket(ci, packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := newPcapFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, layers.LinkTypeEthernet, p.LinkType())
+
+	for i, packet := range packets {
+		data, ci, err := p.ReadPacketData()
+		assert.NoError(t, err)
+		assert.Equal(t, packet, data)
+		assert.Equal(t, len(packet), ci.CaptureLength)
+		assert.Equal(t, len(packet)+i, ci.Length)
+		assert.True(t, ts.Add(time.Duration(i)*time.Second).Equal(ci.Timestamp))
+	}
+
+	_, _, err = p.ReadPacketData()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPcapFileBigEndianNanoseconds(t *testing.T) {
+	var buf bytes.Buffer
+	write := func(v ...interface{}) {
+		for _, v := range v {
+			binary.Write(&buf, binary.BigEndian, v)
+		}
+	}
+	write(uint32(pcapMagicNsec), uint16(2), uint16(4), int32(0), uint32(0), uint32(65535), uint32(layers.LinkTypeLinuxSLL))
+	write(uint32(1484838000), uint32(123456789), uint32(4), uint32(4), []byte("data"))
+
+	p, err := newPcapFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, layers.LinkTypeLinuxSLL, p.LinkType())
+
+	data, ci, err := p.ReadPacketData()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+	assert.True(t, time.Unix(1484838000, 123456789).Equal(ci.Timestamp))
+}
+
+func TestPcapFileErrors(t *testing.T) {
+	_, err := newPcapFile(bytes.NewReader([]byte{0x0a, 0x0d, 0x0d, 0x0a, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, errPcapng, err)
+
+	_, err = newPcapFile(bytes.NewReader([]byte("not a pcap file at all!!")))
+	assert.Error(t, err)
+
+	// truncated record
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	w.WriteFileHeader(65535, layers.LinkTypeEthernet)
+	w.WritePacket(gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: 6, Length: 6}, []byte("packet"))
+	buf.Truncate(buf.Len() - 2)
+
+	p, err := newPcapFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = p.ReadPacketData()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
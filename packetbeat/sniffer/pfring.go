@@ -1,4 +1,4 @@
-// +build linux,havepfring
+// +build linux,havepfring,cgo
 
 package sniffer
 
@@ -45,6 +45,21 @@ func (h *PfringHandle) Enable() (_ error) {
 	return h.Ring.Enable()
 }
 
+// Stats returns the counters of the ring. Packets dropped by the network
+// interface and the utilization of the ring aren't reported.
+func (h *PfringHandle) Stats() (CaptureStats, error) {
+	stats, err := h.Ring.Stats()
+	if err != nil {
+		return CaptureStats{}, err
+	}
+	return CaptureStats{
+		Received:          int64(stats.Received),
+		Dropped:           int64(stats.Dropped),
+		IfDropped:         -1,
+		BufferUtilization: -1,
+	}, nil
+}
+
 func (h *PfringHandle) Close() {
 	h.Ring.Close()
 }
@@ -1,4 +1,4 @@
-// +build !linux !havepfring
+// +build !linux !havepfring !cgo
 
 package sniffer
 
@@ -28,5 +28,9 @@ func (h *PfringHandle) Enable() (_ error) {
 	return fmt.Errorf("Pfring sniffing is not compiled in")
 }
 
+func (h *PfringHandle) Stats() (CaptureStats, error) {
+	return CaptureStats{}, fmt.Errorf("Pfring sniffing is not compiled in")
+}
+
 func (h *PfringHandle) Close() {
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,16 +15,15 @@ import (
 
 	"github.com/tsg/gopacket"
 	"github.com/tsg/gopacket/layers"
-	"github.com/tsg/gopacket/pcap"
 )
 
 type SnifferSetup struct {
-	pcapHandle     *pcap.Handle
+	pcapHandle     pcapHandle
 	afpacketHandle *AfpacketHandle
 	pfringHandle   *PfringHandle
 	config         *config.InterfacesConfig
 	isAlive        bool
-	dumper         *pcap.Dumper
+	dumper         dumper
 
 	// bpf filter
 	filter string
@@ -33,6 +33,31 @@ type SnifferSetup struct {
 	DataSource gopacket.PacketDataSource
 }
 
+// pcapHandle is a live capture or a file opened by the pcap sniffer type.
+// Without libpcap, only files can be opened.
+type pcapHandle interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+	Close()
+}
+
+// dumper writes the sniffed packets to a pcap file.
+type dumper interface {
+	WritePacketData(data []byte, ci gopacket.CaptureInfo) error
+	Close() error
+}
+
+// Device is a network adapter available for sniffing. On Windows, the name of
+// the device contains the GUID of the adapter, while the friendly name is the
+// name shown in the network settings, like "Ethernet".
+type Device struct {
+	Name         string
+	Description  string
+	FriendlyName string
+	Addresses    []string
+	Loopback     bool
+}
+
 type Worker interface {
 	OnPacket(data []byte, ci *gopacket.CaptureInfo)
 }
@@ -72,11 +97,67 @@ func deviceNameFromIndex(index int, devices []string) (string, error) {
 	return devices[index], nil
 }
 
+// resolveDevice returns the name of the configured device. Besides the name,
+// a device can be configured by its index in the list of devices, by its
+// friendly name or description, or as "loopback" for the loopback device.
+// Devices that are not found are returned unchanged.
+func resolveDevice(device string, devices []Device) (string, error) {
+	if index, err := strconv.Atoi(device); err == nil { // Device is numeric
+		names := make([]string, len(devices))
+		for i, dev := range devices {
+			names[i] = dev.Name
+		}
+		name, err := deviceNameFromIndex(index, names)
+		if err != nil {
+			return "", fmt.Errorf("Couldn't understand device index %d: %v", index, err)
+		}
+		return name, nil
+	}
+
+	for _, dev := range devices {
+		if dev.Name == device {
+			return device, nil
+		}
+	}
+
+	loopback := strings.EqualFold(device, "loopback")
+	var matches []string
+	for _, dev := range devices {
+		switch {
+		case loopback && dev.Loopback,
+			!loopback && dev.FriendlyName != "" && strings.EqualFold(dev.FriendlyName, device),
+			!loopback && dev.Description != "" && strings.EqualFold(dev.Description, device):
+			matches = append(matches, dev.Name)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return "", fmt.Errorf("Device '%s' is ambiguous, it matches the devices: %s",
+			device, strings.Join(matches, ", "))
+	case loopback:
+		return "", fmt.Errorf("No loopback device found")
+	}
+	return device, nil
+}
+
+// ListDevices returns the adapters available for sniffing on this computer.
+func ListDevices() ([]Device, error) {
+	devices, err := findAllDevs()
+	if err != nil {
+		return nil, err
+	}
+	addInterfaceInfo(devices)
+	return devices, nil
+}
+
 // ListDevicesNames returns the list of adapters available for sniffing on
 // this computer. If the withDescription parameter is set to true, a human
 // readable version of the adapter name is added.
 func ListDeviceNames(withDescription bool) ([]string, error) {
-	devices, err := pcap.FindAllDevs()
+	devices, err := findAllDevs()
 	if err != nil {
 		return []string{}, err
 	}
@@ -112,16 +193,19 @@ func (sniffer *SnifferSetup) setFromConfig(config *config.InterfacesConfig) erro
 		sniffer.config.Device = "any"
 	}
 
-	if index, err := strconv.Atoi(sniffer.config.Device); err == nil { // Device is numeric
-		devices, err := ListDeviceNames(false)
+	if len(sniffer.config.File) == 0 && sniffer.config.Device != "any" {
+		devices, err := ListDevices()
 		if err != nil {
-			return fmt.Errorf("Error getting devices list: %v", err)
+			logp.Warn("Error getting devices list: %v", err)
 		}
-		sniffer.config.Device, err = deviceNameFromIndex(index, devices)
+		device, err := resolveDevice(sniffer.config.Device, devices)
 		if err != nil {
-			return fmt.Errorf("Couldn't understand device index %d: %v", index, err)
+			return err
+		}
+		if device != sniffer.config.Device {
+			logp.Info("Resolved device %s to device: %s", sniffer.config.Device, device)
+			sniffer.config.Device = device
 		}
-		logp.Info("Resolved device index %d to device: %s", index, sniffer.config.Device)
 	}
 
 	if sniffer.config.Snaplen == 0 {
@@ -129,7 +213,7 @@ func (sniffer *SnifferSetup) setFromConfig(config *config.InterfacesConfig) erro
 	}
 
 	if sniffer.config.Type == "autodetect" || sniffer.config.Type == "" {
-		sniffer.config.Type = "pcap"
+		sniffer.config.Type = autodetectType()
 	}
 
 	logp.Debug("sniffer", "Sniffer type: %s device: %s", sniffer.config.Type, sniffer.config.Device)
@@ -137,23 +221,19 @@ func (sniffer *SnifferSetup) setFromConfig(config *config.InterfacesConfig) erro
 	switch sniffer.config.Type {
 	case "pcap":
 		if len(sniffer.config.File) > 0 {
-			sniffer.pcapHandle, err = pcap.OpenOffline(sniffer.config.File)
+			sniffer.pcapHandle, err = openOffline(sniffer.config.File)
 			if err != nil {
 				return err
 			}
 		} else {
-			sniffer.pcapHandle, err = pcap.OpenLive(
+			sniffer.pcapHandle, err = openLive(
 				sniffer.config.Device,
-				int32(sniffer.config.Snaplen),
-				true,
+				sniffer.config.Snaplen,
+				sniffer.filter,
 				500*time.Millisecond)
 			if err != nil {
 				return err
 			}
-			err = sniffer.pcapHandle.SetBPFFilter(sniffer.filter)
-			if err != nil {
-				return err
-			}
 		}
 
 		sniffer.DataSource = gopacket.PacketDataSource(sniffer.pcapHandle)
@@ -224,7 +304,7 @@ func (sniffer *SnifferSetup) Reopen() error {
 	}
 
 	sniffer.pcapHandle.Close()
-	sniffer.pcapHandle, err = pcap.OpenOffline(sniffer.config.File)
+	sniffer.pcapHandle, err = openOffline(sniffer.config.File)
 	if err != nil {
 		return err
 	}
@@ -258,11 +338,7 @@ func (sniffer *SnifferSetup) Init(test_mode bool, factory WorkerFactory, interfa
 	logp.Debug("sniffer", "BPF filter: '%s'", sniffer.filter)
 
 	if sniffer.config.Dumpfile != "" {
-		p, err := pcap.OpenDead(sniffer.Datalink(), 65535)
-		if err != nil {
-			return err
-		}
-		sniffer.dumper, err = p.NewDumper(sniffer.config.Dumpfile)
+		sniffer.dumper, err = newDumper(sniffer.config.Dumpfile, sniffer.Datalink())
 		if err != nil {
 			return err
 		}
@@ -287,7 +363,7 @@ func (sniffer *SnifferSetup) Run() error {
 
 		data, ci, err := sniffer.DataSource.ReadPacketData()
 
-		if err == pcap.NextErrorTimeoutExpired || err == syscall.EINTR {
+		if isTimeout(err) || err == syscall.EINTR {
 			logp.Debug("sniffer", "Interrupted")
 			continue
 		}
@@ -72,3 +72,36 @@ func Test_deviceNameFromIndex(t *testing.T) {
 	_, err = deviceNameFromIndex(3, devs)
 	assert.Error(t, err)
 }
+
+func Test_resolveDevice(t *testing.T) {
+	devs := []Device{
+		{Name: `\Device\NPF_{4A1F}`, Description: "Intel(R) Ethernet", FriendlyName: "Ethernet"},
+		{Name: `\Device\NPF_{7C2B}`, Description: "Intel(R) Ethernet", FriendlyName: "Ethernet 2"},
+		{Name: `\Device\NPF_Loopback`, FriendlyName: "Npcap Loopback", Loopback: true},
+	}
+
+	tests := []struct {
+		device   string
+		expected string
+	}{
+		{"1", `\Device\NPF_{7C2B}`},
+		{`\Device\NPF_{4A1F}`, `\Device\NPF_{4A1F}`},
+		{"ethernet", `\Device\NPF_{4A1F}`},
+		{"Ethernet 2", `\Device\NPF_{7C2B}`},
+		{"Loopback", `\Device\NPF_Loopback`},
+		{"unknown", "unknown"},
+	}
+	for _, test := range tests {
+		name, err := resolveDevice(test.device, devs)
+		assert.NoError(t, err, test.device)
+		assert.Equal(t, test.expected, name, test.device)
+	}
+
+	// Index out of range, ambiguous description, no loopback device
+	for _, device := range []string{"3", "Intel(R) Ethernet"} {
+		_, err := resolveDevice(device, devs)
+		assert.Error(t, err, device)
+	}
+	_, err := resolveDevice("loopback", devs[:2])
+	assert.Error(t, err)
+}
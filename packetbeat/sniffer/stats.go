@@ -0,0 +1,182 @@
+package sniffer
+
+import (
+	"errors"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/packetbeat/publish"
+)
+
+// CaptureStats are the counters of a live capture since it was opened.
+// Counters and values not reported by the sniffer type are -1.
+type CaptureStats struct {
+	// Received is the number of packets received by the capture, including
+	// the packets dropped by the kernel.
+	Received int64
+
+	// Dropped is the number of packets dropped by the kernel, because the
+	// buffer was full as packets were not read fast enough.
+	Dropped int64
+
+	// IfDropped is the number of packets dropped by the network interface or
+	// its driver.
+	IfDropped int64
+
+	// BufferUtilization is the fraction of the capture buffer holding packets
+	// not read yet, between 0 and 1.
+	BufferUtilization float64
+}
+
+var errNoStats = errors.New("No capture statistics available for pcap files")
+
+// Stats returns the counters of the live capture.
+func (sniffer *SnifferSetup) Stats() (CaptureStats, error) {
+	switch sniffer.config.Type {
+	case "pcap":
+		if sniffer.config.File != "" {
+			return CaptureStats{}, errNoStats
+		}
+		return pcapStats(sniffer.pcapHandle)
+	case "af_packet":
+		return sniffer.afpacketHandle.Stats()
+	case "pfring", "pf_ring":
+		return sniffer.pfringHandle.Stats()
+	}
+	return CaptureStats{}, errNoStats
+}
+
+// StatsReporter periodically publishes a sniffer_stats event with the packets
+// received and dropped by the capture, and the parse errors of the protocol
+// analyzers, since the previous event.
+type StatsReporter struct {
+	pub       publish.Events
+	stats     func() (CaptureStats, error)
+	device    string
+	typ       string
+	protocols []string
+	period    time.Duration
+
+	last        CaptureStats
+	parseErrors map[string]int64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStatsReporter creates a reporter for the capture of the sniffer. The
+// parse errors of the protocols are read from their <protocol>.parse_errors
+// counter.
+func NewStatsReporter(
+	pub publish.Events,
+	sniffer *SnifferSetup,
+	protocols []string,
+	period time.Duration,
+) *StatsReporter {
+	return newStatsReporter(pub, sniffer.Stats, sniffer.config.Device,
+		sniffer.config.Type, protocols, period)
+}
+
+func newStatsReporter(
+	pub publish.Events,
+	stats func() (CaptureStats, error),
+	device, typ string,
+	protocols []string,
+	period time.Duration,
+) *StatsReporter {
+	return &StatsReporter{
+		pub:         pub,
+		stats:       stats,
+		device:      device,
+		typ:         typ,
+		protocols:   protocols,
+		period:      period,
+		parseErrors: map[string]int64{},
+		done:        make(chan struct{}),
+	}
+}
+
+// Start starts publishing the events.
+func (r *StatsReporter) Start() {
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Stop stops publishing the events.
+func (r *StatsReporter) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *StatsReporter) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			event, err := r.event(now)
+			if err != nil {
+				logp.Warn("Reading the sniffer statistics failed: %v", err)
+				continue
+			}
+			r.pub.PublishEvent(event)
+		}
+	}
+}
+
+// event returns the event with the counters since the previous event.
+func (r *StatsReporter) event(now time.Time) (common.MapStr, error) {
+	stats, err := r.stats()
+	if err != nil {
+		return nil, err
+	}
+
+	packets := common.MapStr{}
+	delta := func(name string, current, last int64) {
+		if current >= 0 {
+			packets[name] = current - last
+		}
+	}
+	delta("received", stats.Received, r.last.Received)
+	delta("dropped", stats.Dropped, r.last.Dropped)
+	delta("if_dropped", stats.IfDropped, r.last.IfDropped)
+	r.last = stats
+
+	snifferStats := common.MapStr{
+		"device":  r.device,
+		"type":    r.typ,
+		"packets": packets,
+	}
+	if stats.BufferUtilization >= 0 {
+		snifferStats["buffer_utilization"] = stats.BufferUtilization
+	}
+
+	parseErrors := common.MapStr{}
+	for _, protocol := range r.protocols {
+		v, ok := expvar.Get(protocol + ".parse_errors").(*expvar.Int)
+		if !ok {
+			continue
+		}
+		current, _ := strconv.ParseInt(v.String(), 10, 64)
+		parseErrors[protocol] = current - r.parseErrors[protocol]
+		r.parseErrors[protocol] = current
+	}
+	if len(parseErrors) > 0 {
+		snifferStats["parse_errors"] = parseErrors
+	}
+
+	return common.MapStr{
+		"@timestamp": common.Time(now),
+		"type":       "sniffer_stats",
+		"sniffer":    snifferStats,
+	}, nil
+}
@@ -0,0 +1,73 @@
+// +build !integration
+
+package sniffer
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testEvents []common.MapStr
+
+func (e *testEvents) PublishEvent(event common.MapStr) bool {
+	*e = append(*e, event)
+	return true
+}
+
+var testParseErrors = expvar.NewInt("snifferstatstest.parse_errors")
+
+func TestStatsReporterEvent(t *testing.T) {
+	stats := CaptureStats{Received: 100, Dropped: 5, IfDropped: -1, BufferUtilization: 0.25}
+	source := func() (CaptureStats, error) { return stats, nil }
+
+	testParseErrors.Set(3)
+	r := newStatsReporter(&testEvents{}, source, "eth0", "af_packet",
+		[]string{"snifferstatstest", "unknown"}, time.Second)
+
+	now := time.Now()
+	event, err := r.event(now)
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"@timestamp": common.Time(now),
+		"type":       "sniffer_stats",
+		"sniffer": common.MapStr{
+			"device":             "eth0",
+			"type":               "af_packet",
+			"packets":            common.MapStr{"received": int64(100), "dropped": int64(5)},
+			"buffer_utilization": 0.25,
+			"parse_errors":       common.MapStr{"snifferstatstest": int64(3)},
+		},
+	}, event)
+
+	// the counters since the previous event are reported
+	stats = CaptureStats{Received: 150, Dropped: 5, IfDropped: -1, BufferUtilization: -1}
+	testParseErrors.Add(2)
+	event, err = r.event(now)
+	assert.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"device":       "eth0",
+		"type":         "af_packet",
+		"packets":      common.MapStr{"received": int64(50), "dropped": int64(0)},
+		"parse_errors": common.MapStr{"snifferstatstest": int64(2)},
+	}, event["sniffer"])
+}
+
+func TestStatsReporterPublish(t *testing.T) {
+	source := func() (CaptureStats, error) {
+		return CaptureStats{Received: 1, Dropped: -1, IfDropped: -1, BufferUtilization: -1}, nil
+	}
+
+	events := &testEvents{}
+	r := newStatsReporter(events, source, "any", "af_packet", nil, 10*time.Millisecond)
+	r.Start()
+	time.Sleep(50 * time.Millisecond)
+	r.Stop()
+
+	if assert.NotEmpty(t, *events) {
+		assert.Equal(t, "sniffer_stats", (*events)[0]["type"])
+	}
+}
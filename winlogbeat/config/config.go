@@ -35,10 +35,14 @@ type Settings struct {
 // all problems or nil if there are none.
 func (s Settings) Validate() error {
 	validKeys := []string{
-		"fields", "fields_under_root", "tags",
-		"name", "refresh_topology_freq", "ignore_outgoing", "topology_expire", "geoip",
-		"queue_size", "bulk_queue_size", "max_procs",
-		"filters", "logging", "output", "path", "winlogbeat",
+		"fields", "fields_under_root", "tags", "namespace", "env_tags",
+		"name", "hostname", "fqdn", "refresh_topology_freq", "ignore_outgoing", "topology_expire", "geoip",
+		"queue_size", "bulk_queue_size", "queue_overflow", "max_procs",
+		"worker", "loadbalance", "queue", "flush", "delivery", "shutdown_timeout",
+		"max_events_per_sec", "max_events_burst", "max_bytes_per_sec", "max_bytes_burst",
+		"routes", "dead_letter",
+		"filters", "processors", "logging", "output", "path", "control", "http", "vars",
+		"state_store", "features", "winlogbeat",
 	}
 	sort.Strings(validKeys)
 
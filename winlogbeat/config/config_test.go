@@ -3,9 +3,13 @@
 package config
 
 import (
+	"io/ioutil"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/libbeat/common"
 )
 
 type validationTestCase struct {
@@ -24,6 +28,31 @@ func (v validationTestCase) run(t *testing.T) {
 	}
 }
 
+// Test that all top-level settings of winlogbeat.full.yml are accepted,
+// including the settings that are commented out.
+func TestFullConfigValidate(t *testing.T) {
+	data, err := ioutil.ReadFile("../winlogbeat.full.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := common.NewConfigWithYAML(data, "winlogbeat.full.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings Settings
+	if err := cfg.Unpack(&settings); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, settings.Validate())
+
+	topLevelKey := regexp.MustCompile(`(?m)^#?([a-z_]+)[.:]`)
+	for _, match := range topLevelKey.FindAllStringSubmatch(string(data), -1) {
+		settings.Raw[match[1]] = nil
+	}
+	assert.NoError(t, settings.Validate())
+}
+
 func TestConfigValidate(t *testing.T) {
 	testCases := []validationTestCase{
 		// Top-level config
@@ -45,8 +74,12 @@ func TestConfigValidate(t *testing.T) {
 				map[string]interface{}{"other": "value"},
 			},
 			"1 error: Invalid top-level key 'other' found. Valid keys are bulk_queue_size, " +
-				"fields, fields_under_root, filters, geoip, ignore_outgoing, logging, max_procs, " +
-				"name, output, path, queue_size, refresh_topology_freq, tags, topology_expire, winlogbeat",
+				"control, dead_letter, delivery, env_tags, features, fields, fields_under_root, " +
+				"filters, flush, fqdn, geoip, hostname, http, ignore_outgoing, loadbalance, " +
+				"logging, max_bytes_burst, max_bytes_per_sec, max_events_burst, " +
+				"max_events_per_sec, max_procs, name, namespace, output, path, processors, queue, " +
+				"queue_overflow, queue_size, refresh_topology_freq, routes, shutdown_timeout, " +
+				"state_store, tags, topology_expire, vars, winlogbeat, worker",
 		},
 		{
 			WinlogbeatConfig{},
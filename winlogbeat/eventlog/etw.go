@@ -0,0 +1,175 @@
+// +build windows,amd64
+
+package eventlog
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/elastic/beats/libbeat/logp"
+	"github.com/elastic/beats/winlogbeat/sys"
+	"github.com/elastic/beats/winlogbeat/sys/etw"
+)
+
+// etwQueueSize is the number of events buffered between the trace session
+// and Read. If the buffer is full, the events are buffered by ETW, which
+// drops events once its own buffers are full.
+const etwQueueSize = 1000
+
+// Validate that etwEventLog implements the EventLog interface.
+var _ EventLog = &etwEventLog{}
+
+// etwEventLog implements the EventLog interface for consuming events of ETW
+// providers in a real-time trace session.
+type etwEventLog struct {
+	config  etwConfig
+	session *etw.Session
+	maxRead int
+
+	records      chan Record
+	errs         chan error
+	done         chan struct{}
+	wg           sync.WaitGroup
+	recordNumber uint64 // ETW has no record numbers, so they are counted.
+
+	logPrefix     string               // String to prefix on log messages.
+	eventMetadata common.EventMetadata // Field and tags to add to each event.
+}
+
+// Name returns the name of the event log.
+func (l *etwEventLog) Name() string {
+	return l.config.Name
+}
+
+// Open starts the trace session and enables the providers. Events logged
+// while Winlogbeat was not running can not be read, so the record number is
+// only used to continue the numbering of the records.
+func (l *etwEventLog) Open(recordNumber uint64) error {
+	var known map[string]etw.GUID
+	for _, p := range l.config.Providers {
+		if p.Name != "" {
+			var err error
+			if known, err = etw.Providers(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	session, err := etw.NewSession(l.config.sessionName())
+	if err != nil {
+		return err
+	}
+
+	for _, p := range l.config.Providers {
+		provider, err := p.provider(known)
+		if err == nil {
+			err = session.EnableProvider(provider)
+		}
+		if err != nil {
+			session.Close()
+			return err
+		}
+		debugf("%s Enabled provider %v (level=%d, any=%#x, all=%#x)",
+			l.logPrefix, provider.GUID, provider.Level,
+			provider.MatchAnyKeyword, provider.MatchAllKeyword)
+	}
+
+	l.session = session
+	l.recordNumber = recordNumber
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		if err := session.Process(l.onEvent); err != nil {
+			l.errs <- err
+		}
+	}()
+	return nil
+}
+
+func (l *etwEventLog) onEvent(e sys.Event) {
+	l.recordNumber++
+	e.RecordID = l.recordNumber
+	if e.Channel == "" {
+		e.Channel = l.config.Name
+	}
+
+	if logp.IsDebug(detailSelector) {
+		detailf("%s Event=%+v", l.logPrefix, e)
+	}
+
+	r := Record{
+		API:           etwAPIName,
+		EventMetadata: l.eventMetadata,
+		Event:         e,
+	}
+
+	select {
+	case <-l.done:
+	case l.records <- r:
+	}
+}
+
+// Read returns the events received since the last call, at most maxRead.
+func (l *etwEventLog) Read() ([]Record, error) {
+	var records []Record
+	for len(records) < l.maxRead {
+		select {
+		case r := <-l.records:
+			records = append(records, r)
+			continue
+		default:
+		}
+
+		if len(records) == 0 {
+			select {
+			case err := <-l.errs:
+				return nil, err
+			default:
+			}
+		}
+		break
+	}
+
+	debugf("%s Read() is returning %d records", l.logPrefix, len(records))
+	return records, nil
+}
+
+// Close stops the trace session.
+func (l *etwEventLog) Close() error {
+	debugf("%s Closing session", l.logPrefix)
+	close(l.done)
+	if l.session == nil {
+		return nil
+	}
+
+	err := l.session.Close()
+	l.wg.Wait()
+	return err
+}
+
+// newETWEventLog creates and returns a new EventLog for consuming events of
+// ETW providers.
+func newETWEventLog(options map[string]interface{}) (EventLog, error) {
+	var c etwConfig
+	if err := readConfig(options, &c, etwConfigKeys); err != nil {
+		return nil, err
+	}
+
+	return &etwEventLog{
+		config:        c,
+		maxRead:       defaultMaxNumRead,
+		records:       make(chan Record, etwQueueSize),
+		errs:          make(chan error, 1),
+		done:          make(chan struct{}),
+		logPrefix:     fmt.Sprintf("ETW[%s]", c.Name),
+		eventMetadata: c.EventMetadata,
+	}, nil
+}
+
+func init() {
+	// ETW is never selected automatically, it must be requested with
+	// api: etw.
+	Register(etwAPIName, 2, newETWEventLog, nil)
+}
@@ -0,0 +1,116 @@
+package eventlog
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/winlogbeat/sys/etw"
+	"github.com/joeshaw/multierror"
+)
+
+const (
+	// etwAPIName is the name used to identify Event Tracing for Windows as
+	// both an event type and an API.
+	etwAPIName = "etw"
+
+	// etwSessionPrefix is prefixed to the event log name to build the name of
+	// the trace session if no session_name is configured.
+	etwSessionPrefix = "Winlogbeat-"
+)
+
+var etwConfigKeys = append(commonConfigKeys, "session_name", "providers")
+
+type etwConfig struct {
+	ConfigCommon `config:",inline"`
+	SessionName  string                 `config:"session_name"`
+	Providers    []etwProviderConfig    `config:"providers"`
+	Raw          map[string]interface{} `config:",inline"`
+}
+
+// etwProviderConfig selects a provider by name or GUID and the events that
+// are requested from it.
+type etwProviderConfig struct {
+	Name            string `config:"name"`
+	GUID            string `config:"guid"`
+	Level           string `config:"level"`
+	MatchAnyKeyword uint64 `config:"match_any_keyword"`
+	MatchAllKeyword uint64 `config:"match_all_keyword"`
+}
+
+// Validate validates the etwConfig data and returns an error describing any
+// problems or nil.
+func (c *etwConfig) Validate() error {
+	var errs multierror.Errors
+	if c.Name == "" {
+		errs = append(errs, fmt.Errorf("event log is missing a 'name'"))
+	}
+
+	if len(c.Providers) == 0 {
+		errs = append(errs, fmt.Errorf("event log '%s' requires at least one "+
+			"provider", c.Name))
+	}
+
+	for i, p := range c.Providers {
+		if (p.Name == "") == (p.GUID == "") {
+			errs = append(errs, fmt.Errorf("provider %d of event log '%s' "+
+				"requires either a 'name' or a 'guid'", i, c.Name))
+		}
+
+		if p.GUID != "" {
+			if _, err := etw.ParseGUID(p.GUID); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if p.Level != "" {
+			if _, err := etw.ParseLevel(p.Level); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs.Err()
+}
+
+// sessionName returns the name of the trace session.
+func (c *etwConfig) sessionName() string {
+	if c.SessionName != "" {
+		return c.SessionName
+	}
+	return etwSessionPrefix + c.Name
+}
+
+// provider returns the provider to enable in the session. Providers
+// configured by name are looked up in known, the providers registered on the
+// system.
+func (p etwProviderConfig) provider(known map[string]etw.GUID) (etw.Provider, error) {
+	provider := etw.Provider{
+		Level:           etw.LevelInformation,
+		MatchAnyKeyword: p.MatchAnyKeyword,
+		MatchAllKeyword: p.MatchAllKeyword,
+	}
+
+	if p.Level != "" {
+		level, err := etw.ParseLevel(p.Level)
+		if err != nil {
+			return provider, err
+		}
+		provider.Level = level
+	}
+
+	if p.GUID != "" {
+		guid, err := etw.ParseGUID(p.GUID)
+		if err != nil {
+			return provider, err
+		}
+		provider.GUID = guid
+		return provider, nil
+	}
+
+	guid, found := known[p.Name]
+	if !found {
+		return provider, fmt.Errorf("provider '%s' is not registered on this "+
+			"system", p.Name)
+	}
+	provider.GUID = guid
+	return provider, nil
+}
@@ -0,0 +1,61 @@
+// +build !integration
+
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/winlogbeat/sys/etw"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETWConfig(t *testing.T) {
+	var c etwConfig
+	err := readConfig(map[string]interface{}{
+		"name": "dns",
+		"api":  "etw",
+		"providers": []map[string]interface{}{
+			{"name": "Microsoft-Windows-DNS-Client", "match_any_keyword": uint64(0x8000000000000000)},
+			{"guid": "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}", "level": "verbose"},
+		},
+	}, &c, etwConfigKeys)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "Winlogbeat-dns", c.sessionName())
+
+	dns, _ := etw.ParseGUID("{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}")
+	known := map[string]etw.GUID{"Microsoft-Windows-DNS-Client": dns}
+
+	p, err := c.Providers[0].provider(known)
+	assert.NoError(t, err)
+	assert.Equal(t, etw.Provider{
+		GUID:            dns,
+		Level:           etw.LevelInformation,
+		MatchAnyKeyword: 0x8000000000000000,
+	}, p)
+
+	p, err = c.Providers[1].provider(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, etw.Provider{GUID: dns, Level: etw.LevelVerbose}, p)
+
+	_, err = c.Providers[0].provider(nil)
+	assert.Error(t, err)
+}
+
+func TestETWConfigInvalid(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"name": "dns"},
+		{"providers": []map[string]interface{}{{"name": "a"}}},
+		{"name": "dns", "providers": []map[string]interface{}{{}}},
+		{"name": "dns", "providers": []map[string]interface{}{{"name": "a", "guid": "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}"}}},
+		{"name": "dns", "providers": []map[string]interface{}{{"guid": "a"}}},
+		{"name": "dns", "providers": []map[string]interface{}{{"name": "a", "level": "debug"}}},
+		{"name": "dns", "providers": []map[string]interface{}{{"name": "a"}}, "event_id": "1"},
+	}
+
+	for _, test := range tests {
+		var c etwConfig
+		assert.Error(t, readConfig(test, &c, etwConfigKeys), "%v", test)
+	}
+}
@@ -0,0 +1,9 @@
+/*
+Package etw provides access to Event Tracing for Windows (ETW). It creates
+real-time trace sessions, enables providers in the sessions and decodes the
+events delivered to the consumer using the Trace Data Helper (TDH) API.
+
+Real-time sessions require the user to be an administrator or a member of the
+Performance Log Users group.
+*/
+package etw
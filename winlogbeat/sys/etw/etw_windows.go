@@ -0,0 +1,385 @@
+// +build amd64
+
+package etw
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/elastic/beats/winlogbeat/sys"
+)
+
+// maxSessionNameLen is the maximum length of a session name in characters.
+const maxSessionNameLen = 1024
+
+// Sessions receiving events. The callback is shared by all sessions, because
+// the number of callbacks that can be created is limited. The session is
+// identified by the context passed to OpenTrace.
+var (
+	sessionsMutex sync.Mutex
+	sessions      = map[uintptr]*Session{}
+	lastSessionID uintptr
+
+	eventRecordCallback = syscall.NewCallback(onEventRecord)
+)
+
+// Session is a real-time trace session. Events of the enabled providers are
+// delivered to the consumer while Process is running.
+type Session struct {
+	name      string
+	namePtr   *uint16
+	handle    traceHandle // Handle of the session returned by StartTrace.
+	consumer  traceHandle // Handle of the consumer returned by OpenTrace.
+	id        uintptr     // Context used to find the session in the callback.
+	computer  string
+	callback  func(sys.Event)
+	providers []GUID
+}
+
+// NewSession starts a new real-time trace session. An existing session with
+// the same name, left behind by a process that did not stop it, is stopped
+// first.
+func NewSession(name string) (*Session, error) {
+	if len(name) >= maxSessionNameLen {
+		return nil, fmt.Errorf("session name '%v' is too long", name)
+	}
+
+	namePtr, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	computer, _ := os.Hostname()
+	s := &Session{
+		name:     name,
+		namePtr:  namePtr,
+		computer: computer,
+	}
+
+	err = _StartTrace(&s.handle, namePtr, s.properties())
+	if err == ERROR_ALREADY_EXISTS {
+		if err := _ControlTrace(0, namePtr, s.properties(), eventTraceControlStop); err != nil {
+			return nil, fmt.Errorf("failed to stop existing session '%v': %v", name, err)
+		}
+		err = _StartTrace(&s.handle, namePtr, s.properties())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session '%v': %v", name, err)
+	}
+	return s, nil
+}
+
+// properties returns new EVENT_TRACE_PROPERTIES for a real-time session with
+// space for the session name.
+func (s *Session) properties() *eventTraceProperties {
+	size := unsafe.Sizeof(eventTraceProperties{})
+	buf := make([]byte, size+maxSessionNameLen*2)
+
+	p := (*eventTraceProperties)(unsafe.Pointer(&buf[0]))
+	p.Wnode.BufferSize = uint32(len(buf))
+	p.Wnode.Flags = wnodeFlagTracedGUID
+	p.Wnode.ClientContext = clockTypeSystemTime
+	p.LogFileMode = eventTraceRealTimeMode
+	p.FlushTimer = 1
+	p.LoggerNameOffset = uint32(size)
+	return p
+}
+
+// EnableProvider enables the provider in the session.
+func (s *Session) EnableProvider(p Provider) error {
+	err := _EnableTraceEx2(s.handle, &p.GUID, eventControlCodeEnableProvider,
+		p.Level, p.MatchAnyKeyword, p.MatchAllKeyword, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to enable provider %v: %v", p.GUID, err)
+	}
+	s.providers = append(s.providers, p.GUID)
+	return nil
+}
+
+// Process delivers the events of the session to callback until the session
+// is closed. It blocks, and callback is called from the goroutine calling
+// Process.
+func (s *Session) Process(callback func(sys.Event)) error {
+	sessionsMutex.Lock()
+	lastSessionID++
+	s.id = lastSessionID
+	s.callback = callback
+	sessions[s.id] = s
+	sessionsMutex.Unlock()
+
+	defer func() {
+		sessionsMutex.Lock()
+		delete(sessions, s.id)
+		sessionsMutex.Unlock()
+	}()
+
+	logfile := eventTraceLogfile{
+		LoggerName:       s.namePtr,
+		ProcessTraceMode: processTraceModeRealTime | processTraceModeEventRecord,
+		EventCallback:    eventRecordCallback,
+		Context:          s.id,
+	}
+
+	consumer, err := _OpenTrace(&logfile)
+	if err != nil {
+		return fmt.Errorf("failed to open session '%v': %v", s.name, err)
+	}
+	s.consumer = consumer
+
+	err = _ProcessTrace(&consumer, 1, nil, nil)
+	if err != nil && err != ERROR_CANCELLED {
+		return fmt.Errorf("failed to process session '%v': %v", s.name, err)
+	}
+	return nil
+}
+
+// Close stops the session, which also ends Process.
+func (s *Session) Close() error {
+	for _, guid := range s.providers {
+		guid := guid
+		_EnableTraceEx2(s.handle, &guid, eventControlCodeDisableProvider, 0, 0, 0, 0, 0)
+	}
+
+	err := _ControlTrace(s.handle, nil, s.properties(), eventTraceControlStop)
+	if err != nil && err != ERROR_WMI_INSTANCE_NOT_FOUND {
+		return fmt.Errorf("failed to stop session '%v': %v", s.name, err)
+	}
+
+	if s.consumer != 0 {
+		// Returns ERROR_CTX_CLOSE_PENDING while the remaining events are
+		// delivered, which is not an error.
+		_CloseTrace(s.consumer)
+	}
+	return nil
+}
+
+func onEventRecord(r *eventRecord) uintptr {
+	sessionsMutex.Lock()
+	s := sessions[r.UserContext]
+	sessionsMutex.Unlock()
+
+	if s != nil {
+		s.callback(s.render(r))
+	}
+	return 0
+}
+
+// render converts the event record into an Event. Errors decoding the event
+// properties are reported in RenderErr.
+func (s *Session) render(r *eventRecord) sys.Event {
+	h := &r.EventHeader
+	d := &h.EventDescriptor
+	ft := syscall.Filetime{
+		LowDateTime:  uint32(h.TimeStamp),
+		HighDateTime: uint32(h.TimeStamp >> 32),
+	}
+
+	e := sys.Event{
+		Provider:        sys.Provider{GUID: h.ProviderID.String()},
+		EventIdentifier: sys.EventIdentifier{ID: uint32(d.ID)},
+		Version:         d.Version,
+		LevelRaw:        d.Level,
+		TaskRaw:         d.Task,
+		OpcodeRaw:       d.Opcode,
+		TimeCreated:     sys.TimeCreated{SystemTime: time.Unix(0, ft.Nanoseconds()).UTC()},
+		Computer:        s.computer,
+		Execution: sys.Execution{
+			ProcessID:   h.ProcessID,
+			ThreadID:    h.ThreadID,
+			ProcessorID: uint32(r.ProcessorNumber),
+		},
+	}
+	if !h.ActivityID.IsZero() {
+		e.Correlation.ActivityID = h.ActivityID.String()
+	}
+
+	if h.Flags&eventHeaderFlagStringOnly != 0 {
+		e.Message = utf16PtrToString(r.UserData, int(r.UserDataLength))
+		return e
+	}
+
+	info, err := eventInformation(r)
+	if err != nil {
+		e.RenderErr = err.Error()
+		return e
+	}
+
+	ti := (*traceEventInfo)(unsafe.Pointer(&info[0]))
+	e.Provider.Name = utf16At(info, ti.ProviderNameOffset)
+	e.Level = utf16At(info, ti.LevelNameOffset)
+	e.Task = utf16At(info, ti.TaskNameOffset)
+	e.Opcode = utf16At(info, ti.OpcodeNameOffset)
+	e.Channel = utf16At(info, ti.ChannelNameOffset)
+	e.Keywords = utf16ListAt(info, ti.KeywordsNameOffset)
+
+	e.EventData.Pairs, err = properties(r, info)
+	if err != nil {
+		e.RenderErr = err.Error()
+	}
+	e.Message = formatMessage(utf16At(info, ti.EventMessageOffset), e.EventData.Pairs)
+	return e
+}
+
+// eventInformation returns the TRACE_EVENT_INFO of the event.
+func eventInformation(r *eventRecord) ([]byte, error) {
+	var size uint32
+	err := _TdhGetEventInformation(r, 0, 0, nil, &size)
+	if err != ERROR_INSUFFICIENT_BUFFER {
+		return nil, fmt.Errorf("failed to get event information: %v", err)
+	}
+
+	info := make([]byte, size)
+	if err := _TdhGetEventInformation(r, 0, 0, &info[0], &size); err != nil {
+		return nil, fmt.Errorf("failed to get event information: %v", err)
+	}
+	return info, nil
+}
+
+// properties formats the top-level properties of the event. Structures are
+// not decoded and arrays are formatted as their first element.
+func properties(r *eventRecord, info []byte) ([]sys.KeyValue, error) {
+	ti := (*traceEventInfo)(unsafe.Pointer(&info[0]))
+
+	pointerSize := uint32(8)
+	if r.EventHeader.Flags&eventHeaderFlag32BitHeader != 0 {
+		pointerSize = 4
+	}
+
+	// The property infos follow the last field of TRACE_EVENT_INFO. The Go
+	// structure is padded at the end, so its size can not be used.
+	offset := unsafe.Offsetof(ti.Flags) + unsafe.Sizeof(ti.Flags)
+	propSize := unsafe.Sizeof(eventPropertyInfo{})
+
+	pairs := make([]sys.KeyValue, 0, ti.TopLevelPropertyCount)
+	for i := uintptr(0); i < uintptr(ti.TopLevelPropertyCount); i++ {
+		pi := (*eventPropertyInfo)(unsafe.Pointer(&info[offset+i*propSize]))
+		name := utf16At(info, pi.NameOffset)
+		if pi.Flags&propertyStruct != 0 {
+			continue
+		}
+
+		value, err := property(r, info, pi, pointerSize)
+		if err != nil {
+			return pairs, fmt.Errorf("failed to format property '%v': %v", name, err)
+		}
+		pairs = append(pairs, sys.KeyValue{Key: name, Value: value})
+	}
+	return pairs, nil
+}
+
+// property formats the value of one property.
+func property(r *eventRecord, info []byte, pi *eventPropertyInfo, pointerSize uint32) (string, error) {
+	name := info[pi.NameOffset:]
+	desc := propertyDataDescriptor{
+		PropertyName: uint64(uintptr(unsafe.Pointer(&name[0]))),
+		ArrayIndex:   0xFFFFFFFF,
+	}
+	if pi.Flags&propertyParamCount != 0 || pi.Count > 1 {
+		desc.ArrayIndex = 0
+	}
+
+	var size uint32
+	if err := _TdhGetPropertySize(r, 0, 0, 1, &desc, &size); err != nil {
+		return "", err
+	}
+	if size == 0 {
+		return "", nil
+	}
+
+	data := make([]byte, size)
+	if err := _TdhGetProperty(r, 0, 0, 1, &desc, size, &data[0]); err != nil {
+		return "", err
+	}
+
+	length := pi.Length
+	if pi.Flags&propertyParamLength != 0 {
+		length = uint16(size)
+	}
+
+	buf := make([]uint16, 256)
+	bufSize := uint32(len(buf) * 2)
+	var consumed uint16
+	err := _TdhFormatProperty(&info[0], 0, pointerSize, pi.InType, pi.OutType,
+		length, uint16(size), &data[0], &bufSize, &buf[0], &consumed)
+	if err == ERROR_INSUFFICIENT_BUFFER {
+		buf = make([]uint16, bufSize/2)
+		err = _TdhFormatProperty(&info[0], 0, pointerSize, pi.InType, pi.OutType,
+			length, uint16(size), &data[0], &bufSize, &buf[0], &consumed)
+	}
+	if err != nil {
+		return "", err
+	}
+	return syscall.UTF16ToString(buf), nil
+}
+
+// Providers returns the names and GUIDs of the providers registered on the
+// system.
+func Providers() (map[string]GUID, error) {
+	var size uint32
+	err := _TdhEnumerateProviders(nil, &size)
+	if err != ERROR_INSUFFICIENT_BUFFER {
+		return nil, fmt.Errorf("failed to enumerate providers: %v", err)
+	}
+
+	buf := make([]byte, size)
+	if err := _TdhEnumerateProviders(&buf[0], &size); err != nil {
+		return nil, fmt.Errorf("failed to enumerate providers: %v", err)
+	}
+
+	info := (*providerEnumerationInfo)(unsafe.Pointer(&buf[0]))
+	offset := unsafe.Sizeof(*info)
+	infoSize := unsafe.Sizeof(traceProviderInfo{})
+
+	providers := make(map[string]GUID, info.NumberOfProviders)
+	for i := uintptr(0); i < uintptr(info.NumberOfProviders); i++ {
+		pi := (*traceProviderInfo)(unsafe.Pointer(&buf[offset+i*infoSize]))
+		providers[utf16At(buf, pi.ProviderNameOffset)] = pi.ProviderGUID
+	}
+	return providers, nil
+}
+
+// utf16At returns the null-terminated string at offset in buf. An offset of
+// 0 means the string is not set.
+func utf16At(buf []byte, offset uint32) string {
+	if offset == 0 || int(offset) >= len(buf) {
+		return ""
+	}
+	s, _, _ := sys.UTF16BytesToString(utf16Bytes(buf, offset))
+	return s
+}
+
+// utf16ListAt returns the list of null-terminated strings at offset in buf.
+// The list ends with an empty string.
+func utf16ListAt(buf []byte, offset uint32) []string {
+	var list []string
+	for offset != 0 && int(offset) < len(buf) {
+		s, n, _ := sys.UTF16BytesToString(utf16Bytes(buf, offset))
+		if s == "" {
+			break
+		}
+		list = append(list, s)
+		offset += uint32(n)
+	}
+	return list
+}
+
+// utf16Bytes returns the bytes from offset to the end of buf, truncated to an
+// even length.
+func utf16Bytes(buf []byte, offset uint32) []byte {
+	b := buf[offset:]
+	return b[:len(b)-len(b)%2]
+}
+
+// utf16PtrToString returns the string of at most size bytes at ptr.
+func utf16PtrToString(ptr unsafe.Pointer, size int) string {
+	if ptr == nil || size < 2 {
+		return ""
+	}
+	b := (*[1 << 20]byte)(ptr)[:size:size]
+	s, _, _ := sys.UTF16BytesToString(b)
+	return s
+}
@@ -0,0 +1,41 @@
+package etw
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/beats/winlogbeat/sys"
+)
+
+// formatMessage replaces the %1 to %N inserts of the event message defined in
+// the manifest with the event properties. Inserts without a property are kept
+// as is.
+func formatMessage(msg string, props []sys.KeyValue) string {
+	if !strings.Contains(msg, "%") {
+		return msg
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(msg); i++ {
+		if msg[i] != '%' || i+1 >= len(msg) {
+			buf.WriteByte(msg[i])
+			continue
+		}
+
+		j := i + 1
+		for j < len(msg) && msg[j] >= '0' && msg[j] <= '9' {
+			j++
+		}
+
+		n, err := strconv.Atoi(msg[i+1 : j])
+		if err != nil || n < 1 || n > len(props) {
+			buf.WriteByte(msg[i])
+			continue
+		}
+
+		buf.WriteString(props[n-1].Value)
+		i = j - 1
+	}
+	return buf.String()
+}
@@ -0,0 +1,23 @@
+// +build !integration
+
+package etw
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/winlogbeat/sys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatMessage(t *testing.T) {
+	props := []sys.KeyValue{
+		{Key: "QueryName", Value: "www.elastic.co"},
+		{Key: "QueryType", Value: "1"},
+	}
+
+	assert.Equal(t, "DNS query for www.elastic.co (type 1) is called.",
+		formatMessage("DNS query for %1 (type %2) is called.", props))
+	assert.Equal(t, "100% of %3 and %",
+		formatMessage("100% of %3 and %", props))
+	assert.Equal(t, "", formatMessage("", props))
+}
@@ -0,0 +1,99 @@
+package etw
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GUID identifies a provider. It has the same memory layout as the Windows
+// GUID structure.
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// ParseGUID parses a GUID in the registry format with or without braces
+// (e.g. {1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}).
+func ParseGUID(s string) (GUID, error) {
+	var g GUID
+
+	raw := strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	parts := strings.Split(raw, "-")
+	if len(parts) != 5 || len(parts[0]) != 8 || len(parts[1]) != 4 ||
+		len(parts[2]) != 4 || len(parts[3]) != 4 || len(parts[4]) != 12 {
+		return g, fmt.Errorf("invalid GUID '%v'", s)
+	}
+
+	d1, err1 := strconv.ParseUint(parts[0], 16, 32)
+	d2, err2 := strconv.ParseUint(parts[1], 16, 16)
+	d3, err3 := strconv.ParseUint(parts[2], 16, 16)
+	d4, err4 := hex.DecodeString(parts[3] + parts[4])
+	for _, err := range []error{err1, err2, err3, err4} {
+		if err != nil {
+			return g, fmt.Errorf("invalid GUID '%v'", s)
+		}
+	}
+
+	g.Data1 = uint32(d1)
+	g.Data2 = uint16(d2)
+	g.Data3 = uint16(d3)
+	copy(g.Data4[:], d4)
+	return g, nil
+}
+
+// IsZero returns true if all bytes of the GUID are zero.
+func (g GUID) IsZero() bool {
+	return g == GUID{}
+}
+
+// String returns the GUID in the registry format.
+func (g GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		g.Data1, g.Data2, g.Data3, g.Data4[:2], g.Data4[2:])
+}
+
+// Trace levels. Providers log events with a level less than or equal to the
+// level the provider is enabled with.
+const (
+	LevelCritical    uint8 = 1
+	LevelError       uint8 = 2
+	LevelWarning     uint8 = 3
+	LevelInformation uint8 = 4
+	LevelVerbose     uint8 = 5
+)
+
+var levels = map[string]uint8{
+	"critical":    LevelCritical,
+	"error":       LevelError,
+	"warning":     LevelWarning,
+	"information": LevelInformation,
+	"info":        LevelInformation,
+	"verbose":     LevelVerbose,
+}
+
+// ParseLevel parses a level name (critical, error, warning, information or
+// verbose) or a level number between 1 and 255.
+func ParseLevel(s string) (uint8, error) {
+	if level, found := levels[strings.ToLower(s)]; found {
+		return level, nil
+	}
+
+	level, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || level == 0 {
+		return 0, fmt.Errorf("invalid level '%v'", s)
+	}
+	return uint8(level), nil
+}
+
+// Provider specifies a provider enabled in a trace session and the events
+// requested from the provider.
+type Provider struct {
+	GUID            GUID   // Provider GUID.
+	Level           uint8  // Maximum level of events to log.
+	MatchAnyKeyword uint64 // Events must match any of these keywords. 0 matches all.
+	MatchAllKeyword uint64 // Events must match all of these keywords.
+}
@@ -0,0 +1,65 @@
+// +build !integration
+
+package etw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGUID(t *testing.T) {
+	expected := GUID{
+		Data1: 0x1C95126E,
+		Data2: 0x7EEA,
+		Data3: 0x49A9,
+		Data4: [8]byte{0xA3, 0xFE, 0xA3, 0x78, 0xB0, 0x3D, 0xDB, 0x4D},
+	}
+
+	for _, s := range []string{
+		"{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}",
+		"1c95126e-7eea-49a9-a3fe-a378b03ddb4d",
+	} {
+		guid, err := ParseGUID(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, guid)
+		}
+	}
+	assert.Equal(t, "{1C95126E-7EEA-49A9-A3FE-A378B03DDB4D}", expected.String())
+	assert.False(t, expected.IsZero())
+	assert.True(t, GUID{}.IsZero())
+}
+
+func TestParseGUIDInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"Microsoft-Windows-DNS-Client",
+		"{1C95126E-7EEA-49A9-A3FE-A378B03DDB4}",
+		"{1C95126E-7EEA-49A9-A3FE-A378B03DDB4X}",
+	} {
+		_, err := ParseGUID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]uint8{
+		"critical":    LevelCritical,
+		"Error":       LevelError,
+		"warning":     LevelWarning,
+		"info":        LevelInformation,
+		"information": LevelInformation,
+		"verbose":     LevelVerbose,
+		"16":          16,
+	}
+	for s, expected := range tests {
+		level, err := ParseLevel(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, level, s)
+	}
+
+	for _, s := range []string{"", "0", "256", "debug"} {
+		_, err := ParseLevel(s)
+		assert.Error(t, err, s)
+	}
+}
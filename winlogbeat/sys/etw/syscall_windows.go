@@ -0,0 +1,269 @@
+// +build amd64
+
+package etw
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// traceHandle is a handle to a trace session or to a consumer of a trace
+// session (TRACEHANDLE).
+type traceHandle uint64
+
+// invalidProcessTraceHandle is returned by OpenTrace on failure.
+const invalidProcessTraceHandle traceHandle = 0xFFFFFFFFFFFFFFFF
+
+// ETW error codes.
+const (
+	ERROR_INSUFFICIENT_BUFFER    syscall.Errno = 122
+	ERROR_ALREADY_EXISTS         syscall.Errno = 183
+	ERROR_MORE_DATA              syscall.Errno = 234
+	ERROR_NOT_FOUND              syscall.Errno = 1168
+	ERROR_CANCELLED              syscall.Errno = 1223
+	ERROR_WMI_INSTANCE_NOT_FOUND syscall.Errno = 4201
+)
+
+// Flags and modes of trace sessions and consumers.
+// https://msdn.microsoft.com/en-us/library/windows/desktop/aa363784(v=vs.85).aspx
+const (
+	wnodeFlagTracedGUID = 0x00020000
+
+	eventTraceRealTimeMode = 0x00000100
+
+	processTraceModeRealTime    = 0x00000100
+	processTraceModeEventRecord = 0x10000000
+
+	eventTraceControlStop = 1
+
+	eventControlCodeDisableProvider = 0
+	eventControlCodeEnableProvider  = 1
+
+	// Wnode.ClientContext value for system time timestamps.
+	clockTypeSystemTime = 2
+)
+
+// EVENT_HEADER flags.
+const (
+	eventHeaderFlagStringOnly  = 0x0004
+	eventHeaderFlag32BitHeader = 0x0020
+	eventHeaderFlag64BitHeader = 0x0040
+)
+
+// PROPERTY_FLAGS enumeration
+// https://msdn.microsoft.com/en-us/library/windows/desktop/aa964820(v=vs.85).aspx
+const (
+	propertyStruct      = 0x1
+	propertyParamLength = 0x2
+	propertyParamCount  = 0x4
+)
+
+// wnodeHeader is the WNODE_HEADER structure.
+type wnodeHeader struct {
+	BufferSize        uint32
+	ProviderID        uint32
+	HistoricalContext uint64
+	TimeStamp         int64
+	GUID              GUID
+	ClientContext     uint32
+	Flags             uint32
+}
+
+// eventTraceProperties is the EVENT_TRACE_PROPERTIES structure. The session
+// name is stored in the memory following the structure.
+type eventTraceProperties struct {
+	Wnode               wnodeHeader
+	BufferSize          uint32
+	MinimumBuffers      uint32
+	MaximumBuffers      uint32
+	MaximumFileSize     uint32
+	LogFileMode         uint32
+	FlushTimer          uint32
+	EnableFlags         uint32
+	AgeLimit            int32
+	NumberOfBuffers     uint32
+	FreeBuffers         uint32
+	EventsLost          uint32
+	BuffersWritten      uint32
+	LogBuffersLost      uint32
+	RealTimeBuffersLost uint32
+	LoggerThreadID      uintptr
+	LogFileNameOffset   uint32
+	LoggerNameOffset    uint32
+}
+
+// eventTraceHeader is the EVENT_TRACE_HEADER structure.
+type eventTraceHeader struct {
+	Size           uint16
+	FieldTypeFlags uint16
+	Version        uint32
+	ThreadID       uint32
+	ProcessID      uint32
+	TimeStamp      int64
+	GUID           GUID
+	ProcessorTime  uint64
+}
+
+// eventTrace is the EVENT_TRACE structure.
+type eventTrace struct {
+	Header           eventTraceHeader
+	InstanceID       uint32
+	ParentInstanceID uint32
+	ParentGUID       GUID
+	MofData          uintptr
+	MofLength        uint32
+	ClientContext    uint32
+}
+
+// traceLogfileHeader is the TRACE_LOGFILE_HEADER structure.
+type traceLogfileHeader struct {
+	BufferSize         uint32
+	Version            uint32
+	ProviderVersion    uint32
+	NumberOfProcessors uint32
+	EndTime            int64
+	TimerResolution    uint32
+	MaximumFileSize    uint32
+	LogFileMode        uint32
+	BuffersWritten     uint32
+	LogInstanceGUID    GUID
+	LoggerName         *uint16
+	LogFileName        *uint16
+	TimeZone           syscall.Timezoneinformation
+	BootTime           int64
+	PerfFreq           int64
+	StartTime          int64
+	ReservedFlags      uint32
+	BuffersLost        uint32
+}
+
+// eventTraceLogfile is the EVENT_TRACE_LOGFILEW structure.
+type eventTraceLogfile struct {
+	LogFileName      *uint16
+	LoggerName       *uint16
+	CurrentTime      int64
+	BuffersRead      uint32
+	ProcessTraceMode uint32
+	CurrentEvent     eventTrace
+	LogfileHeader    traceLogfileHeader
+	BufferCallback   uintptr
+	BufferSize       uint32
+	Filled           uint32
+	EventsLost       uint32
+	EventCallback    uintptr
+	IsKernelTrace    uint32
+	Context          uintptr
+}
+
+// eventDescriptor is the EVENT_DESCRIPTOR structure.
+type eventDescriptor struct {
+	ID      uint16
+	Version uint8
+	Channel uint8
+	Level   uint8
+	Opcode  uint8
+	Task    uint16
+	Keyword uint64
+}
+
+// eventHeader is the EVENT_HEADER structure.
+type eventHeader struct {
+	Size            uint16
+	HeaderType      uint16
+	Flags           uint16
+	EventProperty   uint16
+	ThreadID        uint32
+	ProcessID       uint32
+	TimeStamp       int64
+	ProviderID      GUID
+	EventDescriptor eventDescriptor
+	ProcessorTime   uint64
+	ActivityID      GUID
+}
+
+// eventRecord is the EVENT_RECORD structure delivered to the consumer.
+type eventRecord struct {
+	EventHeader       eventHeader
+	ProcessorNumber   uint8
+	Alignment         uint8
+	LoggerID          uint16
+	ExtendedDataCount uint16
+	UserDataLength    uint16
+	ExtendedData      uintptr
+	UserData          unsafe.Pointer
+	UserContext       uintptr
+}
+
+// traceEventInfo is the TRACE_EVENT_INFO structure. The property infos and
+// the strings referenced by the offsets follow in the same buffer.
+type traceEventInfo struct {
+	ProviderGUID                GUID
+	EventGUID                   GUID
+	EventDescriptor             eventDescriptor
+	DecodingSource              uint32
+	ProviderNameOffset          uint32
+	LevelNameOffset             uint32
+	ChannelNameOffset           uint32
+	KeywordsNameOffset          uint32
+	TaskNameOffset              uint32
+	OpcodeNameOffset            uint32
+	EventMessageOffset          uint32
+	ProviderMessageOffset       uint32
+	BinaryXMLOffset             uint32
+	BinaryXMLSize               uint32
+	ActivityIDNameOffset        uint32
+	RelatedActivityIDNameOffset uint32
+	PropertyCount               uint32
+	TopLevelPropertyCount       uint32
+	Flags                       uint32
+}
+
+// eventPropertyInfo is the EVENT_PROPERTY_INFO structure. InType and OutType
+// are only valid for properties that are not structs.
+type eventPropertyInfo struct {
+	Flags         uint32
+	NameOffset    uint32
+	InType        uint16
+	OutType       uint16
+	MapNameOffset uint32
+	Count         uint16
+	Length        uint16
+	Reserved      uint32
+}
+
+// propertyDataDescriptor is the PROPERTY_DATA_DESCRIPTOR structure.
+type propertyDataDescriptor struct {
+	PropertyName uint64
+	ArrayIndex   uint32
+	Reserved     uint32
+}
+
+// providerEnumerationInfo is the PROVIDER_ENUMERATION_INFO structure. The
+// provider infos and their names follow in the same buffer.
+type providerEnumerationInfo struct {
+	NumberOfProviders uint32
+	Reserved          uint32
+}
+
+// traceProviderInfo is the TRACE_PROVIDER_INFO structure.
+type traceProviderInfo struct {
+	ProviderGUID       GUID
+	SchemaSource       uint32
+	ProviderNameOffset uint32
+}
+
+// Add -trace to enable debug prints around syscalls.
+//go:generate go run $GOROOT/src/syscall/mksyscall_windows.go -output zsyscall_windows.go syscall_windows.go
+
+// Windows API calls
+//sys   _StartTrace(handle *traceHandle, name *uint16, properties *eventTraceProperties) (ret error) = advapi32.StartTraceW
+//sys   _ControlTrace(handle traceHandle, name *uint16, properties *eventTraceProperties, controlCode uint32) (ret error) = advapi32.ControlTraceW
+//sys   _EnableTraceEx2(handle traceHandle, providerID *GUID, controlCode uint32, level uint8, matchAnyKeyword uint64, matchAllKeyword uint64, timeout uint32, enableParameters uintptr) (ret error) = advapi32.EnableTraceEx2
+//sys   _OpenTrace(logfile *eventTraceLogfile) (handle traceHandle, err error) [failretval==invalidProcessTraceHandle] = advapi32.OpenTraceW
+//sys   _ProcessTrace(handles *traceHandle, handleCount uint32, startTime *syscall.Filetime, endTime *syscall.Filetime) (ret error) = advapi32.ProcessTrace
+//sys   _CloseTrace(handle traceHandle) (ret error) = advapi32.CloseTrace
+//sys   _TdhGetEventInformation(event *eventRecord, contextCount uint32, context uintptr, buffer *byte, bufferSize *uint32) (ret error) = tdh.TdhGetEventInformation
+//sys   _TdhGetPropertySize(event *eventRecord, contextCount uint32, context uintptr, propertyDataCount uint32, propertyData *propertyDataDescriptor, propertySize *uint32) (ret error) = tdh.TdhGetPropertySize
+//sys   _TdhGetProperty(event *eventRecord, contextCount uint32, context uintptr, propertyDataCount uint32, propertyData *propertyDataDescriptor, bufferSize uint32, buffer *byte) (ret error) = tdh.TdhGetProperty
+//sys   _TdhFormatProperty(eventInfo *byte, mapInfo uintptr, pointerSize uint32, inType uint16, outType uint16, length uint16, userDataLength uint16, userData *byte, bufferSize *uint32, buffer *uint16, userDataConsumed *uint16) (ret error) = tdh.TdhFormatProperty
+//sys   _TdhEnumerateProviders(buffer *byte, bufferSize *uint32) (ret error) = tdh.TdhEnumerateProviders
@@ -0,0 +1,120 @@
+// MACHINE GENERATED BY 'go generate' COMMAND; DO NOT EDIT
+
+// +build amd64
+
+package etw
+
+import "unsafe"
+import "syscall"
+
+var _ unsafe.Pointer
+
+var (
+	modadvapi32 = syscall.NewLazyDLL("advapi32.dll")
+	modtdh      = syscall.NewLazyDLL("tdh.dll")
+
+	procStartTraceW            = modadvapi32.NewProc("StartTraceW")
+	procControlTraceW          = modadvapi32.NewProc("ControlTraceW")
+	procEnableTraceEx2         = modadvapi32.NewProc("EnableTraceEx2")
+	procOpenTraceW             = modadvapi32.NewProc("OpenTraceW")
+	procProcessTrace           = modadvapi32.NewProc("ProcessTrace")
+	procCloseTrace             = modadvapi32.NewProc("CloseTrace")
+	procTdhGetEventInformation = modtdh.NewProc("TdhGetEventInformation")
+	procTdhGetPropertySize     = modtdh.NewProc("TdhGetPropertySize")
+	procTdhGetProperty         = modtdh.NewProc("TdhGetProperty")
+	procTdhFormatProperty      = modtdh.NewProc("TdhFormatProperty")
+	procTdhEnumerateProviders  = modtdh.NewProc("TdhEnumerateProviders")
+)
+
+func _StartTrace(handle *traceHandle, name *uint16, properties *eventTraceProperties) (ret error) {
+	r0, _, _ := syscall.Syscall(procStartTraceW.Addr(), 3, uintptr(unsafe.Pointer(handle)), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(properties)))
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _ControlTrace(handle traceHandle, name *uint16, properties *eventTraceProperties, controlCode uint32) (ret error) {
+	r0, _, _ := syscall.Syscall6(procControlTraceW.Addr(), 4, uintptr(handle), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(properties)), uintptr(controlCode), 0, 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _EnableTraceEx2(handle traceHandle, providerID *GUID, controlCode uint32, level uint8, matchAnyKeyword uint64, matchAllKeyword uint64, timeout uint32, enableParameters uintptr) (ret error) {
+	r0, _, _ := syscall.Syscall9(procEnableTraceEx2.Addr(), 8, uintptr(handle), uintptr(unsafe.Pointer(providerID)), uintptr(controlCode), uintptr(level), uintptr(matchAnyKeyword), uintptr(matchAllKeyword), uintptr(timeout), uintptr(enableParameters), 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _OpenTrace(logfile *eventTraceLogfile) (handle traceHandle, err error) {
+	r0, _, e1 := syscall.Syscall(procOpenTraceW.Addr(), 1, uintptr(unsafe.Pointer(logfile)), 0, 0)
+	handle = traceHandle(r0)
+	if handle == invalidProcessTraceHandle {
+		if e1 != 0 {
+			err = error(e1)
+		} else {
+			err = syscall.EINVAL
+		}
+	}
+	return
+}
+
+func _ProcessTrace(handles *traceHandle, handleCount uint32, startTime *syscall.Filetime, endTime *syscall.Filetime) (ret error) {
+	r0, _, _ := syscall.Syscall6(procProcessTrace.Addr(), 4, uintptr(unsafe.Pointer(handles)), uintptr(handleCount), uintptr(unsafe.Pointer(startTime)), uintptr(unsafe.Pointer(endTime)), 0, 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _CloseTrace(handle traceHandle) (ret error) {
+	r0, _, _ := syscall.Syscall(procCloseTrace.Addr(), 1, uintptr(handle), 0, 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _TdhGetEventInformation(event *eventRecord, contextCount uint32, context uintptr, buffer *byte, bufferSize *uint32) (ret error) {
+	r0, _, _ := syscall.Syscall6(procTdhGetEventInformation.Addr(), 5, uintptr(unsafe.Pointer(event)), uintptr(contextCount), uintptr(context), uintptr(unsafe.Pointer(buffer)), uintptr(unsafe.Pointer(bufferSize)), 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _TdhGetPropertySize(event *eventRecord, contextCount uint32, context uintptr, propertyDataCount uint32, propertyData *propertyDataDescriptor, propertySize *uint32) (ret error) {
+	r0, _, _ := syscall.Syscall6(procTdhGetPropertySize.Addr(), 6, uintptr(unsafe.Pointer(event)), uintptr(contextCount), uintptr(context), uintptr(propertyDataCount), uintptr(unsafe.Pointer(propertyData)), uintptr(unsafe.Pointer(propertySize)))
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _TdhGetProperty(event *eventRecord, contextCount uint32, context uintptr, propertyDataCount uint32, propertyData *propertyDataDescriptor, bufferSize uint32, buffer *byte) (ret error) {
+	r0, _, _ := syscall.Syscall9(procTdhGetProperty.Addr(), 7, uintptr(unsafe.Pointer(event)), uintptr(contextCount), uintptr(context), uintptr(propertyDataCount), uintptr(unsafe.Pointer(propertyData)), uintptr(bufferSize), uintptr(unsafe.Pointer(buffer)), 0, 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _TdhFormatProperty(eventInfo *byte, mapInfo uintptr, pointerSize uint32, inType uint16, outType uint16, length uint16, userDataLength uint16, userData *byte, bufferSize *uint32, buffer *uint16, userDataConsumed *uint16) (ret error) {
+	r0, _, _ := syscall.Syscall12(procTdhFormatProperty.Addr(), 11, uintptr(unsafe.Pointer(eventInfo)), uintptr(mapInfo), uintptr(pointerSize), uintptr(inType), uintptr(outType), uintptr(length), uintptr(userDataLength), uintptr(unsafe.Pointer(userData)), uintptr(unsafe.Pointer(bufferSize)), uintptr(unsafe.Pointer(buffer)), uintptr(unsafe.Pointer(userDataConsumed)), 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}
+
+func _TdhEnumerateProviders(buffer *byte, bufferSize *uint32) (ret error) {
+	r0, _, _ := syscall.Syscall(procTdhEnumerateProviders.Addr(), 2, uintptr(unsafe.Pointer(buffer)), uintptr(unsafe.Pointer(bufferSize)), 0)
+	if r0 != 0 {
+		ret = syscall.Errno(r0)
+	}
+	return
+}